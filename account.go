@@ -1,25 +1,42 @@
 package gotrader
 
 import (
+	"sync"
 	"time"
-
-	"go.uber.org/atomic"
 )
 
 // Account represent the current account status. Mirrors the broker status.
 type Account struct {
+	lock                      sync.RWMutex // held by the writes of the totals, read from any goroutine
 	id                        string
 	instruments               map[string]*Instrument
 	time                      time.Time
 	homeCurrency              string
-	equity                    float64
-	balance                   *atomic.Float64
-	unrealizedNetProfit       float64
-	unrealizedEffectiveProfit float64
-	chargedFees               float64
-	marginUsed                float64
-	marginFree                float64
+	equity                    Decimal
+	balance                   *atomicDecimal
+	unrealizedNetProfit       Decimal
+	unrealizedEffectiveProfit Decimal
+	chargedFees               Decimal
+	marginUsed                Decimal
+	marginFree                Decimal
 	leverage                  float64
+	ledger                    *Ledger
+	events                    *EventBus
+	alerts                    *Alerts
+	slippages                 *Slippages
+	health                    *Health
+	wal                       *WAL
+	recalculator              *recalculator
+	stats                     *pipelineStats
+	equityCurve               *EquityCurve
+	daily                     *dailyMarks
+	fees                      *feeAccruer      // nil without managed fees
+	engine                    Engine           // of the session, closing the trades of the bulk closes
+	totals                    instrumentTotals // sum of the aggregated metrics of the instruments
+	instrumentList            []*Instrument    // the instruments as a slice, iterated on ticks
+	changed                   []*Instrument
+	marginCall                bool
+	writes                    viewGuard // of the trades and the balance, see View
 }
 
 /**************************
@@ -33,49 +50,171 @@ func newAccount(accountID string) *Account {
 	return &Account{
 		id:          accountID,
 		instruments: make(map[string]*Instrument),
-		balance:     atomic.NewFloat64(0.0),
+		balance:     newAtomicDecimal(0),
+		ledger:      newLedger(),
+		alerts:      newAlerts(),
+		slippages:   newSlippages(),
+		health:      newHealth(),
+		daily:       newDailyMarks(0, time.UTC),
 	}
 
 }
 
-func (a *Account) calculateUnrealized() {
+// calculate calculates every instrument and the account totals, when the account is restored.
+func (a *Account) calculate() {
 
-	unrealizedNet := 0.0
-	unrealizedEffective := 0.0
-	chargedFees := 0.0
+	for _, instrument := range a.list() {
+		instrument.calculateUnrealized()
+		instrument.calculateMarginUsed()
+	}
 
-	for _, instrument := range a.instruments {
+	a.aggregate()
+}
 
-		instrument.calculateUnrealized()
+// list returns the instruments as a slice, rebuilt when instruments were added.
+func (a *Account) list() []*Instrument {
+
+	if len(a.instrumentList) != len(a.instruments) {
+		a.instrumentList = a.instrumentList[:0]
+		for _, instrument := range a.instruments {
+			a.instrumentList = append(a.instrumentList, instrument)
+		}
+	}
+
+	return a.instrumentList
+}
+
+/*
+aggregate updates the account totals with the metrics of the instruments calculated since the last aggregation,
+adding the difference with the metrics previously aggregated. The decimals sums are exact, so the totals don't
+drift from a full sum. The instruments are calculated when their trades are opened or closed and on the ticks,
+so neither the account mutations nor the ticks rescan them.
+*/
+func (a *Account) aggregate() {
+
+	for _, instrument := range a.list() {
+
+		if instrument.version.Load() == instrument.aggregatedVersion {
+			continue
+		}
+
+		instrument.lock.RLock()
+		current := instrument.totals()
+		instrument.aggregatedVersion = instrument.version.Load()
+		instrument.lock.RUnlock()
+
+		a.totals = a.totals.add(current).sub(instrument.aggregated)
+		instrument.aggregated = current
+	}
 
-		unrealizedNet += instrument.unrealizedNetProfit
-		unrealizedEffective += instrument.unrealizedEffectiveProfit
-		chargedFees += instrument.chargedFees
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.unrealizedNetProfit = a.totals.unrealizedNetProfit
+	a.unrealizedEffectiveProfit = a.totals.unrealizedEffectiveProfit
+	a.chargedFees = a.totals.chargedFees
+	a.marginUsed = a.totals.marginUsed
+	a.equity = a.unrealizedNetProfit.Add(a.balance.Load())
+	a.marginFree = a.equity.Sub(a.marginUsed)
+}
+
+// recalculate recalculates the instruments changed since their last calculation, sharded by the recalculator,
+// and the account totals. It is the tick path.
+func (a *Account) recalculate() {
+
+	start := a.stats.startRecalculation()
+	a.changed = a.changed[:0]
+
+	for _, instrument := range a.list() {
+		if instrument.changed() {
+			a.changed = append(a.changed, instrument)
+		}
+	}
+
+	a.recalculator.run(a.changed)
+
+	a.aggregate()
+
+	if a.equityCurve != nil {
+		a.equityCurve.record(a.time, a.equity.Float64(), a.balance.Load().Float64())
 	}
 
-	a.unrealizedNetProfit = unrealizedNet
-	a.unrealizedEffectiveProfit = unrealizedEffective
-	a.equity = a.unrealizedNetProfit + a.balance.Load()
-	a.chargedFees = chargedFees
+	a.daily.mark(a.time, a.unrealizedNetProfit.Float64())
+
+	a.stats.recalculated(start)
 }
 
-func (a *Account) calculateMarginUsed() {
+// collectStats sets the stats collected by the account and its instruments.
+func (a *Account) collectStats(stats *pipelineStats) {
 
-	marginUsed := 0.0
+	a.stats = stats
 
 	for _, instrument := range a.instruments {
+		instrument.stats = stats
+	}
+}
 
-		instrument.calculateMarginUsed()
-		marginUsed += instrument.marginUsed
+func (a *Account) setTime(t time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.time = t
+}
+
+// checkMarginCall publishes a MarginCall event when the margin level crosses under the given level.
+func (a *Account) checkMarginCall(level float64) {
+
+	if a.marginUsed <= 0 || a.equity.Float64()/a.marginUsed.Float64() >= level {
+		a.marginCall = false
+		return
+	}
 
+	if !a.marginCall {
+		a.marginCall = true
+		a.events.publish(MarginCall{
+			Time:        a.time,
+			Equity:      a.equity.Float64(),
+			MarginUsed:  a.marginUsed.Float64(),
+			MarginLevel: a.equity.Float64() / a.marginUsed.Float64(),
+		})
 	}
+}
 
-	a.marginUsed = marginUsed
+// record records a transaction in the ledger, crediting the realized profit of its instrument.
+func (a *Account) record(transaction *Transaction) {
+	a.realize(transaction)
+	a.ledger.record(transaction)
+}
 
+// realize credits a transaction to the realized profit of its instrument, the external ones excepted.
+func (a *Account) realize(transaction *Transaction) {
+
+	if transaction.Type.External() {
+		return
+	}
+
+	if inst, exist := a.instruments[transaction.Instrument]; exist {
+		inst.realizedProfit.Add(NewDecimal(transaction.Amount))
+	}
 }
 
-func (a *Account) calculateFreeMargin() {
-	a.marginFree = a.equity - a.marginUsed
+// checkStale publishes a PriceStale event for the instruments not updated since now - staleAfter, the age of
+// the prices discounting the skew of their feed.
+func (a *Account) checkStale(now time.Time, staleAfter time.Duration, feed *feedLatency) {
+
+	if staleAfter <= 0 {
+		return
+	}
+
+	for _, inst := range a.instruments {
+
+		if inst.lastUpdate.IsZero() || inst.stale || now.Sub(inst.lastUpdate)-feed.skew(inst.name) < staleAfter {
+			continue
+		}
+
+		inst.stale = true
+		a.events.publish(PriceStale{Time: now, Instrument: inst.name, LastUpdate: inst.lastUpdate})
+	}
 }
 
 /**************************
@@ -89,10 +228,16 @@ func (a *Account) ID() string {
 }
 
 func (a *Account) Instruments() map[string]*Instrument {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
 	return a.instruments
 }
 
 func (a *Account) Instrument(instrument string) *Instrument {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
 	return a.instruments[instrument]
 }
 
@@ -101,33 +246,85 @@ func (a *Account) HomeCurrency() string {
 }
 
 func (a *Account) Equity() float64 {
-	return a.equity
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.equity.Float64()
 }
 
 func (a *Account) Balance() float64 {
-	return a.balance.Load()
+	return a.balance.Load().Float64()
 }
 
 func (a *Account) UnrealizedNetProfit() float64 {
-	return a.unrealizedNetProfit
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.unrealizedNetProfit.Float64()
 }
 
 func (a *Account) UnrealizedEffectiveProfit() float64 {
-	return a.unrealizedEffectiveProfit
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.unrealizedEffectiveProfit.Float64()
 }
 
 func (a *Account) ChargedFees() float64 {
-	return a.chargedFees
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.chargedFees.Float64()
 }
 
 func (a *Account) MarginUsed() float64 {
-	return a.marginUsed
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.marginUsed.Float64()
 }
 
 func (a *Account) MarginFree() float64 {
-	return a.marginFree
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.marginFree.Float64()
 }
 
 func (a *Account) Time() time.Time {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
 	return a.time
 }
+
+// EquityCurve returns the equity curve of the account, nil without the TrackEquity option.
+func (a *Account) EquityCurve() *EquityCurve {
+	return a.equityCurve
+}
+
+// Events returns the event bus of the account.
+func (a *Account) Events() *EventBus {
+	return a.events
+}
+
+// Alerts returns the price alerts of the account.
+func (a *Account) Alerts() *Alerts {
+	return a.alerts
+}
+
+// Slippages returns the slippages of the live fills of the account.
+func (a *Account) Slippages() *Slippages {
+	return a.slippages
+}
+
+// Health returns the health of the feed and the broker session, monitored by the live engine with the HealthCheck
+// option.
+func (a *Account) Health() *Health {
+	return a.health
+}
+
+// Ledger returns the realized transactions history of the account.
+func (a *Account) Ledger() *Ledger {
+	return a.ledger
+}
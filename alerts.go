@@ -0,0 +1,263 @@
+package gotrader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AlertCondition is the price condition of an Alert.
+type AlertCondition int
+
+const (
+	// CrossAbove triggers when the mid price crosses above the level
+	CrossAbove AlertCondition = iota
+
+	// CrossBelow triggers when the mid price crosses below the level
+	CrossBelow
+
+	// SpreadAbove triggers when the spread exceeds the level, in price units
+	SpreadAbove
+
+	// MoveWithin triggers when the mid price moves by the level, a fraction of the price, from its low or high
+	// within the window, e.g. 0.01 for a 1% move
+	MoveWithin
+)
+
+func (c AlertCondition) String() string {
+
+	names := [...]string{"CROSS_ABOVE", "CROSS_BELOW", "SPREAD_ABOVE", "MOVE_WITHIN"}
+
+	return names[c]
+}
+
+/*
+Alert is a price condition of an instrument registered with Alerts.Add. A cross triggers on the tick crossing the
+level, not on the first tick already beyond it, and a spread alert once per widening: it is rearmed when the spread
+narrows back. A move alert is rearmed with a new window once triggered. Once alerts are removed when they trigger.
+
+OnTrigger is called by the goroutine processing the ticks, before the strategy OnTick, so it must not block; the
+AlertTriggered event is also published on the event bus of the account.
+*/
+type Alert struct {
+	Instrument string
+	Condition  AlertCondition
+	Level      float64
+	Window     time.Duration // of MoveWithin
+	Once       bool
+	OnTrigger  func(event AlertTriggered)
+}
+
+// AlertTriggered is published when an alert triggers, with the mid price and spread of the tick; Move is the
+// fraction moved of the MoveWithin alerts, negative when falling.
+type AlertTriggered struct {
+	Time   time.Time
+	ID     string
+	Alert  Alert
+	Price  float64
+	Spread float64
+	Move   float64
+}
+
+// Alerts is the registry of the price alerts of an account, evaluated on every tick of their instruments. It is
+// safe for concurrent use.
+type Alerts struct {
+	mutex   *sync.Mutex
+	counter int
+	alerts  map[string][]*alertState // by instrument
+}
+
+// alertState is the state of an alert between the ticks.
+type alertState struct {
+	id      string
+	alert   Alert
+	last    float64      // mid price of the last tick, zero before the first one
+	armed   bool         // of the spread alerts
+	samples []pricePoint // of the window of the move alerts, in time order
+}
+
+type pricePoint struct {
+	time  time.Time
+	price float64
+}
+
+func newAlerts() *Alerts {
+	return &Alerts{
+		mutex:  &sync.Mutex{},
+		alerts: make(map[string][]*alertState),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// check returns whether the alert triggers on a tick at the mid price and spread, and the move of MoveWithin.
+func (s *alertState) check(t time.Time, mid, spread float64) (bool, float64) {
+
+	last := s.last
+	s.last = mid
+
+	switch s.alert.Condition {
+	case CrossAbove:
+		return last != 0 && last <= s.alert.Level && mid > s.alert.Level, 0
+	case CrossBelow:
+		return last != 0 && last >= s.alert.Level && mid < s.alert.Level, 0
+	case SpreadAbove:
+		if spread <= s.alert.Level {
+			s.armed = true
+			return false, 0
+		}
+		triggered := s.armed
+		s.armed = false
+		return triggered, 0
+	case MoveWithin:
+		return s.move(t, mid)
+	}
+
+	return false, 0
+}
+
+// move adds a price to the window of a MoveWithin alert, returning whether it moved by the level from the low or
+// the high of the window. The window is cleared when it triggers.
+func (s *alertState) move(t time.Time, mid float64) (bool, float64) {
+
+	expired := 0
+	for expired < len(s.samples) && t.Sub(s.samples[expired].time) > s.alert.Window {
+		expired++
+	}
+	s.samples = append(s.samples[expired:], pricePoint{time: t, price: mid})
+
+	low, high := mid, mid
+	for _, sample := range s.samples {
+		low, high = math.Min(low, sample.price), math.Max(high, sample.price)
+	}
+
+	move := (mid - low) / low
+	if fall := (mid - high) / high; -fall > move {
+		move = fall
+	}
+
+	if math.Abs(move) < s.alert.Level {
+		return false, move
+	}
+
+	s.samples = s.samples[:0]
+
+	return true, move
+}
+
+// evaluate checks the alerts of the instrument of a tick, publishing the ones triggered.
+func (a *Alerts) evaluate(tick *Tick, events *EventBus) {
+
+	a.mutex.Lock()
+
+	states := a.alerts[tick.Instrument]
+	if len(states) == 0 {
+		a.mutex.Unlock()
+		return
+	}
+
+	mid, spread := (tick.Bid+tick.Ask)/2, tick.Ask-tick.Bid
+	triggered := make([]AlertTriggered, 0)
+	kept := states[:0]
+
+	for _, state := range states {
+
+		fired, move := state.check(tick.Time, mid, spread)
+		if fired {
+			triggered = append(triggered, AlertTriggered{Time: tick.Time, ID: state.id, Alert: state.alert,
+				Price: mid, Spread: spread, Move: move})
+		}
+
+		if !fired || !state.alert.Once {
+			kept = append(kept, state)
+		}
+	}
+
+	for i := len(kept); i < len(states); i++ {
+		states[i] = nil
+	}
+	a.alerts[tick.Instrument] = kept
+
+	a.mutex.Unlock()
+
+	for _, event := range triggered {
+
+		if event.Alert.OnTrigger != nil {
+			event.Alert.OnTrigger(event)
+		}
+
+		events.publish(event)
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Add registers an alert, evaluated from the next tick of its instrument, and returns its ID.
+func (a *Alerts) Add(alert Alert) (string, error) {
+
+	if alert.Instrument == "" {
+		return "", errors.New("alert without instrument")
+	}
+
+	switch {
+	case alert.Condition < CrossAbove || alert.Condition > MoveWithin:
+		return "", fmt.Errorf("%s: unsupported alert condition %d", alert.Instrument, alert.Condition)
+	case alert.Condition == MoveWithin && (alert.Level <= 0 || alert.Window <= 0):
+		return "", fmt.Errorf("%s: move alerts need a positive level and window", alert.Instrument)
+	case alert.Condition == SpreadAbove && alert.Level < 0:
+		return "", fmt.Errorf("%s: negative spread level", alert.Instrument)
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.counter++
+	id := "alert-" + strconv.Itoa(a.counter)
+
+	a.alerts[alert.Instrument] = append(a.alerts[alert.Instrument], &alertState{id: id, alert: alert, armed: true})
+
+	return id, nil
+}
+
+// Remove removes an alert, returning false when it is not registered.
+func (a *Alerts) Remove(id string) bool {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for instrument, states := range a.alerts {
+		for i, state := range states {
+			if state.id == id {
+				a.alerts[instrument] = append(states[:i:i], states[i+1:]...)
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// List returns the registered alerts by ID.
+func (a *Alerts) List() map[string]Alert {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	list := make(map[string]Alert)
+	for _, states := range a.alerts {
+		for _, state := range states {
+			list[state.id] = state.alert
+		}
+	}
+
+	return list
+}
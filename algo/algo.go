@@ -0,0 +1,554 @@
+/*
+Package algo works large parent orders with execution algorithms, slicing them into child orders: market orders
+over time with TWAP, evenly over a period, and VWAP, proportionally to the volume observed in it, or resting limit
+orders displaying a part of the parent with Iceberg. Other algorithms implement Algorithm and are started with
+Executor.Start. The strategy forwards its ticks and fills to an Executor, that sends the child orders requested by
+the algorithms, aggregates their fills and tracks the realized price of every parent order against the benchmark of
+its algorithm:
+
+	executor := algo.NewExecutor(engine)
+	execution, err := executor.TWAP(algo.Parent{Instrument: "EUR_USD", Side: gotrader.Long, Units: 100000},
+		tick.Time, time.Hour, 12)
+	...
+	func (s *strategy) OnTick(tick *gotrader.Tick)           { s.executor.OnTick(tick) }
+	func (s *strategy) OnOrderFill(fill *gotrader.OrderFill) { s.executor.OnOrderFill(fill) }
+*/
+package algo
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Parent is the order worked by an execution algorithm, its child orders carry its tag.
+type Parent struct {
+	Instrument string
+	Side       gotrader.Side
+	Units      int32
+	Price      float64 // limit price of the resting child orders
+	Tag        string
+}
+
+// State is the state of a parent order given to its algorithm. Remaining are the units neither filled nor in
+// flight.
+type State struct {
+	Parent    Parent
+	Filled    int32
+	InFlight  int32
+	Remaining int32
+	Resting   int // child orders in flight that are not market orders
+}
+
+// Child is a child order requested by an algorithm, of the side of the parent order. The market orders are the
+// zero Type, the other ones are good till cancelled at Price.
+type Child struct {
+	Type  gotrader.OrderType
+	Units int32
+	Price float64
+}
+
+/*
+Algorithm schedules the child orders of a parent order. Its methods are called by the Executor holding its lock, so
+they must not call it back; the child orders requested beyond the remaining units are trimmed.
+*/
+type Algorithm interface {
+	// OnTick is called with the ticks of the instrument of the parent order, returning the child orders due and
+	// whether the algorithm ended. After it ended, or the execution was cancelled, it keeps being called with
+	// stopped set to update the benchmark, and the child orders returned are ignored.
+	OnTick(tick *gotrader.Tick, state State, stopped bool) (children []Child, ended bool)
+	// OnChildFilled is called when a child order is completely filled, returning the child orders to send.
+	OnChildFilled(fill *gotrader.OrderFill, state State) []Child
+	// OnChildRejected is called when a child order is rejected or fails, returning whether the algorithm ends.
+	OnChildRejected(state State) bool
+	// Benchmark returns the benchmark price of the parent order, 0 when it is not known yet.
+	Benchmark() float64
+}
+
+// ChildState is the state of a child order.
+type ChildState int
+
+const (
+	ChildInFlight ChildState = iota
+	ChildFilled
+	ChildRejected
+	ChildCancelled
+)
+
+func (s ChildState) String() string {
+
+	names := [...]string{
+		"IN_FLIGHT",
+		"FILLED",
+		"REJECTED",
+		"CANCELLED",
+	}
+
+	return names[s]
+}
+
+// ChildReport is the state of a child order, OrderID is empty until the broker accepted it.
+type ChildReport struct {
+	ClientID     string
+	OrderID      string
+	Type         gotrader.OrderType
+	Units        int32
+	Filled       int32
+	AveragePrice float64 // of the fills, 0 without fills
+	State        ChildState
+	Error        string // of the rejection
+}
+
+// Report is the state of an execution, the aggregate of its child orders in send order. Slippage is the cost of the
+// realized price against the benchmark, in price units: positive when the parent order was filled at a worse price
+// than the benchmark.
+type Report struct {
+	ID           string
+	Parent       Parent
+	Filled       int32
+	InFlight     int32 // units of the child orders sent and not filled yet
+	Children     []ChildReport
+	Rejected     int
+	AveragePrice float64 // of the fills, 0 without fills
+	Benchmark    float64 // 0 when not known yet
+	Slippage     float64
+	Done         bool
+}
+
+// ReportHandler receives the reports of the executions.
+type ReportHandler func(report Report)
+
+/*
+Execution is a parent order worked by an algorithm. It is done when filled, or when its algorithm ended or it was
+cancelled without child orders in flight: the units left stay unfilled, see Report.
+*/
+type Execution struct {
+	id        string
+	parent    Parent
+	algorithm Algorithm
+	filled    int32
+	inFlight  int32
+	notional  float64 // of the fills
+	rejected  int
+	ended     bool
+	cancelled bool
+	children  []*child          // in send order
+	pending   map[string]*child // in flight, by client order ID
+}
+
+// child is a child order of an execution.
+type child struct {
+	report   ChildReport
+	notional float64 // of the fills
+}
+
+// ID returns the ID of the execution, the prefix of the client IDs of its child orders.
+func (x *Execution) ID() string {
+	return x.id
+}
+
+func (x *Execution) state() State {
+
+	s := State{
+		Parent:    x.parent,
+		Filled:    x.filled,
+		InFlight:  x.inFlight,
+		Remaining: x.parent.Units - x.filled - x.inFlight,
+	}
+
+	for _, c := range x.pending {
+		if c.report.Type != gotrader.MarketOrder {
+			s.Resting++
+		}
+	}
+
+	return s
+}
+
+func (x *Execution) stopped() bool {
+	return x.ended || x.cancelled
+}
+
+func (x *Execution) done() bool {
+	return x.filled >= x.parent.Units || (x.stopped() && x.inFlight == 0)
+}
+
+func (x *Execution) report() Report {
+
+	r := Report{
+		ID:        x.id,
+		Parent:    x.parent,
+		Filled:    x.filled,
+		InFlight:  x.inFlight,
+		Children:  make([]ChildReport, len(x.children)),
+		Rejected:  x.rejected,
+		Benchmark: x.algorithm.Benchmark(),
+		Done:      x.done(),
+	}
+
+	for i, c := range x.children {
+		r.Children[i] = c.report
+	}
+
+	if x.filled > 0 {
+		r.AveragePrice = x.notional / float64(x.filled)
+	}
+
+	if r.AveragePrice != 0 && r.Benchmark != 0 {
+		r.Slippage = r.AveragePrice - r.Benchmark
+		if x.parent.Side == gotrader.Short {
+			r.Slippage = -r.Slippage
+		}
+	}
+
+	return r
+}
+
+/*
+Executor runs the executions of a strategy on its engine. OnTick and OnOrderFill must be called with every tick and
+fill of the strategy; the fills of other orders are ignored. The child orders are matched to their fills by their
+client order IDs, so the brokers must report them (see gotrader.Order.ClientID).
+*/
+type Executor struct {
+	mutex      *sync.Mutex
+	engine     gotrader.Engine
+	counter    int
+	executions []*Execution
+	children   map[string]*Execution // by client order ID
+	handler    ReportHandler
+}
+
+// NewExecutor is the Executor constructor.
+func NewExecutor(engine gotrader.Engine) *Executor {
+	return &Executor{
+		mutex:    &sync.Mutex{},
+		engine:   engine,
+		children: make(map[string]*Execution),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// due returns the child orders due at the tick, registered as in flight. They are sent without holding the lock,
+// since the backtests fill them synchronously.
+func (e *Executor) due(tick *gotrader.Tick) []*gotrader.Order {
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	orders := make([]*gotrader.Order, 0)
+
+	for _, x := range e.executions {
+
+		if x.parent.Instrument != tick.Instrument {
+			continue
+		}
+
+		stopped := x.stopped() // the benchmark is of the whole period
+		children, ended := x.algorithm.OnTick(tick, x.state(), stopped)
+
+		if !stopped {
+			x.ended = ended
+			orders = append(orders, e.orders(x, children)...)
+		}
+	}
+
+	return orders
+}
+
+// orders returns the orders of the child orders of the execution, registered as in flight.
+func (e *Executor) orders(x *Execution, children []Child) []*gotrader.Order {
+
+	orders := make([]*gotrader.Order, 0, len(children))
+
+	for _, c := range children {
+
+		units := min(c.Units, x.parent.Units-x.filled-x.inFlight)
+		if units <= 0 {
+			continue
+		}
+
+		clientID := x.id + "-" + strconv.Itoa(len(x.children)+1)
+		ch := &child{report: ChildReport{ClientID: clientID, Type: c.Type, Units: units}}
+
+		x.children = append(x.children, ch)
+		x.pending[clientID] = ch
+		x.inFlight += units
+		e.children[clientID] = x
+
+		order := &gotrader.Order{
+			Type:       c.Type,
+			ClientID:   clientID,
+			Instrument: x.parent.Instrument,
+			Side:       x.parent.Side,
+			Units:      units,
+			Tag:        x.parent.Tag,
+		}
+
+		if c.Type != gotrader.MarketOrder {
+			order.Price = c.Price
+			order.TimeInForce = gotrader.GoodTillCancelled
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders
+}
+
+// send submits the child orders.
+func (e *Executor) send(orders []*gotrader.Order) {
+
+	for _, order := range orders {
+
+		id, err := e.engine.SubmitOrder(order)
+		if err != nil {
+			e.notify(e.fail(order.ClientID, ChildRejected, err.Error()))
+			continue
+		}
+
+		e.mutex.Lock()
+		if x, exist := e.children[order.ClientID]; exist {
+			x.pending[order.ClientID].report.OrderID = id
+		}
+		e.mutex.Unlock()
+	}
+}
+
+// fill records a fill of a child order, returning the report of its execution and the child orders its algorithm
+// requested when the child order is filled.
+func (e *Executor) fill(f *gotrader.OrderFill) (*Report, []*gotrader.Order) {
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	x, exist := e.children[f.ClientOrderID]
+	if !exist {
+		return nil, nil
+	}
+
+	c := x.pending[f.ClientOrderID]
+
+	units := f.Units
+	if left := c.report.Units - c.report.Filled; units <= 0 || units > left {
+		units = left
+	}
+
+	c.report.Filled += units
+	c.notional += float64(units) * f.Price
+	c.report.AveragePrice = c.notional / float64(c.report.Filled)
+	x.inFlight -= units
+	x.filled += units
+	x.notional += float64(units) * f.Price
+
+	var orders []*gotrader.Order
+
+	if c.report.Filled >= c.report.Units {
+
+		c.report.State = ChildFilled
+		delete(e.children, f.ClientOrderID)
+		delete(x.pending, f.ClientOrderID)
+
+		if !x.stopped() {
+			orders = e.orders(x, x.algorithm.OnChildFilled(f, x.state()))
+		}
+	}
+
+	r := x.report()
+
+	return &r, orders
+}
+
+// fail forgets a child order that was rejected or cancelled, returning the report of its execution.
+func (e *Executor) fail(clientID string, state ChildState, reason string) *Report {
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	x, exist := e.children[clientID]
+	if !exist {
+		return nil
+	}
+
+	c := x.pending[clientID]
+	c.report.State = state
+	c.report.Error = reason
+	x.inFlight -= c.report.Units - c.report.Filled
+
+	delete(e.children, clientID)
+	delete(x.pending, clientID)
+
+	if state == ChildRejected {
+		x.rejected++
+		if !x.stopped() && x.algorithm.OnChildRejected(x.state()) {
+			x.ended = true
+		}
+	}
+
+	r := x.report()
+
+	return &r
+}
+
+// notify calls the report handler without holding the lock.
+func (e *Executor) notify(report *Report) {
+
+	e.mutex.Lock()
+	handler := e.handler
+	e.mutex.Unlock()
+
+	if report != nil && handler != nil {
+		handler(*report)
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Start works the parent order with the algorithm, it is called from the next tick of the instrument.
+func (e *Executor) Start(parent Parent, algorithm Algorithm) (*Execution, error) {
+
+	if parent.Units <= 0 {
+		return nil, errors.New("parent order without units")
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.counter++
+
+	x := &Execution{
+		id:        "algo-" + strconv.Itoa(e.counter),
+		parent:    parent,
+		algorithm: algorithm,
+		pending:   make(map[string]*child),
+	}
+
+	e.executions = append(e.executions, x)
+
+	return x, nil
+}
+
+// OnReport sets the handler of the reports of the executions, called after every fill, rejection and cancellation
+// of their child orders.
+func (e *Executor) OnReport(handler ReportHandler) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.handler = handler
+}
+
+// OnTick sends the child orders due at the tick.
+func (e *Executor) OnTick(tick *gotrader.Tick) {
+
+	e.send(e.due(tick))
+}
+
+// OnOrderFill records the fills of the child orders, partial fills included, and sends the child orders their
+// algorithms request.
+func (e *Executor) OnOrderFill(fill *gotrader.OrderFill) {
+
+	if fill.ClientOrderID == "" || fill.TradeClose {
+		return
+	}
+
+	if fill.Error != "" {
+		e.notify(e.fail(fill.ClientOrderID, ChildRejected, fill.Error))
+		return
+	}
+
+	report, orders := e.fill(fill)
+	e.notify(report)
+	e.send(orders)
+}
+
+// Cancel stops the algorithm of the execution and cancels its child orders resting at the broker, the market ones
+// in flight may still fill. It returns the errors of the cancellations, the child orders not cancelled stay in
+// flight.
+func (e *Executor) Cancel(x *Execution) error {
+
+	e.mutex.Lock()
+
+	x.cancelled = true
+
+	resting := make([]*ChildReport, 0)
+	for _, c := range x.children {
+		if c.report.State == ChildInFlight && c.report.Type != gotrader.MarketOrder && c.report.OrderID != "" {
+			r := c.report
+			resting = append(resting, &r)
+		}
+	}
+
+	e.mutex.Unlock()
+
+	var errs []error
+
+	for _, c := range resting {
+		if err := e.engine.CancelOrder(c.OrderID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		e.notify(e.fail(c.ClientID, ChildCancelled, ""))
+	}
+
+	return errors.Join(errs...)
+}
+
+// CancelAll cancels the executions not done.
+func (e *Executor) CancelAll() error {
+
+	var errs []error
+
+	for _, x := range e.Active() {
+		if err := e.Cancel(x); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Report returns the state of the execution.
+func (e *Executor) Report(x *Execution) Report {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return x.report()
+}
+
+// Reports returns the state of every execution, in start order.
+func (e *Executor) Reports() []Report {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	reports := make([]Report, len(e.executions))
+	for i, x := range e.executions {
+		reports[i] = x.report()
+	}
+
+	return reports
+}
+
+// Active returns the executions not done, in start order.
+func (e *Executor) Active() []*Execution {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	active := make([]*Execution, 0)
+	for _, x := range e.executions {
+		if !x.done() {
+			active = append(active, x)
+		}
+	}
+
+	return active
+}
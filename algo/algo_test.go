@@ -0,0 +1,162 @@
+package algo
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/gotradertest"
+)
+
+// worker starts an execution on its first tick.
+type worker struct {
+	engine    gotrader.Engine
+	executor  *Executor
+	start     func(executor *Executor, now time.Time) *Execution
+	execution *Execution
+}
+
+func (s *worker) Initialize()                          {}
+func (s *worker) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *worker) OnOrderFill(fill *gotrader.OrderFill) { s.executor.OnOrderFill(fill) }
+func (s *worker) OnStop()                              {}
+
+func (s *worker) OnTick(tick *gotrader.Tick) {
+
+	if s.executor == nil {
+		s.executor = NewExecutor(s.engine)
+		s.execution = s.start(s.executor, tick.Time)
+	}
+
+	s.executor.OnTick(tick)
+}
+
+func TestExecutor(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	run := func(t *testing.T, s *worker, prices ...float64) *gotradertest.Harness {
+
+		broker := gotradertest.NewBroker(instruments, gotradertest.Balance(100000), gotradertest.Leverage(30))
+		broker.Quote("EUR_USD", 1.1, 1.1002)
+
+		h := gotradertest.New(t, s, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+		for _, price := range prices {
+			h.Tick("EUR_USD", price, price+0.0002)
+			h.Settle()
+			h.Advance(time.Minute)
+		}
+
+		return h
+	}
+
+	t.Run("twap slices evenly over the period", func(t *testing.T) {
+
+		s := &worker{start: func(e *Executor, now time.Time) *Execution {
+			x, _ := e.TWAP(Parent{Instrument: "EUR_USD", Side: gotrader.Long, Units: 3000}, now, 3*time.Minute, 3)
+			return x
+		}}
+
+		h := run(t, s, 1.1000, 1.1010, 1.1020, 1.1030)
+		h.AssertUnits("EUR_USD", gotrader.Long, 3000)
+
+		r := s.executor.Report(s.execution)
+		if !r.Done || r.Filled != 3000 || len(r.Children) != 3 {
+			t.Fatalf("unexpected report %+v", r)
+		}
+
+		// filled at the asks of the first three minutes, the mids of the period are 1.1001, 1.1011 and 1.1021
+		if math.Abs(r.AveragePrice-1.1012) > 1e-9 || math.Abs(r.Benchmark-1.1011) > 1e-9 || math.Abs(r.Slippage-0.0001) > 1e-9 {
+			t.Errorf("unexpected prices %+v", r)
+		}
+	})
+
+	t.Run("vwap follows the observed volume", func(t *testing.T) {
+
+		s := &worker{start: func(e *Executor, now time.Time) *Execution {
+			x, _ := e.VWAP(Parent{Instrument: "EUR_USD", Side: gotrader.Short, Units: 4000}, now, 2*time.Minute, 2)
+			return x
+		}}
+
+		h := run(t, s, 1.1000, 1.1010, 1.1020)
+		h.AssertUnits("EUR_USD", gotrader.Short, 4000)
+
+		// a tick in each slice: half of the units at the end of the first slice, the rest at the end of the period
+		r := s.executor.Report(s.execution)
+		if !r.Done || r.Filled != 4000 || len(r.Children) != 2 || math.Abs(r.AveragePrice-1.1015) > 1e-9 {
+			t.Fatalf("unexpected report %+v", r)
+		}
+	})
+	t.Run("iceberg replenishes the filled child orders", func(t *testing.T) {
+
+		s := &worker{start: func(e *Executor, now time.Time) *Execution {
+			x, _ := e.Iceberg(Parent{Instrument: "EUR_USD", Side: gotrader.Long, Units: 2500, Price: 1.0990}, 1000)
+			return x
+		}}
+
+		h := run(t, s, 1.1000, 1.0995, 1.0985, 1.0980)
+
+		r := s.executor.Report(s.execution)
+		if r.Done || r.Filled != 2000 || r.InFlight != 500 || len(r.Children) != 3 {
+			t.Fatalf("expected two child orders filled and one resting, got %+v", r)
+		}
+
+		if err := s.executor.Cancel(s.execution); err != nil {
+			t.Fatal(err)
+		}
+
+		h.AssertUnits("EUR_USD", gotrader.Long, 2000)
+
+		if r := s.executor.Report(s.execution); !r.Done || r.InFlight != 0 || r.Benchmark != 1.1001 {
+			t.Errorf("expected the resting order to be cancelled, got %+v", r)
+		}
+	})
+
+	t.Run("custom algorithms aggregate the fills of their children", func(t *testing.T) {
+
+		reports := make([]Report, 0)
+
+		s := &worker{start: func(e *Executor, now time.Time) *Execution {
+			e.OnReport(func(r Report) { reports = append(reports, r) })
+			x, _ := e.Start(Parent{Instrument: "EUR_USD", Side: gotrader.Long, Units: 1500}, &split{})
+			return x
+		}}
+
+		run(t, s, 1.1000, 1.1010)
+
+		r := s.executor.Report(s.execution)
+		if !r.Done || r.Filled != 1500 || len(r.Children) != 2 || math.Abs(r.AveragePrice-1.1002) > 1e-9 {
+			t.Fatalf("expected two child orders filled, got %+v", r)
+		}
+
+		if c := r.Children[1]; c.State != ChildFilled || c.Units != 500 || c.OrderID == "" {
+			t.Errorf("expected the second child order trimmed to the remaining units, got %+v", c)
+		}
+
+		if len(reports) != 2 || !reports[1].Done {
+			t.Errorf("expected a report per fill, got %+v", reports)
+		}
+	})
+}
+
+// split sends two child orders of a thousand units on the first tick.
+type split struct{ sent bool }
+
+func (a *split) OnTick(_ *gotrader.Tick, _ State, stopped bool) ([]Child, bool) {
+
+	if a.sent || stopped {
+		return nil, false
+	}
+
+	a.sent = true
+
+	return []Child{{Units: 1000}, {Units: 1000}}, true
+}
+
+func (a *split) OnChildFilled(*gotrader.OrderFill, State) []Child { return nil }
+func (a *split) OnChildRejected(State) bool                       { return false }
+func (a *split) Benchmark() float64                               { return 0 }
@@ -0,0 +1,340 @@
+package algo
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+func mid(tick *gotrader.Tick) float64 {
+	return (tick.Bid + tick.Ask) / 2
+}
+
+func volume(tick *gotrader.Tick) float64 {
+
+	if v := tick.BidSize + tick.AskSize; v > 0 {
+		return v
+	}
+
+	return 1
+}
+
+// market returns a child market order of the units, none without units.
+func market(units int32) []Child {
+
+	if units <= 0 {
+		return nil
+	}
+
+	return []Child{{Type: gotrader.MarketOrder, Units: units}}
+}
+
+/*
+TWAP sends a child market order at the start of each of the slices of the period from start, with the units left
+spread evenly over the slices left. A child order that is rejected is not sent again, its units are spread over the
+next slices. The benchmark is the time weighted mid price of the period.
+*/
+type TWAP struct {
+	start, end time.Time
+	interval   time.Duration
+	slices     int
+	sent       int // slices sent
+	last       *gotrader.Tick
+	weighted   float64 // mid prices by their duration
+	duration   time.Duration
+}
+
+// NewTWAP is the TWAP constructor.
+func NewTWAP(start time.Time, period time.Duration, slices int) (*TWAP, error) {
+
+	if slices <= 0 || period <= 0 {
+		return nil, errors.New("twap without slices")
+	}
+
+	return &TWAP{start: start, end: start.Add(period), interval: period / time.Duration(slices), slices: slices}, nil
+}
+
+func (t *TWAP) observe(tick *gotrader.Tick) {
+
+	if tick.Time.Before(t.start) {
+		return
+	}
+
+	if t.last != nil {
+		to := tick.Time
+		if to.After(t.end) {
+			to = t.end
+		}
+		if d := to.Sub(t.last.Time); d > 0 {
+			t.weighted += mid(t.last) * d.Seconds()
+			t.duration += d
+		}
+	}
+
+	if tick.Time.Before(t.end) {
+		last := *tick
+		t.last = &last
+	} else {
+		t.last = nil
+	}
+}
+
+func (t *TWAP) slice(now time.Time, remaining int32) (int32, bool) {
+
+	if now.Before(t.start) {
+		return 0, false
+	}
+
+	due := min(int(now.Sub(t.start)/t.interval)+1, t.slices)
+	if !now.Before(t.end) {
+		due = t.slices
+	}
+
+	if due <= t.sent {
+		return 0, false
+	}
+
+	sent, left := due-t.sent, t.slices-t.sent // the slices skipped without ticks are sent at once
+	t.sent = due
+
+	if t.sent == t.slices {
+		return remaining, true
+	}
+
+	return int32(math.Round(float64(remaining) * float64(sent) / float64(left))), false
+}
+
+func (t *TWAP) OnTick(tick *gotrader.Tick, state State, stopped bool) ([]Child, bool) {
+
+	var units int32
+	var ended bool
+
+	if !stopped {
+		units, ended = t.slice(tick.Time, state.Remaining)
+	}
+
+	t.observe(tick) // in the slice starting at the tick
+
+	return market(units), ended
+}
+
+func (t *TWAP) OnChildFilled(*gotrader.OrderFill, State) []Child {
+	return nil
+}
+
+func (t *TWAP) OnChildRejected(State) bool {
+	return false
+}
+
+func (t *TWAP) Benchmark() float64 {
+
+	if t.duration > 0 {
+		return t.weighted / t.duration.Seconds()
+	}
+
+	if t.last != nil {
+		return mid(t.last)
+	}
+
+	return 0
+}
+
+/*
+VWAP sends a child market order at the end of each of the slices of the period from start, proportional to the
+volume observed in the slice: its share of the volume expected for the slices left, at the mean volume of the slices
+observed. The volume of a tick is its bid and ask sizes, or one when the feed does not report them. A child order
+that is rejected is not sent again. The benchmark is the volume weighted mid price of the period.
+*/
+type VWAP struct {
+	start, end time.Time
+	interval   time.Duration
+	slices     int
+	sent       int     // slices ended
+	volume     float64 // observed in the current slice
+	observed   float64 // in the ended slices
+	weighted   float64 // mid prices by their volume
+	total      float64
+}
+
+// NewVWAP is the VWAP constructor.
+func NewVWAP(start time.Time, period time.Duration, slices int) (*VWAP, error) {
+
+	if slices <= 0 || period <= 0 {
+		return nil, errors.New("vwap without slices")
+	}
+
+	return &VWAP{start: start, end: start.Add(period), interval: period / time.Duration(slices), slices: slices}, nil
+}
+
+func (v *VWAP) observe(tick *gotrader.Tick) {
+
+	if tick.Time.Before(v.start) || !tick.Time.Before(v.end) {
+		return
+	}
+
+	vol := volume(tick)
+	v.volume += vol
+	v.weighted += mid(tick) * vol
+	v.total += vol
+}
+
+func (v *VWAP) slice(now time.Time, remaining int32) (int32, bool) {
+
+	ended := min(int(now.Sub(v.start)/v.interval), v.slices)
+	if !now.Before(v.end) {
+		ended = v.slices
+	}
+
+	if now.Before(v.start) || ended <= v.sent {
+		return 0, false
+	}
+
+	v.sent = ended
+
+	if v.sent == v.slices {
+		return remaining, true
+	}
+
+	v.observed += v.volume
+	expected := v.observed / float64(v.sent) * float64(v.slices-v.sent)
+	units := int32(0)
+
+	if v.volume > 0 {
+		units = int32(math.Round(float64(remaining) * v.volume / (v.volume + expected)))
+	}
+
+	v.volume = 0
+
+	return units, false
+}
+
+func (v *VWAP) OnTick(tick *gotrader.Tick, state State, stopped bool) ([]Child, bool) {
+
+	var units int32
+	var ended bool
+
+	if !stopped {
+		units, ended = v.slice(tick.Time, state.Remaining)
+	}
+
+	v.observe(tick)
+
+	return market(units), ended
+}
+
+func (v *VWAP) OnChildFilled(*gotrader.OrderFill, State) []Child {
+	return nil
+}
+
+func (v *VWAP) OnChildRejected(State) bool {
+	return false
+}
+
+func (v *VWAP) Benchmark() float64 {
+
+	if v.total == 0 {
+		return 0
+	}
+
+	return v.weighted / v.total
+}
+
+/*
+Iceberg rests a limit order at the price of the parent order displaying at most the display units, replenished with
+the units left as soon as it is filled; the first one is sent on the first tick. A rejected child order ends it. The
+benchmark is the arrival price, the mid price of the first tick.
+*/
+type Iceberg struct {
+	display int32
+	arrival float64
+}
+
+// NewIceberg is the Iceberg constructor.
+func NewIceberg(display int32) (*Iceberg, error) {
+
+	if display <= 0 {
+		return nil, errors.New("iceberg without display units")
+	}
+
+	return &Iceberg{display: display}, nil
+}
+
+func (i *Iceberg) next(state State) []Child {
+
+	if state.InFlight > 0 || state.Remaining <= 0 {
+		return nil
+	}
+
+	return []Child{{Type: gotrader.LimitOrder, Units: min(i.display, state.Remaining), Price: state.Parent.Price}}
+}
+
+func (i *Iceberg) OnTick(tick *gotrader.Tick, state State, stopped bool) ([]Child, bool) {
+
+	if i.arrival == 0 {
+		i.arrival = mid(tick)
+	}
+
+	if stopped {
+		return nil, false
+	}
+
+	return i.next(state), false
+}
+
+func (i *Iceberg) OnChildFilled(_ *gotrader.OrderFill, state State) []Child {
+	return i.next(state)
+}
+
+func (i *Iceberg) OnChildRejected(State) bool {
+	return true
+}
+
+func (i *Iceberg) Benchmark() float64 {
+	return i.arrival
+}
+
+/**************************
+*
+*	Executor Algorithms
+*
+***************************/
+
+// TWAP works the parent order with a TWAP over the period from start, see NewTWAP.
+func (e *Executor) TWAP(parent Parent, start time.Time, period time.Duration, slices int) (*Execution, error) {
+
+	t, err := NewTWAP(start, period, slices)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Start(parent, t)
+}
+
+// VWAP works the parent order with a VWAP over the period from start, see NewVWAP.
+func (e *Executor) VWAP(parent Parent, start time.Time, period time.Duration, slices int) (*Execution, error) {
+
+	v, err := NewVWAP(start, period, slices)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Start(parent, v)
+}
+
+// Iceberg works the parent order with an Iceberg displaying at most the display units at the parent price, Cancel
+// cancels the child order resting.
+func (e *Executor) Iceberg(parent Parent, display int32) (*Execution, error) {
+
+	if parent.Price <= 0 {
+		return nil, errors.New("resting child orders without price")
+	}
+
+	i, err := NewIceberg(display)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Start(parent, i)
+}
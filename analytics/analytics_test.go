@@ -0,0 +1,119 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+func TestPerformance(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	t.Run("Equity ratios use the returns of the points", func(t *testing.T) {
+
+		points := make([]gotrader.EquityPoint, 0)
+		for i, equity := range []float64{100, 110, 99, 108.9, 119.79} {
+			points = append(points, gotrader.EquityPoint{Time: start.Add(time.Duration(i) * day), Equity: equity})
+		}
+
+		p := FromEquityPoints(points, Inception)
+
+		if p.Periods != 4 || math.Abs(p.Return-0.1979) > 1e-12 || math.Abs(p.MaxDrawdown-0.1) > 1e-12 {
+			t.Errorf("unexpected performance %+v", p)
+		}
+
+		// returns of 10%, -10%, 10%, 10%: mean 5%, deviation 10%, downside deviation 5%, 365 periods a year
+		if math.Abs(p.Sharpe-0.5*math.Sqrt(365)) > 1e-9 || math.Abs(p.Sortino-math.Sqrt(365)) > 1e-9 {
+			t.Errorf("unexpected ratios %v %v", p.Sharpe, p.Sortino)
+		}
+
+		if math.Abs(p.Calmar/(p.AnnualizedReturn/0.1)-1) > 1e-9 {
+			t.Errorf("unexpected calmar %v", p.Calmar)
+		}
+
+		window := FromEquityPoints(points, 2*day)
+		if window.Periods != 2 || math.Abs(window.Return-0.21) > 1e-12 {
+			t.Errorf("unexpected window performance %+v", window)
+		}
+	})
+
+	t.Run("Ledger returns exclude the funds transfers", func(t *testing.T) {
+
+		transactions := []*gotrader.Transaction{
+			{Type: gotrader.TradeCloseTransaction, Time: start.Add(time.Hour), Amount: 100, Balance: 1100},
+			{Type: gotrader.FundsTransferTransaction, Time: start.Add(day), Amount: 900, Balance: 2000},
+			{Type: gotrader.TradeCloseTransaction, Time: start.Add(day + time.Hour), Amount: -200, Balance: 1800},
+		}
+
+		p := FromTransactions(transactions, 1000, Inception)
+
+		if p.Periods != 2 || math.Abs(p.Return-(1.1*0.9-1)) > 1e-12 || !p.From.Equal(start) {
+			t.Errorf("unexpected performance %+v", p)
+		}
+	})
+}
+
+func TestTrades(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	transactions := make([]*gotrader.Transaction, 0)
+
+	for i, amount := range []float64{10, 20, -5, -5, -10, 30} {
+		instrument := "EUR_USD"
+		if i%2 == 1 {
+			instrument = "USD_JPY"
+		}
+
+		transactions = append(transactions, &gotrader.Transaction{
+			Type:       gotrader.TradeCloseTransaction,
+			Instrument: instrument,
+			Tag:        "trend",
+			OpenTime:   start,
+			Time:       start.Add(time.Duration(i+1) * time.Hour),
+			Amount:     amount,
+		})
+	}
+
+	transactions = append(transactions, &gotrader.Transaction{Type: gotrader.FinancingTransaction, Amount: -1})
+
+	s := Trades(transactions)
+
+	if s.Trades != 6 || s.WinRate != 0.5 || s.AverageWin != 20 || s.AverageLoss != -20.0/3 || s.ProfitFactor != 3 ||
+		s.Expectancy != 40.0/6 || s.AverageHoldingTime != 210*time.Minute || s.LongestWinStreak != 2 || s.LongestLossStreak != 3 {
+		t.Errorf("unexpected statistics %+v", s)
+	}
+
+	byInstrument := TradesByInstrument(transactions)
+	if byInstrument["EUR_USD"].NetProfit != -5 || byInstrument["USD_JPY"].NetProfit != 45 || TradesByTag(transactions)["trend"] != s {
+		t.Errorf("unexpected groups %+v", byInstrument)
+	}
+}
+
+func TestDrawdowns(t *testing.T) {
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]Point, 0)
+	for i, v := range []float64{100, 90, 80, 95, 100, 110, 99, 105} {
+		points = append(points, Point{Time: start.Add(time.Duration(i) * time.Hour), Value: v})
+	}
+
+	episodes := Drawdowns(points)
+
+	if len(episodes) != 2 || episodes[0].Depth != 0.2 || episodes[0].Duration() != 4*time.Hour ||
+		episodes[0].Decline() != 2*time.Hour || episodes[0].Recovery() != 2*time.Hour || !episodes[0].Recovered {
+		t.Errorf("unexpected episodes %+v", episodes)
+	}
+
+	if episodes[1].Recovered || episodes[1].Depth != 0.1 || episodes[1].Duration() != 2*time.Hour {
+		t.Errorf("unexpected ongoing episode %+v", episodes[1])
+	}
+
+	stats := SummarizeDrawdowns(points)
+	if stats.Episodes != 2 || stats.MaxDepth != 0.2 || stats.MaxRecovery != 2*time.Hour || math.Abs(stats.Current-5.0/110) > 1e-12 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"sort"
+
+	"github.com/luismcruz/gotrader"
+)
+
+/*
+Attribution is the profit and exposure of the trades with a tag, e.g. of a strategy of a runner, which tags
+the orders with the strategy names. The amounts are in the home currency, the exposures are the notional of the
+open trades (margin used times leverage).
+*/
+type Attribution struct {
+	Tag              string
+	RealizedProfit   float64 // trade closes and financing of the tagged trades
+	UnrealizedProfit float64 // net of the open trades
+	Fees             float64 // charged to the open and closed trades
+	SpreadCost       float64 // paid by the opens and closes of the trades, in their prices
+	ClosedTrades     int
+	OpenTrades       int
+	LongExposure     float64
+	ShortExposure    float64
+}
+
+// NetProfit returns the realized plus the unrealized profit.
+func (a Attribution) NetProfit() float64 {
+	return a.RealizedProfit + a.UnrealizedProfit
+}
+
+// NetExposure returns the long minus the short exposure.
+func (a Attribution) NetExposure() float64 {
+	return a.LongExposure - a.ShortExposure
+}
+
+// Attribute returns the attribution of every tag of the account trades, open or closed, by tag order. The
+// untagged trades are under the empty tag. It reads the current state, so it can be called at any moment.
+func Attribute(account *gotrader.Account) []Attribution {
+
+	tags := make(map[string]*Attribution)
+
+	get := func(tag string) *Attribution {
+		a, exist := tags[tag]
+		if !exist {
+			a = &Attribution{Tag: tag}
+			tags[tag] = a
+		}
+		return a
+	}
+
+	for _, t := range account.Ledger().Transactions() {
+
+		if t.Type.External() {
+			continue
+		}
+
+		a := get(t.Tag)
+		a.RealizedProfit += t.Amount
+
+		if t.Type == gotrader.TradeCloseTransaction {
+			a.ClosedTrades++
+			a.Fees += t.Fees
+			a.SpreadCost += t.SpreadCost
+		}
+	}
+
+	for _, inst := range account.Instruments() {
+
+		leverage := inst.Leverage()
+
+		inst.RangeTrades(func(trade *gotrader.Trade) bool {
+
+			a := get(trade.Tag())
+			a.OpenTrades++
+			a.UnrealizedProfit += trade.UnrealizedNetProfit()
+			a.Fees += trade.ChargedFees()
+			a.SpreadCost += trade.SpreadCost()
+
+			if trade.Side() == gotrader.Short {
+				a.ShortExposure += trade.MarginUsed() * leverage
+			} else {
+				a.LongExposure += trade.MarginUsed() * leverage
+			}
+
+			return true
+		})
+	}
+
+	attributions := make([]Attribution, 0, len(tags))
+	for _, a := range tags {
+		attributions = append(attributions, *a)
+	}
+
+	sort.Slice(attributions, func(i, j int) bool { return attributions[i].Tag < attributions[j].Tag })
+
+	return attributions
+}
@@ -0,0 +1,166 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Point is a sample of a curve, e.g. of the equity or the balance.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+/*
+Drawdown is an episode of a curve under its previous peak, from the peak until the curve recovers it. Depth is
+the fall from the peak to the trough as a fraction of the peak. The last episode may not be recovered yet, its
+End is the last point of the curve.
+*/
+type Drawdown struct {
+	Start     time.Time // time of the peak
+	Trough    time.Time
+	End       time.Time // time of the recovery
+	Peak      float64
+	Low       float64
+	Depth     float64
+	Recovered bool
+}
+
+// Duration returns the time from the peak until the recovery, or the end of the curve.
+func (d Drawdown) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// Decline returns the time from the peak until the trough.
+func (d Drawdown) Decline() time.Duration {
+	return d.Trough.Sub(d.Start)
+}
+
+// Recovery returns the time from the trough until the recovery, or the end of the curve.
+func (d Drawdown) Recovery() time.Duration {
+	return d.End.Sub(d.Trough)
+}
+
+// DrawdownStats summarize the drawdown episodes of a curve, the maximums are of any episode.
+type DrawdownStats struct {
+	Episodes    int
+	MaxDepth    float64
+	MaxDuration time.Duration
+	MaxRecovery time.Duration // of the recovered episodes
+	Current     float64       // depth of the last point, 0 at a peak
+}
+
+// Drawdowns returns the drawdown episodes of the time ordered points.
+func Drawdowns(points []Point) []Drawdown {
+
+	episodes := make([]Drawdown, 0)
+
+	if len(points) == 0 {
+		return episodes
+	}
+
+	var current *Drawdown
+	peak := points[0]
+
+	for _, p := range points[1:] {
+
+		if p.Value >= peak.Value {
+			if current != nil {
+				current.End = p.Time
+				current.Recovered = true
+				episodes = append(episodes, *current)
+				current = nil
+			}
+			peak = p
+			continue
+		}
+
+		if current == nil {
+			current = &Drawdown{Start: peak.Time, Peak: peak.Value, Low: p.Value, Trough: p.Time}
+		}
+
+		if p.Value < current.Low {
+			current.Low = p.Value
+			current.Trough = p.Time
+		}
+
+		if current.Peak != 0 {
+			current.Depth = (current.Peak - current.Low) / current.Peak
+		}
+	}
+
+	if current != nil {
+		current.End = points[len(points)-1].Time
+		episodes = append(episodes, *current)
+	}
+
+	return episodes
+}
+
+// SummarizeDrawdowns returns the statistics of the drawdown episodes of the points.
+func SummarizeDrawdowns(points []Point) DrawdownStats {
+
+	episodes := Drawdowns(points)
+	stats := DrawdownStats{Episodes: len(episodes)}
+
+	for _, d := range episodes {
+
+		if d.Depth > stats.MaxDepth {
+			stats.MaxDepth = d.Depth
+		}
+
+		if d.Duration() > stats.MaxDuration {
+			stats.MaxDuration = d.Duration()
+		}
+
+		if d.Recovered && d.Recovery() > stats.MaxRecovery {
+			stats.MaxRecovery = d.Recovery()
+		}
+	}
+
+	if n := len(episodes); n > 0 && !episodes[n-1].Recovered && episodes[n-1].Peak != 0 {
+		stats.Current = (episodes[n-1].Peak - points[len(points)-1].Value) / episodes[n-1].Peak
+	}
+
+	return stats
+}
+
+// EquityPoints returns the equity of the equity curve points.
+func EquityPoints(points []gotrader.EquityPoint) []Point {
+
+	curve := make([]Point, len(points))
+	for i, p := range points {
+		curve[i] = Point{Time: p.Time, Value: p.Equity}
+	}
+
+	return curve
+}
+
+// BalancePoints returns the balance of the equity curve points.
+func BalancePoints(points []gotrader.EquityPoint) []Point {
+
+	curve := make([]Point, len(points))
+	for i, p := range points {
+		curve[i] = Point{Time: p.Time, Value: p.Balance}
+	}
+
+	return curve
+}
+
+// LedgerPoints returns the balance after every transaction, starting with the opening balance at the time of
+// the first one. Funds transfers move the balance, so they start or recover drawdowns.
+func LedgerPoints(transactions []*gotrader.Transaction, openingBalance float64) []Point {
+
+	curve := make([]Point, 0, len(transactions)+1)
+
+	if len(transactions) > 0 {
+		curve = append(curve, Point{Time: transactions[0].Time, Value: openingBalance})
+	}
+
+	for _, t := range transactions {
+		curve = append(curve, Point{Time: t.Time, Value: t.Balance})
+	}
+
+	return curve
+}
@@ -0,0 +1,234 @@
+/*
+Package analytics computes the performance analytics of an account: risk adjusted ratios of its equity curve
+and ledger, over the whole session or a rolling window:
+
+	since := analytics.FromEquityCurve(account.EquityCurve(), analytics.Inception)
+	month := analytics.FromLedger(account.Ledger(), analytics.Rolling30Days)
+	fmt.Println(since.Sharpe, month.Sortino)
+
+The ratios assume a zero risk free rate and are annualized with the mean interval of the returns.
+*/
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// The usual windows of the analytics, Inception is the whole session.
+const (
+	Inception     time.Duration = 0
+	Rolling30Days               = 30 * 24 * time.Hour
+	Rolling90Days               = 90 * 24 * time.Hour
+)
+
+const year = 365 * 24 * time.Hour
+
+// Performance are the risk adjusted returns of a window, the returns, volatility and drawdown are fractions.
+type Performance struct {
+	From             time.Time
+	To               time.Time
+	Periods          int // number of returns
+	Return           float64
+	AnnualizedReturn float64
+	Volatility       float64 // annualized standard deviation of the returns
+	MaxDrawdown      float64
+	Sharpe           float64
+	Sortino          float64
+	Calmar           float64
+}
+
+// sample is the return of the period ending at time.
+type sample struct {
+	time time.Time
+	ret  float64
+}
+
+// FromEquityCurve computes the performance of the equity curve points in the window ending at the last point,
+// every point in the window is a period. Deposits and withdrawals are returns of the equity curve, so the
+// ledger performance is more accurate on accounts with funds transfers.
+func FromEquityCurve(curve *gotrader.EquityCurve, window time.Duration) Performance {
+
+	if curve == nil {
+		return Performance{}
+	}
+
+	return FromEquityPoints(curve.Points(), window)
+}
+
+// FromEquityPoints computes the performance of the time ordered equity points in the window ending at the
+// last point, see FromEquityCurve.
+func FromEquityPoints(points []gotrader.EquityPoint, window time.Duration) Performance {
+
+	if len(points) < 2 {
+		return Performance{}
+	}
+
+	start := windowStart(points[len(points)-1].Time, window)
+
+	// the last point before the window is the base of its first return
+	first := sort.Search(len(points), func(i int) bool { return points[i].Time.After(start) })
+	if first > 0 {
+		first--
+	}
+
+	samples := make([]sample, 0, len(points)-first)
+	base := points[first].Equity
+
+	for _, p := range points[first+1:] {
+		r := 0.0
+		if base != 0 {
+			r = p.Equity/base - 1
+		}
+		samples = append(samples, sample{time: p.Time, ret: r})
+		base = p.Equity
+	}
+
+	return compute(points[first].Time, samples)
+}
+
+/*
+FromLedger computes the performance of the daily realized returns of the ledger in the window ending at its
+last transaction, the profit of each day over the balance at its start. Funds transfers move the balance
+without being returns. Days without transactions are not periods, so the ratios of accounts trading rarely
+are annualized with their mean interval.
+*/
+func FromLedger(ledger *gotrader.Ledger, window time.Duration) Performance {
+	return FromTransactions(ledger.Transactions(), ledger.OpeningBalance(), window)
+}
+
+// FromTransactions computes the performance of the time ordered transactions, see FromLedger.
+func FromTransactions(transactions []*gotrader.Transaction, openingBalance float64, window time.Duration) Performance {
+
+	if len(transactions) == 0 {
+		return Performance{}
+	}
+
+	start := windowStart(transactions[len(transactions)-1].Time, window)
+
+	from := transactions[0].Time.Truncate(24 * time.Hour)
+	if start.After(from) {
+		from = start
+	}
+
+	balance := openingBalance
+	samples := make([]sample, 0)
+
+	var day time.Time
+	var profit, dayBase float64
+
+	closeDay := func() {
+		if !day.IsZero() {
+			r := 0.0
+			if dayBase != 0 {
+				r = profit / dayBase
+			}
+			samples = append(samples, sample{time: day.Add(24 * time.Hour), ret: r})
+		}
+	}
+
+	for _, t := range transactions {
+
+		if !t.Time.After(start) { // before the window, only moves the balance
+			balance = t.Balance
+			continue
+		}
+
+		if d := t.Time.Truncate(24 * time.Hour); !d.Equal(day) {
+			closeDay()
+			day, profit, dayBase = d, 0, balance
+		}
+
+		if t.Type.External() {
+			dayBase += t.Amount
+		} else {
+			profit += t.Amount
+		}
+
+		balance = t.Balance
+	}
+
+	closeDay()
+
+	if len(samples) == 0 {
+		return Performance{}
+	}
+
+	return compute(from, samples)
+}
+
+func windowStart(end time.Time, window time.Duration) time.Time {
+
+	if window <= 0 {
+		return time.Time{}
+	}
+
+	return end.Add(-window)
+}
+
+func compute(from time.Time, samples []sample) Performance {
+
+	p := Performance{
+		From:    from,
+		To:      samples[len(samples)-1].time,
+		Periods: len(samples),
+	}
+
+	growth := 1.0
+	peak := 1.0
+	mean := 0.0
+
+	for _, s := range samples {
+		growth *= 1 + s.ret
+		peak = math.Max(peak, growth)
+		p.MaxDrawdown = math.Max(p.MaxDrawdown, (peak-growth)/peak)
+		mean += s.ret
+	}
+
+	mean /= float64(len(samples))
+	p.Return = growth - 1
+
+	elapsed := p.To.Sub(p.From)
+	if elapsed <= 0 {
+		return p
+	}
+
+	years := float64(elapsed) / float64(year)
+	if growth > 0 {
+		p.AnnualizedReturn = math.Pow(growth, 1/years) - 1
+	} else {
+		p.AnnualizedReturn = -1
+	}
+
+	periodsPerYear := float64(len(samples)) / years
+
+	if len(samples) > 1 {
+		variance, downside := 0.0, 0.0
+		for _, s := range samples {
+			variance += (s.ret - mean) * (s.ret - mean)
+			downside += math.Min(s.ret, 0) * math.Min(s.ret, 0)
+		}
+
+		std := math.Sqrt(variance / float64(len(samples)-1))
+		downsideDeviation := math.Sqrt(downside / float64(len(samples)))
+
+		p.Volatility = std * math.Sqrt(periodsPerYear)
+
+		if std > 0 {
+			p.Sharpe = mean / std * math.Sqrt(periodsPerYear)
+		}
+
+		if downsideDeviation > 0 {
+			p.Sortino = mean / downsideDeviation * math.Sqrt(periodsPerYear)
+		}
+	}
+
+	if p.MaxDrawdown > 0 {
+		p.Calmar = p.AnnualizedReturn / p.MaxDrawdown
+	}
+
+	return p
+}
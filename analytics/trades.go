@@ -0,0 +1,132 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// TradeStats are the statistics of closed trades, the profits are the amounts credited to the balance (net of
+// the fees charged on close) and the average loss and the gross loss are negative. Trades closed at zero are
+// neither wins nor losses, and break the streaks.
+type TradeStats struct {
+	Trades             int
+	Wins               int
+	Losses             int
+	WinRate            float64
+	NetProfit          float64
+	GrossProfit        float64
+	GrossLoss          float64
+	AverageWin         float64
+	AverageLoss        float64
+	ProfitFactor       float64 // gross profit / -gross loss, 0 without losses
+	Expectancy         float64 // mean profit per trade
+	AverageHoldingTime time.Duration
+	LongestWinStreak   int
+	LongestLossStreak  int
+}
+
+// tradeStats accumulates the TradeStats of time ordered trades.
+type tradeStats struct {
+	TradeStats
+	holding    time.Duration
+	winStreak  int
+	lossStreak int
+}
+
+func (s *tradeStats) add(t *gotrader.Transaction) {
+
+	s.Trades++
+	s.NetProfit += t.Amount
+	s.holding += t.Time.Sub(t.OpenTime)
+
+	switch {
+	case t.Amount > 0:
+		s.Wins++
+		s.GrossProfit += t.Amount
+		s.winStreak++
+		s.lossStreak = 0
+	case t.Amount < 0:
+		s.Losses++
+		s.GrossLoss += t.Amount
+		s.lossStreak++
+		s.winStreak = 0
+	default:
+		s.winStreak, s.lossStreak = 0, 0
+	}
+
+	if s.winStreak > s.LongestWinStreak {
+		s.LongestWinStreak = s.winStreak
+	}
+
+	if s.lossStreak > s.LongestLossStreak {
+		s.LongestLossStreak = s.lossStreak
+	}
+}
+
+func (s *tradeStats) stats() TradeStats {
+
+	stats := s.TradeStats
+
+	if stats.Trades == 0 {
+		return stats
+	}
+
+	stats.WinRate = float64(stats.Wins) / float64(stats.Trades)
+	stats.Expectancy = stats.NetProfit / float64(stats.Trades)
+	stats.AverageHoldingTime = s.holding / time.Duration(stats.Trades)
+
+	if stats.Wins > 0 {
+		stats.AverageWin = stats.GrossProfit / float64(stats.Wins)
+	}
+
+	if stats.Losses > 0 {
+		stats.AverageLoss = stats.GrossLoss / float64(stats.Losses)
+		stats.ProfitFactor = stats.GrossProfit / -stats.GrossLoss
+	}
+
+	return stats
+}
+
+// Trades returns the statistics of the trade close transactions, the other transactions are ignored.
+func Trades(transactions []*gotrader.Transaction) TradeStats {
+	return groupTrades(transactions, func(*gotrader.Transaction) string { return "" })[""]
+}
+
+// TradesByInstrument returns the statistics of the trade close transactions of every instrument.
+func TradesByInstrument(transactions []*gotrader.Transaction) map[string]TradeStats {
+	return groupTrades(transactions, func(t *gotrader.Transaction) string { return t.Instrument })
+}
+
+// TradesByTag returns the statistics of the trade close transactions of every tag, e.g. of every strategy of a
+// runner, which tags the orders with the strategy names. The untagged trades are under the empty tag.
+func TradesByTag(transactions []*gotrader.Transaction) map[string]TradeStats {
+	return groupTrades(transactions, func(t *gotrader.Transaction) string { return t.Tag })
+}
+
+func groupTrades(transactions []*gotrader.Transaction, key func(t *gotrader.Transaction) string) map[string]TradeStats {
+
+	groups := make(map[string]*tradeStats)
+
+	for _, t := range transactions {
+
+		if t.Type != gotrader.TradeCloseTransaction {
+			continue
+		}
+
+		group, exist := groups[key(t)]
+		if !exist {
+			group = &tradeStats{}
+			groups[key(t)] = group
+		}
+
+		group.add(t)
+	}
+
+	stats := make(map[string]TradeStats, len(groups))
+	for k, group := range groups {
+		stats[k] = group.stats()
+	}
+
+	return stats
+}
@@ -0,0 +1,293 @@
+/*
+Package delta computes the changes of the state of an account, its metrics and its open trades, between two
+updates, so the API servers stream compact deltas to their clients instead of full snapshots: an account with
+thousands of open trades only sends the trades opened, changed or closed since the previous update.
+
+The deltas are versioned, each one applies to the state of the previous version, its base. A client that misses
+a delta, e.g. after reconnecting, detects the gap with State.Apply and resyncs from a full state, a delta with a
+zero base holding the metrics and every open trade.
+*/
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// ErrOutOfSync is returned by State.Apply when a delta does not apply to the version of the state.
+var ErrOutOfSync = errors.New("delta does not apply to the state version")
+
+// Metrics are the account metrics of a delta.
+type Metrics struct {
+	Balance             float64 `json:"balance"`
+	Equity              float64 `json:"equity"`
+	UnrealizedNetProfit float64 `json:"unrealizedNetProfit"`
+	MarginUsed          float64 `json:"marginUsed"`
+	MarginFree          float64 `json:"marginFree"`
+}
+
+// Trade is an open trade of a delta, without the current price of its instrument which the clients stream
+// on their own.
+type Trade struct {
+	ID                  string    `json:"id"`
+	Instrument          string    `json:"instrument"`
+	Side                string    `json:"side"`
+	Units               int32     `json:"units"`
+	OpenPrice           float64   `json:"openPrice"`
+	OpenTime            time.Time `json:"openTime"`
+	UnrealizedNetProfit float64   `json:"unrealizedNetProfit"`
+	ChargedFees         float64   `json:"chargedFees"`
+	StopLoss            float64   `json:"stopLoss,omitempty"`
+	TakeProfit          float64   `json:"takeProfit,omitempty"`
+	Tag                 string    `json:"tag,omitempty"`
+}
+
+// TradeRef identifies a closed trade of a delta.
+type TradeRef struct {
+	ID         string `json:"id"`
+	Instrument string `json:"instrument"`
+}
+
+// Delta is the change of the state of an account from its base version, or the full state if the base is zero.
+type Delta struct {
+	Version uint64     `json:"version"`
+	Base    uint64     `json:"base"`
+	Time    time.Time  `json:"time"`
+	Metrics *Metrics   `json:"metrics,omitempty"` // nil when unchanged
+	Changed []Trade    `json:"changed,omitempty"` // opened or changed, every open trade of a full state
+	Removed []TradeRef `json:"removed,omitempty"` // closed
+}
+
+// Full returns whether the delta is a full state.
+func (d Delta) Full() bool {
+	return d.Base == 0
+}
+
+// Filter returns the delta with the trades of the instruments wanted only, the metrics are of the whole account.
+func (d Delta) Filter(wants func(instrument string) bool) Delta {
+
+	filtered := Delta{Version: d.Version, Base: d.Base, Time: d.Time, Metrics: d.Metrics}
+
+	for _, trade := range d.Changed {
+		if wants(trade.Instrument) {
+			filtered.Changed = append(filtered.Changed, trade)
+		}
+	}
+
+	for _, ref := range d.Removed {
+		if wants(ref.Instrument) {
+			filtered.Removed = append(filtered.Removed, ref)
+		}
+	}
+
+	return filtered
+}
+
+// State is the state of an account rebuilt by a client from the deltas.
+type State struct {
+	Version uint64
+	Time    time.Time
+	Metrics Metrics
+	Trades  map[string]Trade
+}
+
+// NewState is the State constructor, the first delta applied must be a full state.
+func NewState() *State {
+	return &State{Trades: make(map[string]Trade)}
+}
+
+// Apply applies a delta to the state, a delta of another base returns ErrOutOfSync and leaves the state as is.
+func (s *State) Apply(d Delta) error {
+
+	if d.Full() {
+		s.Trades = make(map[string]Trade, len(d.Changed))
+	} else if d.Base != s.Version {
+		return fmt.Errorf("%w: base %d, state %d", ErrOutOfSync, d.Base, s.Version)
+	}
+
+	s.Version, s.Time = d.Version, d.Time
+
+	if d.Metrics != nil {
+		s.Metrics = *d.Metrics
+	}
+
+	for _, trade := range d.Changed {
+		s.Trades[trade.ID] = trade
+	}
+
+	for _, ref := range d.Removed {
+		delete(s.Trades, ref.ID)
+	}
+
+	return nil
+}
+
+// Option represents a Tracker functional option
+type Option func(t *Tracker)
+
+// Resolution is the functional option to round the metrics and the trade profits to a resolution, e.g. 0.01 for
+// cents, so the changes smaller than it are not streamed. The values are not rounded by default.
+func Resolution(resolution float64) Option {
+	return func(t *Tracker) {
+		t.resolution = resolution
+	}
+}
+
+/*
+Tracker tracks the state of an account between its updates, usually at the push interval of a server. A new
+version is only created when the state changes.
+*/
+type Tracker struct {
+	mutex      *sync.Mutex
+	resolution float64
+	state      *State
+}
+
+// NewTracker is the Tracker constructor.
+func NewTracker(opts ...Option) *Tracker {
+
+	t := &Tracker{
+		mutex: &sync.Mutex{},
+		state: NewState(),
+	}
+
+	for _, o := range opts {
+		o(t)
+	}
+
+	return t
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (t *Tracker) round(value float64) float64 {
+
+	if t.resolution <= 0 {
+		return value
+	}
+
+	return math.Round(value/t.resolution) / (1 / t.resolution)
+}
+
+func (t *Tracker) trade(trade *gotrader.Trade) Trade {
+	return Trade{
+		ID:                  trade.ID(),
+		Instrument:          trade.InstrumentName(),
+		Side:                trade.Side().String(),
+		Units:               trade.Units(),
+		OpenPrice:           trade.OpenPrice(),
+		OpenTime:            trade.OpenTime(),
+		UnrealizedNetProfit: t.round(trade.UnrealizedNetProfit()),
+		ChargedFees:         trade.ChargedFees(),
+		StopLoss:            trade.StopLoss(),
+		TakeProfit:          trade.TakeProfit(),
+		Tag:                 trade.Tag(),
+	}
+}
+
+// sortTrades sorts the trades by open time, and by ID for the same open time, so the deltas are deterministic.
+func sortTrades(trades []Trade) {
+	sort.Slice(trades, func(i, j int) bool {
+		if !trades[i].OpenTime.Equal(trades[j].OpenTime) {
+			return trades[i].OpenTime.Before(trades[j].OpenTime)
+		}
+		return trades[i].ID < trades[j].ID
+	})
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Update returns the delta of the account since the previous update, and false if nothing changed.
+func (t *Tracker) Update(account *gotrader.Account) (Delta, bool) {
+
+	metrics := Metrics{
+		Balance:             t.round(account.Balance()),
+		Equity:              t.round(account.Equity()),
+		UnrealizedNetProfit: t.round(account.UnrealizedNetProfit()),
+		MarginUsed:          t.round(account.MarginUsed()),
+		MarginFree:          t.round(account.MarginFree()),
+	}
+
+	open := make(map[string]bool)
+	d := Delta{Time: account.Time()}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, inst := range account.Instruments() {
+		inst.RangeTrades(func(trade *gotrader.Trade) bool {
+
+			current := t.trade(trade)
+			open[current.ID] = true
+
+			if previous, exist := t.state.Trades[current.ID]; !exist || previous != current {
+				d.Changed = append(d.Changed, current)
+			}
+
+			return true
+		})
+	}
+
+	for id, trade := range t.state.Trades {
+		if !open[id] {
+			d.Removed = append(d.Removed, TradeRef{ID: id, Instrument: trade.Instrument})
+		}
+	}
+
+	if t.state.Version == 0 || metrics != t.state.Metrics {
+		d.Metrics = &metrics
+	}
+
+	if d.Metrics == nil && len(d.Changed) == 0 && len(d.Removed) == 0 {
+		return Delta{}, false
+	}
+
+	sortTrades(d.Changed)
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].ID < d.Removed[j].ID })
+
+	// the first delta has every trade and the metrics, a full state
+	d.Version, d.Base = t.state.Version+1, t.state.Version
+	t.state.Apply(d)
+
+	return d, true
+}
+
+// Full returns the full state of the last update, for the clients connecting or resyncing.
+func (t *Tracker) Full() Delta {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	metrics := t.state.Metrics
+	d := Delta{Version: t.state.Version, Time: t.state.Time, Metrics: &metrics,
+		Changed: make([]Trade, 0, len(t.state.Trades))}
+
+	for _, trade := range t.state.Trades {
+		d.Changed = append(d.Changed, trade)
+	}
+
+	sortTrades(d.Changed)
+
+	return d
+}
+
+// Version returns the version of the last update, zero before the first one.
+func (t *Tracker) Version() uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.state.Version
+}
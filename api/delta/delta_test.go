@@ -0,0 +1,103 @@
+package delta
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/gotradertest"
+)
+
+type holder struct{ engine gotrader.Engine }
+
+func (s *holder) Initialize()                          {}
+func (s *holder) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *holder) OnTick(tick *gotrader.Tick)           {}
+func (s *holder) OnOrderFill(fill *gotrader.OrderFill) {}
+func (s *holder) OnStop()                              {}
+
+func TestTracker(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := gotradertest.NewBroker(instruments, gotradertest.Balance(10000), gotradertest.Currency("EUR"),
+		gotradertest.Leverage(30))
+	broker.Quote("EUR_USD", 1.0999, 1.1001)
+
+	s := &holder{}
+	h := gotradertest.New(t, s, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.HomeCurrency("EUR"))
+
+	tracker := NewTracker(Resolution(0.01))
+	client := NewState()
+
+	s.engine.Buy("EUR_USD", 1000)
+	s.engine.Buy("EUR_USD", 2000)
+	h.Settle()
+
+	first, changed := tracker.Update(h.Account())
+	if !changed || !first.Full() || first.Metrics == nil || len(first.Changed) != 2 {
+		t.Fatalf("expected a full first state with the two trades, got %+v", first)
+	}
+
+	if err := client.Apply(first); err != nil {
+		t.Fatal(err)
+	}
+
+	if d, changed := tracker.Update(h.Account()); changed {
+		t.Fatalf("expected no delta without changes, got %+v", d)
+	}
+
+	closed := first.Changed[0]
+	s.engine.CloseTrade("EUR_USD", closed.ID)
+	h.Tick("EUR_USD", 1.1049, 1.1051)
+	h.Settle()
+
+	second, changed := tracker.Update(h.Account())
+	if !changed || second.Full() || second.Base != first.Version || len(second.Removed) != 1 ||
+		second.Removed[0].ID != closed.ID || len(second.Changed) != 1 || second.Metrics == nil {
+		t.Fatalf("expected the close and the profit change of the other trade, got %+v", second)
+	}
+
+	if err := client.Apply(second); err != nil {
+		t.Fatal(err)
+	}
+
+	trade := client.Trades[second.Changed[0].ID]
+	if len(client.Trades) != 1 || client.Version != second.Version || trade.UnrealizedNetProfit <= 0 ||
+		math.Abs(client.Metrics.Balance-h.Account().Balance()) > 0.005 {
+		t.Fatalf("expected the state of the account, got %+v", client)
+	}
+
+	t.Run("gaps are detected", func(t *testing.T) {
+
+		h.Tick("EUR_USD", 1.1099, 1.1101)
+		h.Settle()
+
+		missed, _ := tracker.Update(h.Account())
+		h.Tick("EUR_USD", 1.1149, 1.1151)
+		h.Settle()
+
+		next, _ := tracker.Update(h.Account())
+
+		if err := client.Apply(next); !errors.Is(err, ErrOutOfSync) || client.Version != second.Version {
+			t.Fatalf("expected the delta after a missed one to be out of sync, got %v", err)
+		}
+
+		full := tracker.Full()
+		if err := client.Apply(full); err != nil || !full.Full() || full.Version != missed.Version+1 ||
+			client.Trades[trade.ID].UnrealizedNetProfit != next.Changed[0].UnrealizedNetProfit {
+			t.Fatalf("expected the full state to resync the client, got %+v, %v", client, err)
+		}
+	})
+
+	t.Run("trades are filtered by instrument", func(t *testing.T) {
+
+		d := tracker.Full().Filter(func(instrument string) bool { return instrument != "EUR_USD" })
+		if len(d.Changed) != 0 || d.Metrics == nil {
+			t.Fatalf("expected the metrics only, got %+v", d)
+		}
+	})
+}
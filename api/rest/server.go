@@ -0,0 +1,332 @@
+/*
+Package rest exposes a gotrader session over HTTP with JSON. The read endpoints return the instruments,
+open trades, positions, margin and realized profit of the account, and the authenticated POST endpoints
+open and close trades. The health endpoint answers 503 while a monitored component is unhealthy:
+
+	GET  /instruments             GET  /instruments/{name}
+	GET  /trades?instrument=      GET  /trades/{id}
+	GET  /positions?instrument=   GET  /margin
+	GET  /pnl                     GET  /equity?since=
+	GET  /health                  GET  /daily?date=
+	POST /trades                  {"instrument": "EUR_USD", "side": "LONG", "units": 1000}
+	POST /trades/{id}/close
+
+Writes require the "Authorization: Bearer <token>" header, and are disabled when no token is defined.
+*/
+package rest
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Option represents a Server functional option
+type Option func(s *Server)
+
+// Token is the functional option to define the bearer token of the write endpoints.
+func Token(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// AuthenticateReads is the functional option to also require the token on the read endpoints.
+func AuthenticateReads() Option {
+	return func(s *Server) {
+		s.authenticateReads = true
+	}
+}
+
+// Server is the http.Handler of the REST API.
+type Server struct {
+	engine            gotrader.Engine
+	token             string
+	authenticateReads bool
+}
+
+// NewServer is the Server constructor, the engine is usually the TradingSession Engine.
+func NewServer(engine gotrader.Engine, opts ...Option) *Server {
+
+	s := &Server{engine: engine}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (s *Server) authenticated(r *http.Request) bool {
+
+	if s.token == "" {
+		return false
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
+
+// writeEngineError writes an engine mutation error with the status of its cause.
+func writeEngineError(w http.ResponseWriter, err error) {
+
+	code := http.StatusUnprocessableEntity
+
+	switch {
+	case errors.Is(err, gotrader.ErrInstrumentNotTraded), errors.Is(err, gotrader.ErrTradeNotFound),
+		errors.Is(err, gotrader.ErrOrderNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, gotrader.ErrMarketClosed):
+		code = http.StatusConflict
+	case errors.Is(err, gotrader.ErrUnhealthy):
+		code = http.StatusServiceUnavailable
+	}
+
+	writeError(w, code, err.Error())
+}
+
+// instruments returns the account instruments sorted by name, filtered by name if not empty.
+func instruments(account *gotrader.Account, name string) []*gotrader.Instrument {
+
+	list := make([]*gotrader.Instrument, 0, len(account.Instruments()))
+
+	for _, inst := range account.Instruments() {
+		if name == "" || inst.Name() == name {
+			list = append(list, inst)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+
+	return list
+}
+
+func findTrade(account *gotrader.Account, id string) *gotrader.Trade {
+
+	for _, inst := range account.Instruments() {
+		if trade := inst.Trade(id); trade != nil {
+			return trade
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) get(w http.ResponseWriter, account *gotrader.Account, path []string, r *http.Request) {
+
+	switch {
+	case len(path) == 1 && path[0] == "instruments":
+		list := make([]*Instrument, 0)
+		for _, inst := range instruments(account, "") {
+			list = append(list, newInstrument(inst))
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case len(path) == 2 && path[0] == "instruments":
+		inst := account.Instrument(path[1])
+		if inst == nil {
+			writeError(w, http.StatusNotFound, "instrument "+path[1]+" is not being traded")
+			return
+		}
+		writeJSON(w, http.StatusOK, newInstrument(inst))
+
+	case len(path) == 1 && path[0] == "trades":
+		list := make([]*Trade, 0)
+		for _, inst := range instruments(account, r.URL.Query().Get("instrument")) {
+			for trade := range inst.Trades() {
+				list = append(list, newTrade(trade))
+			}
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case len(path) == 2 && path[0] == "trades":
+		trade := findTrade(account, path[1])
+		if trade == nil {
+			writeError(w, http.StatusNotFound, "trade "+path[1]+" does not exist")
+			return
+		}
+		writeJSON(w, http.StatusOK, newTrade(trade))
+
+	case len(path) == 1 && path[0] == "positions":
+		list := make([]*Position, 0)
+		for _, inst := range instruments(account, r.URL.Query().Get("instrument")) {
+			list = append(list, newPosition(inst.Name(), inst.LongPosition()), newPosition(inst.Name(), inst.ShortPosition()))
+		}
+		writeJSON(w, http.StatusOK, list)
+
+	case len(path) == 1 && path[0] == "margin":
+		writeJSON(w, http.StatusOK, newMargin(account))
+
+	case len(path) == 1 && path[0] == "pnl":
+		writeJSON(w, http.StatusOK, newProfit(account))
+
+	case len(path) == 1 && path[0] == "health":
+		health := newHealth(account)
+		code := http.StatusOK
+		if health.Status == gotrader.Unhealthy.String() {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, health)
+
+	case len(path) == 1 && path[0] == "equity":
+		if account.EquityCurve() == nil {
+			writeError(w, http.StatusNotFound, "the equity is not tracked")
+			return
+		}
+
+		var since time.Time
+		if value := r.URL.Query().Get("since"); value != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339, value); err != nil {
+				writeError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, newEquity(account, since))
+
+	case len(path) == 1 && path[0] == "daily":
+		date := account.Time()
+		if value := r.URL.Query().Get("date"); value != "" {
+			var err error
+			if date, err = time.Parse(time.RFC3339, value); err != nil {
+				writeError(w, http.StatusBadRequest, "date must be an RFC 3339 time")
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, newDay(account, date))
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) post(w http.ResponseWriter, account *gotrader.Account, path []string, r *http.Request) {
+
+	switch {
+	case len(path) == 1 && path[0] == "trades":
+
+		req := &OpenRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if account.Instrument(req.Instrument) == nil {
+			writeError(w, http.StatusNotFound, "instrument "+req.Instrument+" is not being traded")
+			return
+		}
+
+		if req.Units <= 0 {
+			writeError(w, http.StatusBadRequest, "units must be positive")
+			return
+		}
+
+		var err error
+
+		switch strings.ToUpper(req.Side) {
+		case gotrader.Long.String():
+			err = s.engine.Buy(req.Instrument, req.Units)
+		case gotrader.Short.String():
+			err = s.engine.Sell(req.Instrument, req.Units)
+		default:
+			writeError(w, http.StatusBadRequest, "side must be LONG or SHORT")
+			return
+		}
+
+		if err != nil {
+			writeEngineError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, req)
+
+	case len(path) == 3 && path[0] == "trades" && path[2] == "close":
+
+		trade := findTrade(account, path[1])
+		if trade == nil {
+			writeError(w, http.StatusNotFound, "trade "+path[1]+" does not exist")
+			return
+		}
+
+		if err := s.engine.CloseTrade(trade.InstrumentName(), trade.ID()); err != nil {
+			writeEngineError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, newTrade(trade))
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if s.engine == nil || s.engine.Account() == nil {
+		writeError(w, http.StatusServiceUnavailable, "session has not started")
+		return
+	}
+
+	account := s.engine.Account()
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		if s.authenticateReads && !s.authenticated(r) {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		s.get(w, account, path, r)
+
+	case http.MethodPost:
+		if s.token == "" {
+			writeError(w, http.StatusForbidden, "trading is disabled")
+			return
+		}
+		if !s.authenticated(r) {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		s.post(w, account, path, r)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// ListenAndServe serves the API on the given address until it fails.
+func (s *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, s)
+}
@@ -0,0 +1,305 @@
+package rest
+
+import (
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Instrument is the JSON representation of an instrument.
+type Instrument struct {
+	Name                      string  `json:"name"`
+	BaseCurrency              string  `json:"baseCurrency"`
+	QuoteCurrency             string  `json:"quoteCurrency"`
+	Bid                       float64 `json:"bid"`
+	Ask                       float64 `json:"ask"`
+	Spread                    float64 `json:"spread"`
+	Leverage                  float64 `json:"leverage"`
+	PipLocation               int     `json:"pipLocation"`
+	Status                    string  `json:"status"`
+	Trades                    int32   `json:"trades"`
+	UnrealizedNetProfit       float64 `json:"unrealizedNetProfit"`
+	UnrealizedEffectiveProfit float64 `json:"unrealizedEffectiveProfit"`
+	RealizedProfit            float64 `json:"realizedProfit"`
+	MarginUsed                float64 `json:"marginUsed"`
+}
+
+// Trade is the JSON representation of an open trade.
+type Trade struct {
+	ID                        string        `json:"id"`
+	Instrument                string        `json:"instrument"`
+	Side                      string        `json:"side"`
+	Units                     int32         `json:"units"`
+	OpenPrice                 float64       `json:"openPrice"`
+	OpenTime                  time.Time     `json:"openTime"`
+	CurrentPrice              float64       `json:"currentPrice"`
+	UnrealizedNetProfit       float64       `json:"unrealizedNetProfit"`
+	UnrealizedEffectiveProfit float64       `json:"unrealizedEffectiveProfit"`
+	MarginUsed                float64       `json:"marginUsed"`
+	ChargedFees               float64       `json:"chargedFees"`
+	Fees                      gotrader.Fees `json:"fees"`
+	StopLoss                  float64       `json:"stopLoss,omitempty"`
+	TakeProfit                float64       `json:"takeProfit,omitempty"`
+	Tag                       string        `json:"tag,omitempty"`
+}
+
+// Position is the JSON representation of one side of an instrument.
+type Position struct {
+	Instrument                string  `json:"instrument"`
+	Side                      string  `json:"side"`
+	Units                     int32   `json:"units"`
+	Trades                    int32   `json:"trades"`
+	AveragePrice              float64 `json:"averagePrice"`
+	UnrealizedNetProfit       float64 `json:"unrealizedNetProfit"`
+	UnrealizedEffectiveProfit float64 `json:"unrealizedEffectiveProfit"`
+	MarginUsed                float64 `json:"marginUsed"`
+	ChargedFees               float64 `json:"chargedFees"`
+}
+
+// Margin is the JSON representation of the account margin, MarginLevel is equity / margin used.
+type Margin struct {
+	Time        time.Time `json:"time"`
+	Currency    string    `json:"currency"`
+	Balance     float64   `json:"balance"`
+	Equity      float64   `json:"equity"`
+	MarginUsed  float64   `json:"marginUsed"`
+	MarginFree  float64   `json:"marginFree"`
+	MarginLevel float64   `json:"marginLevel,omitempty"`
+}
+
+// ClosedTrade is the JSON representation of a trade close transaction.
+type ClosedTrade struct {
+	TradeID    string    `json:"tradeId"`
+	Instrument string    `json:"instrument"`
+	Side       string    `json:"side"`
+	Units      int32     `json:"units"`
+	OpenPrice  float64   `json:"openPrice"`
+	ClosePrice float64   `json:"closePrice"`
+	OpenTime   time.Time `json:"openTime"`
+	CloseTime  time.Time `json:"closeTime"`
+	Profit     float64   `json:"profit"`
+	Fees       float64   `json:"fees"`
+	SpreadCost float64   `json:"spreadCost"`
+	Tag        string    `json:"tag,omitempty"`
+}
+
+// Profit is the JSON representation of the realized and unrealized profit of the account.
+type Profit struct {
+	Currency                  string         `json:"currency"`
+	OpeningBalance            float64        `json:"openingBalance"`
+	RealizedProfit            float64        `json:"realizedProfit"`
+	UnrealizedNetProfit       float64        `json:"unrealizedNetProfit"`
+	UnrealizedEffectiveProfit float64        `json:"unrealizedEffectiveProfit"`
+	ClosedTrades              []*ClosedTrade `json:"closedTrades"`
+}
+
+// EquityPoint is the JSON representation of a sample of the equity curve.
+type EquityPoint struct {
+	Time    time.Time `json:"time"`
+	Equity  float64   `json:"equity"`
+	Balance float64   `json:"balance"`
+}
+
+// Equity is the JSON representation of the equity curve, the drawdowns are fractions of the high-water mark.
+type Equity struct {
+	Currency      string         `json:"currency"`
+	HighWaterMark float64        `json:"highWaterMark"`
+	Drawdown      float64        `json:"drawdown"`
+	MaxDrawdown   float64        `json:"maxDrawdown"`
+	Points        []*EquityPoint `json:"points"`
+}
+
+// Day is the JSON representation of the statistics of a trading day, the amounts are in the home currency.
+type Day struct {
+	Start            time.Time `json:"start"`
+	End              time.Time `json:"end"`
+	Currency         string    `json:"currency"`
+	PnL              float64   `json:"pnl"`
+	RealizedProfit   float64   `json:"realizedProfit"`
+	UnrealizedProfit float64   `json:"unrealizedProfit"`
+	UnrealizedChange float64   `json:"unrealizedChange"`
+	Fees             float64   `json:"fees"`
+	Opened           int       `json:"opened"`
+	Closed           int       `json:"closed"`
+}
+
+// ComponentHealth is the JSON representation of the health of a monitored component.
+type ComponentHealth struct {
+	Component string    `json:"component"`
+	State     string    `json:"state"`
+	LastBeat  time.Time `json:"lastBeat"`
+	Since     time.Time `json:"since"`
+}
+
+// Health is the JSON representation of the health of the session, Status is the worst state of its components.
+type Health struct {
+	Status     string             `json:"status"`
+	Components []*ComponentHealth `json:"components"`
+}
+
+// OpenRequest is the body of the trade open endpoint.
+type OpenRequest struct {
+	Instrument string `json:"instrument"`
+	Side       string `json:"side"`
+	Units      int32  `json:"units"`
+}
+
+func newInstrument(i *gotrader.Instrument) *Instrument {
+	return &Instrument{
+		Name:                      i.Name(),
+		BaseCurrency:              i.BaseCurrency(),
+		QuoteCurrency:             i.QuoteCurrency(),
+		Bid:                       i.Bid(),
+		Ask:                       i.Ask(),
+		Spread:                    i.Spread(),
+		Leverage:                  i.Leverage(),
+		PipLocation:               i.PipLocation(),
+		Status:                    i.Status().String(),
+		Trades:                    i.TradesNumber(),
+		UnrealizedNetProfit:       i.UnrealizedNetProfit(),
+		UnrealizedEffectiveProfit: i.UnrealizedEffectiveProfit(),
+		RealizedProfit:            i.RealizedProfit(),
+		MarginUsed:                i.MarginUsed(),
+	}
+}
+
+func newTrade(t *gotrader.Trade) *Trade {
+	return &Trade{
+		ID:                        t.ID(),
+		Instrument:                t.InstrumentName(),
+		Side:                      t.Side().String(),
+		Units:                     t.Units(),
+		OpenPrice:                 t.OpenPrice(),
+		OpenTime:                  t.OpenTime(),
+		CurrentPrice:              t.CurrentPrice(),
+		UnrealizedNetProfit:       t.UnrealizedNetProfit(),
+		UnrealizedEffectiveProfit: t.UnrealizedEffectiveProfit(),
+		MarginUsed:                t.MarginUsed(),
+		ChargedFees:               t.ChargedFees(),
+		Fees:                      t.Fees(),
+		StopLoss:                  t.StopLoss(),
+		TakeProfit:                t.TakeProfit(),
+		Tag:                       t.Tag(),
+	}
+}
+
+func newPosition(instrument string, p *gotrader.Position) *Position {
+	return &Position{
+		Instrument:                instrument,
+		Side:                      p.Side().String(),
+		Units:                     p.Units(),
+		Trades:                    p.TradesNumber(),
+		AveragePrice:              p.AveragePrice(),
+		UnrealizedNetProfit:       p.UnrealizedNetProfit(),
+		UnrealizedEffectiveProfit: p.UnrealizedEffectiveProfit(),
+		MarginUsed:                p.MarginUsed(),
+		ChargedFees:               p.ChargedFees(),
+	}
+}
+
+func newMargin(a *gotrader.Account) *Margin {
+
+	m := &Margin{
+		Time:       a.Time(),
+		Currency:   a.HomeCurrency(),
+		Balance:    a.Balance(),
+		Equity:     a.Equity(),
+		MarginUsed: a.MarginUsed(),
+		MarginFree: a.MarginFree(),
+	}
+
+	if m.MarginUsed > 0 {
+		m.MarginLevel = m.Equity / m.MarginUsed
+	}
+
+	return m
+}
+
+func newProfit(a *gotrader.Account) *Profit {
+
+	p := &Profit{
+		Currency:                  a.HomeCurrency(),
+		OpeningBalance:            a.Ledger().OpeningBalance(),
+		RealizedProfit:            a.Ledger().RealizedProfit(),
+		UnrealizedNetProfit:       a.UnrealizedNetProfit(),
+		UnrealizedEffectiveProfit: a.UnrealizedEffectiveProfit(),
+		ClosedTrades:              make([]*ClosedTrade, 0),
+	}
+
+	for _, t := range a.Ledger().ClosedTrades() {
+		p.ClosedTrades = append(p.ClosedTrades, &ClosedTrade{
+			TradeID:    t.TradeID,
+			Instrument: t.Instrument,
+			Side:       t.Side.String(),
+			Units:      t.Units,
+			OpenPrice:  t.OpenPrice,
+			ClosePrice: t.ClosePrice,
+			OpenTime:   t.OpenTime,
+			CloseTime:  t.Time,
+			Profit:     t.Amount,
+			Fees:       t.Fees,
+			SpreadCost: t.SpreadCost,
+			Tag:        t.Tag,
+		})
+	}
+
+	return p
+}
+
+func newEquity(a *gotrader.Account, since time.Time) *Equity {
+
+	c := a.EquityCurve()
+	e := &Equity{
+		Currency:      a.HomeCurrency(),
+		HighWaterMark: c.HighWaterMark(),
+		Drawdown:      c.Drawdown(),
+		MaxDrawdown:   c.MaxDrawdown(),
+		Points:        make([]*EquityPoint, 0),
+	}
+
+	for _, p := range c.Since(since) {
+		e.Points = append(e.Points, &EquityPoint{Time: p.Time, Equity: p.Equity, Balance: p.Balance})
+	}
+
+	return e
+}
+
+func newDay(a *gotrader.Account, date time.Time) *Day {
+
+	d := a.DailyStats(date)
+
+	return &Day{
+		Start:            d.Start,
+		End:              d.End,
+		Currency:         a.HomeCurrency(),
+		PnL:              d.PnL(),
+		RealizedProfit:   d.RealizedProfit,
+		UnrealizedProfit: d.UnrealizedProfit,
+		UnrealizedChange: d.UnrealizedChange,
+		Fees:             d.Fees,
+		Opened:           d.Opened,
+		Closed:           d.Closed,
+	}
+}
+
+func newHealth(a *gotrader.Account) *Health {
+
+	worst := gotrader.Healthy
+	h := &Health{Components: make([]*ComponentHealth, 0)}
+
+	for _, c := range a.Health().Status() {
+		if c.State > worst {
+			worst = c.State
+		}
+		h.Components = append(h.Components, &ComponentHealth{
+			Component: c.Component,
+			State:     c.State.String(),
+			LastBeat:  c.LastBeat,
+			Since:     c.Since,
+		})
+	}
+
+	h.Status = worst.String()
+
+	return h
+}
@@ -0,0 +1,499 @@
+/*
+Package codec converts the gotrader ticks, trades, positions, account state and events to the protobuf
+messages of state.proto and gotrader.proto, and encodes them in the protobuf binary format. The same messages
+are streamed by the Trader service, so a persisted state or event log can be read by any protobuf client.
+
+Ticks, transactions and account states (snapshots) are decoded back to their gotrader types, a snapshot can
+then be restored with gotrader.RestoreAccount. Trades, positions and events hold live session values, so they
+are decoded to the pb messages.
+*/
+package codec
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/api/delta"
+	"github.com/luismcruz/gotrader/api/rpc/pb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+func asTime(ts *timestamppb.Timestamp) time.Time {
+
+	if ts == nil {
+		return time.Time{}
+	}
+
+	return ts.AsTime()
+}
+
+func breakdown(f gotrader.Fees) *pb.Fees {
+
+	if f == (gotrader.Fees{}) {
+		return nil
+	}
+
+	return &pb.Fees{
+		Commission:     f.Commission,
+		Financing:      f.Financing,
+		GuaranteedStop: f.GuaranteedStop,
+		Markup:         f.Markup,
+		Unitemized:     f.Unitemized,
+	}
+}
+
+func asFees(m *pb.Fees) gotrader.Fees {
+
+	if m == nil {
+		return gotrader.Fees{}
+	}
+
+	return gotrader.Fees{
+		Commission:     m.Commission,
+		Financing:      m.Financing,
+		GuaranteedStop: m.GuaranteedStop,
+		Markup:         m.Markup,
+		Unitemized:     m.Unitemized,
+	}
+}
+
+/**************************
+*
+*	Conversions
+*
+***************************/
+
+// Tick returns the message of a tick.
+func Tick(t *gotrader.Tick) *pb.Tick {
+	return &pb.Tick{
+		Instrument: t.Instrument,
+		Bid:        t.Bid,
+		Ask:        t.Ask,
+		BidSize:    t.BidSize,
+		AskSize:    t.AskSize,
+		Time:       timestamp(t.Time),
+	}
+}
+
+// FromTick returns the tick of a message.
+func FromTick(m *pb.Tick) *gotrader.Tick {
+	return &gotrader.Tick{
+		Instrument: m.Instrument,
+		Bid:        m.Bid,
+		Ask:        m.Ask,
+		BidSize:    m.BidSize,
+		AskSize:    m.AskSize,
+		Time:       asTime(m.Time),
+	}
+}
+
+// Trade returns the message of an open trade, with its current profits and margin.
+func Trade(t *gotrader.Trade) *pb.Trade {
+	return &pb.Trade{
+		Id:                        t.ID(),
+		Instrument:                t.InstrumentName(),
+		Side:                      pb.Side(t.Side()),
+		Units:                     t.Units(),
+		OpenPrice:                 t.OpenPrice(),
+		OpenTime:                  timestamp(t.OpenTime()),
+		CurrentPrice:              t.CurrentPrice(),
+		UnrealizedNetProfit:       t.UnrealizedNetProfit(),
+		UnrealizedEffectiveProfit: t.UnrealizedEffectiveProfit(),
+		MarginUsed:                t.MarginUsed(),
+		ChargedFees:               t.ChargedFees(),
+		StopLoss:                  t.StopLoss(),
+		TakeProfit:                t.TakeProfit(),
+		Tag:                       t.Tag(),
+	}
+}
+
+// Position returns the message of a position of the instrument.
+func Position(instrument string, p *gotrader.Position) *pb.Position {
+	return &pb.Position{
+		Instrument:                instrument,
+		Side:                      pb.Side(p.Side()),
+		Units:                     p.Units(),
+		Trades:                    p.TradesNumber(),
+		AveragePrice:              p.AveragePrice(),
+		UnrealizedNetProfit:       p.UnrealizedNetProfit(),
+		UnrealizedEffectiveProfit: p.UnrealizedEffectiveProfit(),
+		MarginUsed:                p.MarginUsed(),
+		ChargedFees:               p.ChargedFees(),
+	}
+}
+
+// Delta returns the message of a state delta, its account metrics are unset if they did not change.
+func Delta(d delta.Delta) *pb.StateDelta {
+
+	m := &pb.StateDelta{Version: d.Version, Base: d.Base, Time: timestamp(d.Time)}
+
+	if d.Metrics != nil {
+		m.Account = &pb.AccountMetrics{
+			Time:                timestamp(d.Time),
+			Balance:             d.Metrics.Balance,
+			Equity:              d.Metrics.Equity,
+			UnrealizedNetProfit: d.Metrics.UnrealizedNetProfit,
+			MarginUsed:          d.Metrics.MarginUsed,
+			MarginFree:          d.Metrics.MarginFree,
+		}
+	}
+
+	for _, t := range d.Changed {
+		m.Changed = append(m.Changed, &pb.Trade{
+			Id:                  t.ID,
+			Instrument:          t.Instrument,
+			Side:                pb.Side(pb.Side_value[t.Side]),
+			Units:               t.Units,
+			OpenPrice:           t.OpenPrice,
+			OpenTime:            timestamp(t.OpenTime),
+			UnrealizedNetProfit: t.UnrealizedNetProfit,
+			ChargedFees:         t.ChargedFees,
+			StopLoss:            t.StopLoss,
+			TakeProfit:          t.TakeProfit,
+			Tag:                 t.Tag,
+		})
+	}
+
+	for _, r := range d.Removed {
+		m.Removed = append(m.Removed, &pb.TradeRef{Id: r.ID, Instrument: r.Instrument})
+	}
+
+	return m
+}
+
+// Fill returns the message of an order fill.
+func Fill(f *gotrader.OrderFill) *pb.Fill {
+	return &pb.Fill{
+		Error:       f.Error,
+		TradeClose:  f.TradeClose,
+		OrderId:     f.OrderID,
+		TradeId:     f.TradeID,
+		Instrument:  f.Instrument.Name,
+		Side:        pb.Side(f.Side),
+		Price:       f.Price,
+		Units:       f.Units,
+		Profit:      f.Profit,
+		ChargedFees: f.ChargedFees,
+		Time:        timestamp(f.Time),
+		Venue:       f.Venue,
+		Tag:         f.Tag,
+		Reason:      pb.CloseReason(f.Reason),
+	}
+}
+
+// Order returns the message of an order.
+func Order(o *gotrader.Order) *pb.Order {
+	return &pb.Order{
+		Id:          o.ID,
+		Type:        pb.OrderType(o.Type),
+		Instrument:  o.Instrument,
+		Side:        pb.Side(o.Side),
+		Units:       o.Units,
+		Price:       o.Price,
+		StopLoss:    o.StopLoss,
+		TakeProfit:  o.TakeProfit,
+		TimeInForce: pb.TimeInForce(o.TimeInForce),
+		Expiry:      timestamp(o.Expiry),
+		CreateTime:  timestamp(o.CreateTime),
+		Tag:         o.Tag,
+	}
+}
+
+// FromOrder returns the order of a message.
+func FromOrder(m *pb.Order) *gotrader.Order {
+	return &gotrader.Order{
+		ID:          m.Id,
+		Type:        gotrader.OrderType(m.Type),
+		Instrument:  m.Instrument,
+		Side:        gotrader.Side(m.Side),
+		Units:       m.Units,
+		Price:       m.Price,
+		StopLoss:    m.StopLoss,
+		TakeProfit:  m.TakeProfit,
+		TimeInForce: gotrader.TimeInForce(m.TimeInForce),
+		Expiry:      asTime(m.Expiry),
+		CreateTime:  asTime(m.CreateTime),
+		Tag:         m.Tag,
+	}
+}
+
+// Transaction returns the message of a ledger transaction.
+func Transaction(t *gotrader.Transaction) *pb.Transaction {
+	return &pb.Transaction{
+		Type:       pb.TransactionType(t.Type),
+		TradeId:    t.TradeID,
+		Instrument: t.Instrument,
+		Side:       pb.Side(t.Side),
+		Units:      t.Units,
+		OpenPrice:  t.OpenPrice,
+		ClosePrice: t.ClosePrice,
+		OpenTime:   timestamp(t.OpenTime),
+		Amount:     t.Amount,
+		Fees:       t.Fees,
+		Balance:    t.Balance,
+		Time:       timestamp(t.Time),
+		Tag:        t.Tag,
+		Reason:     pb.CloseReason(t.Reason),
+		SpreadCost: t.SpreadCost,
+	}
+}
+
+// FromTransaction returns the ledger transaction of a message.
+func FromTransaction(m *pb.Transaction) *gotrader.Transaction {
+	return &gotrader.Transaction{
+		Type:       gotrader.TransactionType(m.Type),
+		TradeID:    m.TradeId,
+		Instrument: m.Instrument,
+		Side:       gotrader.Side(m.Side),
+		Units:      m.Units,
+		OpenPrice:  m.OpenPrice,
+		ClosePrice: m.ClosePrice,
+		OpenTime:   asTime(m.OpenTime),
+		Amount:     m.Amount,
+		Fees:       m.Fees,
+		Balance:    m.Balance,
+		Time:       asTime(m.Time),
+		Tag:        m.Tag,
+		Reason:     gotrader.CloseReason(m.Reason),
+		SpreadCost: m.SpreadCost,
+	}
+}
+
+// AccountState returns the message of an account snapshot.
+func AccountState(s *gotrader.Snapshot) *pb.AccountState {
+
+	m := &pb.AccountState{
+		Version:        int32(s.Version),
+		Time:           timestamp(s.Time),
+		AccountId:      s.AccountID,
+		HomeCurrency:   s.HomeCurrency,
+		Balance:        s.Balance,
+		Leverage:       s.Leverage,
+		OpeningBalance: s.OpeningBalance,
+		Instruments:    make([]*pb.InstrumentState, 0, len(s.Instruments)),
+		Transactions:   make([]*pb.Transaction, 0, len(s.Transactions)),
+		WalSequence:    s.WALSequence,
+	}
+
+	for _, is := range s.Instruments {
+
+		instrument := &pb.InstrumentState{
+			Name:                is.Name,
+			BaseCurrency:        is.BaseCurrency,
+			QuoteCurrency:       is.QuoteCurrency,
+			Leverage:            is.Leverage,
+			PipLocation:         int32(is.PipLocation),
+			Hedge:               pb.Hedge(is.Hedge),
+			Bid:                 is.Bid,
+			Ask:                 is.Ask,
+			BaseConversionRate:  is.BaseConversionRate,
+			QuoteConversionRate: is.QuoteConversionRate,
+			LastUpdate:          timestamp(is.LastUpdate),
+			Trades:              make([]*pb.TradeState, 0, len(is.Trades)),
+		}
+
+		for _, ts := range is.Trades {
+			instrument.Trades = append(instrument.Trades, &pb.TradeState{
+				Id:          ts.ID,
+				Side:        pb.Side(ts.Side),
+				Units:       ts.Units,
+				OpenPrice:   ts.OpenPrice,
+				OpenTime:    timestamp(ts.OpenTime),
+				ChargedFees: ts.ChargedFees,
+				Fees:        breakdown(ts.Fees),
+				StopLoss:    ts.StopLoss,
+				Guaranteed:  ts.Guaranteed,
+				TakeProfit:  ts.TakeProfit,
+				Expiry:      timestamp(ts.Expiry),
+				Venue:       ts.Venue,
+				Tag:         ts.Tag,
+			})
+		}
+
+		m.Instruments = append(m.Instruments, instrument)
+	}
+
+	for _, t := range s.Transactions {
+		m.Transactions = append(m.Transactions, Transaction(t))
+	}
+
+	return m
+}
+
+// FromAccountState returns the account snapshot of a message.
+func FromAccountState(m *pb.AccountState) *gotrader.Snapshot {
+
+	s := &gotrader.Snapshot{
+		Version:        int(m.Version),
+		Time:           asTime(m.Time),
+		AccountID:      m.AccountId,
+		HomeCurrency:   m.HomeCurrency,
+		Balance:        m.Balance,
+		Leverage:       m.Leverage,
+		OpeningBalance: m.OpeningBalance,
+		Instruments:    make([]*gotrader.InstrumentSnapshot, 0, len(m.Instruments)),
+		Transactions:   make([]*gotrader.Transaction, 0, len(m.Transactions)),
+		WALSequence:    m.WalSequence,
+	}
+
+	for _, instrument := range m.Instruments {
+
+		is := &gotrader.InstrumentSnapshot{
+			Name:                instrument.Name,
+			BaseCurrency:        instrument.BaseCurrency,
+			QuoteCurrency:       instrument.QuoteCurrency,
+			Leverage:            instrument.Leverage,
+			PipLocation:         int(instrument.PipLocation),
+			Hedge:               gotrader.Hedge(instrument.Hedge),
+			Bid:                 instrument.Bid,
+			Ask:                 instrument.Ask,
+			BaseConversionRate:  instrument.BaseConversionRate,
+			QuoteConversionRate: instrument.QuoteConversionRate,
+			LastUpdate:          asTime(instrument.LastUpdate),
+			Trades:              make([]*gotrader.TradeSnapshot, 0, len(instrument.Trades)),
+		}
+
+		for _, ts := range instrument.Trades {
+			is.Trades = append(is.Trades, &gotrader.TradeSnapshot{
+				ID:          ts.Id,
+				Side:        gotrader.Side(ts.Side),
+				Units:       ts.Units,
+				OpenPrice:   ts.OpenPrice,
+				OpenTime:    asTime(ts.OpenTime),
+				ChargedFees: ts.ChargedFees,
+				Fees:        asFees(ts.Fees),
+				StopLoss:    ts.StopLoss,
+				Guaranteed:  ts.Guaranteed,
+				TakeProfit:  ts.TakeProfit,
+				Expiry:      asTime(ts.Expiry),
+				Venue:       ts.Venue,
+				Tag:         ts.Tag,
+			})
+		}
+
+		s.Instruments = append(s.Instruments, is)
+	}
+
+	for _, t := range m.Transactions {
+		s.Transactions = append(s.Transactions, FromTransaction(t))
+	}
+
+	return s
+}
+
+// Event returns the message of an account event, nil for an unknown event type.
+func Event(e gotrader.Event) *pb.Event {
+
+	switch e := e.(type) {
+	case gotrader.TradeOpened:
+		return &pb.Event{Event: &pb.Event_TradeOpened{TradeOpened: &pb.TradeOpened{
+			Time: timestamp(e.Time), Trade: Trade(e.Trade),
+		}}}
+	case gotrader.TradeClosed:
+		return &pb.Event{Event: &pb.Event_TradeClosed{TradeClosed: &pb.TradeClosed{
+			Time: timestamp(e.Time), Fill: Fill(e.Fill),
+		}}}
+	case gotrader.OrderFilled:
+		return &pb.Event{Event: &pb.Event_OrderFilled{OrderFilled: &pb.OrderFilled{
+			Time: timestamp(e.Time), Fill: Fill(e.Fill),
+		}}}
+	case gotrader.MarginCall:
+		return &pb.Event{Event: &pb.Event_MarginCall{MarginCall: &pb.MarginCall{
+			Time: timestamp(e.Time), Equity: e.Equity, MarginUsed: e.MarginUsed, MarginLevel: e.MarginLevel,
+		}}}
+	case gotrader.PriceStale:
+		return &pb.Event{Event: &pb.Event_PriceStale{PriceStale: &pb.PriceStale{
+			Time: timestamp(e.Time), Instrument: e.Instrument, LastUpdate: timestamp(e.LastUpdate),
+		}}}
+	case gotrader.SessionClose:
+		return &pb.Event{Event: &pb.Event_SessionClose{SessionClose: &pb.SessionClose{
+			Time: timestamp(e.Time),
+		}}}
+	case gotrader.OrderSubmitted:
+		return &pb.Event{Event: &pb.Event_OrderSubmitted{OrderSubmitted: &pb.OrderSubmitted{
+			Time: timestamp(e.Time), Order: Order(e.Order),
+		}}}
+	case gotrader.TransactionRecorded:
+		return &pb.Event{Event: &pb.Event_TransactionRecorded{TransactionRecorded: &pb.TransactionRecorded{
+			Time: timestamp(e.Time), Transaction: Transaction(e.Transaction),
+		}}}
+	}
+
+	return nil
+}
+
+/**************************
+*
+*	Encoding
+*
+***************************/
+
+// MarshalTick encodes a tick.
+func MarshalTick(t *gotrader.Tick) ([]byte, error) {
+	return proto.Marshal(Tick(t))
+}
+
+// UnmarshalTick decodes a tick.
+func UnmarshalTick(data []byte) (*gotrader.Tick, error) {
+
+	m := &pb.Tick{}
+	if err := proto.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	return FromTick(m), nil
+}
+
+// MarshalSnapshot encodes an account snapshot.
+func MarshalSnapshot(s *gotrader.Snapshot) ([]byte, error) {
+	return proto.Marshal(AccountState(s))
+}
+
+// UnmarshalSnapshot decodes an account snapshot, rejecting the versions other than gotrader.SnapshotVersion.
+func UnmarshalSnapshot(data []byte) (*gotrader.Snapshot, error) {
+
+	m := &pb.AccountState{}
+	if err := proto.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	if m.Version != gotrader.SnapshotVersion {
+		return nil, errors.New("unsupported snapshot version " + strconv.Itoa(int(m.Version)))
+	}
+
+	return FromAccountState(m), nil
+}
+
+// MarshalEvent encodes an account event.
+func MarshalEvent(e gotrader.Event) ([]byte, error) {
+
+	m := Event(e)
+	if m == nil {
+		return nil, errors.New("unknown event type " + e.Type().String())
+	}
+
+	return proto.Marshal(m)
+}
+
+// UnmarshalEvent decodes an account event message.
+func UnmarshalEvent(data []byte) (*pb.Event, error) {
+
+	m := &pb.Event{}
+	if err := proto.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
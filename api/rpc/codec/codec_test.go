@@ -0,0 +1,137 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/api/delta"
+	"github.com/luismcruz/gotrader/api/rpc/pb"
+)
+
+func TestCodec(t *testing.T) {
+
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	t.Run("ticks round trip", func(t *testing.T) {
+
+		tick := &gotrader.Tick{Instrument: "EUR_USD", Bid: 1.1, Ask: 1.1002, BidSize: 1e6, Time: now}
+
+		data, err := MarshalTick(tick)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := UnmarshalTick(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(decoded, tick) {
+			t.Errorf("expected %+v, got %+v", tick, decoded)
+		}
+	})
+
+	t.Run("snapshots round trip", func(t *testing.T) {
+
+		snapshot := &gotrader.Snapshot{
+			Version:      gotrader.SnapshotVersion,
+			Time:         now,
+			AccountID:    "account",
+			HomeCurrency: "USD",
+			Balance:      1000,
+			Leverage:     30,
+			Instruments: []*gotrader.InstrumentSnapshot{{
+				Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4,
+				Hedge: gotrader.NoHedge, Bid: 1.1, Ask: 1.1002, QuoteConversionRate: 1, LastUpdate: now,
+				Trades: []*gotrader.TradeSnapshot{{ID: "1", Side: gotrader.Long, Units: 100, OpenPrice: 1.09, OpenTime: now,
+					ChargedFees: -1.5, Fees: gotrader.Fees{Commission: -1, Financing: -0.5, Markup: -0.2},
+					StopLoss: 1.08, Guaranteed: true, Expiry: now.Add(time.Hour), Tag: "a"}},
+			}},
+			Transactions: []*gotrader.Transaction{
+				{Type: gotrader.FundsTransferTransaction, Amount: 1000, Balance: 1000, Time: now},
+				{Type: gotrader.TradeCloseTransaction, TradeID: "2", Instrument: "EUR_USD", Side: gotrader.Short, Units: 100,
+					OpenPrice: 1.1, ClosePrice: 1.09, OpenTime: now.Add(-time.Hour), Amount: 1, Balance: 1001, Time: now,
+					SpreadCost: 0.02},
+			},
+			WALSequence: 7,
+		}
+
+		data, err := MarshalSnapshot(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := UnmarshalSnapshot(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(decoded, snapshot) {
+			t.Errorf("expected %+v, got %+v", snapshot, decoded)
+		}
+	})
+
+	t.Run("close reasons are mapped", func(t *testing.T) {
+
+		for _, reason := range []gotrader.CloseReason{gotrader.CloseRequested, gotrader.Flattened, gotrader.RolledBack,
+			gotrader.SpecAdjusted} {
+
+			m := Transaction(&gotrader.Transaction{Type: gotrader.TradeCloseTransaction, Reason: reason})
+			if m.Reason.String() != reason.String() {
+				t.Errorf("expected %s, got %s", reason, m.Reason)
+			}
+
+			if decoded := FromTransaction(m); decoded.Reason != reason {
+				t.Errorf("expected %s, got %s", reason, decoded.Reason)
+			}
+		}
+	})
+
+	t.Run("transaction types are mapped", func(t *testing.T) {
+
+		for _, kind := range []gotrader.TransactionType{gotrader.TradeCloseTransaction, gotrader.DividendTransaction,
+			gotrader.ManagementFeeTransaction, gotrader.PerformanceFeeTransaction} {
+
+			m := Transaction(&gotrader.Transaction{Type: kind, Amount: -1})
+			if m.Type.String() != kind.String() {
+				t.Errorf("expected %s, got %s", kind, m.Type)
+			}
+
+			if decoded := FromTransaction(m); decoded.Type != kind {
+				t.Errorf("expected %s, got %s", kind, decoded.Type)
+			}
+		}
+	})
+
+	t.Run("state deltas are converted", func(t *testing.T) {
+
+		m := Delta(delta.Delta{Version: 3, Base: 2, Time: now,
+			Changed: []delta.Trade{{ID: "1", Instrument: "EUR_USD", Side: "SHORT", Units: 100, OpenTime: now}},
+			Removed: []delta.TradeRef{{ID: "2", Instrument: "EUR_USD"}}})
+
+		if m.Account != nil || len(m.Changed) != 1 || m.Changed[0].Side != pb.Side_SHORT ||
+			len(m.Removed) != 1 || m.Removed[0].Id != "2" {
+			t.Errorf("unexpected delta %v", m)
+		}
+	})
+
+	t.Run("events are encoded", func(t *testing.T) {
+
+		data, err := MarshalEvent(gotrader.PriceStale{Time: now, Instrument: "EUR_USD", LastUpdate: now.Add(-time.Minute)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		event, err := UnmarshalEvent(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stale := event.GetPriceStale()
+		if stale == nil || stale.Instrument != "EUR_USD" || !stale.LastUpdate.AsTime().Equal(now.Add(-time.Minute)) {
+			t.Errorf("unexpected event %v", event)
+		}
+	})
+}
@@ -0,0 +1,642 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: gotrader.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Trader_StreamPrices_FullMethodName    = "/gotrader.v1.Trader/StreamPrices"
+	Trader_StreamTrades_FullMethodName    = "/gotrader.v1.Trader/StreamTrades"
+	Trader_StreamPositions_FullMethodName = "/gotrader.v1.Trader/StreamPositions"
+	Trader_StreamAccount_FullMethodName   = "/gotrader.v1.Trader/StreamAccount"
+	Trader_StreamFills_FullMethodName     = "/gotrader.v1.Trader/StreamFills"
+	Trader_StreamState_FullMethodName     = "/gotrader.v1.Trader/StreamState"
+	Trader_Buy_FullMethodName             = "/gotrader.v1.Trader/Buy"
+	Trader_Sell_FullMethodName            = "/gotrader.v1.Trader/Sell"
+	Trader_CloseTrade_FullMethodName      = "/gotrader.v1.Trader/CloseTrade"
+	Trader_SubmitOrder_FullMethodName     = "/gotrader.v1.Trader/SubmitOrder"
+	Trader_CancelOrder_FullMethodName     = "/gotrader.v1.Trader/CancelOrder"
+)
+
+// TraderClient is the client API for Trader service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TraderClient interface {
+	StreamPrices(ctx context.Context, in *PricesRequest, opts ...grpc.CallOption) (Trader_StreamPricesClient, error)
+	StreamTrades(ctx context.Context, in *TradesRequest, opts ...grpc.CallOption) (Trader_StreamTradesClient, error)
+	StreamPositions(ctx context.Context, in *PositionsRequest, opts ...grpc.CallOption) (Trader_StreamPositionsClient, error)
+	StreamAccount(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (Trader_StreamAccountClient, error)
+	StreamFills(ctx context.Context, in *FillsRequest, opts ...grpc.CallOption) (Trader_StreamFillsClient, error)
+	StreamState(ctx context.Context, in *StateRequest, opts ...grpc.CallOption) (Trader_StreamStateClient, error)
+	Buy(ctx context.Context, in *MarketOrderRequest, opts ...grpc.CallOption) (*OrderReply, error)
+	Sell(ctx context.Context, in *MarketOrderRequest, opts ...grpc.CallOption) (*OrderReply, error)
+	CloseTrade(ctx context.Context, in *CloseTradeRequest, opts ...grpc.CallOption) (*OrderReply, error)
+	SubmitOrder(ctx context.Context, in *Order, opts ...grpc.CallOption) (*OrderReply, error)
+	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*OrderReply, error)
+}
+
+type traderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTraderClient(cc grpc.ClientConnInterface) TraderClient {
+	return &traderClient{cc}
+}
+
+func (c *traderClient) StreamPrices(ctx context.Context, in *PricesRequest, opts ...grpc.CallOption) (Trader_StreamPricesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Trader_ServiceDesc.Streams[0], Trader_StreamPrices_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &traderStreamPricesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Trader_StreamPricesClient interface {
+	Recv() (*Price, error)
+	grpc.ClientStream
+}
+
+type traderStreamPricesClient struct {
+	grpc.ClientStream
+}
+
+func (x *traderStreamPricesClient) Recv() (*Price, error) {
+	m := new(Price)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *traderClient) StreamTrades(ctx context.Context, in *TradesRequest, opts ...grpc.CallOption) (Trader_StreamTradesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Trader_ServiceDesc.Streams[1], Trader_StreamTrades_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &traderStreamTradesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Trader_StreamTradesClient interface {
+	Recv() (*TradesSnapshot, error)
+	grpc.ClientStream
+}
+
+type traderStreamTradesClient struct {
+	grpc.ClientStream
+}
+
+func (x *traderStreamTradesClient) Recv() (*TradesSnapshot, error) {
+	m := new(TradesSnapshot)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *traderClient) StreamPositions(ctx context.Context, in *PositionsRequest, opts ...grpc.CallOption) (Trader_StreamPositionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Trader_ServiceDesc.Streams[2], Trader_StreamPositions_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &traderStreamPositionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Trader_StreamPositionsClient interface {
+	Recv() (*PositionsSnapshot, error)
+	grpc.ClientStream
+}
+
+type traderStreamPositionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *traderStreamPositionsClient) Recv() (*PositionsSnapshot, error) {
+	m := new(PositionsSnapshot)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *traderClient) StreamAccount(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (Trader_StreamAccountClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Trader_ServiceDesc.Streams[3], Trader_StreamAccount_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &traderStreamAccountClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Trader_StreamAccountClient interface {
+	Recv() (*AccountMetrics, error)
+	grpc.ClientStream
+}
+
+type traderStreamAccountClient struct {
+	grpc.ClientStream
+}
+
+func (x *traderStreamAccountClient) Recv() (*AccountMetrics, error) {
+	m := new(AccountMetrics)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *traderClient) StreamFills(ctx context.Context, in *FillsRequest, opts ...grpc.CallOption) (Trader_StreamFillsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Trader_ServiceDesc.Streams[4], Trader_StreamFills_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &traderStreamFillsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Trader_StreamFillsClient interface {
+	Recv() (*Fill, error)
+	grpc.ClientStream
+}
+
+type traderStreamFillsClient struct {
+	grpc.ClientStream
+}
+
+func (x *traderStreamFillsClient) Recv() (*Fill, error) {
+	m := new(Fill)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *traderClient) StreamState(ctx context.Context, in *StateRequest, opts ...grpc.CallOption) (Trader_StreamStateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Trader_ServiceDesc.Streams[5], Trader_StreamState_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &traderStreamStateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Trader_StreamStateClient interface {
+	Recv() (*StateDelta, error)
+	grpc.ClientStream
+}
+
+type traderStreamStateClient struct {
+	grpc.ClientStream
+}
+
+func (x *traderStreamStateClient) Recv() (*StateDelta, error) {
+	m := new(StateDelta)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *traderClient) Buy(ctx context.Context, in *MarketOrderRequest, opts ...grpc.CallOption) (*OrderReply, error) {
+	out := new(OrderReply)
+	err := c.cc.Invoke(ctx, Trader_Buy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *traderClient) Sell(ctx context.Context, in *MarketOrderRequest, opts ...grpc.CallOption) (*OrderReply, error) {
+	out := new(OrderReply)
+	err := c.cc.Invoke(ctx, Trader_Sell_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *traderClient) CloseTrade(ctx context.Context, in *CloseTradeRequest, opts ...grpc.CallOption) (*OrderReply, error) {
+	out := new(OrderReply)
+	err := c.cc.Invoke(ctx, Trader_CloseTrade_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *traderClient) SubmitOrder(ctx context.Context, in *Order, opts ...grpc.CallOption) (*OrderReply, error) {
+	out := new(OrderReply)
+	err := c.cc.Invoke(ctx, Trader_SubmitOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *traderClient) CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*OrderReply, error) {
+	out := new(OrderReply)
+	err := c.cc.Invoke(ctx, Trader_CancelOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TraderServer is the server API for Trader service.
+// All implementations must embed UnimplementedTraderServer
+// for forward compatibility
+type TraderServer interface {
+	StreamPrices(*PricesRequest, Trader_StreamPricesServer) error
+	StreamTrades(*TradesRequest, Trader_StreamTradesServer) error
+	StreamPositions(*PositionsRequest, Trader_StreamPositionsServer) error
+	StreamAccount(*AccountRequest, Trader_StreamAccountServer) error
+	StreamFills(*FillsRequest, Trader_StreamFillsServer) error
+	StreamState(*StateRequest, Trader_StreamStateServer) error
+	Buy(context.Context, *MarketOrderRequest) (*OrderReply, error)
+	Sell(context.Context, *MarketOrderRequest) (*OrderReply, error)
+	CloseTrade(context.Context, *CloseTradeRequest) (*OrderReply, error)
+	SubmitOrder(context.Context, *Order) (*OrderReply, error)
+	CancelOrder(context.Context, *CancelOrderRequest) (*OrderReply, error)
+	mustEmbedUnimplementedTraderServer()
+}
+
+// UnimplementedTraderServer must be embedded to have forward compatible implementations.
+type UnimplementedTraderServer struct {
+}
+
+func (UnimplementedTraderServer) StreamPrices(*PricesRequest, Trader_StreamPricesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPrices not implemented")
+}
+func (UnimplementedTraderServer) StreamTrades(*TradesRequest, Trader_StreamTradesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTrades not implemented")
+}
+func (UnimplementedTraderServer) StreamPositions(*PositionsRequest, Trader_StreamPositionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPositions not implemented")
+}
+func (UnimplementedTraderServer) StreamAccount(*AccountRequest, Trader_StreamAccountServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamAccount not implemented")
+}
+func (UnimplementedTraderServer) StreamFills(*FillsRequest, Trader_StreamFillsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamFills not implemented")
+}
+func (UnimplementedTraderServer) StreamState(*StateRequest, Trader_StreamStateServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamState not implemented")
+}
+func (UnimplementedTraderServer) Buy(context.Context, *MarketOrderRequest) (*OrderReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Buy not implemented")
+}
+func (UnimplementedTraderServer) Sell(context.Context, *MarketOrderRequest) (*OrderReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sell not implemented")
+}
+func (UnimplementedTraderServer) CloseTrade(context.Context, *CloseTradeRequest) (*OrderReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseTrade not implemented")
+}
+func (UnimplementedTraderServer) SubmitOrder(context.Context, *Order) (*OrderReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitOrder not implemented")
+}
+func (UnimplementedTraderServer) CancelOrder(context.Context, *CancelOrderRequest) (*OrderReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedTraderServer) mustEmbedUnimplementedTraderServer() {}
+
+// UnsafeTraderServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TraderServer will
+// result in compilation errors.
+type UnsafeTraderServer interface {
+	mustEmbedUnimplementedTraderServer()
+}
+
+func RegisterTraderServer(s grpc.ServiceRegistrar, srv TraderServer) {
+	s.RegisterService(&Trader_ServiceDesc, srv)
+}
+
+func _Trader_StreamPrices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PricesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TraderServer).StreamPrices(m, &traderStreamPricesServer{stream})
+}
+
+type Trader_StreamPricesServer interface {
+	Send(*Price) error
+	grpc.ServerStream
+}
+
+type traderStreamPricesServer struct {
+	grpc.ServerStream
+}
+
+func (x *traderStreamPricesServer) Send(m *Price) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Trader_StreamTrades_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TradesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TraderServer).StreamTrades(m, &traderStreamTradesServer{stream})
+}
+
+type Trader_StreamTradesServer interface {
+	Send(*TradesSnapshot) error
+	grpc.ServerStream
+}
+
+type traderStreamTradesServer struct {
+	grpc.ServerStream
+}
+
+func (x *traderStreamTradesServer) Send(m *TradesSnapshot) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Trader_StreamPositions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(PositionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TraderServer).StreamPositions(m, &traderStreamPositionsServer{stream})
+}
+
+type Trader_StreamPositionsServer interface {
+	Send(*PositionsSnapshot) error
+	grpc.ServerStream
+}
+
+type traderStreamPositionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *traderStreamPositionsServer) Send(m *PositionsSnapshot) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Trader_StreamAccount_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AccountRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TraderServer).StreamAccount(m, &traderStreamAccountServer{stream})
+}
+
+type Trader_StreamAccountServer interface {
+	Send(*AccountMetrics) error
+	grpc.ServerStream
+}
+
+type traderStreamAccountServer struct {
+	grpc.ServerStream
+}
+
+func (x *traderStreamAccountServer) Send(m *AccountMetrics) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Trader_StreamFills_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FillsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TraderServer).StreamFills(m, &traderStreamFillsServer{stream})
+}
+
+type Trader_StreamFillsServer interface {
+	Send(*Fill) error
+	grpc.ServerStream
+}
+
+type traderStreamFillsServer struct {
+	grpc.ServerStream
+}
+
+func (x *traderStreamFillsServer) Send(m *Fill) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Trader_StreamState_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TraderServer).StreamState(m, &traderStreamStateServer{stream})
+}
+
+type Trader_StreamStateServer interface {
+	Send(*StateDelta) error
+	grpc.ServerStream
+}
+
+type traderStreamStateServer struct {
+	grpc.ServerStream
+}
+
+func (x *traderStreamStateServer) Send(m *StateDelta) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Trader_Buy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarketOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TraderServer).Buy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trader_Buy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TraderServer).Buy(ctx, req.(*MarketOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trader_Sell_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MarketOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TraderServer).Sell(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trader_Sell_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TraderServer).Sell(ctx, req.(*MarketOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trader_CloseTrade_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseTradeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TraderServer).CloseTrade(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trader_CloseTrade_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TraderServer).CloseTrade(ctx, req.(*CloseTradeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trader_SubmitOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Order)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TraderServer).SubmitOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trader_SubmitOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TraderServer).SubmitOrder(ctx, req.(*Order))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trader_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TraderServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trader_CancelOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TraderServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Trader_ServiceDesc is the grpc.ServiceDesc for Trader service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Trader_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gotrader.v1.Trader",
+	HandlerType: (*TraderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Buy",
+			Handler:    _Trader_Buy_Handler,
+		},
+		{
+			MethodName: "Sell",
+			Handler:    _Trader_Sell_Handler,
+		},
+		{
+			MethodName: "CloseTrade",
+			Handler:    _Trader_CloseTrade_Handler,
+		},
+		{
+			MethodName: "SubmitOrder",
+			Handler:    _Trader_SubmitOrder_Handler,
+		},
+		{
+			MethodName: "CancelOrder",
+			Handler:    _Trader_CancelOrder_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPrices",
+			Handler:       _Trader_StreamPrices_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamTrades",
+			Handler:       _Trader_StreamTrades_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamPositions",
+			Handler:       _Trader_StreamPositions_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamAccount",
+			Handler:       _Trader_StreamAccount_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamFills",
+			Handler:       _Trader_StreamFills_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamState",
+			Handler:       _Trader_StreamState_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gotrader.proto",
+}
@@ -0,0 +1,451 @@
+/*
+Package rpc exposes a gotrader session through the gRPC Trader service defined in gotrader.proto, streaming
+prices, open trades, positions, account metrics, state deltas and fills, and accepting orders. The generated code is in
+the pb package, other languages can generate their clients from the same proto file.
+*/
+package rpc
+
+//go:generate protoc --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative gotrader.proto state.proto
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/api/delta"
+	"github.com/luismcruz/gotrader/api/rpc/codec"
+	"github.com/luismcruz/gotrader/api/rpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Option represents a Server functional option
+type Option func(s *Server)
+
+// Interval is the functional option to define the default interval of the streams, defaults to 1 second.
+func Interval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.interval = interval
+	}
+}
+
+// MinInterval is the functional option to define the shortest stream interval a client can request,
+// defaults to 100 milliseconds.
+func MinInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.minInterval = interval
+	}
+}
+
+// Server implements the Trader gRPC service over a session engine.
+type Server struct {
+	pb.UnimplementedTraderServer
+	engine      gotrader.Engine
+	interval    time.Duration
+	minInterval time.Duration
+}
+
+// NewServer is the Server constructor, the engine is usually the TradingSession Engine.
+func NewServer(engine gotrader.Engine, opts ...Option) *Server {
+
+	s := &Server{
+		engine:      engine,
+		interval:    time.Second,
+		minInterval: 100 * time.Millisecond,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (s *Server) account() (*gotrader.Account, error) {
+
+	if s.engine == nil || s.engine.Account() == nil {
+		return nil, status.Error(codes.Unavailable, "session has not started")
+	}
+
+	return s.engine.Account(), nil
+}
+
+// instruments returns the requested instruments sorted by name, every instrument if none is requested.
+func (s *Server) instruments(account *gotrader.Account, names []string) ([]*gotrader.Instrument, error) {
+
+	instruments := make([]*gotrader.Instrument, 0)
+
+	if len(names) == 0 {
+		for _, inst := range account.Instruments() {
+			instruments = append(instruments, inst)
+		}
+	} else {
+		for _, name := range names {
+			inst := account.Instrument(name)
+			if inst == nil {
+				return nil, status.Error(codes.NotFound, "instrument "+name+" is not being traded")
+			}
+			instruments = append(instruments, inst)
+		}
+	}
+
+	sort.Slice(instruments, func(i, j int) bool {
+		return instruments[i].Name() < instruments[j].Name()
+	})
+
+	return instruments, nil
+}
+
+// stream calls send at the requested interval until the client cancels or send fails.
+func (s *Server) stream(ctx context.Context, intervalMs int64, send func(account *gotrader.Account) error) error {
+
+	interval := s.interval
+	if intervalMs > 0 {
+		interval = time.Duration(intervalMs) * time.Millisecond
+	}
+
+	if interval < s.minInterval {
+		interval = s.minInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+
+		account, err := s.account()
+		if err != nil {
+			return err
+		}
+
+		if err := send(account); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// engineError returns the status of an engine mutation error.
+func engineError(err error) error {
+
+	switch {
+	case errors.Is(err, gotrader.ErrInstrumentNotTraded), errors.Is(err, gotrader.ErrTradeNotFound),
+		errors.Is(err, gotrader.ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, gotrader.ErrInsufficientMargin):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	return status.Error(codes.FailedPrecondition, err.Error())
+}
+
+func (s *Server) validate(instrument string, units int32) error {
+
+	account, err := s.account()
+	if err != nil {
+		return err
+	}
+
+	if account.Instrument(instrument) == nil {
+		return status.Error(codes.NotFound, "instrument "+instrument+" is not being traded")
+	}
+
+	if units <= 0 {
+		return status.Error(codes.InvalidArgument, "units must be positive")
+	}
+
+	return nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Register registers the Trader service on a gRPC server, which defines the transport security and authentication.
+func (s *Server) Register(server *grpc.Server) {
+	pb.RegisterTraderServer(server, s)
+}
+
+// StreamPrices sends the prices of the instruments when they change.
+func (s *Server) StreamPrices(req *pb.PricesRequest, stream pb.Trader_StreamPricesServer) error {
+
+	last := make(map[string][2]float64)
+
+	return s.stream(stream.Context(), req.IntervalMs, func(account *gotrader.Account) error {
+
+		instruments, err := s.instruments(account, req.Instruments)
+		if err != nil {
+			return err
+		}
+
+		for _, inst := range instruments {
+
+			price := [2]float64{inst.Bid(), inst.Ask()}
+			if price == last[inst.Name()] {
+				continue
+			}
+			last[inst.Name()] = price
+
+			err := stream.Send(&pb.Price{
+				Instrument: inst.Name(),
+				Bid:        price[0],
+				Ask:        price[1],
+				Time:       timestamppb.New(account.Time()),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// StreamTrades sends snapshots of the open trades, in open time order per instrument.
+func (s *Server) StreamTrades(req *pb.TradesRequest, stream pb.Trader_StreamTradesServer) error {
+
+	return s.stream(stream.Context(), req.IntervalMs, func(account *gotrader.Account) error {
+
+		instruments, err := s.instruments(account, req.Instruments)
+		if err != nil {
+			return err
+		}
+
+		snapshot := &pb.TradesSnapshot{Time: timestamppb.New(account.Time())}
+
+		for _, inst := range instruments {
+			for trade := range inst.Trades() {
+				snapshot.Trades = append(snapshot.Trades, codec.Trade(trade))
+			}
+		}
+
+		return stream.Send(snapshot)
+	})
+}
+
+// StreamPositions sends snapshots of the long and short positions.
+func (s *Server) StreamPositions(req *pb.PositionsRequest, stream pb.Trader_StreamPositionsServer) error {
+
+	return s.stream(stream.Context(), req.IntervalMs, func(account *gotrader.Account) error {
+
+		instruments, err := s.instruments(account, req.Instruments)
+		if err != nil {
+			return err
+		}
+
+		snapshot := &pb.PositionsSnapshot{Time: timestamppb.New(account.Time())}
+
+		for _, inst := range instruments {
+			snapshot.Positions = append(snapshot.Positions,
+				codec.Position(inst.Name(), inst.LongPosition()),
+				codec.Position(inst.Name(), inst.ShortPosition()),
+			)
+		}
+
+		return stream.Send(snapshot)
+	})
+}
+
+// StreamAccount sends the account metrics.
+func (s *Server) StreamAccount(req *pb.AccountRequest, stream pb.Trader_StreamAccountServer) error {
+
+	return s.stream(stream.Context(), req.IntervalMs, func(account *gotrader.Account) error {
+		return stream.Send(&pb.AccountMetrics{
+			Id:                        account.ID(),
+			HomeCurrency:              account.HomeCurrency(),
+			Time:                      timestamppb.New(account.Time()),
+			Balance:                   account.Balance(),
+			Equity:                    account.Equity(),
+			UnrealizedNetProfit:       account.UnrealizedNetProfit(),
+			UnrealizedEffectiveProfit: account.UnrealizedEffectiveProfit(),
+			ChargedFees:               account.ChargedFees(),
+			MarginUsed:                account.MarginUsed(),
+			MarginFree:                account.MarginFree(),
+		})
+	})
+}
+
+// StreamState sends a full state of the account metrics and the open trades, then their deltas when they change,
+// see the delta package.
+func (s *Server) StreamState(req *pb.StateRequest, stream pb.Trader_StreamStateServer) error {
+
+	tracker := delta.NewTracker()
+	wanted := make(map[string]bool)
+
+	return s.stream(stream.Context(), req.IntervalMs, func(account *gotrader.Account) error {
+
+		if _, err := s.instruments(account, req.Instruments); err != nil {
+			return err
+		}
+
+		for _, name := range req.Instruments {
+			wanted[name] = true
+		}
+
+		changes, changed := tracker.Update(account)
+		if !changed {
+			return nil
+		}
+
+		if len(wanted) > 0 {
+			changes = changes.Filter(func(instrument string) bool { return wanted[instrument] })
+		}
+
+		m := codec.Delta(changes)
+		if m.Account != nil {
+			m.Account.Id, m.Account.HomeCurrency = account.ID(), account.HomeCurrency()
+		}
+
+		return stream.Send(m)
+	})
+}
+
+// StreamFills sends the order fills as they happen, including trade closes and order errors.
+func (s *Server) StreamFills(req *pb.FillsRequest, stream pb.Trader_StreamFillsServer) error {
+
+	account, err := s.account()
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range req.Instruments {
+		wanted[name] = true
+	}
+
+	fills := make(chan *gotrader.OrderFill, 100)
+
+	subscription := account.Events().Subscribe(func(event gotrader.Event) {
+
+		fill := event.(gotrader.OrderFilled).Fill
+
+		if len(wanted) == 0 || wanted[fill.Instrument.Name] {
+			select {
+			case fills <- fill:
+			case <-stream.Context().Done():
+			}
+		}
+	}, 100, gotrader.OrderFilledEvent)
+	defer subscription.Unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case fill := <-fills:
+			if err := stream.Send(codec.Fill(fill)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Buy opens a long market order, the result is delivered as a fill.
+func (s *Server) Buy(ctx context.Context, req *pb.MarketOrderRequest) (*pb.OrderReply, error) {
+
+	if err := s.validate(req.Instrument, req.Units); err != nil {
+		return nil, err
+	}
+
+	if err := s.engine.Buy(req.Instrument, req.Units); err != nil {
+		return nil, engineError(err)
+	}
+
+	return &pb.OrderReply{}, nil
+}
+
+// Sell opens a short market order, the result is delivered as a fill.
+func (s *Server) Sell(ctx context.Context, req *pb.MarketOrderRequest) (*pb.OrderReply, error) {
+
+	if err := s.validate(req.Instrument, req.Units); err != nil {
+		return nil, err
+	}
+
+	if err := s.engine.Sell(req.Instrument, req.Units); err != nil {
+		return nil, engineError(err)
+	}
+
+	return &pb.OrderReply{}, nil
+}
+
+// CloseTrade closes an open trade, the result is delivered as a fill.
+func (s *Server) CloseTrade(ctx context.Context, req *pb.CloseTradeRequest) (*pb.OrderReply, error) {
+
+	account, err := s.account()
+	if err != nil {
+		return nil, err
+	}
+
+	inst := account.Instrument(req.Instrument)
+	if inst == nil || inst.Trade(req.TradeId) == nil {
+		return nil, status.Error(codes.NotFound, "trade "+req.TradeId+" does not exist")
+	}
+
+	if err := s.engine.CloseTrade(req.Instrument, req.TradeId); err != nil {
+		return nil, engineError(err)
+	}
+
+	return &pb.OrderReply{}, nil
+}
+
+// SubmitOrder submits a market or pending order, returning its ID.
+func (s *Server) SubmitOrder(ctx context.Context, req *pb.Order) (*pb.OrderReply, error) {
+
+	if err := s.validate(req.Instrument, req.Units); err != nil {
+		return nil, err
+	}
+
+	order := &gotrader.Order{
+		Type:        gotrader.OrderType(req.Type),
+		Instrument:  req.Instrument,
+		Side:        gotrader.Side(req.Side),
+		Units:       req.Units,
+		Price:       req.Price,
+		StopLoss:    req.StopLoss,
+		TakeProfit:  req.TakeProfit,
+		TimeInForce: gotrader.TimeInForce(req.TimeInForce),
+		Tag:         req.Tag,
+	}
+
+	if req.Expiry != nil {
+		order.Expiry = req.Expiry.AsTime()
+	}
+
+	id, err := s.engine.SubmitOrder(order)
+	if err != nil {
+		return nil, engineError(err)
+	}
+
+	return &pb.OrderReply{OrderId: id}, nil
+}
+
+// CancelOrder cancels a pending order.
+func (s *Server) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.OrderReply, error) {
+
+	if _, err := s.account(); err != nil {
+		return nil, err
+	}
+
+	if err := s.engine.CancelOrder(req.OrderId); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return &pb.OrderReply{OrderId: req.OrderId}, nil
+}
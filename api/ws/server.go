@@ -0,0 +1,438 @@
+/*
+Package ws pushes the state of a gotrader session to browser dashboards over WebSocket. Clients receive the
+prices of their instruments throttled to the server interval, the trade lifecycle events and the account
+changes, as JSON messages with the notify.Payload layout:
+
+	{"type": "PRICE", "time": "...", "data": {"instrument": "EUR_USD", "bid": 1.1, "ask": 1.1002}}
+	{"type": "ACCOUNT", "time": "...", "data": {"balance": 1000, "equity": 1001.5, ...}}
+	{"type": "TRADE_OPENED" | "TRADE_CLOSED" | "ORDER_FILLED" | "MARGIN_CALL" | ..., "time": "...", "data": {...}}
+	{"type": "STATE", "time": "...", "data": {"version": 8, "base": 7, "changed": [...], "removed": [...]}}
+
+The STATE messages are the deltas of the metrics and the open trades, see the delta package. A client receives
+a full state, with a zero base, when it connects or changes its instruments, and resyncs by sending
+{"action": "resync"} when a delta does not apply to its state.
+
+The instruments are selected with the instruments query parameter (comma separated, every instrument if
+empty), and can be changed by sending {"action": "subscribe" | "unsubscribe", "instruments": [...]}.
+*/
+package ws
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/api/delta"
+	"github.com/luismcruz/gotrader/notify"
+)
+
+const (
+	// PriceMessage is the type of the price messages.
+	PriceMessage = "PRICE"
+
+	// AccountMessage is the type of the account messages.
+	AccountMessage = "ACCOUNT"
+
+	// StateMessage is the type of the state delta messages.
+	StateMessage = "STATE"
+)
+
+// Option represents a Server functional option
+type Option func(s *Server)
+
+// Interval is the functional option to define how often prices and account changes are pushed, defaults to 250ms.
+func Interval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.interval = interval
+	}
+}
+
+// CheckOrigin is the functional option to validate the origin of the connections, defaults to same origin only.
+func CheckOrigin(check func(r *http.Request) bool) Option {
+	return func(s *Server) {
+		s.upgrader.CheckOrigin = check
+	}
+}
+
+// Resolution is the functional option to round the values of the state deltas to a resolution, e.g. 0.01 for
+// cents, so the smaller profit changes of the open trades are not pushed.
+func Resolution(resolution float64) Option {
+	return func(s *Server) {
+		s.tracker = delta.NewTracker(delta.Resolution(resolution))
+	}
+}
+
+// SetLogger is the functional option to define the server logger.
+func SetLogger(logger gotrader.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+// Price is the data of the price messages.
+type Price struct {
+	Instrument string  `json:"instrument"`
+	Bid        float64 `json:"bid"`
+	Ask        float64 `json:"ask"`
+}
+
+// Account is the data of the account messages.
+type Account struct {
+	Balance             float64 `json:"balance"`
+	Equity              float64 `json:"equity"`
+	UnrealizedNetProfit float64 `json:"unrealizedNetProfit"`
+	MarginUsed          float64 `json:"marginUsed"`
+	MarginFree          float64 `json:"marginFree"`
+}
+
+// Request is a subscription change sent by a client.
+type Request struct {
+	Action      string   `json:"action"` // subscribe, unsubscribe or resync
+	Instruments []string `json:"instruments"`
+}
+
+type client struct {
+	conn        *websocket.Conn
+	send        chan *notify.Payload
+	mutex       *sync.RWMutex
+	all         bool
+	instruments map[string]bool
+	fresh       bool // connected since the last push
+	resync      bool // requested a full state
+}
+
+func (c *client) wants(instrument string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.all || c.instruments[instrument]
+}
+
+func (c *client) update(req *Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.resync = true // resyncs the trades of the instruments subscribed or requested
+	if req.Action == "resync" {
+		return
+	}
+
+	for _, inst := range req.Instruments {
+		if req.Action == "unsubscribe" {
+			delete(c.instruments, inst)
+		} else {
+			c.instruments[inst] = true
+		}
+	}
+
+	c.all = len(c.instruments) == 0 && req.Action != "unsubscribe"
+}
+
+// resynced returns whether the client requested a full state since the last push.
+func (c *client) resynced() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	resync := c.resync
+	c.resync = false
+
+	return resync
+}
+
+/*
+Server is the http.Handler of the WebSocket endpoint. The updates are computed once per interval for every
+client, and a client that can't keep up with its messages is disconnected.
+*/
+type Server struct {
+	engine       gotrader.Engine
+	interval     time.Duration
+	upgrader     websocket.Upgrader
+	mutex        *sync.RWMutex
+	clients      map[*client]bool
+	prices       map[string]Price
+	account      Account
+	tracker      *delta.Tracker
+	subscription *gotrader.Subscription
+	done         chan struct{}
+	logger       gotrader.Logger
+}
+
+// NewServer is the Server constructor, the engine is usually the TradingSession Engine.
+// The updates are pushed after Start is called.
+func NewServer(engine gotrader.Engine, opts ...Option) *Server {
+
+	s := &Server{
+		engine:   engine,
+		interval: 250 * time.Millisecond,
+		mutex:    &sync.RWMutex{},
+		clients:  make(map[*client]bool),
+		prices:   make(map[string]Price),
+		done:     make(chan struct{}),
+		tracker:  delta.NewTracker(),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if s.logger == nil {
+		s.logger = gotrader.DefaultLogger()
+	}
+
+	return s
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (s *Server) broadcast(payload *notify.Payload, instrument string) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for c := range s.clients {
+
+		if instrument != "" && !c.wants(instrument) {
+			continue
+		}
+
+		c.deliver(payload)
+	}
+}
+
+func (s *Server) onEvent(event gotrader.Event) {
+
+	instrument := ""
+
+	switch e := event.(type) {
+	case gotrader.TradeOpened:
+		instrument = e.Trade.InstrumentName()
+	case gotrader.TradeUpdated:
+		instrument = e.Trade.InstrumentName()
+	case gotrader.TradeClosed:
+		instrument = e.Fill.Instrument.Name
+	case gotrader.OrderFilled:
+		instrument = e.Fill.Instrument.Name
+	case gotrader.PriceStale:
+		instrument = e.Instrument
+	case gotrader.OrderSubmitted:
+		instrument = e.Order.Instrument
+	case gotrader.OrderExpired:
+		instrument = e.Order.Instrument
+	case gotrader.TransactionRecorded:
+		instrument = e.Transaction.Instrument
+	}
+
+	s.broadcast(notify.NewPayload(event), instrument)
+}
+
+// subscribe subscribes to the account events once the session has started.
+func (s *Server) subscribe() {
+	if s.subscription == nil && s.engine.Account() != nil {
+		s.subscription = s.engine.Account().Events().Subscribe(s.onEvent, 1000)
+	}
+}
+
+func (c *client) deliver(payload *notify.Payload) {
+	select {
+	case c.send <- payload:
+	default: // slow client, the read loop removes it once the connection is closed
+		c.conn.Close()
+	}
+}
+
+// push sends the prices, the account and the state delta if they changed since the last push, and every price,
+// the account and the full state to the clients connected or resyncing since.
+func (s *Server) push() {
+
+	account := s.engine.Account()
+	if account == nil {
+		return
+	}
+
+	s.subscribe()
+
+	names := make([]string, 0, len(account.Instruments()))
+	for name := range account.Instruments() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prices := make([]*notify.Payload, 0, len(names))
+	changed := make(map[*notify.Payload]bool)
+
+	for _, name := range names {
+
+		inst := account.Instrument(name)
+		price := Price{Instrument: name, Bid: inst.Bid(), Ask: inst.Ask()}
+		payload := &notify.Payload{Type: PriceMessage, Time: account.Time(), Data: price}
+
+		prices = append(prices, payload)
+
+		if price != s.prices[name] {
+			s.prices[name] = price
+			changed[payload] = true
+		}
+	}
+
+	state := Account{
+		Balance:             account.Balance(),
+		Equity:              account.Equity(),
+		UnrealizedNetProfit: account.UnrealizedNetProfit(),
+		MarginUsed:          account.MarginUsed(),
+		MarginFree:          account.MarginFree(),
+	}
+
+	accountChanged := state != s.account
+	s.account = state
+	accountPayload := &notify.Payload{Type: AccountMessage, Time: account.Time(), Data: state}
+
+	changes, stateChanged := s.tracker.Update(account)
+	var full *delta.Delta
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for c := range s.clients {
+
+		for _, payload := range prices {
+			if (c.fresh || changed[payload]) && c.wants(payload.Data.(Price).Instrument) {
+				c.deliver(payload)
+			}
+		}
+
+		if c.fresh || accountChanged {
+			c.deliver(accountPayload)
+		}
+
+		if c.resynced() || c.fresh {
+			if full == nil {
+				f := s.tracker.Full()
+				full = &f
+			}
+			c.deliver(&notify.Payload{Type: StateMessage, Time: full.Time, Data: full.Filter(c.wants)})
+		} else if stateChanged {
+			c.deliver(&notify.Payload{Type: StateMessage, Time: changes.Time, Data: changes.Filter(c.wants)})
+		}
+
+		c.fresh = false
+	}
+}
+
+func (s *Server) remove(c *client) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.clients[c] {
+		delete(s.clients, c)
+		close(c.send)
+	}
+}
+
+func (s *Server) readLoop(c *client) {
+
+	defer s.remove(c)
+
+	for {
+		req := &Request{}
+		if err := c.conn.ReadJSON(req); err != nil {
+			return
+		}
+		c.update(req)
+	}
+}
+
+func (s *Server) writeLoop(c *client) {
+
+	defer c.conn.Close()
+
+	for payload := range c.send {
+
+		c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+
+		if err := c.conn.WriteJSON(payload); err != nil {
+			return
+		}
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Start pushes the updates at the server interval until Stop is called.
+func (s *Server) Start() {
+
+	s.subscribe()
+
+	go func() {
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				s.push()
+			}
+		}
+	}()
+}
+
+// Stop stops the updates and disconnects the clients.
+func (s *Server) Stop() {
+
+	close(s.done)
+
+	if s.subscription != nil {
+		s.subscription.Unsubscribe()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for c := range s.clients {
+		c.conn.Close()
+	}
+}
+
+// ServeHTTP implements http.Handler, upgrading the connection to WebSocket.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.Warn(err)
+		return
+	}
+
+	c := &client{
+		conn:        conn,
+		send:        make(chan *notify.Payload, 256),
+		mutex:       &sync.RWMutex{},
+		instruments: make(map[string]bool),
+		fresh:       true,
+	}
+
+	if list := r.URL.Query().Get("instruments"); list != "" {
+		c.update(&Request{Action: "subscribe", Instruments: strings.Split(list, ",")})
+	} else {
+		c.all = true
+	}
+
+	s.mutex.Lock()
+	s.clients[c] = true
+	s.mutex.Unlock()
+
+	go s.writeLoop(c)
+	go s.readLoop(c)
+}
@@ -0,0 +1,458 @@
+package gotrader
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditEvent identifies the state transition of an order or a trade recorded by an AuditRecord.
+type AuditEvent int
+
+const (
+	AuditSubmitted AuditEvent = iota // an order is submitted
+	AuditAmended                     // a pending order is modified
+	AuditCancelled                   // a pending order is cancelled
+	AuditFilled                      // an order is filled, opening a trade
+	AuditClosed                      // a trade is closed, with its close reason
+	AuditRejected                    // an order is rejected or expires, with the error
+	AuditRetried                     // a request to the broker failed and is retried, with the error
+)
+
+func (e AuditEvent) String() string {
+	switch e {
+	case AuditSubmitted:
+		return "SUBMITTED"
+	case AuditAmended:
+		return "AMENDED"
+	case AuditCancelled:
+		return "CANCELLED"
+	case AuditFilled:
+		return "FILLED"
+	case AuditClosed:
+		return "CLOSED"
+	case AuditRejected:
+		return "REJECTED"
+	case AuditRetried:
+		return "RETRIED"
+	}
+
+	return "UNKNOWN"
+}
+
+// AuditRecord is a state transition of an order or a trade. Its hash chains the record to the previous one,
+// so a record altered, removed or reordered in the log breaks the chain.
+type AuditRecord struct {
+	Sequence   uint64
+	Time       time.Time
+	Event      AuditEvent
+	OrderID    string    `json:",omitempty"`
+	TradeID    string    `json:",omitempty"`
+	Instrument string    `json:",omitempty"`
+	Type       OrderType `json:",omitempty"`
+	Side       Side      `json:",omitempty"`
+	Units      int32     `json:",omitempty"`
+	Price      float64   `json:",omitempty"`
+	StopLoss   float64   `json:",omitempty"`
+	TakeProfit float64   `json:",omitempty"`
+	Profit     float64   `json:",omitempty"`
+	Reason     string    `json:",omitempty"` // close reason, rejection or retried error
+	Attempt    int       `json:",omitempty"` // of the retried requests, the failed one
+	Tag        string    `json:",omitempty"`
+	PrevHash   string
+	Hash       string
+}
+
+// AuditQuery selects the records of an AuditLog, zero values match every record.
+type AuditQuery struct {
+	OrderID    string
+	TradeID    string
+	Instrument string
+	Events     []AuditEvent
+	From       time.Time // inclusive
+	To         time.Time // exclusive
+}
+
+/*
+AuditLog is the append-only audit trail of the orders and trades of a session, for compliance review. Every
+submission, amendment, cancellation, fill, close and rejection is recorded with a monotonic sequence number and
+a SHA-256 hash of the record and the hash of the previous one.
+
+A log opened with OpenAuditLog is also written to a file, framed as the WAL entries and synced on every
+record; the file is verified when the log is opened, a broken chain is an error. It is safe for concurrent use.
+*/
+type AuditLog struct {
+	mutex   *sync.RWMutex
+	file    *os.File
+	records []AuditRecord
+	last    string // hash of the last record
+	err     error  // first failed write
+}
+
+// NewAuditLog returns an in-memory AuditLog, e.g. for backtests.
+func NewAuditLog() *AuditLog {
+	return &AuditLog{mutex: &sync.RWMutex{}}
+}
+
+// OpenAuditLog opens (or creates) the audit log at path, verifying the records already written.
+func OpenAuditLog(path string) (*AuditLog, error) {
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	records, size, err := readAudit(file)
+	if err == nil {
+		err = verifyAudit(records)
+	}
+
+	if err == nil {
+		err = checkTail(file, size)
+	}
+
+	if err == nil {
+		err = file.Truncate(size) // drops the torn tail
+	}
+
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+
+	a := &AuditLog{mutex: &sync.RWMutex{}, file: file, records: records}
+	if len(records) > 0 {
+		a.last = records[len(records)-1].Hash
+	}
+
+	return a, nil
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func readAudit(r io.ReadSeeker) ([]AuditRecord, int64, error) {
+
+	bodies, _, err := readFrames(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records := make([]AuditRecord, 0, len(bodies))
+
+	var size int64
+
+	for _, body := range bodies {
+
+		record := AuditRecord{}
+		if err := json.Unmarshal(body, &record); err != nil {
+			break
+		}
+
+		records = append(records, record)
+		size += int64(8 + len(body))
+	}
+
+	return records, size, nil
+}
+
+// checkTail returns an error when the log has a complete but invalid record after its valid size: unlike a record
+// torn by a crash, it was altered.
+func checkTail(file *os.File, size int64) error {
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := make([]byte, 8)
+	if _, err := file.ReadAt(header, size); err != nil {
+		return nil // torn header or end of the log
+	}
+
+	if size+8+int64(binary.BigEndian.Uint32(header[:4])) <= info.Size() {
+		return fmt.Errorf("corrupt record at byte %d", size)
+	}
+
+	return nil
+}
+
+// hashAudit returns the hash of a record, chained to the previous hash.
+func hashAudit(record AuditRecord) (string, error) {
+
+	record.Hash = ""
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body) // the record holds the previous hash
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// verifyAudit checks the sequence numbers and the hash chain of the records.
+func verifyAudit(records []AuditRecord) error {
+
+	prev := ""
+
+	for i, r := range records {
+
+		if r.Sequence != uint64(i+1) {
+			return fmt.Errorf("record %d: unexpected sequence %d", i+1, r.Sequence)
+		}
+
+		if r.PrevHash != prev {
+			return fmt.Errorf("record %d: broken chain", r.Sequence)
+		}
+
+		hash, err := hashAudit(r)
+		if err != nil {
+			return err
+		}
+
+		if hash != r.Hash {
+			return fmt.Errorf("record %d: hash mismatch", r.Sequence)
+		}
+
+		prev = r.Hash
+	}
+
+	return nil
+}
+
+// record appends a record, assigning its sequence and hashes. A nil AuditLog records nothing.
+func (a *AuditLog) record(record AuditRecord) {
+
+	if a == nil {
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if err := a.append(&record); err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+func (a *AuditLog) append(record *AuditRecord) error {
+
+	record.Sequence = uint64(len(a.records) + 1)
+	record.PrevHash = a.last
+
+	hash, err := hashAudit(*record)
+	if err != nil {
+		return err
+	}
+
+	record.Hash = hash
+
+	if a.file != nil {
+
+		body, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		if _, err := a.file.Write(frame(body)); err != nil {
+			return err
+		}
+
+		if err := a.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	a.records = append(a.records, *record)
+	a.last = hash
+
+	return nil
+}
+
+// observe records the transitions published on the event bus.
+func (a *AuditLog) observe(event Event) {
+
+	if a == nil {
+		return
+	}
+
+	switch e := event.(type) {
+	case OrderSubmitted:
+		a.order(AuditSubmitted, e.Time, e.Order)
+	case OrderFilled:
+		a.fill(e.Fill)
+	}
+}
+
+func (a *AuditLog) order(event AuditEvent, t time.Time, order *Order) {
+	a.record(AuditRecord{
+		Time:       t,
+		Event:      event,
+		OrderID:    order.ID,
+		Instrument: order.Instrument,
+		Type:       order.Type,
+		Side:       order.Side,
+		Units:      order.Units,
+		Price:      order.Price,
+		StopLoss:   order.StopLoss,
+		TakeProfit: order.TakeProfit,
+		Tag:        order.Tag,
+	})
+}
+
+func (a *AuditLog) fill(fill *OrderFill) {
+
+	record := AuditRecord{
+		Time:       fill.Time,
+		Event:      AuditFilled,
+		OrderID:    fill.OrderID,
+		TradeID:    fill.TradeID,
+		Instrument: fill.Instrument.Name,
+		Side:       fill.Side,
+		Units:      fill.Units,
+		Price:      fill.Price,
+		Tag:        fill.Tag,
+	}
+
+	switch {
+	case fill.Error != "":
+		record.Event = AuditRejected
+		record.Reason = fill.Error
+	case fill.TradeClose:
+		record.Event = AuditClosed
+		record.Profit = fill.Profit
+		record.Reason = fill.Reason.String()
+	}
+
+	a.record(record)
+}
+
+// amended records the modification of a pending order.
+func (a *AuditLog) amended(t time.Time, id string, order *Order) {
+
+	if a == nil {
+		return
+	}
+
+	amended := *order
+	amended.ID = id
+	a.order(AuditAmended, t, &amended)
+}
+
+// cancelled records the cancellation of a pending order.
+func (a *AuditLog) cancelled(t time.Time, id string) {
+	a.record(AuditRecord{Time: t, Event: AuditCancelled, OrderID: id})
+}
+
+// expired records the expiry of a pending order without fill, the backtests record the one of their rejection.
+func (a *AuditLog) expired(t time.Time, order *Order, reason string) {
+	a.record(AuditRecord{Time: t, Event: AuditRejected, OrderID: order.ID, Instrument: order.Instrument,
+		Reason: reason, Tag: order.Tag})
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Records returns the records matching the query, by sequence.
+func (a *AuditLog) Records(query AuditQuery) []AuditRecord {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	events := make(map[AuditEvent]bool, len(query.Events))
+	for _, e := range query.Events {
+		events[e] = true
+	}
+
+	records := make([]AuditRecord, 0)
+
+	for _, r := range a.records {
+
+		switch {
+		case query.OrderID != "" && r.OrderID != query.OrderID,
+			query.TradeID != "" && r.TradeID != query.TradeID,
+			query.Instrument != "" && r.Instrument != query.Instrument,
+			len(events) > 0 && !events[r.Event],
+			!query.From.IsZero() && r.Time.Before(query.From),
+			!query.To.IsZero() && !r.Time.Before(query.To):
+			continue
+		}
+
+		records = append(records, r)
+	}
+
+	return records
+}
+
+// Verify checks the hash chain of the log, reading its file again when there is one, so the records altered on
+// disk are detected.
+func (a *AuditLog) Verify() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	records := a.records
+
+	if a.file != nil {
+
+		var err error
+
+		records, _, err = readAudit(a.file)
+		if err != nil {
+			return err
+		}
+
+		if _, err := a.file.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+
+		if len(records) != len(a.records) {
+			return fmt.Errorf("%d records on disk, %d recorded", len(records), len(a.records))
+		}
+	}
+
+	if err := verifyAudit(records); err != nil {
+		return err
+	}
+
+	if len(records) > 0 && records[len(records)-1].Hash != a.last {
+		return fmt.Errorf("record %d: hash mismatch", records[len(records)-1].Sequence)
+	}
+
+	return nil
+}
+
+// Err returns the first error writing a record to the file, the records failing to be written are dropped.
+func (a *AuditLog) Err() error {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.err
+}
+
+// Close closes the file of the log.
+func (a *AuditLog) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+
+	return a.file.Close()
+}
@@ -0,0 +1,199 @@
+package gotrader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	submitted := &Order{ID: "1", Type: LimitOrder, Instrument: "EUR_USD", Side: Long, Units: 1000, Price: 1.1}
+
+	write := func(a *AuditLog) {
+		a.order(AuditSubmitted, now, submitted)
+		a.amended(now.Add(time.Second), "1", &Order{Type: LimitOrder, Instrument: "EUR_USD", Units: 1000, Price: 1.09})
+		a.cancelled(now.Add(2*time.Second), "1")
+	}
+
+	open := func(t *testing.T, path string) *AuditLog {
+		t.Helper()
+		a, err := OpenAuditLog(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { a.Close() })
+		return a
+	}
+
+	t.Run("records are chained", func(t *testing.T) {
+
+		a := NewAuditLog()
+		write(a)
+
+		if err := a.Verify(); err != nil {
+			t.Fatal(err)
+		}
+
+		records := a.Records(AuditQuery{})
+		if len(records) != 3 {
+			t.Fatalf("expected 3 records, got %d", len(records))
+		}
+
+		for i, r := range records {
+			if r.Sequence != uint64(i+1) || r.Hash == "" || (i > 0 && r.PrevHash != records[i-1].Hash) {
+				t.Errorf("record %d is not chained: %+v", i+1, r)
+			}
+		}
+
+		if amended := a.Records(AuditQuery{Events: []AuditEvent{AuditAmended}}); len(amended) != 1 ||
+			amended[0].OrderID != "1" || amended[0].Price != 1.09 {
+			t.Errorf("expected the amendment of the order, got %+v", amended)
+		}
+	})
+
+	t.Run("files are verified and appended when opened again", func(t *testing.T) {
+
+		path := filepath.Join(t.TempDir(), "audit.log")
+
+		a := open(t, path)
+		write(a)
+		a.Close()
+
+		a = open(t, path)
+		if err := a.Verify(); err != nil {
+			t.Fatal(err)
+		}
+
+		a.cancelled(now.Add(time.Minute), "2")
+
+		if err := a.Verify(); err != nil || a.Err() != nil {
+			t.Fatalf("expected the chain to continue, got %v, %v", err, a.Err())
+		}
+
+		if records := a.Records(AuditQuery{}); len(records) != 4 || records[3].Sequence != 4 ||
+			records[3].PrevHash != records[2].Hash {
+			t.Errorf("expected the record appended to the chain, got %+v", records)
+		}
+	})
+
+	t.Run("altered records are detected", func(t *testing.T) {
+
+		a := NewAuditLog()
+		write(a)
+		a.records[1].Price = 1.2
+
+		if err := a.Verify(); err == nil || !strings.Contains(err.Error(), "record 2: hash mismatch") {
+			t.Errorf("expected the altered record, got %v", err)
+		}
+
+		a = NewAuditLog()
+		write(a)
+		a.records = append(a.records[:1], a.records[2:]...)
+
+		if err := a.Verify(); err == nil {
+			t.Error("expected the removed record to break the chain")
+		}
+	})
+
+	t.Run("altered files are rejected", func(t *testing.T) {
+
+		path := filepath.Join(t.TempDir(), "audit.log")
+
+		a := open(t, path)
+		write(a)
+
+		records := a.Records(AuditQuery{})
+		records[1].Units = 100000 // a valid frame of an altered record
+
+		var data []byte
+		for _, r := range records {
+			body, _ := json.Marshal(r)
+			data = append(data, frame(body)...)
+		}
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := a.Verify(); err == nil || !strings.Contains(err.Error(), "hash mismatch") {
+			t.Errorf("expected the altered file to fail the verification, got %v", err)
+		}
+
+		a.Close()
+
+		if _, err := OpenAuditLog(path); err == nil || !strings.Contains(err.Error(), "record 2: hash mismatch") {
+			t.Errorf("expected the altered file to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("corrupt records before the tail are rejected", func(t *testing.T) {
+
+		path := filepath.Join(t.TempDir(), "audit.log")
+
+		a := open(t, path)
+		write(a)
+		a.Close()
+
+		data, _ := os.ReadFile(path)
+		data[12] ^= 0xff // the body of the first record, its checksum fails
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := OpenAuditLog(path); err == nil || !strings.Contains(err.Error(), "corrupt record at byte 0") {
+			t.Errorf("expected the corrupt record to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("torn records are dropped", func(t *testing.T) {
+
+		tests := []struct {
+			name     string
+			tear     func(data []byte) []byte
+			expected int // records recovered
+		}{
+			{"torn body", func(data []byte) []byte { return data[:len(data)-5] }, 2},
+			{"torn header", func(data []byte) []byte { return append(data, 0, 0, 1) }, 3},
+		}
+
+		for _, test := range tests {
+
+			path := filepath.Join(t.TempDir(), "audit.log")
+
+			a := open(t, path)
+			write(a)
+			a.Close()
+
+			data, _ := os.ReadFile(path)
+			if err := os.WriteFile(path, test.tear(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			a = open(t, path)
+			if records := a.Records(AuditQuery{}); len(records) != test.expected {
+				t.Fatalf("%s: expected %d records, got %d", test.name, test.expected, len(records))
+			}
+
+			a.cancelled(now.Add(time.Minute), "2")
+
+			if err := a.Verify(); err != nil || a.Err() != nil {
+				t.Errorf("%s: expected the log to be recovered, got %v, %v", test.name, err, a.Err())
+			}
+
+			a.Close()
+
+			if a, err := OpenAuditLog(path); err != nil || len(a.Records(AuditQuery{})) != test.expected+1 {
+				t.Errorf("%s: expected the recovered log to be opened again, got %v", test.name, err)
+			} else {
+				a.Close()
+			}
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package backtest
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/report"
+)
+
+// Parameters is a set of named strategy parameters.
+type Parameters map[string]float64
+
+func (p Parameters) String() string {
+
+	keys := make([]string, 0, len(p))
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + "=" + strconv.FormatFloat(p[k], 'g', -1, 64)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// StrategyFactory builds a new strategy instance configured with the given parameters.
+type StrategyFactory func(params Parameters) gotrader.Strategy
+
+// ClientFactory builds a backtest client that replays the market data between from and to.
+type ClientFactory func(from, to time.Time) gotrader.BrokerClient
+
+// Objective scores a backtest report, higher scores are better.
+type Objective func(r *report.Report) float64
+
+// NetProfit ranks results by realized net profit.
+func NetProfit(r *report.Report) float64 {
+	return r.Summary.NetProfit
+}
+
+// Sharpe ranks results by sharpe ratio.
+func Sharpe(r *report.Report) float64 {
+	return r.Summary.Sharpe
+}
+
+// MaxDrawdown ranks results by the smallest maximum drawdown.
+func MaxDrawdown(r *report.Report) float64 {
+	return -r.Summary.MaxDrawdown
+}
+
+/*
+Config holds everything needed to run a backtest besides the parameters and the period.
+
+The Seed is recorded in the report next to the run fingerprint, the factories should seed every stochastic
+component from it (e.g. btrand.Seed and gotrader.NewRandomSlippage) so runs with the same seed, parameters and
+period have bit-identical results and fingerprints.
+*/
+type Config struct {
+	Options  []gotrader.Option // session options, e.g. instruments, initial balance and home currency
+	Client   ClientFactory
+	Strategy StrategyFactory
+	Seed     int64
+}
+
+// Result is the outcome of a single backtest run.
+type Result struct {
+	Parameters Parameters
+	From       time.Time
+	To         time.Time
+	Account    *gotrader.Account
+	Report     *report.Report
+	Score      float64
+}
+
+// Run executes a backtest of the period [from, to) with the given parameters.
+func Run(cfg *Config, params Parameters, from, to time.Time) (*Result, error) {
+
+	if cfg == nil || cfg.Client == nil || cfg.Strategy == nil {
+		return nil, errors.New("backtest config requires a client and a strategy factory")
+	}
+
+	session := gotrader.NewTradingSession(cfg.Options...)
+	session.SetStrategy(cfg.Strategy(params)).SetClient(cfg.Client(from, to)).Backtest()
+
+	if err := session.Start(); err != nil {
+		return nil, err
+	}
+
+	r := report.New(session.Account())
+	r.Seed = cfg.Seed
+
+	return &Result{
+		Parameters: params,
+		From:       from,
+		To:         to,
+		Account:    session.Account(),
+		Report:     r,
+	}, nil
+}
@@ -0,0 +1,160 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/clients/btrand"
+)
+
+// alternate opens a trade every few ticks and closes the oldest one once a few are open.
+type alternate struct {
+	engine gotrader.Engine
+	ticks  int
+}
+
+func (s *alternate) Initialize()                          {}
+func (s *alternate) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *alternate) OnOrderFill(fill *gotrader.OrderFill) {}
+func (s *alternate) OnStop()                              {}
+
+func (s *alternate) OnTick(tick *gotrader.Tick) {
+
+	s.ticks++
+	if s.ticks%50 != 0 {
+		return
+	}
+
+	inst := s.engine.Account().Instrument(tick.Instrument)
+
+	if inst.TradesNumber() >= 3 {
+		s.engine.CloseTrade(tick.Instrument, inst.TradeByOrder(0).ID())
+		return
+	}
+
+	if s.ticks%100 == 0 {
+		s.engine.Buy(tick.Instrument, 1000)
+	} else {
+		s.engine.Sell(tick.Instrument, 1000)
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "GBP_USD", BaseCurrency: "GBP", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	config := func(seed int64) *Config {
+		return &Config{
+			Options: []gotrader.Option{
+				gotrader.Instruments([]string{"EUR_USD", "GBP_USD"}),
+				gotrader.InitialBalance(10000),
+				gotrader.HomeCurrency("USD"),
+			},
+			Client: func(from, to time.Time) gotrader.BrokerClient {
+				return btrand.NewBTRandClient(instruments, from, to, btrand.Seed(seed))
+			},
+			Strategy: func(params Parameters) gotrader.Strategy { return &alternate{} },
+			Seed:     seed,
+		}
+	}
+
+	from := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	to := from.Add(6 * time.Hour)
+
+	run := func(seed int64) *Result {
+		result, err := Run(config(seed), nil, from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	first, second := run(42), run(42)
+
+	if first.Report.Summary.Trades == 0 {
+		t.Fatal("no trades")
+	}
+
+	if first.Report.Fingerprint != second.Report.Fingerprint {
+		t.Errorf("same seed produced different fingerprints %s and %s", first.Report.Fingerprint, second.Report.Fingerprint)
+	}
+
+	if first.Report.Seed != 42 {
+		t.Errorf("expected the seed 42 in the report, got %d", first.Report.Seed)
+	}
+
+	if other := run(43); other.Report.Fingerprint == first.Report.Fingerprint {
+		t.Error("different seeds produced the same fingerprint")
+	}
+}
+
+func TestParallel(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "EUR_GBP", BaseCurrency: "EUR", QuoteCurrency: "GBP", Leverage: 30, PipLocation: -4},
+	}
+
+	cfg := &Config{
+		Options: []gotrader.Option{gotrader.InitialBalance(10000), gotrader.HomeCurrency("EUR")},
+		Client: func(from, to time.Time) gotrader.BrokerClient {
+			return btrand.NewBTRandClient(instruments, from, to, btrand.Seed(42))
+		},
+		Strategy: func(params Parameters) gotrader.Strategy { return &alternate{} },
+		Seed:     42,
+	}
+
+	shards := []Shard{
+		{Name: "eur", Instruments: []string{"EUR_USD"}},
+		{Name: "gbp", Instruments: []string{"EUR_GBP"}, Options: []gotrader.Option{gotrader.InitialBalance(5000)}},
+	}
+
+	from := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	to := from.Add(2 * time.Hour)
+
+	run := func(workers int, step time.Duration) *ParallelResult {
+		result, err := (&Parallel{Shards: shards, Workers: workers, Step: step}).Run(cfg, nil, from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := run(2, time.Minute)
+
+	for i, shard := range shards {
+
+		alone, err := Run(&Config{Options: append(append(cfg.Options, gotrader.Instruments(shard.Instruments)),
+			shard.Options...), Client: cfg.Client, Strategy: cfg.Strategy}, nil, from, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result.Shards[i].Report.Fingerprint != alone.Report.Fingerprint {
+			t.Errorf("expected the shard %s to trade as if it was run alone", shard.Name)
+		}
+	}
+
+	summary := result.Report.Summary
+	eur, gbp := result.Shards[0].Report.Summary, result.Shards[1].Report.Summary
+
+	if eur.Trades == 0 || gbp.Trades == 0 || summary.Trades != eur.Trades+gbp.Trades {
+		t.Errorf("expected the trades of both shards, got %d, %d and %d", eur.Trades, gbp.Trades, summary.Trades)
+	}
+
+	if summary.InitialBalance != 15000 || math.Abs(summary.NetProfit-eur.NetProfit-gbp.NetProfit) > 1e-6 ||
+		math.Abs(summary.FinalBalance-eur.FinalBalance-gbp.FinalBalance) > 1e-6 {
+		t.Errorf("expected the combined account of the shards, got %+v", summary)
+	}
+
+	for _, other := range []*ParallelResult{run(1, time.Minute), run(2, 0)} {
+		if other.Report.Fingerprint != result.Report.Fingerprint {
+			t.Error("expected the same combined report whatever the workers and the step")
+		}
+	}
+}
@@ -0,0 +1,394 @@
+/*
+Package cluster distributes the backtests of a parameter search over several machines: a Coordinator serves the
+gRPC Coordinator service defined in cluster.proto and the Workers pull its jobs, run them with their own
+backtest.Config and report the results back, so a large walk-forward study runs on as many boxes as available:
+
+	coordinator := cluster.NewCoordinator(space, cluster.Objective(backtest.Sharpe))
+	server := grpc.NewServer()
+	coordinator.Register(server)
+	go server.Serve(listener)
+
+	walk := &backtest.WalkForward{Config: cfg, Search: coordinator, ...}
+	result, err := walk.Run()
+	coordinator.Close()
+
+and on every worker machine, with the same strategy and market data:
+
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	err = (&cluster.Worker{Name: hostname, Config: cfg}).Run(ctx, conn)
+
+A job leased to a worker that doesn't report it in time, e.g. a machine that died, is given to another one. The
+results are the reports of the workers, without account, and a job failing on a worker fails the search.
+*/
+package cluster
+
+//go:generate protoc --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative cluster.proto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader/backtest"
+	"github.com/luismcruz/gotrader/backtest/cluster/pb"
+	"github.com/luismcruz/gotrader/report"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Option represents a Coordinator functional option
+type Option func(c *Coordinator)
+
+// Samples is the functional option to search random samples of the space, a full grid search is done by default.
+func Samples(n int, seed int64) Option {
+	return func(c *Coordinator) {
+		c.samples = n
+		c.seed = seed
+	}
+}
+
+// Objective is the functional option to define the objective ranking the results, defaults to backtest.NetProfit.
+func Objective(objective backtest.Objective) Option {
+	return func(c *Coordinator) {
+		c.objective = objective
+	}
+}
+
+// Lease is the functional option to define the time a worker has to report a job before it is given to another
+// one, defaults to 10 minutes.
+func Lease(lease time.Duration) Option {
+	return func(c *Coordinator) {
+		c.lease = lease
+	}
+}
+
+// job is a backtest of a search, leased to a worker until it reports it.
+type job struct {
+	id       string
+	params   backtest.Parameters
+	from     time.Time
+	to       time.Time
+	search   *search
+	worker   string
+	deadline time.Time // of the lease
+	done     bool
+}
+
+// search is an optimization of the coordinator, done once every job is reported or one failed.
+type search struct {
+	remaining int
+	results   []*backtest.Result
+	err       error
+	done      chan struct{}
+}
+
+// Coordinator is a backtest.Search distributing the backtests of a parameter space to the workers, see the
+// package documentation. It is safe for concurrent use, the searches can run concurrently.
+type Coordinator struct {
+	pb.UnimplementedCoordinatorServer
+	space     backtest.Space
+	samples   int
+	seed      int64
+	objective backtest.Objective
+	lease     time.Duration
+	mutex     *sync.Mutex
+	counter   int64
+	jobs      map[string]*job
+	queue     []*job
+	closed    bool
+}
+
+// NewCoordinator is the Coordinator constructor.
+func NewCoordinator(space backtest.Space, opts ...Option) *Coordinator {
+
+	c := &Coordinator{
+		space:     space,
+		objective: backtest.NetProfit,
+		lease:     10 * time.Minute,
+		mutex:     &sync.Mutex{},
+		jobs:      make(map[string]*job),
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// expire queues again the jobs whose lease expired, with the mutex locked.
+func (c *Coordinator) expire(now time.Time) {
+	for _, j := range c.jobs {
+		if !j.done && j.worker != "" && now.After(j.deadline) {
+			j.worker = ""
+			c.queue = append(c.queue, j)
+		}
+	}
+}
+
+// finish removes the jobs of a search and signals it, with the mutex locked.
+func (c *Coordinator) finish(s *search) {
+
+	for id, j := range c.jobs {
+		if j.search == s {
+			delete(c.jobs, id)
+		}
+	}
+
+	queue := c.queue[:0]
+	for _, j := range c.queue {
+		if j.search != s {
+			queue = append(queue, j)
+		}
+	}
+	c.queue = queue
+
+	close(s.done)
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Register registers the Coordinator service on a gRPC server.
+func (c *Coordinator) Register(server *grpc.Server) {
+	pb.RegisterCoordinatorServer(server, c)
+}
+
+// NextJob leases the next job to a worker.
+func (c *Coordinator) NextJob(ctx context.Context, request *pb.JobRequest) (*pb.Job, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	c.expire(now)
+
+	for len(c.queue) > 0 && c.queue[0].done { // reported by the worker whose lease expired
+		c.queue = c.queue[1:]
+	}
+
+	if len(c.queue) == 0 {
+		return &pb.Job{Wait: !c.closed, Done: c.closed}, nil
+	}
+
+	j := c.queue[0]
+	c.queue = c.queue[1:]
+
+	j.worker = request.Worker
+	j.deadline = now.Add(c.lease)
+
+	return &pb.Job{
+		Id:         j.id,
+		Parameters: j.params,
+		From:       timestamppb.New(j.from),
+		To:         timestamppb.New(j.to),
+	}, nil
+}
+
+// Complete records the result of a job, the results of the jobs already reported, e.g. by a worker whose lease
+// expired, are ignored.
+func (c *Coordinator) Complete(ctx context.Context, result *pb.JobResult) (*pb.Ack, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	j, exist := c.jobs[result.Id]
+	if !exist || j.done {
+		return &pb.Ack{}, nil
+	}
+
+	j.done = true
+	s := j.search
+
+	if result.Error != "" {
+		s.err = errors.New("job " + j.id + " failed on " + result.Worker + ": " + result.Error)
+		c.finish(s)
+		return &pb.Ack{}, nil
+	}
+
+	r := &report.Report{}
+	if err := json.Unmarshal(result.Report, r); err != nil {
+		s.err = errors.New("job " + j.id + " report from " + result.Worker + ": " + err.Error())
+		c.finish(s)
+		return &pb.Ack{}, nil
+	}
+
+	s.results = append(s.results, &backtest.Result{
+		Parameters: j.params,
+		From:       j.from,
+		To:         j.to,
+		Report:     r,
+		Score:      c.objective(r),
+	})
+
+	if s.remaining--; s.remaining == 0 {
+		c.finish(s)
+	}
+
+	return &pb.Ack{}, nil
+}
+
+// Optimize distributes the backtests of the search over [from, to) and returns their results ranked from best to
+// worst, once every one is reported. The backtests run with the configuration of the workers, cfg is not used.
+func (c *Coordinator) Optimize(cfg *backtest.Config, from, to time.Time) ([]*backtest.Result, error) {
+
+	var candidates []backtest.Parameters
+
+	if c.samples > 0 {
+		candidates = c.space.Sample(c.samples, rand.New(rand.NewSource(c.seed)))
+	} else {
+		candidates = c.space.Grid()
+	}
+
+	if len(candidates) == 0 {
+		return nil, errors.New("no candidate parameters to evaluate")
+	}
+
+	s := &search{remaining: len(candidates), done: make(chan struct{})}
+
+	c.mutex.Lock()
+
+	if c.closed {
+		c.mutex.Unlock()
+		return nil, errors.New("coordinator is closed")
+	}
+
+	for _, params := range candidates {
+		c.counter++
+		j := &job{id: strconv.FormatInt(c.counter, 10), params: params, from: from, to: to, search: s}
+		c.jobs[j.id] = j
+		c.queue = append(c.queue, j)
+	}
+
+	c.mutex.Unlock()
+
+	<-s.done
+
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	results := s.results
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+
+	return results, nil
+}
+
+// Best returns the highest ranked result, so the coordinator can be used as a walk-forward Search.
+func (c *Coordinator) Best(cfg *backtest.Config, from, to time.Time) (*backtest.Result, error) {
+
+	results, err := c.Optimize(cfg, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0], nil
+}
+
+// Close stops the workers once the running searches are done, new searches fail.
+func (c *Coordinator) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.closed = true
+}
+
+/*
+Worker runs the jobs of a Coordinator with its Config, which must backtest the same strategy over the same market
+data as the other workers. It runs Parallel backtests at a time, the number of CPUs by default, and polls the
+coordinator every Poll, a second by default, while it has no job for it.
+*/
+type Worker struct {
+	Name     string
+	Config   *backtest.Config
+	Parallel int
+	Poll     time.Duration
+}
+
+// Run runs the jobs until the coordinator is closed, an error of the coordinator or the context is done.
+func (w *Worker) Run(ctx context.Context, conn grpc.ClientConnInterface) error {
+
+	client := pb.NewCoordinatorClient(conn)
+
+	parallel := w.Parallel
+	if parallel <= 0 {
+		parallel = runtime.NumCPU()
+	}
+
+	poll := w.Poll
+	if poll <= 0 {
+		poll = time.Second
+	}
+
+	errs := make(chan error, parallel)
+
+	for i := 0; i < parallel; i++ {
+		go func() {
+			errs <- w.work(ctx, client, poll)
+		}()
+	}
+
+	var err error
+	for i := 0; i < parallel; i++ {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// work runs the jobs one at a time.
+func (w *Worker) work(ctx context.Context, client pb.CoordinatorClient, poll time.Duration) error {
+
+	for {
+
+		j, err := client.NextJob(ctx, &pb.JobRequest{Worker: w.Name})
+		if err != nil {
+			return err
+		}
+
+		if j.Done {
+			return nil
+		}
+
+		if j.Wait {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(poll):
+			}
+			continue
+		}
+
+		result := &pb.JobResult{Id: j.Id, Worker: w.Name}
+
+		r, err := backtest.Run(w.Config, j.Parameters, j.From.AsTime(), j.To.AsTime())
+		if err == nil {
+			result.Report, err = json.Marshal(r.Report)
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+		}
+
+		if _, err := client.Complete(ctx, result); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/backtest"
+	"github.com/luismcruz/gotrader/backtest/cluster/pb"
+	"github.com/luismcruz/gotrader/clients/btrand"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// flip buys and sells every few ticks, closing the previous trade.
+type flip struct {
+	engine gotrader.Engine
+	every  int
+	ticks  int
+}
+
+func (s *flip) Initialize()                      {}
+func (s *flip) SetEngine(engine gotrader.Engine) { s.engine = engine }
+func (s *flip) OnOrderFill(*gotrader.OrderFill)  {}
+func (s *flip) OnStop()                          {}
+
+func (s *flip) OnTick(tick *gotrader.Tick) {
+
+	if s.ticks++; s.ticks%s.every != 0 {
+		return
+	}
+
+	inst := s.engine.Account().Instrument(tick.Instrument)
+	if inst.TradesNumber() > 0 {
+		s.engine.CloseTrade(tick.Instrument, inst.TradeByOrder(0).ID())
+	}
+
+	if s.ticks%(2*s.every) == 0 {
+		s.engine.Buy(tick.Instrument, 1000)
+	} else {
+		s.engine.Sell(tick.Instrument, 1000)
+	}
+}
+
+func config() *backtest.Config {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	return &backtest.Config{
+		Options: []gotrader.Option{gotrader.Instruments([]string{"EUR_USD"}), gotrader.InitialBalance(10000),
+			gotrader.HomeCurrency("EUR")},
+		Client: func(from, to time.Time) gotrader.BrokerClient {
+			return btrand.NewBTRandClient(instruments, from, to, btrand.Seed(7))
+		},
+		Strategy: func(params backtest.Parameters) gotrader.Strategy { return &flip{every: int(params["every"])} },
+		Seed:     7,
+	}
+}
+
+// serve serves the coordinator in memory and returns a connection to it.
+func serve(t *testing.T, c *Coordinator) *grpc.ClientConn {
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	c.Register(server)
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestCoordinator(t *testing.T) {
+
+	space := backtest.Space{{Name: "every", Min: 20, Max: 80, Step: 20}}
+	from := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	local, err := (&backtest.Optimizer{Space: space, Workers: 2}).Optimize(config(), from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coordinator := NewCoordinator(space)
+	conn := serve(t, coordinator)
+
+	workers := make(chan error, 2)
+	for _, name := range []string{"a", "b"} {
+		w := &Worker{Name: name, Config: config(), Parallel: 2, Poll: 10 * time.Millisecond}
+		go func() { workers <- w.Run(context.Background(), conn) }()
+	}
+
+	results, err := coordinator.Optimize(nil, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != len(local) {
+		t.Fatalf("expected %d results, got %d", len(local), len(results))
+	}
+
+	for i := range results {
+		if results[i].Parameters.String() != local[i].Parameters.String() ||
+			results[i].Report.Fingerprint != local[i].Report.Fingerprint || results[i].Score != local[i].Score {
+			t.Errorf("expected the result %d of the local search %v, got %v", i, local[i].Parameters, results[i].Parameters)
+		}
+	}
+
+	coordinator.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := <-workers; err != nil {
+			t.Errorf("expected the workers to stop with the coordinator, got %v", err)
+		}
+	}
+
+	if _, err := coordinator.Optimize(nil, from, to); err == nil {
+		t.Error("expected the searches of a closed coordinator to fail")
+	}
+}
+
+func TestCoordinator_Lease(t *testing.T) {
+
+	ctx := context.Background()
+	coordinator := NewCoordinator(backtest.Space{{Name: "every", Min: 50, Max: 50, Step: 1}}, Lease(time.Millisecond))
+
+	from := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+	results := make(chan []*backtest.Result, 1)
+	go func() {
+		r, _ := coordinator.Optimize(nil, from, from.Add(time.Hour))
+		results <- r
+	}()
+
+	// a worker leases the job and dies
+	var lost *pb.Job
+	for lost == nil || lost.Id == "" {
+		lost, _ = coordinator.NextJob(ctx, &pb.JobRequest{Worker: "lost"})
+		time.Sleep(time.Millisecond)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	conn := serve(t, coordinator)
+	go (&Worker{Name: "b", Config: config(), Parallel: 1, Poll: time.Millisecond}).Run(ctx, conn)
+
+	select {
+	case r := <-results:
+		if len(r) != 1 || r[0].Report.Summary.Trades == 0 {
+			t.Errorf("expected the result of the job leased again, got %+v", r)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the expired job was not leased again")
+	}
+
+	if ack, err := coordinator.Complete(ctx, &pb.JobResult{Id: lost.Id, Worker: "lost", Error: "late"}); err != nil ||
+		ack == nil {
+		t.Errorf("expected the late result to be ignored, got %v", err)
+	}
+
+	coordinator.Close()
+}
@@ -0,0 +1,144 @@
+package backtest
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/luismcruz/gotrader/report"
+)
+
+// Resampling defines how the trade sequence is rearranged on each simulation.
+type Resampling int
+
+const (
+	// Shuffle reorders the trades without repetition
+	Shuffle Resampling = iota
+
+	// Bootstrap draws trades with replacement
+	Bootstrap
+)
+
+// MonteCarlo simulates alternative equity curves from a backtest trade sequence.
+type MonteCarlo struct {
+	Simulations int
+	Resampling  Resampling
+	Seed        int64
+}
+
+// Distribution holds the percentiles of a simulated statistic.
+type Distribution struct {
+	Mean   float64
+	Min    float64
+	Max    float64
+	values []float64
+}
+
+// Percentile returns the value below which the given fraction [0, 1] of the simulations fall, the fractions out
+// of the range are clamped to it (NaN to 0).
+func (d *Distribution) Percentile(p float64) float64 {
+
+	if len(d.values) == 0 {
+		return 0
+	}
+
+	switch {
+	case !(p >= 0):
+		p = 0
+	case p > 1:
+		p = 1
+	}
+
+	idx := p * float64(len(d.values)-1)
+	lower := int(math.Floor(idx))
+	upper := int(math.Ceil(idx))
+
+	return d.values[lower] + (d.values[upper]-d.values[lower])*(idx-float64(lower))
+}
+
+// MonteCarloResult holds the distributions of return and maximum drawdown of the simulations.
+type MonteCarloResult struct {
+	Return      *Distribution
+	MaxDrawdown *Distribution
+}
+
+// Run simulates the report trade sequence.
+func (m *MonteCarlo) Run(r *report.Report) (*MonteCarloResult, error) {
+
+	profits := make([]float64, len(r.Trades))
+	for i, t := range r.Trades {
+		profits[i] = t.Profit
+	}
+
+	return m.Simulate(profits, r.Summary.InitialBalance)
+}
+
+// Simulate resamples the trade profits and returns the return and maximum drawdown distributions.
+func (m *MonteCarlo) Simulate(profits []float64, initialBalance float64) (*MonteCarloResult, error) {
+
+	if len(profits) == 0 {
+		return nil, errors.New("no trades to simulate")
+	}
+
+	if initialBalance <= 0 {
+		return nil, errors.New("initial balance must be positive")
+	}
+
+	simulations := m.Simulations
+	if simulations <= 0 {
+		simulations = 1000
+	}
+
+	rnd := rand.New(rand.NewSource(m.Seed))
+	returns := make([]float64, simulations)
+	drawdowns := make([]float64, simulations)
+	sequence := make([]float64, len(profits))
+
+	for s := 0; s < simulations; s++ {
+
+		if m.Resampling == Bootstrap {
+			for i := range sequence {
+				sequence[i] = profits[rnd.Intn(len(profits))]
+			}
+		} else {
+			copy(sequence, profits)
+			rnd.Shuffle(len(sequence), func(i, j int) { sequence[i], sequence[j] = sequence[j], sequence[i] })
+		}
+
+		balance := initialBalance
+		highWaterMark := initialBalance
+		maxDrawdown := 0.0
+
+		for _, p := range sequence {
+			balance += p
+			highWaterMark = math.Max(highWaterMark, balance)
+			maxDrawdown = math.Max(maxDrawdown, (highWaterMark-balance)/highWaterMark)
+		}
+
+		returns[s] = (balance - initialBalance) / initialBalance
+		drawdowns[s] = maxDrawdown
+	}
+
+	return &MonteCarloResult{
+		Return:      newDistribution(returns),
+		MaxDrawdown: newDistribution(drawdowns),
+	}, nil
+}
+
+func newDistribution(values []float64) *Distribution {
+
+	sort.Float64s(values)
+
+	mean := 0.0
+	for _, v := range values {
+		mean += v
+	}
+
+	return &Distribution{
+		Mean:   mean / float64(len(values)),
+		Min:    values[0],
+		Max:    values[len(values)-1],
+		values: values,
+	}
+}
@@ -0,0 +1,61 @@
+package backtest
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMonteCarlo_Simulate(t *testing.T) {
+
+	profits := []float64{100, -50, 200, -150, 75, -25, 50}
+
+	t.Run("shuffle keeps the final return", func(t *testing.T) {
+
+		mc := &MonteCarlo{Simulations: 200, Seed: 1}
+
+		result, err := mc.Simulate(profits, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if math.Abs(result.Return.Min-0.2) > 1e-9 || math.Abs(result.Return.Max-0.2) > 1e-9 {
+			t.Errorf("expected every shuffle to return 0.2, got [%v, %v]", result.Return.Min, result.Return.Max)
+		}
+
+		if result.MaxDrawdown.Percentile(0.05) > result.MaxDrawdown.Percentile(0.95) {
+			t.Error("percentiles are not ordered")
+		}
+	})
+
+	t.Run("percentiles out of range are clamped", func(t *testing.T) {
+
+		result, err := (&MonteCarlo{Simulations: 50, Resampling: Bootstrap, Seed: 3}).Simulate(profits, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, p := range []float64{-0.5, math.Inf(-1), math.NaN()} {
+			if result.Return.Percentile(p) != result.Return.Min {
+				t.Errorf("expected the percentile %v to be the minimum %v, got %v", p, result.Return.Min,
+					result.Return.Percentile(p))
+			}
+		}
+
+		for _, p := range []float64{1.5, math.Inf(1)} {
+			if result.Return.Percentile(p) != result.Return.Max {
+				t.Errorf("expected the percentile %v to be the maximum %v, got %v", p, result.Return.Max,
+					result.Return.Percentile(p))
+			}
+		}
+	})
+
+	t.Run("bootstrap is deterministic by seed", func(t *testing.T) {
+
+		first, _ := (&MonteCarlo{Simulations: 100, Resampling: Bootstrap, Seed: 7}).Simulate(profits, 1000)
+		second, _ := (&MonteCarlo{Simulations: 100, Resampling: Bootstrap, Seed: 7}).Simulate(profits, 1000)
+
+		if first.Return.Percentile(0.5) != second.Return.Percentile(0.5) {
+			t.Error("same seed produced different results")
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package backtest
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader/report"
+)
+
+// Range is the domain of a single parameter, Step is only used by grid search.
+type Range struct {
+	Name string
+	Min  float64
+	Max  float64
+	Step float64
+}
+
+// Space is the parameters space to be searched.
+type Space []Range
+
+// Grid returns every combination of the space ranges.
+func (s Space) Grid() []Parameters {
+
+	grid := []Parameters{{}}
+
+	for _, r := range s {
+
+		values := make([]float64, 0)
+		if r.Step <= 0 || r.Max <= r.Min {
+			values = append(values, r.Min)
+		} else {
+			steps := int(math.Floor((r.Max-r.Min)/r.Step + 1e-9))
+			for i := 0; i <= steps; i++ {
+				values = append(values, r.Min+float64(i)*r.Step)
+			}
+		}
+
+		next := make([]Parameters, 0, len(grid)*len(values))
+		for _, params := range grid {
+			for _, v := range values {
+				p := make(Parameters, len(params)+1)
+				for k, pv := range params {
+					p[k] = pv
+				}
+				p[r.Name] = v
+				next = append(next, p)
+			}
+		}
+
+		grid = next
+	}
+
+	return grid
+}
+
+// Sample returns n parameter sets uniformly sampled from the space.
+func (s Space) Sample(n int, rnd *rand.Rand) []Parameters {
+
+	samples := make([]Parameters, n)
+
+	for i := range samples {
+		p := make(Parameters, len(s))
+		for _, r := range s {
+			p[r.Name] = r.Min + rnd.Float64()*(r.Max-r.Min)
+		}
+		samples[i] = p
+	}
+
+	return samples
+}
+
+// WeightedObjective is an objective with its weight in a composed score.
+type WeightedObjective struct {
+	Objective Objective
+	Weight    float64
+}
+
+// Weighted composes several objectives into a single score by weighted sum.
+func Weighted(objectives ...WeightedObjective) Objective {
+	return func(r *report.Report) float64 {
+		score := 0.0
+		for _, o := range objectives {
+			score += o.Objective(r) * o.Weight
+		}
+		return score
+	}
+}
+
+// Optimizer runs many backtests over a parameter space in parallel and ranks them by an objective.
+type Optimizer struct {
+	Space     Space
+	Samples   int   // number of random samples, a full grid search is done when zero
+	Seed      int64 // random search seed
+	Workers   int   // defaults to the number of CPUs
+	Objective Objective
+}
+
+// Optimize runs every backtest of the search and returns the results ranked from best to worst.
+func (o *Optimizer) Optimize(cfg *Config, from, to time.Time) ([]*Result, error) {
+
+	var candidates []Parameters
+
+	if o.Samples > 0 {
+		candidates = o.Space.Sample(o.Samples, rand.New(rand.NewSource(o.Seed)))
+	} else {
+		candidates = o.Space.Grid()
+	}
+
+	objective := o.Objective
+	if objective == nil {
+		objective = NetProfit
+	}
+
+	workers := o.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		jobs     = make(chan Parameters)
+		results  = make([]*Result, 0, len(candidates))
+		firstErr error
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for params := range jobs {
+
+				result, err := Run(cfg, params, from, to)
+
+				mutex.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					result.Score = objective(result.Report)
+					results = append(results, result)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, params := range candidates {
+		jobs <- params
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+
+	return results, nil
+}
+
+// Best returns the highest ranked result, so the optimizer can be used as a walk-forward Search.
+func (o *Optimizer) Best(cfg *Config, from, to time.Time) (*Result, error) {
+
+	results, err := o.Optimize(cfg, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, errors.New("parameter space is empty")
+	}
+
+	return results[0], nil
+}
@@ -0,0 +1,154 @@
+package backtest
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/report"
+)
+
+// Shard is an independent part of a parallel backtest, its instruments traded by its own strategy and account.
+type Shard struct {
+	Name        string
+	Instruments []string
+	Options     []gotrader.Option // added to the Config ones, e.g. the initial balance allocated to the shard
+}
+
+/*
+Parallel runs a backtest split in Shards on a pool of workers, e.g. one shard by instrument of a multi-year
+multi-pair backtest, each on a session of its own, synchronized by a gotrader.SharedClock so their times are never
+more than Step apart (lockstep when zero). The shards must be independent: they don't share their account, its
+balance and margin, so a strategy trading several instruments together must be in a single shard.
+
+The combined report merges the ledgers of the shards in time order, as if they were traded on a single account of
+their total initial balance, their trade IDs prefixed by the shard names.
+*/
+type Parallel struct {
+	Shards  []Shard
+	Workers int // defaults to the number of CPUs
+	Step    time.Duration
+}
+
+// ParallelResult is the outcome of a parallel backtest.
+type ParallelResult struct {
+	Shards []*Result // in the shards order
+	Report *report.Report
+}
+
+// Run executes the backtests of the shards over the period [from, to) with the given parameters.
+func (p *Parallel) Run(cfg *Config, params Parameters, from, to time.Time) (*ParallelResult, error) {
+
+	if cfg == nil || cfg.Client == nil || cfg.Strategy == nil {
+		return nil, errors.New("backtest config requires a client and a strategy factory")
+	}
+
+	if len(p.Shards) == 0 {
+		return nil, errors.New("parallel backtest without shards")
+	}
+
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var (
+		clock    = gotrader.NewSharedClock(p.Step)
+		jobs     = make(chan int)
+		results  = make([]*Result, len(p.Shards))
+		firstErr error
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < min(workers, len(p.Shards)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+
+				shard := p.Shards[i]
+
+				options := append([]gotrader.Option(nil), cfg.Options...)
+				options = append(options, gotrader.Instruments(shard.Instruments))
+				options = append(options, shard.Options...)
+				options = append(options, gotrader.SyncClock(clock))
+
+				result, err := Run(&Config{Options: options, Client: cfg.Client, Strategy: cfg.Strategy, Seed: cfg.Seed},
+					params, from, to)
+
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = errors.New("shard " + shard.Name + ": " + err.Error())
+				}
+				results[i] = result
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for i := range p.Shards {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	r := p.merge(results)
+	r.Seed = cfg.Seed
+
+	return &ParallelResult{Shards: results, Report: r}, nil
+}
+
+// merge chains the ledgers of the shards in time order, the balance being the total of their balances.
+func (p *Parallel) merge(results []*Result) *report.Report {
+
+	type shardTransaction struct {
+		shard       int
+		transaction *gotrader.Transaction
+	}
+
+	merged := make([]shardTransaction, 0)
+	balances := make([]float64, len(results))
+	total := 0.0
+
+	for i, result := range results {
+
+		ledger := result.Account.Ledger()
+		balances[i] = ledger.OpeningBalance()
+		total += balances[i]
+
+		for _, t := range ledger.Transactions() {
+			merged = append(merged, shardTransaction{shard: i, transaction: t})
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].transaction.Time.Before(merged[j].transaction.Time)
+	})
+
+	opening := total
+	transactions := make([]*gotrader.Transaction, 0, len(merged))
+
+	for _, m := range merged {
+
+		total += m.transaction.Balance - balances[m.shard]
+		balances[m.shard] = m.transaction.Balance
+
+		t := *m.transaction
+		t.Balance = total
+		if t.TradeID != "" {
+			t.TradeID = p.Shards[m.shard].Name + "/" + t.TradeID
+		}
+		transactions = append(transactions, &t)
+	}
+
+	return report.FromTransactions(transactions, opening, results[0].Account.HomeCurrency())
+}
@@ -0,0 +1,165 @@
+package backtest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/report"
+)
+
+// Search selects the best parameters of a strategy for a given period.
+type Search interface {
+	Best(cfg *Config, from, to time.Time) (*Result, error)
+}
+
+// Candidates is a Search that evaluates a fixed list of parameter sets sequentially.
+type Candidates struct {
+	Parameters []Parameters
+	Objective  Objective
+}
+
+// Best runs every candidate and returns the highest scored result.
+func (c *Candidates) Best(cfg *Config, from, to time.Time) (*Result, error) {
+
+	if len(c.Parameters) == 0 {
+		return nil, errors.New("no candidate parameters to evaluate")
+	}
+
+	objective := c.Objective
+	if objective == nil {
+		objective = NetProfit
+	}
+
+	var best *Result
+
+	for _, params := range c.Parameters {
+
+		result, err := Run(cfg, params, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Score = objective(result.Report)
+
+		if best == nil || result.Score > best.Score {
+			best = result
+		}
+	}
+
+	return best, nil
+}
+
+// Window is a single in-sample/out-of-sample step of a walk-forward analysis.
+type Window struct {
+	InSampleFrom    time.Time
+	InSampleTo      time.Time
+	OutOfSampleFrom time.Time
+	OutOfSampleTo   time.Time
+	InSample        *Result
+	OutOfSample     *Result
+}
+
+// WalkForwardResult holds every window and the stitched out-of-sample report.
+type WalkForwardResult struct {
+	Windows []*Window
+	Report  *report.Report
+}
+
+// WalkForward splits a period into rolling in-sample/out-of-sample windows. On each window the
+// parameters are optimized on the in-sample period and then traded on the following out-of-sample period.
+type WalkForward struct {
+	Config      *Config
+	Search      Search
+	Start       time.Time
+	End         time.Time
+	InSample    time.Duration
+	OutOfSample time.Duration
+	Step        time.Duration // defaults to OutOfSample, so the out-of-sample periods do not overlap
+}
+
+// Windows returns the windows in which the period will be split.
+func (w *WalkForward) Windows() []*Window {
+
+	step := w.Step
+	if step <= 0 {
+		step = w.OutOfSample
+	}
+
+	windows := make([]*Window, 0)
+
+	if w.InSample <= 0 || w.OutOfSample <= 0 {
+		return windows
+	}
+
+	for from := w.Start; !from.Add(w.InSample + w.OutOfSample).After(w.End); from = from.Add(step) {
+		windows = append(windows, &Window{
+			InSampleFrom:    from,
+			InSampleTo:      from.Add(w.InSample),
+			OutOfSampleFrom: from.Add(w.InSample),
+			OutOfSampleTo:   from.Add(w.InSample + w.OutOfSample),
+		})
+	}
+
+	return windows
+}
+
+// Run executes the walk-forward analysis.
+func (w *WalkForward) Run() (*WalkForwardResult, error) {
+
+	if w.Search == nil {
+		return nil, errors.New("walk-forward requires a parameter search")
+	}
+
+	windows := w.Windows()
+	if len(windows) == 0 {
+		return nil, errors.New("period is too short for the in-sample and out-of-sample durations")
+	}
+
+	for _, window := range windows {
+
+		best, err := w.Search.Best(w.Config, window.InSampleFrom, window.InSampleTo)
+		if err != nil {
+			return nil, err
+		}
+
+		window.InSample = best
+
+		window.OutOfSample, err = Run(w.Config, best.Parameters, window.OutOfSampleFrom, window.OutOfSampleTo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &WalkForwardResult{
+		Windows: windows,
+		Report:  stitch(windows),
+	}, nil
+}
+
+// stitch chains the out-of-sample ledgers as if they were traded on a single account.
+func stitch(windows []*Window) *report.Report {
+
+	first := windows[0].OutOfSample.Account
+	openingBalance := first.Ledger().OpeningBalance()
+	balance := openingBalance
+	transactions := make([]*gotrader.Transaction, 0)
+
+	for _, window := range windows {
+
+		ledger := window.OutOfSample.Account.Ledger()
+		windowOpening := ledger.OpeningBalance()
+
+		for _, t := range ledger.Transactions() {
+			stitched := *t
+			stitched.Balance = balance + t.Balance - windowOpening
+			transactions = append(transactions, &stitched)
+		}
+
+		if len(transactions) > 0 {
+			balance = transactions[len(transactions)-1].Balance
+		}
+	}
+
+	return report.FromTransactions(transactions, openingBalance, first.HomeCurrency())
+}
@@ -0,0 +1,135 @@
+package gotrader
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+/*
+A basket is a set of market orders across instruments submitted as one unit, e.g. the legs of a pairs trade, with
+SubmitBasket. The backtests fill it all or nothing: every order is checked, the margin of the whole basket
+included, before the first one is filled. The live sessions submit the orders in turn and roll the basket back on
+the first failure, rejections notified after the submission included: the trades opened by the other orders are
+closed with the RolledBack reason, as soon as they are filled. The rollback is best effort, a close that fails is
+logged and its trade stays open.
+*/
+
+// checkBasket returns the error of a basket that can't be submitted.
+func checkBasket(orders []*Order) error {
+
+	if len(orders) == 0 {
+		return errors.New("empty basket")
+	}
+
+	for i, order := range orders {
+
+		if order.Type != MarketOrder {
+			return fmt.Errorf("basket order %d: basket orders must be market orders", i)
+		}
+
+		if order.Units <= 0 {
+			return fmt.Errorf("basket order %d: order units must be positive", i)
+		}
+	}
+
+	return nil
+}
+
+// basketLeg is an order of a live basket.
+type basketLeg struct {
+	basket     *basket
+	instrument string
+	tradeID    string // empty until filled
+}
+
+type basket struct {
+	clientIDs  []string
+	filled     int
+	rolledBack bool
+}
+
+// baskets tracks the orders of the live baskets by their client IDs, until they are all filled or the basket is
+// rolled back.
+type baskets struct {
+	mutex *sync.Mutex
+	legs  map[string]*basketLeg
+}
+
+func newBaskets() *baskets {
+	return &baskets{
+		mutex: &sync.Mutex{},
+		legs:  make(map[string]*basketLeg),
+	}
+}
+
+// add tracks the orders of a basket, they must have client IDs.
+func (b *baskets) add(orders []*Order) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	bk := &basket{clientIDs: make([]string, len(orders))}
+
+	for i, order := range orders {
+		bk.clientIDs[i] = order.ClientID
+		b.legs[order.ClientID] = &basketLeg{basket: bk}
+	}
+}
+
+// filled records the trade opened by an order, returning whether it must be closed since its basket was rolled
+// back.
+func (b *baskets) filled(clientID, instrument, tradeID string) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	leg, exist := b.legs[clientID]
+	if !exist {
+		return false
+	}
+
+	if leg.basket.rolledBack {
+		delete(b.legs, clientID)
+		return true
+	}
+
+	leg.instrument, leg.tradeID = instrument, tradeID
+	leg.basket.filled++
+
+	if leg.basket.filled == len(leg.basket.clientIDs) {
+		for _, id := range leg.basket.clientIDs {
+			delete(b.legs, id)
+		}
+	}
+
+	return false
+}
+
+// rollback rolls back the basket of the order that failed, returning the legs already filled to close. The client
+// IDs of the orders that won't fill, not sent or rejected, are forgotten; the orders in flight are closed when
+// filled.
+func (b *baskets) rollback(clientID string, forget ...string) []basketLeg {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	leg, exist := b.legs[clientID]
+	if !exist || leg.basket.rolledBack {
+		return nil
+	}
+
+	leg.basket.rolledBack = true
+
+	for _, id := range forget {
+		delete(b.legs, id)
+	}
+
+	filled := make([]basketLeg, 0)
+
+	for _, id := range leg.basket.clientIDs {
+		if l, exist := b.legs[id]; exist && l.tradeID != "" {
+			filled = append(filled, *l)
+			delete(b.legs, id)
+		}
+	}
+
+	return filled
+}
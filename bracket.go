@@ -0,0 +1,78 @@
+package gotrader
+
+import "fmt"
+
+/*
+A bracket is an entry order with attached exits, its StopLoss and TakeProfit: they are submitted with the entry as
+one order and only activate once it fills, attached to the trade it opens. The exits are one-cancels-other, the
+first hit closes the trade and the other is dropped with it; while the entry is pending they are amended or
+cancelled with it.
+*/
+
+// Bracketed returns whether the order has attached exits.
+func (o *Order) Bracketed() bool {
+	return o.StopLoss != 0 || o.TakeProfit != 0
+}
+
+// entryPrice returns the price the order is expected to fill at: the order price of the pending orders, the current
+// price of the instrument for the market ones.
+func (o *Order) entryPrice(inst *Instrument) float64 {
+
+	switch {
+	case o.Type != MarketOrder:
+		return o.Price
+	case o.Side == Long:
+		return inst.Ask()
+	}
+
+	return inst.Bid()
+}
+
+// checkBracket returns ErrInvalidBracket when an exit of the order is not on its side of the entry price: the stop
+// loss below and the take profit above it for the longs, the opposite for the shorts. The market orders are only
+// checked once the instrument has prices.
+func checkBracket(o *Order, inst *Instrument) error {
+
+	if !o.Bracketed() {
+		return nil
+	}
+
+	if o.StopLoss < 0 || o.TakeProfit < 0 {
+		return fmt.Errorf("%s: negative exit: %w", o.Instrument, ErrInvalidBracket)
+	}
+
+	price := o.entryPrice(inst)
+	if price == 0 {
+		return nil
+	}
+
+	sign := 1.0
+	if o.Side == Short {
+		sign = -1
+	}
+
+	if o.StopLoss != 0 && sign*(price-o.StopLoss) <= 0 {
+		return fmt.Errorf("%s: stop loss %v at or beyond the %s entry %v: %w", o.Instrument, o.StopLoss,
+			o.Side, price, ErrInvalidBracket)
+	}
+
+	if o.TakeProfit != 0 && sign*(o.TakeProfit-price) <= 0 {
+		return fmt.Errorf("%s: take profit %v at or beyond the %s entry %v: %w", o.Instrument, o.TakeProfit,
+			o.Side, price, ErrInvalidBracket)
+	}
+
+	return nil
+}
+
+// bracketExits are the exits attached to a live order, set on the trade it opens.
+type bracketExits struct {
+	stopLoss   float64
+	guaranteed bool
+	takeProfit float64
+}
+
+func (x bracketExits) attach(trade *Trade) {
+	trade.stopLoss = x.stopLoss
+	trade.guaranteedStop = x.guaranteed
+	trade.takeProfit = x.takeProfit
+}
@@ -0,0 +1,151 @@
+package gotrader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CloseReport is the outcome of a bulk close, see Account.CloseAllTrades.
+type CloseReport struct {
+	Requested      int
+	Closed         []*OrderFill     // by fill order
+	Failed         map[string]error // by trade ID, the trades still open
+	RealizedProfit float64          // of the closed trades
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+/*
+closeTrades closes the open trades matched on a single AccountView, so a tick can't open or close a trade
+between the matches, and waits for their fills until the context is done. The closes go through the engine one
+by one, so the checks of the engine wrappers (the ownership of the runner strategies) still apply; they fail
+with ErrNoSession without engine.
+*/
+func closeTrades(
+	ctx context.Context, account *Account, engine Engine, instruments []string, match func(TradeState) bool,
+) *CloseReport {
+
+	report := &CloseReport{Failed: make(map[string]error)}
+
+	scope := make(map[string]bool)
+	for _, instrument := range instruments {
+		scope[instrument] = true
+	}
+
+	type selected struct{ instrument, id string }
+	var trades []selected
+
+	for _, iv := range account.View().Instruments {
+		if len(scope) > 0 && !scope[iv.Name] {
+			continue
+		}
+
+		for _, trade := range iv.Trades {
+			if match(trade) {
+				trades = append(trades, selected{instrument: iv.Name, id: trade.ID})
+			}
+		}
+	}
+
+	report.Requested = len(trades)
+	if len(trades) == 0 {
+		return report
+	}
+
+	if engine == nil {
+		for _, trade := range trades {
+			report.Failed[trade.id] = fmt.Errorf("trade %s: %w", trade.id, ErrNoSession)
+		}
+		return report
+	}
+
+	var mutex sync.Mutex
+	pending := make(map[string]bool, len(trades))
+	done := make(chan struct{})
+
+	settle := func(id string, fill *OrderFill, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if !pending[id] {
+			return
+		}
+		delete(pending, id)
+
+		if err != nil {
+			report.Failed[id] = err
+		} else {
+			report.Closed = append(report.Closed, fill)
+			report.RealizedProfit += fill.Profit
+		}
+
+		if len(pending) == 0 {
+			close(done)
+		}
+	}
+
+	for _, trade := range trades {
+		pending[trade.id] = true
+	}
+
+	hook := account.Events().HookTrades(TradeHooks{
+		Instruments: instruments,
+		Closed:      func(event TradeClosed) { settle(event.Fill.TradeID, event.Fill, nil) },
+		CloseFailed: func(event OrderRejected) {
+			settle(event.Fill.TradeID, nil, fmt.Errorf("trade %s: %s", event.Fill.TradeID, event.Fill.Error))
+		},
+		Synchronous: true,
+	})
+	defer hook.Remove()
+
+	for _, trade := range trades {
+		if err := engine.CloseTrade(trade.instrument, trade.id); err != nil {
+			settle(trade.id, nil, err)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		mutex.Lock()
+		for id := range pending {
+			report.Failed[id] = fmt.Errorf("trade %s: %w", id, ctx.Err())
+		}
+		pending = nil
+		mutex.Unlock()
+	}
+
+	return report
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+/*
+CloseAllTrades closes the open trades of the account, or of the instruments given, and returns when their fills
+are received or the context is done. The trades are selected on a single AccountView; the backtests fill them
+at the prices of the current tick, the live sessions request them together and wait for the broker fills. The
+closes go through the engine of the session, not the wrappers of the runner strategies, and fail with ErrNoSession
+on the accounts restored out of a session.
+*/
+func (a *Account) CloseAllTrades(ctx context.Context, instruments ...string) *CloseReport {
+	return closeTrades(ctx, a, a.engine, instruments, func(TradeState) bool { return true })
+}
+
+// CloseSide closes the open trades of a side, see CloseAllTrades.
+func (a *Account) CloseSide(ctx context.Context, side Side, instruments ...string) *CloseReport {
+	return closeTrades(ctx, a, a.engine, instruments, func(trade TradeState) bool { return trade.Side == side })
+}
+
+// CloseByTag closes the open trades opened by the orders of a tag, see CloseAllTrades.
+func (a *Account) CloseByTag(ctx context.Context, tag string, instruments ...string) *CloseReport {
+	return closeTrades(ctx, a, a.engine, instruments, func(trade TradeState) bool { return trade.Tag == tag })
+}
@@ -0,0 +1,236 @@
+package gotrader
+
+import "time"
+
+// SessionCalendar answers when a venue opens and closes for trading.
+type SessionCalendar interface {
+	NextOpen(t time.Time) time.Time  // first session open strictly after t
+	NextClose(t time.Time) time.Time // first session close strictly after t
+}
+
+// DailySession is a SessionCalendar with the same trading hours every trading day, e.g. US equities
+// from 09:30 to 16:00 New York time. A Close before the Open means the session spans midnight. The offsets are
+// wall clock times of the location, so the sessions follow its daylight saving time.
+type DailySession struct {
+	Location *time.Location
+	Open     time.Duration  // offset from midnight
+	Close    time.Duration  // offset from midnight
+	Weekdays []time.Weekday // days the session opens, every day when empty
+}
+
+// localTime returns the time at the offset from the midnight of the date of day in the location, by its wall clock
+// on the days the daylight saving time changes, e.g. 17:00 New York time is 21:00 UTC on the day the clocks spring
+// forward, not 22:00.
+func localTime(day time.Time, offset time.Duration, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, int(offset), loc) // normalized as the wall clock
+}
+
+func (s DailySession) tradingDay(day time.Weekday) bool {
+
+	if len(s.Weekdays) == 0 {
+		return true
+	}
+
+	for _, d := range s.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// next returns the first time strictly after t at the offset of a trading day, when close is true the offset
+// belongs to the session opened the previous day if it spans midnight.
+func (s DailySession) next(t time.Time, offset time.Duration, close bool) time.Time {
+
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	local := t.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	for i := 0; i < 8; i++ {
+
+		day := midnight.AddDate(0, 0, i)
+		candidate := localTime(day, offset, loc)
+
+		openDay := day.Weekday()
+		if close && s.Close < s.Open { // the session closing on this day opened the day before
+			openDay = day.AddDate(0, 0, -1).Weekday()
+		}
+
+		if candidate.After(t) && s.tradingDay(openDay) {
+			return candidate
+		}
+	}
+
+	return time.Time{}
+}
+
+// NextOpen implements SessionCalendar.
+func (s DailySession) NextOpen(t time.Time) time.Time {
+	return s.next(t, s.Open, false)
+}
+
+// NextClose implements SessionCalendar.
+func (s DailySession) NextClose(t time.Time) time.Time {
+	return s.next(t, s.Close, true)
+}
+
+// WeeklySession is a SessionCalendar with a single session a week, e.g. the weekend from Friday 20:00 to Sunday
+// 22:00 New York time.
+type WeeklySession struct {
+	Location *time.Location
+	OpenDay  time.Weekday
+	Open     time.Duration // offset from midnight of the open day
+	CloseDay time.Weekday
+	Close    time.Duration // offset from midnight of the close day
+}
+
+// next returns the first time strictly after t at the offset of the weekday.
+func (s WeeklySession) next(t time.Time, day time.Weekday, offset time.Duration) time.Time {
+
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	local := t.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	for i := 0; i < 8; i++ {
+
+		d := midnight.AddDate(0, 0, i)
+		candidate := localTime(d, offset, loc)
+
+		if d.Weekday() == day && candidate.After(t) {
+			return candidate
+		}
+	}
+
+	return time.Time{}
+}
+
+// NextOpen implements SessionCalendar.
+func (s WeeklySession) NextOpen(t time.Time) time.Time {
+	return s.next(t, s.OpenDay, s.Open)
+}
+
+// NextClose implements SessionCalendar.
+func (s WeeklySession) NextClose(t time.Time) time.Time {
+	return s.next(t, s.CloseDay, s.Close)
+}
+
+// Period is a SessionCalendar with a single session from From to To, e.g. around a scheduled news release.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// NextOpen implements SessionCalendar.
+func (p Period) NextOpen(t time.Time) time.Time {
+
+	if t.Before(p.From) {
+		return p.From
+	}
+
+	return time.Time{}
+}
+
+// NextClose implements SessionCalendar.
+func (p Period) NextClose(t time.Time) time.Time {
+
+	if t.Before(p.To) {
+		return p.To
+	}
+
+	return time.Time{}
+}
+
+/*
+VenueCalendar is the SessionCalendar of the trading Hours of a venue closed on its Holidays: the sessions opening on
+a holiday, by the date in the Location of the venue, do not open, e.g. the US equities on Thanksgiving. It defines
+the market hours of instruments (see VenueHours), and so their flatten policies, the rollovers or the margin
+windows as any other calendar.
+*/
+type VenueCalendar struct {
+	Name     string
+	Hours    SessionCalendar
+	Location *time.Location // of the holiday dates, UTC when nil
+	Holidays []time.Time    // only their dates are used
+}
+
+// VenueHours is the functional option to define the venue of instruments, its calendar is their market hours as
+// with InstrumentMarketHours.
+func VenueHours(venue VenueCalendar, instruments ...string) Option {
+	return func(p *sessionParameters) {
+		for _, instrument := range instruments {
+			InstrumentMarketHours(instrument, venue)(p)
+		}
+	}
+}
+
+// maxHolidaySessions bounds the consecutive sessions of the hours skipped by the holidays of a venue.
+const maxHolidaySessions = 366
+
+// openOf returns the open of the session of the hours closing at close, the last one within the previous week.
+func (v VenueCalendar) openOf(close time.Time) time.Time {
+
+	var last time.Time
+
+	open := v.Hours.NextOpen(close.AddDate(0, 0, -8))
+	for !open.IsZero() && open.Before(close) {
+		last = open
+		open = v.Hours.NextOpen(open)
+	}
+
+	return last
+}
+
+// Holiday returns whether the date of t in the location of the venue is one of its holidays.
+func (v VenueCalendar) Holiday(t time.Time) bool {
+
+	if t.IsZero() {
+		return false
+	}
+
+	loc := v.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	year, month, day := t.In(loc).Date()
+
+	for _, h := range v.Holidays {
+		if y, m, d := h.Date(); y == year && m == month && d == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextOpen implements SessionCalendar.
+func (v VenueCalendar) NextOpen(t time.Time) time.Time {
+
+	open := v.Hours.NextOpen(t)
+	for i := 0; i < maxHolidaySessions && v.Holiday(open); i++ {
+		open = v.Hours.NextOpen(open)
+	}
+
+	return open
+}
+
+// NextClose implements SessionCalendar.
+func (v VenueCalendar) NextClose(t time.Time) time.Time {
+
+	close := v.Hours.NextClose(t)
+	for i := 0; i < maxHolidaySessions && !close.IsZero() && v.Holiday(v.openOf(close)); i++ {
+		close = v.Hours.NextClose(close)
+	}
+
+	return close
+}
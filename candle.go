@@ -0,0 +1,88 @@
+package gotrader
+
+import "time"
+
+// Candle represents the OHLC mid prices of an instrument over a timeframe starting at Time.
+type Candle struct {
+	Instrument string
+	Timeframe  time.Duration
+	Time       time.Time
+	Open       float64
+	High       float64
+	Low        float64
+	Close      float64
+	Ticks      int
+}
+
+// CandleBuilder aggregates ticks of an instrument into candles aligned to the timeframe.
+type CandleBuilder struct {
+	instrument string
+	timeframe  time.Duration
+	current    *Candle
+}
+
+// NewCandleBuilder is the CandleBuilder constructor.
+func NewCandleBuilder(instrument string, timeframe time.Duration) *CandleBuilder {
+	return &CandleBuilder{
+		instrument: instrument,
+		timeframe:  timeframe,
+	}
+}
+
+// Update adds a tick to the current candle and returns the previous candle when the tick starts a new one,
+// nil otherwise.
+func (b *CandleBuilder) Update(tick *Tick) *Candle {
+
+	if tick.Instrument != b.instrument {
+		return nil
+	}
+
+	mid := (tick.Bid + tick.Ask) / 2
+	start := tick.Time.Truncate(b.timeframe)
+
+	var closed *Candle
+
+	if b.current != nil && start.After(b.current.Time) {
+		closed = b.current
+		b.current = nil
+	}
+
+	if b.current == nil {
+		b.current = &Candle{
+			Instrument: b.instrument,
+			Timeframe:  b.timeframe,
+			Time:       start,
+			Open:       mid,
+			High:       mid,
+			Low:        mid,
+		}
+	}
+
+	if mid > b.current.High {
+		b.current.High = mid
+	}
+
+	if mid < b.current.Low {
+		b.current.Low = mid
+	}
+
+	b.current.Close = mid
+	b.current.Ticks++
+
+	return closed
+}
+
+// Instrument returns the instrument of the candles.
+func (b *CandleBuilder) Instrument() string {
+	return b.instrument
+}
+
+// Timeframe returns the timeframe of the candles.
+func (b *CandleBuilder) Timeframe() time.Duration {
+	return b.timeframe
+}
+
+// Current returns the candle being built, nil before the first tick.
+func (b *CandleBuilder) Current() *Candle {
+	return b.current
+}
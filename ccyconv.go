@@ -37,6 +37,7 @@ type currencyConversionEngine struct {
 	dependentBaseInstruments     map[string]map[string]bool // map of a set
 	dependentQuoteInstruments    map[string]map[string]bool // map of a set
 	homeCurrency                 string
+	instruments                  map[string]*Instrument // trading instruments, touched when their rates change
 	l                            Logger
 }
 
@@ -87,6 +88,8 @@ func (ce *currencyConversionEngine) start() {
 
 func (ce *currencyConversionEngine) setPricePointers(instruments map[string]*Instrument) {
 
+	ce.instruments = instruments
+
 	for _, inst := range instruments {
 		ce.conversionInstruments[inst.Name()].Ask = inst.ask
 		ce.conversionInstruments[inst.Name()].Bid = inst.bid
@@ -99,10 +102,18 @@ func (ce *currencyConversionEngine) updateRate(instrument string) {
 
 	for inst := range ce.dependentBaseInstruments[instrument] {
 		ce.conversionInstruments[inst].BaseConversionRate.Store(ce.calculateRate(ce.conversionInstruments[inst].BaseConversionFunction))
+		ce.touch(inst)
 	}
 
 	for inst := range ce.dependentQuoteInstruments[instrument] {
 		ce.conversionInstruments[inst].QuoteConversionRate.Store(ce.calculateRate(ce.conversionInstruments[inst].QuoteConversionFunction))
+		ce.touch(inst)
+	}
+}
+
+func (ce *currencyConversionEngine) touch(instrument string) {
+	if inst, exist := ce.instruments[instrument]; exist {
+		inst.touch()
 	}
 }
 
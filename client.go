@@ -1,6 +1,9 @@
 package gotrader
 
-import "time"
+import (
+	"sync"
+	"time"
+)
 
 type BrokerClient interface {
 	GetAccountStatus(accountID string) (AccountStatus, error)
@@ -16,6 +19,32 @@ type BrokerClient interface {
 	SubscribeFundsTransferNotifications(accountID string, fundsTransferCallback FundsTransferHandler) error
 }
 
+// Broker is a BrokerClient that is also able to manage pending orders, so the account model can be driven
+// by any execution venue. Fills of the submitted orders are streamed through the order fill notifications.
+type Broker interface {
+	BrokerClient
+
+	SubmitOrder(accountID string, order *Order) (string, error)
+	ModifyOrder(accountID, orderID string, order *Order) error
+	CancelOrder(accountID, orderID string) error
+	GetPendingOrders(accountID string) ([]*Order, error)
+}
+
+// Reconnector is implemented by clients that automatically recover their streams, the handler is called after
+// every successful reconnection so the engine can resynchronize its state with the broker.
+type Reconnector interface {
+	SubscribeReconnections(accountID string, callback ReconnectHandler) error
+}
+
+type ReconnectHandler func(t time.Time)
+
+// CandleProvider is implemented by clients serving the historical candles of the broker, to warm up the indicators
+// before trading. GetCandles returns the complete candles of the timeframe starting from from (inclusive) until
+// to (exclusive), sorted by time; brokers may cap the candles of a request, so the result may end before to.
+type CandleProvider interface {
+	GetCandles(instrument string, timeframe time.Duration, from, to time.Time) ([]*Candle, error)
+}
+
 type TradeDetails struct {
 	ID          string
 	Instrument  InstrumentDetails
@@ -24,6 +53,8 @@ type TradeDetails struct {
 	OpenPrice   float64
 	ChargedFees float64
 	OpenTime    time.Time
+	Venue       string
+	Tag         string
 }
 
 type InstrumentDetails struct {
@@ -32,6 +63,9 @@ type InstrumentDetails struct {
 	QuoteCurrency string
 	Leverage      float64
 	PipLocation   int
+	MinUnits      int32   // of the opens, zero without minimum
+	UnitSize      float64 // quantity of the base of a unit, e.g. the lot step of a crypto exchange, 1 when zero
+	StopDistance  float64 // minimum distance of the order levels from the prices in pips, see DistanceError
 }
 
 type AccountStatus struct {
@@ -47,27 +81,66 @@ type AccountStatus struct {
 
 type TickHandler func(tick *Tick)
 
+// Tick is a price update, BidSize and AskSize are the quoted sizes (zero when the venue does not report them).
+// Last and LastSize are the price and the size traded since the previous tick, zero when the venue does not
+// report its trades, see QueueFills.
 type Tick struct {
 	Instrument string
 	Bid        float64
 	Ask        float64
+	BidSize    float64
+	AskSize    float64
+	Last       float64
+	LastSize   float64
 	Time       time.Time
+	Sequence   uint64 // of the feed, zero when it does not number its ticks
+	BidSource  string // feed quoting the bid of a consolidated tick, see Consolidate
+	AskSource  string
+	arrival    time.Time // local arrival time, stamped when the latency is observed
+	pooled     bool
+}
+
+var tickPool = sync.Pool{
+	New: func() interface{} { return &Tick{} },
+}
+
+// AcquireTick returns a zeroed tick from the engines pool. The engine puts the ticks acquired by the clients back
+// into the pool once the strategy handled them, so strategies must copy a tick to keep it after OnTick returns.
+func AcquireTick() *Tick {
+	tick := tickPool.Get().(*Tick)
+	tick.pooled = true
+	return tick
+}
+
+// releaseTick puts a tick acquired with AcquireTick back into the pool, other ticks are left to the GC.
+func releaseTick(tick *Tick) {
+	if tick != nil && tick.pooled {
+		*tick = Tick{}
+		tickPool.Put(tick)
+	}
 }
 
 type OrderFillHandler func(order *OrderFill)
 
 type OrderFill struct {
-	Error       string
-	TradeClose  bool
-	OrderID     string
-	TradeID     string
-	Side        Side
-	Instrument  InstrumentDetails
-	Price       float64
-	Units       int32
-	Profit      float64
-	ChargedFees float64
-	Time        time.Time
+	Error         string
+	Rejection     RejectReason // of the failed fills, classified from Error unless the client sets it
+	TradeClose    bool
+	OrderID       string
+	ClientOrderID string // client ID of the order, when the broker or the engine knows it
+	TradeID       string
+	Side          Side
+	Instrument    InstrumentDetails
+	Price         float64
+	Units         int32
+	Profit        float64
+	ChargedFees   float64
+	Time          time.Time
+	Venue         string
+	Tag           string
+	Reason        CloseReason   // of the trade closes
+	Gap           bool          // filled on the tick of a price gap, see GapPolicy
+	Latency       time.Duration // from the request to the venue, when the client simulates it, see paper.Latency
 }
 
 type SwapChargeHandler func(charges *SwapCharge)
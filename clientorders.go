@@ -0,0 +1,266 @@
+package gotrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+clientOrders tracks the client order IDs of the submitted orders, the idempotency keys sent to the brokers
+supporting them. The orders submitted without one are given a unique ID, the prefix of the session and a
+counter. A submission that failed with a timeout may have reached the broker: it is kept unresolved, so the
+same order can be submitted again with its client ID and the broker dedupes it, and it is resolved by its first
+fill. A submission of a client ID already acknowledged returns the broker ID without sending the order again.
+
+The market orders sent to the clients without order IDs (see BrokerClient.OpenMarketOrder) are resolved by the
+first fill of their instrument and side. The client IDs no longer in flight are forgotten after
+clientOrderRetention, so the retries of a submission are deduped but the session doesn't keep every order.
+*/
+type clientOrders struct {
+	mutex    *sync.Mutex
+	clock    Clock // of the session, timing the retention
+	prefix   string
+	counter  int64
+	byClient map[string]*clientOrder
+	byBroker map[string]string   // client IDs by broker order ID
+	markets  map[string][]string // client IDs of the market orders without broker ID by marketKey, oldest first
+	expiries []clientExpiry      // by time
+}
+
+type clientOrder struct {
+	brokerID string // empty until acknowledged
+	market   string // marketKey of a market order sent without client ID
+	inFlight bool
+	expiry   time.Time // forgotten at, once no longer in flight
+}
+
+type clientExpiry struct {
+	clientID string
+	time     time.Time
+}
+
+// clientOrderRetention is the time the client IDs are kept after their last submission or fill.
+const clientOrderRetention = 15 * time.Minute
+
+func newClientOrders(prefix string, clock Clock) *clientOrders {
+	return &clientOrders{
+		mutex:    &sync.Mutex{},
+		clock:    clock,
+		prefix:   prefix,
+		byClient: make(map[string]*clientOrder),
+		byBroker: make(map[string]string),
+		markets:  make(map[string][]string),
+	}
+}
+
+// sessionPrefix returns a prefix of the client IDs unique to the session start.
+func sessionPrefix(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 36)
+}
+
+// isTimeout returns whether an error leaves the outcome of a request unknown.
+func isTimeout(err error) bool {
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// begin registers the submission of the order, giving it a client ID when it has none. It returns the broker ID
+// when the order was already acknowledged, and ErrDuplicateOrder when it is still in flight.
+func (c *clientOrders) begin(order *Order) (string, bool, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.evict(c.clock.Now())
+	c.generate(order)
+
+	o, exist := c.byClient[order.ClientID]
+
+	switch {
+	case !exist:
+		c.byClient[order.ClientID] = &clientOrder{inFlight: true}
+	case o.brokerID != "":
+		return o.brokerID, true, nil
+	case o.inFlight:
+		return "", false, fmt.Errorf("client order %s: %w", order.ClientID, ErrDuplicateOrder)
+	default: // unresolved after a timeout
+		o.inFlight = true
+	}
+
+	return "", false, nil
+}
+
+// beginMarket registers the submission of a market order to a client without order IDs, giving it a client ID
+// when it has none. The order is resolved by the first fill of its instrument and side, see fill.
+func (c *clientOrders) beginMarket(order *Order) (bool, error) {
+
+	_, acknowledged, err := c.begin(order)
+	if err != nil || acknowledged {
+		return acknowledged, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if o, exist := c.byClient[order.ClientID]; exist && o.market == "" {
+		o.market = marketKey(order.Instrument, order.Side)
+		c.markets[o.market] = append(c.markets[o.market], order.ClientID)
+	}
+
+	return false, nil
+}
+
+// assign gives the order a client ID when it has none, e.g. to track it before it is submitted.
+func (c *clientOrders) assign(order *Order) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generate(order)
+}
+
+func (c *clientOrders) generate(order *Order) {
+
+	if order.ClientID == "" {
+		c.counter++
+		order.ClientID = c.prefix + "-" + strconv.FormatInt(c.counter, 10)
+	}
+}
+
+// end records the outcome of a submission, the rejected client IDs are forgotten.
+func (c *clientOrders) end(clientID, brokerID string, err error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	o, exist := c.byClient[clientID]
+	if !exist {
+		return
+	}
+
+	o.inFlight = false
+
+	switch {
+	case err == nil:
+		c.acknowledge(clientID, o, brokerID)
+	case !isTimeout(err):
+		c.forget(clientID, o)
+		return
+	}
+
+	c.retain(clientID, o)
+}
+
+func (c *clientOrders) acknowledge(clientID string, o *clientOrder, brokerID string) {
+
+	if brokerID == "" || o.brokerID != "" {
+		return
+	}
+
+	o.brokerID = brokerID
+	c.byBroker[brokerID] = clientID
+}
+
+// retain schedules the eviction of a client ID, at clientOrderRetention from now.
+func (c *clientOrders) retain(clientID string, o *clientOrder) {
+
+	o.expiry = c.clock.Now().Add(clientOrderRetention)
+	c.expiries = append(c.expiries, clientExpiry{clientID: clientID, time: o.expiry})
+}
+
+// evict forgets the client IDs whose retention ended, unless they were submitted again meanwhile.
+func (c *clientOrders) evict(now time.Time) {
+
+	for len(c.expiries) > 0 && !c.expiries[0].time.After(now) {
+
+		x := c.expiries[0]
+		c.expiries = c.expiries[1:]
+
+		if o, exist := c.byClient[x.clientID]; exist && !o.inFlight && o.expiry.Equal(x.time) {
+			c.forget(x.clientID, o)
+		}
+	}
+}
+
+func (c *clientOrders) forget(clientID string, o *clientOrder) {
+
+	delete(c.byClient, clientID)
+
+	if o.brokerID != "" {
+		delete(c.byBroker, o.brokerID)
+	}
+
+	if o.market != "" {
+		c.resolve(o.market, clientID)
+	}
+}
+
+// resolve removes a client ID from the market orders waiting for their fill.
+func (c *clientOrders) resolve(key, clientID string) {
+
+	queue := c.markets[key]
+
+	for n, id := range queue {
+		if id == clientID {
+			queue = append(queue[:n], queue[n+1:]...)
+			break
+		}
+	}
+
+	if len(queue) == 0 {
+		delete(c.markets, key)
+		return
+	}
+
+	c.markets[key] = queue
+}
+
+// fill sets the client ID of the fills of the orders known by their broker ID, or of the market orders sent
+// without client ID by their instrument and side, resolves the submissions unresolved after a timeout and forgets
+// the rejected orders, so they can be submitted again.
+func (c *clientOrders) fill(fill *OrderFill) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if fill.ClientOrderID == "" {
+		fill.ClientOrderID = c.byBroker[fill.OrderID]
+	}
+
+	if fill.ClientOrderID == "" && !fill.TradeClose {
+		if queue := c.markets[marketKey(fill.Instrument.Name, fill.Side)]; len(queue) > 0 {
+			fill.ClientOrderID = queue[0]
+		}
+	}
+
+	o, exist := c.byClient[fill.ClientOrderID]
+	if !exist || fill.ClientOrderID == "" {
+		return
+	}
+
+	if o.market != "" {
+		c.resolve(o.market, fill.ClientOrderID)
+		o.market = ""
+	}
+
+	if fill.Error != "" {
+		c.forget(fill.ClientOrderID, o)
+		return
+	}
+
+	c.acknowledge(fill.ClientOrderID, o, fill.OrderID)
+
+	if !o.inFlight {
+		c.retain(fill.ClientOrderID, o)
+	}
+}
@@ -0,0 +1,628 @@
+package alpaca
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/tools"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/atomic"
+)
+
+// venue is the venue of the alpaca symbols in a gotrader.SymbolMap.
+const venue = "alpaca"
+
+// Config holds the alpaca credentials and trading settings.
+type Config struct {
+	KeyID         string
+	SecretKey     string
+	Paper         bool
+	Feed          string // market data feed, iex or sip (defaults to iex)
+	ExtendedHours bool   // allow trading on pre-market and after-hours sessions
+	Symbols       []string
+	SymbolMap     *gotrader.SymbolMap // maps the symbols of the "alpaca" venue to the instrument names
+}
+
+type account struct {
+	Currency    string `json:"currency"`
+	Cash        string `json:"cash"`
+	Equity      string `json:"equity"`
+	BuyingPower string `json:"buying_power"`
+	Multiplier  string `json:"multiplier"`
+	InitMargin  string `json:"initial_margin"`
+}
+
+type position struct {
+	Symbol        string `json:"symbol"`
+	Qty           string `json:"qty"`
+	AvgEntryPrice string `json:"avg_entry_price"`
+	Side          string `json:"side"`
+}
+
+type clock struct {
+	IsOpen    bool      `json:"is_open"`
+	NextOpen  time.Time `json:"next_open"`
+	NextClose time.Time `json:"next_close"`
+}
+
+type orderRequest struct {
+	Symbol        string `json:"symbol"`
+	Qty           string `json:"qty"`
+	Side          string `json:"side"`
+	Type          string `json:"type"`
+	TimeInForce   string `json:"time_in_force"`
+	LimitPrice    string `json:"limit_price,omitempty"`
+	ExtendedHours bool   `json:"extended_hours"`
+	ClientOrderID string `json:"client_order_id"`
+}
+
+type quote struct {
+	Type   string    `json:"T"`
+	Symbol string    `json:"S"`
+	Bid    float64   `json:"bp"`
+	Ask    float64   `json:"ap"`
+	Time   time.Time `json:"t"`
+}
+
+type tradeUpdate struct {
+	Stream string `json:"stream"`
+	Data   struct {
+		Event     string    `json:"event"`
+		Price     string    `json:"price"`
+		Qty       string    `json:"qty"`
+		Timestamp time.Time `json:"timestamp"`
+		Order     struct {
+			ID            string `json:"id"`
+			ClientOrderID string `json:"client_order_id"`
+			Symbol        string `json:"symbol"`
+			Side          string `json:"side"`
+		} `json:"order"`
+	} `json:"data"`
+}
+
+type alpacaTrade struct {
+	details gotrader.TradeDetails
+}
+
+type alpacaClient struct {
+	cfg               Config
+	restURL           string
+	dataURL           string
+	client            http.Client
+	mutex             *sync.Mutex
+	orderCounter      *atomic.Int64
+	quotes            map[string]*gotrader.Tick
+	trades            map[string]*alpacaTrade
+	closeRequests     map[string]string
+	orderFillCallback gotrader.OrderFillHandler
+	reconnectCallback gotrader.ReconnectHandler
+	backoff           *tools.Backoff
+}
+
+// NewAlpacaClient is the alpaca US equities adapter constructor. Alpaca nets positions per symbol,
+// so trades are tracked locally from the fills and closed with opposite side orders.
+func NewAlpacaClient(cfg Config) gotrader.BrokerClient {
+
+	restURL := "https://api.alpaca.markets"
+	if cfg.Paper {
+		restURL = "https://paper-api.alpaca.markets"
+	}
+
+	if cfg.Feed == "" {
+		cfg.Feed = "iex"
+	}
+
+	return &alpacaClient{
+		cfg:           cfg,
+		restURL:       restURL,
+		dataURL:       "wss://stream.data.alpaca.markets/v2/" + cfg.Feed,
+		mutex:         &sync.Mutex{},
+		orderCounter:  atomic.NewInt64(time.Now().Unix()),
+		backoff:       tools.NewBackoff(100*time.Millisecond, time.Minute, 0),
+		quotes:        make(map[string]*gotrader.Tick),
+		trades:        make(map[string]*alpacaTrade),
+		closeRequests: make(map[string]string),
+	}
+}
+
+func (c *alpacaClient) request(method, endpoint string, body interface{}, data interface{}) error {
+	return c.requestContext(context.Background(), method, endpoint, body, data)
+}
+
+// requestContext sends a request propagating the trace of the context in its headers.
+func (c *alpacaClient) requestContext(ctx context.Context, method, endpoint string, body interface{}, data interface{}) error {
+
+	var payload []byte
+
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.restURL+endpoint, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	req.Header.Set("APCA-API-KEY-ID", c.cfg.KeyID)
+	req.Header.Set("APCA-API-SECRET-KEY", c.cfg.SecretKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	response, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable { // not processed
+		return fmt.Errorf("%w: alpaca error %d: %s", gotrader.ErrTransient, res.StatusCode, response)
+	}
+
+	if res.StatusCode >= 300 {
+		return errors.New("alpaca error " + strconv.Itoa(res.StatusCode) + ": " + string(response))
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	return json.Unmarshal(response, data)
+}
+
+func instrumentDetails(name, currency string, leverage float64) gotrader.InstrumentDetails {
+	return gotrader.InstrumentDetails{
+		Name:          name,
+		BaseCurrency:  name,
+		QuoteCurrency: currency,
+		Leverage:      leverage,
+		PipLocation:   -2,
+	}
+}
+
+func (c *alpacaClient) GetAccountStatus(accountID string) (gotrader.AccountStatus, error) {
+
+	acc := account{}
+	if err := c.request(http.MethodGet, "/v2/account", nil, &acc); err != nil {
+		return gotrader.AccountStatus{}, err
+	}
+
+	leverage := parseFloat(acc.Multiplier)
+	if leverage == 0 {
+		leverage = 1
+	}
+
+	return gotrader.AccountStatus{
+		Currency:   acc.Currency,
+		Hedge:      gotrader.NoHedge,
+		Equity:     parseFloat(acc.Equity),
+		Balance:    parseFloat(acc.Cash),
+		MarginUsed: parseFloat(acc.InitMargin),
+		MarginFree: parseFloat(acc.BuyingPower) / leverage,
+		Leverage:   leverage,
+	}, nil
+}
+
+// SupportsHedge implements gotrader.HedgeValidator, the positions of a symbol are netted.
+func (c *alpacaClient) SupportsHedge(instrument gotrader.InstrumentDetails, hedge gotrader.Hedge) bool {
+	return hedge == gotrader.NoHedge
+}
+
+func (c *alpacaClient) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+
+	acc := account{}
+	if err := c.request(http.MethodGet, "/v2/account", nil, &acc); err != nil {
+		return nil, err
+	}
+
+	instruments := make([]gotrader.InstrumentDetails, len(c.cfg.Symbols))
+	for i, s := range c.cfg.Symbols {
+		instruments[i] = instrumentDetails(c.cfg.SymbolMap.Canonical(venue, s), acc.Currency, math.Max(parseFloat(acc.Multiplier), 1))
+	}
+
+	return instruments, nil
+}
+
+// session returns the current session, using the alpaca market clock to detect holidays.
+func (c *alpacaClient) session() (Session, error) {
+
+	clk := clock{}
+	if err := c.request(http.MethodGet, "/v2/clock", nil, &clk); err != nil {
+		return Closed, err
+	}
+
+	if clk.IsOpen {
+		return Regular, nil
+	}
+
+	now := time.Now()
+	session := SessionAt(now)
+
+	if session == Regular || clk.NextOpen.Sub(now) > 24*time.Hour { // holiday
+		return Closed, nil
+	}
+
+	return session, nil
+}
+
+func (c *alpacaClient) placeMarketOrder(ctx context.Context, instrument string, units int32, side gotrader.Side, clientID string) error {
+
+	session, err := c.session()
+	if err != nil {
+		return err
+	}
+
+	order := orderRequest{
+		Symbol:        c.cfg.SymbolMap.Symbol(venue, instrument),
+		Qty:           strconv.Itoa(int(units)),
+		Side:          "buy",
+		Type:          "market",
+		TimeInForce:   "day",
+		ClientOrderID: clientID,
+	}
+
+	if side == gotrader.Short {
+		order.Side = "sell"
+	}
+
+	switch session {
+	case Regular:
+	case PreMarket, AfterHours:
+
+		if !c.cfg.ExtendedHours {
+			return errors.New("market is on " + session.String() + " session and extended hours are disabled")
+		}
+
+		// extended hours only accept limit orders, send a marketable limit at the current quote
+		c.mutex.Lock()
+		q, exist := c.quotes[instrument]
+		c.mutex.Unlock()
+
+		if !exist {
+			return errors.New("no quote available for " + instrument)
+		}
+
+		price := q.Ask
+		if side == gotrader.Short {
+			price = q.Bid
+		}
+
+		order.Type = "limit"
+		order.LimitPrice = strconv.FormatFloat(price, 'f', 2, 64)
+		order.ExtendedHours = true
+
+	default:
+		return errors.New("market is closed")
+	}
+
+	return c.requestContext(ctx, http.MethodPost, "/v2/orders", order, nil)
+}
+
+func (c *alpacaClient) nextClientID() string {
+	return "gt-" + strconv.FormatInt(c.orderCounter.Inc(), 10)
+}
+
+func (c *alpacaClient) OpenMarketOrder(accountID, instrument string, units int32, side string) error {
+	return c.OpenMarketOrderContext(context.Background(), accountID, instrument, units, side)
+}
+
+// OpenMarketOrderContext implements gotrader.ContextClient.
+func (c *alpacaClient) OpenMarketOrderContext(ctx context.Context, accountID, instrument string, units int32, side string) error {
+
+	s := gotrader.Long
+	if side == gotrader.Short.String() {
+		s = gotrader.Short
+	}
+
+	return c.placeMarketOrder(ctx, instrument, units, s, c.nextClientID())
+}
+
+func (c *alpacaClient) CloseTrade(accountID, id string) error {
+	return c.CloseTradeContext(context.Background(), accountID, id)
+}
+
+// CloseTradeContext implements gotrader.ContextClient.
+func (c *alpacaClient) CloseTradeContext(ctx context.Context, accountID, id string) error {
+
+	c.mutex.Lock()
+	trade, exist := c.trades[id]
+	c.mutex.Unlock()
+
+	if !exist {
+		return errors.New("trade " + id + " does not exist")
+	}
+
+	side := gotrader.Long
+	if trade.details.Side == gotrader.Long {
+		side = gotrader.Short
+	}
+
+	clientID := c.nextClientID()
+
+	c.mutex.Lock()
+	c.closeRequests[clientID] = id
+	c.mutex.Unlock()
+
+	return c.placeMarketOrder(ctx, trade.details.Instrument.Name, trade.details.Units, side, clientID)
+}
+
+// GetOpenTrades maps each alpaca position to a single trade.
+func (c *alpacaClient) GetOpenTrades(accountID string) ([]gotrader.TradeDetails, error) {
+
+	acc := account{}
+	if err := c.request(http.MethodGet, "/v2/account", nil, &acc); err != nil {
+		return nil, err
+	}
+
+	positions := make([]position, 0)
+	if err := c.request(http.MethodGet, "/v2/positions", nil, &positions); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	trades := make([]gotrader.TradeDetails, 0, len(positions))
+
+	for _, p := range positions {
+
+		side := gotrader.Long
+		if p.Side == "short" {
+			side = gotrader.Short
+		}
+
+		details := gotrader.TradeDetails{
+			ID:         "POS-" + p.Symbol,
+			Instrument: instrumentDetails(c.cfg.SymbolMap.Canonical(venue, p.Symbol), acc.Currency, math.Max(parseFloat(acc.Multiplier), 1)),
+			Side:       side,
+			Units:      int32(math.Abs(parseFloat(p.Qty))),
+			OpenPrice:  parseFloat(p.AvgEntryPrice),
+			OpenTime:   time.Now(),
+		}
+
+		c.trades[details.ID] = &alpacaTrade{details: details}
+		trades = append(trades, details)
+	}
+
+	return trades, nil
+}
+
+func (c *alpacaClient) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+
+	symbols := make([]string, len(instruments))
+	for i, inst := range instruments {
+		symbols[i] = c.cfg.SymbolMap.Symbol(venue, inst.Name)
+	}
+
+	subscribe := map[string]interface{}{"action": "subscribe", "quotes": symbols}
+
+	return c.stream(c.dataURL, subscribe, func(data []byte) {
+
+		quotes := make([]quote, 0)
+		if json.Unmarshal(data, &quotes) != nil {
+			return
+		}
+
+		for _, q := range quotes {
+
+			if q.Type != "q" || q.Bid == 0 || q.Ask == 0 {
+				continue
+			}
+
+			tick := &gotrader.Tick{Instrument: c.cfg.SymbolMap.Canonical(venue, q.Symbol), Bid: q.Bid, Ask: q.Ask,
+				Time: q.Time}
+
+			c.mutex.Lock()
+			c.quotes[tick.Instrument] = tick
+			c.mutex.Unlock()
+
+			callback(tick)
+		}
+	})
+}
+
+func (c *alpacaClient) SubscribeOrderFillNotifications(accountID string, orderFillCallback gotrader.OrderFillHandler) error {
+
+	c.orderFillCallback = orderFillCallback
+
+	listen := map[string]interface{}{
+		"action": "listen",
+		"data":   map[string][]string{"streams": {"trade_updates"}},
+	}
+
+	return c.stream("wss"+c.restURL[len("https"):]+"/stream", listen, c.onTradeUpdate)
+}
+
+// SubscribeSwapChargeNotifications is a no-op, margin interest is charged in the cash balance.
+func (c *alpacaClient) SubscribeSwapChargeNotifications(accountID string, swapChargeCallback gotrader.SwapChargeHandler) error {
+	return nil
+}
+
+// SubscribeReconnections sets the callback called after a websocket stream is recovered.
+func (c *alpacaClient) SubscribeReconnections(accountID string, callback gotrader.ReconnectHandler) error {
+	c.reconnectCallback = callback
+	return nil
+}
+
+// SubscribeFundsTransferNotifications is a no-op.
+func (c *alpacaClient) SubscribeFundsTransferNotifications(accountID string, fundsTransferCallback gotrader.FundsTransferHandler) error {
+	return nil
+}
+
+// stream authenticates and subscribes a websocket, reconnecting with exponential backoff.
+func (c *alpacaClient) stream(endpoint string, subscription interface{}, handler func(data []byte)) error {
+
+	dial := func() (*websocket.Conn, error) {
+
+		conn, _, err := websocket.DefaultDialer.Dial(endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		auth := map[string]interface{}{"action": "auth", "key": c.cfg.KeyID, "secret": c.cfg.SecretKey}
+		if err := conn.WriteJSON(auth); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		if err := conn.WriteJSON(subscription); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		return conn, nil
+	}
+
+	conn, err := dial()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				conn.Close()
+				c.backoff.Retry(func() (err error) {
+					conn, err = dial()
+					return err
+				})
+				if c.reconnectCallback != nil {
+					c.reconnectCallback(time.Now())
+				}
+				continue
+			}
+			handler(data)
+		}
+	}()
+
+	return nil
+}
+
+func (c *alpacaClient) onTradeUpdate(data []byte) {
+
+	update := tradeUpdate{}
+	if json.Unmarshal(data, &update) != nil || update.Stream != "trade_updates" || c.orderFillCallback == nil {
+		return
+	}
+
+	order := update.Data.Order
+
+	side := gotrader.Long
+	if order.Side == "sell" {
+		side = gotrader.Short
+	}
+
+	switch update.Data.Event {
+	case "rejected", "canceled", "expired":
+		c.mutex.Lock()
+		delete(c.closeRequests, order.ClientOrderID)
+		c.mutex.Unlock()
+
+		c.orderFillCallback(&gotrader.OrderFill{
+			Error:   "ORDER_" + update.Data.Event,
+			OrderID: order.ClientOrderID,
+			Side:    side,
+			Time:    update.Data.Timestamp,
+		})
+		return
+	case "fill", "partial_fill":
+	default:
+		return
+	}
+
+	price := parseFloat(update.Data.Price)
+	units := int32(math.Abs(parseFloat(update.Data.Qty)))
+
+	c.mutex.Lock()
+
+	var fill *gotrader.OrderFill
+
+	if tradeID, isClose := c.closeRequests[order.ClientOrderID]; isClose {
+
+		trade := c.trades[tradeID]
+
+		if update.Data.Event == "fill" {
+			delete(c.closeRequests, order.ClientOrderID)
+			delete(c.trades, tradeID)
+		}
+
+		if trade != nil {
+			fill = &gotrader.OrderFill{
+				TradeClose: true,
+				OrderID:    order.ClientOrderID,
+				TradeID:    tradeID,
+				Side:       trade.details.Side,
+				Instrument: trade.details.Instrument,
+				Price:      price,
+				Units:      units,
+				Profit:     (price - trade.details.OpenPrice) * float64(units) * sign(trade.details.Side),
+				Time:       update.Data.Timestamp,
+			}
+		}
+
+	} else {
+
+		tradeID := order.ID + "-" + strconv.FormatInt(update.Data.Timestamp.UnixNano(), 10)
+		instrument := instrumentDetails(c.cfg.SymbolMap.Canonical(venue, order.Symbol), "USD", 1)
+
+		c.trades[tradeID] = &alpacaTrade{details: gotrader.TradeDetails{
+			ID:         tradeID,
+			Instrument: instrument,
+			Side:       side,
+			Units:      units,
+			OpenPrice:  price,
+			OpenTime:   update.Data.Timestamp,
+		}}
+
+		fill = &gotrader.OrderFill{
+			OrderID:    order.ClientOrderID,
+			TradeID:    tradeID,
+			Side:       side,
+			Instrument: instrument,
+			Price:      price,
+			Units:      units,
+			Time:       update.Data.Timestamp,
+		}
+	}
+
+	c.mutex.Unlock()
+
+	if fill != nil {
+		c.orderFillCallback(fill)
+	}
+}
+
+func parseFloat(value string) float64 {
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
+
+func sign(side gotrader.Side) float64 {
+
+	if side == gotrader.Short {
+		return -1
+	}
+
+	return 1
+}
@@ -0,0 +1,64 @@
+package alpaca
+
+import (
+	"time"
+)
+
+// Session identifies the US equities trading session of a point in time.
+type Session int
+
+const (
+	// Closed means no trading is possible
+	Closed Session = iota
+
+	// PreMarket runs from 04:00 to 09:30 New York time
+	PreMarket
+
+	// Regular runs from 09:30 to 16:00 New York time
+	Regular
+
+	// AfterHours runs from 16:00 to 20:00 New York time
+	AfterHours
+)
+
+func (s Session) String() string {
+
+	names := [...]string{"CLOSED", "PRE_MARKET", "REGULAR", "AFTER_HOURS"}
+
+	return names[s]
+}
+
+var newYork = loadNewYork()
+
+func loadNewYork() *time.Location {
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil { // tzdata is not available, fall back to EST without daylight saving
+		return time.FixedZone("EST", -5*60*60)
+	}
+
+	return loc
+}
+
+// SessionAt returns the session of t for a trading day, holidays must be checked against the market clock.
+func SessionAt(t time.Time) Session {
+
+	local := t.In(newYork)
+
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return Closed
+	}
+
+	minutes := local.Hour()*60 + local.Minute()
+
+	switch {
+	case minutes >= 4*60 && minutes < 9*60+30:
+		return PreMarket
+	case minutes >= 9*60+30 && minutes < 16*60:
+		return Regular
+	case minutes >= 16*60 && minutes < 20*60:
+		return AfterHours
+	}
+
+	return Closed
+}
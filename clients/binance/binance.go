@@ -0,0 +1,767 @@
+package binance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/tools"
+	"go.uber.org/atomic"
+)
+
+// Market selects the binance market the client operates.
+type Market int
+
+const (
+	// Spot market
+	Spot Market = iota
+
+	// USDMFutures is the USD margined perpetual futures market
+	USDMFutures
+)
+
+const listenKeyKeepAlive = 30 * time.Minute
+
+// venue is the venue of the binance symbols in a gotrader.SymbolMap.
+const venue = "binance"
+
+// Config holds the binance credentials and market settings.
+type Config struct {
+	APIKey     string
+	SecretKey  string
+	Market     Market
+	HomeAsset  string  // asset used as account currency, defaults to USDT
+	Leverage   float64 // futures leverage, spot is always 1
+	Testnet    bool
+	Instrument []string            // instruments to load, as BASE_QUOTE, e.g. BTC_USDT. All trading symbols when empty
+	SymbolMap  *gotrader.SymbolMap // aliases of the symbols of the "binance" venue, named BASE_QUOTE without alias
+
+	// Limiter is the rate limiter of the order requests, it can be shared by several clients of the same
+	// binance account. Defaults to the spot limit of 50 orders per 10 seconds.
+	Limiter *tools.RateLimiter
+
+	// StreamErrors is called with the failures of the listen key keep-alives and of the stream reconnections,
+	// the user stream is reconnected with a new listen key after them. Optional.
+	StreamErrors func(err error)
+}
+
+type symbolFilters struct {
+	symbol      string
+	minQty      float64
+	stepSize    float64
+	minNotional float64
+}
+
+type binanceTrade struct {
+	details gotrader.TradeDetails
+}
+
+// closeFill accumulates the executions of a close order, binance fills a market order in several of them.
+type closeFill struct {
+	units      int32
+	notional   float64 // price times units, for the average price
+	profit     float64
+	commission float64
+}
+
+type binanceClient struct {
+	cfg               Config
+	rest              *restClient
+	wsURL             string
+	mutex             *sync.Mutex
+	orderCounter      *atomic.Int64
+	filters           map[string]*symbolFilters // by instrument name
+	instruments       map[string]gotrader.InstrumentDetails
+	bySymbol          map[string]string
+	lastPrices        map[string]*gotrader.Tick
+	trades            map[string]*binanceTrade
+	closeRequests     map[string]string     // client order id to trade id
+	closeFills        map[string]*closeFill // by client order id, until the close order is done
+	orderFillCallback gotrader.OrderFillHandler
+	reconnectCallback gotrader.ReconnectHandler
+	backoff           *tools.Backoff
+	limiter           *tools.RateLimiter
+}
+
+// NewBinanceClient is the binance spot and USD-M futures adapter constructor.
+// Units are expressed in multiples of the symbol LOT_SIZE step, e.g. 1 unit of BTC_USDT with a 0.00001
+// step size is 0.00001 BTC. Spot has no positions, so trades are tracked locally from the fills.
+func NewBinanceClient(cfg Config) gotrader.BrokerClient {
+
+	if cfg.HomeAsset == "" {
+		cfg.HomeAsset = "USDT"
+	}
+
+	if cfg.Market == Spot || cfg.Leverage == 0 {
+		cfg.Leverage = 1
+	}
+
+	var restURL, wsURL string
+
+	switch {
+	case cfg.Market == Spot && !cfg.Testnet:
+		restURL, wsURL = "https://api.binance.com", "wss://stream.binance.com:9443"
+	case cfg.Market == Spot && cfg.Testnet:
+		restURL, wsURL = "https://testnet.binance.vision", "wss://testnet.binance.vision"
+	case cfg.Market == USDMFutures && !cfg.Testnet:
+		restURL, wsURL = "https://fapi.binance.com", "wss://fstream.binance.com"
+	default:
+		restURL, wsURL = "https://testnet.binancefuture.com", "wss://stream.binancefuture.com"
+	}
+
+	if cfg.Limiter == nil {
+		cfg.Limiter = tools.NewRateLimiter(tools.Budget{Rate: 5, Burst: 50, Reserve: 5})
+	}
+
+	return &binanceClient{
+		cfg:           cfg,
+		rest:          &restClient{baseURL: restURL, apiKey: cfg.APIKey, secretKey: cfg.SecretKey},
+		wsURL:         wsURL,
+		mutex:         &sync.Mutex{},
+		orderCounter:  atomic.NewInt64(time.Now().Unix()),
+		backoff:       tools.NewBackoff(100*time.Millisecond, time.Minute, 0),
+		limiter:       cfg.Limiter,
+		filters:       make(map[string]*symbolFilters),
+		instruments:   make(map[string]gotrader.InstrumentDetails),
+		bySymbol:      make(map[string]string),
+		lastPrices:    make(map[string]*gotrader.Tick),
+		trades:        make(map[string]*binanceTrade),
+		closeRequests: make(map[string]string),
+		closeFills:    make(map[string]*closeFill),
+	}
+}
+
+func (c *binanceClient) endpoint(spot, futures string) string {
+
+	if c.cfg.Market == Spot {
+		return spot
+	}
+
+	return futures
+}
+
+func (c *binanceClient) GetAccountStatus(accountID string) (gotrader.AccountStatus, error) {
+
+	if c.cfg.Market == Spot {
+
+		account := spotAccount{}
+		if err := c.rest.signed(http.MethodGet, "/api/v3/account", nil, &account); err != nil {
+			return gotrader.AccountStatus{}, err
+		}
+
+		balance := 0.0
+		for _, b := range account.Balances {
+			if b.Asset == c.cfg.HomeAsset {
+				balance = parseFloat(b.Free) + parseFloat(b.Locked)
+			}
+		}
+
+		return gotrader.AccountStatus{
+			Currency:   c.cfg.HomeAsset,
+			Hedge:      gotrader.NoHedge,
+			Equity:     balance,
+			Balance:    balance,
+			MarginFree: balance,
+			Leverage:   1,
+		}, nil
+	}
+
+	account := futuresAccount{}
+	if err := c.rest.signed(http.MethodGet, "/fapi/v2/account", nil, &account); err != nil {
+		return gotrader.AccountStatus{}, err
+	}
+
+	return gotrader.AccountStatus{
+		Currency:              c.cfg.HomeAsset,
+		Hedge:                 gotrader.NoHedge,
+		Equity:                parseFloat(account.TotalMarginBalance),
+		Balance:               parseFloat(account.TotalWalletBalance),
+		UnrealizedGrossProfit: parseFloat(account.TotalUnrealizedProfit),
+		MarginUsed:            parseFloat(account.TotalInitialMargin),
+		MarginFree:            parseFloat(account.AvailableBalance),
+		Leverage:              c.cfg.Leverage,
+	}, nil
+}
+
+// SupportsHedge implements gotrader.HedgeValidator, spot balances and one-way futures positions are netted.
+func (c *binanceClient) SupportsHedge(instrument gotrader.InstrumentDetails, hedge gotrader.Hedge) bool {
+	return hedge == gotrader.NoHedge
+}
+
+func (c *binanceClient) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+
+	info := exchangeInfo{}
+	if err := c.rest.public(http.MethodGet, c.endpoint("/api/v3/exchangeInfo", "/fapi/v1/exchangeInfo"), nil, &info); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, inst := range c.cfg.Instrument {
+		wanted[inst] = true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	instruments := make([]gotrader.InstrumentDetails, 0, len(info.Symbols))
+
+	for _, s := range info.Symbols {
+
+		name, exist := c.cfg.SymbolMap.Lookup(venue, s.Symbol)
+		if !exist {
+			name = s.BaseAsset + "_" + s.QuoteAsset
+		}
+
+		if s.Status != "TRADING" || (len(wanted) > 0 && !wanted[name]) {
+			continue
+		}
+
+		filters := &symbolFilters{symbol: s.Symbol, stepSize: 1}
+
+		for _, raw := range s.Filters {
+			f := filter{}
+			if json.Unmarshal(raw, &f) != nil {
+				continue
+			}
+			switch f.FilterType {
+			case "LOT_SIZE":
+				filters.minQty = parseFloat(f.MinQty)
+				filters.stepSize = parseFloat(f.StepSize)
+			case "MIN_NOTIONAL", "NOTIONAL":
+				filters.minNotional = math.Max(parseFloat(f.MinNotional), parseFloat(f.Notional))
+			}
+		}
+
+		details := gotrader.InstrumentDetails{
+			Name:          name,
+			BaseCurrency:  s.BaseAsset,
+			QuoteCurrency: s.QuoteAsset,
+			Leverage:      c.cfg.Leverage,
+			PipLocation:   int(math.Round(math.Log10(filters.stepSize))),
+			UnitSize:      filters.stepSize,
+		}
+
+		c.filters[name] = filters
+		c.instruments[name] = details
+		c.bySymbol[s.Symbol] = name
+		instruments = append(instruments, details)
+	}
+
+	return instruments, nil
+}
+
+// validate checks the order against the exchange filters, so orders are rejected before reaching binance.
+func (c *binanceClient) validate(instrument string, units int32) (string, float64, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	f, exist := c.filters[instrument]
+	if !exist {
+		return "", 0, errors.New("unknown instrument " + instrument)
+	}
+
+	quantity := float64(units) * f.stepSize
+
+	if quantity < f.minQty {
+		return "", 0, errors.New("LOT_SIZE: quantity below the minimum of " + strconv.FormatFloat(f.minQty, 'f', -1, 64))
+	}
+
+	if last, exist := c.lastPrices[instrument]; exist && f.minNotional > 0 {
+		if quantity*last.Ask < f.minNotional {
+			return "", 0, errors.New("MIN_NOTIONAL: order notional below " + strconv.FormatFloat(f.minNotional, 'f', -1, 64))
+		}
+	}
+
+	return f.symbol, quantity, nil
+}
+
+func (c *binanceClient) placeMarketOrder(instrument string, units int32, side gotrader.Side, clientID string, reduceOnly bool) error {
+
+	symbol, quantity, err := c.validate(instrument, units)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("symbol", symbol)
+	params.Set("type", "MARKET")
+	params.Set("quantity", strconv.FormatFloat(quantity, 'f', -1, 64))
+	params.Set("newClientOrderId", clientID)
+
+	if side == gotrader.Long {
+		params.Set("side", "BUY")
+	} else {
+		params.Set("side", "SELL")
+	}
+
+	priority := tools.Normal
+
+	if reduceOnly {
+		priority = tools.RiskReducing
+		if c.cfg.Market == USDMFutures {
+			params.Set("reduceOnly", "true")
+		}
+	}
+
+	c.limiter.Wait("order", priority)
+
+	return c.rest.signed(http.MethodPost, c.endpoint("/api/v3/order", "/fapi/v1/order"), params, nil)
+}
+
+func (c *binanceClient) nextClientID() string {
+	return "gt-" + strconv.FormatInt(c.orderCounter.Inc(), 10)
+}
+
+func (c *binanceClient) OpenMarketOrder(accountID, instrument string, units int32, side string) error {
+
+	s := gotrader.Long
+	if side == gotrader.Short.String() {
+		s = gotrader.Short
+	}
+
+	if s == gotrader.Short && c.cfg.Market == Spot {
+		return errors.New("spot market does not allow short selling")
+	}
+
+	return c.placeMarketOrder(instrument, units, s, c.nextClientID(), false)
+}
+
+func (c *binanceClient) CloseTrade(accountID, id string) error {
+
+	c.mutex.Lock()
+	trade, exist := c.trades[id]
+	c.mutex.Unlock()
+
+	if !exist {
+		return errors.New("trade " + id + " does not exist")
+	}
+
+	side := gotrader.Long
+	if trade.details.Side == gotrader.Long {
+		side = gotrader.Short
+	}
+
+	clientID := c.nextClientID()
+
+	c.mutex.Lock()
+	c.closeRequests[clientID] = id
+	c.mutex.Unlock()
+
+	return c.placeMarketOrder(trade.details.Instrument.Name, trade.details.Units, side, clientID, true)
+}
+
+// GetOpenTrades returns the futures positions as trades, spot balances are not considered trades.
+func (c *binanceClient) GetOpenTrades(accountID string) ([]gotrader.TradeDetails, error) {
+
+	if c.cfg.Market == Spot {
+		return []gotrader.TradeDetails{}, nil
+	}
+
+	positions := make([]futuresPosition, 0)
+	if err := c.rest.signed(http.MethodGet, "/fapi/v2/positionRisk", nil, &positions); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	trades := make([]gotrader.TradeDetails, 0)
+
+	for _, p := range positions {
+
+		name, exist := c.bySymbol[p.Symbol]
+		amount := parseFloat(p.PositionAmt)
+		if !exist || amount == 0 {
+			continue
+		}
+
+		side := gotrader.Long
+		if amount < 0 {
+			side = gotrader.Short
+		}
+
+		details := gotrader.TradeDetails{
+			ID:         "POS-" + p.Symbol,
+			Instrument: c.instruments[name],
+			Side:       side,
+			Units:      int32(math.Round(math.Abs(amount) / c.filters[name].stepSize)),
+			OpenPrice:  parseFloat(p.EntryPrice),
+			OpenTime:   time.Unix(0, p.UpdateTime*int64(time.Millisecond)),
+		}
+
+		c.trades[details.ID] = &binanceTrade{details: details}
+		trades = append(trades, details)
+	}
+
+	return trades, nil
+}
+
+func (c *binanceClient) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+
+	streams := make([]string, 0, len(instruments))
+
+	c.mutex.Lock()
+	for _, inst := range instruments {
+		f, exist := c.filters[inst.Name]
+		if !exist {
+			c.mutex.Unlock()
+			return errors.New("unknown instrument " + inst.Name)
+		}
+		streams = append(streams, strings.ToLower(f.symbol)+"@bookTicker")
+	}
+	c.mutex.Unlock()
+
+	endpoint := c.wsURL + "/stream?streams=" + strings.Join(streams, "/")
+
+	address := func() (string, error) { return endpoint, nil }
+
+	return c.stream(address, nil, func(data []byte) {
+
+		msg := streamMessage{}
+		ticker := bookTicker{}
+
+		if json.Unmarshal(data, &msg) != nil || json.Unmarshal(msg.Data, &ticker) != nil {
+			return
+		}
+
+		c.mutex.Lock()
+		name, exist := c.bySymbol[ticker.Symbol]
+		c.mutex.Unlock()
+
+		if !exist {
+			return
+		}
+
+		tickTime := time.Now()
+		if ticker.Time > 0 {
+			tickTime = time.Unix(0, ticker.Time*int64(time.Millisecond))
+		}
+
+		tick := &gotrader.Tick{
+			Instrument: name,
+			Bid:        parseFloat(ticker.Bid),
+			Ask:        parseFloat(ticker.Ask),
+			Time:       tickTime,
+		}
+
+		c.mutex.Lock()
+		c.lastPrices[name] = tick
+		c.mutex.Unlock()
+
+		callback(tick)
+	})
+}
+
+func (c *binanceClient) SubscribeOrderFillNotifications(accountID string, orderFillCallback gotrader.OrderFillHandler) error {
+
+	c.mutex.Lock()
+	c.orderFillCallback = orderFillCallback
+	c.mutex.Unlock()
+
+	var key listenKey
+	keyEndpoint := c.endpoint("/api/v3/userDataStream", "/fapi/v1/listenKey")
+
+	endpoint := func() (string, error) { // the key of an expired stream is replaced by a new one
+		key = listenKey{}
+		if err := c.rest.public(http.MethodPost, keyEndpoint, nil, &key); err != nil {
+			return "", fmt.Errorf("listen key: %w", err)
+		}
+		return c.wsURL + "/ws/" + key.ListenKey, nil
+	}
+
+	keepAlive := func(stop <-chan struct{}) error { // binance closes the user stream if the key is not kept alive
+
+		params := url.Values{}
+		if c.cfg.Market == Spot {
+			params.Set("listenKey", key.ListenKey)
+		}
+
+		ticker := time.NewTicker(listenKeyKeepAlive)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return nil
+			case <-ticker.C:
+				if err := c.rest.public(http.MethodPut, keyEndpoint, params, nil); err != nil {
+					return fmt.Errorf("listen key keep-alive: %w", err)
+				}
+			}
+		}
+	}
+
+	return c.stream(endpoint, keepAlive, c.onUserEvent)
+}
+
+// SubscribeSwapChargeNotifications is a no-op, funding fees are settled in the wallet balance.
+func (c *binanceClient) SubscribeSwapChargeNotifications(accountID string, swapChargeCallback gotrader.SwapChargeHandler) error {
+	return nil
+}
+
+// SubscribeReconnections sets the callback called after a websocket stream is recovered.
+func (c *binanceClient) SubscribeReconnections(accountID string, callback gotrader.ReconnectHandler) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.reconnectCallback = callback
+	return nil
+}
+
+// SubscribeFundsTransferNotifications is a no-op.
+func (c *binanceClient) SubscribeFundsTransferNotifications(accountID string, fundsTransferCallback gotrader.FundsTransferHandler) error {
+	return nil
+}
+
+/*
+stream reads the websocket messages of the endpoint, dialled again with exponential backoff when the connection
+drops. The endpoint is resolved on every dial and keepAlive, when not nil, runs along every connection until it
+drops: its failure closes the connection, so the stream is dialled again.
+*/
+func (c *binanceClient) stream(
+	endpoint func() (string, error),
+	keepAlive func(stop <-chan struct{}) error,
+	handler func(data []byte),
+) error {
+
+	dial := func() (*websocket.Conn, error) {
+		address, err := endpoint()
+		if err != nil {
+			return nil, err
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(address, nil)
+		return conn, err
+	}
+
+	conn, err := dial()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			stop := make(chan struct{})
+			if keepAlive != nil {
+				go func(conn *websocket.Conn) {
+					if err := keepAlive(stop); err != nil {
+						c.streamError(err)
+						conn.Close()
+					}
+				}(conn)
+			}
+
+			for {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					break
+				}
+				handler(data)
+			}
+
+			close(stop)
+			conn.Close()
+
+			c.backoff.Retry(func() (err error) {
+				if conn, err = dial(); err != nil {
+					c.streamError(err)
+				}
+				return err
+			})
+
+			c.mutex.Lock()
+			callback := c.reconnectCallback
+			c.mutex.Unlock()
+
+			if callback != nil {
+				callback(time.Now())
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *binanceClient) streamError(err error) {
+	if c.cfg.StreamErrors != nil {
+		c.cfg.StreamErrors(err)
+	}
+}
+
+func (c *binanceClient) onUserEvent(data []byte) {
+
+	event := userEvent{}
+	if json.Unmarshal(data, &event) != nil {
+		return
+	}
+
+	update := orderUpdate{}
+
+	switch event.EventType {
+	case "executionReport":
+		if json.Unmarshal(data, &update) != nil {
+			return
+		}
+	case "ORDER_TRADE_UPDATE":
+		if json.Unmarshal(event.Order, &update) != nil {
+			return
+		}
+	default:
+		return
+	}
+
+	c.mutex.Lock()
+	name, exist := c.bySymbol[update.Symbol]
+	callback := c.orderFillCallback
+	c.mutex.Unlock()
+
+	if !exist || callback == nil {
+		return
+	}
+
+	side := gotrader.Long
+	if update.Side == "SELL" {
+		side = gotrader.Short
+	}
+
+	fillTime := time.Unix(0, update.TradeTime*int64(time.Millisecond))
+
+	if update.ExecutionType == "REJECTED" || update.ExecutionType == "EXPIRED" {
+		c.mutex.Lock()
+		c.closeDone(update.ClientOrderID)
+		c.mutex.Unlock()
+
+		callback(&gotrader.OrderFill{
+			Error:      update.ExecutionType + ": " + update.RejectReason,
+			OrderID:    update.ClientOrderID,
+			Side:       side,
+			Instrument: c.instruments[name],
+			Time:       fillTime,
+		})
+		return
+	}
+
+	if update.ExecutionType != "TRADE" {
+		return
+	}
+
+	c.mutex.Lock()
+	step := c.filters[name].stepSize
+	units := int32(math.Round(parseFloat(update.LastQty) / step))
+	price := parseFloat(update.LastPrice)
+	commission := parseFloat(update.Commission)
+	tradeID := strconv.FormatInt(update.TradeID, 10)
+
+	var fill *gotrader.OrderFill
+
+	if closing, isClose := c.closeRequests[update.ClientOrderID]; isClose {
+
+		if trade := c.trades[closing]; trade != nil {
+
+			accumulated, exist := c.closeFills[update.ClientOrderID]
+			if !exist {
+				accumulated = &closeFill{}
+				c.closeFills[update.ClientOrderID] = accumulated
+			}
+
+			profit := (price - trade.details.OpenPrice) * float64(units) * step * sign(trade.details.Side)
+			if update.RealizedProfit != "" {
+				profit = parseFloat(update.RealizedProfit)
+			}
+
+			accumulated.units += units
+			accumulated.notional += price * float64(units)
+			accumulated.profit += profit
+			accumulated.commission += commission
+
+			if update.OrderStatus == "FILLED" || accumulated.units >= trade.details.Units {
+				fill = &gotrader.OrderFill{
+					TradeClose:  true,
+					OrderID:     update.ClientOrderID,
+					TradeID:     closing,
+					Side:        trade.details.Side,
+					Instrument:  trade.details.Instrument,
+					Price:       accumulated.notional / float64(accumulated.units),
+					Units:       accumulated.units,
+					Profit:      accumulated.profit,
+					ChargedFees: -accumulated.commission,
+					Time:        fillTime,
+				}
+
+				delete(c.trades, closing)
+				delete(c.closeRequests, update.ClientOrderID)
+				delete(c.closeFills, update.ClientOrderID)
+			}
+		}
+
+	} else {
+
+		c.trades[tradeID] = &binanceTrade{details: gotrader.TradeDetails{
+			ID:          tradeID,
+			Instrument:  c.instruments[name],
+			Side:        side,
+			Units:       units,
+			OpenPrice:   price,
+			ChargedFees: -commission,
+			OpenTime:    fillTime,
+		}}
+
+		fill = &gotrader.OrderFill{
+			OrderID:     update.ClientOrderID,
+			TradeID:     tradeID,
+			Side:        side,
+			Instrument:  c.instruments[name],
+			Price:       price,
+			Units:       units,
+			ChargedFees: -commission,
+			Time:        fillTime,
+		}
+	}
+	c.mutex.Unlock()
+
+	if fill != nil {
+		callback(fill)
+	}
+}
+
+// closeDone forgets a close order that ended without filling, the units it filled are no longer open at
+// binance, so a close of the trade sent again closes the rest. The mutex is held.
+func (c *binanceClient) closeDone(clientID string) {
+
+	closing, isClose := c.closeRequests[clientID]
+	if !isClose {
+		return
+	}
+
+	if accumulated, exist := c.closeFills[clientID]; exist {
+		if trade := c.trades[closing]; trade != nil {
+			trade.details.Units -= accumulated.units
+		}
+	}
+
+	delete(c.closeRequests, clientID)
+	delete(c.closeFills, clientID)
+}
+
+func parseFloat(value string) float64 {
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
+
+func sign(side gotrader.Side) float64 {
+
+	if side == gotrader.Short {
+		return -1
+	}
+
+	return 1
+}
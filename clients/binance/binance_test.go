@@ -0,0 +1,73 @@
+package binance
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/luismcruz/gotrader"
+)
+
+func TestUserEvents(t *testing.T) {
+
+	client := func() (*binanceClient, *[]*gotrader.OrderFill) {
+
+		c := NewBinanceClient(Config{Market: USDMFutures}).(*binanceClient)
+
+		instrument := gotrader.InstrumentDetails{Name: "BTC_USDT", BaseCurrency: "BTC", QuoteCurrency: "USDT"}
+		c.filters["BTC_USDT"] = &symbolFilters{symbol: "BTCUSDT", stepSize: 0.001}
+		c.instruments["BTC_USDT"] = instrument
+		c.bySymbol["BTCUSDT"] = "BTC_USDT"
+
+		c.trades["1"] = &binanceTrade{details: gotrader.TradeDetails{ID: "1", Instrument: instrument,
+			Side: gotrader.Long, Units: 10, OpenPrice: 100}}
+		c.closeRequests["gt-1"] = "1"
+
+		fills := &[]*gotrader.OrderFill{}
+		c.orderFillCallback = func(fill *gotrader.OrderFill) { *fills = append(*fills, fill) }
+
+		return c, fills
+	}
+
+	execution := func(kind, status, qty, price, profit string, id int) []byte {
+		return []byte(fmt.Sprintf(`{"e":"ORDER_TRADE_UPDATE","o":{"s":"BTCUSDT","c":"gt-1","S":"SELL","x":%q,`+
+			`"X":%q,"l":%q,"L":%q,"n":"0.01","T":1700000000000,"t":%d,"rp":%q}}`, kind, status, qty, price, id, profit))
+	}
+
+	t.Run("the executions of a close are accumulated", func(t *testing.T) {
+
+		c, fills := client()
+
+		c.onUserEvent(execution("TRADE", "PARTIALLY_FILLED", "0.004", "110", "0.04", 1))
+		c.onUserEvent(execution("TRADE", "FILLED", "0.006", "120", "0.12", 2))
+
+		if len(*fills) != 1 {
+			t.Fatalf("expected a single close fill, got %d", len(*fills))
+		}
+
+		fill := (*fills)[0]
+		if !fill.TradeClose || fill.TradeID != "1" || fill.Units != 10 || fill.Price != 116 ||
+			fill.Profit != 0.16 || fill.ChargedFees != -0.02 {
+			t.Errorf("unexpected close fill %+v", fill)
+		}
+
+		if len(c.trades) != 0 || len(c.closeRequests) != 0 || len(c.closeFills) != 0 {
+			t.Errorf("expected the close to be forgotten, got %v, %v", c.trades, c.closeRequests)
+		}
+	})
+
+	t.Run("an expired close leaves the units not filled open", func(t *testing.T) {
+
+		c, fills := client()
+
+		c.onUserEvent(execution("TRADE", "PARTIALLY_FILLED", "0.004", "110", "0.04", 1))
+		c.onUserEvent(execution("EXPIRED", "EXPIRED", "0", "0", "", 0))
+
+		if len(*fills) != 1 || (*fills)[0].Error == "" {
+			t.Fatalf("expected the close to fail, got %v", *fills)
+		}
+
+		if trade := c.trades["1"]; trade == nil || trade.details.Units != 6 || len(c.closeRequests) != 0 {
+			t.Errorf("expected the trade left with 6 units, got %+v", trade)
+		}
+	})
+}
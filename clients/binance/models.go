@@ -0,0 +1,83 @@
+package binance
+
+import "encoding/json"
+
+type exchangeInfo struct {
+	Symbols []symbolInfo `json:"symbols"`
+}
+
+type symbolInfo struct {
+	Symbol     string            `json:"symbol"`
+	Status     string            `json:"status"`
+	BaseAsset  string            `json:"baseAsset"`
+	QuoteAsset string            `json:"quoteAsset"`
+	Filters    []json.RawMessage `json:"filters"`
+}
+
+type filter struct {
+	FilterType  string `json:"filterType"`
+	MinQty      string `json:"minQty"`
+	StepSize    string `json:"stepSize"`
+	MinNotional string `json:"minNotional"`
+	Notional    string `json:"notional"` // futures MIN_NOTIONAL filter field
+}
+
+type spotAccount struct {
+	Balances []struct {
+		Asset  string `json:"asset"`
+		Free   string `json:"free"`
+		Locked string `json:"locked"`
+	} `json:"balances"`
+}
+
+type futuresAccount struct {
+	TotalWalletBalance    string `json:"totalWalletBalance"`
+	TotalMarginBalance    string `json:"totalMarginBalance"`
+	TotalUnrealizedProfit string `json:"totalUnrealizedProfit"`
+	TotalInitialMargin    string `json:"totalInitialMargin"`
+	AvailableBalance      string `json:"availableBalance"`
+}
+
+type futuresPosition struct {
+	Symbol      string `json:"symbol"`
+	PositionAmt string `json:"positionAmt"`
+	EntryPrice  string `json:"entryPrice"`
+	UpdateTime  int64  `json:"updateTime"`
+}
+
+type listenKey struct {
+	ListenKey string `json:"listenKey"`
+}
+
+type streamMessage struct {
+	Stream string          `json:"stream"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type bookTicker struct {
+	Symbol string `json:"s"`
+	Bid    string `json:"b"`
+	Ask    string `json:"a"`
+	Time   int64  `json:"T"` // only sent by futures streams
+}
+
+// orderUpdate is the common part of the spot executionReport and the futures ORDER_TRADE_UPDATE order object.
+type orderUpdate struct {
+	Symbol         string `json:"s"`
+	ClientOrderID  string `json:"c"`
+	Side           string `json:"S"`
+	ExecutionType  string `json:"x"`
+	OrderStatus    string `json:"X"` // FILLED once the last execution of the order
+	LastQty        string `json:"l"`
+	LastPrice      string `json:"L"`
+	Commission     string `json:"n"`
+	TradeTime      int64  `json:"T"`
+	TradeID        int64  `json:"t"`
+	RejectReason   string `json:"r"`
+	RealizedProfit string `json:"rp"`
+}
+
+type userEvent struct {
+	EventType string          `json:"e"`
+	Order     json.RawMessage `json:"o"`
+}
@@ -0,0 +1,87 @@
+package binance
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+type restClient struct {
+	baseURL   string
+	apiKey    string
+	secretKey string
+	client    http.Client
+}
+
+type apiError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (c *restClient) public(method, endpoint string, params url.Values, data interface{}) error {
+	return c.request(method, endpoint, params, false, data)
+}
+
+func (c *restClient) signed(method, endpoint string, params url.Values, data interface{}) error {
+	return c.request(method, endpoint, params, true, data)
+}
+
+func (c *restClient) request(method, endpoint string, params url.Values, sign bool, data interface{}) error {
+
+	if params == nil {
+		params = url.Values{}
+	}
+
+	if sign {
+		params.Set("timestamp", strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10))
+		mac := hmac.New(sha256.New, []byte(c.secretKey))
+		mac.Write([]byte(params.Encode()))
+		params.Set("signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-MBX-APIKEY", c.apiKey)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable { // not processed
+		return fmt.Errorf("%w: binance error %d: %s", gotrader.ErrTransient, res.StatusCode, body)
+	}
+
+	if res.StatusCode >= 300 {
+		apiErr := apiError{}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Msg != "" {
+			return errors.New("binance error " + strconv.Itoa(apiErr.Code) + ": " + apiErr.Msg)
+		}
+		return errors.New("binance error " + strconv.Itoa(res.StatusCode) + ": " + string(body))
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	return json.Unmarshal(body, data)
+}
@@ -11,23 +11,37 @@ import (
 type btRandClient struct {
 	gotrader.BrokerClient
 	instruments         []gotrader.InstrumentDetails
-	instrumentsPriceGen map[string]*priceGenerator
+	instrumentsPriceGen []*priceGenerator
 	startTime           time.Time
 	endTime             time.Time
 	currentTime         time.Time
+	seed                int64
 }
 
-func NewBTRandClient(instruments []gotrader.InstrumentDetails,
-	startTime, endTime time.Time) gotrader.BrokerClient {
+// ClientOption configures the random prices client.
+type ClientOption func(c *btRandClient)
 
-	rand.Seed(time.Now().UnixNano())
+// Seed sets the seed of the prices, the same seed and instruments replay bit-identical ticks. The client is
+// seeded from the current time by default.
+func Seed(seed int64) ClientOption {
+	return func(c *btRandClient) {
+		c.seed = seed
+	}
+}
+
+func NewBTRandClient(instruments []gotrader.InstrumentDetails,
+	startTime, endTime time.Time, opts ...ClientOption) gotrader.BrokerClient {
 
 	client := &btRandClient{
-		instruments:         instruments,
-		instrumentsPriceGen: make(map[string]*priceGenerator),
-		startTime:           startTime,
-		endTime:             endTime,
-		currentTime:         startTime,
+		instruments: instruments,
+		startTime:   startTime,
+		endTime:     endTime,
+		currentTime: startTime,
+		seed:        time.Now().UnixNano(),
+	}
+
+	for _, o := range opts {
+		o(client)
 	}
 
 	return client
@@ -41,21 +55,30 @@ func (c *btRandClient) SubscribePrices(accountID string, instruments []gotrader.
 
 	go func() {
 
+		// the generators are seeded by name order, the subscription order may vary between sessions
+		instruments = append([]gotrader.InstrumentDetails(nil), instruments...)
+		sort.Slice(instruments, func(i, j int) bool { return instruments[i].Name < instruments[j].Name })
+
+		rnd := rand.New(rand.NewSource(c.seed))
+		c.instrumentsPriceGen = make([]*priceGenerator, 0, len(instruments))
+
 		for _, inst := range instruments {
-			startPrice := rand.Float64()*0.6 + 0.9
-			c.instrumentsPriceGen[inst.Name] = newCorePriceGenerator(inst.Name, c.startTime, startPrice, rand.Int63())
+			startPrice := rnd.Float64()*0.6 + 0.9
+			c.instrumentsPriceGen = append(c.instrumentsPriceGen, newCorePriceGenerator(inst.Name, c.startTime, startPrice, rnd.Int63()))
 		}
 
+		ticks := make([]*gotrader.Tick, 0, len(c.instrumentsPriceGen))
+
 		for c.currentTime.Before(c.endTime) {
 
-			ticks := make([]*gotrader.Tick, 0, len(c.instrumentsPriceGen))
+			ticks = ticks[:0]
 
 			for _, instGen := range c.instrumentsPriceGen {
 				ticks = append(ticks, instGen.next())
 			}
 
-			// sort ticks
-			sort.Slice(ticks, func(i, j int) bool { return ticks[i].Time.Before(ticks[j].Time) })
+			// sort ticks, keeping the instruments order on equal times
+			sort.SliceStable(ticks, func(i, j int) bool { return ticks[i].Time.Before(ticks[j].Time) })
 
 			// update time
 			c.currentTime = ticks[len(c.instrumentsPriceGen)-1].Time
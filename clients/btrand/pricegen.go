@@ -32,12 +32,11 @@ func (p *priceGenerator) next() *gotrader.Tick {
 
 	p.time = p.time.Add(duration)
 
-	tick := &gotrader.Tick{
-		Instrument: p.instrument,
-		Ask:        p.price + spread,
-		Bid:        p.price,
-		Time:       p.time,
-	}
+	tick := gotrader.AcquireTick()
+	tick.Instrument = p.instrument
+	tick.Ask = p.price + spread
+	tick.Bid = p.price
+	tick.Time = p.time
 
 	return tick
 }
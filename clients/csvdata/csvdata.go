@@ -0,0 +1,314 @@
+/*
+Package csvdata is a backtest client replaying the ticks of CSV files, one per instrument named after it in a
+data directory, e.g. data/EUR_USD.csv:
+
+	time,bid,ask,bidSize,askSize,last,lastSize
+	2024-01-02T00:00:00.125Z,1.10012,1.10020,1000000,500000
+	2024-01-02T00:00:00.250Z,1.10013,1.10021,,,1.10013,250000
+	2024-01-02T00:00:00.375Z,1.10014,1.10022
+
+The time is RFC 3339 or Unix milliseconds, the sizes and the trades (the price and the size traded since the
+previous tick, see gotrader.QueueFills) are optional, empty when missing, and the header row is skipped. The ticks of
+each file must be in time order, the files are merged by time, the instruments in name order on equal times.
+
+NewCandleClient replays files of candles instead, when only candles are available:
+
+	time,open,high,low,close,volume
+	2024-01-02T00:00:00Z,1.10012,1.10030,1.10001,1.10020,1520
+
+The time is the candle open and the volume is optional. Each candle is replayed as 4 ticks of its mid prices,
+a quarter of the timeframe apart: open, low, high and close for a bullish candle, open, high, low and close for a
+bearish one. The ticks have no spread, so the backtests of candles should model it, e.g. with a
+gotrader.SpreadProfile per instrument.
+*/
+package csvdata
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// feed is the CSV file of an instrument, with its next tick.
+type feed struct {
+	name   string
+	file   *os.File
+	reader *csv.Reader
+	line   int
+	next   gotrader.Tick
+	path   []gotrader.Tick // the ticks of the candle not read yet
+}
+
+// Client replays the ticks of the CSV files of a directory in [start, end), zero times replay every tick.
+type Client struct {
+	gotrader.BrokerClient
+	dir         string
+	instruments []gotrader.InstrumentDetails
+	start       time.Time
+	end         time.Time
+	timeframe   time.Duration // of the candles, zero for ticks
+	mutex       *sync.Mutex
+	err         error
+}
+
+// NewCSVClient is the Client constructor.
+func NewCSVClient(dir string, instruments []gotrader.InstrumentDetails, start, end time.Time) *Client {
+	return &Client{
+		dir:         dir,
+		instruments: instruments,
+		start:       start,
+		end:         end,
+		mutex:       &sync.Mutex{},
+	}
+}
+
+// NewCandleClient is the Client constructor of the candle files of a timeframe.
+func NewCandleClient(dir string, instruments []gotrader.InstrumentDetails, timeframe time.Duration,
+	start, end time.Time) *Client {
+
+	c := NewCSVClient(dir, instruments, start, end)
+	c.timeframe = timeframe
+
+	return c
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func parseTime(value string) (time.Time, error) {
+
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+func (c *Client) open(name string) (*feed, error) {
+
+	file, err := os.Open(filepath.Join(c.dir, name+".csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	return &feed{name: name, file: file, reader: reader}, nil
+}
+
+// read reads the next tick of the feed in the replayed period, false at the end of the file or the period.
+func (c *Client) read(f *feed) (bool, error) {
+
+	previous := f.next.Time
+
+	if len(f.path) > 0 {
+		f.next, f.path = f.path[0], f.path[1:]
+		return true, nil
+	}
+
+	for {
+
+		record, err := f.reader.Read()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", f.name, err)
+		}
+
+		f.line++
+
+		if c.timeframe > 0 && len(record) < 5 {
+			return false, fmt.Errorf("%s line %d: expected time, open, high, low and close", f.name, f.line)
+		}
+
+		if len(record) < 3 {
+			return false, fmt.Errorf("%s line %d: expected time, bid and ask", f.name, f.line)
+		}
+
+		t, err := parseTime(record[0])
+		if err != nil {
+			if f.line == 1 {
+				continue // header
+			}
+			return false, fmt.Errorf("%s line %d: %w", f.name, f.line, err)
+		}
+
+		values := make([]float64, 6)
+		for i := 1; i < len(record) && i <= 6; i++ {
+			if i > 2 && record[i] == "" {
+				continue
+			}
+			if values[i-1], err = strconv.ParseFloat(record[i], 64); err != nil {
+				return false, fmt.Errorf("%s line %d: %w", f.name, f.line, err)
+			}
+		}
+
+		if t.Before(previous) {
+			return false, fmt.Errorf("%s line %d: tick before the previous one", f.name, f.line)
+		}
+
+		if t.Before(c.start) {
+			continue
+		}
+
+		if !c.end.IsZero() && !t.Before(c.end) {
+			return false, nil
+		}
+
+		if c.timeframe > 0 {
+			f.path = candlePath(f.name, t, c.timeframe, values[0], values[1], values[2], values[3])
+			f.next, f.path = f.path[0], f.path[1:]
+			return true, nil
+		}
+
+		f.next = gotrader.Tick{Instrument: f.name, Time: t, Bid: values[0], Ask: values[1],
+			BidSize: values[2], AskSize: values[3], Last: values[4], LastSize: values[5]}
+
+		return true, nil
+	}
+}
+
+// candlePath returns the mid price ticks of a candle, through its low first when it is bullish.
+func candlePath(instrument string, t time.Time, timeframe time.Duration,
+	open, high, low, close float64) []gotrader.Tick {
+
+	prices := []float64{open, high, low, close}
+	if close >= open {
+		prices[1], prices[2] = low, high
+	}
+
+	path := make([]gotrader.Tick, len(prices))
+	for i, price := range prices {
+		path[i] = gotrader.Tick{Instrument: instrument, Time: t.Add(time.Duration(i) * timeframe / 4),
+			Bid: price, Ask: price}
+	}
+
+	return path
+}
+
+func (c *Client) fail(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.err = err
+}
+
+// replay sends the ticks of the feeds by time, the ones with a tick are in name order.
+func (c *Client) replay(feeds []*feed, callback gotrader.TickHandler) error {
+
+	for len(feeds) > 0 {
+
+		first := 0
+		for i := range feeds {
+			if feeds[i].next.Time.Before(feeds[first].next.Time) {
+				first = i
+			}
+		}
+
+		f := feeds[first]
+
+		tick := gotrader.AcquireTick()
+		tick.Instrument, tick.Bid, tick.Ask = f.next.Instrument, f.next.Bid, f.next.Ask
+		tick.BidSize, tick.AskSize, tick.Time = f.next.BidSize, f.next.AskSize, f.next.Time
+		tick.Last, tick.LastSize = f.next.Last, f.next.LastSize
+
+		callback(tick)
+
+		more, err := c.read(f)
+		if err != nil {
+			return err
+		}
+
+		if !more {
+			feeds = append(feeds[:first], feeds[first+1:]...)
+		}
+	}
+
+	return nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+func (c *Client) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+	return c.instruments, nil
+}
+
+// SubscribePrices opens the files of the instruments and replays them, a file that can't be read ends the
+// replay and its error is returned by Err.
+func (c *Client) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails,
+	callback gotrader.TickHandler) error {
+
+	instruments = append([]gotrader.InstrumentDetails(nil), instruments...)
+	sort.Slice(instruments, func(i, j int) bool { return instruments[i].Name < instruments[j].Name })
+
+	feeds := make([]*feed, 0, len(instruments))
+	closeAll := func() {
+		for _, f := range feeds {
+			f.file.Close()
+		}
+	}
+
+	for _, inst := range instruments {
+
+		f, err := c.open(inst.Name)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		feeds = append(feeds, f)
+	}
+
+	replayed := make([]*feed, 0, len(feeds))
+	for _, f := range feeds {
+
+		more, err := c.read(f)
+		if err != nil {
+			closeAll()
+			return err
+		}
+
+		if more {
+			replayed = append(replayed, f)
+		}
+	}
+
+	go func() {
+
+		defer closeAll()
+
+		if err := c.replay(replayed, callback); err != nil {
+			c.fail(err)
+		}
+
+		callback(nil)
+
+	}()
+
+	return nil
+}
+
+// Err returns the error that ended the replay, nil if every tick was replayed.
+func (c *Client) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.err
+}
@@ -0,0 +1,262 @@
+package csvdata
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+func replay(t *testing.T, c *Client, instruments ...string) []gotrader.Tick {
+
+	t.Helper()
+
+	details := make([]gotrader.InstrumentDetails, 0, len(instruments))
+	for _, name := range instruments {
+		details = append(details, gotrader.InstrumentDetails{Name: name})
+	}
+
+	done := make(chan struct{})
+	ticks := make([]gotrader.Tick, 0)
+
+	err := c.SubscribePrices("", details, func(tick *gotrader.Tick) {
+		if tick == nil {
+			close(done)
+			return
+		}
+		ticks = append(ticks, *tick)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-done
+
+	return ticks
+}
+
+func TestClient(t *testing.T) {
+
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"EUR_USD": "time,bid,ask,bidSize,askSize\n" +
+			"2024-01-02T00:00:01Z,1.1,1.1002,1000000,500000\n" +
+			"2024-01-02T00:00:03Z,1.1001,1.1003\n" +
+			"2024-01-02T00:00:05Z,1.1002,1.1004\n",
+		"GBP_USD": "1704153601000,1.27,1.2703\n" + // 2024-01-02T00:00:01Z in ms
+			"1704153602000,1.2701,1.2704\n",
+		"USD_JPY": "2024-01-02T00:00:02Z,141.1,141.12\n" +
+			"2024-01-02T00:00:01Z,141.2,141.22\n",
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name+".csv"), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("files are merged by time", func(t *testing.T) {
+
+		ticks := replay(t, NewCSVClient(dir, nil, time.Time{}, time.Time{}), "GBP_USD", "EUR_USD")
+
+		order := make([]string, 0, len(ticks))
+		for _, tick := range ticks {
+			order = append(order, tick.Instrument+"@"+tick.Time.Format("05"))
+		}
+
+		if strings.Join(order, " ") != "EUR_USD@01 GBP_USD@01 GBP_USD@02 EUR_USD@03 EUR_USD@05" {
+			t.Fatalf("unexpected ticks order %v", order)
+		}
+
+		if ticks[0].BidSize != 1000000 || ticks[0].AskSize != 500000 || ticks[1].Ask != 1.2703 {
+			t.Errorf("unexpected ticks %+v", ticks[:2])
+		}
+	})
+
+	t.Run("ticks are replayed in the period", func(t *testing.T) {
+
+		c := NewCSVClient(dir, nil, start.Add(2*time.Second), start.Add(5*time.Second))
+		ticks := replay(t, c, "EUR_USD", "GBP_USD")
+
+		if len(ticks) != 2 || ticks[0].Instrument != "GBP_USD" || ticks[1].Bid != 1.1001 {
+			t.Errorf("expected the ticks from the second 2 to the 5 excluded, got %+v", ticks)
+		}
+	})
+
+	t.Run("replay errors are kept", func(t *testing.T) {
+
+		c := NewCSVClient(dir, nil, time.Time{}, time.Time{})
+
+		if ticks := replay(t, c, "USD_JPY"); len(ticks) != 1 || c.Err() == nil {
+			t.Errorf("expected the tick before the one out of order and an error, got %+v, %v", ticks, c.Err())
+		}
+
+		err := c.SubscribePrices("", []gotrader.InstrumentDetails{{Name: "EUR_GBP"}}, func(*gotrader.Tick) {})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected a missing file error, got %v", err)
+		}
+	})
+}
+
+// limit places a buy limit order at the bid of the first tick.
+type limit struct {
+	engine gotrader.Engine
+	placed bool
+	fills  []*gotrader.OrderFill
+}
+
+func (s *limit) Initialize()                          {}
+func (s *limit) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *limit) OnOrderFill(fill *gotrader.OrderFill) { s.fills = append(s.fills, fill) }
+func (s *limit) OnStop()                              {}
+
+func (s *limit) OnTick(tick *gotrader.Tick) {
+
+	if !s.placed {
+		s.placed = true
+		s.engine.SubmitOrder(&gotrader.Order{Type: gotrader.LimitOrder, Instrument: tick.Instrument,
+			Side: gotrader.Long, Units: 1000, Price: tick.Bid})
+	}
+}
+
+func TestQueueFills(t *testing.T) {
+
+	dir := t.TempDir()
+
+	// the queue ahead of the order: 3000 when it joins, 2000 after a trade, 1000 after a cancellation, then the
+	// trades of 1500 and 500 fill it
+	data := "time,bid,ask,bidSize,askSize,last,lastSize\n" +
+		"2024-01-02T00:00:01Z,1.1000,1.1002,3000,3000\n" +
+		"2024-01-02T00:00:02Z,1.1000,1.1002,3000,3000\n" +
+		"2024-01-02T00:00:03Z,1.1000,1.1002,2000,3000,1.1000,1000\n" +
+		"2024-01-02T00:00:04Z,1.1000,1.1002,1000,3000\n" +
+		"2024-01-02T00:00:05Z,1.1000,1.1002,500,3000,1.1000,1500\n" +
+		"2024-01-02T00:00:06Z,1.1000,1.1002,500,3000,1.1000,500\n" +
+		"2024-01-02T00:00:07Z,1.1000,1.1002,500,3000\n"
+
+	if err := os.WriteFile(filepath.Join(dir, "EUR_USD.csv"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	run := func(opts ...gotrader.Option) *limit {
+
+		s := &limit{}
+		opts = append(opts, gotrader.Instruments([]string{"EUR_USD"}), gotrader.InitialBalance(10000),
+			gotrader.HomeCurrency("EUR"))
+
+		session := gotrader.NewTradingSession(opts...).SetStrategy(s).
+			SetClient(NewCSVClient(dir, instruments, time.Time{}, time.Time{})).Backtest()
+		if err := session.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	}
+
+	if s := run(); len(s.fills) != 0 {
+		t.Errorf("expected the order not to be filled without the ask at its price, got %+v", s.fills[0])
+	}
+
+	s := run(gotrader.QueueFills())
+	if len(s.fills) != 1 {
+		t.Fatalf("expected a fill, got %d", len(s.fills))
+	}
+
+	if fill := s.fills[0]; fill.Error != "" || fill.Price != 1.1 || fill.Time.Second() != 6 {
+		t.Errorf("expected the fill at the level on the sixth tick, got %+v", fill)
+	}
+}
+
+// quotes records the ticks of the session.
+type quotes struct {
+	ticks []gotrader.Tick
+}
+
+func (s *quotes) Initialize()                     {}
+func (s *quotes) SetEngine(gotrader.Engine)       {}
+func (s *quotes) OnOrderFill(*gotrader.OrderFill) {}
+func (s *quotes) OnStop()                         {}
+func (s *quotes) OnTick(tick *gotrader.Tick)      { s.ticks = append(s.ticks, *tick) }
+
+func TestCandleClient(t *testing.T) {
+
+	dir := t.TempDir()
+
+	data := "time,open,high,low,close,volume\n" +
+		"2024-01-02T06:00:00Z,1.1000,1.1010,1.0990,1.1005,1200\n" + // bullish
+		"2024-01-02T07:00:00Z,1.1005,1.1008,1.0995,1.1000,\n" // bearish
+
+	if err := os.WriteFile(filepath.Join(dir, "EUR_USD.csv"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	t.Run("candles are replayed as ticks", func(t *testing.T) {
+
+		ticks := replay(t, NewCandleClient(dir, nil, time.Hour, time.Time{}, time.Time{}), "EUR_USD")
+
+		path := make([]string, 0, len(ticks))
+		for _, tick := range ticks {
+			if tick.Bid != tick.Ask {
+				t.Errorf("expected mid price ticks, got %+v", tick)
+			}
+			path = append(path, tick.Time.Format("15:04")+"="+strconv.FormatFloat(tick.Bid, 'f', 4, 64))
+		}
+
+		expected := "06:00=1.1000 06:15=1.0990 06:30=1.1010 06:45=1.1005 " +
+			"07:00=1.1005 07:15=1.1008 07:30=1.0995 07:45=1.1000"
+		if strings.Join(path, " ") != expected {
+			t.Errorf("unexpected ticks %v", path)
+		}
+	})
+
+	t.Run("spreads are reconstructed by the profile", func(t *testing.T) {
+
+		profile := &gotrader.SpreadProfile{
+			Default: 0.0002,
+			Periods: []gotrader.SpreadPeriod{{From: 6*time.Hour + 30*time.Minute, To: 7 * time.Hour, Spread: 0.0004}},
+		}
+
+		s := &quotes{}
+		session := gotrader.NewTradingSession(gotrader.Instruments([]string{"EUR_USD"}),
+			gotrader.InitialBalance(10000), gotrader.HomeCurrency("EUR"),
+			gotrader.InstrumentSpread("EUR_USD", profile)).
+			SetStrategy(s).SetClient(NewCandleClient(dir, instruments, time.Hour, time.Time{}, time.Time{})).Backtest()
+		if err := session.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		// the first tick readies the session
+		if len(s.ticks) != 7 {
+			t.Fatalf("expected the ticks of the candles after the first one, got %d", len(s.ticks))
+		}
+
+		for _, tick := range s.ticks {
+
+			spread := 0.0002
+			if tick.Time.Hour() == 6 && tick.Time.Minute() >= 30 {
+				spread = 0.0004
+			}
+
+			if math.Abs(tick.Ask-tick.Bid-spread) > 1e-9 {
+				t.Errorf("expected the spread %v at %s, got %+v", spread, tick.Time.Format("15:04"), tick)
+			}
+		}
+	})
+}
@@ -0,0 +1,610 @@
+package fix
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"go.uber.org/atomic"
+)
+
+// venue is the venue of the symbols of the FIX sessions in a gotrader.SymbolMap.
+const venue = "fix"
+
+// Config holds the FIX session and account settings. FIX doesn't define a standard account status
+// request, so the account currency, leverage and balance are configured.
+type Config struct {
+	Address      string
+	SenderCompID string
+	TargetCompID string
+	Username     string
+	Password     string
+	Account      string
+	HeartBeat    time.Duration
+	Currency     string
+	Leverage     float64
+	Balance      float64
+	Instruments  []gotrader.InstrumentDetails
+	Symbols      map[string]string   // instrument name to venue symbol, e.g. EUR_USD -> EUR/USD
+	SymbolMap    *gotrader.SymbolMap // maps the other symbols of the "fix" venue, the Symbols are added as aliases
+}
+
+type fixTrade struct {
+	details gotrader.TradeDetails
+}
+
+type closeRequest struct {
+	tradeID string
+}
+
+type fixClient struct {
+	cfg               Config
+	session           *session
+	sessionOnce       sync.Once
+	sessionErr        error
+	mutex             *sync.Mutex
+	clOrdCounter      *atomic.Int64
+	symbols           *gotrader.SymbolMap
+	instruments       map[string]gotrader.InstrumentDetails
+	quotes            map[string]*gotrader.Tick
+	trades            map[string]*fixTrade
+	closeRequests     map[string]*closeRequest
+	pendingOrders     map[string]*gotrader.Order
+	tickCallback      gotrader.TickHandler
+	orderFillCallback gotrader.OrderFillHandler
+	heartbeatCallback gotrader.HeartbeatHandler
+}
+
+// NewFIXClient is the FIX 4.4 adapter constructor, the returned client also implements gotrader.Broker.
+// FIX only operates positions, so trades are tracked locally and closed with opposite side orders.
+func NewFIXClient(cfg Config) gotrader.BrokerClient {
+
+	c := &fixClient{
+		cfg:           cfg,
+		mutex:         &sync.Mutex{},
+		clOrdCounter:  atomic.NewInt64(time.Now().Unix()),
+		symbols:       cfg.SymbolMap,
+		instruments:   make(map[string]gotrader.InstrumentDetails),
+		quotes:        make(map[string]*gotrader.Tick),
+		trades:        make(map[string]*fixTrade),
+		closeRequests: make(map[string]*closeRequest),
+		pendingOrders: make(map[string]*gotrader.Order),
+	}
+
+	if c.symbols == nil {
+		c.symbols = gotrader.NewSymbolMap()
+	}
+
+	for _, inst := range cfg.Instruments {
+		c.instruments[inst.Name] = inst
+		c.symbols.Register(inst.Name)
+	}
+
+	for name, symbol := range cfg.Symbols {
+		c.symbols.Alias(venue, symbol, name)
+	}
+
+	return c
+}
+
+func (c *fixClient) connect() error {
+
+	c.sessionOnce.Do(func() {
+		c.session = newSession(c.cfg, c.onMessage)
+		c.sessionErr = c.session.logon()
+	})
+
+	return c.sessionErr
+}
+
+func (c *fixClient) instrumentBySymbol(symbol string) string {
+	return c.symbols.Canonical(venue, symbol)
+}
+
+func (c *fixClient) nextClOrdID() string {
+	return strconv.FormatInt(c.clOrdCounter.Inc(), 10)
+}
+
+func (c *fixClient) GetAccountStatus(accountID string) (gotrader.AccountStatus, error) {
+
+	if err := c.connect(); err != nil {
+		return gotrader.AccountStatus{}, err
+	}
+
+	return gotrader.AccountStatus{
+		Currency: c.cfg.Currency,
+		Hedge:    gotrader.NoHedge,
+		Balance:  c.cfg.Balance,
+		Equity:   c.cfg.Balance,
+		Leverage: c.cfg.Leverage,
+	}, nil
+}
+
+func (c *fixClient) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+	return c.cfg.Instruments, nil
+}
+
+func (c *fixClient) OpenMarketOrder(accountID, instrument string, units int32, side string) error {
+
+	s := gotrader.Long
+	if side == gotrader.Short.String() {
+		s = gotrader.Short
+	}
+
+	_, err := c.SubmitOrder(accountID, &gotrader.Order{
+		Type:       gotrader.MarketOrder,
+		Instrument: instrument,
+		Side:       s,
+		Units:      units,
+	})
+
+	return err
+}
+
+func (c *fixClient) CloseTrade(accountID, id string) error {
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	trade, exist := c.trades[id]
+	c.mutex.Unlock()
+
+	if !exist {
+		return errors.New("trade " + id + " does not exist")
+	}
+
+	side := gotrader.Long
+	if trade.details.Side == gotrader.Long {
+		side = gotrader.Short
+	}
+
+	clOrdID := c.nextClOrdID()
+
+	c.mutex.Lock()
+	c.closeRequests[clOrdID] = &closeRequest{tradeID: id}
+	c.mutex.Unlock()
+
+	return c.session.send(c.newOrderSingle(clOrdID, &gotrader.Order{
+		Type:       gotrader.MarketOrder,
+		Instrument: trade.details.Instrument.Name,
+		Side:       side,
+		Units:      trade.details.Units,
+	}))
+}
+
+func (c *fixClient) GetOpenTrades(accountID string) ([]gotrader.TradeDetails, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	trades := make([]gotrader.TradeDetails, 0, len(c.trades))
+	for _, t := range c.trades {
+		trades = append(trades, t.details)
+	}
+
+	return trades, nil
+}
+
+func (c *fixClient) SubmitOrder(accountID string, order *gotrader.Order) (string, error) {
+
+	if err := c.connect(); err != nil {
+		return "", err
+	}
+
+	clOrdID := order.ClientID // the venues reject the duplicated ClOrdIDs
+	if clOrdID == "" {
+		clOrdID = c.nextClOrdID()
+	}
+
+	if order.Type != gotrader.MarketOrder {
+		o := *order
+		o.ID = clOrdID
+		c.mutex.Lock()
+		c.pendingOrders[clOrdID] = &o
+		c.mutex.Unlock()
+	}
+
+	return clOrdID, c.session.send(c.newOrderSingle(clOrdID, order))
+}
+
+func (c *fixClient) ModifyOrder(accountID, orderID string, order *gotrader.Order) error {
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	clOrdID := c.nextClOrdID()
+
+	msg := c.newOrderSingle(clOrdID, order)
+	msg.Fields[0].Value = msgOrderCancelReplaceRequest
+	msg.Set(tagOrigClOrdID, orderID)
+
+	c.mutex.Lock()
+	if pending, exist := c.pendingOrders[orderID]; exist {
+		delete(c.pendingOrders, orderID)
+		o := *order
+		o.ID = clOrdID
+		o.CreateTime = pending.CreateTime
+		c.pendingOrders[clOrdID] = &o
+	}
+	c.mutex.Unlock()
+
+	return c.session.send(msg)
+}
+
+func (c *fixClient) CancelOrder(accountID, orderID string) error {
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	order, exist := c.pendingOrders[orderID]
+	c.mutex.Unlock()
+
+	if !exist {
+		return errors.New("order " + orderID + " does not exist")
+	}
+
+	return c.session.send(NewMessage(msgOrderCancelRequest).
+		Set(tagOrigClOrdID, orderID).
+		Set(tagClOrdID, c.nextClOrdID()).
+		Set(tagSymbol, c.symbols.Symbol(venue, order.Instrument)).
+		Set(tagSide, fixSide(order.Side)).
+		Set(tagOrderQty, strconv.Itoa(int(order.Units))).
+		Set(tagTransactTime, time.Now().UTC().Format(sendingTimeFmt)))
+}
+
+func (c *fixClient) GetPendingOrders(accountID string) ([]*gotrader.Order, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	orders := make([]*gotrader.Order, 0, len(c.pendingOrders))
+	for _, o := range c.pendingOrders {
+		orders = append(orders, o)
+	}
+
+	return orders, nil
+}
+
+func (c *fixClient) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	c.tickCallback = callback
+
+	msg := NewMessage(msgMarketDataRequest).
+		Set(tagMDReqID, c.nextClOrdID()).
+		Set(tagSubscriptionReq, "1").
+		Set(tagMarketDepth, "1").
+		Set(tagMDUpdateType, "1").
+		Set(tagNoMDEntryTypes, "2").
+		Set(tagMDEntryType, "0").
+		Set(tagMDEntryType, "1").
+		Set(tagNoRelatedSym, strconv.Itoa(len(instruments)))
+
+	for _, inst := range instruments {
+		msg.Set(tagSymbol, c.symbols.Symbol(venue, inst.Name))
+	}
+
+	return c.session.send(msg)
+}
+
+func (c *fixClient) SubscribeOrderFillNotifications(accountID string, orderFillCallback gotrader.OrderFillHandler) error {
+	c.orderFillCallback = orderFillCallback
+	return nil
+}
+
+// SubscribeSwapChargeNotifications is a no-op, financing is not reported through FIX.
+func (c *fixClient) SubscribeSwapChargeNotifications(accountID string, swapChargeCallback gotrader.SwapChargeHandler) error {
+	return nil
+}
+
+// SubscribeFundsTransferNotifications is a no-op, transfers are not reported through FIX.
+func (c *fixClient) SubscribeFundsTransferNotifications(accountID string, fundsTransferCallback gotrader.FundsTransferHandler) error {
+	return nil
+}
+
+// SubscribeHeartbeats beats the broker session on every message of the counterparty, its heartbeats included.
+func (c *fixClient) SubscribeHeartbeats(accountID string, heartbeatCallback gotrader.HeartbeatHandler) error {
+	c.heartbeatCallback = heartbeatCallback
+	return nil
+}
+
+func (c *fixClient) newOrderSingle(clOrdID string, order *gotrader.Order) *Message {
+
+	msg := NewMessage(msgNewOrderSingle).
+		Set(tagClOrdID, clOrdID).
+		Set(tagSymbol, c.symbols.Symbol(venue, order.Instrument)).
+		Set(tagSide, fixSide(order.Side)).
+		Set(tagOrderQty, strconv.Itoa(int(order.Units))).
+		Set(tagTransactTime, time.Now().UTC().Format(sendingTimeFmt))
+
+	if c.cfg.Account != "" {
+		msg.Set(tagAccount, c.cfg.Account)
+	}
+
+	switch order.Type {
+	case gotrader.MarketOrder:
+		msg.Set(tagOrdType, "1")
+	case gotrader.LimitOrder:
+		msg.Set(tagOrdType, "2").Set(tagPrice, strconv.FormatFloat(order.Price, 'f', -1, 64))
+	case gotrader.StopOrder:
+		msg.Set(tagOrdType, "3").Set(tagStopPx, strconv.FormatFloat(order.Price, 'f', -1, 64))
+	}
+
+	switch order.TimeInForce {
+	case gotrader.GoodTillCancelled:
+		if order.Type == gotrader.MarketOrder {
+			msg.Set(tagTimeInForce, "3") // IOC
+		} else {
+			msg.Set(tagTimeInForce, "1")
+		}
+	case gotrader.GoodTillDate:
+		msg.Set(tagTimeInForce, "6").Set(tagExpireTime, order.Expiry.UTC().Format(sendingTimeFmt))
+	case gotrader.FillOrKill:
+		msg.Set(tagTimeInForce, "4")
+	case gotrader.ImmediateOrCancel:
+		msg.Set(tagTimeInForce, "3")
+	}
+
+	return msg
+}
+
+func fixSide(side gotrader.Side) string {
+
+	if side == gotrader.Long {
+		return "1"
+	}
+
+	return "2"
+}
+
+func (c *fixClient) onMessage(msg *Message) {
+
+	if c.heartbeatCallback != nil {
+		c.heartbeatCallback(gotrader.BrokerComponent, time.Now())
+	}
+
+	switch msg.Type() {
+	case msgMarketDataSnapshot:
+		c.onMarketDataSnapshot(msg)
+	case msgMarketDataIncremental:
+		c.onMarketDataIncremental(msg)
+	case msgExecutionReport:
+		c.onExecutionReport(msg)
+	case msgOrderCancelReject, msgReject, msgMarketDataRequestReject:
+		text, _ := msg.Get(tagText)
+		clOrdID, _ := msg.Get(tagClOrdID)
+		if c.orderFillCallback != nil {
+			c.orderFillCallback(&gotrader.OrderFill{Error: "REJECTED: " + text, OrderID: clOrdID, Time: time.Now()})
+		}
+	}
+}
+
+// rejectReason returns the reason of an OrdRejReason (103) code, the others are classified from the text.
+func rejectReason(code string) gotrader.RejectReason {
+
+	switch code {
+	case "2", "4": // exchange closed, too late to enter
+		return gotrader.RejectMarketClosed
+	case "3", "13": // order exceeds limit, incorrect quantity
+		return gotrader.RejectSizeLimit
+	case "16": // price exceeds current price band
+		return gotrader.RejectPriceChanged
+	}
+
+	return gotrader.RejectUnknown
+}
+
+// onMarketDataSnapshot handles full refresh messages (MsgType W).
+func (c *fixClient) onMarketDataSnapshot(msg *Message) {
+
+	symbol, _ := msg.Get(tagSymbol)
+	entryType := ""
+
+	for _, f := range msg.Fields {
+		switch f.Tag {
+		case tagMDEntryType:
+			entryType = f.Value
+		case tagMDEntryPx:
+			c.updateQuote(c.instrumentBySymbol(symbol), entryType, f.Value)
+		}
+	}
+
+	c.emitTick(c.instrumentBySymbol(symbol))
+}
+
+// onMarketDataIncremental handles incremental refresh messages (MsgType X), each entry carries its symbol.
+func (c *fixClient) onMarketDataIncremental(msg *Message) {
+
+	var (
+		entryType string
+		action    string
+		price     string
+		updated   = make(map[string]bool)
+	)
+
+	flush := func(symbol string) {
+		if price != "" && action != "2" { // deletes are ignored, the next update replaces the level
+			inst := c.instrumentBySymbol(symbol)
+			c.updateQuote(inst, entryType, price)
+			updated[inst] = true
+		}
+		entryType, action, price = "", "", ""
+	}
+
+	for _, f := range msg.Fields {
+		switch f.Tag {
+		case tagMDUpdateAction:
+			action = f.Value
+		case tagMDEntryType:
+			entryType = f.Value
+		case tagMDEntryPx:
+			price = f.Value
+		case tagSymbol:
+			flush(f.Value)
+		}
+	}
+
+	for inst := range updated {
+		c.emitTick(inst)
+	}
+}
+
+func (c *fixClient) updateQuote(instrument, entryType, value string) {
+
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	quote, exist := c.quotes[instrument]
+	if !exist {
+		quote = &gotrader.Tick{Instrument: instrument}
+		c.quotes[instrument] = quote
+	}
+
+	switch entryType {
+	case "0":
+		quote.Bid = price
+	case "1":
+		quote.Ask = price
+	}
+}
+
+func (c *fixClient) emitTick(instrument string) {
+
+	c.mutex.Lock()
+	quote, exist := c.quotes[instrument]
+	if !exist || quote.Bid == 0 || quote.Ask == 0 {
+		c.mutex.Unlock()
+		return
+	}
+	tick := &gotrader.Tick{Instrument: instrument, Bid: quote.Bid, Ask: quote.Ask, Time: time.Now()}
+	c.mutex.Unlock()
+
+	if c.tickCallback != nil {
+		c.tickCallback(tick)
+	}
+}
+
+func (c *fixClient) onExecutionReport(msg *Message) {
+
+	execType, _ := msg.Get(tagExecType)
+	clOrdID, _ := msg.Get(tagClOrdID)
+	symbol, _ := msg.Get(tagSymbol)
+	instrument := c.instruments[c.instrumentBySymbol(symbol)]
+
+	side := gotrader.Long
+	if s, _ := msg.Get(tagSide); s == "2" {
+		side = gotrader.Short
+	}
+
+	fillTime := time.Now()
+	if t, exist := msg.Get(tagTransactTime); exist {
+		if parsed, err := time.Parse(sendingTimeFmt, t); err == nil {
+			fillTime = parsed
+		}
+	}
+
+	switch execType {
+	case "8", "4", "C": // rejected, cancelled, expired
+		c.mutex.Lock()
+		delete(c.pendingOrders, clOrdID)
+		delete(c.closeRequests, clOrdID)
+		c.mutex.Unlock()
+
+		if execType == "8" && c.orderFillCallback != nil {
+			text, _ := msg.Get(tagText)
+			code, _ := msg.Get(tagOrdRejReason)
+			c.orderFillCallback(&gotrader.OrderFill{
+				Error:         text,
+				Rejection:     rejectReason(code),
+				OrderID:       clOrdID,
+				ClientOrderID: clOrdID,
+				Side:          side,
+				Instrument:    instrument,
+				Time:          fillTime,
+			})
+		}
+		return
+	case "F": // trade
+	default:
+		return
+	}
+
+	execID, _ := msg.Get(tagExecID)
+	qty := int32(math.Round(msg.GetFloat(tagLastQty)))
+	price := msg.GetFloat(tagLastPx)
+
+	c.mutex.Lock()
+	if status, _ := msg.Get(tagOrdStatus); status == "2" {
+		delete(c.pendingOrders, clOrdID)
+	}
+	closing, isClose := c.closeRequests[clOrdID]
+	var trade *fixTrade
+	if isClose {
+		delete(c.closeRequests, clOrdID)
+		trade = c.trades[closing.tradeID]
+		delete(c.trades, closing.tradeID)
+	} else {
+		c.trades[execID] = &fixTrade{details: gotrader.TradeDetails{
+			ID:         execID,
+			Instrument: instrument,
+			Side:       side,
+			Units:      qty,
+			OpenPrice:  price,
+			OpenTime:   fillTime,
+		}}
+	}
+	c.mutex.Unlock()
+
+	if c.orderFillCallback == nil {
+		return
+	}
+
+	if isClose && trade != nil { // profit is reported in quote currency, FIX has no realized P&L field
+		c.orderFillCallback(&gotrader.OrderFill{
+			TradeClose: true,
+			OrderID:    clOrdID,
+			TradeID:    trade.details.ID,
+			Side:       trade.details.Side,
+			Instrument: trade.details.Instrument,
+			Price:      price,
+			Units:      trade.details.Units,
+			Profit:     (price - trade.details.OpenPrice) * float64(trade.details.Units) * sign(trade.details.Side),
+			Time:       fillTime,
+		})
+		return
+	}
+
+	c.orderFillCallback(&gotrader.OrderFill{
+		OrderID:       clOrdID,
+		ClientOrderID: clOrdID,
+		TradeID:       execID,
+		Side:          side,
+		Instrument:    instrument,
+		Price:         price,
+		Units:         qty,
+		Time:          fillTime,
+	})
+}
+
+func sign(side gotrader.Side) float64 {
+
+	if side == gotrader.Short {
+		return -1
+	}
+
+	return 1
+}
@@ -0,0 +1,275 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const soh = '\x01'
+
+// FIX tags used by the adapter
+const (
+	tagAccount         = 1
+	tagAvgPx           = 6
+	tagBeginString     = 8
+	tagBodyLength      = 9
+	tagCheckSum        = 10
+	tagClOrdID         = 11
+	tagCumQty          = 14
+	tagExecID          = 17
+	tagLastPx          = 31
+	tagLastQty         = 32
+	tagMsgSeqNum       = 34
+	tagMsgType         = 35
+	tagOrderID         = 37
+	tagOrderQty        = 38
+	tagOrdStatus       = 39
+	tagOrdType         = 40
+	tagOrigClOrdID     = 41
+	tagPrice           = 44
+	tagSenderCompID    = 49
+	tagSendingTime     = 52
+	tagSide            = 54
+	tagSymbol          = 55
+	tagTargetCompID    = 56
+	tagText            = 58
+	tagTimeInForce     = 59
+	tagTransactTime    = 60
+	tagStopPx          = 99
+	tagEncryptMethod   = 98
+	tagOrdRejReason    = 103
+	tagHeartBtInt      = 108
+	tagTestReqID       = 112
+	tagExpireTime      = 126
+	tagResetSeqNumFlag = 141
+	tagNoRelatedSym    = 146
+	tagExecType        = 150
+	tagMDReqID         = 262
+	tagSubscriptionReq = 263
+	tagMarketDepth     = 264
+	tagMDUpdateType    = 265
+	tagNoMDEntryTypes  = 267
+	tagNoMDEntries     = 268
+	tagMDEntryType     = 269
+	tagMDEntryPx       = 270
+	tagMDUpdateAction  = 279
+	tagUsername        = 553
+	tagPassword        = 554
+)
+
+// FIX message types used by the adapter
+const (
+	msgHeartbeat                 = "0"
+	msgTestRequest               = "1"
+	msgReject                    = "3"
+	msgLogout                    = "5"
+	msgExecutionReport           = "8"
+	msgOrderCancelReject         = "9"
+	msgLogon                     = "A"
+	msgNewOrderSingle            = "D"
+	msgOrderCancelRequest        = "F"
+	msgOrderCancelReplaceRequest = "G"
+	msgMarketDataRequest         = "V"
+	msgMarketDataSnapshot        = "W"
+	msgMarketDataIncremental     = "X"
+	msgMarketDataRequestReject   = "Y"
+)
+
+// Field is a single tag=value pair.
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message is a FIX message, fields are kept by order so repeating groups can be walked.
+type Message struct {
+	Fields []Field
+}
+
+// NewMessage creates a message of the given type.
+func NewMessage(msgType string) *Message {
+	return &Message{Fields: []Field{{Tag: tagMsgType, Value: msgType}}}
+}
+
+// Set appends a field to the message.
+func (m *Message) Set(tag int, value string) *Message {
+	m.Fields = append(m.Fields, Field{Tag: tag, Value: value})
+	return m
+}
+
+// Get returns the first value of the tag.
+func (m *Message) Get(tag int) (string, bool) {
+
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// GetFloat returns the first value of the tag parsed as float.
+func (m *Message) GetFloat(tag int) float64 {
+
+	v, _ := m.Get(tag)
+	f, _ := strconv.ParseFloat(v, 64)
+
+	return f
+}
+
+// Type returns the message type.
+func (m *Message) Type() string {
+
+	t, _ := m.Get(tagMsgType)
+
+	return t
+}
+
+// Bytes encodes the message computing the body length and checksum.
+func (m *Message) Bytes(beginString string) []byte {
+
+	var body bytes.Buffer
+
+	for _, f := range m.Fields {
+		if f.Tag == tagBeginString || f.Tag == tagBodyLength || f.Tag == tagCheckSum {
+			continue
+		}
+		body.WriteString(strconv.Itoa(f.Tag))
+		body.WriteByte('=')
+		body.WriteString(f.Value)
+		body.WriteByte(soh)
+	}
+
+	var msg bytes.Buffer
+
+	fmt.Fprintf(&msg, "8=%s%c9=%d%c", beginString, soh, body.Len(), soh)
+	msg.Write(body.Bytes())
+	fmt.Fprintf(&msg, "10=%03d%c", checksum(msg.Bytes()), soh)
+
+	return msg.Bytes()
+}
+
+// ParseMessage decodes a complete FIX message, validating its checksum.
+func ParseMessage(data []byte) (*Message, error) {
+
+	idx := bytes.LastIndex(data[:len(data)-1], []byte{soh, '1', '0', '='})
+	if idx < 0 {
+		return nil, errors.New("fix message without checksum")
+	}
+
+	expected, err := strconv.Atoi(string(bytes.TrimRight(data[idx+4:], string(soh))))
+	if err != nil {
+		return nil, err
+	}
+
+	if checksum(data[:idx+1]) != expected {
+		return nil, errors.New("fix message checksum mismatch")
+	}
+
+	m := &Message{Fields: make([]Field, 0, 16)}
+
+	for _, raw := range bytes.Split(bytes.TrimRight(data, string(soh)), []byte{soh}) {
+
+		eq := bytes.IndexByte(raw, '=')
+		if eq < 0 {
+			return nil, errors.New("malformed fix field " + string(raw))
+		}
+
+		tag, err := strconv.Atoi(string(raw[:eq]))
+		if err != nil {
+			return nil, err
+		}
+
+		m.Fields = append(m.Fields, Field{Tag: tag, Value: string(raw[eq+1:])})
+	}
+
+	return m, nil
+}
+
+// readMessage reads a single message from the stream using the declared body length.
+func readMessage(reader *bufio.Reader) (*Message, error) {
+
+	begin, err := reader.ReadBytes(soh)
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := reader.ReadBytes(soh)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(length, []byte("9=")) {
+		return nil, errors.New("fix message without body length")
+	}
+
+	bodyLength, err := strconv.Atoi(string(length[2 : len(length)-1]))
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+
+	trailer, err := reader.ReadBytes(soh)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(begin)+len(length)+len(body)+len(trailer))
+	data = append(data, begin...)
+	data = append(data, length...)
+	data = append(data, body...)
+	data = append(data, trailer...)
+
+	return ParseMessage(data)
+}
+
+func checksum(data []byte) int {
+
+	sum := 0
+	for _, b := range data {
+		sum += int(b)
+	}
+
+	return sum % 256
+}
+
+// ReadMessages decodes the messages of a stream until its end, e.g. a drop copy file, the whitespace between
+// messages is skipped.
+func ReadMessages(r io.Reader) ([]*Message, error) {
+
+	reader := bufio.NewReader(r)
+	messages := make([]*Message, 0)
+
+	for {
+
+		for {
+			b, err := reader.ReadByte()
+			if err == io.EOF {
+				return messages, nil
+			}
+			if err != nil {
+				return messages, err
+			}
+			if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+				reader.UnreadByte()
+				break
+			}
+		}
+
+		msg, err := readMessage(reader)
+		if err != nil {
+			return messages, err
+		}
+
+		messages = append(messages, msg)
+	}
+}
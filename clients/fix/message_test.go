@@ -0,0 +1,44 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestMessage_Bytes(t *testing.T) {
+
+	t.Run("encode and parse", func(t *testing.T) {
+
+		msg := NewMessage(msgNewOrderSingle).
+			Set(tagClOrdID, "1").
+			Set(tagSymbol, "EUR/USD").
+			Set(tagSide, "1").
+			Set(tagOrderQty, "1000")
+
+		data := msg.Bytes("FIX.4.4")
+
+		parsed, err := readMessage(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if parsed.Type() != msgNewOrderSingle {
+			t.Errorf("expected message type %s, got %s", msgNewOrderSingle, parsed.Type())
+		}
+
+		if symbol, _ := parsed.Get(tagSymbol); symbol != "EUR/USD" {
+			t.Errorf("expected symbol EUR/USD, got %s", symbol)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+
+		data := NewMessage(msgHeartbeat).Bytes("FIX.4.4")
+		data[len(data)-2] = '9'
+
+		if _, err := ParseMessage(data); err == nil {
+			t.Error("expected checksum error")
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+const (
+	beginString     = "FIX.4.4"
+	sendingTimeFmt  = "20060102-15:04:05.000"
+	defaultHeartBtS = 30
+)
+
+// session implements the FIX session layer: logon, heartbeats and sequence numbers.
+type session struct {
+	cfg      Config
+	conn     net.Conn
+	mutex    *sync.Mutex
+	outSeq   *atomic.Int64
+	inSeq    *atomic.Int64
+	handler  func(msg *Message)
+	loggedOn chan error
+	stop     chan bool
+}
+
+func newSession(cfg Config, handler func(msg *Message)) *session {
+	return &session{
+		cfg:      cfg,
+		mutex:    &sync.Mutex{},
+		outSeq:   atomic.NewInt64(0),
+		inSeq:    atomic.NewInt64(0),
+		handler:  handler,
+		loggedOn: make(chan error, 1),
+		stop:     make(chan bool),
+	}
+}
+
+func (s *session) heartBeat() time.Duration {
+
+	if s.cfg.HeartBeat <= 0 {
+		return defaultHeartBtS * time.Second
+	}
+
+	return s.cfg.HeartBeat
+}
+
+// logon dials the counterparty and waits for the logon acknowledgement.
+func (s *session) logon() error {
+
+	conn, err := net.DialTimeout("tcp", s.cfg.Address, 10*time.Second)
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+
+	go s.readLoop(bufio.NewReader(conn))
+
+	logon := NewMessage(msgLogon).
+		Set(tagEncryptMethod, "0").
+		Set(tagHeartBtInt, strconv.Itoa(int(s.heartBeat()/time.Second))).
+		Set(tagResetSeqNumFlag, "Y")
+
+	if s.cfg.Username != "" {
+		logon.Set(tagUsername, s.cfg.Username).Set(tagPassword, s.cfg.Password)
+	}
+
+	if err := s.send(logon); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-s.loggedOn:
+		if err != nil {
+			return err
+		}
+	case <-time.After(10 * time.Second):
+		return errors.New("fix logon timeout")
+	}
+
+	go s.heartBeatLoop()
+
+	return nil
+}
+
+func (s *session) logout() {
+	s.send(NewMessage(msgLogout))
+	close(s.stop)
+	s.conn.Close()
+}
+
+// send adds the standard header and writes the message.
+func (s *session) send(msg *Message) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	header := []Field{
+		msg.Fields[0], // MsgType
+		{Tag: tagSenderCompID, Value: s.cfg.SenderCompID},
+		{Tag: tagTargetCompID, Value: s.cfg.TargetCompID},
+		{Tag: tagMsgSeqNum, Value: strconv.FormatInt(s.outSeq.Inc(), 10)},
+		{Tag: tagSendingTime, Value: time.Now().UTC().Format(sendingTimeFmt)},
+	}
+
+	full := &Message{Fields: append(header, msg.Fields[1:]...)}
+
+	_, err := s.conn.Write(full.Bytes(beginString))
+
+	return err
+}
+
+func (s *session) heartBeatLoop() {
+
+	ticker := time.NewTicker(s.heartBeat())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.send(NewMessage(msgHeartbeat))
+		}
+	}
+}
+
+func (s *session) readLoop(reader *bufio.Reader) {
+
+	for {
+
+		msg, err := readMessage(reader)
+		if err != nil {
+			select {
+			case s.loggedOn <- err:
+			default:
+			}
+			return
+		}
+
+		s.inSeq.Inc()
+
+		switch msg.Type() {
+		case msgLogon:
+			s.loggedOn <- nil
+		case msgLogout:
+			text, _ := msg.Get(tagText)
+			select {
+			case s.loggedOn <- errors.New("fix logout: " + text):
+			default:
+			}
+			return
+		case msgTestRequest:
+			id, _ := msg.Get(tagTestReqID)
+			s.send(NewMessage(msgHeartbeat).Set(tagTestReqID, id))
+		default: // the heartbeats are handled as the liveness of the counterparty
+			s.handler(msg)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package ib
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/luismcruz/gotrader"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+type restClient struct {
+	baseURL string
+	client  http.Client
+}
+
+func newRestClient(baseURL string, insecure bool) *restClient {
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if insecure { // the gateway listens with a self signed certificate by default
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+
+	return &restClient{
+		baseURL: baseURL,
+		client:  http.Client{Transport: transport},
+	}
+}
+
+func (c *restClient) get(endpoint string, data interface{}) error {
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.do(req, data)
+}
+
+func (c *restClient) post(endpoint string, body interface{}, data interface{}) error {
+	return c.postContext(context.Background(), endpoint, body, data)
+}
+
+// postContext sends a post request propagating the trace of the context in its headers.
+func (c *restClient) postContext(ctx context.Context, endpoint string, body interface{}, data interface{}) error {
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+endpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return err
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	req.Header.Set("Content-Type", "application/json")
+
+	return c.do(req, data)
+}
+
+func (c *restClient) do(req *http.Request, data interface{}) error {
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable { // not processed
+		return fmt.Errorf("%w: ib gateway error %d: %s", gotrader.ErrTransient, res.StatusCode, body)
+	}
+
+	if res.StatusCode >= 300 {
+		return errors.New("ib gateway error " + strconv.Itoa(res.StatusCode) + ": " + string(body))
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	return json.Unmarshal(body, data)
+}
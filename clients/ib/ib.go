@@ -0,0 +1,533 @@
+package ib
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"go.uber.org/atomic"
+)
+
+// Contract maps an IB contract to a gotrader instrument.
+type Contract struct {
+	ConID    int
+	SecType  string // CASH, STK, CFD, FUT...
+	Symbol   string // e.g. EUR for EUR.USD cash pairs, AAPL for stocks
+	Currency string
+	Leverage float64
+	PipLoc   int
+}
+
+// Config holds the Client Portal gateway settings.
+type Config struct {
+	BaseURL      string // defaults to https://localhost:5000/v1/api
+	Insecure     bool   // skip tls verification of the self signed gateway certificate
+	Leverage     float64
+	PollInterval time.Duration
+	Contracts    map[string]Contract // instrument name to contract
+}
+
+// PositionBreak is a difference between the locally tracked trades and the IB reported position.
+type PositionBreak struct {
+	Instrument  string
+	LocalUnits  float64
+	BrokerUnits float64
+}
+
+// Reconciler is implemented by the IB client, it compares the local book with the IB position report.
+type Reconciler interface {
+	ReconcilePositions(accountID string) ([]PositionBreak, error)
+	Commissions() map[string]float64
+}
+
+type ibTrade struct {
+	details gotrader.TradeDetails
+	conID   int
+}
+
+type ibClient struct {
+	cfg               Config
+	rest              *restClient
+	mutex             *sync.Mutex
+	refCounter        *atomic.Int64
+	byConID           map[int]string
+	trades            map[string]*ibTrade
+	closeRequests     map[string]string // order ref to trade id
+	seenExecutions    map[string]bool
+	commissions       map[string]float64 // execution id to commission
+	orderFillCallback gotrader.OrderFillHandler
+	stop              chan bool
+}
+
+// NewIBClient is the Interactive Brokers gateway adapter constructor.
+// IB nets positions, so trades are tracked from the executions and closed with opposite side orders.
+func NewIBClient(cfg Config) gotrader.BrokerClient {
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://localhost:5000/v1/api"
+	}
+
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+
+	c := &ibClient{
+		cfg:            cfg,
+		rest:           newRestClient(cfg.BaseURL, cfg.Insecure),
+		mutex:          &sync.Mutex{},
+		refCounter:     atomic.NewInt64(time.Now().Unix()),
+		byConID:        make(map[int]string),
+		trades:         make(map[string]*ibTrade),
+		closeRequests:  make(map[string]string),
+		seenExecutions: make(map[string]bool),
+		commissions:    make(map[string]float64),
+		stop:           make(chan bool),
+	}
+
+	for name, contract := range cfg.Contracts {
+		c.byConID[contract.ConID] = name
+	}
+
+	return c
+}
+
+func (c *ibClient) GetAccountStatus(accountID string) (gotrader.AccountStatus, error) {
+
+	summary := accountSummary{}
+	if err := c.rest.get("/portfolio/"+accountID+"/summary", &summary); err != nil {
+		return gotrader.AccountStatus{}, err
+	}
+
+	leverage := c.cfg.Leverage
+	if leverage == 0 {
+		leverage = 1
+	}
+
+	return gotrader.AccountStatus{
+		Currency:              summary.NetLiquidation.Currency,
+		Hedge:                 gotrader.NoHedge,
+		Equity:                summary.NetLiquidation.Amount,
+		Balance:               summary.TotalCashValue.Amount,
+		UnrealizedGrossProfit: summary.UnrealizedPnL.Amount,
+		MarginUsed:            summary.InitMarginReq.Amount,
+		MarginFree:            summary.AvailableFunds.Amount,
+		Leverage:              leverage,
+	}, nil
+}
+
+func (c *ibClient) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+
+	instruments := make([]gotrader.InstrumentDetails, 0, len(c.cfg.Contracts))
+
+	for name, contract := range c.cfg.Contracts {
+		instruments = append(instruments, instrumentDetails(name, contract))
+	}
+
+	return instruments, nil
+}
+
+func instrumentDetails(name string, contract Contract) gotrader.InstrumentDetails {
+
+	leverage := contract.Leverage
+	if leverage == 0 {
+		leverage = 1
+	}
+
+	return gotrader.InstrumentDetails{
+		Name:          name,
+		BaseCurrency:  contract.Symbol,
+		QuoteCurrency: contract.Currency,
+		Leverage:      leverage,
+		PipLocation:   contract.PipLoc,
+	}
+}
+
+func (c *ibClient) OpenMarketOrder(accountID, instrument string, units int32, side string) error {
+	return c.OpenMarketOrderContext(context.Background(), accountID, instrument, units, side)
+}
+
+// OpenMarketOrderContext implements gotrader.ContextClient.
+func (c *ibClient) OpenMarketOrderContext(ctx context.Context, accountID, instrument string, units int32, side string) error {
+
+	s := gotrader.Long
+	if side == gotrader.Short.String() {
+		s = gotrader.Short
+	}
+
+	_, err := c.placeOrder(ctx, accountID, instrument, s, units, c.nextRef())
+
+	return err
+}
+
+func (c *ibClient) CloseTrade(accountID, id string) error {
+	return c.CloseTradeContext(context.Background(), accountID, id)
+}
+
+// CloseTradeContext implements gotrader.ContextClient.
+func (c *ibClient) CloseTradeContext(ctx context.Context, accountID, id string) error {
+
+	c.mutex.Lock()
+	trade, exist := c.trades[id]
+	c.mutex.Unlock()
+
+	if !exist {
+		return errors.New("trade " + id + " does not exist")
+	}
+
+	side := gotrader.Long
+	if trade.details.Side == gotrader.Long {
+		side = gotrader.Short
+	}
+
+	ref := c.nextRef()
+
+	c.mutex.Lock()
+	c.closeRequests[ref] = id
+	c.mutex.Unlock()
+
+	_, err := c.placeOrder(ctx, accountID, trade.details.Instrument.Name, side, trade.details.Units, ref)
+
+	return err
+}
+
+// GetOpenTrades maps every IB position to a single trade, since IB only reports the aggregated position.
+func (c *ibClient) GetOpenTrades(accountID string) ([]gotrader.TradeDetails, error) {
+
+	positions := make([]position, 0)
+	if err := c.rest.get("/portfolio/"+accountID+"/positions/0", &positions); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	trades := make([]gotrader.TradeDetails, 0, len(positions))
+
+	for _, p := range positions {
+
+		name, exist := c.byConID[p.ConID]
+		if !exist || p.Position == 0 {
+			continue
+		}
+
+		side := gotrader.Long
+		if p.Position < 0 {
+			side = gotrader.Short
+		}
+
+		details := gotrader.TradeDetails{
+			ID:         "POS-" + strconv.Itoa(p.ConID),
+			Instrument: instrumentDetails(name, c.cfg.Contracts[name]),
+			Side:       side,
+			Units:      int32(math.Abs(p.Position)),
+			OpenPrice:  p.AvgPrice,
+			OpenTime:   time.Now(),
+		}
+
+		c.trades[details.ID] = &ibTrade{details: details, conID: p.ConID}
+		trades = append(trades, details)
+	}
+
+	return trades, nil
+}
+
+func (c *ibClient) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+
+	conIDs := make([]string, 0, len(instruments))
+	for _, inst := range instruments {
+		contract, exist := c.cfg.Contracts[inst.Name]
+		if !exist {
+			return errors.New("no ib contract configured for " + inst.Name)
+		}
+		conIDs = append(conIDs, strconv.Itoa(contract.ConID))
+	}
+
+	endpoint := "/iserver/marketdata/snapshot?conids=" + strings.Join(conIDs, ",") + "&fields=84,86"
+
+	go func() {
+
+		ticker := time.NewTicker(c.cfg.PollInterval)
+		defer ticker.Stop()
+
+		last := make(map[int]snapshot)
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+
+				snapshots := make([]snapshot, 0)
+				if err := c.rest.get(endpoint, &snapshots); err != nil {
+					continue
+				}
+
+				for _, s := range snapshots {
+
+					if prev, exist := last[s.ConID]; exist && prev.Bid == s.Bid && prev.Ask == s.Ask {
+						continue // conflate unchanged quotes
+					}
+					last[s.ConID] = s
+
+					bid, ask := parsePrice(s.Bid), parsePrice(s.Ask)
+					if bid == 0 || ask == 0 {
+						continue
+					}
+
+					callback(&gotrader.Tick{
+						Instrument: c.byConID[s.ConID],
+						Bid:        bid,
+						Ask:        ask,
+						Time:       time.Now(),
+					})
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (c *ibClient) SubscribeOrderFillNotifications(accountID string, orderFillCallback gotrader.OrderFillHandler) error {
+
+	c.orderFillCallback = orderFillCallback
+
+	go func() {
+
+		ticker := time.NewTicker(c.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-ticker.C:
+				executions := make([]execution, 0)
+				if err := c.rest.get("/iserver/account/trades", &executions); err != nil {
+					continue
+				}
+				for _, e := range executions {
+					c.onExecution(e)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// SubscribeSwapChargeNotifications is a no-op, IB accrues financing in the cash balance.
+func (c *ibClient) SubscribeSwapChargeNotifications(accountID string, swapChargeCallback gotrader.SwapChargeHandler) error {
+	return nil
+}
+
+// SubscribeFundsTransferNotifications is a no-op, transfers are not reported by the gateway.
+func (c *ibClient) SubscribeFundsTransferNotifications(accountID string, fundsTransferCallback gotrader.FundsTransferHandler) error {
+	return nil
+}
+
+// ReconcilePositions compares the units of the locally tracked trades against the IB position report.
+func (c *ibClient) ReconcilePositions(accountID string) ([]PositionBreak, error) {
+
+	positions := make([]position, 0)
+	if err := c.rest.get("/portfolio/"+accountID+"/positions/0", &positions); err != nil {
+		return nil, err
+	}
+
+	broker := make(map[string]float64)
+	for _, p := range positions {
+		if name, exist := c.byConID[p.ConID]; exist {
+			broker[name] += p.Position
+		}
+	}
+
+	c.mutex.Lock()
+	local := make(map[string]float64)
+	for _, t := range c.trades {
+		local[t.details.Instrument.Name] += float64(t.details.Units) * sign(t.details.Side)
+	}
+	c.mutex.Unlock()
+
+	breaks := make([]PositionBreak, 0)
+
+	for name := range c.cfg.Contracts {
+		if local[name] != broker[name] {
+			breaks = append(breaks, PositionBreak{
+				Instrument:  name,
+				LocalUnits:  local[name],
+				BrokerUnits: broker[name],
+			})
+		}
+	}
+
+	return breaks, nil
+}
+
+// Commissions returns the commission reported for each execution.
+func (c *ibClient) Commissions() map[string]float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	commissions := make(map[string]float64, len(c.commissions))
+	for k, v := range c.commissions {
+		commissions[k] = v
+	}
+
+	return commissions
+}
+
+func (c *ibClient) nextRef() string {
+	return "GT-" + strconv.FormatInt(c.refCounter.Inc(), 10)
+}
+
+func (c *ibClient) placeOrder(ctx context.Context, accountID, instrument string, side gotrader.Side, units int32, ref string) (string, error) {
+
+	contract, exist := c.cfg.Contracts[instrument]
+	if !exist {
+		return "", errors.New("no ib contract configured for " + instrument)
+	}
+
+	ibSide := "BUY"
+	if side == gotrader.Short {
+		ibSide = "SELL"
+	}
+
+	replies := make([]orderReply, 0)
+	err := c.rest.postContext(ctx, "/iserver/account/"+accountID+"/orders", ordersRequest{Orders: []orderRequest{{
+		ConID:     contract.ConID,
+		COID:      ref,
+		OrderType: "MKT",
+		Side:      ibSide,
+		Quantity:  float64(units),
+		TIF:       "DAY",
+	}}}, &replies)
+
+	for i := 0; err == nil && len(replies) > 0 && replies[0].OrderID == "" && replies[0].ID != ""; i++ {
+
+		if i > 3 {
+			return "", errors.New("ib order was not confirmed: " + strings.Join(replies[0].Message, "; "))
+		}
+
+		// The gateway asks to confirm precautionary warnings before accepting the order
+		next := make([]orderReply, 0)
+		err = c.rest.postContext(ctx, "/iserver/reply/"+replies[0].ID, map[string]bool{"confirmed": true}, &next)
+		replies = next
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(replies) == 0 {
+		return "", errors.New("empty ib order reply")
+	}
+
+	return replies[0].OrderID, nil
+}
+
+func (c *ibClient) onExecution(e execution) {
+
+	c.mutex.Lock()
+
+	if c.seenExecutions[e.ExecutionID] {
+		c.mutex.Unlock()
+		return
+	}
+	c.seenExecutions[e.ExecutionID] = true
+
+	name, exist := c.byConID[e.ConID]
+	if !exist { // execution of an instrument not managed by this client
+		c.mutex.Unlock()
+		return
+	}
+
+	commission, _ := strconv.ParseFloat(e.Commission, 64)
+	c.commissions[e.ExecutionID] = commission
+
+	side := gotrader.Long
+	if e.Side == "S" || e.Side == "SELL" {
+		side = gotrader.Short
+	}
+
+	price := parsePrice(e.Price)
+	units := int32(math.Round(e.Size))
+	fillTime := time.Unix(0, e.TradeTimeMsec*int64(time.Millisecond))
+	instrument := instrumentDetails(name, c.cfg.Contracts[name])
+
+	var fill *gotrader.OrderFill
+
+	if tradeID, isClose := c.closeRequests[e.OrderRef]; isClose {
+
+		delete(c.closeRequests, e.OrderRef)
+		trade := c.trades[tradeID]
+		delete(c.trades, tradeID)
+
+		if trade != nil {
+			fill = &gotrader.OrderFill{
+				TradeClose:  true,
+				OrderID:     e.OrderRef,
+				TradeID:     tradeID,
+				Side:        trade.details.Side,
+				Instrument:  instrument,
+				Price:       price,
+				Units:       trade.details.Units,
+				Profit:      (price - trade.details.OpenPrice) * float64(trade.details.Units) * sign(trade.details.Side),
+				ChargedFees: -commission,
+				Time:        fillTime,
+			}
+		}
+
+	} else {
+
+		details := gotrader.TradeDetails{
+			ID:          e.ExecutionID,
+			Instrument:  instrument,
+			Side:        side,
+			Units:       units,
+			OpenPrice:   price,
+			ChargedFees: -commission,
+			OpenTime:    fillTime,
+		}
+		c.trades[e.ExecutionID] = &ibTrade{details: details, conID: e.ConID}
+
+		fill = &gotrader.OrderFill{
+			OrderID:     e.OrderRef,
+			TradeID:     e.ExecutionID,
+			Side:        side,
+			Instrument:  instrument,
+			Price:       price,
+			Units:       units,
+			ChargedFees: -commission,
+			Time:        fillTime,
+		}
+	}
+
+	c.mutex.Unlock()
+
+	if fill != nil && c.orderFillCallback != nil {
+		c.orderFillCallback(fill)
+	}
+}
+
+// parsePrice parses gateway prices, which may carry a prefix such as C (close) or H (halted).
+func parsePrice(value string) float64 {
+
+	value = strings.TrimLeft(value, "CH")
+	price, _ := strconv.ParseFloat(value, 64)
+
+	return price
+}
+
+func sign(side gotrader.Side) float64 {
+
+	if side == gotrader.Short {
+		return -1
+	}
+
+	return 1
+}
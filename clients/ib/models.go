@@ -0,0 +1,64 @@
+package ib
+
+type summaryValue struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+type accountSummary struct {
+	NetLiquidation summaryValue `json:"netliquidation"`
+	TotalCashValue summaryValue `json:"totalcashvalue"`
+	InitMarginReq  summaryValue `json:"initmarginreq"`
+	AvailableFunds summaryValue `json:"availablefunds"`
+	UnrealizedPnL  summaryValue `json:"unrealizedpnl"`
+}
+
+type position struct {
+	ConID    int     `json:"conid"`
+	Position float64 `json:"position"`
+	AvgCost  float64 `json:"avgCost"`
+	AvgPrice float64 `json:"avgPrice"`
+	Currency string  `json:"currency"`
+}
+
+type orderRequest struct {
+	ConID      int     `json:"conid"`
+	COID       string  `json:"cOID"`
+	OrderType  string  `json:"orderType"`
+	Side       string  `json:"side"`
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price,omitempty"`
+	AuxPrice   float64 `json:"auxPrice,omitempty"`
+	TIF        string  `json:"tif"`
+	OutsideRTH bool    `json:"outsideRTH"`
+}
+
+type ordersRequest struct {
+	Orders []orderRequest `json:"orders"`
+}
+
+// orderReply is either an order acknowledgement or a question that must be confirmed.
+type orderReply struct {
+	ID          string   `json:"id"`
+	Message     []string `json:"message"`
+	OrderID     string   `json:"order_id"`
+	OrderStatus string   `json:"order_status"`
+}
+
+type execution struct {
+	ExecutionID   string  `json:"execution_id"`
+	ConID         int     `json:"conid"`
+	Side          string  `json:"side"`
+	Size          float64 `json:"size"`
+	Price         string  `json:"price"`
+	OrderRef      string  `json:"order_ref"`
+	Commission    string  `json:"commission"`
+	NetAmount     float64 `json:"net_amount"`
+	TradeTimeMsec int64   `json:"trade_time_r"`
+}
+
+type snapshot struct {
+	ConID int    `json:"conid"`
+	Bid   string `json:"84"`
+	Ask   string `json:"86"`
+}
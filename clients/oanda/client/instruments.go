@@ -0,0 +1,59 @@
+package oandacl
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+)
+
+type Candles struct {
+	Instrument   string   `json:"instrument"`
+	Granularity  string   `json:"granularity"`
+	Candles      []Candle `json:"candles"`
+	ErrorMessage string   `json:"errorMessage"`
+}
+
+type Candle struct {
+	Complete bool       `json:"complete"`
+	Volume   int        `json:"volume"`
+	Time     time.Time  `json:"time"`
+	Mid      CandleData `json:"mid"`
+}
+
+type CandleData struct {
+	O float64 `json:"o,string"`
+	H float64 `json:"h,string"`
+	L float64 `json:"l,string"`
+	C float64 `json:"c,string"`
+}
+
+// GetCandles returns the mid candles of the instrument granularity (M1, H1, D...) from from (inclusive), at most
+// 5000 candles are returned by request.
+func (c *OandaClient) GetCandles(instrument, granularity string, from, to time.Time) (Candles, error) {
+
+	query := url.Values{}
+	query.Set("price", "M")
+	query.Set("granularity", granularity)
+	query.Set("from", from.UTC().Format(time.RFC3339Nano))
+	query.Set("to", to.UTC().Format(time.RFC3339Nano))
+
+	response, err := c.get("/instruments/" + instrument + "/candles?" + query.Encode())
+
+	if err != nil {
+		return Candles{}, err
+	}
+
+	data := Candles{}
+	err = json.Unmarshal(response, &data)
+
+	if err != nil {
+		return Candles{}, err
+	}
+
+	if data.ErrorMessage != "" {
+		return Candles{}, errors.New(data.ErrorMessage)
+	}
+
+	return data, nil
+}
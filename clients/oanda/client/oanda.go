@@ -6,6 +6,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/tools"
 )
 
 type Headers struct {
@@ -24,6 +28,9 @@ type OandaClient struct {
 	transactionSubscriptions map[string]*transactionTypeLogic
 	mutex                    sync.Locker
 	stopPriceSubscripton     chan bool
+	backoff                  *tools.Backoff
+	reconnectHandler         func()
+	logger                   gotrader.Logger
 }
 
 func NewClient(token string, live bool) *OandaClient {
@@ -56,11 +63,23 @@ func NewClient(token string, live bool) *OandaClient {
 		streamClient:             http.Client{},
 		transactionSubscriptions: make(map[string]*transactionTypeLogic),
 		mutex: &sync.Mutex{},
+		backoff:                  tools.NewBackoff(100*time.Millisecond, 30*time.Second, 10),
+		logger:                   gotrader.DefaultLogger(),
 	}
 
 	return connection
 }
 
+// SetLogger sets the logger of the stream errors and reconnections.
+func (c *OandaClient) SetLogger(logger gotrader.Logger) {
+	c.logger = logger
+}
+
+// OnReconnect sets the handler called every time a stream subscription is recovered.
+func (c *OandaClient) OnReconnect(handler func()) {
+	c.reconnectHandler = handler
+}
+
 func (c *OandaClient) get(endpoint string) ([]byte, error) {
 
 	url := c.restURL + endpoint
@@ -74,11 +93,11 @@ func (c *OandaClient) get(endpoint string) ([]byte, error) {
 	return c.makeRequest(req)
 }
 
-func (c *OandaClient) put(endpoint string) ([]byte, error) {
+func (c *OandaClient) put(endpoint string, data []byte) ([]byte, error) {
 
 	url := c.restURL + endpoint
 
-	req, err := http.NewRequest(http.MethodPut, url, nil)
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(data))
 
 	if err != nil {
 		return nil, err
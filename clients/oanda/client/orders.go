@@ -17,8 +17,36 @@ type Order struct {
 	TimeInForce      string            `json:"timeInForce"`
 	Type             string            `json:"type"`
 	PositionFill     string            `json:"positionFill,omitempty"`
-	Price            float64           `json:"price,omitempty"`
+	Price            float64           `json:"price,string,omitempty"`
+	GtdTime          *time.Time        `json:"gtdTime,omitempty"`
+	StopLossOnFill   *PriceDetails     `json:"stopLossOnFill,omitempty"`
+	GuaranteedStop   *PriceDetails     `json:"guaranteedStopLossOnFill,omitempty"`
+	TakeProfitOnFill *PriceDetails     `json:"takeProfitOnFill,omitempty"`
 	ClientExtensions *ClientExtensions `json:"tradeClientExtensions,omitempty"`
+	OrderExtensions  *ClientExtensions `json:"clientExtensions,omitempty"`
+}
+
+type PriceDetails struct {
+	Price float64 `json:"price,string"`
+}
+
+type PendingOrders struct {
+	Orders []PendingOrder `json:"orders"`
+}
+
+type PendingOrder struct {
+	ID               string        `json:"id"`
+	Type             string        `json:"type"`
+	State            string        `json:"state"`
+	Instrument       string        `json:"instrument"`
+	Units            int32         `json:"units,string"`
+	Price            float64       `json:"price,string"`
+	TimeInForce      string        `json:"timeInForce"`
+	GtdTime          time.Time     `json:"gtdTime"`
+	CreateTime       time.Time     `json:"createTime"`
+	StopLossOnFill   *PriceDetails `json:"stopLossOnFill"`
+	GuaranteedStop   *PriceDetails `json:"guaranteedStopLossOnFill"`
+	TakeProfitOnFill *PriceDetails `json:"takeProfitOnFill"`
 }
 
 type OrderRequest struct {
@@ -72,9 +100,10 @@ type OrderFillTransaction struct {
 }
 
 type TradeOpened struct {
-	TradeID string  `json:"tradeID"`
-	Units   int32   `json:"units,string"`
-	Price   float64 `json:"price,string"`
+	TradeID          string            `json:"tradeID"`
+	Units            int32             `json:"units,string"`
+	Price            float64           `json:"price,string"`
+	ClientExtensions *ClientExtensions `json:"clientExtensions"`
 }
 
 func (c *OandaClient) CreateMarketOrder(accountID, instrument, side string, units int32) (OrderResponse, error) {
@@ -117,3 +146,99 @@ func (c *OandaClient) CreateMarketOrder(accountID, instrument, side string, unit
 	return data, nil
 
 }
+
+func (c *OandaClient) CreateOrder(accountID string, order Order) (OrderResponse, error) {
+
+	body := OrderRequest{Order: order}
+
+	endpoint := "/accounts/" + accountID + "/orders"
+
+	jsonBody, err := json.Marshal(body)
+
+	if err != nil {
+		return OrderResponse{}, err
+	}
+
+	response, err := c.post(endpoint, jsonBody)
+
+	if err != nil {
+		return OrderResponse{}, err
+	}
+
+	data := OrderResponse{}
+	err = json.Unmarshal(response, &data)
+
+	if err != nil {
+		return OrderResponse{}, err
+	}
+
+	return data, nil
+}
+
+func (c *OandaClient) ReplaceOrder(accountID, orderID string, order Order) (OrderResponse, error) {
+
+	body := OrderRequest{Order: order}
+
+	endpoint := "/accounts/" + accountID + "/orders/" + orderID
+
+	jsonBody, err := json.Marshal(body)
+
+	if err != nil {
+		return OrderResponse{}, err
+	}
+
+	response, err := c.put(endpoint, jsonBody)
+
+	if err != nil {
+		return OrderResponse{}, err
+	}
+
+	data := OrderResponse{}
+	err = json.Unmarshal(response, &data)
+
+	if err != nil {
+		return OrderResponse{}, err
+	}
+
+	return data, nil
+}
+
+func (c *OandaClient) CancelOrder(accountID, orderID string) (CloseTradeResponse, error) {
+
+	endpoint := "/accounts/" + accountID + "/orders/" + orderID + "/cancel"
+
+	response, err := c.put(endpoint, nil)
+
+	if err != nil {
+		return CloseTradeResponse{}, err
+	}
+
+	data := CloseTradeResponse{}
+	err = json.Unmarshal(response, &data)
+
+	if err != nil {
+		return CloseTradeResponse{}, err
+	}
+
+	return data, nil
+}
+
+func (c *OandaClient) GetPendingOrders(accountID string) (PendingOrders, error) {
+
+	endpoint := "/accounts/" + accountID + "/pendingOrders"
+
+	response, err := c.get(endpoint)
+
+	if err != nil {
+		return PendingOrders{}, err
+	}
+
+	data := PendingOrders{}
+	err = json.Unmarshal(response, &data)
+
+	if err != nil {
+		return PendingOrders{}, err
+	}
+
+	return data, nil
+}
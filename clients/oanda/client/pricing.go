@@ -3,13 +3,12 @@ package oandacl
 import (
 	"bufio"
 	"encoding/json"
-	"math"
 	"net/url"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/sirupsen/logrus"
+	"github.com/luismcruz/gotrader"
 )
 
 type Pricings struct {
@@ -55,7 +54,7 @@ func (c *OandaClient) GetPrices(accountID string, instruments []string) (Pricing
 
 func (c *OandaClient) SubscribePrices(accountID string, instruments []string, handler PriceHandler) (*PriceSubscription, error) {
 
-	subscription := newPriceSubscrption(c.dial, handler, accountID)
+	subscription := newPriceSubscrption(c.dial, c.reconnect, handler, accountID, c.logger)
 	err := subscription.subscribe(instruments)
 
 	if err != nil {
@@ -72,20 +71,24 @@ type PriceSubscription struct {
 	handler              PriceHandler
 	mutex                *sync.Mutex
 	dial                 func(endpoint string) (*bufio.Reader, error)
+	reconnect            func(endpoint string) (*bufio.Reader, error)
 	accountID            string
 	activeSubscription   bool
+	logger               gotrader.Logger
 }
 
-func newPriceSubscrption(dial func(endpoint string) (*bufio.Reader, error),
-	handler PriceHandler, accountID string) *PriceSubscription {
+func newPriceSubscrption(dial, reconnect func(endpoint string) (*bufio.Reader, error),
+	handler PriceHandler, accountID string, logger gotrader.Logger) *PriceSubscription {
 
 	return &PriceSubscription{
 		priceSubscriptions:   make(map[string]bool),
 		stopPriceSubscripton: make(chan bool),
 		mutex:                &sync.Mutex{},
 		dial:                 dial,
+		reconnect:            reconnect,
 		accountID:            accountID,
 		handler:              handler,
+		logger:               logger,
 	}
 }
 
@@ -138,7 +141,7 @@ func (s *PriceSubscription) subscribePrices() error {
 
 				if err != nil {
 
-					logrus.Warn(err)
+					s.logger.Warn(err)
 
 					if reader, err = s.reconnect(endpoint); err != nil { // Did not recover subscription, break outer loop
 						break subLoop
@@ -155,7 +158,7 @@ func (s *PriceSubscription) subscribePrices() error {
 				err = json.Unmarshal(line, &data)
 
 				if err != nil {
-					logrus.Warn(err)
+					s.logger.Warn(err)
 					continue
 				}
 
@@ -170,25 +173,6 @@ func (s *PriceSubscription) subscribePrices() error {
 	return nil
 }
 
-func (s *PriceSubscription) reconnect(endpoint string) (reader *bufio.Reader, err error) {
-
-	for i := 0; i < 3; i++ { // Try reconnection 3 times with exponential backoff
-
-		logrus.Info("Trying to recover subscription...")
-
-		time.Sleep(time.Duration(math.Pow(2, float64(i))) * 100 * time.Millisecond)
-
-		reader, err = s.dial(endpoint)
-
-		if err == nil {
-			logrus.Info("Subscription recovered")
-			return
-		}
-	}
-
-	return
-}
-
 func (s *PriceSubscription) needsToDial(instruments []string) (subscribe bool) {
 
 	for _, inst := range instruments {
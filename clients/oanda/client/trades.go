@@ -20,6 +20,8 @@ type Trade struct {
 	RealizedPL   float64   `json:"realizedPL,string"`
 	State        string    `json:"state"`
 	UnrealizedPL float64   `json:"unrealizedPL,string"`
+
+	ClientExtensions *ClientExtensions `json:"clientExtensions"`
 }
 
 type CloseTradeResponse struct {
@@ -71,7 +73,7 @@ func (c *OandaClient) CloseTrade(accountID, tradeID string) (CloseTradeResponse,
 
 	endpoint := "/accounts/" + accountID + "/trades/" + tradeID + "/close"
 
-	response, err := c.put(endpoint)
+	response, err := c.put(endpoint, nil)
 
 	if err != nil {
 		return CloseTradeResponse{}, nil
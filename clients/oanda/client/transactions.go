@@ -3,10 +3,8 @@ package oandacl
 import (
 	"bufio"
 	"encoding/json"
-	"math"
 	"time"
 
-	"github.com/sirupsen/logrus"
 	"go.uber.org/atomic"
 )
 
@@ -29,6 +27,7 @@ type Transaction struct {
 	ID                 string               `json:"id"`
 	Instrument         string               `json:"instrument"`
 	OrderID            string               `json:"orderID"`
+	ClientOrderID      string               `json:"clientOrderID"`
 	Pl                 float64              `json:"pl,string"`
 	Price              float64              `json:"price,string"`
 	Reason             string               `json:"reason"`
@@ -180,7 +179,7 @@ func (c *OandaClient) subscribeTransactions(accountID string, handler Transactio
 
 			if err != nil {
 
-				logrus.Warn(err)
+				c.logger.Warn(err)
 
 				if reader, err = c.reconnect(endpoint); err != nil { // Did not recover subscription, break outer loop
 					break subLoop
@@ -193,7 +192,7 @@ func (c *OandaClient) subscribeTransactions(accountID string, handler Transactio
 			err = json.Unmarshal(line, data)
 
 			if err != nil {
-				logrus.Warn(err)
+				c.logger.Warn(err)
 				continue
 			}
 
@@ -210,17 +209,21 @@ func (c *OandaClient) subscribeTransactions(accountID string, handler Transactio
 
 func (c *OandaClient) reconnect(endpoint string) (reader *bufio.Reader, err error) {
 
-	for i := 0; i < 3; i++ { // Try reconnection 3 times with exponential backoff
+	err = c.backoff.Retry(func() error { // Try reconnection with exponential backoff
 
-		logrus.Debug("Trying to recover subscription...")
-
-		time.Sleep(time.Duration(math.Pow(2, float64(i))) * 100 * time.Millisecond)
+		c.logger.Debug("Trying to recover subscription...")
 
 		reader, err = c.dial(endpoint)
 
-		if err == nil {
-			logrus.Debug("Subscription recovered")
-			return
+		return err
+	})
+
+	if err == nil {
+
+		c.logger.Debug("Subscription recovered")
+
+		if c.reconnectHandler != nil {
+			c.reconnectHandler()
 		}
 	}
 
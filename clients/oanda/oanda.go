@@ -1,12 +1,15 @@
 package oanda
 
 import (
+	"errors"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/luismcruz/gotrader"
 
 	"github.com/luismcruz/gotrader/clients/oanda/client"
+	"github.com/luismcruz/gotrader/tools"
 )
 
 type oandaClientWrapper struct {
@@ -15,15 +18,17 @@ type oandaClientWrapper struct {
 	priceSubscription       *priceSubscription
 	transactionSubscription map[string]*transactionSubscription
 	mutex                   *sync.Mutex
+	limiter                 *tools.RateLimiter
 }
 
-// NewOandaClient is the oanda client wrapper constructor
+// NewOandaClient is the oanda client wrapper constructor, the returned client also implements gotrader.Broker
 func NewOandaClient(token string, live bool) gotrader.BrokerClient {
 	return &oandaClientWrapper{
 		client:                  oandacl.NewClient(token, live),
 		instrumentsDetails:      make(map[string]gotrader.InstrumentDetails),
 		transactionSubscription: make(map[string]*transactionSubscription),
 		mutex: &sync.Mutex{},
+		limiter:                 tools.NewRateLimiter(tools.Budget{Rate: 100, Burst: 100, Reserve: 10}), // oanda allows 100 requests per second
 	}
 }
 
@@ -86,6 +91,8 @@ func (c *oandaClientWrapper) GetAvailableInstruments(accountID string) ([]gotrad
 
 func (c *oandaClientWrapper) OpenMarketOrder(accountID, instrument string, units int32, side string) error {
 
+	c.limiter.Wait("orders", tools.Normal)
+
 	_, err := c.client.CreateMarketOrder(accountID, instrument, side, units)
 
 	if err != nil {
@@ -97,6 +104,8 @@ func (c *oandaClientWrapper) OpenMarketOrder(accountID, instrument string, units
 
 func (c *oandaClientWrapper) CloseTrade(accountID, id string) error {
 
+	c.limiter.Wait("orders", tools.RiskReducing)
+
 	_, err := c.client.CloseTrade(accountID, id)
 
 	if err != nil {
@@ -106,6 +115,149 @@ func (c *oandaClientWrapper) CloseTrade(accountID, id string) error {
 	return nil
 }
 
+func (c *oandaClientWrapper) SubmitOrder(accountID string, order *gotrader.Order) (string, error) {
+
+	c.limiter.Wait("orders", tools.Normal)
+
+	resp, err := c.client.CreateOrder(accountID, toOandaOrder(order))
+
+	if err != nil {
+		return "", err
+	}
+
+	if resp.OrderCreateTransaction == nil {
+		return "", errors.New("order was not created")
+	}
+
+	return resp.OrderCreateTransaction.ID, nil
+}
+
+func (c *oandaClientWrapper) ModifyOrder(accountID, orderID string, order *gotrader.Order) error {
+
+	c.limiter.Wait("orders", tools.Normal)
+
+	_, err := c.client.ReplaceOrder(accountID, orderID, toOandaOrder(order))
+
+	return err
+}
+
+func (c *oandaClientWrapper) CancelOrder(accountID, orderID string) error {
+
+	c.limiter.Wait("orders", tools.RiskReducing)
+
+	_, err := c.client.CancelOrder(accountID, orderID)
+
+	return err
+}
+
+func (c *oandaClientWrapper) GetPendingOrders(accountID string) ([]*gotrader.Order, error) {
+
+	resp, err := c.client.GetPendingOrders(accountID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*gotrader.Order, 0, len(resp.Orders))
+
+	for _, o := range resp.Orders {
+
+		order := &gotrader.Order{
+			ID:         o.ID,
+			Instrument: o.Instrument,
+			Side:       gotrader.Long,
+			Units:      o.Units,
+			Price:      o.Price,
+			Expiry:     o.GtdTime,
+			CreateTime: o.CreateTime,
+		}
+
+		switch o.Type {
+		case "LIMIT":
+			order.Type = gotrader.LimitOrder
+		case "STOP", "MARKET_IF_TOUCHED":
+			order.Type = gotrader.StopOrder
+		default: // trade dependent orders (stop loss, take profit, ...) are reported on the trade
+			continue
+		}
+
+		if order.Units < 0 {
+			order.Side = gotrader.Short
+			order.Units = -order.Units
+		}
+
+		if o.TimeInForce == "GTD" {
+			order.TimeInForce = gotrader.GoodTillDate
+		}
+
+		if o.StopLossOnFill != nil {
+			order.StopLoss = o.StopLossOnFill.Price
+		}
+
+		if o.GuaranteedStop != nil {
+			order.StopLoss = o.GuaranteedStop.Price
+			order.GuaranteedStop = true
+		}
+
+		if o.TakeProfitOnFill != nil {
+			order.TakeProfit = o.TakeProfitOnFill.Price
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+func toOandaOrder(order *gotrader.Order) oandacl.Order {
+
+	units := order.Units
+	if order.Side == gotrader.Short {
+		units = -units
+	}
+
+	o := oandacl.Order{
+		Units:        units,
+		Instrument:   order.Instrument,
+		Type:         order.Type.String(),
+		TimeInForce:  order.TimeInForce.String(),
+		PositionFill: "DEFAULT",
+	}
+
+	if order.Type != gotrader.MarketOrder {
+		o.Price = order.Price
+	} else if order.TimeInForce == gotrader.GoodTillCancelled { // market orders can't rest in the book
+		o.TimeInForce = gotrader.FillOrKill.String()
+	}
+
+	if order.TimeInForce == gotrader.GoodTillDate {
+		expiry := order.Expiry
+		o.GtdTime = &expiry
+	}
+
+	if order.StopLoss != 0 && order.GuaranteedStop {
+		o.GuaranteedStop = &oandacl.PriceDetails{Price: order.StopLoss}
+	} else if order.StopLoss != 0 {
+		o.StopLossOnFill = &oandacl.PriceDetails{Price: order.StopLoss}
+	}
+
+	if order.TakeProfit != 0 {
+		o.TakeProfitOnFill = &oandacl.PriceDetails{Price: order.TakeProfit}
+	}
+
+	if order.Tag != "" {
+		tag := order.Tag
+		o.ClientExtensions = &oandacl.ClientExtensions{Tag: &tag}
+	}
+
+	if order.ClientID != "" { // OANDA rejects the orders with the ID of an existing one
+		id := order.ClientID
+		o.OrderExtensions = &oandacl.ClientExtensions{ID: &id}
+	}
+
+	return o
+}
+
 func (c *oandaClientWrapper) GetOpenTrades(accountID string) ([]gotrader.TradeDetails, error) {
 
 	tradesResp, err := c.client.GetOpenTrades(accountID)
@@ -137,12 +289,78 @@ func (c *oandaClientWrapper) GetOpenTrades(accountID string) ([]gotrader.TradeDe
 			OpenTime:    tr.OpenTime,
 		}
 
+		if tr.ClientExtensions != nil && tr.ClientExtensions.Tag != nil {
+			response[i].Tag = *tr.ClientExtensions.Tag
+		}
+
 	}
 
 	return response, nil
 
 }
 
+// granularities are the candle granularities of oanda by timeframe.
+var granularities = map[time.Duration]string{
+	5 * time.Second:  "S5",
+	10 * time.Second: "S10",
+	15 * time.Second: "S15",
+	30 * time.Second: "S30",
+	time.Minute:      "M1",
+	2 * time.Minute:  "M2",
+	4 * time.Minute:  "M4",
+	5 * time.Minute:  "M5",
+	10 * time.Minute: "M10",
+	15 * time.Minute: "M15",
+	30 * time.Minute: "M30",
+	time.Hour:        "H1",
+	2 * time.Hour:    "H2",
+	3 * time.Hour:    "H3",
+	4 * time.Hour:    "H4",
+	6 * time.Hour:    "H6",
+	8 * time.Hour:    "H8",
+	12 * time.Hour:   "H12",
+	24 * time.Hour:   "D",
+}
+
+// GetCandles implements gotrader.CandleProvider, at most 5000 candles are returned by request.
+func (c *oandaClientWrapper) GetCandles(instrument string, timeframe time.Duration, from, to time.Time) ([]*gotrader.Candle, error) {
+
+	granularity, exist := granularities[timeframe]
+	if !exist {
+		return nil, errors.New("oanda has no candles of timeframe " + timeframe.String())
+	}
+
+	c.limiter.Wait("candles", tools.Normal)
+
+	resp, err := c.client.GetCandles(instrument, granularity, from, to)
+
+	if err != nil {
+		return nil, err
+	}
+
+	candles := make([]*gotrader.Candle, 0, len(resp.Candles))
+
+	for _, cd := range resp.Candles {
+
+		if !cd.Complete || !cd.Time.Before(to) {
+			continue
+		}
+
+		candles = append(candles, &gotrader.Candle{
+			Instrument: instrument,
+			Timeframe:  timeframe,
+			Time:       cd.Time,
+			Open:       cd.Mid.O,
+			High:       cd.Mid.H,
+			Low:        cd.Mid.L,
+			Close:      cd.Mid.C,
+			Ticks:      cd.Volume,
+		})
+	}
+
+	return candles, nil
+}
+
 func (c *oandaClientWrapper) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
 
 	instrumentsStrings := make([]string, len(instruments), len(instruments))
@@ -227,6 +445,15 @@ func (c *oandaClientWrapper) SubscribeFundsTransferNotifications(accountID strin
 	return nil
 }
 
+func (c *oandaClientWrapper) SubscribeReconnections(accountID string, callback gotrader.ReconnectHandler) error {
+
+	c.client.OnReconnect(func() {
+		callback(time.Now())
+	})
+
+	return nil
+}
+
 type priceSubscription struct {
 	handler gotrader.TickHandler
 }
@@ -300,14 +527,19 @@ func (t *transactionSubscription) transactionHandler(transaction *oandacl.Transa
 			}
 
 			orderFill = &gotrader.OrderFill{
-				TradeClose: false,
-				OrderID:    transaction.OrderID,
-				TradeID:    transaction.TradeOpened.TradeID,
-				Side:       side,
-				Instrument: t.insturmentDetails[transaction.Instrument],
-				Price:      transaction.TradeOpened.Price,
-				Units:      transaction.TradeOpened.Units,
-				Time:       transaction.Time,
+				TradeClose:    false,
+				OrderID:       transaction.OrderID,
+				ClientOrderID: transaction.ClientOrderID,
+				TradeID:       transaction.TradeOpened.TradeID,
+				Side:          side,
+				Instrument:    t.insturmentDetails[transaction.Instrument],
+				Price:         transaction.TradeOpened.Price,
+				Units:         transaction.TradeOpened.Units,
+				Time:          transaction.Time,
+			}
+
+			if ext := transaction.TradeOpened.ClientExtensions; ext != nil && ext.Tag != nil {
+				orderFill.Tag = *ext.Tag
 			}
 
 			t.orderFillCallback(orderFill)
@@ -0,0 +1,90 @@
+package paper
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LatencyModel returns the latency of an order of an instrument submitted at a time, from its submission to its
+// arrival at the venue: the order is filled against the first quote streamed after it, so the fast strategies see
+// the prices moving against them as they would live.
+type LatencyModel interface {
+	Latency(instrument string, t time.Time) time.Duration
+}
+
+// FixedLatency is a LatencyModel of a constant latency.
+type FixedLatency time.Duration
+
+// Latency implements LatencyModel.
+func (l FixedLatency) Latency(instrument string, t time.Time) time.Duration {
+	return time.Duration(l)
+}
+
+/*
+LogNormalLatency is a LatencyModel of latencies drawn from a log-normal distribution, the usual shape of network
+round trips: most orders arrive close to the Median and a few much later, the more the higher Sigma (the standard
+deviation of the logarithm of the latency). The latencies are replayed for the same Seed, it is safe for concurrent use.
+*/
+type LogNormalLatency struct {
+	Median time.Duration
+	Sigma  float64
+	Seed   int64
+
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+// Latency implements LatencyModel.
+func (l *LogNormalLatency) Latency(instrument string, t time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.rand == nil {
+		l.rand = rand.New(rand.NewSource(l.Seed))
+	}
+
+	return time.Duration(float64(l.Median) * math.Exp(l.Sigma*l.rand.NormFloat64()))
+}
+
+/*
+VenueLatency is a LatencyModel replaying the latencies measured on a venue, drawn at random from its Samples, or from
+the Hourly ones of the hour of day of the order (in Location, UTC when nil) when they were measured, since the venues
+slow down at their busy hours. The latencies are replayed for the same Seed, it is safe for concurrent use.
+*/
+type VenueLatency struct {
+	Samples  []time.Duration
+	Hourly   [24][]time.Duration
+	Location *time.Location
+	Seed     int64
+
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+// Latency implements LatencyModel, zero without samples.
+func (l *VenueLatency) Latency(instrument string, t time.Time) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	location := l.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	samples := l.Hourly[t.In(location).Hour()]
+	if len(samples) == 0 {
+		samples = l.Samples
+	}
+
+	if len(samples) == 0 {
+		return 0
+	}
+
+	if l.rand == nil {
+		l.rand = rand.New(rand.NewSource(l.Seed))
+	}
+
+	return samples[l.rand.Intn(len(samples))]
+}
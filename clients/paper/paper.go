@@ -0,0 +1,708 @@
+package paper
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"go.uber.org/atomic"
+)
+
+// Option represents a paper broker functional option
+type Option func(c *paperClient)
+
+// Balance is the functional option to define the initial balance of the paper account.
+func Balance(value float64) Option {
+	return func(c *paperClient) {
+		c.balance = value
+	}
+}
+
+// Currency is the functional option to define the home currency of the paper account.
+func Currency(ccy string) Option {
+	return func(c *paperClient) {
+		c.currency = ccy
+	}
+}
+
+// Leverage is the functional option to define the leverage of the paper account.
+func Leverage(leverage float64) Option {
+	return func(c *paperClient) {
+		c.leverage = leverage
+	}
+}
+
+// HedgeType is the functional option to define the hedge type reported by the paper account.
+func HedgeType(hedge gotrader.Hedge) Option {
+	return func(c *paperClient) {
+		c.hedge = hedge
+	}
+}
+
+// Slippage is the functional option to define the slippage model applied to market fills.
+func Slippage(model gotrader.SlippageModel) Option {
+	return func(c *paperClient) {
+		c.slippage = model
+	}
+}
+
+// Commission is the functional option to define the commission model charged on every fill.
+func Commission(model gotrader.CommissionModel) Option {
+	return func(c *paperClient) {
+		c.commission = model
+	}
+}
+
+// GuaranteedStopPremium is the functional option to define the premium charged on the fills of the orders with
+// a guaranteed stop loss, which closes exactly at its level.
+func GuaranteedStopPremium(model gotrader.CommissionModel) Option {
+	return func(c *paperClient) {
+		c.premium = model
+	}
+}
+
+// Markup is the functional option to mark up the streamed prices, the fills and the ticks of the session use the
+// marked up quotes.
+func Markup(markup gotrader.PriceMarkup) Option {
+	return func(c *paperClient) {
+		c.markup = &markup
+	}
+}
+
+// Latency is the functional option to delay the orders and the trade closes by the latency of a model, they reach
+// the venue on the first quote of their instrument after it, immediately by default. The latency is reported by the
+// fills.
+func Latency(model LatencyModel) Option {
+	return func(c *paperClient) {
+		c.latency = model
+	}
+}
+
+type paperTrade struct {
+	details    gotrader.TradeDetails
+	stopLoss   float64
+	guaranteed bool
+	takeProfit float64
+}
+
+// inFlight is an order or a trade close on its way to the venue.
+type inFlight struct {
+	arrival time.Time
+	latency time.Duration
+}
+
+type paperClient struct {
+	prices            gotrader.BrokerClient
+	balance           float64
+	currency          string
+	leverage          float64
+	hedge             gotrader.Hedge
+	slippage          gotrader.SlippageModel
+	commission        gotrader.CommissionModel
+	markup            *gotrader.PriceMarkup
+	premium           gotrader.CommissionModel
+	latency           LatencyModel
+	mutex             *sync.Mutex
+	counter           *atomic.Int64
+	instruments       map[string]gotrader.InstrumentDetails
+	quotes            map[string]*gotrader.Tick
+	trades            map[string]*paperTrade
+	orders            map[string]*gotrader.Order
+	clientOrders      map[string]string   // order IDs by client ID
+	inFlight          map[string]inFlight // by order ID, kept until the order is filled
+	closing           map[string]inFlight // by trade ID
+	orderFillCallback gotrader.OrderFillHandler
+}
+
+// NewPaperClient is the paper trading broker constructor. Prices, instruments and time are taken from the
+// wrapped client (usually a live one), while orders are filled locally against the streamed prices,
+// so a strategy can be paper traded without any change from live mode.
+func NewPaperClient(prices gotrader.BrokerClient, opts ...Option) gotrader.BrokerClient {
+
+	c := &paperClient{
+		prices:       prices,
+		balance:      100000,
+		currency:     "USD",
+		leverage:     1,
+		hedge:        gotrader.FullHedge,
+		slippage:     gotrader.FixedSlippage(0),
+		commission:   gotrader.PerUnitCommission(0),
+		mutex:        &sync.Mutex{},
+		counter:      atomic.NewInt64(0),
+		instruments:  make(map[string]gotrader.InstrumentDetails),
+		quotes:       make(map[string]*gotrader.Tick),
+		trades:       make(map[string]*paperTrade),
+		orders:       make(map[string]*gotrader.Order),
+		clientOrders: make(map[string]string),
+		inFlight:     make(map[string]inFlight),
+		closing:      make(map[string]inFlight),
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (c *paperClient) nextID() string {
+	return strconv.FormatInt(c.counter.Inc(), 10)
+}
+
+// immediate returns true if the order is filled or cancelled when it reaches the venue.
+func immediate(order *gotrader.Order) bool {
+	return order.Type == gotrader.MarketOrder || order.TimeInForce == gotrader.FillOrKill ||
+		order.TimeInForce == gotrader.ImmediateOrCancel
+}
+
+// delay returns the in flight state of a request of an instrument at a time, false without latency.
+func (c *paperClient) delay(instrument string, t time.Time) (inFlight, bool) {
+
+	if c.latency == nil {
+		return inFlight{}, false
+	}
+
+	latency := c.latency.Latency(instrument, t)
+	if latency <= 0 {
+		return inFlight{}, false
+	}
+
+	return inFlight{arrival: t.Add(latency), latency: latency}, true
+}
+
+// conversionRate returns the rate to convert an amount in ccy to the home currency, using the streamed quotes.
+func (c *paperClient) conversionRate(ccy string) float64 {
+
+	if ccy == c.currency {
+		return 1
+	}
+
+	for name, inst := range c.instruments {
+
+		q, exist := c.quotes[name]
+		if !exist {
+			continue
+		}
+
+		mid := (q.Bid + q.Ask) / 2
+
+		if inst.BaseCurrency == ccy && inst.QuoteCurrency == c.currency {
+			return mid
+		}
+
+		if inst.BaseCurrency == c.currency && inst.QuoteCurrency == ccy {
+			return 1 / mid
+		}
+	}
+
+	return 1
+}
+
+func (c *paperClient) unrealized() float64 {
+
+	profit := 0.0
+
+	for _, t := range c.trades {
+		if q, exist := c.quotes[t.details.Instrument.Name]; exist {
+			profit += c.profit(t, q)
+		}
+	}
+
+	return profit
+}
+
+func (c *paperClient) profit(t *paperTrade, q *gotrader.Tick) float64 {
+
+	price := q.Bid
+	diff := price - t.details.OpenPrice
+
+	if t.details.Side == gotrader.Short {
+		price = q.Ask
+		diff = t.details.OpenPrice - price
+	}
+
+	return diff * float64(t.details.Units) * c.conversionRate(t.details.Instrument.QuoteCurrency)
+}
+
+// fill opens a trade from an order at the current quote, must be called with the mutex locked.
+func (c *paperClient) fill(order *gotrader.Order, q *gotrader.Tick) *gotrader.OrderFill {
+
+	inst := c.instruments[order.Instrument]
+	slippage := c.slippage.Slippage(order.Instrument, order.Side, order.Units, q.Bid, q.Ask)
+
+	price := q.Ask + slippage
+	if order.Side == gotrader.Short {
+		price = q.Bid - slippage
+	}
+
+	if order.Type == gotrader.LimitOrder { // limit orders never fill worse than their price
+		if order.Side == gotrader.Long && price > order.Price || order.Side == gotrader.Short && price < order.Price {
+			price = order.Price
+		}
+	}
+
+	commission := c.commission.Commission(order.Instrument, order.Units, price)
+
+	guaranteed := order.GuaranteedStop && order.StopLoss != 0
+	if guaranteed && c.premium != nil {
+		commission += c.premium.Commission(order.Instrument, order.Units, price)
+	}
+
+	c.balance -= commission
+
+	trade := &paperTrade{
+		details: gotrader.TradeDetails{
+			ID:          c.nextID(),
+			Instrument:  inst,
+			Side:        order.Side,
+			Units:       order.Units,
+			OpenPrice:   price,
+			ChargedFees: -commission,
+			OpenTime:    q.Time,
+			Tag:         order.Tag,
+		},
+		stopLoss:   order.StopLoss,
+		guaranteed: guaranteed,
+		takeProfit: order.TakeProfit,
+	}
+	c.trades[trade.details.ID] = trade
+
+	return &gotrader.OrderFill{
+		OrderID:       order.ID,
+		ClientOrderID: order.ClientID,
+		TradeID:       trade.details.ID,
+		Side:          order.Side,
+		Instrument:    inst,
+		Price:         price,
+		Units:         order.Units,
+		ChargedFees:   -commission,
+		Time:          q.Time,
+		Tag:           order.Tag,
+	}
+}
+
+// close closes a trade at the current quote, must be called with the mutex locked.
+func (c *paperClient) close(t *paperTrade, q *gotrader.Tick) *gotrader.OrderFill {
+
+	slippage := c.slippage.Slippage(t.details.Instrument.Name, t.details.Side, t.details.Units, q.Bid, q.Ask)
+
+	price := q.Bid - slippage
+	if t.details.Side == gotrader.Short {
+		price = q.Ask + slippage
+	}
+
+	return c.closeAt(t, price, q.Time)
+}
+
+// closeAt closes a trade at a price, without slippage, must be called with the mutex locked.
+func (c *paperClient) closeAt(t *paperTrade, price float64, closeTime time.Time) *gotrader.OrderFill {
+
+	profit := c.profit(t, &gotrader.Tick{Instrument: t.details.Instrument.Name, Bid: price, Ask: price})
+
+	commission := c.commission.Commission(t.details.Instrument.Name, t.details.Units, price)
+	c.balance += profit - commission
+
+	delete(c.trades, t.details.ID)
+
+	return &gotrader.OrderFill{
+		TradeClose:  true,
+		OrderID:     c.nextID(),
+		TradeID:     t.details.ID,
+		Side:        t.details.Side,
+		Instrument:  t.details.Instrument,
+		Price:       price,
+		Units:       t.details.Units,
+		Profit:      profit,
+		ChargedFees: -commission,
+		Time:        closeTime,
+		Tag:         t.details.Tag,
+	}
+}
+
+// process fills the triggered pending orders, expires the good till date ones and closes the trades
+// that reached their stop loss or take profit.
+func (c *paperClient) process(q *gotrader.Tick) []*gotrader.OrderFill {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	fills := make([]*gotrader.OrderFill, 0)
+
+	for id, order := range c.orders {
+
+		if order.TimeInForce == gotrader.GoodTillDate && !order.Expiry.IsZero() && order.Expiry.Before(q.Time) {
+			delete(c.orders, id)
+			delete(c.inFlight, id)
+			fills = append(fills, c.failed(order, "ORDER_EXPIRED", q.Time))
+			continue
+		}
+
+		flight := c.inFlight[id]
+		if order.Instrument != q.Instrument || flight.arrival.After(q.Time) {
+			continue
+		}
+
+		var fill *gotrader.OrderFill
+
+		switch {
+		case order.Triggered(q.Bid, q.Ask):
+			fill = c.fill(order, q)
+		case immediate(order):
+			fill = c.failed(order, "ORDER_CANCELLED", q.Time)
+		default:
+			continue
+		}
+
+		delete(c.orders, id)
+		delete(c.inFlight, id)
+
+		fill.Latency = flight.latency
+		fills = append(fills, fill)
+	}
+
+	for _, t := range c.trades {
+
+		if t.details.Instrument.Name != q.Instrument {
+			continue
+		}
+
+		price := q.Bid
+		if t.details.Side == gotrader.Short {
+			price = q.Ask
+		}
+
+		stopped, hit := false, false
+
+		if t.stopLoss != 0 {
+			stopped = t.details.Side == gotrader.Long && price <= t.stopLoss || t.details.Side == gotrader.Short && price >= t.stopLoss
+			hit = stopped
+		}
+
+		if t.takeProfit != 0 && !hit {
+			hit = t.details.Side == gotrader.Long && price >= t.takeProfit || t.details.Side == gotrader.Short && price <= t.takeProfit
+		}
+
+		flight, closing := c.closing[t.details.ID]
+
+		switch {
+		case stopped && t.guaranteed: // filled at the level, regardless of the gaps
+			fills = append(fills, c.closeAt(t, t.stopLoss, q.Time))
+		case hit:
+			fills = append(fills, c.close(t, q))
+		case closing && !flight.arrival.After(q.Time):
+			fill := c.close(t, q)
+			fill.Latency = flight.latency
+			fills = append(fills, fill)
+		default:
+			continue
+		}
+
+		delete(c.closing, t.details.ID)
+	}
+
+	return fills
+}
+
+// failed returns the failed fill of an order, must be called with the mutex locked.
+func (c *paperClient) failed(order *gotrader.Order, reason string, t time.Time) *gotrader.OrderFill {
+	return &gotrader.OrderFill{
+		Error:         reason,
+		OrderID:       order.ID,
+		ClientOrderID: order.ClientID,
+		Side:          order.Side,
+		Instrument:    c.instruments[order.Instrument],
+		Units:         order.Units,
+		Time:          t,
+		Tag:           order.Tag,
+	}
+}
+
+func (c *paperClient) notify(fills ...*gotrader.OrderFill) {
+
+	if c.orderFillCallback == nil {
+		return
+	}
+
+	for _, f := range fills {
+		c.orderFillCallback(f)
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+func (c *paperClient) GetAccountStatus(accountID string) (gotrader.AccountStatus, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	unrealized := c.unrealized()
+
+	return gotrader.AccountStatus{
+		Currency:              c.currency,
+		Hedge:                 c.hedge,
+		Equity:                c.balance + unrealized,
+		Balance:               c.balance,
+		UnrealizedGrossProfit: unrealized,
+		Leverage:              c.leverage,
+	}, nil
+}
+
+func (c *paperClient) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+
+	instruments, err := c.prices.GetAvailableInstruments(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, inst := range instruments {
+		c.instruments[inst.Name] = inst
+	}
+
+	return instruments, nil
+}
+
+func (c *paperClient) OpenMarketOrder(accountID, instrument string, units int32, side string) error {
+
+	s := gotrader.Long
+	if strings.EqualFold(side, gotrader.Short.String()) {
+		s = gotrader.Short
+	}
+
+	_, err := c.SubmitOrder(accountID, &gotrader.Order{
+		Type:       gotrader.MarketOrder,
+		Instrument: instrument,
+		Side:       s,
+		Units:      units,
+	})
+
+	return err
+}
+
+func (c *paperClient) CloseTrade(accountID, id string) error {
+
+	c.mutex.Lock()
+
+	trade, exist := c.trades[id]
+	if !exist {
+		c.mutex.Unlock()
+		return errors.New("trade " + id + " does not exist")
+	}
+
+	q, exist := c.quotes[trade.details.Instrument.Name]
+	if !exist {
+		c.mutex.Unlock()
+		return errors.New("no price available for " + trade.details.Instrument.Name)
+	}
+
+	if _, closing := c.closing[id]; closing {
+		c.mutex.Unlock()
+		return nil
+	}
+
+	if flight, delayed := c.delay(trade.details.Instrument.Name, q.Time); delayed {
+		c.closing[id] = flight
+		c.mutex.Unlock()
+		return nil
+	}
+
+	fill := c.close(trade, q)
+	c.mutex.Unlock()
+
+	c.notify(fill)
+
+	return nil
+}
+
+func (c *paperClient) GetOpenTrades(accountID string) ([]gotrader.TradeDetails, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	trades := make([]gotrader.TradeDetails, 0, len(c.trades))
+	for _, t := range c.trades {
+		trades = append(trades, t.details)
+	}
+
+	return trades, nil
+}
+
+func (c *paperClient) SubmitOrder(accountID string, order *gotrader.Order) (string, error) {
+
+	if order.Units <= 0 {
+		return "", errors.New("order units must be positive")
+	}
+
+	c.mutex.Lock()
+
+	if _, exist := c.instruments[order.Instrument]; !exist {
+		c.mutex.Unlock()
+		return "", errors.New("instrument " + order.Instrument + " is not available")
+	}
+
+	if id, exist := c.clientOrders[order.ClientID]; exist && order.ClientID != "" { // a retry of the same order
+		c.mutex.Unlock()
+		return id, nil
+	}
+
+	q, hasQuote := c.quotes[order.Instrument]
+
+	o := *order
+	o.ID = c.nextID()
+	o.CreateTime = time.Now()
+	if hasQuote {
+		o.CreateTime = q.Time
+	}
+
+	if flight, delayed := c.delay(o.Instrument, o.CreateTime); delayed {
+
+		if immediate(&o) && !hasQuote {
+			c.mutex.Unlock()
+			return "", errors.New("order can't be filled immediately")
+		}
+
+		c.inFlight[o.ID] = flight
+
+	} else if immediate(&o) {
+
+		if !hasQuote || !o.Triggered(q.Bid, q.Ask) {
+			c.mutex.Unlock()
+			return "", errors.New("order can't be filled immediately")
+		}
+
+		fill := c.fill(&o, q)
+		c.remember(&o)
+		c.mutex.Unlock()
+
+		c.notify(fill)
+
+		return o.ID, nil
+	}
+
+	c.orders[o.ID] = &o
+	c.remember(&o)
+	c.mutex.Unlock()
+
+	return o.ID, nil
+}
+
+// remember records the ID of an accepted order by its client ID, must be called with the mutex locked.
+func (c *paperClient) remember(order *gotrader.Order) {
+	if order.ClientID != "" {
+		c.clientOrders[order.ClientID] = order.ID
+	}
+}
+
+func (c *paperClient) ModifyOrder(accountID, orderID string, order *gotrader.Order) error {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	pending, exist := c.orders[orderID]
+	if !exist || immediate(pending) {
+		return errors.New("order " + orderID + " does not exist")
+	}
+
+	if order.Units <= 0 {
+		return errors.New("order units must be positive")
+	}
+
+	pending.Units = order.Units
+	pending.Price = order.Price
+	pending.StopLoss = order.StopLoss
+	pending.GuaranteedStop = order.GuaranteedStop
+	pending.TakeProfit = order.TakeProfit
+	pending.TimeInForce = order.TimeInForce
+	pending.Expiry = order.Expiry
+
+	return nil
+}
+
+func (c *paperClient) CancelOrder(accountID, orderID string) error {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if pending, exist := c.orders[orderID]; !exist || immediate(pending) {
+		return errors.New("order " + orderID + " does not exist")
+	}
+
+	delete(c.orders, orderID)
+	delete(c.inFlight, orderID)
+
+	return nil
+}
+
+func (c *paperClient) GetPendingOrders(accountID string) ([]*gotrader.Order, error) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	orders := make([]*gotrader.Order, 0, len(c.orders))
+	for _, o := range c.orders {
+		if immediate(o) { // in flight
+			continue
+		}
+		order := *o
+		orders = append(orders, &order)
+	}
+
+	return orders, nil
+}
+
+func (c *paperClient) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+
+	pipLocations := make(map[string]int, len(instruments))
+	for _, inst := range instruments {
+		pipLocations[inst.Name] = inst.PipLocation
+	}
+
+	return c.prices.SubscribePrices(accountID, instruments, func(tick *gotrader.Tick) {
+
+		if tick == nil { // end of a historical stream, there is no end of session in live mode
+			return
+		}
+
+		if c.markup != nil {
+			c.markup.Apply(tick, pipLocations[tick.Instrument])
+		}
+
+		c.mutex.Lock()
+		c.quotes[tick.Instrument] = tick
+		c.mutex.Unlock()
+
+		c.notify(c.process(tick)...)
+
+		callback(tick)
+	})
+}
+
+func (c *paperClient) SubscribeOrderFillNotifications(accountID string, orderFillCallback gotrader.OrderFillHandler) error {
+	c.orderFillCallback = orderFillCallback
+	return nil
+}
+
+// SubscribeSwapChargeNotifications is a no-op, financing is not simulated.
+func (c *paperClient) SubscribeSwapChargeNotifications(accountID string, swapChargeCallback gotrader.SwapChargeHandler) error {
+	return nil
+}
+
+// SubscribeFundsTransferNotifications is a no-op.
+func (c *paperClient) SubscribeFundsTransferNotifications(accountID string, fundsTransferCallback gotrader.FundsTransferHandler) error {
+	return nil
+}
@@ -0,0 +1,114 @@
+package paper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// stream is the prices client of the tests, its ticks are sent by the test.
+type stream struct {
+	gotrader.BrokerClient
+	callback gotrader.TickHandler
+}
+
+func (s *stream) GetAvailableInstruments(string) ([]gotrader.InstrumentDetails, error) {
+	return []gotrader.InstrumentDetails{{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD",
+		Leverage: 30, PipLocation: -4}}, nil
+}
+
+func (s *stream) SubscribePrices(_ string, _ []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+	s.callback = callback
+	return nil
+}
+
+func TestLatency(t *testing.T) {
+
+	start := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	prices := &stream{}
+	client := NewPaperClient(prices, Currency("EUR"), Latency(FixedLatency(25*time.Millisecond))).(*paperClient)
+
+	fills := make([]*gotrader.OrderFill, 0)
+	client.SubscribeOrderFillNotifications("", func(fill *gotrader.OrderFill) { fills = append(fills, fill) })
+
+	instruments, _ := client.GetAvailableInstruments("")
+	client.SubscribePrices("", instruments, func(*gotrader.Tick) {})
+
+	quote := func(ms int, bid float64) {
+		prices.callback(&gotrader.Tick{Instrument: "EUR_USD", Time: start.Add(time.Duration(ms) * time.Millisecond),
+			Bid: bid, Ask: bid + 0.0002})
+	}
+
+	quote(0, 1.1)
+
+	if err := client.OpenMarketOrder("", "EUR_USD", 1000, "long"); err != nil {
+		t.Fatal(err)
+	}
+
+	if orders, _ := client.GetPendingOrders(""); len(fills) != 0 || len(orders) != 0 {
+		t.Fatalf("expected the order in flight, got the fills %+v and the orders %+v", fills, orders)
+	}
+
+	quote(10, 1.1001)
+	quote(30, 1.1003)
+
+	if len(fills) != 1 || fills[0].Price != 1.1005 || fills[0].Latency != 25*time.Millisecond {
+		t.Fatalf("expected the fill at the ask of the first quote after the latency, got %+v", fills)
+	}
+
+	if err := client.CloseTrade("", fills[0].TradeID); err != nil {
+		t.Fatal(err)
+	}
+
+	quote(40, 1.1002)
+	quote(60, 1.1001)
+
+	if len(fills) != 2 || !fills[1].TradeClose || fills[1].Price != 1.1001 ||
+		fills[1].Time.Sub(start) != 60*time.Millisecond {
+		t.Fatalf("expected the close at the bid of the first quote after the latency, got %+v", fills[1:])
+	}
+
+	// a limit order rests on the venue once it arrives
+	if _, err := client.SubmitOrder("", &gotrader.Order{Type: gotrader.LimitOrder, Instrument: "EUR_USD",
+		Side: gotrader.Long, Units: 1000, Price: 1.1}); err != nil {
+		t.Fatal(err)
+	}
+
+	quote(70, 1.0990)
+	quote(90, 1.0990)
+
+	if len(fills) != 3 || fills[2].Price != 1.0992 || fills[2].Latency != 25*time.Millisecond {
+		t.Fatalf("expected the limit order filled once arrived, got %+v", fills[2:])
+	}
+}
+
+func TestLatencyModels(t *testing.T) {
+
+	at := time.Date(2024, 1, 2, 14, 30, 0, 0, time.UTC)
+
+	a := &LogNormalLatency{Median: 40 * time.Millisecond, Sigma: 0.5, Seed: 7}
+	b := &LogNormalLatency{Median: 40 * time.Millisecond, Sigma: 0.5, Seed: 7}
+
+	for i := 0; i < 10; i++ {
+		if la, lb := a.Latency("EUR_USD", at), b.Latency("EUR_USD", at); la != lb || la <= 0 {
+			t.Fatalf("expected the same positive latencies for a seed, got %v and %v", la, lb)
+		}
+	}
+
+	if l := (&LogNormalLatency{Median: 40 * time.Millisecond}).Latency("EUR_USD", at); l != 40*time.Millisecond {
+		t.Errorf("expected the median without sigma, got %v", l)
+	}
+
+	venue := &VenueLatency{Samples: []time.Duration{time.Millisecond}}
+	venue.Hourly[14] = []time.Duration{50 * time.Millisecond, 60 * time.Millisecond}
+
+	if l := venue.Latency("EUR_USD", at); l != 50*time.Millisecond && l != 60*time.Millisecond {
+		t.Errorf("expected a sample of the hour, got %v", l)
+	}
+
+	if l := venue.Latency("EUR_USD", at.Add(time.Hour)); l != time.Millisecond {
+		t.Errorf("expected a sample of the venue outside the measured hours, got %v", l)
+	}
+}
@@ -0,0 +1,132 @@
+package gotrader
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+Clock is the time source of a session: the creation time of the orders, the expiry of the pending orders, the
+trade closes and the events not timed by the broker are taken from it instead of time.Now, so the time can be
+controlled by the backtests and the tests.
+
+Live sessions use the WallClock by default, backtests a SimulatedClock set to the time of every tick.
+*/
+type Clock interface {
+	Now() time.Time
+}
+
+// TimerClock is implemented by the clocks firing their own timers, so the engine schedules its periodic work on the
+// session time. The engine times the other clocks with the wall clock.
+type TimerClock interface {
+	Clock
+	After(d time.Duration) <-chan time.Time // receives the time of the clock once d elapsed on it
+}
+
+type wallClock struct{}
+
+// Now implements Clock.
+func (wallClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements TimerClock.
+func (wallClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// WallClock returns the system clock, its times carry the monotonic clock reading so the durations measured
+// between them are not affected by the wall clock adjustments.
+func WallClock() Clock {
+	return wallClock{}
+}
+
+// SimulatedClock is a Clock that only moves when it is set or advanced, it is safe for concurrent use.
+type SimulatedClock struct {
+	mutex  *sync.RWMutex
+	now    time.Time
+	timers []*simulatedTimer // not fired yet
+}
+
+// simulatedTimer is a timer of a SimulatedClock, fired when the clock reaches its time.
+type simulatedTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// NewSimulatedClock is the SimulatedClock constructor, the clock starts at t.
+func NewSimulatedClock(t time.Time) *SimulatedClock {
+	return &SimulatedClock{
+		mutex: &sync.RWMutex{},
+		now:   t,
+	}
+}
+
+// Now implements Clock.
+func (c *SimulatedClock) Now() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.now
+}
+
+// Set moves the clock to t, which may be before its current time.
+func (c *SimulatedClock) Set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = t
+	c.fire()
+}
+
+// Advance moves the clock forward by d.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// After implements TimerClock, the channel receives the time of the clock when it is set or advanced past now + d.
+func (c *SimulatedClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	timer := &simulatedTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	c.fire()
+
+	return timer.c
+}
+
+// fire fires the timers due at the time of the clock, with the mutex held.
+func (c *SimulatedClock) fire() {
+
+	pending := c.timers[:0]
+
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- c.now
+	}
+
+	for i := len(pending); i < len(c.timers); i++ {
+		c.timers[i] = nil
+	}
+
+	c.timers = pending
+}
+
+// after returns a channel receiving the time once d elapsed on the clock, timed with the wall clock when the clock
+// is not a TimerClock.
+func after(clock Clock, d time.Duration) <-chan time.Time {
+
+	if timer, isTimer := clock.(TimerClock); isTimer {
+		return timer.After(d)
+	}
+
+	return time.After(d)
+}
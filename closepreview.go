@@ -0,0 +1,85 @@
+package gotrader
+
+import (
+	"fmt"
+)
+
+// ClosePreview is the outcome of a hypothetical close of a trade, see Account.PreviewClose.
+type ClosePreview struct {
+	TradeID             string
+	Instrument          string
+	Side                Side
+	Units               int32   // closed, the units of the trade left open are Trade.Units minus Units
+	ClosePrice          float64 // the bid for the longs, the ask for the shorts
+	QuoteConversionRate float64 // of the quote currency to the home currency, applied to the price difference
+	NetProfit           float64 // of the price difference, in home currency
+	Fees                float64 // charged to the units closed, pro rata of the charged fees of the trade
+	RealizedProfit      float64 // credited to the balance, net profit plus fees
+	SpreadCost          float64 // the half spread paid at the close, already in the close price
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+/*
+PreviewClose returns the profit the close of the units of an open trade would realize at the current bid or ask,
+without closing it, for the "close now" values of the strategies and the user interfaces. All the units of the
+trade are previewed when units is zero; the fees already charged, the financing and the commissions at the open,
+are counted pro rata of the units closed, as the close doesn't charge any.
+*/
+func (a *Account) PreviewClose(tradeID string, units int32) (ClosePreview, error) {
+
+	preview := ClosePreview{TradeID: tradeID, Units: units}
+
+	var inst *Instrument
+	var trade *Trade
+
+	for _, instrument := range a.instruments {
+		if t, exist := instrument.trades.Get(tradeID); exist {
+			inst, trade = instrument, t
+			break
+		}
+	}
+
+	if trade == nil {
+		return preview, fmt.Errorf("%s: %w", tradeID, ErrTradeNotFound)
+	}
+
+	inst.lock.RLock()
+	defer inst.lock.RUnlock()
+
+	if units == 0 {
+		units = trade.units
+	}
+
+	if units < 0 || units > trade.units {
+		return preview, fmt.Errorf("%s: %w", tradeID, ErrInvalidQuantity)
+	}
+
+	price := inst.bid.Load()
+	if trade.side == Short {
+		price = inst.ask.Load()
+	}
+
+	rate := trade.ccyConversion.QuoteConversionRate.Load()
+	fraction := NewDecimal(float64(units)).Div(NewDecimal(float64(trade.units)))
+
+	net := NewDecimal(price).Sub(NewDecimal(trade.openPrice)).MulInt(int64(units) * int64(trade.sideSign)).MulFloat(rate)
+	fees := trade.chargedFees.Load().Mul(fraction)
+	_, spreadCost := inst.halfSpreadCost(trade)
+
+	preview.Instrument = inst.name
+	preview.Side = trade.side
+	preview.Units = units
+	preview.ClosePrice = price
+	preview.QuoteConversionRate = rate
+	preview.NetProfit = net.Float64()
+	preview.Fees = fees.Float64()
+	preview.RealizedProfit = net.Add(fees).Float64()
+	preview.SpreadCost = spreadCost.Mul(fraction).Float64()
+
+	return preview, nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/config"
+	"github.com/luismcruz/gotrader/report"
+	"github.com/luismcruz/gotrader/rules"
+	"github.com/luismcruz/gotrader/runner"
+)
+
+// parseDate parses a flag date, as RFC 3339 or 2006-01-02 in UTC.
+func parseDate(value string) (time.Time, error) {
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse("2006-01-02", value)
+}
+
+// strategies returns the strategies selected, every strategy of the configuration if none is.
+func strategies(cfg *config.Config, selected string) ([]string, error) {
+
+	if selected == "" {
+
+		names := make([]string, 0, len(cfg.Strategies))
+		for name := range cfg.Strategies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if len(names) == 0 {
+			return nil, errors.New("no strategies in the configuration")
+		}
+
+		return names, nil
+	}
+
+	names := strings.Split(selected, ",")
+	for _, name := range names {
+		if _, exist := cfg.Strategies[name]; !exist {
+			return nil, errors.New("unknown strategy " + name)
+		}
+	}
+
+	return names, nil
+}
+
+// writeFile creates the file at path and writes it with write.
+func writeFile(path string, write func(f *os.File) error) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return f.Close()
+}
+
+// artifacts writes the report and the transactions of the account to the output directory.
+func artifacts(account *gotrader.Account, r *report.Report, out string) error {
+
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+
+	err := writeFile(filepath.Join(out, "report.json"), func(f *os.File) error { return r.WriteJSON(f) })
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(filepath.Join(out, "report.html"), func(f *os.File) error { return r.WriteHTML(f) })
+	if err != nil {
+		return err
+	}
+
+	return writeFile(filepath.Join(out, "transactions.csv"), func(f *os.File) error {
+		return report.NewExporter().WriteCSV(f, account.Ledger().Transactions())
+	})
+}
+
+func backtest(args []string) error {
+
+	flags := flag.NewFlagSet("backtest", flag.ExitOnError)
+	path := flags.String("config", "", "configuration file, required")
+	data := flags.String("data", "", "directory of the CSV tick files, replaces the broker of the configuration")
+	from := flags.String("from", "", "start of the backtest, as 2006-01-02 or RFC 3339, replaces the broker one")
+	to := flags.String("to", "", "end of the backtest, excluded, replaces the broker one")
+	selected := flags.String("strategy", "", "comma separated strategies of the configuration, every one by default")
+	plugin := flags.String("plugin", "", "plugin of the strategy, replaces the configuration one of a single strategy")
+	out := flags.String("out", "report", "directory of the report artifacts")
+
+	flags.Parse(args)
+
+	if *path == "" {
+		flags.Usage()
+		return errors.New("no configuration file")
+	}
+
+	cfg, err := config.Load(*path)
+	if err != nil {
+		return err
+	}
+
+	if *data != "" {
+		cfg.Broker.Type, cfg.Broker.Data = "csv", *data
+	}
+
+	for _, period := range []struct {
+		value string
+		time  *time.Time
+	}{{*from, &cfg.Broker.Start}, {*to, &cfg.Broker.End}} {
+
+		if period.value == "" {
+			continue
+		}
+
+		if *period.time, err = parseDate(period.value); err != nil {
+			return err
+		}
+	}
+
+	if !cfg.Backtest() {
+		return errors.New(cfg.Broker.Type + " is not a backtest broker, use -data to replay tick files")
+	}
+
+	names, err := strategies(cfg, *selected)
+	if err != nil {
+		return err
+	}
+
+	if *plugin != "" && len(names) > 1 {
+		return errors.New("a plugin requires a single strategy")
+	}
+
+	logger := gotrader.DefaultLogger()
+	r := runner.New(logger)
+
+	for _, name := range names {
+
+		file := cfg.Strategies[name].Plugin
+		if *plugin != "" {
+			file = *plugin
+		}
+
+		if conditions := cfg.Strategies[name].Rules; file == "" && conditions != nil {
+
+			strategy, err := rules.Compile(*conditions)
+			if err != nil {
+				return fmt.Errorf("strategy %s: %w", name, err)
+			}
+
+			if err := r.Add(name, strategy, cfg.StrategyOptions(name)...); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if file == "" {
+			return errors.New("strategy " + name + " has no plugin or rules")
+		}
+
+		if err := r.AddPlugin(name, file, cfg.StrategyOptions(name)...); err != nil {
+			return fmt.Errorf("strategy %s: %w", name, err)
+		}
+	}
+
+	client, err := cfg.Client()
+	if err != nil {
+		return err
+	}
+
+	session := gotrader.NewTradingSession(cfg.SessionOptions()...).SetClient(client).SetStrategy(r).Backtest()
+
+	started := time.Now()
+	if err := session.Start(); err != nil {
+		return err
+	}
+
+	if replay, ok := client.(interface{ Err() error }); ok && replay.Err() != nil {
+		return replay.Err()
+	}
+
+	for _, name := range names {
+		if err := r.Err(name); err != nil {
+			logger.Errorf("strategy %s stopped: %v", name, err)
+		}
+	}
+
+	rep := report.New(session.Account())
+	rep.Seed = cfg.Broker.Seed
+
+	if err := artifacts(session.Account(), rep, *out); err != nil {
+		return err
+	}
+
+	s := rep.Summary
+	fmt.Printf("%d trades, net profit %.2f %s (%.2f%%), max drawdown %.2f%%, sharpe %.2f, in %v\n", s.Trades,
+		s.NetProfit, rep.HomeCurrency, s.Return*100, s.MaxDrawdown*100, s.Sharpe, time.Since(started).Round(time.Millisecond))
+	fmt.Println("report written to " + *out)
+
+	return nil
+}
@@ -0,0 +1,54 @@
+/*
+Command gotrader runs the gotrader sessions of a configuration file (see the config package) without writing a
+main for each experiment.
+
+Usage:
+
+	gotrader backtest -config session.yaml [-data dir] [-from date] [-to date] [-strategy names]
+		[-plugin path] [-out dir]
+
+The backtest command runs the strategies of the configuration, loaded from their plugins (see
+runner.LoadPlugin) or compiled from their rules (see the rules package), on the backtest broker of the
+configuration, or on the CSV tick files of a data directory (see the csvdata package). It writes the report
+artifacts to the output directory: report.json, report.html and the transactions as transactions.csv.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const usage = `usage: gotrader <command> [flags]
+
+commands:
+  backtest   runs a backtest of a configuration and writes its report
+
+run gotrader <command> -h for the flags of a command
+`
+
+func main() {
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+
+	switch os.Args[1] {
+	case "backtest":
+		err = backtest(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %s\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gotrader "+os.Args[1]+":", err)
+		os.Exit(1)
+	}
+}
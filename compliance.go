@@ -0,0 +1,299 @@
+package gotrader
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AssetClass is the regulatory class of an instrument, its leverage is capped by the ComplianceRules.
+type AssetClass int
+
+const (
+	OtherAsset AssetClass = iota
+	MajorForex            // pairs of USD, EUR, JPY, GBP, CAD and CHF
+	MinorForex            // the other currency pairs
+	Gold
+	Commodity
+	Index
+	Equity
+	Crypto
+)
+
+func (c AssetClass) String() string {
+	switch c {
+	case MajorForex:
+		return "MAJOR_FOREX"
+	case MinorForex:
+		return "MINOR_FOREX"
+	case Gold:
+		return "GOLD"
+	case Commodity:
+		return "COMMODITY"
+	case Index:
+		return "INDEX"
+	case Equity:
+		return "EQUITY"
+	case Crypto:
+		return "CRYPTO"
+	}
+
+	return "OTHER"
+}
+
+var (
+	majorCurrencies = map[string]bool{"USD": true, "EUR": true, "JPY": true, "GBP": true, "CAD": true, "CHF": true}
+	metals          = map[string]bool{"XAG": true, "XPT": true, "XPD": true, "XCU": true}
+	cryptos         = map[string]bool{"BTC": true, "ETH": true, "LTC": true, "XRP": true, "BCH": true, "SOL": true}
+)
+
+// ClassifyInstrument returns the asset class of an instrument by its currencies: the currency pairs and the
+// metals and cryptocurrencies quoted in a currency, every other instrument is an OtherAsset.
+func ClassifyInstrument(base, quote string) AssetClass {
+
+	switch {
+	case base == "XAU":
+		return Gold
+	case metals[base]:
+		return Commodity
+	case cryptos[base]:
+		return Crypto
+	case majorCurrencies[base] && majorCurrencies[quote]:
+		return MajorForex
+	case isCurrency(base) && isCurrency(quote):
+		return MinorForex
+	}
+
+	return OtherAsset
+}
+
+func isCurrency(code string) bool {
+	return len(code) == 3 && strings.ToUpper(code) == code && !strings.ContainsAny(code, "0123456789")
+}
+
+// ComplianceRule identifies the rule breached by a ComplianceError.
+type ComplianceRule int
+
+const (
+	LeverageRule ComplianceRule = iota // the margin at the capped leverages exceeds the equity
+	FIFORule                           // a trade is closed before an older one
+	HedgingRule                        // a trade is opened against an open position
+)
+
+func (r ComplianceRule) String() string {
+	switch r {
+	case LeverageRule:
+		return "LEVERAGE"
+	case FIFORule:
+		return "FIFO"
+	case HedgingRule:
+		return "NO_HEDGING"
+	}
+
+	return "UNKNOWN"
+}
+
+// ComplianceError is the error of an open or a close breaching the ComplianceRules of the session, it matches
+// ErrComplianceViolation with errors.Is.
+type ComplianceError struct {
+	Regime     string
+	Rule       ComplianceRule
+	Instrument string
+	Detail     string
+}
+
+func (e *ComplianceError) Error() string {
+
+	regime := e.Regime
+	if regime == "" {
+		regime = "compliance"
+	}
+
+	return fmt.Sprintf("%s: %s %s rule: %s", e.Instrument, regime, e.Rule, e.Detail)
+}
+
+func (e *ComplianceError) Unwrap() error {
+	return ErrComplianceViolation
+}
+
+/*
+ComplianceRules are the rules of a regulatory regime evaluated before every open and close of the strategy,
+their violations are returned as a *ComplianceError. The closes of the stop loss, take profit and expiry of the
+trades are not checked.
+
+MaxLeverage caps the leverage of the asset classes (the instrument leverage when it is lower): an open is
+rejected when the margin of the positions after it, at the capped leverages, exceeds the equity. The margins of
+the sides are combined by the hedge type and raised by the margin multiplier of the instruments, as their margins
+are. The classes are given by Classes, by instrument name, or else by ClassifyInstrument.
+
+FIFO rejects the close of a trade while an older trade of the same position is open: the live engine closes
+asynchronously, so the closes of several trades are sent from the oldest as the previous ones fill. NoHedging
+rejects an open against an open position, whatever the hedge type: the engines don't net the positions of the
+NoHedge instruments.
+*/
+type ComplianceRules struct {
+	Regime      string
+	MaxLeverage map[AssetClass]float64
+	Classes     map[string]AssetClass // by instrument name
+	FIFO        bool
+	NoHedging   bool
+}
+
+// ESMA returns the rules of the retail CFDs in the EU.
+func ESMA() ComplianceRules {
+	return ComplianceRules{
+		Regime: "ESMA",
+		MaxLeverage: map[AssetClass]float64{
+			MajorForex: 30,
+			MinorForex: 20,
+			Gold:       20,
+			Index:      20,
+			Commodity:  10,
+			OtherAsset: 10,
+			Equity:     5,
+			Crypto:     2,
+		},
+	}
+}
+
+// NFA returns the rules of the retail forex in the US, with FIFO closes and no hedging.
+func NFA() ComplianceRules {
+	return ComplianceRules{
+		Regime: "NFA",
+		MaxLeverage: map[AssetClass]float64{
+			MajorForex: 50,
+			MinorForex: 20,
+		},
+		FIFO:      true,
+		NoHedging: true,
+	}
+}
+
+// Compliance is the functional option to check the opens and closes of the strategy against the rules.
+func Compliance(rules ComplianceRules) Option {
+	return func(p *sessionParameters) {
+		p.compliance = &rules
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (c *ComplianceRules) class(inst *Instrument) AssetClass {
+
+	if class, exist := c.Classes[inst.name]; exist {
+		return class
+	}
+
+	return ClassifyInstrument(inst.baseCurrency, inst.quoteCurrency)
+}
+
+// leverage returns the leverage of the instrument margin, capped by the class.
+func (c *ComplianceRules) leverage(inst *Instrument) float64 {
+
+	leverage := inst.leverage.Load()
+
+	if limit, exist := c.MaxLeverage[c.class(inst)]; exist && limit > 0 && (leverage <= 0 || limit < leverage) {
+		leverage = limit
+	}
+
+	return leverage
+}
+
+// margin returns the margin of the positions of the instrument at the capped leverage, with the order units. The
+// margins of the sides are combined as the instrument margin is, see hedgedMargin.
+func (c *ComplianceRules) margin(inst *Instrument, side Side, units int32) float64 {
+
+	leverage := c.leverage(inst)
+	if leverage <= 0 {
+		return 0
+	}
+
+	long, short := float64(inst.longPosition.Units()), float64(inst.shortPosition.Units())
+
+	if side == Long {
+		long += float64(units)
+	} else {
+		short += float64(units)
+	}
+
+	rate := inst.ccyConversion.BaseConversionRate.Load() / leverage
+
+	return hedgedMargin(inst.HedgeType(), NewDecimal(short*rate), NewDecimal(long*rate), inst.MarginMultiplier()).
+		Float64()
+}
+
+// checkOpen returns the violation of an open of the units of the instrument. Nil rules check nothing.
+func (c *ComplianceRules) checkOpen(account *Account, instrument string, side Side, units int32) error {
+
+	if c == nil {
+		return nil
+	}
+
+	inst, exist := account.instruments[instrument]
+	if !exist {
+		return nil
+	}
+
+	opposite := inst.shortPosition
+	if side == Short {
+		opposite = inst.longPosition
+	}
+
+	if c.NoHedging && opposite.TradesNumber() > 0 {
+		return &ComplianceError{Regime: c.Regime, Rule: HedgingRule, Instrument: instrument,
+			Detail: fmt.Sprintf("%s position is open", opposite.side)}
+	}
+
+	if len(c.MaxLeverage) == 0 {
+		return nil
+	}
+
+	margin := 0.0
+	for name, i := range account.instruments {
+		if name == instrument {
+			margin += c.margin(i, side, units)
+		} else {
+			margin += c.margin(i, side, 0)
+		}
+	}
+
+	if equity := account.Equity(); margin > equity {
+		return &ComplianceError{Regime: c.Regime, Rule: LeverageRule, Instrument: instrument,
+			Detail: fmt.Sprintf("margin %.2f exceeds equity %.2f at %.0f:1", margin, equity, c.leverage(inst))}
+	}
+
+	return nil
+}
+
+// checkClose returns the violation of a close of the trade. Nil rules check nothing.
+func (c *ComplianceRules) checkClose(account *Account, instrument, id string) error {
+
+	if c == nil || !c.FIFO {
+		return nil
+	}
+
+	inst, exist := account.instruments[instrument]
+	if !exist {
+		return nil
+	}
+
+	trade := inst.Trade(id)
+	if trade == nil {
+		return nil
+	}
+
+	position := inst.longPosition
+	if trade.Side() == Short {
+		position = inst.shortPosition
+	}
+
+	if oldest := position.TradeByOrder(0); oldest != nil && oldest.ID() != id {
+		return &ComplianceError{Regime: c.Regime, Rule: FIFORule, Instrument: instrument,
+			Detail: fmt.Sprintf("trade %s is older than %s", oldest.ID(), id)}
+	}
+
+	return nil
+}
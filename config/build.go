@@ -0,0 +1,520 @@
+package config
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/clients/alpaca"
+	"github.com/luismcruz/gotrader/clients/binance"
+	"github.com/luismcruz/gotrader/clients/btrand"
+	"github.com/luismcruz/gotrader/clients/csvdata"
+	"github.com/luismcruz/gotrader/clients/fix"
+	"github.com/luismcruz/gotrader/clients/oanda"
+	"github.com/luismcruz/gotrader/clients/paper"
+	"github.com/luismcruz/gotrader/runner"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func hedge(name string) (gotrader.Hedge, error) {
+
+	switch strings.ToLower(name) {
+	case "", "full":
+		return gotrader.FullHedge, nil
+	case "half":
+		return gotrader.HalfHedge, nil
+	case "none":
+		return gotrader.NoHedge, nil
+	}
+
+	return gotrader.FullHedge, errors.New("invalid hedge " + name)
+}
+
+// offset parses a time of day as an offset from midnight.
+func offset(clock string) (time.Duration, error) {
+
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, errors.New("invalid time of day " + clock)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// calendar returns the gotrader.DailySession of the hours, closed on their holidays, nil without hours.
+func (h *Hours) calendar() (gotrader.SessionCalendar, error) {
+
+	if h == nil {
+		return nil, nil
+	}
+
+	var err error
+	session := gotrader.DailySession{Location: time.UTC}
+
+	if h.Location != "" {
+		if session.Location, err = time.LoadLocation(h.Location); err != nil {
+			return nil, err
+		}
+	}
+
+	if session.Open, err = offset(h.Open); err != nil {
+		return nil, err
+	}
+
+	if session.Close, err = offset(h.Close); err != nil {
+		return nil, err
+	}
+
+	for _, name := range h.Weekdays {
+
+		day, exist := weekdays[strings.ToLower(name)[:min(3, len(name))]]
+		if !exist {
+			return nil, errors.New("invalid weekday " + name)
+		}
+
+		session.Weekdays = append(session.Weekdays, day)
+	}
+
+	if len(h.Holidays) == 0 {
+		return session, nil
+	}
+
+	venue := gotrader.VenueCalendar{Hours: session, Location: session.Location}
+
+	for _, date := range h.Holidays {
+
+		holiday, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, errors.New("invalid holiday " + date)
+		}
+
+		venue.Holidays = append(venue.Holidays, holiday)
+	}
+
+	return venue, nil
+}
+
+// profile returns the gotrader.SpreadProfile of the spread with the pip location of its instrument, nil without
+// spread.
+func (s *Spread) profile(pipLocation int) (*gotrader.SpreadProfile, error) {
+
+	if s == nil {
+		return nil, nil
+	}
+
+	pip := math.Pow10(pipLocation)
+	profile := &gotrader.SpreadProfile{Default: s.Pips * pip, Location: time.UTC}
+
+	if s.Location != "" {
+		location, err := time.LoadLocation(s.Location)
+		if err != nil {
+			return nil, err
+		}
+		profile.Location = location
+	}
+
+	if s.Pips < 0 {
+		return nil, errors.New("negative pips")
+	}
+
+	for _, period := range s.Periods {
+
+		from, err := offset(period.From)
+		if err != nil {
+			return nil, err
+		}
+
+		to, err := offset(period.To)
+		if err != nil {
+			return nil, err
+		}
+
+		if period.Pips < 0 {
+			return nil, errors.New("negative pips")
+		}
+
+		profile.Periods = append(profile.Periods, gotrader.SpreadPeriod{From: from, To: to, Spread: period.Pips * pip})
+	}
+
+	return profile, nil
+}
+
+// model returns the paper.LatencyModel of the latency, nil without latency.
+func (l *Latency) model() paper.LatencyModel {
+
+	switch {
+	case l == nil:
+		return nil
+	case l.Median != 0:
+		return &paper.LogNormalLatency{Median: l.Median, Sigma: l.Sigma, Seed: l.Seed}
+	case len(l.Samples) > 0:
+		return &paper.VenueLatency{Samples: l.Samples, Seed: l.Seed}
+	}
+
+	return paper.FixedLatency(l.Fixed)
+}
+
+// model returns the gotrader.FinancingModel of the financing, nil without swaps nor rates.
+func (f *Financing) model() gotrader.FinancingModel {
+
+	if len(f.Swaps) > 0 {
+		swaps := make(gotrader.StaticSwaps, len(f.Swaps))
+		for name, swap := range f.Swaps {
+			swaps[name] = gotrader.Swap{Long: swap.Long, Short: swap.Short}
+		}
+		return swaps
+	}
+
+	if len(f.Rates) > 0 {
+		financing := gotrader.InterestRateFinancing{Rates: make(map[string]gotrader.RateCurve), Markup: f.Markup}
+		for ccy, rates := range f.Rates {
+			curve := make(gotrader.RateCurve, 0, len(rates))
+			for _, r := range rates {
+				curve = append(curve, gotrader.RatePoint{Time: r.Time, Rate: r.Rate})
+			}
+			sort.SliceStable(curve, func(i, j int) bool { return curve[i].Time.Before(curve[j].Time) })
+			financing.Rates[ccy] = curve
+		}
+		return financing
+	}
+
+	return nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// InstrumentNames returns the names of the instruments.
+func (c *Config) InstrumentNames() []string {
+
+	names := make([]string, 0, len(c.Instruments))
+	for _, inst := range c.Instruments {
+		names = append(names, inst.Name)
+	}
+
+	return names
+}
+
+// InstrumentDetails returns the details of the instruments.
+func (c *Config) InstrumentDetails() []gotrader.InstrumentDetails {
+
+	details := make([]gotrader.InstrumentDetails, 0, len(c.Instruments))
+	for _, inst := range c.Instruments {
+		details = append(details, gotrader.InstrumentDetails{
+			Name:          inst.Name,
+			BaseCurrency:  inst.Base,
+			QuoteCurrency: inst.Quote,
+			Leverage:      inst.Leverage,
+			PipLocation:   inst.PipLocation,
+			UnitSize:      inst.UnitSize,
+			StopDistance:  inst.StopDistance,
+		})
+	}
+
+	return details
+}
+
+// Backtest returns true when the broker is a backtest one, so the session should run with the backtest engine.
+func (c *Config) Backtest() bool {
+	return c.Broker.Type == "btrand" || c.Broker.Type == "csv"
+}
+
+// SymbolMap returns the mapping of the symbols of the venues to the instrument names, the one of the clients it
+// builds. The instruments are registered, the venue "*" is the one of the aliases of every venue.
+func (c *Config) SymbolMap() *gotrader.SymbolMap {
+
+	if c.symbols != nil {
+		return c.symbols
+	}
+
+	c.symbols = gotrader.NewSymbolMap()
+	c.symbols.Register(c.InstrumentNames()...)
+
+	for name, venue := range c.Symbols {
+
+		if name == "*" {
+			name = ""
+		}
+
+		if venue.Separator != nil {
+			c.symbols.SetFormat(name, gotrader.SymbolFormat{Separator: *venue.Separator, Lower: venue.Lower})
+		}
+
+		for symbol, instrument := range venue.Aliases {
+			c.symbols.Alias(name, symbol, instrument)
+		}
+	}
+
+	return c.symbols
+}
+
+// Client returns the broker client.
+func (c *Config) Client() (gotrader.BrokerClient, error) {
+
+	var client gotrader.BrokerClient
+	b := c.Broker
+
+	switch b.Type {
+	case "btrand":
+		var opts []btrand.ClientOption
+		if b.Seed != 0 {
+			opts = append(opts, btrand.Seed(b.Seed))
+		}
+		client = btrand.NewBTRandClient(c.InstrumentDetails(), b.Start, b.End, opts...)
+	case "csv":
+		if b.Candles > 0 {
+			client = csvdata.NewCandleClient(b.Data, c.InstrumentDetails(), b.Candles, b.Start, b.End)
+		} else {
+			client = csvdata.NewCSVClient(b.Data, c.InstrumentDetails(), b.Start, b.End)
+		}
+	case "oanda":
+		client = oanda.NewOandaClient(b.Token, b.Live)
+	case "binance":
+		market := binance.Spot
+		if b.Futures {
+			market = binance.USDMFutures
+		}
+		client = binance.NewBinanceClient(binance.Config{
+			APIKey:     b.Key,
+			SecretKey:  b.Secret,
+			Market:     market,
+			HomeAsset:  c.Account.HomeCurrency,
+			Leverage:   c.Account.Leverage,
+			Testnet:    b.Testnet,
+			Instrument: c.InstrumentNames(),
+			SymbolMap:  c.SymbolMap(),
+		})
+	case "alpaca":
+		client = alpaca.NewAlpacaClient(alpaca.Config{
+			KeyID:     b.Key,
+			SecretKey: b.Secret,
+			Paper:     !b.Live,
+			Feed:      b.Feed,
+			Symbols:   c.InstrumentNames(),
+			SymbolMap: c.SymbolMap(),
+		})
+	case "fix":
+		client = fix.NewFIXClient(fix.Config{
+			Address:      b.Address,
+			SenderCompID: b.SenderCompID,
+			TargetCompID: b.TargetCompID,
+			Username:     b.Username,
+			Password:     b.Password,
+			Account:      c.Account.ID,
+			HeartBeat:    b.HeartBeat,
+			Currency:     c.Account.HomeCurrency,
+			Leverage:     c.Account.Leverage,
+			Balance:      c.Account.Balance,
+			Instruments:  c.InstrumentDetails(),
+			Symbols:      b.Symbols,
+			SymbolMap:    c.SymbolMap(),
+		})
+	default:
+		return nil, errors.New("unsupported broker " + b.Type)
+	}
+
+	if !b.Paper {
+		return client, nil
+	}
+
+	hedge, _ := hedge(c.Account.Hedge)
+	opts := []paper.Option{
+		paper.HedgeType(hedge),
+		paper.Commission(c.Schedule()),
+		paper.Slippage(c.Schedule()),
+	}
+
+	if c.Account.Balance != 0 {
+		opts = append(opts, paper.Balance(c.Account.Balance))
+	}
+
+	if c.Account.HomeCurrency != "" {
+		opts = append(opts, paper.Currency(c.Account.HomeCurrency))
+	}
+
+	if c.Account.Leverage != 0 {
+		opts = append(opts, paper.Leverage(c.Account.Leverage))
+	}
+
+	if model := b.Latency.model(); model != nil {
+		opts = append(opts, paper.Latency(model))
+	}
+
+	return paper.NewPaperClient(client, opts...), nil
+}
+
+// SessionOptions returns the options of the trading session.
+func (c *Config) SessionOptions() []gotrader.Option {
+
+	opts := []gotrader.Option{
+		gotrader.AccountID(c.Account.ID),
+		gotrader.Instruments(c.InstrumentNames()),
+	}
+
+	if c.Backtest() {
+
+		hedge, _ := hedge(c.Account.Hedge)
+		opts = append(opts, gotrader.HedgeType(hedge))
+
+		if c.Account.Balance != 0 {
+			opts = append(opts, gotrader.InitialBalance(c.Account.Balance))
+		}
+
+		if c.Account.HomeCurrency != "" {
+			opts = append(opts, gotrader.HomeCurrency(c.Account.HomeCurrency))
+		}
+
+		if c.Account.Leverage != 0 {
+			opts = append(opts, gotrader.Leverage(c.Account.Leverage))
+		}
+
+		opts = append(opts, gotrader.Financing(c.Schedule()))
+	}
+
+	s := c.Session
+
+	if s.MarginCallLevel != 0 {
+		opts = append(opts, gotrader.MarginCallLevel(s.MarginCallLevel))
+	}
+
+	if s.StaleAfter != 0 {
+		opts = append(opts, gotrader.StaleAfter(s.StaleAfter))
+	}
+
+	if s.DedupeTicks {
+		opts = append(opts, gotrader.DedupeTicks())
+	}
+
+	if s.RecalculationShards != 0 {
+		opts = append(opts, gotrader.RecalculationShards(s.RecalculationShards))
+	}
+
+	if s.TrackEquity != 0 {
+		opts = append(opts, gotrader.TrackEquity(s.TrackEquity))
+	}
+
+	if s.CollectStats {
+		opts = append(opts, gotrader.CollectStats())
+	}
+
+	if s.QueueFills {
+		opts = append(opts, gotrader.QueueFills())
+	}
+
+	if calendar, _ := s.MarketHours.calendar(); calendar != nil {
+		opts = append(opts, gotrader.MarketHours(calendar))
+	}
+
+	opts = append(opts, gotrader.Markups(c.Schedule()))
+
+	if s.Flatten != nil {
+		opts = append(opts, gotrader.FlattenBeforeClose(gotrader.FlattenPolicy(*s.Flatten)))
+	}
+
+	if s.Retry != nil {
+		opts = append(opts, gotrader.Retry(gotrader.RetryPolicy{Attempts: s.Retry.Attempts, Base: s.Retry.Base,
+			Max: s.Retry.Max}))
+	}
+
+	if s.Health != nil {
+		opts = append(opts, gotrader.HealthCheck(gotrader.HealthPolicy(*s.Health)))
+	}
+
+	var dividends gotrader.DividendSchedule
+
+	for _, inst := range c.Instruments {
+
+		for _, dividend := range inst.Dividends {
+			dividends = append(dividends, gotrader.Dividend{Instrument: inst.Name, ExDate: dividend.ExDate, Amount: dividend.Amount})
+		}
+
+		if calendar, _ := inst.Hours.calendar(); calendar != nil {
+			opts = append(opts, gotrader.InstrumentMarketHours(inst.Name, calendar))
+		}
+
+		if inst.Flatten != nil {
+			opts = append(opts, gotrader.InstrumentFlattenBeforeClose(inst.Name, gotrader.FlattenPolicy(*inst.Flatten)))
+		}
+
+		if inst.Hedge != "" {
+			hedge, _ := hedge(inst.Hedge)
+			opts = append(opts, gotrader.InstrumentHedge(inst.Name, hedge))
+		}
+
+		if profile, _ := inst.Spread.profile(inst.PipLocation); profile != nil && c.Backtest() {
+			opts = append(opts, gotrader.InstrumentSpread(inst.Name, profile))
+		}
+	}
+
+	if len(dividends) > 0 {
+		opts = append(opts, gotrader.Dividends(dividends))
+	}
+
+	return opts
+}
+
+// NewSession returns the trading session of the configuration, with its broker client and engine, the strategy
+// and the other options are added by the caller.
+func (c *Config) NewSession(opts ...gotrader.Option) (*gotrader.TradingSession, error) {
+
+	client, err := c.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	session := gotrader.NewTradingSession(append(c.SessionOptions(), opts...)...).SetClient(client)
+
+	if c.Backtest() {
+		return session.Backtest(), nil
+	}
+
+	return session.Live(), nil
+}
+
+// StrategyOptions returns the runner registration options of a strategy, its instruments, candles, allocation
+// and risk limits, nil if the strategy is not configured. The candles of the timeframe of the rules are included.
+func (c *Config) StrategyOptions(name string) []runner.Option {
+
+	strategy, exist := c.Strategies[name]
+	if !exist {
+		return nil
+	}
+
+	opts := []runner.Option{
+		runner.Limits(runner.RiskLimits{
+			MaxUnits:      strategy.Limits.MaxUnits,
+			MaxOpenTrades: strategy.Limits.MaxOpenTrades,
+			MaxDrawdown:   strategy.Limits.MaxDrawdown,
+		}),
+	}
+
+	if len(strategy.Instruments) > 0 {
+		opts = append(opts, runner.Instruments(strategy.Instruments...))
+	}
+
+	candles := strategy.Candles
+	if strategy.Rules != nil && !slices.Contains(candles, strategy.Rules.Timeframe) {
+		candles = append(candles[:len(candles):len(candles)], strategy.Rules.Timeframe)
+	}
+
+	if len(candles) > 0 {
+		opts = append(opts, runner.Candles(candles...))
+	}
+
+	if strategy.Allocation != 0 {
+		opts = append(opts, runner.Allocate(strategy.Allocation))
+	}
+
+	return opts
+}
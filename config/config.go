@@ -0,0 +1,545 @@
+/*
+Package config builds a trading session from a YAML file: the account, its instruments with their trading
+hours and fees, the broker and the risk limits of the strategies, so a deployment is configured instead of
+hand-wired in main():
+
+	account:
+	  id: 101-004-1234567-001
+	  homeCurrency: USD
+	  balance: 100000        # backtests and paper trading
+	  leverage: 30
+	  hedge: full            # full, half or none
+	broker:
+	  type: oanda            # btrand, csv, oanda, binance, alpaca or fix
+	  token: ${OANDA_TOKEN}
+	  paper: true            # fill the orders locally against the broker prices
+	  latency: {median: 40ms, sigma: 0.5}  # of the paper orders, or fixed, or the samples measured on the venue
+	session:
+	  marginCallLevel: 1
+	  staleAfter: 30s
+	  trackEquity: 1m
+	  marketHours: {location: UTC, open: "22:00", close: "21:00", weekdays: [sun, mon, tue, wed, thu], holidays: [2024-12-25]}
+	  markup: {pips: 0.2}    # per side, or percent
+	  flatten: {before: 15m, weekendOnly: true} # close the trades before the market hours close
+	  retry: {attempts: 3, base: 200ms, max: 2s}  # the requests to the broker failing with a transient error
+	  health: {degraded: 10s, unhealthy: 30s}     # silences of the feed and broker session, the opens are blocked when unhealthy
+	fees:
+	  commission: {percent: 0.0001, tiers: [{units: 1000000, percent: 0.00005}]}
+	financing:               # backtests, static swaps by instrument or interest rates by currency
+	  markup: 0.005
+	  rates:
+	    EUR: [{rate: 0.04}]
+	    USD: [{rate: 0.05}, {time: 2024-09-18T18:00:00Z, rate: 0.045}]
+	instruments:
+	  - name: EUR_USD
+	    base: EUR
+	    quote: USD
+	    leverage: 30
+	    pipLocation: -4
+	    hedge: half          # replaces the account hedge
+	    spread:              # backtests, by time of day, e.g. of the candles of a csv broker with candles: 1h
+	      pips: 1.2
+	      periods: [{from: "21:00", to: "23:00", pips: 5}, {from: "07:00", to: "16:00", pips: 0.8}]
+	    fees:
+	      slippage: {spread: 0.5}
+	  - name: SPX500_USD
+	    base: SPX500
+	    quote: USD
+	    leverage: 20
+	    pipLocation: 0
+	    dividends:           # applied to the open trades by the backtests and the paper broker
+	      - {exDate: 2024-03-15T00:00:00Z, amount: 1.6}
+	strategies:
+	  trend:
+	    instruments: [EUR_USD]
+	    candles: [1m, 1h]
+	    allocation: 10000
+	    limits: {maxUnits: 100000, maxOpenTrades: 5, maxDrawdown: 0.1}
+	    plugin: strategies/trend.so # loaded by the gotrader command
+	  breakout:
+	    instruments: [EUR_USD]
+	    rules:               # instead of a plugin, see the rules package
+	      timeframe: 1h
+	      units: 10000
+	      entryLong: close > bb_upper(20, 2) and rsi(14) < 70
+	      exitLong: close < bb_middle(20, 2)
+	symbols:                 # by broker type or venue of the data, "*" for every venue
+	  fix: {separator: /}
+	  cme: {aliases: {6E: EUR_USD}}
+
+The environment variables referenced as ${NAME} are expanded before the file is decoded, so the credentials
+can be kept out of it. Unknown keys are rejected.
+
+The instrument leverage, pip location, unit size and stop distance are the details of the backtests (btrand and csv)
+and FIX instruments, other brokers report them. The fees are charged by the paper broker, the instrument fees replace
+the account ones; the fees, financing and markups can be reloaded at runtime, see Schedule. The symbols of the
+venues are mapped to the instrument names by the broker clients, see SymbolMap.
+*/
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/rules"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of a trading session.
+type Config struct {
+	Account     Account             `yaml:"account"`
+	Broker      Broker              `yaml:"broker"`
+	Session     Session             `yaml:"session"`
+	Fees        Fees                `yaml:"fees"`
+	Financing   Financing           `yaml:"financing"`
+	Instruments []Instrument        `yaml:"instruments"`
+	Strategies  map[string]Strategy `yaml:"strategies"`
+	Symbols     map[string]Venue    `yaml:"symbols"` // by venue, see SymbolMap
+
+	schedule *Schedule
+	symbols  *gotrader.SymbolMap
+}
+
+// Account is the account of the session, the balance, home currency, leverage and hedge are those of the
+// backtest and paper accounts.
+type Account struct {
+	ID           string  `yaml:"id"`
+	HomeCurrency string  `yaml:"homeCurrency"`
+	Balance      float64 `yaml:"balance"`
+	Leverage     float64 `yaml:"leverage"`
+	Hedge        string  `yaml:"hedge"` // full, half or none, defaults to full
+}
+
+// Broker is the broker client of the session, only the fields of its type are used.
+type Broker struct {
+	Type    string   `yaml:"type"`    // btrand, csv, oanda, binance, alpaca or fix
+	Paper   bool     `yaml:"paper"`   // wraps the client with the paper broker
+	Latency *Latency `yaml:"latency"` // paper, of the orders and the trade closes
+
+	// btrand, the random prices backtest, and csv, the backtest of the tick or candle files of a directory
+	Start   time.Time     `yaml:"start"` // csv, every tick when zero
+	End     time.Time     `yaml:"end"`
+	Seed    int64         `yaml:"seed"`    // replays the same prices, random when zero
+	Data    string        `yaml:"data"`    // csv directory, see the csvdata package
+	Candles time.Duration `yaml:"candles"` // csv timeframe of the candle files, tick files when zero
+
+	// oanda, binance and alpaca
+	Token   string `yaml:"token"` // oanda
+	Live    bool   `yaml:"live"`  // oanda live environment, practice by default; alpaca live account, paper by default
+	Key     string `yaml:"key"`
+	Secret  string `yaml:"secret"`
+	Testnet bool   `yaml:"testnet"` // binance
+	Futures bool   `yaml:"futures"` // binance USD-M futures, spot by default
+	Feed    string `yaml:"feed"`    // alpaca market data feed, iex or sip
+
+	// fix
+	Address      string            `yaml:"address"`
+	SenderCompID string            `yaml:"senderCompID"`
+	TargetCompID string            `yaml:"targetCompID"`
+	Username     string            `yaml:"username"`
+	Password     string            `yaml:"password"`
+	HeartBeat    time.Duration     `yaml:"heartBeat"`
+	Symbols      map[string]string `yaml:"symbols"` // instrument name to venue symbol
+}
+
+// Latency is the latency of the requests of the paper broker, fixed, drawn from a log-normal distribution of median
+// and sigma, or drawn from the samples measured on the venue, see the paper package.
+type Latency struct {
+	Fixed   time.Duration   `yaml:"fixed"`
+	Median  time.Duration   `yaml:"median"`
+	Sigma   float64         `yaml:"sigma"`
+	Samples []time.Duration `yaml:"samples"`
+	Seed    int64           `yaml:"seed"` // replays the same latencies of the distribution and samples
+}
+
+// Session are the options of the trading session, zero values keep the session defaults.
+type Session struct {
+	MarginCallLevel     float64       `yaml:"marginCallLevel"`
+	StaleAfter          time.Duration `yaml:"staleAfter"`
+	DedupeTicks         bool          `yaml:"dedupeTicks"`
+	RecalculationShards int           `yaml:"recalculationShards"`
+	TrackEquity         time.Duration `yaml:"trackEquity"` // resolution of the equity curve
+	CollectStats        bool          `yaml:"collectStats"`
+	QueueFills          bool          `yaml:"queueFills"` // backtests, see gotrader.QueueFills
+	MarketHours         *Hours        `yaml:"marketHours"`
+	Markup              *Markup       `yaml:"markup"`
+	Flatten             *Flatten      `yaml:"flatten"`
+	Retry               *Retry        `yaml:"retry"`
+	Health              *Health       `yaml:"health"`
+}
+
+// Hours are the daily trading hours of a venue, see gotrader.DailySession and gotrader.VenueCalendar.
+type Hours struct {
+	Location string   `yaml:"location"` // IANA time zone, defaults to UTC
+	Open     string   `yaml:"open"`     // as 15:04
+	Close    string   `yaml:"close"`
+	Weekdays []string `yaml:"weekdays"` // as mon or monday, every day when empty
+	Holidays []string `yaml:"holidays"` // as 2006-01-02, the sessions opening on them are closed
+}
+
+// Markup widens the quotes of the instruments, see gotrader.PriceMarkup.
+type Markup struct {
+	Pips    float64 `yaml:"pips"`
+	Percent float64 `yaml:"percent"`
+}
+
+// Spread is the spread profile of the backtest prices of an instrument by time of day, in pips, reconstructing
+// the bid and ask of the candles, see gotrader.SpreadProfile.
+type Spread struct {
+	Pips     float64        `yaml:"pips"`     // outside the periods
+	Location string         `yaml:"location"` // IANA time zone of the periods, defaults to UTC
+	Periods  []SpreadPeriod `yaml:"periods"`
+}
+
+// SpreadPeriod is the spread of a period of the day, wrapping around midnight when it ends before it starts.
+type SpreadPeriod struct {
+	From string  `yaml:"from"` // as 15:04
+	To   string  `yaml:"to"`
+	Pips float64 `yaml:"pips"`
+}
+
+// Flatten closes the open trades before the close of the market hours, see gotrader.FlattenPolicy.
+type Flatten struct {
+	Before      time.Duration `yaml:"before"`
+	WeekendOnly bool          `yaml:"weekendOnly"`
+}
+
+// Retry retries the requests to the broker failing with a transient error, see gotrader.RetryPolicy.
+type Retry struct {
+	Attempts int           `yaml:"attempts"`
+	Base     time.Duration `yaml:"base"`
+	Max      time.Duration `yaml:"max"`
+}
+
+// Health monitors the heartbeats of the feed and the broker session, see gotrader.HealthPolicy.
+type Health struct {
+	Degraded  time.Duration `yaml:"degraded"`
+	Unhealthy time.Duration `yaml:"unhealthy"` // twice the degraded one when zero
+}
+
+// Fees are the costs of the fills, at most one commission and one slippage model are set.
+type Fees struct {
+	Commission Commission `yaml:"commission"`
+	Slippage   struct {
+		Fixed  float64 `yaml:"fixed"`  // price adjustment
+		Spread float64 `yaml:"spread"` // fraction of the spread
+	} `yaml:"slippage"`
+}
+
+// Commission is the commission of the fills, per unit or percent of the notional. The tiers replace it for the
+// fills of at least their units, the largest tier applies.
+type Commission struct {
+	PerUnit float64 `yaml:"perUnit"`
+	Percent float64 `yaml:"percent"` // fraction of the notional, e.g. 0.001 for 10bps
+	Tiers   []Tier  `yaml:"tiers"`
+}
+
+// Tier is a commission of the fills of at least Units units.
+type Tier struct {
+	Units   int32   `yaml:"units"`
+	PerUnit float64 `yaml:"perUnit"`
+	Percent float64 `yaml:"percent"`
+}
+
+// Financing is the financing of the trades held over the rollovers in the backtests, from static swaps or from
+// interest rates, see gotrader.Financing.
+type Financing struct {
+	Swaps map[string]struct {
+		Long  float64 `yaml:"long"`
+		Short float64 `yaml:"short"`
+	} `yaml:"swaps"` // by instrument, per unit and day in the quote currency
+	Rates  map[string][]Rate `yaml:"rates"`  // by currency
+	Markup float64           `yaml:"markup"` // annual, of the interest rates
+}
+
+// Rate is an annual interest rate effective from its time, the first rate of a currency has no time.
+type Rate struct {
+	Time time.Time `yaml:"time"`
+	Rate float64   `yaml:"rate"`
+}
+
+// Instrument is an instrument traded by the session.
+type Instrument struct {
+	Name         string     `yaml:"name"`
+	Base         string     `yaml:"base"`
+	Quote        string     `yaml:"quote"`
+	Leverage     float64    `yaml:"leverage"`
+	PipLocation  int        `yaml:"pipLocation"`
+	UnitSize     float64    `yaml:"unitSize"`     // quantity of a unit, e.g. 0.001 for fractional shares
+	StopDistance float64    `yaml:"stopDistance"` // minimum distance of the order levels from the prices, in pips
+	Hedge        string     `yaml:"hedge"`        // full, half or none, replaces the account hedge
+	Hours        *Hours     `yaml:"hours"`        // replaces the session market hours
+	Markup       *Markup    `yaml:"markup"`       // replaces the session markup
+	Spread       *Spread    `yaml:"spread"`       // backtests, replaces the spreads of the prices
+	Fees         *Fees      `yaml:"fees"`         // replaces the account fees
+	Flatten      *Flatten   `yaml:"flatten"`      // replaces the session flatten policy
+	Dividends    []Dividend `yaml:"dividends"`
+}
+
+// Venue is the format of the symbols of a venue and their aliases, see gotrader.SymbolMap.
+type Venue struct {
+	Separator *string           `yaml:"separator"` // of BASE and QUOTE, e.g. "/" for EUR/USD, the names when unset
+	Lower     bool              `yaml:"lower"`
+	Aliases   map[string]string `yaml:"aliases"` // instrument name by symbol, e.g. 6E: EUR_USD
+}
+
+// Dividend is a cash dividend of an instrument, see gotrader.Dividend.
+type Dividend struct {
+	ExDate time.Time `yaml:"exDate"`
+	Amount float64   `yaml:"amount"` // per unit, in the quote currency
+}
+
+// Strategy is the registration of a runner strategy, see StrategyOptions.
+type Strategy struct {
+	Instruments []string        `yaml:"instruments"`
+	Candles     []time.Duration `yaml:"candles"`
+	Allocation  float64         `yaml:"allocation"`
+	Limits      struct {
+		MaxUnits      int32   `yaml:"maxUnits"`
+		MaxOpenTrades int     `yaml:"maxOpenTrades"`
+		MaxDrawdown   float64 `yaml:"maxDrawdown"`
+	} `yaml:"limits"`
+	Plugin string        `yaml:"plugin"` // strategy plugin, see runner.LoadPlugin
+	Rules  *rules.Config `yaml:"rules"`  // conditions of a rules strategy, instead of a plugin
+}
+
+// Load reads and validates the configuration file at path.
+func Load(path string) (*Config, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Parse decodes and validates a YAML configuration, expanding its environment variables.
+func Parse(data []byte) (*Config, error) {
+
+	decoder := yaml.NewDecoder(bytes.NewReader([]byte(os.ExpandEnv(string(data)))))
+	decoder.KnownFields(true)
+
+	cfg := &Config{}
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (c *Config) validate() error {
+
+	if len(c.Instruments) == 0 {
+		return errors.New("no instruments")
+	}
+
+	if _, err := hedge(c.Account.Hedge); err != nil {
+		return err
+	}
+
+	if err := c.Fees.validate(); err != nil {
+		return err
+	}
+
+	if len(c.Financing.Swaps) > 0 && len(c.Financing.Rates) > 0 {
+		return errors.New("financing: both swaps and rates")
+	}
+
+	if _, err := c.Session.MarketHours.calendar(); err != nil {
+		return fmt.Errorf("market hours: %w", err)
+	}
+
+	if err := c.Session.Flatten.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Session.Retry.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Session.Health.validate(); err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(c.Instruments))
+
+	for _, inst := range c.Instruments {
+
+		if inst.Name == "" {
+			return errors.New("instrument without name")
+		}
+
+		if names[inst.Name] {
+			return errors.New("duplicated instrument " + inst.Name)
+		}
+		names[inst.Name] = true
+
+		if _, err := hedge(inst.Hedge); err != nil {
+			return fmt.Errorf("%s: %w", inst.Name, err)
+		}
+
+		if inst.UnitSize < 0 || inst.StopDistance < 0 {
+			return errors.New(inst.Name + ": negative unit size or stop distance")
+		}
+
+		if _, err := inst.Hours.calendar(); err != nil {
+			return fmt.Errorf("%s hours: %w", inst.Name, err)
+		}
+
+		if inst.Fees != nil {
+			if err := inst.Fees.validate(); err != nil {
+				return fmt.Errorf("%s: %w", inst.Name, err)
+			}
+		}
+
+		if err := inst.Flatten.validate(); err != nil {
+			return fmt.Errorf("%s: %w", inst.Name, err)
+		}
+
+		if _, err := inst.Spread.profile(inst.PipLocation); err != nil {
+			return fmt.Errorf("%s spread: %w", inst.Name, err)
+		}
+
+		for _, dividend := range inst.Dividends {
+			if dividend.ExDate.IsZero() {
+				return fmt.Errorf("%s: dividend without ex-dividend date", inst.Name)
+			}
+		}
+	}
+
+	for name, strategy := range c.Strategies {
+		for _, inst := range strategy.Instruments {
+			if !names[inst] {
+				return fmt.Errorf("strategy %s: unknown instrument %s", name, inst)
+			}
+		}
+
+		if strategy.Rules != nil {
+			if strategy.Plugin != "" {
+				return fmt.Errorf("strategy %s: plugin and rules", name)
+			}
+			if _, err := rules.Compile(*strategy.Rules); err != nil {
+				return fmt.Errorf("strategy %s: %w", name, err)
+			}
+		}
+	}
+
+	if err := c.Broker.Latency.validate(); err != nil {
+		return err
+	}
+
+	switch c.Broker.Type {
+	case "btrand":
+		if !c.Broker.End.After(c.Broker.Start) {
+			return errors.New("btrand broker: end must be after start")
+		}
+	case "csv":
+		if c.Broker.Data == "" {
+			return errors.New("csv broker: no data directory")
+		}
+	case "oanda", "binance", "alpaca", "fix":
+	default:
+		return errors.New("unsupported broker " + strings.TrimSpace(c.Broker.Type))
+	}
+
+	return nil
+}
+
+func (f *Fees) validate() error {
+
+	if f.Commission.PerUnit != 0 && f.Commission.Percent != 0 {
+		return errors.New("fees: more than one commission model")
+	}
+
+	for i, tier := range f.Commission.Tiers {
+
+		if tier.PerUnit != 0 && tier.Percent != 0 {
+			return fmt.Errorf("fees: more than one commission model in tier %d", i)
+		}
+
+		if tier.Units <= 0 || i > 0 && tier.Units <= f.Commission.Tiers[i-1].Units {
+			return errors.New("fees: commission tiers must have increasing positive units")
+		}
+	}
+
+	if f.Slippage.Fixed != 0 && f.Slippage.Spread != 0 {
+		return errors.New("fees: more than one slippage model")
+	}
+
+	return nil
+}
+
+func (f *Flatten) validate() error {
+
+	if f != nil && f.Before <= 0 {
+		return errors.New("flatten: before must be positive")
+	}
+
+	return nil
+}
+
+func (h *Health) validate() error {
+
+	if h != nil && (h.Degraded <= 0 || h.Unhealthy < 0 || h.Unhealthy > 0 && h.Unhealthy < h.Degraded) {
+		return errors.New("health: degraded must be positive and not above unhealthy")
+	}
+
+	return nil
+}
+
+func (l *Latency) validate() error {
+
+	if l == nil {
+		return nil
+	}
+
+	models := 0
+	for _, set := range []bool{l.Fixed != 0, l.Median != 0, len(l.Samples) > 0} {
+		if set {
+			models++
+		}
+	}
+
+	if models != 1 {
+		return errors.New("latency: one of fixed, median or samples")
+	}
+
+	if l.Fixed < 0 || l.Median < 0 || l.Sigma < 0 || slices.ContainsFunc(l.Samples, func(d time.Duration) bool {
+		return d < 0
+	}) {
+		return errors.New("latency: negative latency or sigma")
+	}
+
+	return nil
+}
+
+func (r *Retry) validate() error {
+
+	if r != nil && (r.Attempts < 1 || r.Base < 0 || r.Max < 0) {
+		return errors.New("retry: attempts must be positive and the delays non-negative")
+	}
+
+	return nil
+}
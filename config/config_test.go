@@ -0,0 +1,260 @@
+package config
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+const example = `
+account:
+  id: test
+  homeCurrency: USD
+  balance: 10000
+  hedge: none
+broker:
+  type: btrand
+  start: 2024-01-02T00:00:00Z
+  end: 2024-01-03T00:00:00Z
+  paper: true
+  latency: {samples: [20ms, 35ms], seed: 1}
+session:
+  staleAfter: 30s
+  marketHours: {location: America/New_York, open: "09:30", close: "16:00", weekdays: [mon, tue, wed, thu, fri], holidays: [2024-01-15]}
+  flatten: {before: 10m}
+fees:
+  commission: {perUnit: 0.01}
+financing:
+  rates: {USD: [{rate: 0.05}]}
+instruments:
+  - name: EUR_USD
+    base: EUR
+    quote: USD
+    leverage: 30
+    pipLocation: -4
+    spread: {pips: 1, periods: [{from: "21:00", to: "23:00", pips: 5}]}
+  - name: SPY
+    base: SPY
+    quote: USD
+    leverage: 1
+    hedge: none
+    fees: {commission: {percent: 0.001}}
+    dividends: [{exDate: 2024-01-02T12:00:00Z, amount: 0.5}]
+strategies:
+  trend:
+    instruments: [EUR_USD]
+    candles: [1m, 1h]
+    limits: {maxUnits: 1000}
+    plugin: trend.so
+  breakout:
+    instruments: [EUR_USD]
+    rules: {timeframe: 1h, units: 1000, entryLong: "close > sma(20)", exitLong: "close < sma(20)"}
+`
+
+func TestConfig(t *testing.T) {
+
+	t.Run("configurations are decoded", func(t *testing.T) {
+
+		t.Setenv("BROKER", "btrand")
+
+		cfg, err := Parse([]byte(strings.Replace(example, "type: btrand", "type: ${BROKER}", 1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !cfg.Backtest() || len(cfg.InstrumentDetails()) != 2 || cfg.InstrumentDetails()[0].PipLocation != -4 {
+			t.Errorf("unexpected configuration %+v", cfg)
+		}
+
+		if cfg.Session.StaleAfter != 30*time.Second || cfg.Strategies["trend"].Candles[1] != time.Hour ||
+			cfg.Strategies["trend"].Plugin != "trend.so" {
+			t.Errorf("expected the durations to be decoded, got %+v", cfg)
+		}
+
+		calendar, err := cfg.Session.MarketHours.calendar()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sunday := time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC)
+		if open := calendar.NextOpen(sunday).UTC(); open.Weekday() != time.Monday || open.Hour() != 14 || open.Minute() != 30 {
+			t.Errorf("unexpected market open %v", open)
+		}
+
+		if open := calendar.NextOpen(sunday.AddDate(0, 0, 7)).UTC(); open.Weekday() != time.Tuesday {
+			t.Errorf("expected the holiday to be closed, got the market open %v", open)
+		}
+
+		if cfg.Session.Flatten == nil || cfg.Session.Flatten.Before != 10*time.Minute {
+			t.Errorf("unexpected flatten policy %+v", cfg.Session.Flatten)
+		}
+
+		commission := cfg.Schedule()
+		if c := commission.Commission("EUR_USD", 100, 1.1); c != 1 {
+			t.Errorf("expected the account commission, got %v", c)
+		}
+
+		if c := commission.Commission("SPY", 10, 500); c != 5 {
+			t.Errorf("expected the instrument commission, got %v", c)
+		}
+
+		if d := cfg.Instruments[1].Dividends; len(d) != 1 || d[0].Amount != 0.5 || d[0].ExDate.Hour() != 12 {
+			t.Errorf("unexpected dividends %+v", d)
+		}
+
+		latency := cfg.Broker.Latency.model().Latency("EUR_USD", time.Time{})
+		if latency != 20*time.Millisecond && latency != 35*time.Millisecond {
+			t.Errorf("expected a latency of the venue samples, got %v", latency)
+		}
+
+		profile, err := cfg.Instruments[0].Spread.profile(-4)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rollover := time.Date(2024, 1, 2, 22, 0, 0, 0, time.UTC)
+		if s := profile.Spread("EUR_USD", 1.1, rollover); math.Abs(s-0.0005) > 1e-12 {
+			t.Errorf("expected the spread of the period in pips, got %v", s)
+		}
+
+		if s := profile.Spread("EUR_USD", 1.1, rollover.Add(time.Hour)); math.Abs(s-0.0001) > 1e-12 {
+			t.Errorf("expected the spread outside the periods, got %v", s)
+		}
+
+		if len(cfg.StrategyOptions("trend")) != 3 || cfg.StrategyOptions("other") != nil {
+			t.Error("unexpected strategy options")
+		}
+
+		if r := cfg.Strategies["breakout"].Rules; r == nil || r.Timeframe != time.Hour || r.EntryLong != "close > sma(20)" ||
+			len(cfg.StrategyOptions("breakout")) != 3 {
+			t.Errorf("expected the rules to be decoded with the candles of their timeframe, got %+v", r)
+		}
+
+		if _, err := cfg.NewSession(gotrader.CollectStats()); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("fee schedules are reloaded", func(t *testing.T) {
+
+		path := filepath.Join(t.TempDir(), "session.yaml")
+		if err := os.WriteFile(path, []byte(example), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		schedule := cfg.Schedule()
+		if _, marked := schedule.Markup("EUR_USD"); marked {
+			t.Error("expected no markup")
+		}
+
+		reloaded := strings.Replace(example, "perUnit: 0.01}", "perUnit: 0.01, tiers: [{units: 1000, perUnit: 0.005}]}\n  slippage: {fixed: 0.0001}", 1)
+		reloaded = strings.Replace(reloaded, "  flatten:", "  markup: {pips: 0.5}\n  flatten:", 1)
+		if err := os.WriteFile(path, []byte(reloaded), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := schedule.Reload(path); err != nil {
+			t.Fatal(err)
+		}
+
+		if c := schedule.Commission("EUR_USD", 100, 1.1); c != 1 {
+			t.Errorf("expected the base commission below the tiers, got %v", c)
+		}
+
+		if c := schedule.Commission("EUR_USD", 2000, 1.1); c != 10 {
+			t.Errorf("expected the tier commission, got %v", c)
+		}
+
+		if markup, marked := schedule.Markup("EUR_USD"); !marked || markup.Pips != 0.5 {
+			t.Errorf("expected the reloaded markup, got %+v", markup)
+		}
+
+		if err := os.WriteFile(path, []byte("fees: ["), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := schedule.Reload(path); err == nil || schedule.Slippage("EUR_USD", gotrader.Long, 1, 1, 1) != 0.0001 {
+			t.Error("expected an invalid file to keep the fees")
+		}
+	})
+
+	t.Run("symbols are mapped to the instruments", func(t *testing.T) {
+
+		cfg, err := Parse([]byte(example + `
+symbols:
+  fix: {separator: /}
+  binance: {separator: "", lower: true}
+  cme: {aliases: {6E: EUR_USD}}
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		symbols := cfg.SymbolMap()
+
+		for _, symbol := range []struct{ venue, symbol string }{
+			{"fix", "EUR/USD"}, {"binance", "eurusd"}, {"cme", "6E"}, {"oanda", "EUR_USD"}, {"ib", "EUR.USD"},
+		} {
+			if name := symbols.Canonical(symbol.venue, symbol.symbol); name != "EUR_USD" {
+				t.Errorf("expected %s of %s mapped to EUR_USD, got %s", symbol.symbol, symbol.venue, name)
+			}
+		}
+
+		if symbol := symbols.Symbol("fix", "EUR_USD"); symbol != "EUR/USD" {
+			t.Errorf("expected the fix symbol EUR/USD, got %s", symbol)
+		}
+
+		if symbol := symbols.Symbol("binance", "EUR_USD"); symbol != "eurusd" {
+			t.Errorf("expected the binance symbol eurusd, got %s", symbol)
+		}
+
+		if symbol := symbols.Symbol("cme", "EUR_USD"); symbol != "6E" {
+			t.Errorf("expected the cme alias 6E, got %s", symbol)
+		}
+
+		if name := symbols.Canonical("alpaca", "spy"); name != "SPY" {
+			t.Errorf("expected SPY, got %s", name)
+		}
+	})
+
+	t.Run("invalid configurations are rejected", func(t *testing.T) {
+
+		invalid := map[string]string{
+			"unknown keys":             strings.Replace(example, "staleAfter", "staleafter", 1),
+			"unknown hedge":            strings.Replace(example, "hedge: none", "hedge: some", 1),
+			"unknown instrument hedge": strings.Replace(example, "    hedge: none", "    hedge: some", 1),
+			"unknown broker":           strings.Replace(example, "type: btrand", "type: other", 1),
+			"csv without data":         strings.Replace(example, "type: btrand", "type: csv", 1),
+			"invalid hours":            strings.Replace(example, `"16:00"`, `"4pm"`, 1),
+			"invalid holiday":          strings.Replace(example, "2024-01-15", "15/01/2024", 1),
+			"unknown instruments":      strings.Replace(example, "instruments: [EUR_USD]", "instruments: [GBP_USD]", 1),
+			"several commissions":      strings.Replace(example, "perUnit: 0.01", "perUnit: 0.01, percent: 0.1", 1),
+			"decreasing tiers":         strings.Replace(example, "perUnit: 0.01", "tiers: [{units: 10}, {units: 5}]", 1),
+			"dividend without date":    strings.Replace(example, "exDate: 2024-01-02T12:00:00Z, ", "", 1),
+			"negative flatten":         strings.Replace(example, "before: 10m", "before: -10m", 1),
+			"health without degraded":  strings.Replace(example, "flatten: {before: 10m}", "flatten: {before: 10m}\n  health: {unhealthy: 30s}", 1),
+			"swaps and rates":          strings.Replace(example, "rates:", "swaps: {SPY: {long: -0.01}}\n  rates:", 1),
+			"invalid spread period":    strings.Replace(example, `"23:00"`, `"11pm"`, 1),
+			"negative spread":          strings.Replace(example, "pips: 5", "pips: -5", 1),
+			"several latencies":        strings.Replace(example, "samples: [20ms, 35ms]", "samples: [20ms, 35ms], fixed: 10ms", 1),
+			"invalid rules":            strings.Replace(example, "close > sma(20)", "close > sma(0)", 1),
+			"plugin and rules":         strings.Replace(example, "    rules:", "    plugin: breakout.so\n    rules:", 1),
+		}
+
+		for name, data := range invalid {
+			if _, err := Parse([]byte(data)); err == nil {
+				t.Errorf("expected an error for %s", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+/*
+Schedule is the fee schedule of a configuration: the commissions and slippages of the paper broker, the financing
+of the backtests and the markups of the quotes, by instrument. The sessions built from the configuration query it
+for every fill, rollover and tick, so Update, Reload and Watch change the fees of a running session without
+restarting it: the new fees apply from the next calculation on, the fees already charged are kept.
+
+	cfg, err := config.Load("session.yaml")
+	...
+	session, err := cfg.NewSession(gotrader.Strategy(strategy))
+	...
+	err = cfg.Schedule().Watch("session.yaml", time.Minute, func(err error) { log.Println(err) })
+*/
+type Schedule struct {
+	mutex     *sync.RWMutex
+	fees      Fees
+	overrides map[string]Fees // by instrument
+	financing gotrader.FinancingModel
+	markup    *Markup
+	markups   map[string]Markup // by instrument
+	done      chan struct{}
+}
+
+func newSchedule(c *Config) *Schedule {
+
+	s := &Schedule{mutex: &sync.RWMutex{}}
+	s.update(c)
+
+	return s
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// update replaces the fees with the ones of the configuration, with the lock held.
+func (s *Schedule) update(c *Config) {
+
+	s.fees = c.Fees
+	s.overrides = make(map[string]Fees)
+	s.financing = c.Financing.model()
+	s.markup = c.Session.Markup
+	s.markups = make(map[string]Markup)
+
+	for _, inst := range c.Instruments {
+
+		if inst.Fees != nil {
+			s.overrides[inst.Name] = *inst.Fees
+		}
+
+		if inst.Markup != nil {
+			s.markups[inst.Name] = *inst.Markup
+		}
+	}
+}
+
+// feesOf returns the fees of an instrument.
+func (s *Schedule) feesOf(instrument string) Fees {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if fees, exist := s.overrides[instrument]; exist {
+		return fees
+	}
+
+	return s.fees
+}
+
+// model returns the commission model of a fill of the units.
+func (c Commission) model(units int32) gotrader.CommissionModel {
+
+	perUnit, percent := c.PerUnit, c.Percent
+
+	for _, tier := range c.Tiers {
+		if units >= tier.Units {
+			perUnit, percent = tier.PerUnit, tier.Percent
+		}
+	}
+
+	if percent != 0 {
+		return gotrader.PercentCommission(percent)
+	}
+
+	return gotrader.PerUnitCommission(perUnit)
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Schedule returns the fee schedule of the configuration, the one of the sessions and clients it builds.
+func (c *Config) Schedule() *Schedule {
+
+	if c.schedule == nil {
+		c.schedule = newSchedule(c)
+	}
+
+	return c.schedule
+}
+
+// Update replaces the fees, financing and markups of the schedule with the ones of a configuration, e.g. the
+// configuration file edited. The other settings of the configuration are ignored.
+func (s *Schedule) Update(c *Config) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.update(c)
+}
+
+// Reload updates the schedule from the configuration file at path, it is left as is when the file is invalid.
+func (s *Schedule) Reload(path string) error {
+
+	cfg, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	s.Update(cfg)
+
+	return nil
+}
+
+// Watch reloads the schedule from the configuration file at path whenever it is modified, checking it at every
+// interval until Stop is called. The errors of the reloads are passed to onError, which may be nil.
+// The interval defaults to 10 seconds.
+func (s *Schedule) Watch(path string, interval time.Duration, onError func(error)) error {
+
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	modified := info.ModTime()
+	done := make(chan struct{})
+
+	s.mutex.Lock()
+	if s.done != nil {
+		close(s.done)
+	}
+	s.done = done
+	s.mutex.Unlock()
+
+	go func() {
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err == nil && info.ModTime().Equal(modified) {
+				continue
+			}
+
+			if err == nil {
+				modified = info.ModTime()
+				err = s.Reload(path)
+			}
+
+			if err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops watching the configuration file, the fees are kept.
+func (s *Schedule) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+}
+
+// Commission implements gotrader.CommissionModel.
+func (s *Schedule) Commission(instrument string, units int32, price float64) float64 {
+	return s.feesOf(instrument).Commission.model(units).Commission(instrument, units, price)
+}
+
+// Slippage implements gotrader.SlippageModel.
+func (s *Schedule) Slippage(instrument string, side gotrader.Side, units int32, bid, ask float64) float64 {
+
+	fees := s.feesOf(instrument)
+
+	if fees.Slippage.Spread != 0 {
+		return gotrader.ProportionalSlippage(fees.Slippage.Spread).Slippage(instrument, side, units, bid, ask)
+	}
+
+	return gotrader.FixedSlippage(fees.Slippage.Fixed).Slippage(instrument, side, units, bid, ask)
+}
+
+// Financing implements gotrader.FinancingModel, the trades are not financed without swaps nor rates.
+func (s *Schedule) Financing(instrument gotrader.InstrumentDetails, side gotrader.Side, units int32, price float64,
+	t time.Time) float64 {
+
+	s.mutex.RLock()
+	financing := s.financing
+	s.mutex.RUnlock()
+
+	if financing == nil {
+		return 0
+	}
+
+	return financing.Financing(instrument, side, units, price, t)
+}
+
+// Markup implements gotrader.MarkupModel, the instrument markups replace the session one.
+func (s *Schedule) Markup(instrument string) (gotrader.PriceMarkup, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if markup, exist := s.markups[instrument]; exist {
+		return gotrader.PriceMarkup(markup), true
+	}
+
+	if s.markup != nil {
+		return gotrader.PriceMarkup(*s.markup), true
+	}
+
+	return gotrader.PriceMarkup{}, false
+}
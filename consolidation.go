@@ -0,0 +1,177 @@
+package gotrader
+
+import (
+	"sync"
+	"time"
+)
+
+// BrokerFeed is the name of the prices of the client of the session in a consolidation, see Consolidate.
+const BrokerFeed = "broker"
+
+// NamedFeed is a price source of a consolidation, its name attributes the quotes of the consolidated ticks.
+type NamedFeed struct {
+	Name        string
+	Feed        PriceFeed
+	Instruments []string // every instrument subscribed when empty
+}
+
+// ConsolidatedQuote is the best bid and ask of an instrument across the feeds of a consolidation.
+type ConsolidatedQuote struct {
+	Time      time.Time
+	Bid       float64
+	Ask       float64
+	BidSize   float64
+	AskSize   float64
+	BidSource string
+	AskSource string
+}
+
+/*
+Consolidate is the functional option to value the instruments of the live engine at the best bid and ask quoted by
+the client of the session, named BrokerFeed, and the feeds: every tick of a feed updates its quote of the instrument
+and the engine receives the consolidated tick of the instrument, carrying the feeds quoting its bid and its ask. The
+quotes older than maxAge are left out (none when zero), and a crossed consolidation keeps the quote of the tick. The
+orders are still sent to the client, so the fills may differ from the consolidated prices. The backtests ignore the
+consolidation.
+*/
+func Consolidate(maxAge time.Duration, feeds ...NamedFeed) Option {
+	return func(p *sessionParameters) {
+		p.consolidation = &consolidation{
+			mutex:  &sync.Mutex{},
+			maxAge: maxAge,
+			feeds:  feeds,
+			quotes: make(map[string]map[string]*sourceQuote),
+			best:   make(map[string]ConsolidatedQuote),
+		}
+	}
+}
+
+// sourceQuote is the last quote of an instrument by a feed.
+type sourceQuote struct {
+	bid     float64
+	ask     float64
+	bidSize float64
+	askSize float64
+	arrival time.Time
+}
+
+// consolidation consolidates the quotes of the feeds, it is nil without the Consolidate option.
+type consolidation struct {
+	mutex  *sync.Mutex
+	maxAge time.Duration
+	feeds  []NamedFeed
+	quotes map[string]map[string]*sourceQuote // by instrument and feed
+	best   map[string]ConsolidatedQuote
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// update records the quote of a tick of the source arrived at now, replacing it by the consolidated one.
+func (c *consolidation) update(source string, tick *Tick, now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	quotes, exist := c.quotes[tick.Instrument]
+	if !exist {
+		quotes = make(map[string]*sourceQuote)
+		c.quotes[tick.Instrument] = quotes
+	}
+
+	quotes[source] = &sourceQuote{bid: tick.Bid, ask: tick.Ask, bidSize: tick.BidSize, askSize: tick.AskSize,
+		arrival: now}
+
+	best := ConsolidatedQuote{Time: tick.Time, Bid: tick.Bid, Ask: tick.Ask, BidSize: tick.BidSize,
+		AskSize: tick.AskSize, BidSource: source, AskSource: source}
+
+	for name, q := range quotes {
+
+		if name == source || c.maxAge > 0 && now.Sub(q.arrival) > c.maxAge {
+			continue
+		}
+
+		if q.bid > best.Bid {
+			best.Bid, best.BidSize, best.BidSource = q.bid, q.bidSize, name
+		}
+
+		if q.ask < best.Ask {
+			best.Ask, best.AskSize, best.AskSource = q.ask, q.askSize, name
+		}
+	}
+
+	if best.Bid >= best.Ask {
+		best = ConsolidatedQuote{Time: tick.Time, Bid: tick.Bid, Ask: tick.Ask, BidSize: tick.BidSize,
+			AskSize: tick.AskSize, BidSource: source, AskSource: source}
+	}
+
+	c.best[tick.Instrument] = best
+
+	tick.Bid, tick.Ask, tick.BidSize, tick.AskSize = best.Bid, best.Ask, best.BidSize, best.AskSize
+	tick.BidSource, tick.AskSource = best.BidSource, best.AskSource
+	tick.Sequence = 0 // the sequences of the feeds are not comparable
+}
+
+// handler returns the tick handler consolidating the ticks of the source before the callback, the callback itself
+// without consolidation.
+func (c *consolidation) handler(source string, clock Clock, callback TickHandler) TickHandler {
+
+	if c == nil {
+		return callback
+	}
+
+	return func(tick *Tick) {
+		c.update(source, tick, clock.Now())
+		callback(tick)
+	}
+}
+
+// subscribe subscribes the feeds of the consolidation to the prices of their instruments.
+func (c *consolidation) subscribe(accountID string, details []InstrumentDetails, clock Clock,
+	callback TickHandler) error {
+
+	if c == nil {
+		return nil
+	}
+
+	for _, feed := range c.feeds {
+
+		instruments := details
+		if len(feed.Instruments) > 0 {
+			instruments = make([]InstrumentDetails, 0, len(feed.Instruments))
+			for _, d := range details {
+				for _, name := range feed.Instruments {
+					if d.Name == name {
+						instruments = append(instruments, d)
+					}
+				}
+			}
+		}
+
+		if err := feed.Feed.SubscribePrices(accountID, instruments, c.handler(feed.Name, clock, callback)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *consolidation) snapshot() map[string]ConsolidatedQuote {
+
+	snapshot := make(map[string]ConsolidatedQuote)
+
+	if c == nil {
+		return snapshot
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for instrument, q := range c.best {
+		snapshot[instrument] = q
+	}
+
+	return snapshot
+}
@@ -0,0 +1,308 @@
+package gotrader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// CorporateActionType identifies the type of a CorporateAction.
+type CorporateActionType int
+
+const (
+	StockSplit   CorporateActionType = iota // Ratio new shares for every share, a reverse split below one
+	TickerChange                            // the instrument is renamed to NewInstrument
+)
+
+func (t CorporateActionType) String() string {
+	switch t {
+	case StockSplit:
+		return "STOCK_SPLIT"
+	case TickerChange:
+		return "TICKER_CHANGE"
+	}
+
+	return "UNKNOWN"
+}
+
+// CorporateAction is a split or a ticker change of an equity or CFD instrument, e.g. a 4 for 1 split is a
+// StockSplit with Ratio 4.
+type CorporateAction struct {
+	Type          CorporateActionType
+	Instrument    string
+	Ratio         float64
+	NewInstrument string
+	Time          time.Time
+}
+
+type CorporateActionHandler func(action *CorporateAction)
+
+/*
+CorporateActionNotifier is implemented by clients with a corporate actions feed. The sessions adjust the open
+trades and the pending orders of the instruments to the actions, between the ticks:
+
+  - a split multiplies the units of the trades by its ratio, rounded, and divides their open price, stop loss and
+    take profit and the current prices of the instrument, so the cost of the trades and their profit are
+    unchanged.
+  - a ticker change renames the instrument, its trades and its pending orders, the ticks of the instrument are
+    expected with its new name.
+*/
+type CorporateActionNotifier interface {
+	SubscribeCorporateActions(accountID string, callback CorporateActionHandler) error
+}
+
+// CorporateActionApplied is published when a corporate action adjusted an instrument, with the trades adjusted.
+type CorporateActionApplied struct {
+	Time   time.Time
+	Action CorporateAction
+	Trades []string
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (a *CorporateAction) validate() error {
+
+	switch a.Type {
+	case StockSplit:
+		if a.Ratio <= 0 || math.IsInf(a.Ratio, 0) || math.IsNaN(a.Ratio) {
+			return fmt.Errorf("%s split: invalid ratio %v", a.Instrument, a.Ratio)
+		}
+	case TickerChange:
+		if a.NewInstrument == "" || a.NewInstrument == a.Instrument {
+			return fmt.Errorf("%s ticker change: invalid new instrument %q", a.Instrument, a.NewInstrument)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported corporate action %s", a.Instrument, a.Type)
+	}
+
+	return nil
+}
+
+// splitUnits returns the units after a split, rounded and at least one.
+func splitUnits(units int32, ratio float64) int32 {
+	return int32(math.Max(1, math.Round(float64(units)*ratio)))
+}
+
+// splitTrade returns the WAL entry of a trade adjusted to a split, the open price keeps the cost of the trade.
+func splitTrade(trade *Trade, ratio float64, t time.Time) *WALEntry {
+
+	units := splitUnits(trade.units, ratio)
+
+	return &WALEntry{
+		Operation:  WALAdjustTrade,
+		Time:       t,
+		Instrument: trade.instrumentName,
+		TradeID:    trade.id,
+		Units:      units,
+		Price:      trade.openPrice * float64(trade.units) / float64(units),
+		StopLoss:   trade.stopLoss / ratio,
+		TakeProfit: trade.takeProfit / ratio,
+	}
+}
+
+// adjustTrade changes the units and the prices of a trade, returning false when it does not exist.
+func (i *Instrument) adjustTrade(id string, units int32, openPrice, stopLoss, takeProfit float64) bool {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	trade, exist := i.trades.Get(id)
+	if !exist {
+		return false
+	}
+
+	position := i.longPosition
+	if trade.side == Short {
+		position = i.shortPosition
+	}
+
+	position.units.Add(units - trade.units)
+	trade.units = units
+	trade.openPrice = openPrice
+	trade.stopLoss = stopLoss
+	trade.takeProfit = takeProfit
+	i.touch()
+
+	i.unrealized()
+	i.margin()
+
+	return true
+}
+
+// scalePrices divides the current prices of the instrument, so its trades are valued at the split prices until
+// the next tick.
+func (i *Instrument) scalePrices(ratio float64) {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	i.bid.Store(i.bid.Load() / ratio)
+	i.ask.Store(i.ask.Load() / ratio)
+	i.touch()
+}
+
+// rename changes the name of the instrument and of its trades.
+func (i *Instrument) rename(name string) []*Trade {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	i.name = name
+
+	trades := make([]*Trade, 0, i.tradesNumber.Load())
+	for _, position := range []*Position{i.longPosition, i.shortPosition} {
+		for _, trade := range position.list() {
+			trade.instrumentName = name
+			trades = append(trades, trade)
+		}
+	}
+
+	return trades
+}
+
+// rename re-keys an instrument, its conversion rates and the conversion functions depending on it.
+func (ce *currencyConversionEngine) rename(from, to string) {
+
+	if inst, exist := ce.conversionInstruments[from]; exist {
+		inst.Name = to
+		ce.conversionInstruments[to] = inst
+		delete(ce.conversionInstruments, from)
+	}
+
+	if ce.conversionSet[from] {
+		ce.conversionSet[to] = true
+		delete(ce.conversionSet, from)
+	}
+
+	for i, details := range ce.conversionInstrumentsDetails {
+		if details.Name == from {
+			ce.conversionInstrumentsDetails[i].Name = to
+		}
+	}
+
+	for _, dependents := range []map[string]map[string]bool{ce.dependentBaseInstruments, ce.dependentQuoteInstruments} {
+
+		if set, exist := dependents[from]; exist {
+			dependents[to] = set
+			delete(dependents, from)
+		}
+
+		for _, set := range dependents {
+			if set[from] {
+				set[to] = true
+				delete(set, from)
+			}
+		}
+	}
+
+	for _, inst := range ce.conversionInstruments {
+		for _, function := range [][]string{inst.BaseConversionFunction, inst.QuoteConversionFunction} {
+			for i, term := range function {
+				if term == from {
+					function[i] = to
+				}
+			}
+		}
+	}
+}
+
+// adjust applies a function to the pending orders of an instrument.
+func (b *orderBook) adjust(instrument string, f func(order *Order)) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, order := range b.orders {
+		if order.Instrument == instrument {
+			f(order)
+		}
+	}
+}
+
+/*
+applyCorporateAction adjusts the account and the pending orders to a corporate action, logging the adjusted
+trades, and returns the event to publish. It must be called by the goroutine processing the ticks, a ticker
+change replaces the instruments map of the account.
+*/
+func applyCorporateAction(
+	account *Account,
+	conversion *currencyConversionEngine,
+	orders *orderBook,
+	action *CorporateAction,
+	logger Logger,
+) (CorporateActionApplied, error) {
+
+	event := CorporateActionApplied{Time: action.Time, Action: *action}
+
+	if err := action.validate(); err != nil {
+		return event, err
+	}
+
+	inst, exist := account.instruments[action.Instrument]
+	if !exist {
+		return event, fmt.Errorf("%s: %w", action.Instrument, ErrInstrumentNotTraded)
+	}
+
+	var trades []*Trade
+
+	switch action.Type {
+	case StockSplit:
+		inst.scalePrices(action.Ratio)
+
+		for _, position := range []*Position{inst.longPosition, inst.shortPosition} {
+			for _, trade := range position.list() {
+
+				entry := splitTrade(trade, action.Ratio, action.Time)
+				account.wal.write(entry, logger)
+
+				inst.adjustTrade(trade.id, entry.Units, entry.Price, entry.StopLoss, entry.TakeProfit)
+				trades = append(trades, trade)
+			}
+		}
+
+		orders.adjust(action.Instrument, func(o *Order) {
+			o.Units = splitUnits(o.Units, action.Ratio)
+			o.Price /= action.Ratio
+			o.StopLoss /= action.Ratio
+			o.TakeProfit /= action.Ratio
+		})
+	case TickerChange:
+		if _, exist := account.instruments[action.NewInstrument]; exist {
+			return event, errors.New("ticker change: instrument " + action.NewInstrument + " already traded")
+		}
+
+		account.wal.write(&WALEntry{
+			Operation:  WALRenameInstrument,
+			Time:       action.Time,
+			Instrument: action.NewInstrument,
+			Renamed:    action.Instrument,
+		}, logger)
+
+		trades = inst.rename(action.NewInstrument)
+		conversion.rename(action.Instrument, action.NewInstrument)
+
+		orders.adjust(action.Instrument, func(o *Order) {
+			o.Instrument = action.NewInstrument
+		})
+
+		instruments := make(map[string]*Instrument, len(account.instruments))
+		for name, instrument := range account.instruments {
+			instruments[name] = instrument
+		}
+		delete(instruments, action.Instrument)
+		instruments[action.NewInstrument] = inst
+
+		account.lock.Lock()
+		account.instruments = instruments
+		account.lock.Unlock()
+		conversion.instruments = instruments
+	}
+
+	for _, trade := range trades {
+		event.Trades = append(event.Trades, trade.id)
+		account.events.publish(TradeUpdated{Time: action.Time, Trade: trade, Update: CorporateActionUpdate})
+	}
+
+	return event, nil
+}
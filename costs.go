@@ -0,0 +1,84 @@
+package gotrader
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// SlippageModel returns the price adjustment, always adverse to the order side, applied to a fill of the
+// given size at the current bid/ask.
+type SlippageModel interface {
+	Slippage(instrument string, side Side, units int32, bid, ask float64) float64
+}
+
+// CommissionModel returns the commission, in home currency, charged for a fill of the given size and price.
+type CommissionModel interface {
+	Commission(instrument string, units int32, price float64) float64
+}
+
+// FixedSlippage is a slippage model with a constant price adjustment.
+type FixedSlippage float64
+
+// Slippage implements SlippageModel.
+func (s FixedSlippage) Slippage(instrument string, side Side, units int32, bid, ask float64) float64 {
+	return float64(s)
+}
+
+// ProportionalSlippage is a slippage model with a price adjustment proportional to the spread,
+// e.g. 0.5 slips half a spread on every fill.
+type ProportionalSlippage float64
+
+// Slippage implements SlippageModel.
+func (s ProportionalSlippage) Slippage(instrument string, side Side, units int32, bid, ask float64) float64 {
+	return float64(s) * (ask - bid)
+}
+
+// RandomSlippage is a seeded slippage model with a price adjustment uniformly drawn between zero and a fraction
+// of the spread, the same seed and sequence of fills slip the same. It is safe for concurrent use.
+type RandomSlippage struct {
+	fraction float64
+	mutex    *sync.Mutex
+	rand     *rand.Rand
+}
+
+// NewRandomSlippage is the RandomSlippage constructor, e.g. 0.5 slips up to half a spread on every fill.
+func NewRandomSlippage(fraction float64, seed int64) *RandomSlippage {
+	return &RandomSlippage{
+		fraction: fraction,
+		mutex:    &sync.Mutex{},
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Slippage implements SlippageModel.
+func (s *RandomSlippage) Slippage(instrument string, side Side, units int32, bid, ask float64) float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.rand.Float64() * s.fraction * (ask - bid)
+}
+
+// GuaranteedStopPremium is the functional option to define the premium charged to the trades opened with a
+// guaranteed stop loss by the backtest engine, as a fee of the trade when it is opened. No premium is charged
+// by default.
+func GuaranteedStopPremium(model CommissionModel) Option {
+	return func(p *sessionParameters) {
+		p.guaranteedStopPremium = model
+	}
+}
+
+// PerUnitCommission is a commission model charging a fixed amount per traded unit.
+type PerUnitCommission float64
+
+// Commission implements CommissionModel.
+func (c PerUnitCommission) Commission(instrument string, units int32, price float64) float64 {
+	return float64(c) * float64(units)
+}
+
+// PercentCommission is a commission model charging a fraction of the traded notional, e.g. 0.001 for 10bps.
+type PercentCommission float64
+
+// Commission implements CommissionModel.
+func (c PercentCommission) Commission(instrument string, units int32, price float64) float64 {
+	return float64(c) * float64(units) * price
+}
@@ -0,0 +1,194 @@
+package gotrader
+
+import (
+	"sync"
+	"time"
+)
+
+// DayStats are the profit and the activity of the account in a trading day, see the DayBoundary option. The
+// amounts are in the home currency.
+type DayStats struct {
+	Start            time.Time
+	End              time.Time
+	RealizedProfit   float64 // of the trade close, financing and dividend transactions of the day
+	UnrealizedProfit float64 // of the open trades at the end of the day, at the last tick for the current day
+	UnrealizedChange float64 // since the start of the day
+	Fees             float64 // of the trades closed in the day
+	Opened           int     // trades opened in the day
+	Closed           int     // trades closed in the day
+}
+
+// PnL returns the profit of the day, realized and unrealized.
+func (d DayStats) PnL() float64 {
+	return d.RealizedProfit + d.UnrealizedChange
+}
+
+// DayBoundary is the functional option to define the start of the trading days of the daily statistics, as an
+// offset from midnight in the location, e.g. 17 hours in New York for FX. The days start at midnight UTC by
+// default.
+func DayBoundary(offset time.Duration, location *time.Location) Option {
+	return func(p *sessionParameters) {
+		p.dayOffset = offset
+		p.dayLocation = location
+	}
+}
+
+// dayMark is the unrealized profit of the open trades at the start and at the last tick of a day.
+type dayMark struct {
+	start float64
+	end   float64
+}
+
+// dailyMarks marks the unrealized profit of the trading days on the ticks, the realized values of the days are
+// read from the ledger. The first day of a session starts at its first tick.
+type dailyMarks struct {
+	mutex    *sync.RWMutex
+	offset   time.Duration
+	location *time.Location
+	current  time.Time // start of the day of the last mark
+	marks    map[time.Time]*dayMark
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newDailyMarks(offset time.Duration, location *time.Location) *dailyMarks {
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	return &dailyMarks{
+		mutex:    &sync.RWMutex{},
+		offset:   offset,
+		location: location,
+		marks:    make(map[time.Time]*dayMark),
+	}
+}
+
+// dayStart returns the start of the trading day of t.
+func (d *dailyMarks) dayStart(t time.Time) time.Time {
+
+	local := t.In(d.location)
+	start := localTime(local, d.offset, d.location)
+
+	for start.After(local) {
+		start = start.AddDate(0, 0, -1)
+	}
+
+	for !start.AddDate(0, 0, 1).After(local) {
+		start = start.AddDate(0, 0, 1)
+	}
+
+	return start
+}
+
+// mark records the unrealized profit of the account at t, a new day starts at the last mark of the previous one.
+func (d *dailyMarks) mark(t time.Time, unrealized float64) {
+
+	if t.IsZero() {
+		return
+	}
+
+	day := d.dayStart(t)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if mark, exist := d.marks[day]; exist {
+		mark.end = unrealized
+		return
+	}
+
+	start := unrealized
+	if previous, exist := d.marks[d.current]; exist && d.current.Before(day) {
+		start = previous.end
+	}
+
+	d.marks[day] = &dayMark{start: start, end: unrealized}
+	if day.After(d.current) {
+		d.current = day
+	}
+}
+
+func (d *dailyMarks) markOf(day time.Time) (dayMark, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	mark, exist := d.marks[day]
+	if !exist {
+		return dayMark{}, false
+	}
+
+	return *mark, true
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// DailyStats returns the statistics of the trading day of the date, see the DayBoundary option. The unrealized
+// profit of the days without ticks is zero.
+func (a *Account) DailyStats(date time.Time) DayStats {
+
+	start := a.daily.dayStart(date)
+	stats := DayStats{Start: start, End: start.AddDate(0, 0, 1)}
+
+	within := func(t time.Time) bool {
+		return !t.Before(stats.Start) && t.Before(stats.End)
+	}
+
+	var realized, fees Decimal
+
+	for _, t := range a.ledger.Transactions() {
+
+		if t.Type == TradeCloseTransaction && within(t.OpenTime) {
+			stats.Opened++
+		}
+
+		if !within(t.Time) || t.Type.External() {
+			continue
+		}
+
+		realized = realized.Add(NewDecimal(t.Amount))
+
+		if t.Type == TradeCloseTransaction {
+			fees = fees.Add(NewDecimal(t.Fees))
+			stats.Closed++
+		}
+	}
+
+	for _, inst := range a.Instruments() {
+		for _, trade := range inst.trades.Values() {
+			if within(trade.OpenTime()) {
+				stats.Opened++
+			}
+		}
+	}
+
+	stats.RealizedProfit, stats.Fees = realized.Float64(), fees.Float64()
+
+	if mark, exist := a.daily.markOf(start); exist {
+		stats.UnrealizedProfit = mark.end
+		stats.UnrealizedChange = NewDecimal(mark.end).Sub(NewDecimal(mark.start)).Float64()
+	}
+
+	return stats
+}
+
+// TodayPnL returns the profit, realized and unrealized, of the current trading day of the account, at its last
+// tick.
+func (a *Account) TodayPnL() float64 {
+
+	now := a.Time()
+	if now.IsZero() {
+		return 0
+	}
+
+	return a.DailyStats(now).PnL()
+}
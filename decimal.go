@@ -0,0 +1,274 @@
+package gotrader
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"go.uber.org/atomic"
+)
+
+// DecimalPlaces is the number of decimal places of a Decimal.
+const DecimalPlaces = 8
+
+const decimalScale = 100000000 // 10^DecimalPlaces
+
+/*
+Decimal is a fixed-point decimal number with DecimalPlaces decimal places, ranging about ±92 billion.
+Profits, fees, margins and balances are accumulated as Decimal so thousands of additions don't drift as float64
+sums do, the float64 accessors return the exact decimal value rounded to the nearest float64.
+
+Prices and conversion rates are market inputs and stay float64, they are converted when they are multiplied
+into an amount, with a single rounding.
+*/
+type Decimal int64
+
+// NewDecimal returns the decimal nearest to f, halves are rounded away from zero. Values out of range saturate
+// and NaN is zero.
+func NewDecimal(f float64) Decimal {
+
+	scaled := math.Round(f * decimalScale)
+
+	switch {
+	case math.IsNaN(scaled):
+		return 0
+	case scaled >= math.MaxInt64:
+		return math.MaxInt64
+	case scaled <= math.MinInt64:
+		return math.MinInt64
+	}
+
+	return Decimal(scaled)
+}
+
+// DecimalFromInt returns the decimal of the integer n.
+func DecimalFromInt(n int64) Decimal {
+	return Decimal(n * decimalScale)
+}
+
+// ParseDecimal parses a decimal string, e.g. "-1234.5678", extra decimal places are rounded half away from zero.
+func ParseDecimal(s string) (Decimal, error) {
+
+	text := strings.TrimSpace(s)
+	negative := strings.HasPrefix(text, "-")
+	if negative || strings.HasPrefix(text, "+") {
+		text = text[1:]
+	}
+
+	integer, fraction := text, ""
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		integer, fraction = text[:i], text[i+1:]
+	}
+
+	if integer == "" && fraction == "" {
+		return 0, errors.New("invalid decimal " + strconv.Quote(s))
+	}
+
+	for _, c := range integer + fraction {
+		if c < '0' || c > '9' {
+			return 0, errors.New("invalid decimal " + strconv.Quote(s))
+		}
+	}
+
+	roundUp := false
+	if len(fraction) > DecimalPlaces {
+		roundUp = fraction[DecimalPlaces] >= '5'
+		fraction = fraction[:DecimalPlaces]
+	}
+	fraction += strings.Repeat("0", DecimalPlaces-len(fraction))
+
+	value, err := strconv.ParseInt(integer+fraction, 10, 64)
+	if err != nil || (roundUp && value == math.MaxInt64) {
+		return 0, errors.New("decimal out of range " + strconv.Quote(s))
+	}
+
+	if roundUp {
+		value++
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return Decimal(value), nil
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// mulDiv returns a * b / c rounded half away from zero, with a 128 bits intermediate product.
+func mulDiv(a, b, c int64) int64 {
+
+	negative := (a < 0) != (b < 0) != (c < 0)
+
+	hi, lo := bits.Mul64(abs64(a), abs64(b))
+	divisor := abs64(c)
+
+	saturated := int64(math.MaxInt64) // the quotient overflows, saturates like a float64 conversion would
+	if negative {
+		saturated = math.MinInt64
+	}
+
+	if hi >= divisor {
+		return saturated
+	}
+
+	quotient, remainder := bits.Div64(hi, lo, divisor)
+	if quotient > math.MaxInt64 {
+		return saturated
+	}
+
+	if remainder >= divisor-remainder {
+		quotient++
+	}
+
+	switch {
+	case quotient > math.MaxInt64: // -2^63 is MinInt64, also the saturation
+		return saturated
+	case negative:
+		return -int64(quotient)
+	}
+
+	return int64(quotient)
+}
+
+func abs64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-n)
+	}
+	return uint64(n)
+}
+
+// atomicDecimal is a Decimal accessed atomically.
+type atomicDecimal struct {
+	v *atomic.Int64
+}
+
+func newAtomicDecimal(d Decimal) *atomicDecimal {
+	return &atomicDecimal{v: atomic.NewInt64(int64(d))}
+}
+
+func (a *atomicDecimal) Load() Decimal {
+	return Decimal(a.v.Load())
+}
+
+func (a *atomicDecimal) Store(d Decimal) {
+	a.v.Store(int64(d))
+}
+
+// Add adds the delta and returns the new value.
+func (a *atomicDecimal) Add(delta Decimal) Decimal {
+	return Decimal(a.v.Add(int64(delta)))
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Float64 returns the float64 nearest to the decimal.
+func (d Decimal) Float64() float64 {
+	return float64(d) / decimalScale
+}
+
+// Add returns d + o.
+func (d Decimal) Add(o Decimal) Decimal {
+	return d + o
+}
+
+// Sub returns d - o.
+func (d Decimal) Sub(o Decimal) Decimal {
+	return d - o
+}
+
+// Neg returns -d.
+func (d Decimal) Neg() Decimal {
+	return -d
+}
+
+// Abs returns the absolute value of d.
+func (d Decimal) Abs() Decimal {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Sign returns -1, 0 or 1 as d is negative, zero or positive.
+func (d Decimal) Sign() int {
+	switch {
+	case d < 0:
+		return -1
+	case d > 0:
+		return 1
+	}
+	return 0
+}
+
+// MulInt returns d * n, it is exact.
+func (d Decimal) MulInt(n int64) Decimal {
+	return d * Decimal(n)
+}
+
+// Mul returns d * o rounded to DecimalPlaces.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal(mulDiv(int64(d), int64(o), decimalScale))
+}
+
+// MulFloat returns d * f rounded to DecimalPlaces, f is usually a price or a conversion rate.
+func (d Decimal) MulFloat(f float64) Decimal {
+	return NewDecimal(d.Float64() * f)
+}
+
+// Div returns d / o rounded to DecimalPlaces, it panics when o is zero.
+func (d Decimal) Div(o Decimal) Decimal {
+
+	if o == 0 {
+		panic("gotrader: decimal division by zero")
+	}
+
+	return Decimal(mulDiv(int64(d), decimalScale, int64(o)))
+}
+
+// String returns the decimal with its significant decimal places, e.g. "-12.5".
+func (d Decimal) String() string {
+
+	sign := ""
+	if d < 0 {
+		sign = "-"
+	}
+
+	value := abs64(int64(d))
+	integer := strconv.FormatUint(value/decimalScale, 10)
+	fraction := strings.TrimRight(strconv.FormatUint(value%decimalScale+decimalScale, 10)[1:], "0")
+
+	if fraction == "" {
+		return sign + integer
+	}
+
+	return sign + integer + "." + fraction
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Decimal) UnmarshalText(text []byte) error {
+
+	value, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = value
+
+	return nil
+}
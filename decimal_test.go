@@ -0,0 +1,104 @@
+package gotrader
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDecimal(t *testing.T) {
+
+	t.Run("overflows saturate", func(t *testing.T) {
+
+		tests := []struct {
+			name     string
+			value    Decimal
+			expected Decimal
+		}{
+			{"product above the range", DecimalFromInt(90000000000).Mul(DecimalFromInt(2)), math.MaxInt64},
+			{"negative product", DecimalFromInt(-90000000000).Mul(DecimalFromInt(2)), math.MinInt64},
+			{"quotient above the range", DecimalFromInt(90000000000).Div(NewDecimal(0.5)), math.MaxInt64},
+			{"negative quotient", DecimalFromInt(90000000000).Div(NewDecimal(-0.5)), math.MinInt64},
+			{"product just above the range", Decimal(math.MaxInt64).Mul(NewDecimal(1.00000001)), math.MaxInt64},
+			{"quotient rounded above the range", Decimal(math.MaxInt64).Div(NewDecimal(0.99999999)), math.MaxInt64},
+			{"largest product", Decimal(math.MaxInt64).Mul(DecimalFromInt(1)), math.MaxInt64},
+			{"smallest product", Decimal(math.MinInt64 + 1).Mul(DecimalFromInt(1)), math.MinInt64 + 1},
+			{"float above the range", NewDecimal(1e12), math.MaxInt64},
+			{"float below the range", NewDecimal(-1e12), math.MinInt64},
+			{"NaN", NewDecimal(math.NaN()), 0},
+		}
+
+		for _, test := range tests {
+			if test.value != test.expected {
+				t.Errorf("%s: expected %d, got %d", test.name, test.expected, test.value)
+			}
+		}
+	})
+
+	t.Run("rounding", func(t *testing.T) {
+
+		tests := []struct {
+			name     string
+			value    Decimal
+			expected string
+		}{
+			{"half up product", NewDecimal(0.00000001).Mul(NewDecimal(0.5)), "0.00000001"},
+			{"half down product", NewDecimal(-0.00000001).Mul(NewDecimal(0.5)), "-0.00000001"},
+			{"product below the half", NewDecimal(0.00000001).Mul(NewDecimal(0.49999999)), "0"},
+			{"repeating quotient", DecimalFromInt(2).Div(DecimalFromInt(3)), "0.66666667"},
+			{"negative repeating quotient", DecimalFromInt(-1).Div(DecimalFromInt(3)), "-0.33333333"},
+			{"half float", NewDecimal(0.000000005), "0.00000001"},
+			{"negative half float", NewDecimal(-0.000000005), "-0.00000001"},
+			{"exact sum", NewDecimal(0.1).Add(NewDecimal(0.2)), "0.3"},
+		}
+
+		for _, test := range tests {
+			if value := test.value.String(); value != test.expected {
+				t.Errorf("%s: expected %s, got %s", test.name, test.expected, value)
+			}
+		}
+	})
+
+	t.Run("parsing", func(t *testing.T) {
+
+		tests := []struct {
+			text     string
+			expected string
+		}{
+			{"-1234.5678", "-1234.5678"},
+			{"+12", "12"},
+			{" 0.5 ", "0.5"},
+			{".25", "0.25"},
+			{"7.", "7"},
+			{"0.123456785", "0.12345679"},
+			{"-0.123456785", "-0.12345679"},
+			{"0.123456784999", "0.12345678"},
+			{"92233720368.54775807", "92233720368.54775807"},
+		}
+
+		for _, test := range tests {
+
+			value, err := ParseDecimal(test.text)
+			if err != nil {
+				t.Errorf("%q: %v", test.text, err)
+				continue
+			}
+
+			if value.String() != test.expected {
+				t.Errorf("%q: expected %s, got %s", test.text, test.expected, value)
+			}
+		}
+	})
+
+	t.Run("parsing errors", func(t *testing.T) {
+
+		for _, text := range []string{
+			"", ".", "-", "abc", "1.2x", "1.2.3", "--1", "+-1", "1e5", "1,5", "1.-5",
+			"0.123456789x", "0.12345678-", "1.00000000000000x5", // the invalid digits beyond the decimal places
+			"92233720369", "92233720368.547758075", // out of range
+		} {
+			if value, err := ParseDecimal(text); err == nil {
+				t.Errorf("%q: expected an error, got %s", text, value)
+			}
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package gotrader
+
+import (
+	"fmt"
+	"math"
+)
+
+// MinStopDistance is the functional option to define the minimum distance, in pips, of the order levels from the
+// current prices of every instrument, replacing the distances reported by the broker (see InstrumentDetails).
+func MinStopDistance(pips float64) Option {
+	return func(p *sessionParameters) {
+		p.stopDistance = &pips
+	}
+}
+
+// InstrumentMinStopDistance is the functional option to define the minimum distance of the order levels of an
+// instrument, used instead of the MinStopDistance one.
+func InstrumentMinStopDistance(instrument string, pips float64) Option {
+	return func(p *sessionParameters) {
+		if p.instrumentStopDistances == nil {
+			p.instrumentStopDistances = make(map[string]float64)
+		}
+		p.instrumentStopDistances[instrument] = pips
+	}
+}
+
+// OrderLevel is a price level of an order checked against the minimum distance.
+type OrderLevel int
+
+const (
+	// EntryLevel is the price of a pending order
+	EntryLevel OrderLevel = iota
+
+	// StopLossLevel is the stop loss attached to an order
+	StopLossLevel
+
+	// TakeProfitLevel is the take profit attached to an order
+	TakeProfitLevel
+)
+
+func (l OrderLevel) String() string {
+
+	names := [...]string{"ENTRY", "STOP_LOSS", "TAKE_PROFIT"}
+
+	return names[l]
+}
+
+/*
+DistanceError is the error of an order level closer than the minimum distance to its reference price, the rules of
+the brokers stop levels: the entry of a pending order is measured from the ask for the buys and from the bid for
+the sells, and must be below it by Minimum for the buy limits and the sell stops, above it for the buy stops and
+the sell limits. The exits of a pending order are measured from its entry, and those of a market order from the
+price closing the trade, the bid for the buys and the ask for the sells. It matches ErrStopDistance with
+errors.Is.
+*/
+type DistanceError struct {
+	Instrument string
+	Level      OrderLevel
+	Price      float64 // of the level
+	Reference  float64
+	Minimum    float64 // in price units
+}
+
+func (e *DistanceError) Error() string {
+	return fmt.Sprintf("%s: %s %v within %v of %v", e.Instrument, e.Level, e.Price, e.Minimum, e.Reference)
+}
+
+func (e *DistanceError) Unwrap() error {
+	return ErrStopDistance
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// minStopDistance returns the minimum distance of the order levels of an instrument, in price units.
+func (p *sessionParameters) minStopDistance(inst *Instrument) float64 {
+
+	pips := inst.stopDistance
+	if distance, exist := p.instrumentStopDistances[inst.name]; exist {
+		pips = distance
+	} else if p.stopDistance != nil {
+		pips = *p.stopDistance
+	}
+
+	return pips * math.Pow10(inst.pipLocation)
+}
+
+// checkDistance returns the *DistanceError of the first level of the order closer than the minimum distance to
+// its reference price. The levels are not checked before the instrument has prices.
+func (p *sessionParameters) checkDistance(o *Order, inst *Instrument) error {
+
+	minimum := p.minStopDistance(inst)
+	if minimum <= 0 || inst.Bid() == 0 || inst.Ask() == 0 {
+		return nil
+	}
+
+	sign := 1.0 // of the side, the distances are positive on the side of the order
+	if o.Side == Short {
+		sign = -1
+	}
+
+	check := func(level OrderLevel, price, reference, direction float64) error {
+		if price != 0 && direction*(price-reference) < minimum-1e-9*minimum {
+			return &DistanceError{Instrument: o.Instrument, Level: level, Price: price, Reference: reference,
+				Minimum: minimum}
+		}
+		return nil
+	}
+
+	exitReference := inst.Bid()
+	if o.Side == Short {
+		exitReference = inst.Ask()
+	}
+
+	if o.Type != MarketOrder {
+
+		entryReference := inst.Ask()
+		if o.Side == Short {
+			entryReference = inst.Bid()
+		}
+
+		direction := -sign // the limits are below the ask for the buys, above the bid for the sells
+		if o.Type == StopOrder {
+			direction = sign
+		}
+
+		if err := check(EntryLevel, o.Price, entryReference, direction); err != nil {
+			return err
+		}
+
+		exitReference = o.Price
+	}
+
+	if err := check(StopLossLevel, o.StopLoss, exitReference, -sign); err != nil {
+		return err
+	}
+
+	return check(TakeProfitLevel, o.TakeProfit, exitReference, sign)
+}
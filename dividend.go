@@ -0,0 +1,131 @@
+package gotrader
+
+import (
+	"sort"
+	"time"
+)
+
+// Dividend is a cash dividend of an instrument, Amount per unit in its quote currency, adjusting the trades open
+// at its ex-dividend date: the long trades are credited and the short ones debited.
+type Dividend struct {
+	Instrument string
+	ExDate     time.Time
+	Amount     float64
+}
+
+// DividendCalendar returns the dividends with an ex-dividend date after from and not after to.
+type DividendCalendar interface {
+	Dividends(from, to time.Time) []Dividend
+}
+
+// DividendSchedule is a DividendCalendar of a fixed list of dividends.
+type DividendSchedule []Dividend
+
+// Dividends implements DividendCalendar.
+func (s DividendSchedule) Dividends(from, to time.Time) []Dividend {
+
+	dividends := make([]Dividend, 0)
+
+	for _, d := range s {
+		if d.ExDate.After(from) && !d.ExDate.After(to) {
+			dividends = append(dividends, d)
+		}
+	}
+
+	sort.SliceStable(dividends, func(i, j int) bool { return dividends[i].ExDate.Before(dividends[j].ExDate) })
+
+	return dividends
+}
+
+/*
+Dividends is the functional option to apply the dividend adjustments of a calendar to the open trades on their
+ex-dividend dates, recorded in the ledger as DividendTransaction. It is meant for the brokers not notifying the
+dividends, e.g. the backtests and the paper broker, the others credit them as financing.
+*/
+func Dividends(calendar DividendCalendar) Option {
+	return func(p *sessionParameters) {
+		p.dividends = calendar
+	}
+}
+
+// dividendPayer applies the dividends of a calendar as the session time moves, it is nil without calendar.
+type dividendPayer struct {
+	calendar DividendCalendar
+	last     time.Time
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newDividendPayer(calendar DividendCalendar) *dividendPayer {
+
+	if calendar == nil {
+		return nil
+	}
+
+	return &dividendPayer{calendar: calendar}
+}
+
+// pay applies the dividends with an ex-dividend date since the last call to the trades open at t, the first call
+// only sets the start time.
+func (p *dividendPayer) pay(account *Account, t time.Time, logger Logger) {
+
+	if p == nil {
+		return
+	}
+
+	if p.last.IsZero() {
+		p.last = t
+		return
+	}
+
+	if !t.After(p.last) {
+		return
+	}
+
+	from := p.last
+	p.last = t
+
+	for _, dividend := range p.calendar.Dividends(from, t) {
+
+		inst, exist := account.instruments[dividend.Instrument]
+		if !exist {
+			continue
+		}
+
+		for _, position := range []*Position{inst.longPosition, inst.shortPosition} {
+			for _, trade := range position.list() {
+
+				amount := NewDecimal(dividend.Amount).MulInt(int64(trade.units) * int64(trade.sideSign)).
+					MulFloat(inst.ccyConversion.QuoteConversionRate.Load())
+
+				transaction := &Transaction{
+					Type:       DividendTransaction,
+					TradeID:    trade.id,
+					Instrument: dividend.Instrument,
+					Side:       trade.side,
+					Units:      trade.units,
+					Amount:     amount.Float64(),
+					Time:       dividend.ExDate,
+					Tag:        trade.tag,
+				}
+
+				account.wal.write(&WALEntry{
+					Operation:   WALDividend,
+					Time:        dividend.ExDate,
+					Instrument:  dividend.Instrument,
+					TradeID:     trade.id,
+					Transaction: transaction,
+				}, logger)
+
+				account.writes.begin()
+				transaction.Balance = account.balance.Add(amount).Float64()
+				account.record(transaction)
+				account.writes.end()
+			}
+		}
+	}
+}
@@ -1,26 +1,42 @@
 package gotrader
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"math"
 	"os"
 	"os/signal"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/atomic"
 )
 
 // Engine is the interface used for interaction from strategy.
 // Is used to check the state of the account, open or close trades and to stop the session.
+//
+// The mutations return the errors found before reaching the broker, ErrInstrumentNotTraded, ErrTradeNotFound,
+// ErrOrderNotFound, ErrInsufficientMargin or ErrMarketClosed wrapped, and are not notified as order fills.
+// The broker rejections and the pending orders failing when triggered are still notified as order fills.
 type Engine interface {
 	Account() *Account
-	Buy(instrument string, units int32)
-	Sell(instrument string, units int32)
-	CloseTrade(instrument string, id string)
-	StopSession() // Gracefully stops trading session from strategy
+	Buy(instrument string, units int32) error
+	Sell(instrument string, units int32) error
+	CloseTrade(instrument string, id string) error
+	SubmitOrder(order *Order) (string, error)
+	SubmitBasket(orders []*Order) ([]string, error) // market orders as one unit, see basket.go
+	ModifyOrder(id string, order *Order) error
+	CancelOrder(id string) error
+	PendingOrders(instrument string) []*Order                   // copies, of every instrument when empty
+	SetHedge(instrument string, hedge Hedge) error              // see HedgeChanged
+	SetStatus(instrument string, status InstrumentStatus) error // see StatusChanged
+	StopSession()                                               // Gracefully stops trading session from strategy
 }
 
 /***********************************************************************************************
@@ -40,6 +56,22 @@ type liveEngine struct {
 	orders                   chan *OrderFill
 	fundsTransfers           chan *FundsTransfer
 	swapCharges              chan *SwapCharge
+	reconnections            chan time.Time
+	corporateActions         chan *CorporateAction
+	specUpdates              chan *SpecUpdate
+	pendingOrders            *orderBook
+	sweeper                  *orderSweeper                   // nil without sweeps of the pending orders
+	lifetimes                *syncMap[string, time.Duration] // maximum lifetime of the trades by order ID or clientKey
+	lifetimesLock            *sync.RWMutex                   // held while the lifetimes and exits are keyed by order ID
+	brackets                 *syncMap[string, bracketExits]  // exits attached to the orders by order ID or clientKey
+	closeReasons             *syncMap[string, CloseReason]   // reason of the closes requested by the engine
+	clientOrders             *clientOrders
+	baskets                  *baskets
+	tracing                  *orderTracer
+	latency                  *latencyHooks
+	clock                    Clock
+	margins                  *marginSchedule
+	dividends                *dividendPayer
 	ready                    bool
 	endOfSession             chan bool
 	logger                   Logger
@@ -51,6 +83,15 @@ func newLiveEngine(logger Logger) *liveEngine {
 		orders:                  make(chan *OrderFill, 100),
 		fundsTransfers:          make(chan *FundsTransfer, 100),
 		swapCharges:             make(chan *SwapCharge, 100),
+		reconnections:           make(chan time.Time, 1),
+		corporateActions:        make(chan *CorporateAction, 100),
+		specUpdates:             make(chan *SpecUpdate, 100),
+		pendingOrders:           newOrderBook(),
+		lifetimes:               newSyncMap[string, time.Duration](),
+		lifetimesLock:           &sync.RWMutex{},
+		brackets:                newSyncMap[string, bracketExits](),
+		closeReasons:            newSyncMap[string, CloseReason](),
+		baskets:                 newBaskets(),
 		availableInstrumentsMap: make(map[string]InstrumentDetails),
 		endOfSession:            make(chan bool, 1),
 		logger:                  logger,
@@ -59,20 +100,43 @@ func newLiveEngine(logger Logger) *liveEngine {
 
 func (e *liveEngine) start() error {
 
+	e.tracing = newOrderTracer(e.parameters.tracer)
+	e.latency = newLatencyHooks(e.parameters.latency)
+	e.clock = e.parameters.clock
+	e.clientOrders = newClientOrders(sessionPrefix(e.clock.Now()), e.clock)
+	e.margins = newMarginSchedule(e.parameters.marginWindows)
+	e.dividends = newDividendPayer(e.parameters.dividends)
 	e.account = newAccount(e.parameters.account)
+	e.account.engine = e
+	e.account.events = e.parameters.events
+	e.account.ledger.events = e.parameters.events
+	e.account.wal = e.parameters.wal
+	e.account.equityCurve = e.parameters.equityCurve()
+	e.account.daily = newDailyMarks(e.parameters.dayOffset, e.parameters.dayLocation)
+	e.account.fees = newFeeAccruer(e.parameters.managedFees)
+	e.account.health.policy = e.parameters.health
 
 	// Account Status Retrieval
-	accountStatus, err := e.client.GetAccountStatus(e.parameters.account)
+	var accountStatus AccountStatus
+	err := e.retry("account status", AuditRecord{}, true, func() (err error) {
+		accountStatus, err = e.client.GetAccountStatus(e.parameters.account)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	e.account.balance.Store(accountStatus.Balance)
+	e.account.balance.Store(NewDecimal(accountStatus.Balance))
+	e.account.ledger.setOpeningBalance(accountStatus.Balance)
 	e.account.homeCurrency = accountStatus.Currency
 	e.account.leverage = accountStatus.Leverage
 
 	// Initialize Trading Instruments
-	availableInstruments, err := e.client.GetAvailableInstruments(e.account.id)
+	var availableInstruments []InstrumentDetails
+	err = e.retry("available instruments", AuditRecord{}, true, func() (err error) {
+		availableInstruments, err = e.client.GetAvailableInstruments(e.account.id)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -93,7 +157,12 @@ func (e *liveEngine) start() error {
 					inst.PipLocation,
 					e.logger,
 				)
-				e.account.instruments[inst.Name].hedgeType = accountStatus.Hedge
+				e.account.instruments[inst.Name].hedgeType = e.parameters.hedge(inst.Name, accountStatus.Hedge)
+				e.account.instruments[inst.Name].minUnits.Store(inst.MinUnits)
+				if inst.UnitSize > 0 {
+					e.account.instruments[inst.Name].unitSize = inst.UnitSize
+				}
+				e.account.instruments[inst.Name].stopDistance = inst.StopDistance
 				conversionInstruments[inst.Name] = newInstrumentConversion(
 					inst.Name,
 					inst.BaseCurrency,
@@ -106,6 +175,10 @@ func (e *liveEngine) start() error {
 	}
 
 	// Initialize Currency Conversion Engine
+	if err := checkHedges(e.client, e.account, e.availableInstrumentsMap); err != nil {
+		return err
+	}
+
 	e.currencyConversionEngine = newCurrencyConversionEngine(
 		conversionInstruments,
 		e.availableInstrumentsMap,
@@ -116,8 +189,19 @@ func (e *liveEngine) start() error {
 
 	e.currencyConversionEngine.setPricePointers(e.account.instruments)
 
+	subscriptions, err := e.parameters.synthetics.add(e.account, e.availableInstrumentsMap,
+		e.currencyConversionEngine.conversionInstrumentsDetails, e.logger)
+	if err != nil {
+		return err
+	}
+	e.currencyConversionEngine.conversionInstrumentsDetails = subscriptions
+
 	// Hydrate current positions state from Broker sorted by open time
-	trades, err := e.client.GetOpenTrades(e.account.id)
+	var trades []TradeDetails
+	err = e.retry("open trades", AuditRecord{}, true, func() (err error) {
+		trades, err = e.client.GetOpenTrades(e.account.id)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -133,12 +217,24 @@ func (e *liveEngine) start() error {
 		inst, exist := e.account.instruments[t.Instrument.Name]
 		if exist {
 			trade := inst.openTrade(t.ID, t.Side, t.OpenTime, t.Units, t.OpenPrice)
-			trade.chargedFees.Add(t.ChargedFees)
+			trade.charge(UnitemizedFee, NewDecimal(t.ChargedFees))
+			trade.venue = t.Venue
+			trade.tag = t.Tag
 		}
 	}
 
+	if e.parameters.snapshot != nil {
+		e.account.restoreTrades(e.parameters.snapshot, true)
+		e.account.restoreLedger(e.parameters.snapshot)
+		e.account.fees.restore(e.parameters.snapshot.Accruals)
+	}
+
+	if err := e.account.replayWAL(e.parameters.wal, e.parameters.snapshot, true); err != nil {
+		return err
+	}
+
 	// Subscribe prices
-	err = e.client.SubscribePrices(e.account.id, e.currencyConversionEngine.conversionInstrumentsDetails, e.onTick)
+	err = e.subscribePrices(e.currencyConversionEngine.conversionInstrumentsDetails)
 	if err != nil {
 		return err
 	}
@@ -159,6 +255,56 @@ func (e *liveEngine) start() error {
 		return err
 	}
 
+	if heartbeater, isHeartbeater := e.client.(Heartbeater); isHeartbeater && e.parameters.health != nil {
+		err = heartbeater.SubscribeHeartbeats(e.account.id, func(component string, t time.Time) {
+			e.account.health.beat(component, e.clock.Now(), e.account.events)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if reconnector, isReconnector := e.client.(Reconnector); isReconnector {
+		err = reconnector.SubscribeReconnections(e.account.id, e.onReconnect)
+		if err != nil {
+			return err
+		}
+	}
+
+	if notifier, isNotifier := e.client.(CorporateActionNotifier); isNotifier {
+		err = notifier.SubscribeCorporateActions(e.account.id, e.onCorporateAction)
+		if err != nil {
+			return err
+		}
+	}
+
+	if notifier, isNotifier := e.client.(SpecNotifier); isNotifier {
+		err = notifier.SubscribeSpecUpdates(e.account.id, e.onSpecUpdate)
+		if err != nil {
+			return err
+		}
+	}
+
+	if broker, isBroker := e.client.(Broker); isBroker {
+		var orders []*Order
+		err := e.retry("pending orders", AuditRecord{}, true, func() (err error) {
+			orders, err = broker.GetPendingOrders(e.account.id)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, o := range orders {
+			e.pendingOrders.add(o)
+		}
+
+		e.sweeper = newOrderSweeper(e.parameters.sweep, e.client)
+	}
+
+	e.account.collectStats(e.parameters.stats)
+	e.parameters.stats.watch(e.ticks, e.orders)
+
 	// Initialize consumers (buffered channels are used to prevent race conditions)
 	e.startOrderFillConsumer()
 	e.startSwapChargesConsumer()
@@ -172,16 +318,19 @@ func (e *liveEngine) start() error {
 	e.shutdownHook()
 
 	// Run strategy
+	e.account.recalculator = newRecalculator(e.account.instruments, e.parameters.recalculationShards)
 	e.run()
+	e.account.recalculator.stop()
 
 	// Stop strategy
+	e.account.events.publish(SessionClose{Time: e.clock.Now()})
 	e.strategy.OnStop()
 
 	return nil
 }
 
 func (e *liveEngine) shutdownHook() {
-	var singalChan = make(chan os.Signal)
+	var singalChan = make(chan os.Signal, 1)
 	signal.Notify(singalChan, syscall.SIGTERM)
 	signal.Notify(singalChan, syscall.SIGINT)
 
@@ -194,10 +343,31 @@ func (e *liveEngine) shutdownHook() {
 
 func (e *liveEngine) onTick(tick *Tick) { // Ticks callback
 
+	e.latency.arrived(tick)
+	e.parameters.feedLatency.received(tick, time.Now())
+	e.account.health.beat(FeedComponent, e.clock.Now(), e.account.events)
+
+	synthetic := e.parameters.synthetics.update(tick) // before the tick is queued, and may be released
+
+	e.queueTick(tick)
+
+	for _, t := range synthetic {
+		e.queueTick(t)
+	}
+}
+
+// queueTick queues a tick for the run loop.
+func (e *liveEngine) queueTick(tick *Tick) {
+
 	select { // non blocking buffered channel
 	case e.ticks <- tick:
 	default: // Replaces older ticks by newer ones (extreme case)
-		<-e.ticks
+		select { // the consumer may have drained the channel meanwhile
+		case dropped := <-e.ticks:
+			e.parameters.stats.tickDropped()
+			releaseTick(dropped)
+		default:
+		}
 		e.ticks <- tick
 	}
 
@@ -215,27 +385,146 @@ func (e *liveEngine) onFundsTransfer(funds *FundsTransfer) { // Funds transfer c
 	e.fundsTransfers <- funds
 }
 
+func (e *liveEngine) onReconnect(t time.Time) { // Reconnections callback
+
+	select { // several reconnections before the reconciliation runs only need one pass
+	case e.reconnections <- t:
+	default:
+	}
+}
+
+func (e *liveEngine) onCorporateAction(action *CorporateAction) { // Corporate actions callback
+	e.corporateActions <- action
+}
+
+func (e *liveEngine) onSpecUpdate(update *SpecUpdate) { // Instrument specification updates callback
+	e.specUpdates <- update
+}
+
 func (e *liveEngine) startOrderFillConsumer() {
 
 	go func() {
 		for orderFill := range e.orders {
 
+			e.clientOrders.fill(orderFill)
+			classifyRejection(orderFill)
+			ctx, traced := e.tracing.fill(orderFill)
+			if inst, exist := e.account.instruments[orderFill.Instrument.Name]; exist {
+				e.account.slippages.fill(orderFill, inst.pipLocation)
+			}
+
+			if orderFill.OrderID != "" {
+				e.pendingOrders.remove(orderFill.OrderID)
+			}
+
+			if orderFill.Error != "" && orderFill.TradeID != "" { // a failed close is requested again
+				e.closeReasons.Del(orderFill.TradeID)
+			}
+
+			var trade *Trade
+
 			if orderFill.Error == "" {
+				update := e.tracing.update(ctx)
+				e.account.writes.begin()
+				orderFill.Gap = e.parameters.gaps.gapped(orderFill.Instrument.Name)
 				if !orderFill.TradeClose {
-					e.account.instruments[orderFill.Instrument.Name].openTrade(
+					expiry := e.expiry(orderFill)
+					exits := e.exits(orderFill)
+					e.account.wal.write(&WALEntry{
+						Operation:  WALOpenTrade,
+						Time:       orderFill.Time,
+						Instrument: orderFill.Instrument.Name,
+						TradeID:    orderFill.TradeID,
+						Side:       orderFill.Side,
+						Units:      orderFill.Units,
+						Price:      orderFill.Price,
+						Fees:       orderFill.ChargedFees,
+						StopLoss:   exits.stopLoss,
+						Guaranteed: exits.guaranteed,
+						TakeProfit: exits.takeProfit,
+						Expiry:     expiry,
+						Venue:      orderFill.Venue,
+						Tag:        orderFill.Tag,
+					}, e.logger)
+					inst := e.account.instruments[orderFill.Instrument.Name]
+					trade = inst.openTrade(
 						orderFill.TradeID,
 						orderFill.Side,
 						orderFill.Time,
 						orderFill.Units,
 						orderFill.Price,
 					)
+					inst.paySpread(trade)
+					inst.amend(func() {
+						trade.venue = orderFill.Venue
+						trade.tag = orderFill.Tag
+						trade.expiry = expiry
+						trade.gapFill = orderFill.Gap
+						exits.attach(trade)
+					})
+					if orderFill.ChargedFees != 0 { // the opening commissions, guaranteed stop premiums included
+						trade.charge(CommissionFee, NewDecimal(orderFill.ChargedFees))
+						inst.touch()
+					}
 				} else {
-					e.account.instruments[orderFill.Instrument.Name].closeTrade(orderFill.TradeID)
-					e.account.balance.Add(orderFill.Profit)
+					if reason, exist := e.closeReasons.Get(orderFill.TradeID); exist {
+						orderFill.Reason = reason
+						e.closeReasons.Del(orderFill.TradeID)
+					}
+
+					inst := e.account.instruments[orderFill.Instrument.Name]
+					transaction := &Transaction{
+						Type:       TradeCloseTransaction,
+						TradeID:    orderFill.TradeID,
+						Instrument: orderFill.Instrument.Name,
+						Side:       orderFill.Side,
+						Units:      orderFill.Units,
+						ClosePrice: orderFill.Price,
+						Amount:     orderFill.Profit,
+						Fees:       orderFill.ChargedFees,
+						Time:       orderFill.Time,
+						Reason:     orderFill.Reason,
+					}
+
+					if tr := inst.Trade(orderFill.TradeID); tr != nil {
+						transaction.OpenPrice = tr.openPrice
+						transaction.OpenTime = tr.openTime
+						transaction.Tag = tr.tag
+						transaction.SpreadCost = inst.closeSpread(tr).Float64()
+						if orderFill.Tag == "" {
+							orderFill.Tag = tr.tag
+						}
+					}
+
+					e.account.wal.write(&WALEntry{
+						Operation:   WALCloseTrade,
+						Time:        orderFill.Time,
+						Instrument:  orderFill.Instrument.Name,
+						TradeID:     orderFill.TradeID,
+						Transaction: transaction,
+					}, e.logger)
+					if err := inst.closeTrade(orderFill.TradeID); err != nil {
+						e.logger.Warn(err)
+					}
+					transaction.Balance = e.account.balance.Add(NewDecimal(orderFill.Profit)).Float64()
+					e.account.record(transaction)
 				}
+				e.account.writes.end()
+				update.End()
 			}
 
+			traced()
+
+			e.account.events.publishFill(orderFill, trade)
 			e.strategy.OnOrderFill(orderFill)
+			notifyRejection(e.strategy, orderFill)
+
+			switch {
+			case trade != nil && e.baskets.filled(orderFill.ClientOrderID, trade.instrumentName, trade.id):
+				e.closeRolledBack(trade.instrumentName, trade.id)
+			case orderFill.Error != "" && !orderFill.TradeClose && orderFill.ClientOrderID != "":
+				e.rollback(orderFill.ClientOrderID, orderFill.ClientOrderID)
+			}
 		}
 	}()
 }
@@ -246,15 +535,40 @@ func (e *liveEngine) startSwapChargesConsumer() {
 		for swapCharge := range e.swapCharges {
 			for _, charge := range swapCharge.Charges {
 
-				tr, exist := e.account.instruments[charge.Instrument.Name].trades.Get(charge.ID)
+				trade, exist := e.account.instruments[charge.Instrument.Name].trades.Get(charge.ID)
 				if !exist {
 					e.logger.Warn(charge, "charging swap on unexisting trade")
 					continue
 				}
 
-				trade := tr.(*Trade)
-				trade.chargedFees.Add(charge.Ammount)
-				e.account.balance.Add(charge.Ammount)
+				transaction := &Transaction{
+					Type:       FinancingTransaction,
+					TradeID:    charge.ID,
+					Instrument: charge.Instrument.Name,
+					Side:       trade.side,
+					Units:      trade.units,
+					Amount:     charge.Ammount,
+					Time:       swapCharge.Time,
+					Tag:        trade.tag,
+				}
+
+				e.account.wal.write(&WALEntry{
+					Operation:   WALFinancing,
+					Time:        swapCharge.Time,
+					Instrument:  charge.Instrument.Name,
+					TradeID:     charge.ID,
+					Fees:        charge.Ammount,
+					Transaction: transaction,
+				}, e.logger)
+
+				e.account.writes.begin()
+				trade.charge(FinancingFee, NewDecimal(charge.Ammount))
+				e.account.instruments[charge.Instrument.Name].touch()
+				transaction.Balance = e.account.balance.Add(NewDecimal(charge.Ammount)).Float64()
+				e.account.record(transaction)
+				e.account.writes.end()
+
+				e.account.events.publish(TradeUpdated{Time: swapCharge.Time, Trade: trade, Update: FinancingUpdate})
 			}
 		}
 	}()
@@ -265,7 +579,18 @@ func (e *liveEngine) startFundsTransferConsumer() {
 
 	go func() {
 		for funds := range e.fundsTransfers {
-			e.account.balance.Add(funds.Ammount)
+			transaction := &Transaction{
+				Type:   FundsTransferTransaction,
+				Amount: funds.Ammount,
+				Time:   funds.Time,
+			}
+
+			e.account.wal.write(&WALEntry{Operation: WALFunds, Time: funds.Time, Transaction: transaction}, e.logger)
+
+			e.account.writes.begin()
+			transaction.Balance = e.account.balance.Add(NewDecimal(funds.Ammount)).Float64()
+			e.account.record(transaction)
+			e.account.writes.end()
 		}
 	}()
 
@@ -273,25 +598,99 @@ func (e *liveEngine) startFundsTransferConsumer() {
 
 func (e *liveEngine) run() {
 
+	var staleChecks <-chan time.Time // nil channel when staleness is not checked
+
+	if e.parameters.staleAfter > 0 {
+		ticker := time.NewTicker(e.parameters.staleAfter / 2)
+		defer ticker.Stop()
+		staleChecks = ticker.C
+	}
+
+	var healthChecks <-chan time.Time // nil channel when the health is not monitored
+
+	if e.parameters.health != nil {
+		ticker := time.NewTicker(e.parameters.health.interval())
+		defer ticker.Stop()
+		healthChecks = ticker.C
+	}
+
+	var feedChecks <-chan time.Time // nil channel without failovers
+
+	if e.parameters.feeds != nil {
+		ticker := time.NewTicker(e.parameters.feeds.interval())
+		defer ticker.Stop()
+		feedChecks = ticker.C
+	}
+
+	var sweeps <-chan time.Time // nil channel without sweeps of the pending orders
+
+	broker, isBroker := e.client.(Broker)
+	swept := make(chan *sweep, 1) // the sweeps run out of the loop, one at a time
+	sweeping := false
+
+	if e.sweeper != nil && isBroker { // scheduled on the session clock
+		sweeps = after(e.clock, e.sweeper.interval)
+	}
+
 	for { // Application blocks until end of session
 
 		select {
 		case <-e.endOfSession:
 			return
+		case t := <-e.reconnections:
+			e.reconcile(t)
+		case action := <-e.corporateActions:
+			e.applyCorporateAction(action)
+		case update := <-e.specUpdates:
+			e.applySpecUpdate(update)
+		case now := <-staleChecks:
+			e.account.checkStale(now, e.parameters.staleAfter, e.parameters.feedLatency)
+		case <-healthChecks:
+			e.account.health.check(e.clock.Now(), e.account.events)
+		case <-feedChecks:
+			e.checkFeeds()
+		case <-sweeps:
+			sweeps = after(e.clock, e.sweeper.interval)
+			if !sweeping { // the sweeps don't overlap, a slow broker skips the ticks meanwhile
+				sweeping = true
+				go func() { swept <- e.sweepOrders(broker) }()
+			}
+		case s := <-swept:
+			sweeping = false
+			e.applySweep(s)
 		case tick := <-e.ticks:
 
-			if _, exist := e.account.instruments[tick.Instrument]; exist {
+			if !e.parameters.tickOrder.accept(tick) {
+				e.parameters.stats.tickDiscarded()
+				releaseTick(tick)
+				continue
+			}
+
+			for _, update := range e.account.limitExpiries(tick.Time) {
+				e.applySpecUpdate(update)
+			}
+
+			if inst, exist := e.account.instruments[tick.Instrument]; exist {
 
-				e.account.instruments[tick.Instrument].updatePrice(tick)
+				e.parameters.stats.tickProcessed()
+				e.parameters.markUp(inst, tick)
+				inst.updatePrice(tick)
+				e.publishGap(inst, tick)
+				e.account.alerts.evaluate(tick, e.account.events)
 				e.currencyConversionEngine.updateRate(tick.Instrument)
-				e.account.time = tick.Time
+				e.account.setTime(tick.Time)
+				e.margins.update(e.account, e.clock.Now())
+				e.dividends.pay(e.account, e.clock.Now(), e.logger)
 
 				if e.ready {
-					e.account.calculateUnrealized()
-					e.account.calculateMarginUsed()
-					e.account.calculateFreeMargin()
+					e.account.recalculate()
+					e.account.fees.accrue(e.account, e.clock.Now(), e.logger)
+					e.account.checkMarginCall(e.parameters.marginCallLevel)
+					e.closeDue(inst)
 
+					e.latency.deciding(tick)
 					e.strategy.OnTick(tick)
+					e.latency.decided(tick)
 				} else {
 					e.checkState()
 				}
@@ -304,185 +703,675 @@ func (e *liveEngine) run() {
 					inst.Bid.Store(tick.Bid)
 					inst.Ask.Store(tick.Ask)
 					e.currencyConversionEngine.updateRate(tick.Instrument)
-				} else {
+				} else if !e.parameters.synthetics.leg(tick.Instrument) {
 					e.logger.Warn("received a tick from an instrument that was not subscribed and it has been ignored")
 				}
 			}
+
+			releaseTick(tick)
 		}
 	}
 }
 
-// Check if all instruments have already a price defined
-func (e *liveEngine) checkState() {
-	for _, inst := range e.currencyConversionEngine.conversionInstruments {
-		if inst.Ask.Load() == 0.0 {
-			return
-		}
+// publishGap publishes the gap of the instrument price updated by the tick, if any.
+func (e *liveEngine) publishGap(inst *Instrument, tick *Tick) {
+	if gap := e.parameters.gaps.update(inst, tick, e.parameters.calendar(inst.name)); gap != nil {
+		e.account.events.publish(*gap)
 	}
+}
 
-	e.ready = true
+// expiry returns the expiry of the trade opened by a fill, zero when its order has no maximum lifetime.
+func (e *liveEngine) expiry(orderFill *OrderFill) time.Time {
+
+	e.lifetimesLock.RLock()
+	defer e.lifetimesLock.RUnlock()
+
+	lifetime, exist := e.lifetimes.Get(orderFill.OrderID)
+	if !exist && orderFill.ClientOrderID != "" { // filled before its ID was returned
+		lifetime, exist = e.lifetimes.Get(clientKey(orderFill.ClientOrderID))
+	}
+
+	if !exist {
+		return time.Time{}
+	}
+
+	e.lifetimes.Del(orderFill.OrderID)
+	e.lifetimes.Del(clientKey(orderFill.ClientOrderID))
+
+	return orderFill.Time.Add(lifetime)
 }
 
-func (e *liveEngine) calcMarginUsed(instrument string, units int32) float64 {
+// exits returns the exits attached to the order of the fill, activated on the trade it opens.
+func (e *liveEngine) exits(orderFill *OrderFill) bracketExits {
 
-	leverage := e.account.instruments[instrument].leverage
-	conversionRate := e.account.instruments[instrument].ccyConversion.BaseConversionRate.Load()
-	marginUsed := float64(units) / leverage.Load() * conversionRate
+	e.lifetimesLock.RLock()
+	defer e.lifetimesLock.RUnlock()
 
-	return marginUsed
+	exits, exist := e.brackets.Get(orderFill.OrderID)
+	if !exist && orderFill.ClientOrderID != "" {
+		exits, _ = e.brackets.Get(clientKey(orderFill.ClientOrderID))
+	}
+
+	e.brackets.Del(orderFill.OrderID)
+	e.brackets.Del(clientKey(orderFill.ClientOrderID))
+
+	return exits
 }
 
-/**************************
-*
-*	Accessible Methods
-*
-***************************/
+// rollback rolls back the basket of the order that failed, closing the trades opened by its other orders.
+func (e *liveEngine) rollback(clientID string, forget ...string) {
+	for _, leg := range e.baskets.rollback(clientID, forget...) {
+		e.closeRolledBack(leg.instrument, leg.tradeID)
+	}
+}
 
-func (e *liveEngine) Account() *Account {
-	return e.account
+func (e *liveEngine) closeRolledBack(instrument, id string) {
+
+	e.closeReasons.Set(id, RolledBack)
+	if err := e.CloseTrade(instrument, id); err != nil {
+		e.closeReasons.Del(id)
+		e.logger.Errorf("rolling back the trade %s of a basket: %v", id, err)
+	}
 }
 
-func (e *liveEngine) Buy(instrument string, units int32) {
+// closeDue closes, once, the trades of an instrument whose maximum lifetime elapsed and those to flatten before the
+// session close.
+func (e *liveEngine) closeDue(inst *Instrument) {
 
-	go func() {
+	now := e.clock.Now()
+	flatten := e.parameters.flattening(inst.name, now)
 
-		if e.calcMarginUsed(instrument, units) > e.account.marginFree { // Only send request if there is enough margin
-			e.orders <- &OrderFill{
-				Error:      "NOT_ENOUGH_MARGIN",
-				Instrument: e.availableInstrumentsMap[instrument],
-				Side:       Long,
-				Units:      units,
-				Time:       time.Now(),
+	for _, position := range []*Position{inst.longPosition, inst.shortPosition} {
+		for _, trade := range position.list() {
+
+			reason := Flattened
+			if trade.expired(now) {
+				reason = Expired
+			} else if !flatten {
+				continue
 			}
-			return
-		}
 
-		err := e.client.OpenMarketOrder(e.account.id, instrument, units, Long.String())
-		if err != nil {
-			e.orders <- &OrderFill{
-				Error:      err.Error(),
-				Instrument: e.availableInstrumentsMap[instrument],
-				Side:       Short,
-				Units:      units,
-				Time:       time.Now(),
+			if _, closing := e.closeReasons.Get(trade.id); closing {
+				continue
+			}
+
+			e.closeReasons.Set(trade.id, reason)
+			if err := e.CloseTrade(inst.name, trade.id); err != nil {
+				e.closeReasons.Del(trade.id)
+				e.logger.Errorf("closing the trade %s (%s): %v", trade.id, reason, err)
 			}
 		}
+	}
+}
 
-	}()
+// applyCorporateAction adjusts the account to a corporate action, between the ticks.
+func (e *liveEngine) applyCorporateAction(action *CorporateAction) {
+
+	event, err := applyCorporateAction(e.account, e.currencyConversionEngine, e.pendingOrders, action, e.logger)
+	if err != nil {
+		e.logger.Errorf("corporate action %s: %v", action.Type, err)
+		return
+	}
+
+	if action.Type == TickerChange {
+		details := e.availableInstrumentsMap[action.Instrument]
+		details.Name = action.NewInstrument
+		e.availableInstrumentsMap[action.NewInstrument] = details
+	}
 
+	e.account.events.publish(event)
 }
 
-func (e *liveEngine) Sell(instrument string, units int32) {
+// applySpecUpdate updates an instrument to the specification pushed by the broker, between the ticks.
+func (e *liveEngine) applySpecUpdate(update *SpecUpdate) {
 
-	go func() {
+	event, err := applySpecUpdate(e.account, e.parameters.specPolicy, update)
+	if err != nil {
+		e.logger.Errorf("instrument specification update: %v", err)
+		return
+	}
 
-		if e.calcMarginUsed(instrument, units) > e.account.marginFree { // Only send request if there is enough margin
-			e.orders <- &OrderFill{
-				Error:      "NOT_ENOUGH_MARGIN",
-				Instrument: e.availableInstrumentsMap[instrument],
-				Side:       Short,
-				Units:      units,
-				Time:       time.Now(),
-			}
-			return
-		}
+	e.account.events.publish(event)
 
-		err := e.client.OpenMarketOrder(e.account.id, instrument, units, Short.String())
-		if err != nil {
-			e.orders <- &OrderFill{
-				Error:      err.Error(),
-				Instrument: e.availableInstrumentsMap[instrument],
-				Side:       Short,
-				Units:      units,
-				Time:       time.Now(),
-			}
+	for _, id := range event.Closed {
+		e.closeReasons.Set(id, SpecAdjusted)
+		if err := e.CloseTrade(update.Instrument, id); err != nil {
+			e.closeReasons.Del(id)
+			e.logger.Errorf("closing the trade %s (%s): %v", id, SpecAdjusted, err)
 		}
+	}
+}
 
-	}()
+// Check if all instruments have already a price defined
+func (e *liveEngine) checkState() {
+	for _, inst := range e.currencyConversionEngine.conversionInstruments {
+		if inst.Ask.Load() == 0.0 {
+			return
+		}
+	}
 
+	e.ready = true
 }
 
-func (e *liveEngine) CloseTrade(instrument, id string) {
+// openMarketOrder sends a market order to the broker, the broker errors are notified as order fills. The order
+// is tracked by its client ID, which the BrokerClient can't send, see clientOrders.beginMarket.
+func (e *liveEngine) openMarketOrder(order *Order) error {
+
+	instrument, units, side := order.Instrument, order.Units, order.Side
+
+	if err := checkInstrument(e.account, e.parameters.calendar(instrument), instrument, e.clock.Now()); err != nil {
+		return err
+	}
+
+	if err := e.parameters.news.check(e.account, instrument, e.clock.Now()); err != nil {
+		return err
+	}
+
+	if err := e.account.health.checkOpen(); err != nil {
+		return err
+	}
+
+	if err := checkSpec(e.account, instrument, side, units); err != nil {
+		return err
+	}
+
+	if err := e.parameters.compliance.checkOpen(e.account, instrument, side, units); err != nil {
+		return err
+	}
+
+	if e.calcMarginUsed(instrument, units).Float64() > e.account.MarginFree() { // Only send request if there is enough margin
+		return fmt.Errorf("%s: %w", instrument, ErrInsufficientMargin)
+	}
+
+	if acknowledged, err := e.clientOrders.beginMarket(order); err != nil || acknowledged {
+		return err // the retry of an acknowledged order is not sent again
+	}
+
+	ctx, _ := e.tracing.start(marketKey(instrument, side), "market", orderAttributes(instrument, side, units)...)
+	e.account.slippages.request(marketKey(instrument, side), requestedPrice(e.account.instruments[instrument], side))
+	decision := e.latency.decision()
 
 	go func() {
 
-		err := e.client.CloseTrade(e.account.id, id)
+		e.latency.submitted(decision)
+
+		err := e.tracing.submit(ctx, func(ctx context.Context) error {
+			record := AuditRecord{Instrument: instrument, Type: MarketOrder, Side: side, Units: units}
+			return e.retry("market order", record, false, func() error {
+				if client, ok := e.client.(ContextClient); ok {
+					return client.OpenMarketOrderContext(ctx, e.account.id, instrument, units, side.String())
+				}
+				return e.client.OpenMarketOrder(e.account.id, instrument, units, side.String())
+			})
+		})
+
+		e.clientOrders.end(order.ClientID, "", err)
+
 		if err != nil {
 			e.orders <- &OrderFill{
-				Error:      err.Error(),
-				Instrument: e.availableInstrumentsMap[instrument],
-				TradeID:    id,
-				Time:       time.Now(),
+				Error:         err.Error(),
+				Instrument:    e.availableInstrumentsMap[instrument],
+				Side:          side,
+				Units:         units,
+				Time:          e.clock.Now(),
+				ClientOrderID: order.ClientID,
 			}
+			return
 		}
 
+		submitted := *order
+		submitted.CreateTime = e.clock.Now()
+
+		e.account.events.publish(OrderSubmitted{Time: submitted.CreateTime, Order: &submitted})
+
 	}()
 
+	return nil
 }
 
-func (e *liveEngine) StopSession() {
-	e.endOfSession <- true
+func (e *liveEngine) calcMarginUsed(instrument string, units int32) Decimal {
+
+	leverage := e.account.instruments[instrument].leverage
+	conversionRate := e.account.instruments[instrument].ccyConversion.BaseConversionRate.Load()
+	marginUsed := DecimalFromInt(int64(units)).MulFloat(conversionRate / leverage.Load())
+
+	return marginUsed
 }
 
-/***********************************************************************************************
-*
-*											Backtest Engine
-*
+/**************************
 *
+*	Accessible Methods
 *
-************************************************************************************************/
+***************************/
 
-type btEngine struct {
-	account                  *Account
-	client                   BrokerClient
-	parameters               *sessionParameters
-	strategy                 Strategy
-	currencyConversionEngine *currencyConversionEngine
-	ticks                    chan *Tick
-	tradesCounter            *atomic.Int32
-	instrumentsDetails       map[string]InstrumentDetails
-	ready                    bool
-	endOfSession             chan bool
-	logger                   Logger
+func (e *liveEngine) Account() *Account {
+	return e.account
 }
 
-func newBtEngine(logger Logger) *btEngine {
-	return &btEngine{
-		ticks:              make(chan *Tick, 300),
-		tradesCounter:      atomic.NewInt32(0),
-		instrumentsDetails: make(map[string]InstrumentDetails),
-		endOfSession:       make(chan bool, 1),
-		logger:             logger,
-	}
+func (e *liveEngine) Buy(instrument string, units int32) error {
+	return e.openMarketOrder(&Order{Type: MarketOrder, Instrument: instrument, Side: Long, Units: units})
 }
 
-func (e *btEngine) start() error {
+func (e *liveEngine) Sell(instrument string, units int32) error {
+	return e.openMarketOrder(&Order{Type: MarketOrder, Instrument: instrument, Side: Short, Units: units})
+}
 
-	e.account = newAccount(e.parameters.account)
+func (e *liveEngine) CloseTrade(instrument, id string) error {
 
-	if e.parameters == nil || e.parameters.testParameters == nil {
-		return errors.New("parameters are no defined")
+	if err := checkInstrument(e.account, e.parameters.calendar(instrument), instrument, e.clock.Now()); err != nil {
+		return err
 	}
 
-	// Account Status Retrieval
-	e.account.balance.Store(e.parameters.testParameters.initialBalance)
-	e.account.homeCurrency = e.parameters.testParameters.homeCurrency
-	e.account.leverage = e.parameters.testParameters.leverage
-	if e.account.leverage == 0 {
-		e.account.leverage = 1
+	trade := e.account.instruments[instrument].Trade(id)
+	if trade == nil {
+		return fmt.Errorf("%s trade %s: %w", instrument, id, ErrTradeNotFound)
 	}
 
-	// Initialize Trading Instruments
-	availableInstruments, err := e.client.GetAvailableInstruments(e.account.id)
-	if err != nil {
+	if err := e.parameters.compliance.checkClose(e.account, instrument, id); err != nil {
 		return err
 	}
 
-	availableInstrumentsMap := make(map[string]InstrumentDetails)
+	e.account.slippages.request(closeKey(id), requestedPrice(e.account.instruments[instrument], opposite(trade.side)))
 
-	conversionInstruments := make(map[string]*instrumentConversion)
+	ctx, _ := e.tracing.start(closeKey(id), "close",
+		attribute.String("order.instrument", instrument),
+		attribute.String("trade.id", id),
+	)
+	decision := e.latency.decision()
 
-	for _, inst := range availableInstruments {
+	go func() {
+
+		e.latency.submitted(decision)
+
+		err := e.tracing.submit(ctx, func(ctx context.Context) error {
+			record := AuditRecord{TradeID: id, Instrument: instrument}
+			return e.retry("trade close", record, false, func() error {
+				if client, ok := e.client.(ContextClient); ok {
+					return client.CloseTradeContext(ctx, e.account.id, id)
+				}
+				return e.client.CloseTrade(e.account.id, id)
+			})
+		})
+
+		if err != nil {
+			e.orders <- &OrderFill{
+				Error:      err.Error(),
+				Instrument: e.availableInstrumentsMap[instrument],
+				TradeID:    id,
+				Time:       e.clock.Now(),
+			}
+		}
+
+	}()
+
+	return nil
+}
+
+func (e *liveEngine) SubmitOrder(order *Order) (string, error) {
+
+	broker, isBroker := e.client.(Broker)
+
+	if !isBroker {
+
+		if order.Type != MarketOrder {
+			return "", errors.New("client does not support pending orders")
+		}
+
+		if order.MaxLifetime > 0 {
+			return "", errors.New("client does not support the lifetime of the trades")
+		}
+
+		if order.Bracketed() {
+			return "", errors.New("client does not support the exits of the trades")
+		}
+
+		return "", e.openMarketOrder(order)
+	}
+
+	if err := checkInstrument(e.account, e.parameters.calendar(order.Instrument), order.Instrument, e.clock.Now()); err != nil {
+		return "", err
+	}
+
+	if err := e.parameters.news.check(e.account, order.Instrument, e.clock.Now()); err != nil {
+		return "", err
+	}
+
+	if err := e.account.health.checkOpen(); err != nil {
+		return "", err
+	}
+
+	if err := checkSpec(e.account, order.Instrument, order.Side, order.Units); err != nil {
+		return "", err
+	}
+
+	if err := e.parameters.compliance.checkOpen(e.account, order.Instrument, order.Side, order.Units); err != nil {
+		return "", err
+	}
+
+	if err := checkBracket(order, e.account.instruments[order.Instrument]); err != nil {
+		return "", err
+	}
+
+	if err := e.parameters.checkDistance(order, e.account.instruments[order.Instrument]); err != nil {
+		return "", err
+	}
+
+	id, acknowledged, err := e.clientOrders.begin(order)
+	if err != nil || acknowledged { // the retry of an acknowledged order is not sent again
+		return id, err
+	}
+
+	key := marketKey(order.Instrument, order.Side)
+	if order.Type != MarketOrder {
+		key = "submit:" + order.Instrument
+	}
+
+	ctx, span := e.tracing.start(key, strings.ToLower(order.Type.String()),
+		orderAttributes(order.Instrument, order.Side, order.Units)...,
+	)
+
+	e.latency.submitted(e.latency.decision())
+
+	if order.Type == MarketOrder {
+		e.account.slippages.request(key, requestedPrice(e.account.instruments[order.Instrument], order.Side))
+	}
+
+	exits := bracketExits{
+		stopLoss:   order.StopLoss,
+		guaranteed: order.GuaranteedStop && order.StopLoss != 0,
+		takeProfit: order.TakeProfit,
+	}
+
+	// the fill may be notified before the order ID is returned, the lifetime and the exits are found by client ID
+	if order.MaxLifetime > 0 {
+		e.lifetimes.Set(clientKey(order.ClientID), order.MaxLifetime)
+	}
+	if order.Bracketed() {
+		e.brackets.Set(clientKey(order.ClientID), exits)
+	}
+
+	err = e.tracing.submit(ctx, func(ctx context.Context) error {
+		record := AuditRecord{Instrument: order.Instrument, Type: order.Type, Side: order.Side, Units: order.Units,
+			Price: order.Price, Tag: order.Tag}
+		return e.retry("order submission", record, false, func() error {
+			var err error
+			id, err = broker.SubmitOrder(e.account.id, order)
+			return err
+		})
+	})
+
+	e.clientOrders.end(order.ClientID, id, err)
+
+	if err == nil && (order.MaxLifetime > 0 || order.Bracketed()) {
+		e.lifetimesLock.Lock()
+		if lifetime, exist := e.lifetimes.Get(clientKey(order.ClientID)); exist { // not filled yet
+			e.lifetimes.Set(id, lifetime)
+			e.lifetimes.Del(clientKey(order.ClientID))
+		}
+		if exits, exist := e.brackets.Get(clientKey(order.ClientID)); exist {
+			e.brackets.Set(id, exits)
+			e.brackets.Del(clientKey(order.ClientID))
+		}
+		e.lifetimesLock.Unlock()
+	}
+
+	if err != nil && !isTimeout(err) { // a timed out order may still fill
+		e.lifetimes.Del(clientKey(order.ClientID))
+		e.brackets.Del(clientKey(order.ClientID))
+	}
+
+	if err != nil {
+		e.tracing.end(key, err)
+		if order.Type == MarketOrder {
+			e.account.slippages.drop(key)
+		}
+		return "", err
+	}
+
+	if order.Type != MarketOrder {
+		e.tracing.rekey(span, key, orderKey(id))
+		e.account.slippages.request(orderKey(id), order.Price)
+	}
+
+	submitted := *order
+	submitted.ID = id
+	submitted.CreateTime = e.clock.Now()
+
+	if order.Type != MarketOrder {
+		pending := submitted
+		e.pendingOrders.add(&pending)
+	}
+
+	e.account.events.publish(OrderSubmitted{Time: submitted.CreateTime, Order: &submitted})
+
+	return id, nil
+}
+
+func (e *liveEngine) SubmitBasket(orders []*Order) ([]string, error) {
+
+	if err := checkBasket(orders); err != nil {
+		return nil, err
+	}
+
+	for _, order := range orders {
+		e.clientOrders.assign(order)
+	}
+
+	e.baskets.add(orders)
+
+	ids := make([]string, 0, len(orders))
+
+	for i, order := range orders {
+
+		id, err := e.SubmitOrder(order)
+		if err != nil {
+			forget := make([]string, 0, len(orders)-i)
+			if !isTimeout(err) { // the order may still fill
+				forget = append(forget, order.ClientID)
+			}
+			for _, unsent := range orders[i+1:] {
+				forget = append(forget, unsent.ClientID)
+			}
+			e.rollback(order.ClientID, forget...)
+			return nil, fmt.Errorf("basket order %d: %w", i, err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (e *liveEngine) ModifyOrder(id string, order *Order) error {
+
+	broker, isBroker := e.client.(Broker)
+	if !isBroker {
+		return errors.New("client does not support pending orders")
+	}
+
+	if pending, exist := e.pendingOrders.get(id); exist {
+		amended := *order
+		amended.Type, amended.Instrument, amended.Side = pending.Type, pending.Instrument, pending.Side
+		if err := checkBracket(&amended, e.account.instruments[pending.Instrument]); err != nil {
+			return err
+		}
+		if err := e.parameters.checkDistance(&amended, e.account.instruments[pending.Instrument]); err != nil {
+			return err
+		}
+	}
+
+	amended := func() {
+		if order.Bracketed() {
+			e.brackets.Set(id, bracketExits{
+				stopLoss:   order.StopLoss,
+				guaranteed: order.GuaranteedStop && order.StopLoss != 0,
+				takeProfit: order.TakeProfit,
+			})
+		} else {
+			e.brackets.Del(id)
+		}
+
+		e.sweeper.amended(id, e.clock.Now())
+		e.parameters.audit.amended(e.clock.Now(), id, order)
+	}
+
+	return e.retryAsync("order modification", AuditRecord{OrderID: id}, amended, func() error {
+		return broker.ModifyOrder(e.account.id, id, order)
+	})
+}
+
+func (e *liveEngine) CancelOrder(id string) error {
+
+	broker, isBroker := e.client.(Broker)
+	if !isBroker {
+		return errors.New("client does not support pending orders")
+	}
+
+	cancelled := func() {
+		e.pendingOrders.remove(id)
+		e.brackets.Del(id)
+		e.tracing.end(orderKey(id), nil)
+		e.account.slippages.drop(orderKey(id))
+		e.parameters.audit.cancelled(e.clock.Now(), id)
+	}
+
+	return e.retryAsync("order cancellation", AuditRecord{OrderID: id}, cancelled, func() error {
+		return broker.CancelOrder(e.account.id, id)
+	})
+}
+
+func (e *liveEngine) PendingOrders(instrument string) []*Order {
+	return e.pendingOrders.pending(instrument)
+}
+
+func (e *liveEngine) SetHedge(instrument string, hedge Hedge) error {
+
+	event, migration, err := migrateHedge(e.account, instrument, hedge, e.clock.Now())
+	if err != nil {
+		return err
+	}
+
+	e.account.events.publish(event)
+
+	for _, id := range migration.closes {
+		if err := e.CloseTrade(instrument, id); err != nil {
+			return err
+		}
+	}
+
+	if migration.units > 0 {
+		return e.openMarketOrder(&Order{Type: MarketOrder, Instrument: instrument, Side: migration.side,
+			Units: migration.units})
+	}
+
+	return nil
+}
+
+func (e *liveEngine) SetStatus(instrument string, status InstrumentStatus) error {
+
+	inst, exist := e.account.instruments[instrument]
+	if !exist {
+		return fmt.Errorf("%s: %w", instrument, ErrInstrumentNotTraded)
+	}
+
+	return setStatus(e.account, inst, status, e.clock.Now(), true)
+}
+
+func (e *liveEngine) StopSession() {
+	e.endOfSession <- true
+}
+
+/***********************************************************************************************
+*
+*											Backtest Engine
+*
+*
+*
+************************************************************************************************/
+
+type btEngine struct {
+	account                  *Account
+	client                   BrokerClient
+	parameters               *sessionParameters
+	strategy                 Strategy
+	currencyConversionEngine *currencyConversionEngine
+	ticks                    chan *Tick
+	tradesCounter            *atomic.Int32
+	ordersCounter            *atomic.Int32
+	orders                   *orderBook
+	clientOrders             *clientOrders
+	basket                   map[*Order]string // trade IDs of the orders of the basket being submitted
+	corporateActions         chan *CorporateAction
+	scheduledActions         []*CorporateAction // received, applied on the first tick at or after their time
+	specUpdates              chan *SpecUpdate
+	scheduledUpdates         []*SpecUpdate // received, applied on the first tick at or after their time
+	instrumentsDetails       map[string]InstrumentDetails
+	latency                  *latencyHooks
+	clock                    *SimulatedClock
+	shared                   *clockMember // of the SharedClock of the parallel backtests
+	margins                  *marginSchedule
+	dividends                *dividendPayer
+	financing                *financingCharger
+	ready                    bool
+	endOfSession             chan bool
+	logger                   Logger
+}
+
+func newBtEngine(logger Logger) *btEngine {
+	return &btEngine{
+		ticks:              make(chan *Tick, 300),
+		tradesCounter:      atomic.NewInt32(0),
+		ordersCounter:      atomic.NewInt32(0),
+		orders:             newOrderBook(),
+		corporateActions:   make(chan *CorporateAction, 100),
+		specUpdates:        make(chan *SpecUpdate, 100),
+		instrumentsDetails: make(map[string]InstrumentDetails),
+		endOfSession:       make(chan bool, 1),
+		logger:             logger,
+	}
+}
+
+func (e *btEngine) start() error {
+
+	e.account = newAccount(e.parameters.account)
+	e.account.engine = e
+	e.account.events = e.parameters.events
+	e.account.ledger.events = e.parameters.events
+	e.account.wal = e.parameters.wal
+	e.account.equityCurve = e.parameters.equityCurve()
+	e.account.daily = newDailyMarks(e.parameters.dayOffset, e.parameters.dayLocation)
+	e.account.fees = newFeeAccruer(e.parameters.managedFees)
+	e.latency = newLatencyHooks(e.parameters.latency)
+	e.clock = e.parameters.clock.(*SimulatedClock)
+	e.clientOrders = newClientOrders("bt", e.clock)
+	e.margins = newMarginSchedule(e.parameters.marginWindows)
+	e.dividends = newDividendPayer(e.parameters.dividends)
+	e.financing = newFinancingCharger(e.parameters.financing, e.parameters.rollover)
+
+	if e.parameters == nil || e.parameters.testParameters == nil {
+		return errors.New("parameters are no defined")
+	}
+
+	// Account Status Retrieval
+	e.account.balance.Store(NewDecimal(e.parameters.testParameters.initialBalance))
+	e.account.ledger.setOpeningBalance(e.parameters.testParameters.initialBalance)
+	e.account.homeCurrency = e.parameters.testParameters.homeCurrency
+	e.account.leverage = e.parameters.testParameters.leverage
+	if e.account.leverage == 0 {
+		e.account.leverage = 1
+	}
+
+	// Initialize Trading Instruments
+	availableInstruments, err := e.client.GetAvailableInstruments(e.account.id)
+	if err != nil {
+		return err
+	}
+
+	availableInstrumentsMap := make(map[string]InstrumentDetails)
+
+	conversionInstruments := make(map[string]*instrumentConversion)
+
+	for _, inst := range availableInstruments {
 
 		availableInstrumentsMap[inst.Name] = inst
 
@@ -499,7 +1388,12 @@ func (e *btEngine) start() error {
 					inst.PipLocation,
 					e.logger,
 				)
-				e.account.instruments[inst.Name].hedgeType = e.parameters.testParameters.hedge
+				e.account.instruments[inst.Name].hedgeType = e.parameters.hedge(inst.Name, e.parameters.testParameters.hedge)
+				e.account.instruments[inst.Name].minUnits.Store(inst.MinUnits)
+				if inst.UnitSize > 0 {
+					e.account.instruments[inst.Name].unitSize = inst.UnitSize
+				}
+				e.account.instruments[inst.Name].stopDistance = inst.StopDistance
 				conversionInstruments[inst.Name] = newInstrumentConversion(
 					inst.Name,
 					inst.BaseCurrency,
@@ -522,6 +1416,42 @@ func (e *btEngine) start() error {
 
 	e.currencyConversionEngine.setPricePointers(e.account.instruments)
 
+	subscriptions, err := e.parameters.synthetics.add(e.account, availableInstrumentsMap,
+		e.currencyConversionEngine.conversionInstrumentsDetails, e.logger)
+	if err != nil {
+		return err
+	}
+	e.currencyConversionEngine.conversionInstrumentsDetails = subscriptions
+
+	if e.parameters.snapshot != nil {
+		e.account.balance.Store(NewDecimal(e.parameters.snapshot.Balance))
+		e.account.restoreTrades(e.parameters.snapshot, false)
+		e.account.restoreLedger(e.parameters.snapshot)
+		e.account.fees.restore(e.parameters.snapshot.Accruals)
+	}
+
+	if err := e.account.replayWAL(e.parameters.wal, e.parameters.snapshot, false); err != nil {
+		return err
+	}
+
+	if e.parameters.snapshot != nil || e.parameters.wal != nil {
+		e.tradesCounter.Store(e.account.Snapshot().maxTradeID())
+	}
+
+	if notifier, isNotifier := e.client.(CorporateActionNotifier); isNotifier {
+		err = notifier.SubscribeCorporateActions(e.account.id, e.onCorporateAction)
+		if err != nil {
+			return err
+		}
+	}
+
+	if notifier, isNotifier := e.client.(SpecNotifier); isNotifier {
+		err = notifier.SubscribeSpecUpdates(e.account.id, e.onSpecUpdate)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Subscribe prices
 	err = e.client.SubscribePrices(e.account.id, e.currencyConversionEngine.conversionInstrumentsDetails, e.onTick)
 	if err != nil {
@@ -533,118 +1463,419 @@ func (e *btEngine) start() error {
 	e.strategy.Initialize()
 
 	// Run strategy
+	e.account.collectStats(e.parameters.stats)
+	e.parameters.stats.watch(e.ticks, nil)
+	e.account.recalculator = newRecalculator(e.account.instruments, e.parameters.recalculationShards)
+	e.shared = e.parameters.sharedClock.join()
 	e.run()
+	e.shared.leave()
+	e.account.recalculator.stop()
 
 	// Stop strategy
+	e.account.events.publish(SessionClose{Time: e.clock.Now()})
 	e.strategy.OnStop()
 
 	return nil
 }
 
 func (e *btEngine) onTick(tick *Tick) { // Ticks callback
+
+	synthetic := e.parameters.synthetics.update(tick) // before the tick is queued, and may be released
+
 	e.ticks <- tick
+
+	for _, t := range synthetic {
+		e.ticks <- t
+	}
 }
 
-func (e *btEngine) onOrderOpen(instrument string, units int32, side Side) {
+func (e *btEngine) onCorporateAction(action *CorporateAction) { // Corporate actions callback
+	e.corporateActions <- action
+}
 
-	var (
-		price float64
-		order *OrderFill
-	)
+func (e *btEngine) onSpecUpdate(update *SpecUpdate) { // Instrument specification updates callback
+	e.specUpdates <- update
+}
+
+func (e *btEngine) onOrderOpen(instrument string, units int32, side Side) error {
+
+	order := &Order{
+		ID:         strconv.FormatInt(int64(e.ordersCounter.Inc()), 10),
+		Type:       MarketOrder,
+		Instrument: instrument,
+		Side:       side,
+		Units:      units,
+		CreateTime: e.clock.Now(),
+	}
+
+	e.account.events.publish(OrderSubmitted{Time: order.CreateTime, Order: order})
 
-	if side == Long {
+	return e.executeOrder(order)
+}
+
+// executeOrder fills the order at the current price, it is not filled when the margin is insufficient.
+func (e *btEngine) executeOrder(o *Order) error {
+	return e.executeOrderAt(o, 0)
+}
+
+// executeOrderAt fills an order at a price, e.g. the level of a limit order triggered by a gap, zero fills it at
+// the current price.
+func (e *btEngine) executeOrderAt(o *Order, price float64) error {
+
+	instrument := o.Instrument
+
+	if price == 0 && o.Side == Long {
 		price = e.account.instruments[instrument].Ask()
-	} else {
+	} else if price == 0 {
 		price = e.account.instruments[instrument].Bid()
 	}
 
+	marginUsed := e.marginOf(instrument, o.Units)
+
+	tradeID := strconv.FormatInt(int64(e.tradesCounter.Inc()), 10)
+	time := e.clock.Now()
+
+	if marginUsed >= e.account.marginFree {
+		return fmt.Errorf("%s: %w", instrument, ErrInsufficientMargin)
+	}
+
+	guaranteed := o.GuaranteedStop && o.StopLoss != 0
+	premium := 0.0
+	if guaranteed && e.parameters.guaranteedStopPremium != nil {
+		premium = -e.parameters.guaranteedStopPremium.Commission(instrument, o.Units, price)
+	}
+
+	e.account.wal.write(&WALEntry{
+		Operation:  WALOpenTrade,
+		Time:       time,
+		Instrument: instrument,
+		TradeID:    tradeID,
+		Side:       o.Side,
+		Units:      o.Units,
+		Price:      price,
+		Fees:       premium,
+		StopLoss:   o.StopLoss,
+		Guaranteed: guaranteed,
+		TakeProfit: o.TakeProfit,
+		Expiry:     o.expiry(time),
+		Tag:        o.Tag,
+	}, e.logger)
+
+	e.account.writes.begin()
+	trade := e.account.instruments[instrument].openTrade(
+		tradeID,
+		o.Side,
+		time,
+		o.Units,
+		price,
+	)
+	e.account.instruments[instrument].paySpread(trade)
+	e.account.instruments[instrument].amend(func() {
+		trade.stopLoss = o.StopLoss
+		trade.guaranteedStop = guaranteed
+		trade.takeProfit = o.TakeProfit
+		trade.expiry = o.expiry(time)
+		trade.tag = o.Tag
+		trade.gapFill = e.parameters.gaps.gapped(instrument)
+	})
+
+	if _, basket := e.basket[o]; basket {
+		e.basket[o] = tradeID
+	}
+
+	if markup := e.parameters.markupOf(e.account.instruments[instrument]); markup != nil {
+		trade.itemize(MarkupFee, NewDecimal(markup.cost(o.Side, price, e.account.instruments[instrument].pipLocation)).
+			MulInt(int64(o.Units)).MulFloat(trade.ccyConversion.QuoteConversionRate.Load()))
+	}
+
+	if premium != 0 {
+		trade.charge(GuaranteedStopFee, NewDecimal(premium))
+		e.account.instruments[instrument].recalculate()
+	}
+
+	e.account.aggregate()
+	e.account.writes.end()
+
+	order := &OrderFill{
+		TradeClose:    false,
+		OrderID:       o.ID,
+		TradeID:       tradeID,
+		Side:          o.Side,
+		Instrument:    e.instrumentsDetails[instrument],
+		Price:         price,
+		Units:         o.Units,
+		Profit:        0.0,
+		ChargedFees:   premium,
+		Time:          time,
+		Tag:           o.Tag,
+		ClientOrderID: o.ClientID,
+		Gap:           trade.gapFill,
+	}
+
+	e.account.events.publishFill(order, trade)
+	e.strategy.OnOrderFill(order)
+
+	return nil
+}
+
+// marginOf returns the margin used by a trade of the units of the instrument.
+func (e *btEngine) marginOf(instrument string, units int32) Decimal {
+
 	leverage := e.account.instruments[instrument].leverage
 	conversionRate := e.account.instruments[instrument].ccyConversion.BaseConversionRate.Load()
-	marginUsed := float64(units) / leverage.Load() / conversionRate
+	multiplier := e.account.instruments[instrument].MarginMultiplier()
 
-	tradeID := strconv.FormatInt(int64(e.tradesCounter.Inc()), 10)
-	time := e.account.time
-
-	if marginUsed < e.account.marginFree {
-
-		e.account.instruments[instrument].openTrade(
-			tradeID,
-			side,
-			time,
-			units,
-			price,
-		)
-
-		e.account.calculateMarginUsed()
-		e.account.calculateFreeMargin()
-
-		order = &OrderFill{
-			TradeClose:  false,
-			OrderID:     tradeID,
-			TradeID:     tradeID,
-			Side:        side,
-			Instrument:  e.instrumentsDetails[instrument],
-			Price:       price,
-			Units:       units,
-			Profit:      0.0,
-			ChargedFees: 0.0,
-			Time:        time,
+	return DecimalFromInt(int64(units)).MulFloat(multiplier / leverage.Load() / conversionRate)
+}
+
+// submitOrder fills the market and immediate orders and books the pending ones.
+func (e *btEngine) submitOrder(inst *Instrument, order *Order) (string, error) {
+
+	order.ID = strconv.FormatInt(int64(e.ordersCounter.Inc()), 10)
+	order.CreateTime = e.clock.Now()
+
+	e.latency.submitted(e.latency.decision())
+	e.account.events.publish(OrderSubmitted{Time: order.CreateTime, Order: order})
+
+	if order.Type == MarketOrder {
+		if err := e.executeOrder(order); err != nil {
+			return "", err
 		}
+		return order.ID, nil
+	}
 
-	} else {
-		order = &OrderFill{
-			Error:      "NOT_ENOUGH_MARGIN",
-			Side:       side,
-			Instrument: e.instrumentsDetails[instrument],
-			Time:       time,
+	if order.TimeInForce == FillOrKill || order.TimeInForce == ImmediateOrCancel {
+
+		if !order.Triggered(inst.Bid(), inst.Ask()) {
+			return "", errors.New("order can not be filled immediately")
 		}
+
+		if err := e.executeOrder(order); err != nil {
+			return "", err
+		}
+		return order.ID, nil
 	}
 
-	e.strategy.OnOrderFill(order)
+	e.orders.add(order)
+
+	return order.ID, nil
 }
 
-func (e *btEngine) onCloseTrade(tradeID, instrument string) {
+// processOrders expires and fills the pending orders and closes the trades that hit their exit levels.
+func (e *btEngine) processOrders(tick *Tick) {
 
-	var (
-		order *OrderFill
-	)
+	for _, order := range e.orders.expired(e.clock.Now()) {
+		e.rejectOrder(order, "ORDER_EXPIRED")
+		e.account.events.publish(OrderExpired{Time: e.clock.Now(), Order: order})
+	}
 
-	tr := e.account.instruments[instrument].Trade(tradeID)
+	instrument := tick.Instrument
+	inst := e.account.instruments[instrument]
+	queued := make(map[*Order]bool) // limit orders filled at their level by their queue position
 
-	if tr != nil {
+	trigger := func(order *Order) bool {
 
-		e.account.balance.Add(tr.unrealizedEffectiveProfit)
-		e.account.instruments[instrument].closeTrade(tradeID)
-		e.account.calculateUnrealized()
-		e.account.calculateMarginUsed()
-		e.account.calculateFreeMargin()
+		if order.Triggered(inst.Bid(), inst.Ask()) {
+			return true
+		}
 
-		order = &OrderFill{
-			Error:       "",
-			TradeClose:  true,
-			OrderID:     tradeID,
-			TradeID:     tradeID,
-			Side:        tr.side,
-			Instrument:  e.instrumentsDetails[instrument],
-			Price:       tr.CurrentPrice(),
-			Units:       tr.units,
-			Profit:      tr.unrealizedNetProfit,
-			ChargedFees: 0.0,
-			Time:        e.account.time,
+		if e.parameters.queueFills && order.Type == LimitOrder && order.advanceQueue(tick) {
+			queued[order] = true
+			return true
 		}
 
-	} else {
+		return false
+	}
+
+	for _, order := range e.orders.triggered(instrument, trigger) {
+
+		if err := e.parameters.news.check(e.account, instrument, e.clock.Now()); err != nil {
+			e.rejectOrder(order, "TRADING_PAUSED")
+			continue
+		}
+
+		if err := checkSpec(e.account, instrument, order.Side, order.Units); errors.Is(err, ErrBelowMinUnits) {
+			e.rejectOrder(order, "UNITS_BELOW_MINIMUM")
+			continue
+		} else if errors.Is(err, ErrLimitState) {
+			e.rejectOrder(order, "LIMIT_STATE")
+			continue
+		} else if err != nil {
+			e.rejectOrder(order, statusRejection(err))
+			continue
+		}
+
+		if err := e.parameters.compliance.checkOpen(e.account, instrument, order.Side, order.Units); err != nil {
+			e.rejectOrder(order, "COMPLIANCE_VIOLATION")
+			continue
+		}
 
-		order = &OrderFill{
-			Error:      "TRADE_DOES_NOT_EXIST",
-			TradeClose: true,
-			Time:       e.account.time,
-			Instrument: e.instrumentsDetails[instrument],
+		price := 0.0 // without the price improvement of a gap
+		if order.Type == LimitOrder && (queued[order] || e.parameters.gaps.pessimistic(instrument)) {
+			price = order.Price
+		}
+
+		if err := e.executeOrderAt(order, price); errors.Is(err, ErrInsufficientMargin) {
+			e.rejectOrder(order, "NOT_ENOUGH_MARGIN")
+		}
+	}
+
+	type exit struct {
+		price  float64 // zero at the current price
+		reason CloseReason
+	}
+
+	exits := make(map[string]exit) // by trade ID
+	now := e.clock.Now()
+	flatten := e.parameters.flattening(instrument, now)
+
+	for _, position := range []*Position{inst.longPosition, inst.shortPosition} {
+		for _, trade := range position.list() {
+			if trade.stopLossHit() && trade.guaranteedStop {
+				exits[trade.id] = exit{price: trade.stopLoss, reason: StopLossClose}
+			} else if trade.stopLossHit() {
+				exits[trade.id] = exit{reason: StopLossClose}
+			} else if trade.takeProfitHit() && e.parameters.gaps.pessimistic(instrument) {
+				exits[trade.id] = exit{price: trade.takeProfit, reason: TakeProfitClose}
+			} else if trade.takeProfitHit() {
+				exits[trade.id] = exit{reason: TakeProfitClose}
+			} else if trade.expired(now) {
+				exits[trade.id] = exit{reason: Expired}
+			} else if flatten {
+				exits[trade.id] = exit{reason: Flattened}
+			}
 		}
 	}
 
+	for _, position := range []*Position{inst.longPosition, inst.shortPosition} {
+		for _, trade := range position.list() {
+			if x, exist := exits[trade.id]; exist {
+				e.closeTradeAt(trade.id, instrument, x.price, x.reason)
+			}
+		}
+	}
+}
+
+// rejectOrder notifies the pending order failure as an order fill.
+func (e *btEngine) rejectOrder(o *Order, reason string) {
+
+	fill := &OrderFill{
+		Error:         reason,
+		OrderID:       o.ID,
+		Side:          o.Side,
+		Instrument:    e.instrumentsDetails[o.Instrument],
+		Units:         o.Units,
+		Time:          e.clock.Now(),
+		Tag:           o.Tag,
+		ClientOrderID: o.ClientID,
+	}
+
+	classifyRejection(fill)
+	e.clientOrders.fill(fill)
+	e.account.events.publishFill(fill, nil)
+	e.strategy.OnOrderFill(fill)
+	notifyRejection(e.strategy, fill)
+}
+
+func (e *btEngine) onCloseTrade(tradeID, instrument string) error {
+	return e.closeTradeAt(tradeID, instrument, 0, CloseRequested)
+}
+
+// closeTradeAt closes a trade at a price, e.g. the level of a guaranteed stop, zero closes it at the current price.
+func (e *btEngine) closeTradeAt(tradeID, instrument string, price float64, reason CloseReason) error {
+
+	inst := e.account.instruments[instrument]
+
+	tr := inst.Trade(tradeID)
+	if tr == nil {
+		return fmt.Errorf("%s trade %s: %w", instrument, tradeID, ErrTradeNotFound)
+	}
+
+	inst.lock.RLock()
+	netProfit, effectiveProfit := tr.unrealizedNetProfit, tr.unrealizedEffectiveProfit
+	if price != 0 {
+		netProfit, effectiveProfit = tr.profitAt(price)
+	} else {
+		price = tr.CurrentPrice()
+	}
+	inst.lock.RUnlock()
+
+	transaction := &Transaction{
+		Type:       TradeCloseTransaction,
+		TradeID:    tradeID,
+		Instrument: instrument,
+		Side:       tr.side,
+		Units:      tr.units,
+		OpenPrice:  tr.openPrice,
+		ClosePrice: price,
+		OpenTime:   tr.openTime,
+		Amount:     effectiveProfit.Float64(),
+		Fees:       tr.ChargedFees(),
+		SpreadCost: inst.closeSpread(tr).Float64(),
+		Time:       e.clock.Now(),
+		Tag:        tr.tag,
+		Reason:     reason,
+	}
+
+	e.account.wal.write(&WALEntry{
+		Operation:   WALCloseTrade,
+		Time:        e.clock.Now(),
+		Instrument:  instrument,
+		TradeID:     tradeID,
+		Transaction: transaction,
+	}, e.logger)
+
+	e.account.writes.begin()
+	transaction.Balance = e.account.balance.Add(effectiveProfit).Float64()
+	e.account.record(transaction)
+	inst.closeTrade(tradeID)
+	e.account.writes.end()
+	e.protectBalance()
+	e.account.aggregate()
+
+	order := &OrderFill{
+		Error:       "",
+		TradeClose:  true,
+		OrderID:     tradeID,
+		TradeID:     tradeID,
+		Side:        tr.side,
+		Instrument:  e.instrumentsDetails[instrument],
+		Price:       price,
+		Units:       tr.units,
+		Profit:      netProfit.Float64(),
+		ChargedFees: 0.0,
+		Time:        e.clock.Now(),
+		Tag:         tr.tag,
+		Reason:      reason,
+		Gap:         e.parameters.gaps.gapped(instrument),
+	}
+
+	e.account.events.publishFill(order, nil)
 	e.strategy.OnOrderFill(order)
 
+	return nil
+}
+
+// protectBalance writes a negative balance back to zero with the negative balance protection.
+func (e *btEngine) protectBalance() {
+
+	balance := e.account.balance.Load()
+	if !e.parameters.negativeBalanceProtection || balance.Sign() >= 0 {
+		return
+	}
+
+	transaction := &Transaction{
+		Type:   BalanceAdjustmentTransaction,
+		Amount: balance.Neg().Float64(),
+		Time:   e.clock.Now(),
+	}
+
+	e.account.wal.write(&WALEntry{Operation: WALAdjustment, Time: transaction.Time, Transaction: transaction}, e.logger)
+
+	e.account.writes.begin()
+	transaction.Balance = e.account.balance.Add(balance.Neg()).Float64()
+	e.account.record(transaction)
+	e.account.writes.end()
 }
 
 func (e *btEngine) run() {
@@ -660,18 +1891,48 @@ func (e *btEngine) run() {
 				return
 			}
 
-			if _, exist := e.account.instruments[tick.Instrument]; exist {
+			if !e.parameters.tickOrder.accept(tick) {
+				e.parameters.stats.tickDiscarded()
+				releaseTick(tick)
+				continue
+			}
+
+			e.shared.wait(tick.Time)
+			e.applyCorporateActions(tick.Time)
+			e.applySpecUpdates(tick.Time)
+			for _, update := range e.account.limitExpiries(tick.Time) {
+				e.applySpecUpdate(update)
+			}
+
+			e.latency.arrived(tick)
+
+			if inst, exist := e.account.instruments[tick.Instrument]; exist {
 
-				e.account.instruments[tick.Instrument].updatePrice(tick)
+				e.parameters.stats.tickProcessed()
+				e.parameters.respread(inst, tick)
+				e.parameters.markUp(inst, tick)
+				e.parameters.news.widen(inst, tick)
+				inst.updatePrice(tick)
+				e.publishGap(inst, tick)
+				e.account.alerts.evaluate(tick, e.account.events)
 				e.currencyConversionEngine.updateRate(tick.Instrument)
-				e.account.time = tick.Time
+				e.account.setTime(tick.Time)
+				e.clock.Set(tick.Time)
+				e.margins.update(e.account, tick.Time)
+				e.dividends.pay(e.account, tick.Time, e.logger)
+				e.financing.charge(e.account, e.instrumentsDetails, tick.Time, e.logger)
 
 				if e.ready {
-					e.account.calculateUnrealized()
-					e.account.calculateMarginUsed()
-					e.account.calculateFreeMargin()
+					e.account.recalculate()
+					e.account.fees.accrue(e.account, tick.Time, e.logger)
+					e.account.checkMarginCall(e.parameters.marginCallLevel)
+					e.account.checkStale(tick.Time, e.parameters.staleAfter, nil)
 
+					e.processOrders(tick)
+
+					e.latency.deciding(tick)
 					e.strategy.OnTick(tick)
+					e.latency.decided(tick)
 				} else {
 					e.checkState()
 				}
@@ -684,10 +1945,115 @@ func (e *btEngine) run() {
 					inst.Bid.Store(tick.Bid)
 					inst.Ask.Store(tick.Ask)
 					e.currencyConversionEngine.updateRate(tick.Instrument)
-				} else {
+				} else if !e.parameters.synthetics.leg(tick.Instrument) {
 					e.logger.Warn("received a tick from an instrument that was not subscribed and it has been ignored")
 				}
 			}
+
+			releaseTick(tick)
+		}
+	}
+}
+
+// publishGap publishes the gap of the instrument price updated by the tick, if any.
+func (e *btEngine) publishGap(inst *Instrument, tick *Tick) {
+	if gap := e.parameters.gaps.update(inst, tick, e.parameters.calendar(inst.name)); gap != nil {
+		e.account.events.publish(*gap)
+	}
+}
+
+// applyCorporateActions applies the corporate actions received whose time is not after t, in time order, so
+// the backtests replay them at the same ticks.
+func (e *btEngine) applyCorporateActions(t time.Time) {
+
+	for received := true; received; {
+		select {
+		case action := <-e.corporateActions:
+			e.scheduledActions = append(e.scheduledActions, action)
+		default:
+			received = false
+		}
+	}
+
+	if len(e.scheduledActions) == 0 {
+		return
+	}
+
+	sort.SliceStable(e.scheduledActions, func(i, j int) bool {
+		return e.scheduledActions[i].Time.Before(e.scheduledActions[j].Time)
+	})
+
+	due := 0
+	for due < len(e.scheduledActions) && !e.scheduledActions[due].Time.After(t) {
+		e.applyCorporateAction(e.scheduledActions[due])
+		due++
+	}
+
+	e.scheduledActions = e.scheduledActions[due:]
+}
+
+// applyCorporateAction adjusts the account to a corporate action, between the ticks.
+func (e *btEngine) applyCorporateAction(action *CorporateAction) {
+
+	event, err := applyCorporateAction(e.account, e.currencyConversionEngine, e.orders, action, e.logger)
+	if err != nil {
+		e.logger.Errorf("corporate action %s: %v", action.Type, err)
+		return
+	}
+
+	if action.Type == TickerChange {
+		details := e.instrumentsDetails[action.Instrument]
+		details.Name = action.NewInstrument
+		e.instrumentsDetails[action.NewInstrument] = details
+	}
+
+	e.account.events.publish(event)
+}
+
+// applySpecUpdates applies the specification updates received whose time is not after t, in time order.
+func (e *btEngine) applySpecUpdates(t time.Time) {
+
+	for received := true; received; {
+		select {
+		case update := <-e.specUpdates:
+			e.scheduledUpdates = append(e.scheduledUpdates, update)
+		default:
+			received = false
+		}
+	}
+
+	if len(e.scheduledUpdates) == 0 {
+		return
+	}
+
+	sort.SliceStable(e.scheduledUpdates, func(i, j int) bool {
+		return e.scheduledUpdates[i].Time.Before(e.scheduledUpdates[j].Time)
+	})
+
+	due := 0
+	for due < len(e.scheduledUpdates) && !e.scheduledUpdates[due].Time.After(t) {
+		e.applySpecUpdate(e.scheduledUpdates[due])
+		due++
+	}
+
+	e.scheduledUpdates = e.scheduledUpdates[due:]
+}
+
+// applySpecUpdate updates an instrument to the specification pushed by the broker, between the ticks.
+func (e *btEngine) applySpecUpdate(update *SpecUpdate) {
+
+	event, err := applySpecUpdate(e.account, e.parameters.specPolicy, update)
+	if err != nil {
+		e.logger.Errorf("instrument specification update: %v", err)
+		return
+	}
+
+	e.account.aggregate()
+	e.account.events.publish(event)
+
+	for _, id := range event.Closed {
+		if err := e.closeTradeAt(id, update.Instrument, 0, SpecAdjusted); err != nil {
+			e.logger.Errorf("closing the trade %s (%s): %v", id, SpecAdjusted, err)
 		}
 	}
 }
@@ -713,22 +2079,258 @@ func (e *btEngine) Account() *Account {
 	return e.account
 }
 
-func (e *btEngine) Buy(instrument string, units int32) {
+func (e *btEngine) Buy(instrument string, units int32) error {
+
+	if err := checkInstrument(e.account, e.parameters.calendar(instrument), instrument, e.clock.Now()); err != nil {
+		return err
+	}
+
+	if err := e.parameters.news.check(e.account, instrument, e.clock.Now()); err != nil {
+		return err
+	}
+
+	if err := checkSpec(e.account, instrument, Long, units); err != nil {
+		return err
+	}
+
+	if err := e.parameters.compliance.checkOpen(e.account, instrument, Long, units); err != nil {
+		return err
+	}
+
+	e.latency.submitted(e.latency.decision())
+
+	return e.onOrderOpen(instrument, units, Long)
+}
+
+func (e *btEngine) Sell(instrument string, units int32) error {
+
+	if err := checkInstrument(e.account, e.parameters.calendar(instrument), instrument, e.clock.Now()); err != nil {
+		return err
+	}
+
+	if err := e.parameters.news.check(e.account, instrument, e.clock.Now()); err != nil {
+		return err
+	}
+
+	if err := checkSpec(e.account, instrument, Short, units); err != nil {
+		return err
+	}
+
+	if err := e.parameters.compliance.checkOpen(e.account, instrument, Short, units); err != nil {
+		return err
+	}
+
+	e.latency.submitted(e.latency.decision())
+
+	return e.onOrderOpen(instrument, units, Short)
+}
+
+func (e *btEngine) CloseTrade(instrument, id string) error {
+
+	if err := checkInstrument(e.account, e.parameters.calendar(instrument), instrument, e.clock.Now()); err != nil {
+		return err
+	}
+
+	if err := e.parameters.compliance.checkClose(e.account, instrument, id); err != nil {
+		return err
+	}
+
+	e.latency.submitted(e.latency.decision())
+
+	return e.onCloseTrade(id, instrument)
+}
+
+func (e *btEngine) SubmitOrder(order *Order) (string, error) {
+
+	if err := checkInstrument(e.account, e.parameters.calendar(order.Instrument), order.Instrument, e.clock.Now()); err != nil {
+		return "", err
+	}
+
+	if err := e.parameters.news.check(e.account, order.Instrument, e.clock.Now()); err != nil {
+		return "", err
+	}
+
+	if err := checkSpec(e.account, order.Instrument, order.Side, order.Units); err != nil {
+		return "", err
+	}
+
+	if err := e.parameters.compliance.checkOpen(e.account, order.Instrument, order.Side, order.Units); err != nil {
+		return "", err
+	}
+
+	inst := e.account.instruments[order.Instrument]
+
+	if order.Units <= 0 {
+		return "", errors.New("order units must be positive")
+	}
+
+	if err := checkBracket(order, inst); err != nil {
+		return "", err
+	}
+
+	if err := e.parameters.checkDistance(order, inst); err != nil {
+		return "", err
+	}
+
+	if id, submitted, err := e.clientOrders.begin(order); err != nil || submitted {
+		return id, err
+	}
+
+	id, err := e.submitOrder(inst, order)
+	e.clientOrders.end(order.ClientID, id, err)
+
+	return id, err
+}
+
+func (e *btEngine) SubmitBasket(orders []*Order) ([]string, error) {
+
+	if err := checkBasket(orders); err != nil {
+		return nil, err
+	}
+
+	margin := Decimal(0)
+
+	for i, order := range orders {
+
+		err := checkInstrument(e.account, e.parameters.calendar(order.Instrument), order.Instrument, e.clock.Now())
+		if err == nil {
+			err = e.parameters.news.check(e.account, order.Instrument, e.clock.Now())
+		}
+		if err == nil {
+			err = checkSpec(e.account, order.Instrument, order.Side, order.Units)
+		}
+		if err == nil {
+			err = e.parameters.compliance.checkOpen(e.account, order.Instrument, order.Side, order.Units)
+		}
+		if err == nil {
+			err = checkBracket(order, e.account.instruments[order.Instrument])
+		}
+		if err == nil {
+			err = e.parameters.checkDistance(order, e.account.instruments[order.Instrument])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("basket order %d: %w", i, err)
+		}
+
+		margin = margin.Add(e.marginOf(order.Instrument, order.Units))
+	}
+
+	if margin >= e.account.marginFree {
+		return nil, fmt.Errorf("basket: %w", ErrInsufficientMargin)
+	}
+
+	if e.basket != nil {
+		return nil, errors.New("basket submitted on the fill of another basket")
+	}
+
+	e.basket = make(map[*Order]string, len(orders))
+	defer func() { e.basket = nil }()
+
+	for _, order := range orders {
+		e.basket[order] = ""
+	}
+
+	ids := make([]string, 0, len(orders))
+
+	for i, order := range orders {
+
+		id, err := e.SubmitOrder(order)
+		if err != nil { // e.g. the margin taken by the trades opened on the fills of the previous orders
+			for _, filled := range orders[:i] {
+				if err := e.closeTradeAt(e.basket[filled], filled.Instrument, 0, RolledBack); err != nil {
+					e.logger.Errorf("rolling back the trade %s of a basket: %v", e.basket[filled], err)
+				}
+			}
+			return nil, fmt.Errorf("basket order %d: %w", i, err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (e *btEngine) ModifyOrder(id string, order *Order) error {
+
+	pending, exist := e.orders.get(id)
+	if !exist {
+		return fmt.Errorf("order %s: %w", id, ErrOrderNotFound)
+	}
+
+	amended := *order
+	amended.Type, amended.Instrument, amended.Side = pending.Type, pending.Instrument, pending.Side
+	if err := checkBracket(&amended, e.account.instruments[pending.Instrument]); err != nil {
+		return err
+	}
+
+	if err := e.parameters.checkDistance(&amended, e.account.instruments[pending.Instrument]); err != nil {
+		return err
+	}
+
+	if order.Price != pending.Price || order.Units > pending.Units {
+		pending.queue = nil // back of the queue
+	}
+
+	pending.Units = order.Units
+	pending.Price = order.Price
+	pending.StopLoss = order.StopLoss
+	pending.GuaranteedStop = order.GuaranteedStop
+	pending.TakeProfit = order.TakeProfit
+	pending.MaxLifetime = order.MaxLifetime
+	pending.TimeInForce = order.TimeInForce
+	pending.Expiry = order.Expiry
+
+	e.parameters.audit.amended(e.clock.Now(), id, pending)
+
+	return nil
+}
+
+func (e *btEngine) CancelOrder(id string) error {
 
-	e.onOrderOpen(instrument, units, Long)
+	if _, exist := e.orders.remove(id); !exist {
+		return fmt.Errorf("order %s: %w", id, ErrOrderNotFound)
+	}
 
+	e.parameters.audit.cancelled(e.clock.Now(), id)
+
+	return nil
+}
+
+func (e *btEngine) PendingOrders(instrument string) []*Order {
+	return e.orders.pending(instrument)
 }
 
-func (e *btEngine) Sell(instrument string, units int32) {
+func (e *btEngine) SetHedge(instrument string, hedge Hedge) error {
+
+	event, migration, err := migrateHedge(e.account, instrument, hedge, e.clock.Now())
+	if err != nil {
+		return err
+	}
 
-	e.onOrderOpen(instrument, units, Short)
+	e.account.aggregate()
+	e.account.events.publish(event)
 
+	for _, id := range migration.closes {
+		if err := e.onCloseTrade(id, instrument); err != nil {
+			return err
+		}
+	}
+
+	if migration.units > 0 {
+		return e.onOrderOpen(instrument, migration.units, migration.side)
+	}
+
+	return nil
 }
 
-func (e *btEngine) CloseTrade(instrument, id string) {
+func (e *btEngine) SetStatus(instrument string, status InstrumentStatus) error {
 
-	e.onCloseTrade(id, instrument)
+	inst, exist := e.account.instruments[instrument]
+	if !exist {
+		return fmt.Errorf("%s: %w", instrument, ErrInstrumentNotTraded)
+	}
 
+	return setStatus(e.account, inst, status, e.clock.Now(), true)
 }
 
 func (e *btEngine) StopSession() {
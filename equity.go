@@ -0,0 +1,127 @@
+package gotrader
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// EquityPoint is a sample of the account equity and balance.
+type EquityPoint struct {
+	Time    time.Time
+	Equity  float64
+	Balance float64
+}
+
+/*
+EquityCurve samples the account equity on the ticks, see the TrackEquity option. The curve keeps a point per
+resolution interval, starting at the interval start, with the last equity of the interval, so the last point
+moves until the interval ends. The high-water mark and the drawdowns are tracked on every tick.
+
+It is safe to read from any goroutine, e.g. by a dashboard, while the session runs.
+*/
+type EquityCurve struct {
+	mutex         *sync.RWMutex
+	resolution    time.Duration
+	points        []EquityPoint
+	highWaterMark float64
+	drawdown      float64
+	maxDrawdown   float64
+}
+
+func newEquityCurve(resolution time.Duration) *EquityCurve {
+	return &EquityCurve{
+		mutex:      &sync.RWMutex{},
+		resolution: resolution,
+		points:     make([]EquityPoint, 0, 1024),
+	}
+}
+
+func (c *EquityCurve) record(t time.Time, equity, balance float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	point := EquityPoint{Time: t, Equity: equity, Balance: balance}
+	if c.resolution > 0 {
+		point.Time = t.Truncate(c.resolution)
+	}
+
+	if n := len(c.points); n > 0 && !point.Time.After(c.points[n-1].Time) {
+		c.points[n-1].Equity = equity
+		c.points[n-1].Balance = balance
+	} else {
+		c.points = append(c.points, point)
+	}
+
+	if equity > c.highWaterMark {
+		c.highWaterMark = equity
+	}
+
+	c.drawdown = 0
+	if c.highWaterMark > 0 {
+		c.drawdown = (c.highWaterMark - equity) / c.highWaterMark
+	}
+
+	if c.drawdown > c.maxDrawdown {
+		c.maxDrawdown = c.drawdown
+	}
+}
+
+// Resolution returns the interval of the points, every tick is a point when it is not positive.
+func (c *EquityCurve) Resolution() time.Duration {
+	return c.resolution
+}
+
+// Points returns a copy of the points of the curve, by time order.
+func (c *EquityCurve) Points() []EquityPoint {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return append([]EquityPoint(nil), c.points...)
+}
+
+// Since returns a copy of the points at or after t, by time order.
+func (c *EquityCurve) Since(t time.Time) []EquityPoint {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	i := sort.Search(len(c.points), func(i int) bool { return !c.points[i].Time.Before(t) })
+
+	return append([]EquityPoint(nil), c.points[i:]...)
+}
+
+// Last returns the last point, the zero point before the first tick.
+func (c *EquityCurve) Last() EquityPoint {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if len(c.points) == 0 {
+		return EquityPoint{}
+	}
+
+	return c.points[len(c.points)-1]
+}
+
+// HighWaterMark returns the highest equity since the session started.
+func (c *EquityCurve) HighWaterMark() float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.highWaterMark
+}
+
+// Drawdown returns the current drawdown of the equity from the high-water mark, as a fraction.
+func (c *EquityCurve) Drawdown() float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.drawdown
+}
+
+// MaxDrawdown returns the maximum drawdown of the equity since the session started, as a fraction.
+func (c *EquityCurve) MaxDrawdown() float64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.maxDrawdown
+}
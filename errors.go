@@ -0,0 +1,115 @@
+package gotrader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The errors returned by the engine mutations, they are wrapped with the instrument, trade or order concerned
+// and are matched with errors.Is.
+var (
+	// ErrInstrumentNotTraded is returned when the instrument is not one of the session instruments
+	ErrInstrumentNotTraded = errors.New("instrument is not being traded")
+
+	// ErrTradeNotFound is returned when the trade is not open
+	ErrTradeNotFound = errors.New("trade not found")
+
+	// ErrOrderNotFound is returned when the order is not pending
+	ErrOrderNotFound = errors.New("order not found")
+
+	// ErrInsufficientMargin is returned when the free margin doesn't cover the margin of the order
+	ErrInsufficientMargin = errors.New("insufficient margin")
+
+	// ErrMarketClosed is returned when the MarketHours calendar of the session is closed
+	ErrMarketClosed = errors.New("market is closed")
+
+	// ErrTradingPaused is returned when the opens of the instrument are paused around an economic event
+	ErrTradingPaused = errors.New("trading is paused")
+
+	// ErrDuplicateOrder is returned when an order is submitted again with the client ID of an order in flight
+	ErrDuplicateOrder = errors.New("duplicate order in flight")
+
+	// ErrComplianceViolation is matched by the *ComplianceError of the opens and closes breaching the ComplianceRules
+	ErrComplianceViolation = errors.New("compliance violation")
+
+	// ErrInstrumentHalted is returned when the trading of the instrument is halted (see InstrumentStatus)
+	ErrInstrumentHalted = errors.New("instrument is halted")
+
+	// ErrCloseOnly is returned when the opens of the instrument are rejected by its close only status
+	ErrCloseOnly = errors.New("instrument is close only")
+
+	// ErrInstrumentExpired is returned when the instrument is expired
+	ErrInstrumentExpired = errors.New("instrument is expired")
+
+	// ErrBelowMinUnits is returned when the units of an open are below the minimum units of the instrument
+	ErrBelowMinUnits = errors.New("units below the instrument minimum")
+
+	// ErrInvalidQuantity is returned when a Quantity is not a positive multiple of the unit size of the instrument
+	ErrInvalidQuantity = errors.New("invalid quantity")
+
+	// ErrLimitState is matched by the *LimitError of the opens blocked by the limit state of the instrument
+	ErrLimitState = errors.New("instrument is in a limit state")
+
+	// ErrStopDistance is matched by the *DistanceError of the order levels closer than the minimum distance
+	ErrStopDistance = errors.New("level within the minimum distance")
+
+	// ErrInvalidBracket is returned when the exits attached to an order are not on their side of its entry price
+	ErrInvalidBracket = errors.New("invalid bracket")
+
+	// ErrTransient is wrapped by the clients in the errors of the requests that did not reach the broker, e.g. a
+	// refused connection or a 503, so they are retried by the RetryPolicy
+	ErrTransient = errors.New("transient broker error")
+
+	// ErrSyntheticInstrument is returned when an order of a synthetic cross is requested, see Synthetic
+	ErrSyntheticInstrument = errors.New("instrument is synthetic")
+
+	// ErrUnhealthy is returned when an open is requested while a monitored component is unhealthy, see Health
+	ErrUnhealthy = errors.New("component is unhealthy")
+
+	// ErrReadOnly is returned when a trade or a position book-kept by an instrument is decoded into
+	ErrReadOnly = errors.New("read only")
+
+	// ErrNoSession is returned when the trades of an account out of a session are closed, e.g. restored offline
+	ErrNoSession = errors.New("account is not in a session")
+)
+
+// marketOpen returns whether the calendar is in session at t, it is always open without a calendar.
+func marketOpen(calendar SessionCalendar, t time.Time) bool {
+
+	if calendar == nil {
+		return true
+	}
+
+	nextClose := calendar.NextClose(t)
+	if nextClose.IsZero() {
+		return false
+	}
+
+	nextOpen := calendar.NextOpen(t)
+
+	return nextOpen.IsZero() || nextClose.Before(nextOpen)
+}
+
+// checkInstrument returns the error of an order or a trade close on the instrument at t.
+func checkInstrument(account *Account, calendar SessionCalendar, instrument string, t time.Time) error {
+
+	inst, exist := account.instruments[instrument]
+	if !exist {
+		return fmt.Errorf("%s: %w", instrument, ErrInstrumentNotTraded)
+	}
+
+	if inst.synthetic {
+		return fmt.Errorf("%s: %w", instrument, ErrSyntheticInstrument)
+	}
+
+	if !marketOpen(calendar, t) {
+		return fmt.Errorf("%s: %w", instrument, ErrMarketClosed)
+	}
+
+	if err := checkCloseStatus(inst); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package gotrader
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// EventType identifies the type of an account event.
+type EventType int
+
+const (
+	TradeOpenedEvent EventType = iota
+	TradeClosedEvent
+	OrderFilledEvent
+	MarginCallEvent
+	PriceStaleEvent
+	SessionCloseEvent
+	OrderSubmittedEvent
+	TransactionRecordedEvent
+	HedgeChangedEvent
+	CorporateActionAppliedEvent
+	PriceGapEvent
+	SpecChangedEvent
+	AlertTriggeredEvent
+	StatusChangedEvent
+	OrderRejectedEvent
+	HealthChangedEvent
+	FeedSwitchedEvent
+	TradeUpdatedEvent
+	OrderExpiredEvent
+)
+
+func (t EventType) String() string {
+	switch t {
+	case TradeOpenedEvent:
+		return "TRADE_OPENED"
+	case TradeClosedEvent:
+		return "TRADE_CLOSED"
+	case OrderFilledEvent:
+		return "ORDER_FILLED"
+	case MarginCallEvent:
+		return "MARGIN_CALL"
+	case PriceStaleEvent:
+		return "PRICE_STALE"
+	case SessionCloseEvent:
+		return "SESSION_CLOSE"
+	case OrderSubmittedEvent:
+		return "ORDER_SUBMITTED"
+	case TransactionRecordedEvent:
+		return "TRANSACTION_RECORDED"
+	case HedgeChangedEvent:
+		return "HEDGE_CHANGED"
+	case CorporateActionAppliedEvent:
+		return "CORPORATE_ACTION_APPLIED"
+	case PriceGapEvent:
+		return "PRICE_GAP"
+	case SpecChangedEvent:
+		return "SPEC_CHANGED"
+	case AlertTriggeredEvent:
+		return "ALERT_TRIGGERED"
+	case StatusChangedEvent:
+		return "STATUS_CHANGED"
+	case OrderRejectedEvent:
+		return "ORDER_REJECTED"
+	case HealthChangedEvent:
+		return "HEALTH_CHANGED"
+	case FeedSwitchedEvent:
+		return "FEED_SWITCHED"
+	case TradeUpdatedEvent:
+		return "TRADE_UPDATED"
+	case OrderExpiredEvent:
+		return "ORDER_EXPIRED"
+	}
+
+	return "UNKNOWN"
+}
+
+// Event is implemented by every event published on the EventBus, the concrete type is given by Type.
+type Event interface {
+	Type() EventType
+}
+
+// TradeOpened is published when a trade is opened.
+type TradeOpened struct {
+	Time  time.Time
+	Trade *Trade
+}
+
+// TradeClosed is published when a trade is closed, the fill has the realized profit.
+type TradeClosed struct {
+	Time time.Time
+	Fill *OrderFill
+}
+
+// OrderFilled is published for every order fill, including trade closes and order errors.
+type OrderFilled struct {
+	Time time.Time
+	Fill *OrderFill
+}
+
+// MarginCall is published when the margin level (equity / margin used) falls below the session margin call level.
+// It is published once, and again only after the level has recovered.
+type MarginCall struct {
+	Time        time.Time
+	Equity      float64
+	MarginUsed  float64
+	MarginLevel float64
+}
+
+// PriceStale is published when an instrument has not been updated for longer than the session stale threshold.
+type PriceStale struct {
+	Time       time.Time
+	Instrument string
+	LastUpdate time.Time
+}
+
+// SessionClose is published when the session stops, before the strategy OnStop.
+type SessionClose struct {
+	Time time.Time
+}
+
+// OrderSubmitted is published when an order is accepted, market orders sent with Buy and Sell on live
+// sessions have no ID.
+type OrderSubmitted struct {
+	Time  time.Time
+	Order *Order
+}
+
+// TransactionRecorded is published when a transaction is recorded in the ledger.
+type TransactionRecorded struct {
+	Time        time.Time
+	Transaction *Transaction
+}
+
+func (TradeOpened) Type() EventType            { return TradeOpenedEvent }
+func (TradeClosed) Type() EventType            { return TradeClosedEvent }
+func (OrderFilled) Type() EventType            { return OrderFilledEvent }
+func (MarginCall) Type() EventType             { return MarginCallEvent }
+func (PriceStale) Type() EventType             { return PriceStaleEvent }
+func (SessionClose) Type() EventType           { return SessionCloseEvent }
+func (OrderSubmitted) Type() EventType         { return OrderSubmittedEvent }
+func (TransactionRecorded) Type() EventType    { return TransactionRecordedEvent }
+func (HedgeChanged) Type() EventType           { return HedgeChangedEvent }
+func (CorporateActionApplied) Type() EventType { return CorporateActionAppliedEvent }
+func (PriceGap) Type() EventType               { return PriceGapEvent }
+func (SpecChanged) Type() EventType            { return SpecChangedEvent }
+func (AlertTriggered) Type() EventType         { return AlertTriggeredEvent }
+func (StatusChanged) Type() EventType          { return StatusChangedEvent }
+func (OrderRejected) Type() EventType          { return OrderRejectedEvent }
+func (HealthChanged) Type() EventType          { return HealthChangedEvent }
+func (FeedSwitched) Type() EventType           { return FeedSwitchedEvent }
+func (TradeUpdated) Type() EventType           { return TradeUpdatedEvent }
+func (OrderExpired) Type() EventType           { return OrderExpiredEvent }
+
+// EventHandler represents the event handler function type
+type EventHandler func(event Event)
+
+/*
+EventBus publishes the account events to its subscribers. Each subscription has its own buffer and goroutine,
+so a slow subscriber never blocks the engine nor the other subscribers: when its buffer is full the new events
+are dropped for that subscriber and counted.
+*/
+type EventBus struct {
+	mutex         *sync.RWMutex
+	subscriptions map[*Subscription]bool
+	hooks         []*TradeHook // the synchronous ones, see HookTrades
+	audit         *AuditLog
+}
+
+// Subscription is an EventBus subscription.
+type Subscription struct {
+	bus     *EventBus
+	types   map[EventType]bool
+	events  chan Event
+	handler EventHandler
+	dropped *atomic.Int64
+	once    *sync.Once
+}
+
+func newEventBus() *EventBus {
+	return &EventBus{
+		mutex:         &sync.RWMutex{},
+		subscriptions: make(map[*Subscription]bool),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (b *EventBus) publish(event Event) {
+
+	if b == nil {
+		return
+	}
+
+	b.audit.observe(event)
+	b.callHooks(event)
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for s := range b.subscriptions {
+
+		if len(s.types) > 0 && !s.types[event.Type()] {
+			continue
+		}
+
+		select {
+		case s.events <- event:
+		default:
+			s.dropped.Inc()
+		}
+	}
+}
+
+// publishFill publishes the events of an order fill, trade is the opened trade if any.
+func (b *EventBus) publishFill(fill *OrderFill, trade *Trade) {
+
+	if fill.Error == "" {
+		if fill.TradeClose {
+			b.publish(TradeClosed{Time: fill.Time, Fill: fill})
+		} else if trade != nil {
+			b.publish(TradeOpened{Time: fill.Time, Trade: trade})
+		}
+	}
+
+	b.publish(OrderFilled{Time: fill.Time, Fill: fill})
+
+	if fill.Error != "" {
+		b.publish(OrderRejected{Time: fill.Time, Fill: fill, Reason: fill.Rejection})
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Subscribe delivers the events of the given types to the handler, every event if no type is given.
+// Events are delivered in order on a goroutine of the subscription, buffer defaults to 100 events.
+func (b *EventBus) Subscribe(handler EventHandler, buffer int, types ...EventType) *Subscription {
+
+	if buffer <= 0 {
+		buffer = 100
+	}
+
+	s := &Subscription{
+		bus:     b,
+		types:   make(map[EventType]bool),
+		events:  make(chan Event, buffer),
+		handler: handler,
+		dropped: atomic.NewInt64(0),
+		once:    &sync.Once{},
+	}
+
+	for _, t := range types {
+		s.types[t] = true
+	}
+
+	go func() {
+		for event := range s.events {
+			s.handler(event)
+		}
+	}()
+
+	b.mutex.Lock()
+	b.subscriptions[s] = true
+	b.mutex.Unlock()
+
+	return s
+}
+
+// Unsubscribe stops the subscription, the events already buffered are still delivered.
+func (s *Subscription) Unsubscribe() {
+
+	s.once.Do(func() {
+		s.bus.mutex.Lock()
+		delete(s.bus.subscriptions, s)
+		close(s.events)
+		s.bus.mutex.Unlock()
+	})
+}
+
+// Dropped returns the number of events dropped because the subscription buffer was full.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
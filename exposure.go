@@ -0,0 +1,163 @@
+package gotrader
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// CurrencyExposure is the exposure of the open trades to a currency, in units of the currency: a long EUR_JPY
+// trade is long its units of EUR and short their value in JPY.
+type CurrencyExposure struct {
+	Currency string
+	Long     float64 // units bought
+	Short    float64 // units sold, positive
+	Net      float64 // long minus short
+	Rate     float64 // value of a unit in the home currency, zero when no instrument converts the currency
+	Notional float64 // value of the net units in the home currency
+}
+
+// ExposureReport is the exposure of an account to the currencies of its open trades, see
+// AccountView.CurrencyExposures.
+type ExposureReport struct {
+	Time          time.Time
+	HomeCurrency  string
+	Currencies    []CurrencyExposure // by currency order, the home currency included
+	GrossNotional float64            // of the currencies other than the home one, the sum of the absolute notionals
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (e *CurrencyExposure) add(units float64) {
+	if units > 0 {
+		e.Long += units
+	} else {
+		e.Short -= units
+	}
+}
+
+// conversionRate returns the value of a unit of the currency in the home currency, at the mid price of its pair
+// with the home currency or else at the conversion rates of the instruments, zero without any.
+func (v *AccountView) conversionRate(currency string) float64 {
+
+	if currency == v.HomeCurrency {
+		return 1
+	}
+
+	for _, iv := range v.Instruments {
+
+		mid := (iv.Bid + iv.Ask) / 2
+		if mid == 0 {
+			continue
+		}
+
+		switch {
+		case iv.BaseCurrency == currency && iv.QuoteCurrency == v.HomeCurrency:
+			return mid
+		case iv.BaseCurrency == v.HomeCurrency && iv.QuoteCurrency == currency:
+			return 1 / mid
+		}
+	}
+
+	for _, iv := range v.Instruments {
+		switch {
+		case iv.BaseCurrency == currency && iv.BaseConversionRate != 0:
+			return iv.BaseConversionRate
+		case iv.QuoteCurrency == currency && iv.QuoteConversionRate != 0:
+			return iv.QuoteConversionRate
+		}
+	}
+
+	return 0
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+/*
+CurrencyExposures aggregates the exposure of the open trades of the view by currency, of the trades matched or
+of all of them when match is nil. A trade is exposed to its units of the base currency and to their value in the
+quote currency at the mid price of the view, the notionals are converted to the home currency at the mid prices.
+*/
+func (v *AccountView) CurrencyExposures(match func(trade TradeState) bool) *ExposureReport {
+
+	exposures := make(map[string]*CurrencyExposure)
+
+	exposure := func(currency string) *CurrencyExposure {
+		e, exist := exposures[currency]
+		if !exist {
+			e = &CurrencyExposure{Currency: currency}
+			exposures[currency] = e
+		}
+		return e
+	}
+
+	for _, iv := range v.Instruments {
+
+		mid := (iv.Bid + iv.Ask) / 2
+
+		for _, trade := range iv.Trades {
+
+			if match != nil && !match(trade) {
+				continue
+			}
+
+			units := float64(trade.Units)
+			if trade.Side == Short {
+				units = -units
+			}
+
+			exposure(iv.BaseCurrency).add(units)
+			exposure(iv.QuoteCurrency).add(-units * mid)
+		}
+	}
+
+	report := &ExposureReport{
+		Time:         v.Time,
+		HomeCurrency: v.HomeCurrency,
+		Currencies:   make([]CurrencyExposure, 0, len(exposures)),
+	}
+
+	for currency, e := range exposures {
+
+		e.Net = e.Long - e.Short
+		e.Rate = v.conversionRate(currency)
+		e.Notional = e.Net * e.Rate
+
+		if currency != v.HomeCurrency {
+			report.GrossNotional += math.Abs(e.Notional)
+		}
+
+		report.Currencies = append(report.Currencies, *e)
+	}
+
+	sort.Slice(report.Currencies, func(i, j int) bool {
+		return report.Currencies[i].Currency < report.Currencies[j].Currency
+	})
+
+	return report
+}
+
+// CurrencyExposures returns the exposure of all the open trades of the account, see AccountView.CurrencyExposures.
+func (a *Account) CurrencyExposures() *ExposureReport {
+	return a.View().CurrencyExposures(nil)
+}
+
+// Currency returns the exposure to a currency, nil when the trades aren't exposed to it.
+func (r *ExposureReport) Currency(currency string) *CurrencyExposure {
+
+	for n := range r.Currencies {
+		if r.Currencies[n].Currency == currency {
+			return &r.Currencies[n]
+		}
+	}
+
+	return nil
+}
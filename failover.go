@@ -0,0 +1,321 @@
+package gotrader
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// PriceFeed is a source of prices, e.g. a BrokerClient or a market data client.
+type PriceFeed interface {
+	SubscribePrices(accountID string, instruments []InstrumentDetails, callback TickHandler) error
+}
+
+// FeedSource is the price source of an instrument with a failover.
+type FeedSource int
+
+const (
+	// PrimaryFeed is the preferred source
+	PrimaryFeed FeedSource = iota
+
+	// SecondaryFeed is the backup source, used while the primary one is silent
+	SecondaryFeed
+)
+
+func (s FeedSource) String() string {
+
+	names := [...]string{"PRIMARY", "SECONDARY"}
+
+	return names[s]
+}
+
+/*
+FeedFailover defines the primary and the secondary price sources of instruments in the live engine: an instrument
+switches to its secondary source when its primary one is silent for After, or when the primary one is the client of
+the session and its broker session is unhealthy (see HealthCheck), and switches back on the next tick of the primary
+one. The ticks of the inactive source are discarded, and every switch is published as a FeedSwitched event.
+*/
+type FeedFailover struct {
+	Instruments []string      // every instrument subscribed when empty
+	Primary     PriceFeed     // the client of the session when nil
+	Secondary   PriceFeed     // required
+	After       time.Duration // the StaleAfter of the session when zero
+}
+
+// Failover is the functional option to define the backup price source of instruments, see FeedFailover. The
+// backtests ignore the failovers.
+func Failover(failover FeedFailover) Option {
+	return func(p *sessionParameters) {
+		if p.feeds == nil {
+			p.feeds = newFeedRoutes()
+		}
+		p.feeds.failovers = append(p.feeds.failovers, failover)
+	}
+}
+
+// FeedSwitched is published when an instrument switches its price source.
+type FeedSwitched struct {
+	Time        time.Time
+	Instrument  string
+	From        FeedSource
+	To          FeedSource
+	LastPrimary time.Time // tick of the primary source, or the start of the session without ticks
+}
+
+// feedRoute is the price source of an instrument with a failover.
+type feedRoute struct {
+	primary     PriceFeed
+	secondary   PriceFeed
+	after       time.Duration
+	active      FeedSource
+	lastPrimary time.Time
+}
+
+// feedRoutes routes the ticks of the feeds of the failovers, it is nil without the Failover option.
+type feedRoutes struct {
+	mutex      *sync.Mutex
+	failovers  []FeedFailover
+	client     PriceFeed
+	clientDown bool // the broker session of the client is unhealthy
+	routes     map[string]*feedRoute
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newFeedRoutes() *feedRoutes {
+	return &feedRoutes{
+		mutex:  &sync.Mutex{},
+		routes: make(map[string]*feedRoute),
+	}
+}
+
+// route defines the routes of the instruments subscribed with the failovers at now.
+func (f *feedRoutes) route(client PriceFeed, details []InstrumentDetails, staleAfter time.Duration, now time.Time) error {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.client = client
+
+	for _, failover := range f.failovers {
+
+		if failover.Secondary == nil {
+			return errors.New("failover without secondary feed")
+		}
+
+		route := &feedRoute{primary: failover.Primary, secondary: failover.Secondary, after: failover.After,
+			lastPrimary: now}
+
+		if route.primary == nil {
+			route.primary = client
+		}
+
+		if route.after <= 0 {
+			route.after = staleAfter
+		}
+
+		if route.after <= 0 {
+			return errors.New("failover without silence, define its After or the StaleAfter option")
+		}
+
+		instruments := failover.Instruments
+		if len(instruments) == 0 {
+			for _, d := range details {
+				instruments = append(instruments, d.Name)
+			}
+		}
+
+		for _, name := range instruments {
+			r := *route
+			f.routes[name] = &r
+		}
+	}
+
+	return nil
+}
+
+// subscriptions returns the instruments subscribed to every feed, the client one first.
+func (f *feedRoutes) subscriptions(details []InstrumentDetails) ([]PriceFeed, [][]InstrumentDetails) {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	feeds := []PriceFeed{f.client}
+	instruments := [][]InstrumentDetails{nil}
+
+	add := func(feed PriceFeed, d InstrumentDetails) {
+		for i := range feeds {
+			if feeds[i] == feed {
+				instruments[i] = append(instruments[i], d)
+				return
+			}
+		}
+		feeds = append(feeds, feed)
+		instruments = append(instruments, []InstrumentDetails{d})
+	}
+
+	for _, d := range details {
+		if route, exist := f.routes[d.Name]; exist {
+			add(route.primary, d)
+			add(route.secondary, d)
+		} else {
+			add(f.client, d)
+		}
+	}
+
+	return feeds, instruments
+}
+
+// accept returns whether a tick of the instrument from the feed is delivered, switching back to the primary source
+// on its ticks.
+func (f *feedRoutes) accept(feed PriceFeed, instrument string, now time.Time, events *EventBus) bool {
+
+	f.mutex.Lock()
+
+	route, exist := f.routes[instrument]
+	if !exist {
+		f.mutex.Unlock()
+		return feed == f.client
+	}
+
+	if feed != route.primary {
+		f.mutex.Unlock()
+		return feed == route.secondary && route.active == SecondaryFeed
+	}
+
+	route.lastPrimary = now
+
+	if route.active == PrimaryFeed || route.primary == f.client && f.clientDown {
+		accepted := route.active == PrimaryFeed
+		f.mutex.Unlock()
+		return accepted
+	}
+
+	route.active = PrimaryFeed
+	f.mutex.Unlock()
+
+	events.publish(FeedSwitched{Time: now, Instrument: instrument, From: SecondaryFeed, To: PrimaryFeed,
+		LastPrimary: now})
+
+	return true
+}
+
+// check switches to their secondary source the instruments with a silent primary one at now, or with the client as
+// primary one while its broker session is unhealthy.
+func (f *feedRoutes) check(now time.Time, clientDown bool, events *EventBus) {
+
+	var switches []FeedSwitched
+
+	f.mutex.Lock()
+
+	f.clientDown = clientDown
+
+	for instrument, route := range f.routes {
+
+		if route.active == SecondaryFeed {
+			continue
+		}
+
+		if now.Sub(route.lastPrimary) >= route.after || route.primary == f.client && clientDown {
+			route.active = SecondaryFeed
+			switches = append(switches, FeedSwitched{Time: now, Instrument: instrument, From: PrimaryFeed,
+				To: SecondaryFeed, LastPrimary: route.lastPrimary})
+		}
+	}
+
+	f.mutex.Unlock()
+
+	for _, s := range switches {
+		events.publish(s)
+	}
+}
+
+// interval returns the interval of the checks, a quarter of the shortest silence up to maxHealthInterval.
+func (f *feedRoutes) interval() time.Duration {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	interval := maxHealthInterval
+
+	for _, route := range f.routes {
+		if route.after/4 > 0 && route.after/4 < interval {
+			interval = route.after / 4
+		}
+	}
+
+	return interval
+}
+
+func (f *feedRoutes) snapshot() map[string]FeedSource {
+
+	snapshot := make(map[string]FeedSource)
+
+	if f == nil {
+		return snapshot
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for instrument, route := range f.routes {
+		snapshot[instrument] = route.active
+	}
+
+	return snapshot
+}
+
+// subscribePrices subscribes the client, the feeds of the failovers and the ones of the consolidation to the prices
+// of the instruments. The ticks delivered by the failovers are consolidated as the ones of the client.
+func (e *liveEngine) subscribePrices(details []InstrumentDetails) error {
+
+	onTick := e.parameters.consolidation.handler(BrokerFeed, e.clock, e.onTick)
+
+	routes := e.parameters.feeds
+	if routes == nil {
+		if err := e.client.SubscribePrices(e.account.id, details, onTick); err != nil {
+			return err
+		}
+
+		return e.parameters.consolidation.subscribe(e.account.id, details, e.clock, e.onTick)
+	}
+
+	if err := routes.route(e.client, details, e.parameters.staleAfter, e.clock.Now()); err != nil {
+		return err
+	}
+
+	feeds, instruments := routes.subscriptions(details)
+
+	for i, feed := range feeds {
+
+		if len(instruments[i]) == 0 {
+			continue
+		}
+
+		feed := feed
+		err := feed.SubscribePrices(e.account.id, instruments[i], func(tick *Tick) {
+			if routes.accept(feed, tick.Instrument, e.clock.Now(), e.account.events) {
+				onTick(tick)
+			} else {
+				releaseTick(tick)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.parameters.consolidation.subscribe(e.account.id, details, e.clock, e.onTick)
+}
+
+// checkFeeds switches the instruments with a silent primary source to their secondary one.
+func (e *liveEngine) checkFeeds() {
+
+	broker, _ := e.account.health.Component(BrokerComponent)
+
+	e.parameters.feeds.check(e.clock.Now(), broker.State == Unhealthy, e.account.events)
+}
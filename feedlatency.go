@@ -0,0 +1,116 @@
+package gotrader
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+FeedLatency are the statistics of the delay between the timestamps of the ticks of an instrument and their
+arrival, measured by the live engine with the MeasureFeedLatency option. The delay includes the offset between
+the clocks of the venue and the local one, so it can be negative: Skew estimates that offset as the smallest delay
+measured, the transit of the fastest tick being the closest to zero.
+*/
+type FeedLatency struct {
+	Count uint64
+	Last  time.Duration
+	Min   time.Duration
+	Max   time.Duration
+	Sum   time.Duration
+}
+
+// Mean returns the mean delay, 0 without ticks.
+func (l FeedLatency) Mean() time.Duration {
+
+	if l.Count == 0 {
+		return 0
+	}
+
+	return l.Sum / time.Duration(l.Count)
+}
+
+// Skew returns the estimated offset of the clock of the venue behind the local one, negative when it is ahead.
+func (l FeedLatency) Skew() time.Duration {
+	return l.Min
+}
+
+// MeasureFeedLatency is the functional option to measure the delay of the ticks of the live feeds, read with
+// the FeedLatency method of the session. With compensateSkew, the staleness of the prices (see StaleAfter) is
+// measured on the local clock, discounting the skew of every instrument from the age of its last tick.
+func MeasureFeedLatency(compensateSkew bool) Option {
+	return func(p *sessionParameters) {
+		p.feedLatency = &feedLatency{
+			mutex:          &sync.Mutex{},
+			compensateSkew: compensateSkew,
+			instruments:    make(map[string]*FeedLatency),
+		}
+	}
+}
+
+// feedLatency measures the delay of the ticks, it is nil (and measures nothing) without the MeasureFeedLatency
+// option. The clients may deliver the ticks from several goroutines.
+type feedLatency struct {
+	mutex          *sync.Mutex
+	compensateSkew bool
+	instruments    map[string]*FeedLatency
+}
+
+// received records the delay of a tick arriving now.
+func (f *feedLatency) received(tick *Tick, now time.Time) {
+
+	if f == nil || tick.Time.IsZero() {
+		return
+	}
+
+	delay := now.Sub(tick.Time)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	l, exist := f.instruments[tick.Instrument]
+	if !exist {
+		l = &FeedLatency{Min: delay, Max: delay}
+		f.instruments[tick.Instrument] = l
+	}
+
+	l.Count++
+	l.Last = delay
+	l.Sum += delay
+	l.Min = min(l.Min, delay)
+	l.Max = max(l.Max, delay)
+}
+
+// skew returns the skew discounted from the age of the prices of the instrument, 0 without compensation.
+func (f *feedLatency) skew(instrument string) time.Duration {
+
+	if f == nil || !f.compensateSkew {
+		return 0
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if l, exist := f.instruments[instrument]; exist {
+		return l.Skew()
+	}
+
+	return 0
+}
+
+func (f *feedLatency) snapshot() map[string]FeedLatency {
+
+	snapshot := make(map[string]FeedLatency)
+
+	if f == nil {
+		return snapshot
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for instrument, l := range f.instruments {
+		snapshot[instrument] = *l
+	}
+
+	return snapshot
+}
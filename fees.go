@@ -0,0 +1,105 @@
+package gotrader
+
+// FeeType is the kind of a fee of a trade.
+type FeeType int
+
+const (
+	// CommissionFee is a commission of the fills, the fees the brokers charge at the open
+	CommissionFee FeeType = iota
+
+	// FinancingFee is a financing of the trade over a rollover, or swap
+	FinancingFee
+
+	// GuaranteedStopFee is the premium of a guaranteed stop loss (see GuaranteedStopPremium)
+	GuaranteedStopFee
+
+	// MarkupFee is the cost of the markup of the quotes paid by the open (see PriceMarkup)
+	MarkupFee
+
+	// UnitemizedFee is a fee of unknown kind, e.g. the fees of the trades hydrated from the broker
+	UnitemizedFee
+)
+
+const feeTypes = int(UnitemizedFee) + 1
+
+func (f FeeType) String() string {
+
+	names := [...]string{"COMMISSION", "FINANCING", "GUARANTEED_STOP", "MARKUP", "UNITEMIZED"}
+
+	return names[f]
+}
+
+/*
+Fees is the breakdown of the costs of a trade by FeeType, in the home currency and negative when charged. The
+ChargedFees of the trade sum the ones charged to it, but two costs are only itemized: the Markup, paid in the open
+price and so in the profits, and the Financing of the backtests, settled in the balance at each rollover.
+*/
+type Fees struct {
+	Commission     float64 `json:"commission,omitempty"`
+	Financing      float64 `json:"financing,omitempty"`
+	GuaranteedStop float64 `json:"guaranteedStop,omitempty"`
+	Markup         float64 `json:"markup,omitempty"`
+	Unitemized     float64 `json:"unitemized,omitempty"`
+}
+
+// Total returns the sum of the costs.
+func (f Fees) Total() float64 {
+	return f.Commission + f.Financing + f.GuaranteedStop + f.Markup + f.Unitemized
+}
+
+// Get returns the cost of a fee type.
+func (f Fees) Get(feeType FeeType) float64 {
+
+	items := [...]float64{f.Commission, f.Financing, f.GuaranteedStop, f.Markup, f.Unitemized}
+
+	return items[feeType]
+}
+
+// feeItems are the costs of a trade by fee type.
+type feeItems [feeTypes]*atomicDecimal
+
+func newFeeItems() feeItems {
+
+	var items feeItems
+	for i := range items {
+		items[i] = newAtomicDecimal(0)
+	}
+
+	return items
+}
+
+func (items feeItems) breakdown() Fees {
+	return Fees{
+		Commission:     items[CommissionFee].Load().Float64(),
+		Financing:      items[FinancingFee].Load().Float64(),
+		GuaranteedStop: items[GuaranteedStopFee].Load().Float64(),
+		Markup:         items[MarkupFee].Load().Float64(),
+		Unitemized:     items[UnitemizedFee].Load().Float64(),
+	}
+}
+
+// charge charges a fee to the trade.
+func (t *Trade) charge(feeType FeeType, fee Decimal) {
+	t.fees[feeType].Add(fee)
+	t.chargedFees.Add(fee)
+}
+
+// itemize records a cost of the trade that is not charged to it, the markup or the financing of the backtests.
+func (t *Trade) itemize(feeType FeeType, fee Decimal) {
+	t.fees[feeType].Add(fee)
+}
+
+// restoreFees replaces the fees of the trade, the charged ones are unitemized without breakdown, e.g. saved before
+// it.
+func (t *Trade) restoreFees(charged float64, fees Fees) {
+
+	if fees == (Fees{}) {
+		fees.Unitemized = charged
+	}
+
+	for i := range t.fees {
+		t.fees[i].Store(NewDecimal(fees.Get(FeeType(i))))
+	}
+
+	t.chargedFees.Store(NewDecimal(charged))
+}
@@ -0,0 +1,199 @@
+package gotrader
+
+import (
+	"sort"
+	"time"
+)
+
+// FinancingModel returns the financing of a trade held over one rollover, per day in the quote currency of the
+// instrument, positive when it is credited.
+type FinancingModel interface {
+	Financing(instrument InstrumentDetails, side Side, units int32, price float64, t time.Time) float64
+}
+
+// Swap are the static financing of the long and short trades of an instrument, per unit and day in its quote
+// currency, negative when they are charged.
+type Swap struct {
+	Long  float64
+	Short float64
+}
+
+// StaticSwaps is a FinancingModel with static swaps by instrument, the instruments without swaps are not financed.
+type StaticSwaps map[string]Swap
+
+// Financing implements FinancingModel.
+func (s StaticSwaps) Financing(instrument InstrumentDetails, side Side, units int32, price float64, t time.Time) float64 {
+
+	swap := s[instrument.Name]
+	if side == Long {
+		return swap.Long * float64(units)
+	}
+
+	return swap.Short * float64(units)
+}
+
+// RatePoint is an annual interest rate, e.g. 0.05 for 5%, effective from its time.
+type RatePoint struct {
+	Time time.Time
+	Rate float64
+}
+
+// RateCurve is the history of the interest rate of a currency, its points sorted by time.
+type RateCurve []RatePoint
+
+// Rate returns the rate effective at t, the first rate before it and zero without points.
+func (c RateCurve) Rate(t time.Time) float64 {
+
+	if len(c) == 0 {
+		return 0
+	}
+
+	i := sort.Search(len(c), func(i int) bool { return c[i].Time.After(t) })
+	if i == 0 {
+		return c[0].Rate
+	}
+
+	return c[i-1].Rate
+}
+
+/*
+InterestRateFinancing is a FinancingModel computing the financing from the interest rates of the currencies: the
+long trades earn the rate of the base currency and pay the rate of the quote one on their notional, the short
+trades the opposite, and the broker Markup is charged to both sides, e.g. long EUR_USD with EUR at 4%, USD at 5%
+and a markup of 0.5% pays 1.5% a year.
+*/
+type InterestRateFinancing struct {
+	Rates    map[string]RateCurve // by currency, the currencies without curve have a zero rate
+	Markup   float64              // annual
+	DayCount float64              // days of the year, 365 when zero
+}
+
+// Financing implements FinancingModel.
+func (f InterestRateFinancing) Financing(instrument InstrumentDetails, side Side, units int32, price float64, t time.Time) float64 {
+
+	dayCount := f.DayCount
+	if dayCount == 0 {
+		dayCount = 365
+	}
+
+	differential := f.Rates[instrument.BaseCurrency].Rate(t) - f.Rates[instrument.QuoteCurrency].Rate(t)
+	if side == Short {
+		differential = -differential
+	}
+
+	return float64(units) * price * (differential - f.Markup) / dayCount
+}
+
+// Financing is the functional option to charge the financing of the trades held over the rollovers in the
+// backtest engine, recorded in the ledger as FinancingTransaction. The live brokers notify their own charges.
+func Financing(model FinancingModel) Option {
+	return func(p *sessionParameters) {
+		p.financing = model
+	}
+}
+
+// Rollover is the functional option to define the rollovers of the financing as the opens of a calendar, every
+// day at 17:00 New York time by default. The days without ticks, e.g. the weekends, are charged on the next tick.
+func Rollover(calendar SessionCalendar) Option {
+	return func(p *sessionParameters) {
+		p.rollover = calendar
+	}
+}
+
+// defaultRollover is every day at 17:00 New York time.
+func defaultRollover() SessionCalendar {
+
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		location = time.FixedZone("EST", -5*60*60)
+	}
+
+	return DailySession{Location: location, Open: 17 * time.Hour, Close: 17 * time.Hour}
+}
+
+// financingCharger charges the financing at the rollovers, it is nil without financing model.
+type financingCharger struct {
+	model    FinancingModel
+	rollover SessionCalendar
+	next     time.Time
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newFinancingCharger(model FinancingModel, rollover SessionCalendar) *financingCharger {
+
+	if model == nil {
+		return nil
+	}
+
+	if rollover == nil {
+		rollover = defaultRollover()
+	}
+
+	return &financingCharger{model: model, rollover: rollover}
+}
+
+// charge charges the trades open at t for every rollover since the last call, the first call only schedules the
+// next rollover.
+func (c *financingCharger) charge(account *Account, details map[string]InstrumentDetails, t time.Time, logger Logger) {
+
+	if c == nil {
+		return
+	}
+
+	if c.next.IsZero() {
+		c.next = c.rollover.NextOpen(t)
+		return
+	}
+
+	for !c.next.IsZero() && !c.next.After(t) {
+
+		rollover := c.next
+		c.next = c.rollover.NextOpen(rollover)
+
+		for _, inst := range account.list() {
+			for _, position := range []*Position{inst.longPosition, inst.shortPosition} {
+				for _, trade := range position.list() {
+
+					financing := c.model.Financing(details[inst.name], trade.side, trade.units, trade.currentPrice.Load(), rollover)
+					if financing == 0 {
+						continue
+					}
+
+					amount := NewDecimal(financing).MulFloat(inst.ccyConversion.QuoteConversionRate.Load())
+
+					transaction := &Transaction{
+						Type:       FinancingTransaction,
+						TradeID:    trade.id,
+						Instrument: inst.name,
+						Side:       trade.side,
+						Units:      trade.units,
+						Amount:     amount.Float64(),
+						Time:       rollover,
+						Tag:        trade.tag,
+					}
+
+					account.wal.write(&WALEntry{
+						Operation:   WALFinancing,
+						Time:        rollover,
+						Instrument:  inst.name,
+						TradeID:     trade.id,
+						Transaction: transaction,
+					}, logger)
+
+					account.writes.begin()
+					transaction.Balance = account.balance.Add(amount).Float64()
+					account.record(transaction)
+					trade.itemize(FinancingFee, amount)
+					account.writes.end()
+
+					account.events.publish(TradeUpdated{Time: rollover, Trade: trade, Update: FinancingUpdate})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gotrader
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+/*
+FlattenPolicy closes the open trades of an instrument Before the close of its market hours (see MarketHours and
+InstrumentMarketHours), e.g. to avoid holding intraday trades overnight or over the weekend gap. The trades are
+closed on the ticks of the instrument, the trades opened within the window are closed on the next one.
+*/
+type FlattenPolicy struct {
+	Before      time.Duration
+	WeekendOnly bool // only before the closes followed by more than a day without session, e.g. the weekend
+}
+
+// FlattenBeforeClose is the functional option to flatten the trades of every instrument with market hours.
+func FlattenBeforeClose(policy FlattenPolicy) Option {
+	return func(p *sessionParameters) {
+		p.flatten = &policy
+	}
+}
+
+// InstrumentFlattenBeforeClose is the functional option to define the flatten policy of an instrument, used
+// instead of the FlattenBeforeClose one.
+func InstrumentFlattenBeforeClose(instrument string, policy FlattenPolicy) Option {
+	return func(p *sessionParameters) {
+		if p.instrumentFlatten == nil {
+			p.instrumentFlatten = make(map[string]FlattenPolicy)
+		}
+		p.instrumentFlatten[instrument] = policy
+	}
+}
+
+// FlattenReport is the outcome of FlattenInstrument.
+type FlattenReport struct {
+	Instrument     string
+	Cancelled      []string         // IDs of the pending orders cancelled
+	Closed         []*OrderFill     // of the trades closed, with their close prices
+	Failed         map[string]error // by order or trade ID
+	RealizedProfit float64          // of the trades closed
+	ResidualTrades int              // left open
+	ResidualUnits  int32            // net units left open, the short ones negative
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// due returns whether the trades must be flattened at t, within the window before a close of the calendar.
+func (f FlattenPolicy) due(calendar SessionCalendar, t time.Time) bool {
+
+	if calendar == nil || f.Before <= 0 || !marketOpen(calendar, t) {
+		return false
+	}
+
+	nextClose := calendar.NextClose(t)
+	if nextClose.IsZero() || nextClose.Sub(t) > f.Before {
+		return false
+	}
+
+	if f.WeekendOnly {
+		nextOpen := calendar.NextOpen(nextClose)
+		return nextOpen.IsZero() || nextOpen.Sub(nextClose) > 24*time.Hour
+	}
+
+	return true
+}
+
+// flattening returns whether the trades of an instrument must be flattened at t.
+func (p *sessionParameters) flattening(instrument string, t time.Time) bool {
+
+	policy, exist := p.instrumentFlatten[instrument]
+	if !exist {
+		if p.flatten == nil {
+			return false
+		}
+		policy = *p.flatten
+	}
+
+	return policy.due(p.calendar(instrument), t)
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+/*
+FlattenInstrument removes the exposure of an instrument, for a kill switch or the end of the day: it cancels its
+pending orders first, so none fills meanwhile, then closes its trades as Account.CloseAllTrades does. The residual
+exposure is the one of the instrument when the fills are received or the context is done.
+*/
+func FlattenInstrument(ctx context.Context, engine Engine, instrument string) (*FlattenReport, error) {
+
+	inst := engine.Account().Instrument(instrument)
+	if inst == nil {
+		return nil, fmt.Errorf("%s: %w", instrument, ErrInstrumentNotTraded)
+	}
+
+	report := &FlattenReport{Instrument: instrument, Failed: make(map[string]error)}
+
+	for _, order := range engine.PendingOrders(instrument) {
+		if err := engine.CancelOrder(order.ID); err != nil {
+			report.Failed[order.ID] = err
+			continue
+		}
+		report.Cancelled = append(report.Cancelled, order.ID)
+	}
+
+	closes := closeTrades(ctx, engine.Account(), engine, []string{instrument}, func(TradeState) bool { return true })
+	report.Closed = closes.Closed
+	report.RealizedProfit = closes.RealizedProfit
+	for id, err := range closes.Failed {
+		report.Failed[id] = err
+	}
+
+	view := inst.View()
+	report.ResidualTrades = len(view.Trades)
+	report.ResidualUnits = view.Long.Units - view.Short.Units
+
+	return report, nil
+}
@@ -0,0 +1,145 @@
+package gotrader
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// GapDirection is the direction of a PriceGap.
+type GapDirection int
+
+const (
+	GapUp GapDirection = iota
+	GapDown
+)
+
+func (d GapDirection) String() string {
+	if d == GapUp {
+		return "UP"
+	}
+
+	return "DOWN"
+}
+
+// PriceGap is published when the mid price of an instrument jumps between consecutive ticks by more than the
+// threshold of the GapPolicy of the session. SessionBoundary is set when the market hours of the instrument
+// closed between the ticks, e.g. the weekend gaps.
+type PriceGap struct {
+	Time            time.Time
+	Instrument      string
+	Direction       GapDirection
+	Pips            float64 // size of the gap, in pips of the instrument
+	From            float64 // mid price of the previous tick
+	To              float64
+	SessionBoundary bool
+}
+
+/*
+GapPolicy defines the price gaps of the instruments: the jumps of the mid price between consecutive ticks of at
+least Pips, or SessionPips when the market hours closed between the ticks (Pips when zero). The fills on the
+tick of a gap are marked with OrderFill.Gap and Trade.GapFill.
+
+With Pessimistic, the backtests fill the limit orders and close the take profits triggered by a gap at their
+levels instead of the gapped price, so the strategies are not credited with the price improvement of the gaps;
+the stops are always filled at the gapped price.
+*/
+type GapPolicy struct {
+	Pips        float64
+	SessionPips float64
+	Pessimistic bool
+}
+
+// DetectGaps is the functional option to publish the PriceGap events of the instruments, gaps are not detected
+// by default.
+func DetectGaps(policy GapPolicy) Option {
+	return func(p *sessionParameters) {
+		p.gaps = &gapDetector{
+			policy: policy,
+			mutex:  &sync.Mutex{},
+			last:   make(map[string]gapState),
+		}
+	}
+}
+
+// gapDetector detects the gaps of the instruments, it is nil (and detects nothing) without the DetectGaps option.
+// The live engine reads it from the fills goroutine.
+type gapDetector struct {
+	policy GapPolicy
+	mutex  *sync.Mutex
+	last   map[string]gapState // by instrument
+}
+
+type gapState struct {
+	mid    float64
+	time   time.Time
+	gapped bool // the last tick is the one of a gap
+}
+
+// update records the tick of the instrument, returning its gap from the previous tick, if any. The calendar
+// is the one of the market hours of the instrument, nil when it is always open.
+func (g *gapDetector) update(inst *Instrument, tick *Tick, calendar SessionCalendar) *PriceGap {
+
+	if g == nil {
+		return nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	mid := (tick.Bid + tick.Ask) / 2
+	last, exist := g.last[inst.name]
+	g.last[inst.name] = gapState{mid: mid, time: tick.Time}
+
+	if !exist {
+		return nil
+	}
+
+	boundary := calendar != nil && !calendar.NextClose(last.time).After(tick.Time)
+
+	threshold := g.policy.Pips
+	if boundary && g.policy.SessionPips > 0 {
+		threshold = g.policy.SessionPips
+	}
+
+	pips := math.Abs(mid-last.mid) / math.Pow10(inst.pipLocation)
+	if threshold <= 0 || pips < threshold {
+		return nil
+	}
+
+	g.last[inst.name] = gapState{mid: mid, time: tick.Time, gapped: true}
+
+	gap := &PriceGap{
+		Time:            tick.Time,
+		Instrument:      inst.name,
+		Direction:       GapUp,
+		Pips:            pips,
+		From:            last.mid,
+		To:              mid,
+		SessionBoundary: boundary,
+	}
+
+	if mid < last.mid {
+		gap.Direction = GapDown
+	}
+
+	return gap
+}
+
+// gapped returns whether the last tick of the instrument is the one of a gap.
+func (g *gapDetector) gapped(instrument string) bool {
+
+	if g == nil {
+		return false
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.last[instrument].gapped
+}
+
+// pessimistic returns whether the backtest fills on the gap of the instrument are priced at the order levels.
+func (g *gapDetector) pessimistic(instrument string) bool {
+	return g != nil && g.policy.Pessimistic && g.gapped(instrument)
+}
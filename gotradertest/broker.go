@@ -0,0 +1,797 @@
+package gotradertest
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Response is the programmed outcome of the next order request (market order, trade close or order
+// submission): an Error returned by the request, a fill rejected with the Reject reason, or a fill at Price,
+// the current quote when zero. The fill is notified after Latency, immediately when zero. A Lost response
+// fills the order and returns the Error anyway, as a timeout after the broker accepted it.
+type Response struct {
+	Error   error
+	Reject  string
+	Price   float64
+	Latency time.Duration
+	Lost    bool
+}
+
+// RequestType identifies the kind of request received by the Broker.
+type RequestType int
+
+const (
+	MarketOrderRequest RequestType = iota
+	CloseTradeRequest
+	SubmitOrderRequest
+	ModifyOrderRequest
+	CancelOrderRequest
+)
+
+func (r RequestType) String() string {
+
+	names := [...]string{"MARKET_ORDER", "CLOSE_TRADE", "SUBMIT_ORDER", "MODIFY_ORDER", "CANCEL_ORDER"}
+
+	return names[r]
+}
+
+// Request is a request received by the Broker, Order is set for the submissions and modifications.
+type Request struct {
+	Type       RequestType
+	Instrument string
+	Side       gotrader.Side
+	Units      int32
+	TradeID    string
+	OrderID    string
+	Order      *gotrader.Order
+	Time       time.Time
+}
+
+// Option represents a Broker functional option
+type Option func(b *Broker)
+
+// Balance is the functional option to define the account balance, defaults to 100000.
+func Balance(value float64) Option {
+	return func(b *Broker) {
+		b.status.Balance = value
+	}
+}
+
+// Currency is the functional option to define the account currency, defaults to USD.
+func Currency(ccy string) Option {
+	return func(b *Broker) {
+		b.status.Currency = ccy
+	}
+}
+
+// Leverage is the functional option to define the account leverage, defaults to 1.
+func Leverage(leverage float64) Option {
+	return func(b *Broker) {
+		b.status.Leverage = leverage
+	}
+}
+
+// HedgeType is the functional option to define the account hedge, defaults to full hedge.
+func HedgeType(hedge gotrader.Hedge) Option {
+	return func(b *Broker) {
+		b.status.Hedge = hedge
+	}
+}
+
+// WithClock is the functional option to define the clock timing the fills and the ticks, defaults to a
+// SimulatedClock at the start of 2024. A Harness shares its clock with the session.
+func WithClock(clock *gotrader.SimulatedClock) Option {
+	return func(b *Broker) {
+		b.clock = clock
+	}
+}
+
+/*
+Broker is a scriptable mock gotrader.Broker. Orders are filled at the quotes set with Quote and Tick, unless
+a Response was programmed for them, so a test controls the fills, the rejections and their latencies. Every
+request is recorded and can be inspected with Requests.
+
+Pending orders are filled when a tick triggers them, and the trades are closed when a tick hits the stop loss or
+the take profit attached to their order. Profits are converted to the account currency with the
+quotes of the instruments, as the paper broker does.
+*/
+type Broker struct {
+	mutex       *sync.Mutex
+	clock       *gotrader.SimulatedClock
+	status      gotrader.AccountStatus
+	instruments []gotrader.InstrumentDetails
+	details     map[string]gotrader.InstrumentDetails
+	quotes      map[string]*gotrader.Tick
+	trades      map[string]*gotrader.TradeDetails
+	exits       map[string]*gotrader.Order // orders of the trades with exits, by trade ID
+	orders      map[string]*gotrader.Order
+	clients     map[string]string // order IDs by client ID
+	responses   []Response
+	requests    []Request
+	subscribed  []string
+	counter     int
+	notified    int // fills notified, including the errors of the asynchronous requests
+	inflight    *sync.WaitGroup
+	onTick      gotrader.TickHandler
+	onFill      gotrader.OrderFillHandler
+	onSwap      gotrader.SwapChargeHandler
+	onFunds     gotrader.FundsTransferHandler
+	onReconnect gotrader.ReconnectHandler
+	onSpec      gotrader.SpecUpdateHandler
+}
+
+// NewBroker is the Broker constructor, with the instruments available to the sessions.
+func NewBroker(instruments []gotrader.InstrumentDetails, opts ...Option) *Broker {
+
+	b := &Broker{
+		mutex:       &sync.Mutex{},
+		clock:       gotrader.NewSimulatedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		status:      gotrader.AccountStatus{Currency: "USD", Balance: 100000, Leverage: 1, Hedge: gotrader.FullHedge},
+		instruments: instruments,
+		details:     make(map[string]gotrader.InstrumentDetails, len(instruments)),
+		quotes:      make(map[string]*gotrader.Tick),
+		trades:      make(map[string]*gotrader.TradeDetails),
+		exits:       make(map[string]*gotrader.Order),
+		orders:      make(map[string]*gotrader.Order),
+		clients:     make(map[string]string),
+		inflight:    &sync.WaitGroup{},
+	}
+
+	for _, inst := range instruments {
+		b.details[inst.Name] = inst
+	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	return b
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (b *Broker) nextID() string {
+	b.counter++
+	return strconv.Itoa(b.counter)
+}
+
+// next returns the programmed response of a request, the zero response when none is left.
+func (b *Broker) next() Response {
+
+	if len(b.responses) == 0 {
+		return Response{}
+	}
+
+	response := b.responses[0]
+	b.responses = b.responses[1:]
+
+	return response
+}
+
+func (b *Broker) record(r Request) {
+	r.Time = b.clock.Now()
+	b.requests = append(b.requests, r)
+}
+
+// conversionRate returns the rate to convert an amount in ccy to the account currency.
+func (b *Broker) conversionRate(ccy string) float64 {
+
+	if ccy == b.status.Currency {
+		return 1
+	}
+
+	for _, inst := range b.instruments {
+
+		q, exist := b.quotes[inst.Name]
+		if !exist {
+			continue
+		}
+
+		mid := (q.Bid + q.Ask) / 2
+
+		if inst.BaseCurrency == ccy && inst.QuoteCurrency == b.status.Currency {
+			return mid
+		}
+
+		if inst.BaseCurrency == b.status.Currency && inst.QuoteCurrency == ccy {
+			return 1 / mid
+		}
+	}
+
+	return 1
+}
+
+func (b *Broker) profit(trade *gotrader.TradeDetails, price float64) float64 {
+
+	diff := price - trade.OpenPrice
+	if trade.Side == gotrader.Short {
+		diff = -diff
+	}
+
+	return diff * float64(trade.Units) * b.conversionRate(trade.Instrument.QuoteCurrency)
+}
+
+// fill opens a trade for the order, or rejects it, must be called with the mutex locked.
+func (b *Broker) fill(order *gotrader.Order, response Response) *gotrader.OrderFill {
+
+	fill := &gotrader.OrderFill{
+		OrderID:       order.ID,
+		ClientOrderID: order.ClientID,
+		Side:          order.Side,
+		Instrument:    b.details[order.Instrument],
+		Units:         order.Units,
+		Time:          b.clock.Now(),
+		Tag:           order.Tag,
+	}
+
+	q, exist := b.quotes[order.Instrument]
+	if response.Reject == "" && !exist && response.Price == 0 {
+		response.Reject = "NO_PRICE"
+	}
+
+	if response.Reject != "" {
+		fill.Error = response.Reject
+		return fill
+	}
+
+	fill.Price = response.Price
+	if fill.Price == 0 {
+		fill.Price = q.Ask
+		if order.Side == gotrader.Short {
+			fill.Price = q.Bid
+		}
+	}
+
+	fill.TradeID = b.nextID()
+	b.trades[fill.TradeID] = &gotrader.TradeDetails{
+		ID:         fill.TradeID,
+		Instrument: fill.Instrument,
+		Side:       order.Side,
+		Units:      order.Units,
+		OpenPrice:  fill.Price,
+		OpenTime:   fill.Time,
+		Tag:        order.Tag,
+	}
+
+	if order.StopLoss != 0 || order.TakeProfit != 0 {
+		b.exits[fill.TradeID] = order
+	}
+
+	return fill
+}
+
+// exit returns the close reason of the trade when the quote hits one of its exits, the stop loss first.
+func (b *Broker) exit(trade *gotrader.TradeDetails, q *gotrader.Tick) (gotrader.CloseReason, bool) {
+
+	order, exist := b.exits[trade.ID]
+	if !exist {
+		return 0, false
+	}
+
+	price, sign := q.Bid, 1.0
+	if trade.Side == gotrader.Short {
+		price, sign = q.Ask, -1
+	}
+
+	switch {
+	case order.StopLoss != 0 && sign*(price-order.StopLoss) <= 0:
+		return gotrader.StopLossClose, true
+	case order.TakeProfit != 0 && sign*(price-order.TakeProfit) >= 0:
+		return gotrader.TakeProfitClose, true
+	}
+
+	return 0, false
+}
+
+// close closes a trade, or rejects the close, must be called with the mutex locked.
+func (b *Broker) close(trade *gotrader.TradeDetails, response Response) *gotrader.OrderFill {
+
+	fill := &gotrader.OrderFill{
+		TradeClose: true,
+		OrderID:    b.nextID(),
+		TradeID:    trade.ID,
+		Side:       trade.Side,
+		Instrument: trade.Instrument,
+		Units:      trade.Units,
+		Time:       b.clock.Now(),
+		Tag:        trade.Tag,
+	}
+
+	if response.Reject != "" {
+		fill.Error = response.Reject
+		return fill
+	}
+
+	fill.Price = response.Price
+	if fill.Price == 0 {
+		q := b.quotes[trade.Instrument.Name]
+		fill.Price = q.Bid
+		if trade.Side == gotrader.Short {
+			fill.Price = q.Ask
+		}
+	}
+
+	fill.Profit = b.profit(trade, fill.Price)
+	b.status.Balance += fill.Profit
+	delete(b.trades, trade.ID)
+	delete(b.exits, trade.ID)
+
+	return fill
+}
+
+// notify delivers the fill after the latency, must be called with the mutex locked.
+func (b *Broker) notify(fill *gotrader.OrderFill, latency time.Duration) {
+
+	b.notified++
+
+	if b.onFill == nil {
+		return
+	}
+
+	callback := b.onFill
+
+	if latency <= 0 {
+		callback(fill)
+		return
+	}
+
+	b.inflight.Add(1)
+	time.AfterFunc(latency, func() {
+		defer b.inflight.Done()
+		callback(fill)
+	})
+}
+
+/**************************
+*
+*	Scripting Methods
+*
+***************************/
+
+// Program queues the responses of the next order requests, in order.
+func (b *Broker) Program(responses ...Response) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.responses = append(b.responses, responses...)
+}
+
+// Reject programs the rejection of the next order request.
+func (b *Broker) Reject(reason string) {
+	b.Program(Response{Reject: reason})
+}
+
+// Quote sets the quote of an instrument without streaming it, e.g. the prices before the session starts.
+func (b *Broker) Quote(instrument string, bid, ask float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.quotes[instrument] = &gotrader.Tick{Instrument: instrument, Bid: bid, Ask: ask, Time: b.clock.Now()}
+}
+
+// Tick streams a price at the clock time, filling the pending orders it triggers and closing the trades hitting
+// their exits.
+func (b *Broker) Tick(instrument string, bid, ask float64) {
+
+	b.mutex.Lock()
+
+	tick := &gotrader.Tick{Instrument: instrument, Bid: bid, Ask: ask, Time: b.clock.Now()}
+	b.quotes[instrument] = tick
+
+	fills := make([]*gotrader.OrderFill, 0)
+	for id, order := range b.orders {
+		if order.Instrument == instrument && order.Triggered(bid, ask) {
+			delete(b.orders, id)
+			fills = append(fills, b.fill(order, b.next()))
+		}
+	}
+
+	for _, trade := range b.trades {
+		if reason, hit := b.exit(trade, tick); trade.Instrument.Name == instrument && hit {
+			fill := b.close(trade, Response{})
+			fill.Reason = reason
+			fills = append(fills, fill)
+		}
+	}
+
+	callback := b.onTick
+	b.mutex.Unlock()
+
+	if callback != nil {
+		callback(&gotrader.Tick{Instrument: instrument, Bid: bid, Ask: ask, Time: tick.Time})
+	}
+
+	b.mutex.Lock()
+	for _, fill := range fills {
+		b.notify(fill, 0)
+	}
+	b.mutex.Unlock()
+}
+
+// OpenTrade adds a trade open at the broker, e.g. before the session starts to test its hydration.
+func (b *Broker) OpenTrade(trade gotrader.TradeDetails) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	trade.Instrument = b.details[trade.Instrument.Name]
+	b.trades[trade.ID] = &trade
+}
+
+// ChargeSwap notifies a swap charge of an open trade.
+func (b *Broker) ChargeSwap(tradeID string, amount float64) error {
+
+	b.mutex.Lock()
+
+	trade, exist := b.trades[tradeID]
+	if !exist {
+		b.mutex.Unlock()
+		return errors.New("trade " + tradeID + " does not exist")
+	}
+
+	b.status.Balance += amount
+	charge := &gotrader.SwapCharge{
+		Charges: []*gotrader.TradeSwapCharge{{ID: tradeID, Ammount: amount, Instrument: trade.Instrument}},
+		Time:    b.clock.Now(),
+	}
+
+	callback := b.onSwap
+	b.mutex.Unlock()
+
+	if callback != nil {
+		callback(charge)
+	}
+
+	return nil
+}
+
+// Transfer notifies a deposit, or a withdrawal when the amount is negative.
+func (b *Broker) Transfer(amount float64) {
+
+	b.mutex.Lock()
+	b.status.Balance += amount
+	funds := &gotrader.FundsTransfer{Ammount: amount, Time: b.clock.Now()}
+	callback := b.onFunds
+	b.mutex.Unlock()
+
+	if callback != nil {
+		callback(funds)
+	}
+}
+
+// Reconnect notifies a reconnection of the broker streams, so the engine resynchronizes its trades.
+func (b *Broker) Reconnect() {
+
+	b.mutex.Lock()
+	callback := b.onReconnect
+	b.mutex.Unlock()
+
+	if callback != nil {
+		callback(b.clock.Now())
+	}
+}
+
+// UpdateSpec notifies a change of the specification of an instrument, the fills of the broker are not affected.
+func (b *Broker) UpdateSpec(update gotrader.SpecUpdate) {
+
+	b.mutex.Lock()
+	if update.Time.IsZero() {
+		update.Time = b.clock.Now()
+	}
+	callback := b.onSpec
+	b.mutex.Unlock()
+
+	if callback != nil {
+		callback(&update)
+	}
+}
+
+// Requests returns the requests received by the broker, in order.
+func (b *Broker) Requests() []Request {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return append([]Request(nil), b.requests...)
+}
+
+// Subscribed returns the instruments of the price subscription, the traded instruments and those used to
+// convert their profits to the account currency.
+func (b *Broker) Subscribed() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return append([]string(nil), b.subscribed...)
+}
+
+// Wait waits for the fills delayed by their latency to be notified.
+func (b *Broker) Wait() {
+	b.inflight.Wait()
+}
+
+// counts returns the market orders and trade closes received, sent asynchronously by the engine, and the
+// fills notified.
+func (b *Broker) counts() (requests, notified int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, r := range b.requests {
+		if r.Type == MarketOrderRequest || r.Type == CloseTradeRequest {
+			requests++
+		}
+	}
+
+	return requests, b.notified
+}
+
+/**************************
+*
+*	gotrader.Broker
+*
+***************************/
+
+// GetAccountStatus implements gotrader.BrokerClient.
+func (b *Broker) GetAccountStatus(accountID string) (gotrader.AccountStatus, error) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	status := b.status
+	status.UnrealizedGrossProfit = 0
+
+	for _, trade := range b.trades {
+		if q, exist := b.quotes[trade.Instrument.Name]; exist {
+			price := q.Bid
+			if trade.Side == gotrader.Short {
+				price = q.Ask
+			}
+			status.UnrealizedGrossProfit += b.profit(trade, price)
+		}
+	}
+
+	status.Equity = status.Balance + status.UnrealizedGrossProfit
+
+	return status, nil
+}
+
+// GetAvailableInstruments implements gotrader.BrokerClient.
+func (b *Broker) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+	return b.instruments, nil
+}
+
+// OpenMarketOrder implements gotrader.BrokerClient.
+func (b *Broker) OpenMarketOrder(accountID, instrument string, units int32, side string) error {
+
+	s := gotrader.Long
+	if strings.EqualFold(side, gotrader.Short.String()) {
+		s = gotrader.Short
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.record(Request{Type: MarketOrderRequest, Instrument: instrument, Side: s, Units: units})
+
+	response := b.next()
+	if response.Error != nil {
+		b.notified++ // notified by the engine
+		return response.Error
+	}
+
+	b.notify(b.fill(&gotrader.Order{Type: gotrader.MarketOrder, Instrument: instrument, Side: s, Units: units}, response), response.Latency)
+
+	return nil
+}
+
+// CloseTrade implements gotrader.BrokerClient.
+func (b *Broker) CloseTrade(accountID, id string) error {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	trade, exist := b.trades[id]
+
+	request := Request{Type: CloseTradeRequest, TradeID: id}
+	if exist {
+		request.Instrument, request.Side, request.Units = trade.Instrument.Name, trade.Side, trade.Units
+	}
+	b.record(request)
+
+	response := b.next()
+	if response.Error == nil && !exist {
+		response.Error = errors.New("trade " + id + " does not exist")
+	}
+
+	if response.Error != nil {
+		b.notified++ // notified by the engine
+		return response.Error
+	}
+
+	b.notify(b.close(trade, response), response.Latency)
+
+	return nil
+}
+
+// GetOpenTrades implements gotrader.BrokerClient.
+func (b *Broker) GetOpenTrades(accountID string) ([]gotrader.TradeDetails, error) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	trades := make([]gotrader.TradeDetails, 0, len(b.trades))
+	for _, trade := range b.trades {
+		trades = append(trades, *trade)
+	}
+
+	return trades, nil
+}
+
+// SubscribePrices implements gotrader.BrokerClient.
+func (b *Broker) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.onTick = callback
+	b.subscribed = b.subscribed[:0]
+
+	for _, inst := range instruments {
+		b.subscribed = append(b.subscribed, inst.Name)
+	}
+
+	return nil
+}
+
+// SubscribeOrderFillNotifications implements gotrader.BrokerClient.
+func (b *Broker) SubscribeOrderFillNotifications(accountID string, callback gotrader.OrderFillHandler) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.onFill = callback
+
+	return nil
+}
+
+// SubscribeSwapChargeNotifications implements gotrader.BrokerClient.
+func (b *Broker) SubscribeSwapChargeNotifications(accountID string, callback gotrader.SwapChargeHandler) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.onSwap = callback
+
+	return nil
+}
+
+// SubscribeFundsTransferNotifications implements gotrader.BrokerClient.
+func (b *Broker) SubscribeFundsTransferNotifications(accountID string, callback gotrader.FundsTransferHandler) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.onFunds = callback
+
+	return nil
+}
+
+// SubscribeReconnections implements gotrader.Reconnector.
+func (b *Broker) SubscribeReconnections(accountID string, callback gotrader.ReconnectHandler) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.onReconnect = callback
+
+	return nil
+}
+
+// SubscribeSpecUpdates implements gotrader.SpecNotifier.
+func (b *Broker) SubscribeSpecUpdates(accountID string, callback gotrader.SpecUpdateHandler) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.onSpec = callback
+
+	return nil
+}
+
+// SubmitOrder implements gotrader.Broker, market orders are filled immediately.
+func (b *Broker) SubmitOrder(accountID string, order *gotrader.Order) (string, error) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	submitted := *order
+	submitted.ID = b.nextID()
+	b.record(Request{Type: SubmitOrderRequest, Instrument: order.Instrument, Side: order.Side, Units: order.Units, OrderID: submitted.ID, Order: &submitted})
+
+	if _, exist := b.details[order.Instrument]; !exist {
+		return "", errors.New("unknown instrument " + order.Instrument)
+	}
+
+	if id, exist := b.clients[order.ClientID]; exist && order.ClientID != "" { // deduped by the client ID
+		return id, nil
+	}
+
+	if order.Type != gotrader.MarketOrder {
+		b.orders[submitted.ID] = &submitted
+		b.accept(&submitted)
+		return submitted.ID, nil
+	}
+
+	response := b.next()
+	if response.Error != nil && !response.Lost {
+		return "", response.Error
+	}
+
+	b.accept(&submitted)
+	b.notify(b.fill(&submitted, response), response.Latency)
+
+	if response.Error != nil {
+		return "", response.Error
+	}
+
+	return submitted.ID, nil
+}
+
+// accept records the ID of an accepted order by its client ID.
+func (b *Broker) accept(order *gotrader.Order) {
+	if order.ClientID != "" {
+		b.clients[order.ClientID] = order.ID
+	}
+}
+
+// ModifyOrder implements gotrader.Broker.
+func (b *Broker) ModifyOrder(accountID, orderID string, order *gotrader.Order) error {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	modified := *order
+	modified.ID = orderID
+	b.record(Request{Type: ModifyOrderRequest, Instrument: order.Instrument, Side: order.Side, Units: order.Units, OrderID: orderID, Order: &modified})
+
+	if _, exist := b.orders[orderID]; !exist {
+		return errors.New("order " + orderID + " does not exist")
+	}
+
+	b.orders[orderID] = &modified
+
+	return nil
+}
+
+// CancelOrder implements gotrader.Broker.
+func (b *Broker) CancelOrder(accountID, orderID string) error {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.record(Request{Type: CancelOrderRequest, OrderID: orderID})
+
+	if _, exist := b.orders[orderID]; !exist {
+		return errors.New("order " + orderID + " does not exist")
+	}
+
+	delete(b.orders, orderID)
+
+	return nil
+}
+
+// GetPendingOrders implements gotrader.Broker.
+func (b *Broker) GetPendingOrders(accountID string) ([]*gotrader.Order, error) {
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	orders := make([]*gotrader.Order, 0, len(b.orders))
+	for _, order := range b.orders {
+		copied := *order
+		orders = append(orders, &copied)
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,480 @@
+/*
+Package gotradertest helps testing strategies without a live connection: a scriptable mock Broker and a
+Harness running a strategy on a live session against it, with assertion helpers over the account state.
+
+	broker := gotradertest.NewBroker(instruments, gotradertest.Balance(10000))
+	broker.Quote("EUR_USD", 1.1, 1.1002)
+
+	h := gotradertest.New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+	h.Tick("EUR_USD", 1.1010, 1.1012) // the strategy buys
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 1)
+
+	broker.Reject("INSUFFICIENT_LIQUIDITY")
+	h.Tick("EUR_USD", 1.1020, 1.1022)
+	h.Settle()
+	h.AssertRejections(1)
+*/
+package gotradertest
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Timeout is the time waited for the session to process the ticks and the fills before the test fails.
+var Timeout = 5 * time.Second
+
+// Tolerance is the absolute difference accepted by the assertions of amounts.
+var Tolerance = 1e-6
+
+// recorder wraps the strategy under test, counting its callbacks and the asynchronous requests of its engine.
+type recorder struct {
+	gotrader.Strategy
+	mutex    *sync.Mutex
+	warm     bool
+	ticks    int
+	fills    []*gotrader.OrderFill
+	requests int
+	started  chan struct{}
+	stopped  chan struct{}
+}
+
+// engine counts the market orders and trade closes accepted by the engine, sent to the broker asynchronously.
+type engine struct {
+	gotrader.Engine
+	recorder *recorder
+}
+
+func (e *engine) accepted(err error) error {
+
+	if err == nil {
+		e.recorder.mutex.Lock()
+		e.recorder.requests++
+		e.recorder.mutex.Unlock()
+	}
+
+	return err
+}
+
+func (e *engine) Buy(instrument string, units int32) error {
+	return e.accepted(e.Engine.Buy(instrument, units))
+}
+
+func (e *engine) Sell(instrument string, units int32) error {
+	return e.accepted(e.Engine.Sell(instrument, units))
+}
+
+func (e *engine) CloseTrade(instrument, id string) error {
+	return e.accepted(e.Engine.CloseTrade(instrument, id))
+}
+
+func (r *recorder) SetEngine(e gotrader.Engine) {
+	r.Strategy.SetEngine(&engine{Engine: e, recorder: r})
+}
+
+func (r *recorder) Initialize() {
+	r.Strategy.Initialize()
+	close(r.started)
+}
+
+func (r *recorder) OnTick(tick *gotrader.Tick) {
+
+	r.mutex.Lock()
+	warm := r.warm
+	r.mutex.Unlock()
+
+	if warm {
+		r.Strategy.OnTick(tick)
+	}
+
+	r.mutex.Lock()
+	r.ticks++
+	r.mutex.Unlock()
+}
+
+func (r *recorder) OnOrderFill(fill *gotrader.OrderFill) {
+
+	r.Strategy.OnOrderFill(fill)
+
+	r.mutex.Lock()
+	r.fills = append(r.fills, fill)
+	r.mutex.Unlock()
+}
+
+func (r *recorder) OnOrderRejected(fill *gotrader.OrderFill, reason gotrader.RejectReason) {
+	if handler, ok := r.Strategy.(gotrader.RejectionHandler); ok {
+		handler.OnOrderRejected(fill, reason)
+	}
+}
+
+func (r *recorder) OnStop() {
+	r.Strategy.OnStop()
+	close(r.stopped)
+}
+
+/*
+Harness runs a strategy on a live session with a mock Broker and a SimulatedClock shared by both, and stops
+it when the test ends. The session starts once every subscribed instrument has a price, so the broker must be
+quoted (see Broker.Quote) for the traded instruments and those converting their profits.
+
+The test drives the session with Tick, which returns after the strategy OnTick, and Settle, which returns once
+the orders of the strategy are filled. Ticks of the instruments only used for the conversion rates are not
+delivered to the strategy, they are processed before the next tick of a traded instrument.
+*/
+type Harness struct {
+	t        testing.TB
+	Broker   *Broker
+	Clock    *gotrader.SimulatedClock
+	Session  *gotrader.TradingSession
+	recorder *recorder
+	traded   map[string]bool
+	done     chan error
+	once     *sync.Once
+}
+
+// New starts the strategy on a live session with the broker, and the session options (e.g. the instruments).
+func New(t testing.TB, strategy gotrader.Strategy, broker *Broker, opts ...gotrader.Option) *Harness {
+
+	t.Helper()
+
+	h := &Harness{
+		t:      t,
+		Broker: broker,
+		Clock:  broker.clock,
+		recorder: &recorder{
+			Strategy: strategy,
+			mutex:    &sync.Mutex{},
+			started:  make(chan struct{}),
+			stopped:  make(chan struct{}),
+		},
+		traded: make(map[string]bool),
+		done:   make(chan error, 1),
+		once:   &sync.Once{},
+	}
+
+	opts = append(opts, gotrader.SetClock(h.Clock))
+	h.Session = gotrader.NewTradingSession(opts...).SetClient(broker).SetStrategy(h.recorder).Live()
+
+	go func() {
+		h.done <- h.Session.Start()
+	}()
+
+	select {
+	case <-h.recorder.started:
+	case err := <-h.done:
+		t.Fatalf("session failed to start: %v", err)
+	case <-time.After(Timeout):
+		t.Fatal("session did not start")
+	}
+
+	t.Cleanup(h.Stop)
+
+	for name := range h.Account().Instruments() {
+		h.traded[name] = true
+	}
+
+	h.warmUp()
+
+	return h
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// warmUp streams the quotes of the subscribed instruments, then a traded one again, not delivered to the
+// strategy, to wait for the engine to be ready.
+func (h *Harness) warmUp() {
+
+	h.t.Helper()
+
+	var last *gotrader.Tick
+
+	for _, name := range h.Broker.Subscribed() {
+
+		h.Broker.mutex.Lock()
+		q, exist := h.Broker.quotes[name]
+		h.Broker.mutex.Unlock()
+
+		if !exist {
+			h.t.Fatalf("no quote for %s, every subscribed instrument must be quoted", name)
+		}
+
+		h.Broker.Tick(name, q.Bid, q.Ask)
+
+		if h.traded[name] {
+			last = q
+		}
+	}
+
+	if last == nil {
+		h.t.Fatal("no traded instruments")
+	}
+
+	h.Broker.Tick(last.Instrument, last.Bid, last.Ask)
+	h.waitFor("the session to be ready", func() bool {
+		h.recorder.mutex.Lock()
+		defer h.recorder.mutex.Unlock()
+		return h.recorder.ticks > 0
+	})
+
+	h.recorder.mutex.Lock()
+	h.recorder.warm = true
+	h.recorder.mutex.Unlock()
+}
+
+func (h *Harness) waitFor(what string, condition func() bool) {
+
+	h.t.Helper()
+
+	deadline := time.Now().Add(Timeout)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (h *Harness) assertAmount(what string, got, want float64) {
+
+	h.t.Helper()
+
+	if math.Abs(got-want) > Tolerance {
+		h.t.Errorf("expected %s %v, got %v", what, want, got)
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Account returns the account of the session.
+func (h *Harness) Account() *gotrader.Account {
+	return h.Session.Account()
+}
+
+// Advance moves the clock of the session and the broker forward.
+func (h *Harness) Advance(d time.Duration) {
+	h.Clock.Advance(d)
+}
+
+// Tick streams a price and waits for the strategy to process it.
+func (h *Harness) Tick(instrument string, bid, ask float64) {
+
+	h.t.Helper()
+
+	h.recorder.mutex.Lock()
+	expected := h.recorder.ticks + 1
+	h.recorder.mutex.Unlock()
+
+	h.Broker.Tick(instrument, bid, ask)
+
+	if !h.traded[instrument] {
+		return
+	}
+
+	h.waitFor(fmt.Sprintf("the tick of %s", instrument), func() bool {
+		h.recorder.mutex.Lock()
+		defer h.recorder.mutex.Unlock()
+		return h.recorder.ticks >= expected
+	})
+}
+
+// Settle waits for the orders sent by the strategy to reach the broker and for their fills, including the
+// delayed ones, to be processed.
+func (h *Harness) Settle() {
+
+	h.t.Helper()
+
+	h.waitFor("the orders to settle", func() bool {
+
+		requests, notified := h.Broker.counts()
+
+		h.recorder.mutex.Lock()
+		defer h.recorder.mutex.Unlock()
+
+		return requests >= h.recorder.requests && len(h.recorder.fills) >= notified
+	})
+
+	h.Broker.Wait()
+
+	h.waitFor("the delayed fills", func() bool {
+
+		_, notified := h.Broker.counts()
+
+		h.recorder.mutex.Lock()
+		defer h.recorder.mutex.Unlock()
+
+		return len(h.recorder.fills) >= notified
+	})
+}
+
+// Transfer notifies a funds transfer and waits for its ledger transaction.
+func (h *Harness) Transfer(amount float64) {
+
+	h.t.Helper()
+
+	transactions := h.Account().Ledger().Len()
+	h.Broker.Transfer(amount)
+
+	h.waitFor("the funds transfer", func() bool {
+		return h.Account().Ledger().Len() > transactions
+	})
+}
+
+// ChargeSwap notifies a swap charge of an open trade and waits for its ledger transaction.
+func (h *Harness) ChargeSwap(tradeID string, amount float64) {
+
+	h.t.Helper()
+
+	transactions := h.Account().Ledger().Len()
+	if err := h.Broker.ChargeSwap(tradeID, amount); err != nil {
+		h.t.Fatal(err)
+	}
+
+	h.waitFor("the swap charge", func() bool {
+		return h.Account().Ledger().Len() > transactions
+	})
+}
+
+// UpdateSpec notifies a change of the specification of an instrument and waits for the session to apply it.
+func (h *Harness) UpdateSpec(update gotrader.SpecUpdate) {
+
+	h.t.Helper()
+
+	applied := make(chan struct{}, 1)
+	subscription := h.Account().Events().Subscribe(func(event gotrader.Event) {
+		applied <- struct{}{}
+	}, 1, gotrader.SpecChangedEvent)
+	defer subscription.Unsubscribe()
+
+	h.Broker.UpdateSpec(update)
+
+	select {
+	case <-applied:
+	case <-time.After(Timeout):
+		h.t.Fatalf("timeout waiting for the specification update of %s", update.Instrument)
+	}
+}
+
+// Fills returns the fills delivered to the strategy, including the rejections and the trade closes.
+func (h *Harness) Fills() []*gotrader.OrderFill {
+	h.recorder.mutex.Lock()
+	defer h.recorder.mutex.Unlock()
+
+	return append([]*gotrader.OrderFill(nil), h.recorder.fills...)
+}
+
+// Stop stops the session, it is called when the test ends.
+func (h *Harness) Stop() {
+
+	h.once.Do(func() {
+
+		h.Session.Engine().StopSession()
+
+		select {
+		case <-h.recorder.stopped:
+		case <-time.After(Timeout):
+			h.t.Error("session did not stop")
+		}
+	})
+}
+
+// AssertBalance checks the account balance.
+func (h *Harness) AssertBalance(want float64) {
+	h.t.Helper()
+	h.assertAmount("balance", h.Account().Balance(), want)
+}
+
+// AssertEquity checks the account equity.
+func (h *Harness) AssertEquity(want float64) {
+	h.t.Helper()
+	h.assertAmount("equity", h.Account().Equity(), want)
+}
+
+// AssertRealizedProfit checks the profit realized by the closed trades.
+func (h *Harness) AssertRealizedProfit(want float64) {
+	h.t.Helper()
+	h.assertAmount("realized profit", h.Account().Ledger().RealizedProfit(), want)
+}
+
+// AssertOpenTrades checks the number of open trades of an instrument.
+func (h *Harness) AssertOpenTrades(instrument string, want int) {
+
+	h.t.Helper()
+
+	inst := h.Account().Instrument(instrument)
+	if inst == nil {
+		h.t.Fatalf("%s is not traded", instrument)
+	}
+
+	if got := int(inst.TradesNumber()); got != want {
+		h.t.Errorf("expected %d open trades of %s, got %d", want, instrument, got)
+	}
+}
+
+// AssertUnits checks the units of a position of an instrument.
+func (h *Harness) AssertUnits(instrument string, side gotrader.Side, want int32) {
+
+	h.t.Helper()
+
+	inst := h.Account().Instrument(instrument)
+	if inst == nil {
+		h.t.Fatalf("%s is not traded", instrument)
+	}
+
+	position := inst.LongPosition()
+	if side == gotrader.Short {
+		position = inst.ShortPosition()
+	}
+
+	if got := position.Units(); got != want {
+		h.t.Errorf("expected %d %s units of %s, got %d", want, side, instrument, got)
+	}
+}
+
+// AssertRejections checks the number of rejected orders and trade closes delivered to the strategy.
+func (h *Harness) AssertRejections(want int) {
+
+	h.t.Helper()
+
+	got := 0
+	for _, fill := range h.Fills() {
+		if fill.Error != "" {
+			got++
+		}
+	}
+
+	if got != want {
+		h.t.Errorf("expected %d rejections, got %d", want, got)
+	}
+}
+
+// AssertRequests checks the types of the requests received by the broker, in order.
+func (h *Harness) AssertRequests(want ...RequestType) {
+
+	h.t.Helper()
+
+	requests := h.Broker.Requests()
+	got := make([]RequestType, 0, len(requests))
+
+	for _, r := range requests {
+		got = append(got, r.Type)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		h.t.Errorf("expected the requests %v, got %v", want, got)
+	}
+}
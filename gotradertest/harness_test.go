@@ -0,0 +1,2206 @@
+package gotradertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// breakout buys 1000 units when the ask crosses the level and closes its trades when the bid falls below it.
+type breakout struct {
+	engine gotrader.Engine
+	level  float64
+}
+
+func (s *breakout) Initialize()                          {}
+func (s *breakout) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *breakout) OnOrderFill(fill *gotrader.OrderFill) {}
+func (s *breakout) OnStop()                              {}
+
+func (s *breakout) OnTick(tick *gotrader.Tick) {
+
+	inst := s.engine.Account().Instrument(tick.Instrument)
+
+	if tick.Ask > s.level && inst.TradesNumber() == 0 {
+		s.engine.Buy(tick.Instrument, 1000)
+	}
+
+	if tick.Bid < s.level {
+		for trade := range inst.Trades() {
+			s.engine.CloseTrade(tick.Instrument, trade.ID())
+		}
+	}
+}
+
+func TestHarness(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	h := New(t, &breakout{level: 1.1}, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	h.Tick("EUR_USD", 1.1010, 1.1012)
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 1)
+	h.AssertUnits("EUR_USD", gotrader.Long, 1000)
+
+	h.Advance(time.Minute)
+	h.Tick("EUR_USD", 1.0980, 1.0982)
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 0)
+	h.AssertRealizedProfit(-3.2)
+	h.AssertBalance(10000 - 3.2)
+	h.assertAmount("instrument realized profit", h.Account().Instrument("EUR_USD").RealizedProfit(), -3.2)
+
+	broker.Reject("INSUFFICIENT_LIQUIDITY")
+	broker.Program(Response{Error: errors.New("timeout")}, Response{Price: 1.1030, Latency: 10 * time.Millisecond})
+
+	h.Tick("EUR_USD", 1.1020, 1.1022) // rejected
+	h.Settle()
+	h.Tick("EUR_USD", 1.1020, 1.1022) // request failed
+	h.Settle()
+	h.Tick("EUR_USD", 1.1020, 1.1022) // filled after the latency
+	h.Settle()
+
+	h.AssertRejections(2)
+	h.AssertOpenTrades("EUR_USD", 1)
+	h.AssertRequests(MarketOrderRequest, CloseTradeRequest, MarketOrderRequest, MarketOrderRequest, MarketOrderRequest)
+
+	if trade := h.Account().Instrument("EUR_USD").TradeByOrder(0); trade == nil || trade.OpenPrice() != 1.1030 {
+		t.Errorf("expected the programmed fill price, got %v", trade)
+	}
+
+	h.Transfer(500)
+	h.AssertBalance(10500 - 3.2)
+}
+
+func TestHarness_ClientOrderIDs(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &breakout{level: 2}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	broker.Program(Response{Error: context.DeadlineExceeded, Lost: true})
+
+	order := func() *gotrader.Order {
+		return &gotrader.Order{Type: gotrader.MarketOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 1000, ClientID: "a-1"}
+	}
+
+	if _, err := strategy.engine.SubmitOrder(order()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the timeout, got %v", err)
+	}
+
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 1)
+
+	id, err := strategy.engine.SubmitOrder(order()) // the retry is resolved by the fill
+	if err != nil || id == "" {
+		t.Fatalf("expected the ID of the filled order, got %q, %v", id, err)
+	}
+
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 1)
+	h.AssertRequests(SubmitOrderRequest)
+
+	if fills := h.Fills(); len(fills) != 1 || fills[0].ClientOrderID != "a-1" || fills[0].OrderID != id {
+		t.Errorf("expected the fill of the client order, got %+v", fills)
+	}
+
+	generated := &gotrader.Order{Type: gotrader.MarketOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 1000}
+	if _, err := strategy.engine.SubmitOrder(generated); err != nil || generated.ClientID == "" {
+		t.Errorf("expected a generated client ID, got %q, %v", generated.ClientID, err)
+	}
+
+	h.Settle()
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil { // sent without client ID, matched by its fill
+		t.Fatal(err)
+	}
+
+	h.Settle()
+
+	fills := h.Fills()
+	if last := fills[len(fills)-1]; last.ClientOrderID == "" || last.ClientOrderID == generated.ClientID {
+		t.Errorf("expected the fill of the market order with its own client ID, got %+v", last)
+	}
+
+	h.Advance(16 * time.Minute) // the retention of the session clock
+
+	sent := len(broker.Requests())
+	if _, err := strategy.engine.SubmitOrder(order()); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Settle()
+
+	if len(broker.Requests()) != sent+1 {
+		t.Error("expected the client ID to be forgotten after its retention")
+	}
+}
+
+// passive only submits the orders of the tests.
+type passive struct{ engine gotrader.Engine }
+
+func (s *passive) Initialize()                          {}
+func (s *passive) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *passive) OnTick(tick *gotrader.Tick)           {}
+func (s *passive) OnOrderFill(fill *gotrader.OrderFill) {}
+func (s *passive) OnStop()                              {}
+
+func TestHarness_Bracket(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	order := &gotrader.Order{Type: gotrader.LimitOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 1000,
+		Price: 1.0980, StopLoss: 1.0985, TakeProfit: 1.1000}
+
+	if _, err := strategy.engine.SubmitOrder(order); !errors.Is(err, gotrader.ErrInvalidBracket) {
+		t.Fatalf("expected the stop loss above the entry to be rejected, got %v", err)
+	}
+
+	order.StopLoss = 1.0960
+	if _, err := strategy.engine.SubmitOrder(order); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Tick("EUR_USD", 1.0970, 1.0972) // the entry fills, activating its exits
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 1)
+
+	trade := h.Account().Instrument("EUR_USD").TradeByOrder(0)
+	if trade == nil || trade.StopLoss() != 1.0960 || trade.TakeProfit() != 1.1000 {
+		t.Fatalf("expected the exits attached to the trade, got %v", trade)
+	}
+
+	h.Advance(time.Minute)
+	h.Tick("EUR_USD", 1.1001, 1.1003) // the take profit closes the trade, cancelling the stop loss
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 0)
+
+	h.Tick("EUR_USD", 1.0950, 1.0952)
+	h.Settle()
+
+	if fills := h.Fills(); len(fills) != 2 || fills[1].Reason != gotrader.TakeProfitClose {
+		t.Errorf("expected the trade closed once by its take profit, got %+v", fills)
+	}
+}
+
+func TestHarness_StopDistance(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.MinStopDistance(5))
+
+	for _, test := range []struct {
+		order *gotrader.Order
+		level gotrader.OrderLevel
+	}{
+		{&gotrader.Order{Type: gotrader.MarketOrder, Side: gotrader.Long, StopLoss: 1.0988}, gotrader.StopLossLevel},
+		{&gotrader.Order{Type: gotrader.MarketOrder, Side: gotrader.Short, TakeProfit: 1.0988}, gotrader.TakeProfitLevel},
+		{&gotrader.Order{Type: gotrader.LimitOrder, Side: gotrader.Long, Price: 1.0990}, gotrader.EntryLevel},
+		{&gotrader.Order{Type: gotrader.StopOrder, Side: gotrader.Short, Price: 1.0988}, gotrader.EntryLevel},
+		{&gotrader.Order{Type: gotrader.LimitOrder, Side: gotrader.Long, Price: 1.0980, TakeProfit: 1.0983},
+			gotrader.TakeProfitLevel},
+	} {
+		test.order.Instrument, test.order.Units = "EUR_USD", 1000
+
+		var distanceErr *gotrader.DistanceError
+		if _, err := strategy.engine.SubmitOrder(test.order); !errors.As(err, &distanceErr) || distanceErr.Level != test.level {
+			t.Fatalf("expected the %s of %+v too close, got %v", test.level, test.order, err)
+		}
+	}
+
+	order := &gotrader.Order{Type: gotrader.LimitOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 1000,
+		Price: 1.0980, StopLoss: 1.0975, TakeProfit: 1.0990}
+
+	if _, err := strategy.engine.SubmitOrder(order); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHarness_Basket(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "GBP_USD", BaseCurrency: "GBP", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+	broker.Quote("GBP_USD", 1.2700, 1.2703)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD", "GBP_USD"}))
+
+	basket := func() []*gotrader.Order {
+		return []*gotrader.Order{
+			{Type: gotrader.MarketOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 1000},
+			{Type: gotrader.MarketOrder, Instrument: "GBP_USD", Side: gotrader.Short, Units: 1000},
+		}
+	}
+
+	broker.Program(Response{}, Response{Reject: "INSUFFICIENT_LIQUIDITY"})
+
+	if _, err := strategy.engine.SubmitBasket(basket()); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Settle() // the rejection notified after the submission rolls the basket back
+	h.AssertOpenTrades("EUR_USD", 0)
+	h.AssertOpenTrades("GBP_USD", 0)
+
+	if fills := h.Fills(); len(fills) != 3 || fills[2].Reason != gotrader.RolledBack {
+		t.Fatalf("expected the trade of the first order rolled back, got %+v", fills)
+	}
+
+	if ids, err := strategy.engine.SubmitBasket(basket()); err != nil || len(ids) != 2 {
+		t.Fatalf("expected the basket filled, got %v, %v", ids, err)
+	}
+
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 1)
+	h.AssertOpenTrades("GBP_USD", 1)
+}
+
+func TestHarness_SpecUpdate(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	start := func(policy gotrader.SpecPolicy) (*Harness, *passive) {
+
+		broker := NewBroker(instruments, Balance(10000), Leverage(30))
+		broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+		strategy := &passive{}
+		h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.SpecChanges(policy))
+
+		if err := strategy.engine.Buy("EUR_USD", 3000); err != nil {
+			t.Fatal(err)
+		}
+		h.Settle()
+
+		return h, strategy
+	}
+
+	t.Run("grandfathered trades keep their leverage", func(t *testing.T) {
+
+		h, strategy := start(gotrader.Grandfathered)
+		inst := h.Account().Instrument("EUR_USD")
+		margin := inst.MarginUsed()
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", Leverage: 10, MinUnits: 5000, Halted: true})
+
+		if inst.Leverage() != 10 || inst.MarginUsed() != margin {
+			t.Fatalf("expected the leverage cut without margin change, got %v and %v", inst.Leverage(), inst.MarginUsed())
+		}
+
+		if err := strategy.engine.Buy("EUR_USD", 5000); !errors.Is(err, gotrader.ErrInstrumentHalted) {
+			t.Fatalf("expected the halted instrument to reject the opens, got %v", err)
+		}
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", Resumed: true})
+
+		if err := strategy.engine.Buy("EUR_USD", 1000); !errors.Is(err, gotrader.ErrBelowMinUnits) {
+			t.Fatalf("expected the opens below the minimum rejected, got %v", err)
+		}
+
+		if err := strategy.engine.Buy("EUR_USD", 5000); err != nil {
+			t.Fatal(err)
+		}
+
+		h.Settle()
+		h.AssertOpenTrades("EUR_USD", 2)
+	})
+
+	t.Run("force adjusted trades are margined and closed", func(t *testing.T) {
+
+		h, _ := start(gotrader.ForceAdjusted)
+		inst := h.Account().Instrument("EUR_USD")
+		margin := inst.MarginUsed()
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", Leverage: 10})
+
+		if got := inst.MarginUsed(); math.Abs(got-3*margin) > 1e-6 {
+			t.Fatalf("expected the margin at the new leverage, got %v from %v", got, margin)
+		}
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", MinUnits: 5000})
+		h.waitFor("the close of the trade", func() bool { return inst.TradesNumber() == 0 })
+		h.Settle()
+
+		if fills := h.Fills(); fills[len(fills)-1].Reason != gotrader.SpecAdjusted {
+			t.Fatalf("expected the trade closed below the minimum, got %+v", fills[len(fills)-1])
+		}
+	})
+
+	t.Run("limit states block one side until cleared", func(t *testing.T) {
+
+		h, strategy := start(gotrader.Grandfathered)
+		inst := h.Account().Instrument("EUR_USD")
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", Limit: gotrader.LimitUp,
+			LimitUntil: h.Clock.Now().Add(time.Minute)})
+
+		var limitErr *gotrader.LimitError
+		if err := strategy.engine.Buy("EUR_USD", 1000); !errors.As(err, &limitErr) || limitErr.State != gotrader.LimitUp {
+			t.Fatalf("expected the buys blocked by the limit up, got %v", err)
+		}
+
+		if err := strategy.engine.Sell("EUR_USD", 1000); err != nil {
+			t.Fatal(err)
+		}
+
+		h.Advance(time.Minute)
+		h.Tick("EUR_USD", 1.0990, 1.0992)
+
+		if inst.Limit() != gotrader.NoLimit {
+			t.Fatalf("expected the limit state cleared by its timer, got %s", inst.Limit())
+		}
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", Limit: gotrader.LimitDown})
+
+		if err := strategy.engine.Sell("EUR_USD", 1000); !errors.Is(err, gotrader.ErrLimitState) {
+			t.Fatalf("expected the sells blocked by the limit down, got %v", err)
+		}
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", LimitCleared: true})
+
+		if err := strategy.engine.Sell("EUR_USD", 1000); err != nil {
+			t.Fatal(err)
+		}
+
+		h.Settle()
+		h.AssertUnits("EUR_USD", gotrader.Short, 2000)
+	})
+
+	t.Run("status transitions are published", func(t *testing.T) {
+
+		h, strategy := start(gotrader.Grandfathered)
+
+		transitions := make(chan gotrader.StatusChanged, 10)
+		subscription := h.Account().Events().Subscribe(func(event gotrader.Event) {
+			transitions <- event.(gotrader.StatusChanged)
+		}, 10, gotrader.StatusChangedEvent)
+		defer subscription.Unsubscribe()
+
+		var id string
+		for trade := range h.Account().Instrument("EUR_USD").Trades() {
+			id = trade.ID()
+		}
+
+		if err := strategy.engine.SetStatus("EUR_USD", gotrader.InstrumentCloseOnly); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := strategy.engine.Buy("EUR_USD", 1000); !errors.Is(err, gotrader.ErrCloseOnly) {
+			t.Fatalf("expected the opens rejected by the close only status, got %v", err)
+		}
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", Halted: true})
+
+		if err := strategy.engine.CloseTrade("EUR_USD", id); !errors.Is(err, gotrader.ErrInstrumentHalted) {
+			t.Fatalf("expected the closes rejected by the halt, got %v", err)
+		}
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", Status: gotrader.InstrumentCloseOnly})
+
+		if err := strategy.engine.CloseTrade("EUR_USD", id); err != nil {
+			t.Fatal(err)
+		}
+		h.Settle()
+
+		h.UpdateSpec(gotrader.SpecUpdate{Instrument: "EUR_USD", Status: gotrader.InstrumentExpired})
+
+		if err := strategy.engine.SetStatus("EUR_USD", gotrader.InstrumentTradeable); !errors.Is(err, gotrader.ErrInstrumentExpired) {
+			t.Fatalf("expected the expired status final, got %v", err)
+		}
+
+		want := []gotrader.InstrumentStatus{gotrader.InstrumentCloseOnly, gotrader.InstrumentHalted,
+			gotrader.InstrumentCloseOnly, gotrader.InstrumentExpired}
+
+		for i, status := range want {
+			select {
+			case event := <-transitions:
+				if event.To != status || event.Manual != (i == 0) {
+					t.Fatalf("expected the transition %d to %s, got %+v", i, status, event)
+				}
+			case <-time.After(Timeout):
+				t.Fatalf("timeout waiting for the transition to %s", status)
+			}
+		}
+	})
+}
+
+func TestHarness_Alerts(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	h := New(t, &passive{}, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	triggered := make(map[gotrader.AlertCondition]int)
+	count := func(event gotrader.AlertTriggered) { triggered[event.Alert.Condition]++ }
+
+	alerts := h.Account().Alerts()
+	for _, alert := range []gotrader.Alert{
+		{Instrument: "EUR_USD", Condition: gotrader.CrossAbove, Level: 1.1, Once: true, OnTrigger: count},
+		{Instrument: "EUR_USD", Condition: gotrader.CrossBelow, Level: 1.1, OnTrigger: count},
+		{Instrument: "EUR_USD", Condition: gotrader.SpreadAbove, Level: 0.0005, OnTrigger: count},
+		{Instrument: "EUR_USD", Condition: gotrader.MoveWithin, Level: 0.005, Window: time.Minute, OnTrigger: count},
+	} {
+		if _, err := alerts.Add(alert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+	h.Advance(10 * time.Second)
+	h.Tick("EUR_USD", 1.1010, 1.1012)
+	h.Advance(10 * time.Second)
+	h.Tick("EUR_USD", 1.0980, 1.0990)
+	h.Advance(10 * time.Second)
+	h.Tick("EUR_USD", 1.1040, 1.1050)
+	h.Advance(10 * time.Second)
+	h.Tick("EUR_USD", 1.1050, 1.1052)
+
+	want := map[gotrader.AlertCondition]int{
+		gotrader.CrossAbove:  1,
+		gotrader.CrossBelow:  1,
+		gotrader.SpreadAbove: 1,
+		gotrader.MoveWithin:  1,
+	}
+
+	for condition, n := range want {
+		if triggered[condition] != n {
+			t.Fatalf("expected %s triggered %d times, got %d", condition, n, triggered[condition])
+		}
+	}
+
+	if list := alerts.List(); len(list) != 3 {
+		t.Fatalf("expected the once alert removed, got %d alerts", len(list))
+	}
+}
+
+func TestHarness_Quantity(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "BTC_USD", BaseCurrency: "BTC", QuoteCurrency: "USD", Leverage: 2, PipLocation: -2, UnitSize: 0.001,
+			MinUnits: 10},
+	}
+
+	broker := NewBroker(instruments, Balance(100000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+	broker.Quote("BTC_USD", 60000, 60010)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD", "BTC_USD"}))
+
+	fx, btc := h.Account().Instrument("EUR_USD"), h.Account().Instrument("BTC_USD")
+
+	if units, err := fx.Units(gotrader.Lots(1.5, gotrader.MiniLot)); err != nil || units != 15000 {
+		t.Fatalf("expected 15000 units, got %d (%v)", units, err)
+	}
+
+	units, err := btc.Units(0.25)
+	if err != nil || units != 250 {
+		t.Fatalf("expected 250 units of 0.001 BTC, got %d (%v)", units, err)
+	}
+
+	if _, err := btc.Units(0.0105); !errors.Is(err, gotrader.ErrInvalidQuantity) {
+		t.Fatalf("expected the quantity off the unit size rejected, got %v", err)
+	}
+
+	if _, err := btc.Units(0.005); !errors.Is(err, gotrader.ErrBelowMinUnits) {
+		t.Fatalf("expected the quantity below the minimum rejected, got %v", err)
+	}
+
+	if q := btc.RoundQuantity(0.0105); math.Abs(float64(q)-0.01) > 1e-12 {
+		t.Fatalf("expected the quantity rounded down to 0.01, got %v", q)
+	}
+
+	if err := strategy.engine.Buy("BTC_USD", units); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Settle()
+	h.AssertUnits("BTC_USD", gotrader.Long, 250)
+
+	if q := btc.Quantity(250); math.Abs(float64(q)-0.25) > 1e-12 {
+		t.Fatalf("expected 0.25 BTC, got %v", q)
+	}
+}
+
+func TestHarness_SpreadCost(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	if err := strategy.engine.Buy("EUR_USD", 10000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+
+	var trade *gotrader.Trade
+	for trade = range h.Account().Instrument("EUR_USD").Trades() {
+	}
+
+	if spread := trade.OpenSpread(); math.Abs(spread-0.0002) > 1e-9 {
+		t.Fatalf("expected the open spread of 2 pips, got %v", spread)
+	}
+
+	open := trade.SpreadCost()
+	if math.Abs(open+1/1.0991) > 1e-3 {
+		t.Fatalf("expected the half spread of 1 USD paid at the open, got %v EUR", open)
+	}
+
+	h.Tick("EUR_USD", 1.0995, 1.0999)
+
+	if err := strategy.engine.CloseTrade("EUR_USD", trade.ID()); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+
+	closed := h.Account().Ledger().ClosedTrades()
+	if len(closed) != 1 || math.Abs(closed[0].SpreadCost-open+2/1.0997) > 1e-3 {
+		t.Fatalf("expected the half spreads of the open and the close, got %+v", closed)
+	}
+
+	if cost := h.Account().Instrument("EUR_USD").SpreadCost(); math.Abs(cost-closed[0].SpreadCost) > 1e-9 {
+		t.Fatalf("expected the spread cost of the instrument %v, got %v", closed[0].SpreadCost, cost)
+	}
+
+	if err := strategy.engine.Buy("EUR_USD", 10000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+
+	for trade = range h.Account().Instrument("EUR_USD").Trades() {
+	}
+
+	data, err := json.Marshal(trade)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &gotrader.Trade{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.OpenSpread() != trade.OpenSpread() || decoded.SpreadCost() != trade.SpreadCost() {
+		t.Errorf("expected the spreads encoded, got %v and %v", decoded.OpenSpread(), decoded.SpreadCost())
+	}
+
+	if data, err = json.Marshal(h.Account().Instrument("EUR_USD")); err != nil {
+		t.Fatal(err)
+	}
+
+	var reopened gotrader.Instrument
+	if err := json.Unmarshal(data, &reopened); err != nil {
+		t.Fatal(err)
+	}
+
+	if tr := reopened.Trade(trade.ID()); tr == nil || tr.SpreadCost() != trade.SpreadCost() {
+		t.Errorf("expected the spread cost of the reopened trade, got %+v", tr)
+	}
+
+	restored := gotrader.RestoreAccount(h.Account().Snapshot(), gotrader.NopLogger())
+
+	inst := restored.Instrument("EUR_USD")
+	if tr := inst.Trade(trade.ID()); tr == nil || tr.OpenSpread() != trade.OpenSpread() ||
+		tr.SpreadCost() != trade.SpreadCost() {
+		t.Fatalf("expected the spreads of the trade restored, got %+v", tr)
+	}
+
+	if cost, want := inst.SpreadCost(), h.Account().Instrument("EUR_USD").SpreadCost(); math.Abs(cost-want) > 1e-9 {
+		t.Errorf("expected the spread cost of the instrument %v restored, got %v", want, cost)
+	}
+}
+
+func TestHarness_Slippage(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	broker.Program(Response{Price: 1.0994}, Response{Price: 1.0989})
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+
+	var id string
+	for trade := range h.Account().Instrument("EUR_USD").Trades() {
+		id = trade.ID()
+	}
+
+	if err := strategy.engine.CloseTrade("EUR_USD", id); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+
+	fills := h.Account().Slippages().Fills()
+	if len(fills) != 2 || math.Abs(fills[0].Pips-2) > 1e-6 || fills[1].Side != gotrader.Short ||
+		math.Abs(fills[1].Pips-1) > 1e-6 {
+		t.Fatalf("expected 2 and 1 pips of slippage, got %+v", fills)
+	}
+
+	d := h.Account().Slippages().ByInstrument()["EUR_USD"]
+	if d.Count != 2 || math.Abs(d.Mean-1.5) > 1e-6 || math.Abs(d.Quantile(1)-2) > 1e-6 {
+		t.Fatalf("expected the distribution of 2 fills of mean 1.5 pips, got %+v", d)
+	}
+
+	if venues := h.Account().Slippages().ByVenue(); venues[""].Count != 2 {
+		t.Fatalf("expected the fills of the client without venues, got %+v", venues)
+	}
+}
+
+// rejecting records the rejections of its orders.
+type rejecting struct {
+	passive
+	reasons chan gotrader.RejectReason
+}
+
+func (s *rejecting) OnOrderRejected(fill *gotrader.OrderFill, reason gotrader.RejectReason) {
+	s.reasons <- reason
+}
+
+func TestHarness_Rejections(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &rejecting{reasons: make(chan gotrader.RejectReason, 10)}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	events := make(chan gotrader.OrderRejected, 10)
+	subscription := h.Account().Events().Subscribe(func(event gotrader.Event) {
+		events <- event.(gotrader.OrderRejected)
+	}, 10, gotrader.OrderRejectedEvent)
+	defer subscription.Unsubscribe()
+
+	for reject, want := range map[string]gotrader.RejectReason{
+		"INSUFFICIENT_MARGIN":       gotrader.RejectInsufficientMargin,
+		"Market is closed":          gotrader.RejectMarketClosed,
+		"PRICE_BOUND_VIOLATION":     gotrader.RejectPriceChanged,
+		"UNITS_LIMIT_EXCEEDED":      gotrader.RejectSizeLimit,
+		"Filter failure: LOT_SIZE":  gotrader.RejectSizeLimit,
+		"INSUFFICIENT_LIQUIDITY":    gotrader.RejectUnknown,
+		"EXPIRED: Order would fill": gotrader.RejectOrderExpired,
+	} {
+		broker.Reject(reject)
+
+		if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+			t.Fatal(err)
+		}
+		h.Settle()
+
+		select {
+		case reason := <-strategy.reasons:
+			if reason != want {
+				t.Fatalf("expected %s classified %s, got %s", reject, want, reason)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected the rejection %s delivered to the strategy", reject)
+		}
+
+		select {
+		case event := <-events:
+			if event.Reason != want || event.Fill.Error != reject {
+				t.Fatalf("expected the %s rejection published, got %+v", want, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected the rejection %s published", reject)
+		}
+	}
+}
+
+func TestHarness_Retry(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	audit := gotrader.NewAuditLog()
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.Audit(audit),
+		gotrader.Retry(gotrader.RetryPolicy{Attempts: 3, Base: time.Millisecond}))
+
+	// the broker counts a fill for every failed request, so the fills are awaited instead of settled
+	unavailable := fmt.Errorf("%w: 503", gotrader.ErrTransient)
+	broker.Program(Response{Error: unavailable}, Response{Error: unavailable})
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h.waitFor("the retried order", func() bool { return len(h.Fills()) == 1 })
+	h.AssertUnits("EUR_USD", gotrader.Long, 1000)
+
+	retries := audit.Records(gotrader.AuditQuery{Events: []gotrader.AuditEvent{gotrader.AuditRetried}})
+	if len(retries) != 2 || retries[1].Attempt != 2 || retries[1].Instrument != "EUR_USD" {
+		t.Fatalf("expected 2 retries recorded, got %+v", retries)
+	}
+
+	broker.Program(Response{Error: errors.New("invalid request")})
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h.waitFor("the failed order", func() bool { return len(h.Fills()) == 2 })
+
+	if fill := h.Fills()[1]; fill.Error != "invalid request" {
+		t.Fatalf("expected the error not retried, got %+v", fill)
+	}
+	h.AssertRequests(MarketOrderRequest, MarketOrderRequest, MarketOrderRequest, MarketOrderRequest)
+}
+
+func TestHarness_Health(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.HealthCheck(gotrader.HealthPolicy{Degraded: time.Minute}))
+
+	changes := make(chan gotrader.HealthChanged, 10)
+	subscription := h.Account().Events().Subscribe(func(event gotrader.Event) {
+		changes <- event.(gotrader.HealthChanged)
+	}, 10, gotrader.HealthChangedEvent)
+	defer subscription.Unsubscribe()
+
+	health := h.Account().Health()
+	state := func() gotrader.HealthState {
+		c, _ := health.Component(gotrader.FeedComponent)
+		return c.State
+	}
+
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+	if !health.Healthy() {
+		t.Fatalf("expected a healthy feed, got %+v", health.Status())
+	}
+
+	h.Advance(90 * time.Second)
+	h.waitFor("the degraded feed", func() bool { return state() == gotrader.Degraded })
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatalf("expected the opens allowed on a degraded feed, got %v", err)
+	}
+	h.Settle()
+
+	h.Advance(time.Minute)
+	h.waitFor("the unhealthy feed", func() bool { return state() == gotrader.Unhealthy })
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); !errors.Is(err, gotrader.ErrUnhealthy) {
+		t.Fatalf("expected the opens rejected on an unhealthy feed, got %v", err)
+	}
+
+	h.Tick("EUR_USD", 1.0991, 1.0993)
+	if state() != gotrader.Healthy {
+		t.Fatalf("expected the feed recovered by the tick, got %s", state())
+	}
+
+	for _, want := range []gotrader.HealthState{gotrader.Degraded, gotrader.Unhealthy, gotrader.Healthy} {
+		select {
+		case change := <-changes:
+			if change.Component != gotrader.FeedComponent || change.To != want {
+				t.Fatalf("expected the feed %s, got %+v", want, change)
+			}
+		case <-time.After(Timeout):
+			t.Fatalf("timeout waiting for the feed %s", want)
+		}
+	}
+}
+
+// backupFeed is a secondary price source streaming the ticks of the test.
+type backupFeed struct {
+	callback gotrader.TickHandler
+}
+
+func (f *backupFeed) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails,
+	callback gotrader.TickHandler) error {
+	f.callback = callback
+	return nil
+}
+
+func TestHarness_Failover(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	backup := &backupFeed{}
+	h := New(t, &passive{}, broker, gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.Failover(gotrader.FeedFailover{Secondary: backup, After: time.Minute}))
+
+	switches := make(chan gotrader.FeedSwitched, 10)
+	subscription := h.Account().Events().Subscribe(func(event gotrader.Event) {
+		switches <- event.(gotrader.FeedSwitched)
+	}, 10, gotrader.FeedSwitchedEvent)
+	defer subscription.Unsubscribe()
+
+	inst := h.Account().Instrument("EUR_USD")
+	backupTick := func(bid, ask float64) {
+		backup.callback(&gotrader.Tick{Instrument: "EUR_USD", Bid: bid, Ask: ask, Time: h.Clock.Now()})
+	}
+
+	h.Tick("EUR_USD", 1.0991, 1.0993)
+	backupTick(1.2000, 1.2002)
+
+	if inst.Bid() != 1.0991 || h.Session.ActiveFeeds()["EUR_USD"] != gotrader.PrimaryFeed {
+		t.Fatalf("expected the ticks of the secondary feed discarded, got the bid %v", inst.Bid())
+	}
+
+	h.Advance(2 * time.Minute)
+	h.waitFor("the failover", func() bool { return h.Session.ActiveFeeds()["EUR_USD"] == gotrader.SecondaryFeed })
+
+	backupTick(1.1000, 1.1002)
+	h.waitFor("the tick of the secondary feed", func() bool { return inst.Bid() == 1.1000 })
+
+	h.Tick("EUR_USD", 1.0995, 1.0997)
+	if inst.Bid() != 1.0995 || h.Session.ActiveFeeds()["EUR_USD"] != gotrader.PrimaryFeed {
+		t.Fatalf("expected the primary feed restored, got the bid %v", inst.Bid())
+	}
+
+	for _, want := range []gotrader.FeedSource{gotrader.SecondaryFeed, gotrader.PrimaryFeed} {
+		select {
+		case s := <-switches:
+			if s.Instrument != "EUR_USD" || s.To != want {
+				t.Fatalf("expected the switch to the %s feed, got %+v", want, s)
+			}
+		case <-time.After(Timeout):
+			t.Fatalf("timeout waiting for the switch to the %s feed", want)
+		}
+	}
+}
+
+func TestHarness_Consolidation(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.0990, 1.0994)
+
+	ecn := &backupFeed{}
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.Consolidate(time.Minute, gotrader.NamedFeed{Name: "ecn", Feed: ecn}))
+
+	inst := h.Account().Instrument("EUR_USD")
+	ecnTick := func(bid, ask float64) {
+		ecn.callback(&gotrader.Tick{Instrument: "EUR_USD", Bid: bid, Ask: ask, Time: h.Clock.Now()})
+	}
+
+	ecnTick(1.0991, 1.0995)
+	h.waitFor("the consolidated bid", func() bool { return inst.Bid() == 1.0991 })
+
+	quote := h.Session.ConsolidatedQuotes()["EUR_USD"]
+	if quote.Ask != 1.0994 || quote.BidSource != "ecn" || quote.AskSource != gotrader.BrokerFeed {
+		t.Fatalf("expected the ecn bid and the broker ask, got %+v", quote)
+	}
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	h.AssertOpenTrades("EUR_USD", 1)
+
+	for trade := range inst.Trades() {
+		if trade.OpenPrice() != 1.0994 {
+			t.Fatalf("expected the order filled by the broker at 1.0994, got %v", trade.OpenPrice())
+		}
+	}
+
+	h.Advance(2 * time.Minute)
+	h.Tick("EUR_USD", 1.0989, 1.0993)
+
+	if quote := h.Session.ConsolidatedQuotes()["EUR_USD"]; quote.Bid != 1.0989 || quote.BidSource != gotrader.BrokerFeed {
+		t.Fatalf("expected the aged ecn quote left out, got %+v", quote)
+	}
+}
+
+func TestHarness_Synthetic(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "GBP_USD", BaseCurrency: "GBP", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30))
+	broker.Quote("EUR_USD", 1.1000, 1.1002)
+	broker.Quote("GBP_USD", 1.2500, 1.2503)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.Synthetic(gotrader.SyntheticCross{First: "EUR_USD", Second: "GBP_USD"}))
+
+	cross := h.Account().Instrument("EUR_GBP")
+	if cross == nil || !cross.Synthetic() || cross.BaseCurrency() != "EUR" || cross.QuoteCurrency() != "GBP" {
+		t.Fatalf("expected the synthetic EUR_GBP, got %+v", cross)
+	}
+
+	h.Tick("GBP_USD", 1.2400, 1.2402)
+	h.waitFor("the cross of the GBP_USD tick", func() bool {
+		return math.Abs(cross.Bid()-1.1000/1.2402) < 1e-9 && math.Abs(cross.Ask()-1.1002/1.2400) < 1e-9
+	})
+
+	h.Tick("EUR_USD", 1.1100, 1.1102)
+	h.waitFor("the cross of the EUR_USD tick", func() bool {
+		return math.Abs(cross.Bid()-1.1100/1.2402) < 1e-9 && math.Abs(cross.Ask()-1.1102/1.2400) < 1e-9
+	})
+
+	if err := strategy.engine.Buy("EUR_GBP", 1000); !errors.Is(err, gotrader.ErrSyntheticInstrument) {
+		t.Fatalf("expected the orders of the synthetic rejected, got %v", err)
+	}
+}
+
+func TestHarness_DailyStats(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.DayBoundary(17*time.Hour, time.UTC))
+
+	if err := strategy.engine.Buy("EUR_USD", 10000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	h.Tick("EUR_USD", 1.0995, 1.0999)
+
+	account := h.Account()
+	first := h.Clock.Now()
+	unrealized := account.UnrealizedNetProfit()
+
+	if today := account.DailyStats(first); today.Opened != 1 || today.Closed != 0 ||
+		math.Abs(account.TodayPnL()-unrealized) > 1e-9 {
+		t.Fatalf("expected the open trade valued in the day, got %+v and %v", today, account.TodayPnL())
+	}
+
+	h.Advance(24 * time.Hour)
+	h.Tick("EUR_USD", 1.0995, 1.0999)
+
+	var trade *gotrader.Trade
+	for trade = range account.Instrument("EUR_USD").Trades() {
+	}
+
+	if err := strategy.engine.CloseTrade("EUR_USD", trade.ID()); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	h.Tick("EUR_USD", 1.0995, 1.0999)
+
+	closed := account.Ledger().ClosedTrades()
+	today := account.DailyStats(h.Clock.Now())
+
+	if today.Opened != 0 || today.Closed != 1 || math.Abs(today.RealizedProfit-closed[0].Amount) > 1e-6 ||
+		math.Abs(today.UnrealizedChange+unrealized) > 1e-9 {
+		t.Fatalf("expected the close realized in the next day, got %+v", today)
+	}
+
+	if previous := account.DailyStats(first); previous.Start.Hour() != 17 || previous.Opened != 1 ||
+		math.Abs(previous.UnrealizedProfit-unrealized) > 1e-9 {
+		t.Fatalf("expected the open kept in the previous day, got %+v", previous)
+	}
+}
+
+func TestHarness_Calendar(t *testing.T) {
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	rollover := gotrader.DailySession{Location: newYork, Open: 17 * time.Hour, Close: 17 * time.Hour}
+	if open := rollover.NextOpen(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)); !open.Equal(time.Date(2024, 3, 10, 21, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the rollover at 17:00 on the daylight saving change, got %v", open)
+	}
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	venue := gotrader.VenueCalendar{
+		Name:     "FX",
+		Hours:    gotrader.DailySession{Location: time.UTC, Open: 0, Close: 23 * time.Hour},
+		Holidays: []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.VenueHours(venue, "EUR_USD"))
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); !errors.Is(err, gotrader.ErrMarketClosed) {
+		t.Fatalf("expected the market closed on the holiday, got %v", err)
+	}
+
+	h.Advance(24*time.Hour + time.Minute)
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatalf("expected the market open after the holiday, got %v", err)
+	}
+	h.Settle()
+
+	if trades := h.Account().Instrument("EUR_USD").TradesNumber(); trades != 1 {
+		t.Fatalf("expected 1 trade, got %d", trades)
+	}
+}
+
+func TestHarness_StateAt(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	wal, err := gotrader.OpenWAL(t.TempDir()+"/account.wal", gotrader.NoSync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	audit := gotrader.NewAuditLog()
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.WriteAheadLog(wal),
+		gotrader.Audit(audit))
+
+	snapshot := h.Account().Snapshot()
+
+	h.Advance(time.Minute)
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	opened := h.Clock.Now()
+
+	h.Advance(time.Hour)
+	if _, err := strategy.engine.SubmitOrder(&gotrader.Order{Type: gotrader.LimitOrder, Instrument: "EUR_USD",
+		Side: gotrader.Long, Units: 1000, Price: 1.09}); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	submitted := h.Clock.Now()
+
+	h.Advance(time.Hour)
+	for trade := range h.Account().Instrument("EUR_USD").Trades() {
+		if err := strategy.engine.CloseTrade("EUR_USD", trade.ID()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.Settle()
+
+	state, err := gotrader.StateAt(snapshot, wal, audit, opened.Add(30*time.Minute), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trades := state.Account.Instrument("EUR_USD").TradesNumber(); trades != 1 || len(state.Orders) != 0 ||
+		state.Account.Balance() != 10000 || state.WALSequence != 1 {
+		t.Fatalf("expected the open trade alone, got %d trades, %d orders and %+v", trades, len(state.Orders), state)
+	}
+
+	state, err = gotrader.StateAt(snapshot, wal, audit, submitted, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trades := state.Account.Instrument("EUR_USD").TradesNumber(); trades != 1 || len(state.Orders) != 1 ||
+		state.Orders[0].Price != 1.09 {
+		t.Fatalf("expected the open trade and the pending order, got %d trades and %+v", trades, state.Orders)
+	}
+
+	state, err = gotrader.StateAt(snapshot, wal, audit, h.Clock.Now(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trades := state.Account.Instrument("EUR_USD").TradesNumber(); trades != 0 ||
+		len(state.Account.Ledger().ClosedTrades()) != 1 ||
+		math.Abs(state.Account.Balance()-h.Account().Balance()) > Tolerance {
+		t.Fatalf("expected the trade closed, got %d trades and balance %v", trades, state.Account.Balance())
+	}
+
+	if _, err := gotrader.StateAt(snapshot, wal, audit, snapshot.Time.Add(-time.Second), nil); err == nil {
+		t.Fatal("expected the states before the snapshot to fail")
+	}
+}
+
+// checkView returns an error when the view disagrees with itself: the balance with the last transaction, the
+// trades with the instrument metrics, or an open trade with a close in the ledger.
+func checkView(view *gotrader.AccountView, openingBalance float64) error {
+
+	balance := openingBalance
+	closed := make(map[string]bool)
+	for _, transaction := range view.Transactions {
+		balance = transaction.Balance
+		closed[transaction.TradeID] = true
+	}
+
+	if math.Abs(view.Balance-balance) > 1e-9 {
+		return fmt.Errorf("balance %v, last transaction balance %v", view.Balance, balance)
+	}
+
+	for _, iv := range view.Instruments {
+
+		if int(iv.TradesNumber) != len(iv.Trades) {
+			return fmt.Errorf("%s: %d trades, %d copied", iv.Name, iv.TradesNumber, len(iv.Trades))
+		}
+
+		unrealized := 0.0
+		for _, trade := range iv.Trades {
+			if closed[trade.ID] {
+				return fmt.Errorf("%s: trade %s open and closed", iv.Name, trade.ID)
+			}
+			unrealized += trade.UnrealizedNetProfit
+		}
+
+		if math.Abs(unrealized-iv.UnrealizedNetProfit) > 1e-6 {
+			return fmt.Errorf("%s: trades unrealized %v, instrument %v", iv.Name, unrealized, iv.UnrealizedNetProfit)
+		}
+	}
+
+	return nil
+}
+
+func TestHarness_View(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "EUR_GBP", BaseCurrency: "EUR", QuoteCurrency: "GBP", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+	broker.Quote("EUR_GBP", 0.8590, 0.8592)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD", "EUR_GBP"}))
+
+	done := make(chan struct{})
+	result := make(chan error, 1)
+	go func() {
+		views := 0
+		for {
+			select {
+			case <-done:
+				if views == 0 {
+					result <- errors.New("no view built")
+				}
+				close(result)
+				return
+			default:
+			}
+
+			if err := checkView(h.Account().View(), 10000); err != nil {
+				result <- err
+				close(result)
+				return
+			}
+			views++
+		}
+	}()
+
+	for n := 0; n < 40; n++ {
+
+		h.Advance(time.Second)
+		h.Tick("EUR_USD", 1.0990+float64(n)*0.0001, 1.0992+float64(n)*0.0001)
+		h.Tick("EUR_GBP", 0.8590-float64(n)*0.0001, 0.8592-float64(n)*0.0001)
+
+		for _, instrument := range []string{"EUR_USD", "EUR_GBP"} {
+			if err := strategy.engine.Buy(instrument, 1000); err != nil {
+				t.Fatal(err)
+			}
+
+			if n%3 == 2 {
+				trade := h.Account().Instrument(instrument).TradeByOrder(0)
+				if err := strategy.engine.CloseTrade(instrument, trade.ID()); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		h.Settle()
+	}
+
+	close(done)
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	h.Tick("EUR_USD", 1.1030, 1.1032) // the account totals are aggregated on the ticks
+	h.Settle()
+
+	view := h.Account().View()
+	if err := checkView(view, 10000); err != nil {
+		t.Fatal(err)
+	}
+
+	h.assertAmount("view equity", view.Equity, h.Account().Equity())
+	h.assertAmount("view margin used", view.MarginUsed, h.Account().MarginUsed())
+
+	if view.TradesNumber() != 54 || view.Instrument("EUR_GBP").Trades[0].OpenTime.After(
+		view.Instrument("EUR_GBP").Trades[1].OpenTime) {
+		t.Errorf("expected 54 trades by open time order, got %d", view.TradesNumber())
+	}
+
+	if again := h.Account().View(); again.Instrument("EUR_USD") != view.Instrument("EUR_USD") {
+		t.Error("expected the view of an unchanged instrument to be reused")
+	}
+}
+
+func TestHarness_Readers(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	h.Tick("EUR_USD", 1.1010, 1.1012)
+	h.Settle()
+
+	inst := h.Account().Instrument("EUR_USD")
+	trade := inst.TradeByOrder(0)
+	reader := inst.TradeReader(trade.ID())
+
+	if state := reader.State(); state.ID != trade.ID() || state.Units != 1000 || state.CurrentPrice != 1.1010 ||
+		state.UnrealizedNetProfit != trade.UnrealizedNetProfit() {
+		t.Errorf("expected the state of trade %s, got %+v", trade.ID(), state)
+	}
+
+	long := inst.LongPositionReader()
+	if states := long.TradeStates(); len(states) != 1 || states[0] != reader.State() || long.State().Units != 1000 {
+		t.Errorf("expected the long position with the trade, got %+v and %+v", long.State(), states)
+	}
+
+	data, err := json.Marshal(trade)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(data, trade); !errors.Is(err, gotrader.ErrReadOnly) {
+		t.Errorf("expected the book-kept trade to be read only, got %v", err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"side":"SHORT"}`), inst.LongPosition()); !errors.Is(err, gotrader.ErrReadOnly) {
+		t.Errorf("expected the book-kept position to be read only, got %v", err)
+	}
+
+	var decoded gotrader.Trade
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID() != trade.ID() {
+		t.Fatalf("expected a detached copy, got %v", err)
+	}
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Errorf("expected a detached copy to be decoded again, got %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	if expiry, exist := fields["expiry"]; exist || !decoded.Expiry().IsZero() {
+		t.Errorf("expected the expiry of the trade without maximum lifetime omitted, got %s", expiry)
+	}
+
+	h.AssertUnits("EUR_USD", gotrader.Long, 1000)
+	h.assertAmount("trade unrealized profit", trade.UnrealizedNetProfit(), reader.UnrealizedNetProfit())
+}
+
+func TestHarness_TradeHooks(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "EUR_GBP", BaseCurrency: "EUR", QuoteCurrency: "GBP", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+	broker.Quote("EUR_GBP", 0.8590, 0.8592)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD", "EUR_GBP"}))
+
+	var mutex sync.Mutex
+	var synchronous, subscribed []string
+	record := func(calls *[]string, call string) {
+		mutex.Lock()
+		*calls = append(*calls, call)
+		mutex.Unlock()
+	}
+	calls := func(calls *[]string) []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), *calls...)
+	}
+
+	hook := h.Account().Events().HookTrades(gotrader.TradeHooks{
+		Instruments: []string{"EUR_USD"},
+		Opened:      func(e gotrader.TradeOpened) { record(&synchronous, "opened "+e.Trade.InstrumentName()) },
+		Updated:     func(e gotrader.TradeUpdated) { record(&synchronous, "updated "+e.Update.String()) },
+		Closed:      func(e gotrader.TradeClosed) { record(&synchronous, "closed "+e.Fill.Instrument.Name) },
+		Synchronous: true,
+	})
+	h.Account().Events().HookTrades(gotrader.TradeHooks{
+		Opened: func(e gotrader.TradeOpened) { record(&subscribed, "opened "+e.Trade.InstrumentName()) },
+		Closed: func(e gotrader.TradeClosed) { record(&subscribed, "closed "+e.Fill.Instrument.Name) },
+	})
+
+	for _, instrument := range []string{"EUR_USD", "EUR_GBP"} {
+		if err := strategy.engine.Buy(instrument, 1000); err != nil {
+			t.Fatal(err)
+		}
+		h.Settle()
+	}
+
+	trade := h.Account().Instrument("EUR_USD").TradeByOrder(0)
+	h.ChargeSwap(trade.ID(), -0.5)
+	h.waitFor("the trade update", func() bool { return len(calls(&synchronous)) == 2 })
+
+	for _, instrument := range []string{"EUR_USD", "EUR_GBP"} {
+		id := h.Account().Instrument(instrument).TradeByOrder(0).ID()
+		if err := strategy.engine.CloseTrade(instrument, id); err != nil {
+			t.Fatal(err)
+		}
+		h.Settle()
+	}
+	h.waitFor("the subscribed hooks", func() bool { return len(calls(&subscribed)) == 4 })
+
+	want := []string{"opened EUR_USD", "updated FINANCING", "closed EUR_USD"}
+	if got := calls(&synchronous); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected the synchronous hooks %v, got %v", want, got)
+	}
+
+	want = []string{"opened EUR_USD", "opened EUR_GBP", "closed EUR_USD", "closed EUR_GBP"}
+	if got := calls(&subscribed); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected the subscribed hooks %v, got %v", want, got)
+	}
+
+	hook.Remove()
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+
+	if got := calls(&synchronous); len(got) != 3 {
+		t.Errorf("expected no call after the removal, got %v", got)
+	}
+}
+
+func TestHarness_PriceObservers(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.1000, 1.1002)
+
+	h := New(t, &passive{}, broker, gotrader.Instruments([]string{"EUR_USD"}))
+	inst := h.Account().Instrument("EUR_USD")
+
+	moves := make(chan gotrader.PriceUpdate, 100)
+	throttled := make(chan gotrader.PriceUpdate, 100)
+	byMove := inst.ObservePrices(func(update gotrader.PriceUpdate) { moves <- update }, gotrader.MinMove(5))
+	byTime := inst.ObservePrices(func(update gotrader.PriceUpdate) { throttled <- update },
+		gotrader.Throttle(time.Hour))
+	defer byTime.Stop()
+
+	receive := func(updates chan gotrader.PriceUpdate) gotrader.PriceUpdate {
+		select {
+		case update := <-updates:
+			return update
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a price update")
+		}
+		return gotrader.PriceUpdate{}
+	}
+
+	for n := 1; n <= 12; n++ {
+		h.Advance(time.Second)
+		h.Tick("EUR_USD", 1.1000+float64(n)*0.0001, 1.1002+float64(n)*0.0001)
+		h.Settle()
+
+		if n == 1 || n == 6 || n == 11 {
+			if update := receive(moves); math.Abs(update.Bid-(1.1000+float64(n)*0.0001)) > 1e-9 {
+				t.Errorf("expected the move of tick %d, got %+v", n, update)
+			}
+		}
+	}
+
+	if update := receive(throttled); update.Bid != 1.1001 || update.Ticks != 1 {
+		t.Errorf("expected the first price, got %+v", update)
+	}
+
+	byMove.Stop()
+	h.Tick("EUR_USD", 1.1100, 1.1102)
+	h.Settle()
+
+	select {
+	case update := <-moves:
+		t.Errorf("expected the moves conflated to the ones of 5 pips, got %+v", update)
+	case update := <-throttled:
+		t.Errorf("expected a single update per interval, got %+v", update)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHarness_BulkClose(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "EUR_GBP", BaseCurrency: "EUR", QuoteCurrency: "GBP", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+	broker.Quote("EUR_GBP", 0.8590, 0.8592)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD", "EUR_GBP"}))
+
+	for _, order := range []*gotrader.Order{
+		{Type: gotrader.MarketOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 1000, Tag: "trend"},
+		{Type: gotrader.MarketOrder, Instrument: "EUR_USD", Side: gotrader.Short, Units: 1000, Tag: "revert"},
+		{Type: gotrader.MarketOrder, Instrument: "EUR_GBP", Side: gotrader.Long, Units: 1000, Tag: "trend"},
+		{Type: gotrader.MarketOrder, Instrument: "EUR_GBP", Side: gotrader.Short, Units: 1000, Tag: "revert"},
+	} {
+		if _, err := strategy.engine.SubmitOrder(order); err != nil {
+			t.Fatal(err)
+		}
+		h.Settle()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	report := strategy.engine.Account().CloseByTag(ctx, "trend", "EUR_USD")
+	if report.Requested != 1 || len(report.Closed) != 1 || len(report.Failed) != 0 ||
+		report.Closed[0].Instrument.Name != "EUR_USD" {
+		t.Fatalf("expected the trend trade of EUR_USD closed, got %+v", report)
+	}
+	h.assertAmount("realized profit", report.RealizedProfit, report.Closed[0].Profit)
+
+	report = strategy.engine.Account().CloseSide(ctx, gotrader.Short)
+	if report.Requested != 2 || len(report.Closed) != 2 || len(report.Failed) != 0 {
+		t.Fatalf("expected the short trades closed, got %+v", report)
+	}
+	h.AssertOpenTrades("EUR_USD", 0)
+
+	broker.Reject("MARKET_HALTED")
+	report = strategy.engine.Account().CloseAllTrades(ctx)
+	if report.Requested != 1 || len(report.Closed) != 0 || len(report.Failed) != 1 {
+		t.Fatalf("expected the rejected close to fail, got %+v", report)
+	}
+	h.AssertOpenTrades("EUR_GBP", 1)
+
+	restored := gotrader.RestoreAccount(strategy.engine.Account().Snapshot(), gotrader.NopLogger())
+	if report = restored.CloseAllTrades(ctx); report.Requested != 1 || len(report.Failed) != 1 {
+		t.Errorf("expected the close of the restored account to fail, got %+v", report)
+	}
+	for _, err := range report.Failed {
+		if !errors.Is(err, gotrader.ErrNoSession) {
+			t.Errorf("expected ErrNoSession, got %v", err)
+		}
+	}
+
+	if report = strategy.engine.Account().CloseAllTrades(ctx, "EUR_USD"); report.Requested != 0 {
+		t.Errorf("expected no trade of EUR_USD, got %+v", report)
+	}
+
+	report = strategy.engine.Account().CloseAllTrades(ctx)
+	if report.Requested != 1 || len(report.Closed) != 1 {
+		t.Fatalf("expected the trade of EUR_GBP closed, got %+v", report)
+	}
+	h.AssertOpenTrades("EUR_GBP", 0)
+}
+
+func TestHarness_FlattenInstrument(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "EUR_GBP", BaseCurrency: "EUR", QuoteCurrency: "GBP", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+	broker.Quote("EUR_GBP", 0.8590, 0.8592)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD", "EUR_GBP"}))
+
+	for _, order := range []*gotrader.Order{
+		{Type: gotrader.MarketOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 1000},
+		{Type: gotrader.MarketOrder, Instrument: "EUR_USD", Side: gotrader.Short, Units: 3000},
+		{Type: gotrader.LimitOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 1000, Price: 1.05},
+		{Type: gotrader.LimitOrder, Instrument: "EUR_GBP", Side: gotrader.Long, Units: 1000, Price: 0.80},
+	} {
+		if _, err := strategy.engine.SubmitOrder(order); err != nil {
+			t.Fatal(err)
+		}
+		h.Settle()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := gotrader.FlattenInstrument(ctx, strategy.engine, "GBP_USD"); !errors.Is(err,
+		gotrader.ErrInstrumentNotTraded) {
+		t.Errorf("expected the instrument not traded, got %v", err)
+	}
+
+	broker.Reject("MARKET_HALTED")
+	report, err := gotrader.FlattenInstrument(ctx, strategy.engine, "EUR_USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Cancelled) != 1 || len(report.Closed) != 1 || len(report.Failed) != 1 ||
+		report.ResidualTrades != 1 || report.ResidualUnits != 1000 && report.ResidualUnits != -3000 {
+		t.Fatalf("expected the order cancelled, a trade closed and the rejected one left, got %+v", report)
+	}
+	h.assertAmount("realized profit", report.RealizedProfit, report.Closed[0].Profit)
+
+	if orders := strategy.engine.PendingOrders(""); len(orders) != 1 || orders[0].Instrument != "EUR_GBP" {
+		t.Errorf("expected the order of EUR_GBP pending, got %+v", orders)
+	}
+
+	report, err = gotrader.FlattenInstrument(ctx, strategy.engine, "EUR_USD")
+	if err != nil || len(report.Closed) != 1 || report.ResidualTrades != 0 || report.ResidualUnits != 0 {
+		t.Fatalf("expected the instrument flat, got %+v and %v", report, err)
+	}
+	h.AssertOpenTrades("EUR_USD", 0)
+}
+
+func TestHarness_PreviewMargin(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	if err := strategy.engine.Buy("EUR_USD", 3000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+	h.Settle()
+
+	account := h.Account()
+	h.assertAmount("margin used", account.MarginUsed(), 100)
+
+	preview, err := account.PreviewMargin("EUR_USD", gotrader.Long, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.assertAmount("required margin", preview.MarginRequired, 50)
+	h.assertAmount("margin impact", preview.MarginImpact, 50)
+	h.assertAmount("margin used", preview.MarginUsed, 150)
+	h.assertAmount("margin free", preview.MarginFree, account.Equity()-150)
+	h.assertAmount("margin level", preview.MarginLevel, account.Equity()/150)
+
+	if err := strategy.engine.SetHedge("EUR_USD", gotrader.NoHedge); err != nil {
+		t.Fatal(err)
+	}
+
+	preview, _ = account.PreviewMargin("EUR_USD", gotrader.Short, 1500)
+	h.assertAmount("margin impact without hedge", preview.MarginImpact, 50)
+
+	if err := strategy.engine.SetHedge("EUR_USD", gotrader.FullHedge); err != nil {
+		t.Fatal(err)
+	}
+
+	if preview, _ = account.PreviewMargin("EUR_USD", gotrader.Short, 1500); !preview.Sufficient {
+		t.Errorf("expected the hedging order to be covered, got %+v", preview)
+	}
+	h.assertAmount("hedged margin impact", preview.MarginImpact, -50)
+
+	if preview, _ = account.PreviewMargin("EUR_USD", gotrader.Long, 1000000); preview.Sufficient {
+		t.Errorf("expected the free margin not to cover the order, got %+v", preview)
+	}
+
+	if account.MarginUsed() != 100 {
+		t.Errorf("expected the previews not to change the margin used, got %v", account.MarginUsed())
+	}
+
+	if _, err := account.PreviewMargin("GBP_USD", gotrader.Long, 1000); !errors.Is(err, gotrader.ErrInstrumentNotTraded) {
+		t.Errorf("expected the instrument not traded, got %v", err)
+	}
+}
+
+func TestHarness_PreviewClose(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	if err := strategy.engine.Sell("EUR_USD", 2000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	h.Tick("EUR_USD", 1.0970, 1.0972)
+	h.Settle()
+
+	account := h.Account()
+	trade := account.Instrument("EUR_USD").TradeByOrder(0)
+
+	preview, err := account.PreviewClose(trade.ID(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if preview.Instrument != "EUR_USD" || preview.Side != gotrader.Short || preview.Units != 2000 ||
+		preview.ClosePrice != 1.0972 {
+		t.Errorf("expected the close of the short trade at the ask, got %+v", preview)
+	}
+	h.assertAmount("net profit", preview.NetProfit, trade.UnrealizedNetProfit())
+	h.assertAmount("realized profit", preview.RealizedProfit, trade.UnrealizedEffectiveProfit())
+	h.assertAmount("net profit in quote currency", preview.NetProfit/preview.QuoteConversionRate, 0.0018*2000)
+	h.assertAmount("spread cost", preview.SpreadCost/preview.QuoteConversionRate, -0.0002*2000/2)
+
+	half, err := account.PreviewClose(trade.ID(), 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.assertAmount("partial net profit", half.NetProfit, preview.NetProfit/4)
+	h.assertAmount("partial fees", half.Fees, preview.Fees/4)
+	h.assertAmount("partial realized profit", half.RealizedProfit, preview.RealizedProfit/4)
+
+	if _, err := account.PreviewClose(trade.ID(), 3000); !errors.Is(err, gotrader.ErrInvalidQuantity) {
+		t.Errorf("expected more units than the trade to be invalid, got %v", err)
+	}
+
+	if _, err := account.PreviewClose("unknown", 0); !errors.Is(err, gotrader.ErrTradeNotFound) {
+		t.Errorf("expected the trade not found, got %v", err)
+	}
+
+	if account.Instrument("EUR_USD").TradesNumber() != 1 {
+		t.Error("expected the previews not to close the trade")
+	}
+}
+
+func TestHarness_CurrencyExposures(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "EUR_GBP", BaseCurrency: "EUR", QuoteCurrency: "GBP", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0999, 1.1001)
+	broker.Quote("EUR_GBP", 0.8499, 0.8501)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD", "EUR_GBP"}))
+
+	for _, order := range []*gotrader.Order{
+		{Type: gotrader.MarketOrder, Instrument: "EUR_USD", Side: gotrader.Long, Units: 2000, Tag: "trend"},
+		{Type: gotrader.MarketOrder, Instrument: "EUR_GBP", Side: gotrader.Short, Units: 1000, Tag: "hedge"},
+	} {
+		if _, err := strategy.engine.SubmitOrder(order); err != nil {
+			t.Fatal(err)
+		}
+		h.Settle()
+	}
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Tick("EUR_GBP", 0.8499, 0.8501)
+	h.Settle()
+
+	report := h.Account().CurrencyExposures()
+	if report.HomeCurrency != "EUR" || len(report.Currencies) != 3 || report.Currencies[0].Currency != "EUR" {
+		t.Fatalf("expected the exposures to EUR, GBP and USD, got %+v", report)
+	}
+
+	eur, gbp, usd := report.Currency("EUR"), report.Currency("GBP"), report.Currency("USD")
+
+	h.assertAmount("long EUR", eur.Long, 2000)
+	h.assertAmount("short EUR", eur.Short, 1000)
+	h.assertAmount("EUR notional", eur.Notional, 1000)
+	h.assertAmount("net GBP", gbp.Net, 850)
+	h.assertAmount("GBP notional", gbp.Notional, 1000)
+	h.assertAmount("net USD", usd.Net, -2200)
+	h.assertAmount("USD notional", usd.Notional, -2000)
+	h.assertAmount("gross notional", report.GrossNotional, 3000)
+
+	trend := h.Account().View().CurrencyExposures(func(trade gotrader.TradeState) bool { return trade.Tag == "trend" })
+	if trend.Currency("GBP") != nil {
+		t.Errorf("expected the trend trades not to be exposed to GBP, got %+v", trend.Currencies)
+	}
+	h.assertAmount("trend EUR", trend.Currency("EUR").Net, 2000)
+}
+
+func TestHarness_OrderSweeper(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.OrderSweeper(gotrader.SweepPolicy{Interval: time.Minute, KeepAlive: time.Hour})) // session time
+
+	var mutex sync.Mutex
+	var expired []gotrader.OrderExpired
+
+	subscription := h.Account().Events().Subscribe(func(event gotrader.Event) {
+		mutex.Lock()
+		expired = append(expired, event.(gotrader.OrderExpired))
+		mutex.Unlock()
+	}, 0, gotrader.OrderExpiredEvent)
+	defer subscription.Unsubscribe()
+
+	expiries := func() []gotrader.OrderExpired {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]gotrader.OrderExpired(nil), expired...)
+	}
+
+	submit := func(order *gotrader.Order) string {
+		id, err := strategy.engine.SubmitOrder(order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	resting := submit(&gotrader.Order{Type: gotrader.LimitOrder, Instrument: "EUR_USD", Side: gotrader.Long,
+		Units: 1000, Price: 1.0900})
+	dated := submit(&gotrader.Order{Type: gotrader.LimitOrder, Instrument: "EUR_USD", Side: gotrader.Long,
+		Units: 1000, Price: 1.0900, TimeInForce: gotrader.GoodTillDate, Expiry: h.Clock.Now().Add(90 * time.Minute)})
+	dropped := submit(&gotrader.Order{Type: gotrader.LimitOrder, Instrument: "EUR_USD", Side: gotrader.Long,
+		Units: 1000, Price: 1.0900})
+
+	if err := broker.CancelOrder("", dropped); err != nil { // dropped by the broker, e.g. on a maintenance window
+		t.Fatal(err)
+	}
+
+	h.Advance(61 * time.Minute)
+
+	h.waitFor("the lapse of the dropped order", func() bool { return len(expiries()) == 1 })
+
+	if e := expiries()[0]; e.Order.ID != dropped || !e.Lapsed {
+		t.Errorf("expected the dropped order to lapse, got %+v", e)
+	}
+
+	kept := make(map[string]bool)
+	for _, r := range broker.Requests() {
+		if r.Type == ModifyOrderRequest {
+			kept[r.OrderID] = true
+		}
+	}
+	if !kept[resting] || !kept[dated] {
+		t.Errorf("expected the resting orders to be kept alive, got %v", kept)
+	}
+
+	h.Advance(30 * time.Minute)
+
+	h.waitFor("the expiry of the dated order", func() bool { return len(expiries()) == 2 })
+
+	if e := expiries()[1]; e.Order.ID != dated || e.Lapsed {
+		t.Errorf("expected the dated order to expire, got %+v", e)
+	}
+
+	if pending := strategy.engine.PendingOrders("EUR_USD"); len(pending) != 1 || pending[0].ID != resting {
+		t.Errorf("expected only the resting order pending, got %v", pending)
+	}
+
+	if orders, _ := broker.GetPendingOrders(""); len(orders) != 1 || orders[0].ID != resting {
+		t.Errorf("expected the dated order cancelled at the broker, got %v", orders)
+	}
+}
+
+func TestHarness_ManagedFees(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	strategy := &passive{}
+	h := New(t, strategy, broker, gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.ManagedFees(gotrader.FeePolicy{
+			ManagementRate:  0.0365,
+			PerformanceRate: 0.2,
+			Schedule:        gotrader.DailySession{Location: time.UTC, Open: 12 * time.Hour, Close: 12 * time.Hour},
+		}))
+
+	account := h.Account()
+	start := h.Clock.Now()
+
+	if fees := account.FeeAccruals(); fees.HighWaterMark != 10000 || !fees.NextAccrual.Equal(start.Add(12*time.Hour)) {
+		t.Fatalf("expected the high-water mark of the opening equity, got %+v", fees)
+	}
+
+	h.Advance(12 * time.Hour)
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+	h.Settle()
+
+	fees := account.FeeAccruals()
+	h.assertAmount("management fee of half a day", fees.ManagementFees, 0.5)
+	h.assertAmount("performance fee below the mark", fees.PerformanceFees, 0)
+	h.AssertBalance(9999.5)
+
+	h.Transfer(1000) // raises the mark, not charged as performance
+
+	if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+		t.Fatal(err)
+	}
+	h.Settle()
+	h.ChargeSwap(account.Instrument("EUR_USD").TradeByOrder(0).ID(), 100)
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+	h.Settle()
+
+	equity := account.Equity()
+
+	h.Advance(24 * time.Hour)
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+	h.Settle()
+
+	management := equity * 0.0001
+	performance := 0.2 * (equity - management - 11000)
+
+	fees = account.FeeAccruals()
+	h.assertAmount("management fees", fees.ManagementFees, 0.5+management)
+	h.assertAmount("performance fee", fees.PerformanceFees, performance)
+	h.assertAmount("high-water mark", fees.HighWaterMark, equity-management-performance)
+	h.assertAmount("gross equity", fees.GrossEquity, account.Equity()+fees.ManagementFees+fees.PerformanceFees)
+
+	transactions := account.Ledger().Transactions()
+	if n := len(transactions); transactions[n-2].Type != gotrader.ManagementFeeTransaction ||
+		transactions[n-1].Type != gotrader.PerformanceFeeTransaction {
+		t.Errorf("expected the fees recorded in the ledger, got %v and %v", transactions[n-2], transactions[n-1])
+	}
+}
+
+func TestHarness_ManagedFeesRestore(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	policy := gotrader.ManagedFees(gotrader.FeePolicy{
+		ManagementRate:  0.0365,
+		PerformanceRate: 0.2,
+		Schedule:        gotrader.DailySession{Location: time.UTC, Open: 12 * time.Hour, Close: 12 * time.Hour},
+	})
+
+	wal, err := gotrader.OpenWAL(t.TempDir()+"/account.wal", gotrader.NoSync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+	h := New(t, &passive{}, broker, gotrader.Instruments([]string{"EUR_USD"}), policy, gotrader.WriteAheadLog(wal))
+
+	snapshot := h.Account().Snapshot()
+
+	h.Advance(12 * time.Hour)
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+	h.Settle()
+
+	accrued := h.Account().FeeAccruals()
+	h.assertAmount("management fee of half a day", accrued.ManagementFees, 0.5)
+
+	t.Run("the snapshot keeps the high-water mark", func(t *testing.T) {
+
+		broker := NewBroker(instruments, Balance(9999.5), Leverage(30), Currency("EUR"))
+		broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+		r := New(t, &passive{}, broker, gotrader.Instruments([]string{"EUR_USD"}), policy,
+			gotrader.Restore(h.Account().Snapshot()))
+
+		if fees := r.Account().FeeAccruals(); fees.HighWaterMark != 10000 || fees.ManagementFees != 0.5 ||
+			!fees.NextAccrual.Equal(accrued.NextAccrual) {
+			t.Errorf("expected the accruals of the snapshot, got %+v", fees)
+		}
+	})
+
+	t.Run("the write-ahead log replays the high-water mark", func(t *testing.T) {
+
+		broker := NewBroker(instruments, Balance(9999.5), Leverage(30), Currency("EUR"))
+		broker.Quote("EUR_USD", 1.0990, 1.0992)
+
+		r := New(t, &passive{}, broker, gotrader.Instruments([]string{"EUR_USD"}), policy,
+			gotrader.Restore(snapshot), gotrader.WriteAheadLog(wal))
+
+		if fees := r.Account().FeeAccruals(); fees.HighWaterMark != 10000 || fees.ManagementFees != 0.5 ||
+			!fees.NextAccrual.Equal(accrued.NextAccrual) {
+			t.Errorf("expected the accruals of the write-ahead log, got %+v", fees)
+		}
+	})
+}
+
+func TestHarness_ManagedFeesOpenTrades(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := NewBroker(instruments, Balance(10000), Leverage(30), Currency("EUR"))
+	broker.Quote("EUR_USD", 1.0990, 1.0992)
+	broker.OpenTrade(gotrader.TradeDetails{
+		ID: "1", Instrument: instruments[0], Side: gotrader.Long, Units: 10000, OpenPrice: 1.0890,
+		OpenTime: broker.clock.Now().Add(-time.Hour),
+	})
+
+	h := New(t, &passive{}, broker, gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.ManagedFees(gotrader.FeePolicy{
+			PerformanceRate: 0.2,
+			Schedule:        gotrader.DailySession{Location: time.UTC, Open: 12 * time.Hour, Close: 12 * time.Hour},
+		}))
+
+	account := h.Account()
+	equity := account.Equity()
+
+	if equity <= 10000 {
+		t.Fatalf("expected the open trade in profit, got an equity of %f", equity)
+	}
+
+	h.assertAmount("high-water mark of the open trade", account.FeeAccruals().HighWaterMark, equity)
+
+	h.Advance(12 * time.Hour)
+	h.Tick("EUR_USD", 1.0990, 1.0992)
+	h.Settle()
+
+	h.assertAmount("performance fee of the opening profit", account.FeeAccruals().PerformanceFees, 0)
+	h.AssertBalance(10000)
+}
+
+func TestHarness_Compliance(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 100, PipLocation: -4},
+		{Name: "USD_JPY", BaseCurrency: "USD", QuoteCurrency: "JPY", Leverage: 100, PipLocation: -2},
+	}
+
+	session := func(t *testing.T, opts ...gotrader.Option) (*Harness, *passive) {
+
+		broker := NewBroker(instruments, Balance(1000), Leverage(100), Currency("EUR"))
+		broker.Quote("EUR_USD", 1.0999, 1.1001)
+		broker.Quote("USD_JPY", 149.99, 150.01)
+
+		strategy := &passive{}
+		opts = append(opts, gotrader.Instruments([]string{"EUR_USD", "USD_JPY"}))
+
+		return New(t, strategy, broker, opts...), strategy
+	}
+
+	violation := func(t *testing.T, err error, rule gotrader.ComplianceRule) {
+
+		t.Helper()
+
+		var compliance *gotrader.ComplianceError
+		if !errors.Is(err, gotrader.ErrComplianceViolation) || !errors.As(err, &compliance) || compliance.Rule != rule {
+			t.Fatalf("expected a %s violation, got %v", rule, err)
+		}
+	}
+
+	t.Run("the leverage is capped by asset class", func(t *testing.T) {
+
+		h, strategy := session(t, gotrader.Compliance(gotrader.ESMA()))
+
+		if err := strategy.engine.Buy("EUR_USD", 20000); err != nil { // 667 of margin at 30:1
+			t.Fatalf("expected the open within the capped leverage, got %v", err)
+		}
+		h.Settle()
+
+		violation(t, strategy.engine.Buy("EUR_USD", 15000), gotrader.LeverageRule)
+		violation(t, strategy.engine.Sell("USD_JPY", 15000), gotrader.LeverageRule) // the margin of both
+		h.AssertOpenTrades("EUR_USD", 1)
+
+		if err := strategy.engine.Sell("USD_JPY", 5000); err != nil {
+			t.Errorf("expected the open within the margin left, got %v", err)
+		}
+	})
+
+	t.Run("the sides are margined by the hedge type", func(t *testing.T) {
+
+		h, strategy := session(t, gotrader.Compliance(gotrader.ESMA()),
+			gotrader.InstrumentHedge("EUR_USD", gotrader.NoHedge))
+
+		if err := strategy.engine.Buy("EUR_USD", 20000); err != nil {
+			t.Fatalf("expected the open within the capped leverage, got %v", err)
+		}
+		h.Settle()
+
+		violation(t, strategy.engine.Sell("EUR_USD", 15000), gotrader.LeverageRule) // the margin of both sides
+
+		if err := strategy.engine.Sell("EUR_USD", 5000); err != nil {
+			t.Errorf("expected the open within the margin left, got %v", err)
+		}
+	})
+
+	t.Run("the instrument classes override the classification", func(t *testing.T) {
+
+		rules := gotrader.ESMA()
+		rules.Classes = map[string]gotrader.AssetClass{"EUR_USD": gotrader.Crypto}
+
+		h, strategy := session(t, gotrader.Compliance(rules))
+
+		if err := strategy.engine.Buy("EUR_USD", 1000); err != nil { // 500 of margin at 2:1
+			t.Fatalf("expected the open within the capped leverage, got %v", err)
+		}
+		h.Settle()
+
+		violation(t, strategy.engine.Buy("EUR_USD", 1500), gotrader.LeverageRule)
+	})
+
+	t.Run("the trades are closed first in first out", func(t *testing.T) {
+
+		h, strategy := session(t, gotrader.Compliance(gotrader.NFA()))
+
+		for i := 0; i < 2; i++ {
+			if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+				t.Fatal(err)
+			}
+			h.Settle()
+		}
+
+		inst := h.Account().Instrument("EUR_USD")
+		oldest, newest := inst.TradeByOrder(0).ID(), inst.TradeByOrder(1).ID()
+
+		violation(t, strategy.engine.CloseTrade("EUR_USD", newest), gotrader.FIFORule)
+		h.AssertOpenTrades("EUR_USD", 2)
+
+		if err := strategy.engine.CloseTrade("EUR_USD", oldest); err != nil {
+			t.Fatalf("expected the close of the oldest trade, got %v", err)
+		}
+		h.Settle()
+
+		if err := strategy.engine.CloseTrade("EUR_USD", newest); err != nil {
+			t.Errorf("expected the close of the trade left, got %v", err)
+		}
+		h.Settle()
+		h.AssertOpenTrades("EUR_USD", 0)
+	})
+
+	t.Run("the positions are not hedged", func(t *testing.T) {
+
+		h, strategy := session(t, gotrader.Compliance(gotrader.NFA()),
+			gotrader.InstrumentHedge("USD_JPY", gotrader.NoHedge))
+
+		for _, instrument := range []string{"EUR_USD", "USD_JPY"} {
+			if err := strategy.engine.Buy(instrument, 1000); err != nil {
+				t.Fatal(err)
+			}
+		}
+		h.Settle()
+
+		violation(t, strategy.engine.Sell("EUR_USD", 1000), gotrader.HedgingRule)
+
+		if err := strategy.engine.Buy("EUR_USD", 1000); err != nil {
+			t.Errorf("expected the open on the side of the position, got %v", err)
+		}
+
+		violation(t, strategy.engine.Sell("USD_JPY", 1000), gotrader.HedgingRule) // not netted by the engine
+		h.Settle()
+
+		h.AssertOpenTrades("EUR_USD", 2)
+		h.AssertOpenTrades("USD_JPY", 1)
+	})
+}
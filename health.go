@@ -0,0 +1,244 @@
+package gotrader
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The components of the session monitored by the Health of the account.
+const (
+	FeedComponent   = "feed"   // the price stream, beating on every tick
+	BrokerComponent = "broker" // the broker session, beating on the heartbeats of a Heartbeater client
+)
+
+// HealthState is the state of a monitored component, by the time since its last heartbeat.
+type HealthState int
+
+const (
+	// Healthy beat within the degraded delay
+	Healthy HealthState = iota
+
+	// Degraded is silent for the degraded delay
+	Degraded
+
+	// Unhealthy is silent for the unhealthy delay, the opens are rejected with ErrUnhealthy
+	Unhealthy
+)
+
+func (s HealthState) String() string {
+
+	names := [...]string{"HEALTHY", "DEGRADED", "UNHEALTHY"}
+
+	return names[s]
+}
+
+// HealthPolicy defines the silences of the components degrading their health, Unhealthy defaults to twice the
+// Degraded one.
+type HealthPolicy struct {
+	Degraded  time.Duration
+	Unhealthy time.Duration
+}
+
+// HealthCheck is the functional option to monitor the health of the feed and the broker session of the live
+// engine, see Health. The backtests are not monitored.
+func HealthCheck(policy HealthPolicy) Option {
+	return func(p *sessionParameters) {
+		if policy.Unhealthy <= 0 {
+			policy.Unhealthy = 2 * policy.Degraded
+		}
+		p.health = &policy
+	}
+}
+
+// maxHealthInterval bounds the interval of the health checks.
+const maxHealthInterval = 250 * time.Millisecond
+
+// Heartbeater is implemented by the clients reporting the heartbeats of their sessions, e.g. the FIX heartbeats,
+// beating the component, BrokerComponent for the broker session.
+type Heartbeater interface {
+	SubscribeHeartbeats(accountID string, handler HeartbeatHandler) error
+}
+
+// HeartbeatHandler is the callback of the heartbeats of a component.
+type HeartbeatHandler func(component string, t time.Time)
+
+// ComponentHealth is the health of a monitored component.
+type ComponentHealth struct {
+	Component string
+	State     HealthState
+	LastBeat  time.Time
+	Since     time.Time // of the state
+}
+
+// HealthChanged is published when the state of a monitored component changes.
+type HealthChanged struct {
+	Time      time.Time
+	Component string
+	From      HealthState
+	To        HealthState
+	LastBeat  time.Time
+}
+
+/*
+Health monitors the heartbeats of the feed and the broker session of the live engine with the HealthCheck option:
+a component silent for the Degraded delay of the policy is degraded, and unhealthy after the Unhealthy delay, until
+its next heartbeat. The transitions are published as HealthChanged events, and the engine rejects the opens with
+ErrUnhealthy while a component is unhealthy, the closes are still sent. The components are monitored from their
+first heartbeat, the broker session only with a Heartbeater client.
+*/
+type Health struct {
+	mutex      *sync.RWMutex
+	policy     *HealthPolicy // nil when not monitored
+	components map[string]*ComponentHealth
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// interval returns the interval of the health checks, a quarter of the degraded delay up to maxHealthInterval.
+func (p *HealthPolicy) interval() time.Duration {
+
+	if interval := p.Degraded / 4; interval > 0 && interval < maxHealthInterval {
+		return interval
+	}
+
+	return maxHealthInterval
+}
+
+func newHealth() *Health {
+	return &Health{
+		mutex:      &sync.RWMutex{},
+		components: make(map[string]*ComponentHealth),
+	}
+}
+
+// beat records a heartbeat of the component at t, publishing its recovery.
+func (h *Health) beat(component string, t time.Time, events *EventBus) {
+
+	if h.policy == nil {
+		return
+	}
+
+	h.mutex.Lock()
+
+	c, exist := h.components[component]
+	if !exist {
+		c = &ComponentHealth{Component: component, Since: t}
+		h.components[component] = c
+	}
+
+	from := c.State
+	c.LastBeat = t
+
+	if from == Healthy {
+		h.mutex.Unlock()
+		return
+	}
+
+	c.State, c.Since = Healthy, t
+	h.mutex.Unlock()
+
+	events.publish(HealthChanged{Time: t, Component: component, From: from, To: Healthy, LastBeat: t})
+}
+
+// check degrades the components silent at now, publishing their transitions.
+func (h *Health) check(now time.Time, events *EventBus) {
+
+	if h.policy == nil {
+		return
+	}
+
+	var changes []HealthChanged
+
+	h.mutex.Lock()
+
+	for _, c := range h.components {
+
+		state := Healthy
+		switch silence := now.Sub(c.LastBeat); {
+		case silence >= h.policy.Unhealthy:
+			state = Unhealthy
+		case silence >= h.policy.Degraded:
+			state = Degraded
+		}
+
+		if state != c.State {
+			changes = append(changes, HealthChanged{Time: now, Component: c.Component, From: c.State, To: state,
+				LastBeat: c.LastBeat})
+			c.State, c.Since = state, now
+		}
+	}
+
+	h.mutex.Unlock()
+
+	for _, change := range changes {
+		events.publish(change)
+	}
+}
+
+// checkOpen returns the error of an open while a component is unhealthy.
+func (h *Health) checkOpen() error {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, c := range h.components {
+		if c.State == Unhealthy {
+			return fmt.Errorf("%s: %w since %s", c.Component, ErrUnhealthy, c.Since.Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Status returns the health of the monitored components by name.
+func (h *Health) Status() []ComponentHealth {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	status := make([]ComponentHealth, 0, len(h.components))
+	for _, c := range h.components {
+		status = append(status, *c)
+	}
+
+	sort.Slice(status, func(i, j int) bool { return status[i].Component < status[j].Component })
+
+	return status
+}
+
+// Component returns the health of a monitored component.
+func (h *Health) Component(component string) (ComponentHealth, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	c, exist := h.components[component]
+	if !exist {
+		return ComponentHealth{}, false
+	}
+
+	return *c, true
+}
+
+// Healthy returns true when every monitored component is healthy.
+func (h *Health) Healthy() bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, c := range h.components {
+		if c.State != Healthy {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,173 @@
+package gotrader
+
+import (
+	"fmt"
+	"time"
+)
+
+func (h Hedge) String() string {
+	switch h {
+	case FullHedge:
+		return "FULL_HEDGE"
+	case NoHedge:
+		return "NO_HEDGE"
+	case HalfHedge:
+		return "HALF_HEDGE"
+	}
+
+	return "UNKNOWN"
+}
+
+// HedgeValidator is implemented by the clients whose venue restricts the hedge types, e.g. the ones netting the
+// positions, so the sessions configured with an unsupported hedge type fail to start.
+type HedgeValidator interface {
+	SupportsHedge(instrument InstrumentDetails, hedge Hedge) bool
+}
+
+/*
+HedgeChanged is published when the hedge type of an instrument is changed with Engine.SetHedge.
+
+Switching to NoHedge nets the long and short positions: the trades of the smaller position are closed, with
+the oldest trades of the larger one covering its units, and the units closed in excess are opened again with a
+market order of the larger side. The closes and the order are sent after the event, their fills are notified as
+usual. The margin used is the one of the instrument before and after the change of the hedge type.
+*/
+type HedgeChanged struct {
+	Time         time.Time
+	Instrument   string
+	From         Hedge
+	To           Hedge
+	ClosedTrades []string
+	ReopenSide   Side
+	ReopenUnits  int32
+	MarginBefore float64
+	MarginAfter  float64
+}
+
+// hedgeMigration is the netting of the positions of an instrument switched to NoHedge.
+type hedgeMigration struct {
+	closes []string
+	side   Side
+	units  int32
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// hedge returns the hedge type of an instrument, the fallback when it is not defined with InstrumentHedge.
+func (p *sessionParameters) hedge(instrument string, fallback Hedge) Hedge {
+
+	if hedge, exist := p.instrumentHedges[instrument]; exist {
+		return hedge
+	}
+
+	return fallback
+}
+
+// checkHedges checks the hedge types of the instruments are supported by the client.
+func checkHedges(client BrokerClient, account *Account, details map[string]InstrumentDetails) error {
+
+	validator, ok := client.(HedgeValidator)
+	if !ok {
+		return nil
+	}
+
+	for _, inst := range account.list() {
+
+		if !validator.SupportsHedge(details[inst.name], inst.hedgeType) {
+			return fmt.Errorf("%s: hedge type %s is not supported by the broker", inst.name, inst.hedgeType)
+		}
+	}
+
+	return nil
+}
+
+// setHedge changes the hedge type and recalculates the margin used, returning the margin before and after.
+func (i *Instrument) setHedge(hedge Hedge) (float64, float64) {
+
+	i.acquire()
+	defer i.lock.Unlock()
+
+	before := i.marginUsed.Float64()
+
+	i.hedgeType = hedge
+	i.touch()
+	i.margin()
+
+	return before, i.marginUsed.Float64()
+}
+
+// netting returns the trades to close and the units to open again to net the long and short positions.
+func (i *Instrument) netting() hedgeMigration {
+
+	long, short := i.longPosition.list(), i.shortPosition.list()
+
+	smaller, larger, side := short, long, Long
+	if i.shortPosition.Units() > i.longPosition.Units() {
+		smaller, larger, side = long, short, Short
+	}
+
+	migration := hedgeMigration{side: side}
+
+	var offset int32
+	for _, trade := range smaller {
+		migration.closes = append(migration.closes, trade.id)
+		offset += trade.units
+	}
+
+	for _, trade := range larger {
+
+		if offset <= 0 {
+			break
+		}
+
+		migration.closes = append(migration.closes, trade.id)
+		offset -= trade.units
+	}
+
+	migration.units = -offset
+
+	return migration
+}
+
+// migrateHedge changes the hedge type of an instrument, returning the netting to execute when switching to
+// NoHedge from a hedged type.
+func migrateHedge(account *Account, instrument string, hedge Hedge, now time.Time) (HedgeChanged, hedgeMigration, error) {
+
+	inst, exist := account.instruments[instrument]
+	if !exist {
+		return HedgeChanged{}, hedgeMigration{}, fmt.Errorf("%s: %w", instrument, ErrInstrumentNotTraded)
+	}
+
+	event := HedgeChanged{Time: now, Instrument: instrument, From: inst.HedgeType(), To: hedge}
+
+	var migration hedgeMigration
+	if hedge == NoHedge && event.From != NoHedge &&
+		inst.longPosition.Units() > 0 && inst.shortPosition.Units() > 0 {
+		migration = inst.netting()
+	}
+
+	event.ClosedTrades = migration.closes
+	event.ReopenSide = migration.side
+	event.ReopenUnits = migration.units
+	event.MarginBefore, event.MarginAfter = inst.setHedge(hedge)
+
+	return event, migration, nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// HedgeType returns the hedge type of the instrument, how the margin of its long and short positions is offset.
+func (i *Instrument) HedgeType() Hedge {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.hedgeType
+}
@@ -0,0 +1,125 @@
+package gotrader
+
+import (
+	"errors"
+	"time"
+)
+
+// HistoricalState is the state of an account as of a point in time, rebuilt from the logs of its session by StateAt.
+type HistoricalState struct {
+	Time        time.Time
+	Account     *Account // balance, open trades and ledger
+	Orders      []*Order // pending, by submission, nil without audit log
+	WALSequence uint64   // of the last entry replayed
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// pendingOrdersAt returns the orders of the audit log pending at t: submitted or amended, and not filled,
+// cancelled, rejected or expired by then.
+func pendingOrdersAt(audit *AuditLog, t time.Time) []*Order {
+
+	orders := make(map[string]*Order)
+	ids := make([]string, 0)
+
+	for _, r := range audit.Records(AuditQuery{}) {
+
+		if r.Time.After(t) {
+			break
+		}
+
+		switch r.Event {
+		case AuditSubmitted, AuditAmended:
+			if r.Type == MarketOrder {
+				continue
+			}
+			if _, exist := orders[r.OrderID]; !exist {
+				ids = append(ids, r.OrderID)
+			}
+			orders[r.OrderID] = &Order{ID: r.OrderID, Type: r.Type, Instrument: r.Instrument, Side: r.Side,
+				Units: r.Units, Price: r.Price, StopLoss: r.StopLoss, TakeProfit: r.TakeProfit, Tag: r.Tag,
+				CreateTime: r.Time}
+		case AuditFilled, AuditCancelled, AuditRejected:
+			delete(orders, r.OrderID)
+		}
+	}
+
+	pending := make([]*Order, 0, len(orders))
+	for _, id := range ids {
+		if order, exist := orders[id]; exist {
+			pending = append(pending, order)
+		}
+	}
+
+	return pending
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+/*
+StateAt rebuilds what a session believed at t, e.g. to investigate a decision taken at 14:32:05: the account of a
+snapshot taken before t, usually the one restored when the session started, is replayed with the write-ahead log
+entries up to t, included, and the pending orders are the ones of the audit log, optional, submitted and not yet
+filled, cancelled or rejected by then. The log must still hold the entries after the snapshot, it must not have
+been compacted past it.
+
+The logs do not record the prices, so the instruments keep the prices of the snapshot, and the unrealized profits
+and the margin used are valued at them.
+*/
+func StateAt(snapshot *Snapshot, wal *WAL, audit *AuditLog, t time.Time, logger Logger) (*HistoricalState, error) {
+
+	if snapshot == nil || wal == nil {
+		return nil, errors.New("state reconstruction requires a snapshot and a write-ahead log")
+	}
+
+	if snapshot.Time.After(t) {
+		return nil, errors.New("the snapshot was taken after " + t.Format(time.RFC3339Nano))
+	}
+
+	entries, err := wal.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &HistoricalState{Time: t, WALSequence: snapshot.WALSequence}
+
+	for _, entry := range entries {
+
+		if entry.Sequence <= snapshot.WALSequence {
+			continue
+		}
+
+		if entry.Sequence > state.WALSequence+1 {
+			return nil, errors.New("the write-ahead log was compacted past the snapshot")
+		}
+
+		if entry.Time.After(t) {
+			break
+		}
+
+		state.WALSequence = entry.Sequence
+	}
+
+	if logger == nil {
+		logger = DefaultLogger()
+	}
+
+	state.Account = RestoreAccount(snapshot, logger)
+	state.Account.replay(entries, snapshot.WALSequence, t, false)
+	state.Account.time = t
+	state.Account.calculate()
+
+	if audit != nil {
+		state.Orders = pendingOrdersAt(audit, t)
+	}
+
+	return state, nil
+}
@@ -0,0 +1,168 @@
+package gotrader
+
+import (
+	"time"
+)
+
+// TradeUpdate is the change of an open trade published by TradeUpdated.
+type TradeUpdate int
+
+const (
+	FinancingUpdate       TradeUpdate = iota // charged a financing or swap
+	CorporateActionUpdate                    // adjusted by a stock split or renamed by a ticker change
+)
+
+func (u TradeUpdate) String() string {
+	switch u {
+	case FinancingUpdate:
+		return "FINANCING"
+	case CorporateActionUpdate:
+		return "CORPORATE_ACTION"
+	}
+
+	return "UNKNOWN"
+}
+
+// TradeUpdated is published when an open trade changes without being opened or closed.
+type TradeUpdated struct {
+	Time   time.Time
+	Trade  *Trade
+	Update TradeUpdate
+}
+
+/*
+TradeHooks are the callbacks of the trade lifecycle registered with EventBus.HookTrades, so the journaling or the
+notifications don't poll the trades of the instruments. The nil callbacks are skipped.
+
+The hooks are of the account scope, or of the instruments listed. They are called on a subscription of the
+event bus, in order and without ever blocking the engine, or synchronously by the engine goroutine publishing the
+event when Synchronous is set: the trade is then seen as it is when the event is published, and a slow hook
+delays the session.
+*/
+type TradeHooks struct {
+	Instruments []string // of the trades hooked, every instrument when empty
+	Opened      func(event TradeOpened)
+	Updated     func(event TradeUpdated)
+	Closed      func(event TradeClosed)
+	CloseFailed func(event OrderRejected) // of the closes the broker failed, the trade stays open
+	Synchronous bool
+	Buffer      int // of the subscription, see EventBus.Subscribe
+}
+
+// TradeHook is a registration of TradeHooks.
+type TradeHook struct {
+	bus          *EventBus
+	hooks        TradeHooks
+	instruments  map[string]bool
+	subscription *Subscription
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// call calls the hook of the event, if it is a trade event of the instruments hooked.
+func (h *TradeHook) call(event Event) {
+
+	switch e := event.(type) {
+	case TradeOpened:
+		if h.hooks.Opened != nil && h.hooked(e.Trade.InstrumentName()) {
+			h.hooks.Opened(e)
+		}
+	case TradeUpdated:
+		if h.hooks.Updated != nil && h.hooked(e.Trade.InstrumentName()) {
+			h.hooks.Updated(e)
+		}
+	case TradeClosed:
+		if h.hooks.Closed != nil && h.hooked(e.Fill.Instrument.Name) {
+			h.hooks.Closed(e)
+		}
+	case OrderRejected:
+		if h.hooks.CloseFailed != nil && e.Fill.TradeID != "" && h.hooked(e.Fill.Instrument.Name) {
+			h.hooks.CloseFailed(e)
+		}
+	}
+}
+
+func (h *TradeHook) hooked(instrument string) bool {
+	return len(h.instruments) == 0 || h.instruments[instrument]
+}
+
+// callHooks calls the synchronous trade hooks, outside the bus lock so they can remove themselves.
+func (b *EventBus) callHooks(event Event) {
+
+	switch event.(type) {
+	case TradeOpened, TradeUpdated, TradeClosed, OrderRejected:
+	default:
+		return
+	}
+
+	b.mutex.RLock()
+	hooks := b.hooks
+	b.mutex.RUnlock()
+
+	for _, h := range hooks {
+		h.call(event)
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// HookTrades registers the trade lifecycle callbacks, see TradeHooks.
+func (b *EventBus) HookTrades(hooks TradeHooks) *TradeHook {
+
+	h := &TradeHook{bus: b, hooks: hooks, instruments: make(map[string]bool)}
+	for _, instrument := range hooks.Instruments {
+		h.instruments[instrument] = true
+	}
+
+	if !hooks.Synchronous {
+		h.subscription = b.Subscribe(h.call, hooks.Buffer, TradeOpenedEvent, TradeUpdatedEvent, TradeClosedEvent,
+			OrderRejectedEvent)
+		return h
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.hooks = append(b.hooks[:len(b.hooks):len(b.hooks)], h) // copied on write, called without the lock
+
+	return h
+}
+
+// Remove unregisters the hooks, the events of a subscription already buffered are still delivered.
+func (h *TradeHook) Remove() {
+
+	if h.subscription != nil {
+		h.subscription.Unsubscribe()
+		return
+	}
+
+	h.bus.mutex.Lock()
+	defer h.bus.mutex.Unlock()
+
+	hooks := make([]*TradeHook, 0, len(h.bus.hooks))
+	for _, registered := range h.bus.hooks {
+		if registered != h {
+			hooks = append(hooks, registered)
+		}
+	}
+
+	h.bus.hooks = hooks
+}
+
+// Dropped returns the number of events dropped by the subscription of the hooks, see Subscription.Dropped.
+func (h *TradeHook) Dropped() int64 {
+
+	if h.subscription == nil {
+		return 0
+	}
+
+	return h.subscription.Dropped()
+}
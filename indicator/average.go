@@ -0,0 +1,178 @@
+package indicator
+
+import (
+	"github.com/luismcruz/gotrader"
+)
+
+// SMA is the simple moving average of the close prices over a period.
+type SMA struct {
+	*Series
+	noTicks
+	period int
+	window *window
+	count  int
+	sum    float64
+}
+
+// NewSMA is the SMA constructor, a period below 1 is clamped to 1.
+func NewSMA(period int) *SMA {
+	period = validPeriod(period)
+	return &SMA{Series: newSeries(), period: period, window: newWindow(period)}
+}
+
+// WarmupPeriod implements Indicator, the period.
+func (a *SMA) WarmupPeriod() int {
+	return a.period
+}
+
+// OnCandle implements Indicator.
+func (a *SMA) OnCandle(candle *gotrader.Candle) {
+	a.Update(candle.Close)
+}
+
+// Update adds a value to the average.
+func (a *SMA) Update(value float64) {
+
+	a.sum += value - a.window.add(value)
+
+	if a.count < a.period {
+		a.count++
+	}
+
+	if a.count == a.period {
+		a.push(a.sum / float64(a.period))
+	}
+}
+
+/*
+EMA is the exponential moving average of the close prices over a period, with a smoothing factor of
+2 / (period + 1). It is seeded with the simple average of the first period values, so it is ready after them.
+*/
+type EMA struct {
+	*Series
+	noTicks
+	period int
+	alpha  float64
+	count  int
+	ema    float64
+}
+
+// NewEMA is the EMA constructor, a period below 1 is clamped to 1.
+func NewEMA(period int) *EMA {
+	period = validPeriod(period)
+	return &EMA{Series: newSeries(), period: period, alpha: 2 / float64(period+1)}
+}
+
+// WarmupPeriod implements Indicator, the period.
+func (a *EMA) WarmupPeriod() int {
+	return a.period
+}
+
+// OnCandle implements Indicator.
+func (a *EMA) OnCandle(candle *gotrader.Candle) {
+	a.Update(candle.Close)
+}
+
+// Update adds a value to the average.
+func (a *EMA) Update(value float64) {
+
+	if a.count < a.period {
+		a.count++
+		a.ema += (value - a.ema) / float64(a.count) // the simple average of the seed values
+
+		if a.count == a.period {
+			a.push(a.ema)
+		}
+
+		return
+	}
+
+	a.ema += a.alpha * (value - a.ema)
+	a.push(a.ema)
+}
+
+// WMA is the linearly weighted moving average of the close prices over a period, the newest weighting period.
+type WMA struct {
+	*Series
+	noTicks
+	period   int
+	window   *window
+	count    int
+	sum      float64
+	weighted float64
+}
+
+// NewWMA is the WMA constructor, a period below 1 is clamped to 1.
+func NewWMA(period int) *WMA {
+	period = validPeriod(period)
+	return &WMA{Series: newSeries(), period: period, window: newWindow(period)}
+}
+
+// WarmupPeriod implements Indicator, the period.
+func (a *WMA) WarmupPeriod() int {
+	return a.period
+}
+
+// OnCandle implements Indicator.
+func (a *WMA) OnCandle(candle *gotrader.Candle) {
+	a.Update(candle.Close)
+}
+
+// Update adds a value to the average. Once the window is full, every weight decreases by one when a value is
+// added, which is subtracting the sum of the window, so the weighted sum is updated in constant time.
+func (a *WMA) Update(value float64) {
+
+	dropped := a.window.add(value)
+
+	if a.count < a.period {
+		a.count++
+		a.weighted += float64(a.count) * value
+		a.sum += value
+	} else {
+		a.weighted += float64(a.period)*value - a.sum
+		a.sum += value - dropped
+	}
+
+	if a.count == a.period {
+		a.push(a.weighted / float64(a.period*(a.period+1)/2))
+	}
+}
+
+// DEMA is the double exponential moving average, 2 * EMA - EMA(EMA), ready after 2 * period - 1 values.
+type DEMA struct {
+	*Series
+	noTicks
+	ema    *EMA
+	emaEMA *EMA
+}
+
+// NewDEMA is the DEMA constructor.
+func NewDEMA(period int) *DEMA {
+	return &DEMA{Series: newSeries(), ema: NewEMA(period), emaEMA: NewEMA(period)}
+}
+
+// WarmupPeriod implements Indicator, 2 * period - 1.
+func (a *DEMA) WarmupPeriod() int {
+	return 2*a.ema.period - 1
+}
+
+// OnCandle implements Indicator.
+func (a *DEMA) OnCandle(candle *gotrader.Candle) {
+	a.Update(candle.Close)
+}
+
+// Update adds a value to the average.
+func (a *DEMA) Update(value float64) {
+
+	a.ema.Update(value)
+
+	if !a.ema.Ready() {
+		return
+	}
+
+	a.emaEMA.Update(a.ema.Value())
+
+	if a.emaEMA.Ready() {
+		a.push(2*a.ema.Value() - a.emaEMA.Value())
+	}
+}
@@ -0,0 +1,94 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMovingAverages(t *testing.T) {
+
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	t.Run("SMA averages the last period values", func(t *testing.T) {
+
+		sma := NewSMA(3)
+		for _, v := range values {
+			sma.Update(v)
+		}
+
+		if sma.Value() != 9 || sma.At(1) != 8 || sma.Len() != 8 {
+			t.Errorf("unexpected SMA %v %v %v", sma.Value(), sma.At(1), sma.Len())
+		}
+	})
+
+	t.Run("WMA matches the weighted sum of the window", func(t *testing.T) {
+
+		wma := NewWMA(4)
+		for _, v := range values {
+			wma.Update(v)
+		}
+
+		expected := (7*1 + 8*2 + 9*3 + 10*4) / 10.0
+		if math.Abs(wma.Value()-expected) > 1e-12 {
+			t.Errorf("expected WMA %v, got %v", expected, wma.Value())
+		}
+	})
+
+	t.Run("EMA is seeded with the SMA", func(t *testing.T) {
+
+		ema := NewEMA(3)
+		for _, v := range values[:3] {
+			ema.Update(v)
+		}
+
+		if ema.Value() != 2 {
+			t.Errorf("expected the seed 2, got %v", ema.Value())
+		}
+
+		ema.Update(4)
+		if ema.Value() != 3 {
+			t.Errorf("expected EMA 3, got %v", ema.Value())
+		}
+	})
+
+	t.Run("periods below 1 are clamped to 1", func(t *testing.T) {
+
+		for _, period := range []int{0, -3} {
+
+			averages := []interface {
+				Update(float64)
+				Value() float64
+				WarmupPeriod() int
+			}{NewSMA(period), NewWMA(period), NewEMA(period)}
+
+			for i, average := range averages {
+
+				average.Update(5)
+				average.Update(7)
+
+				if average.WarmupPeriod() != 1 || average.Value() != 7 {
+					t.Errorf("average %d of period %d: expected the last value, got %v", i, period, average.Value())
+				}
+			}
+		}
+	})
+
+	t.Run("DEMA is ready after 2 * period - 1 values", func(t *testing.T) {
+
+		dema := NewDEMA(3)
+		for i, v := range values[:5] {
+			if dema.Update(v); dema.Ready() != (i == 4) {
+				t.Errorf("unexpected readiness after %d values", i+1)
+			}
+		}
+
+		// on a linear series the DEMA removes the EMA lag
+		for _, v := range values[5:] {
+			dema.Update(v)
+		}
+
+		if math.Abs(dema.Value()-10) > 1e-9 {
+			t.Errorf("expected DEMA 10, got %v", dema.Value())
+		}
+	})
+}
@@ -0,0 +1,132 @@
+/*
+Package indicator implements technical indicators of the candles of an instrument. The indicators are updated
+incrementally, in constant time per candle, and keep the history of their last values.
+
+Indicators can be fed by hand or attached to the candle stream of a runner strategy:
+
+	sma := indicator.NewSMA(20)
+	ctx.Attach("EUR_USD", time.Minute, sma)
+	...
+	if sma.Ready() {
+		fmt.Println(sma.Value(), sma.At(1))
+	}
+*/
+package indicator
+
+import (
+	"github.com/luismcruz/gotrader"
+)
+
+// HistorySize is the number of values kept by the indicators created afterwards.
+var HistorySize = 256
+
+/*
+Indicator is computed on the closed candles of an instrument timeframe, and optionally on its ticks. Custom
+indicators implement it to be attached like the built-in ones:
+
+	type Spread struct{ last float64 }
+
+	func (s *Spread) OnCandle(candle *gotrader.Candle) {}
+	func (s *Spread) OnTick(tick *gotrader.Tick)       { s.last = tick.Ask - tick.Bid }
+	func (s *Spread) Value() float64                   { return s.last }
+	func (s *Spread) WarmupPeriod() int                { return 0 }
+
+The value is valid once the indicator has been computed on WarmupPeriod candles, see Pipeline.Ready. A candle
+is delivered before the tick that closes it, as to the strategies.
+*/
+type Indicator interface {
+	OnCandle(candle *gotrader.Candle)
+	OnTick(tick *gotrader.Tick)
+	Value() float64
+	WarmupPeriod() int
+}
+
+// noTicks implements the OnTick of the indicators computed only on the candles.
+type noTicks struct{}
+
+// OnTick implements Indicator, the indicator is only computed on the candles.
+func (noTicks) OnTick(tick *gotrader.Tick) {}
+
+// Series is the history of the values of an indicator, from the newest. Values are only added once the
+// indicator is warmed up.
+type Series struct {
+	values []float64
+	next   int
+	len    int
+}
+
+func newSeries() *Series {
+
+	size := HistorySize
+	if size < 1 {
+		size = 1
+	}
+
+	return &Series{values: make([]float64, size)}
+}
+
+func (s *Series) push(value float64) {
+
+	s.values[s.next] = value
+	s.next = (s.next + 1) % len(s.values)
+
+	if s.len < len(s.values) {
+		s.len++
+	}
+}
+
+// Value returns the current value, 0 before the indicator is ready.
+func (s *Series) Value() float64 {
+	return s.At(0)
+}
+
+// At returns the value n candles ago, 0 when it is not kept in the history.
+func (s *Series) At(n int) float64 {
+
+	if n < 0 || n >= s.len {
+		return 0
+	}
+
+	return s.values[(s.next-1-n+len(s.values))%len(s.values)]
+}
+
+// Len returns the number of values kept in the history.
+func (s *Series) Len() int {
+	return s.len
+}
+
+// Ready returns true once the indicator is warmed up and has a value, after WarmupPeriod values.
+func (s *Series) Ready() bool {
+	return s.len > 0
+}
+
+// window is a ring of the last inputs of an indicator.
+type window struct {
+	values []float64
+	next   int
+	full   bool
+}
+
+func newWindow(period int) *window {
+	return &window{values: make([]float64, validPeriod(period))}
+}
+
+// validPeriod returns the period of an indicator, at least 1: the periods below are clamped to 1.
+func validPeriod(period int) int {
+	return max(period, 1)
+}
+
+// add adds the value and returns the value that left the window, 0 until it is full.
+func (w *window) add(value float64) (dropped float64) {
+
+	dropped = w.values[w.next]
+	w.values[w.next] = value
+	w.next = (w.next + 1) % len(w.values)
+
+	if !w.full {
+		dropped = 0
+		w.full = w.next == 0
+	}
+
+	return dropped
+}
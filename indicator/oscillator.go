@@ -0,0 +1,243 @@
+package indicator
+
+import (
+	"github.com/luismcruz/gotrader"
+)
+
+// RSI is the relative strength index of the close prices, with the Wilder smoothing of the gains and losses.
+// It is ready after period + 1 values.
+type RSI struct {
+	*Series
+	noTicks
+	period int
+	count  int
+	last   float64
+	gain   float64
+	loss   float64
+}
+
+// NewRSI is the RSI constructor.
+func NewRSI(period int) *RSI {
+	return &RSI{Series: newSeries(), period: period}
+}
+
+// WarmupPeriod implements Indicator, period + 1.
+func (r *RSI) WarmupPeriod() int {
+	return r.period + 1
+}
+
+// OnCandle implements Indicator.
+func (r *RSI) OnCandle(candle *gotrader.Candle) {
+	r.Update(candle.Close)
+}
+
+// Update adds a value to the index.
+func (r *RSI) Update(value float64) {
+
+	change := value - r.last
+	r.last = value
+
+	if r.count == 0 {
+		r.count++
+		return
+	}
+
+	gain, loss := 0.0, 0.0
+	if change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+
+	if r.count <= r.period { // the simple average of the first period changes
+		r.gain += (gain - r.gain) / float64(r.count)
+		r.loss += (loss - r.loss) / float64(r.count)
+		r.count++
+
+		if r.count <= r.period {
+			return
+		}
+	} else {
+		r.gain += (gain - r.gain) / float64(r.period)
+		r.loss += (loss - r.loss) / float64(r.period)
+	}
+
+	if r.loss == 0 {
+		r.push(100)
+		return
+	}
+
+	r.push(100 - 100/(1+r.gain/r.loss))
+}
+
+/*
+MACD is the moving average convergence divergence of the close prices, the difference between a fast and a
+slow EMA. Its Signal is an EMA of the MACD line and its Histogram the difference between them, Value and
+Ready are the ones of the MACD line.
+*/
+type MACD struct {
+	*Series
+	noTicks
+	fast      *EMA
+	slow      *EMA
+	signal    *EMA
+	histogram *Series
+}
+
+// NewMACD is the MACD constructor, the periods are usually 12, 26 and 9.
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{
+		Series:    newSeries(),
+		fast:      NewEMA(fast),
+		slow:      NewEMA(slow),
+		signal:    NewEMA(signal),
+		histogram: newSeries(),
+	}
+}
+
+// WarmupPeriod implements Indicator, the slow period.
+func (m *MACD) WarmupPeriod() int {
+	return max(m.fast.period, m.slow.period)
+}
+
+// OnCandle implements Indicator.
+func (m *MACD) OnCandle(candle *gotrader.Candle) {
+	m.Update(candle.Close)
+}
+
+// Update adds a value to the averages.
+func (m *MACD) Update(value float64) {
+
+	m.fast.Update(value)
+	m.slow.Update(value)
+
+	if !m.fast.Ready() || !m.slow.Ready() {
+		return
+	}
+
+	m.push(m.fast.Value() - m.slow.Value())
+	m.signal.Update(m.Value())
+
+	if m.signal.Ready() {
+		m.histogram.push(m.Value() - m.signal.Value())
+	}
+}
+
+// Signal returns the signal line, the EMA of the MACD line.
+func (m *MACD) Signal() *Series {
+	return m.signal.Series
+}
+
+// Histogram returns the difference between the MACD and the signal lines.
+func (m *MACD) Histogram() *Series {
+	return m.histogram
+}
+
+/*
+Stochastic is the stochastic oscillator of the candles, the position of the close in the range of the highs and
+lows over a period, from 0 to 100. Value and Ready are the ones of %K, smoothed by an SMA when smoothing is
+greater than 1 (the slow stochastic), and D returns its SMA.
+*/
+type Stochastic struct {
+	*Series
+	noTicks
+	highs  *extremes
+	lows   *extremes
+	count  int
+	period int
+	k      *SMA
+	d      *SMA
+}
+
+// NewStochastic is the Stochastic constructor, the periods are usually 14, 3 and 3 (or 1 for the fast stochastic).
+func NewStochastic(period, smoothing, d int) *Stochastic {
+
+	if smoothing < 1 {
+		smoothing = 1
+	}
+
+	return &Stochastic{
+		Series: newSeries(),
+		highs:  newExtremes(period, func(a, b float64) bool { return a >= b }),
+		lows:   newExtremes(period, func(a, b float64) bool { return a <= b }),
+		period: period,
+		k:      NewSMA(smoothing),
+		d:      NewSMA(d),
+	}
+}
+
+// WarmupPeriod implements Indicator, period + smoothing - 1.
+func (s *Stochastic) WarmupPeriod() int {
+	return s.period + s.k.period - 1
+}
+
+// OnCandle implements Indicator.
+func (s *Stochastic) OnCandle(candle *gotrader.Candle) {
+	s.Update(candle.High, candle.Low, candle.Close)
+}
+
+// Update adds the high, low and close of a candle to the oscillator.
+func (s *Stochastic) Update(high, low, closePrice float64) {
+
+	highest := s.highs.add(high)
+	lowest := s.lows.add(low)
+
+	if s.count < s.period {
+		s.count++
+
+		if s.count < s.period {
+			return
+		}
+	}
+
+	k := 50.0 // a flat range
+	if highest > lowest {
+		k = 100 * (closePrice - lowest) / (highest - lowest)
+	}
+
+	s.k.Update(k)
+
+	if s.k.Ready() {
+		s.push(s.k.Value())
+		s.d.Update(s.k.Value())
+	}
+}
+
+// D returns the %D line, the SMA of %K.
+func (s *Stochastic) D() *Series {
+	return s.d.Series
+}
+
+// extremes keeps the extreme of a sliding window with a monotonic queue, in amortized constant time.
+type extremes struct {
+	period  int
+	index   int
+	values  []float64
+	indexes []int
+	keeps   func(a, b float64) bool // true if a is kept in front of b
+}
+
+func newExtremes(period int, keeps func(a, b float64) bool) *extremes {
+	return &extremes{period: period, keeps: keeps}
+}
+
+// add adds a value and returns the extreme of the window.
+func (e *extremes) add(value float64) float64 {
+
+	for len(e.values) > 0 && !e.keeps(e.values[len(e.values)-1], value) {
+		e.values = e.values[:len(e.values)-1]
+		e.indexes = e.indexes[:len(e.indexes)-1]
+	}
+
+	e.values = append(e.values, value)
+	e.indexes = append(e.indexes, e.index)
+
+	if e.indexes[0] <= e.index-e.period {
+		e.values = e.values[1:]
+		e.indexes = e.indexes[1:]
+	}
+
+	e.index++
+
+	return e.values[0]
+}
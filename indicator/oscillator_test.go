@@ -0,0 +1,57 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOscillators(t *testing.T) {
+
+	t.Run("RSI is 100 without losses and 50 with equal averages", func(t *testing.T) {
+
+		rsi := NewRSI(2)
+		for _, v := range []float64{1, 2, 3} {
+			rsi.Update(v)
+		}
+
+		if !rsi.Ready() || rsi.Value() != 100 {
+			t.Errorf("expected RSI 100, got %v", rsi.Value())
+		}
+
+		rsi.Update(1) // gain (1 + 0) / 2, loss (0 + 2) / 2
+		if math.Abs(rsi.Value()-100.0/3) > 1e-12 {
+			t.Errorf("expected RSI 33.3, got %v", rsi.Value())
+		}
+	})
+
+	t.Run("MACD histogram is the MACD minus the signal", func(t *testing.T) {
+
+		macd := NewMACD(3, 6, 4)
+		for i := 0; i < 20; i++ {
+			macd.Update(float64(i * i))
+		}
+
+		if macd.Histogram().Value() != macd.Value()-macd.Signal().Value() || macd.Len() != 15 || macd.Histogram().Len() != 12 {
+			t.Errorf("unexpected MACD %v %v %v", macd.Value(), macd.Signal().Value(), macd.Histogram().Value())
+		}
+	})
+
+	t.Run("Stochastic uses the range of the period", func(t *testing.T) {
+
+		stochastic := NewStochastic(3, 1, 2)
+		stochastic.Update(10, 5, 8)
+		stochastic.Update(12, 6, 7)
+		stochastic.Update(9, 4, 9) // range 4 to 12
+
+		if stochastic.Value() != 62.5 {
+			t.Errorf("expected %%K 62.5, got %v", stochastic.Value())
+		}
+
+		stochastic.Update(8, 7, 8) // the 10 high left the window, range 4 to 12
+		stochastic.Update(8, 7, 7) // range 4 to 9
+
+		if stochastic.Value() != 60 || stochastic.D().Value() != 55 {
+			t.Errorf("unexpected %%K %v and %%D %v", stochastic.Value(), stochastic.D().Value())
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package indicator
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Updater is an indicator of a series of values, like the moving averages and the RSI, that can be computed
+// on the values of another indicator.
+type Updater interface {
+	Indicator
+	Update(value float64)
+}
+
+// Dependent is implemented by the indicators computed with the values of other indicators, the pipelines
+// compute the dependencies first.
+type Dependent interface {
+	Dependencies() []Indicator
+}
+
+// Derived is an indicator computed on the values of a source indicator, see Of.
+type Derived struct {
+	noTicks
+	indicator Updater
+	source    Indicator
+	candles   int
+}
+
+/*
+Of returns the indicator computed on the values of the source, e.g. an EMA of the RSI:
+
+	rsi := indicator.NewRSI(14)
+	smoothed := indicator.NewEMA(9)
+	ctx.Attach("EUR_USD", time.Minute, indicator.Of(smoothed, rsi))
+
+The source is computed by the pipeline before the indicator, which is updated once the source is warmed up.
+*/
+func Of(indicator Updater, source Indicator) *Derived {
+	return &Derived{indicator: indicator, source: source}
+}
+
+// OnCandle implements Indicator, updating the indicator with the value of the source computed on the candle.
+func (d *Derived) OnCandle(candle *gotrader.Candle) {
+
+	d.candles++
+
+	if d.candles >= d.source.WarmupPeriod() {
+		d.indicator.Update(d.source.Value())
+	}
+}
+
+// Value implements Indicator.
+func (d *Derived) Value() float64 {
+	return d.indicator.Value()
+}
+
+// WarmupPeriod implements Indicator, the candles warming up the source and then the indicator.
+func (d *Derived) WarmupPeriod() int {
+
+	if d.source.WarmupPeriod() == 0 {
+		return d.indicator.WarmupPeriod()
+	}
+
+	return d.source.WarmupPeriod() + d.indicator.WarmupPeriod() - 1
+}
+
+// Dependencies implements Dependent.
+func (d *Derived) Dependencies() []Indicator {
+	return []Indicator{d.source}
+}
+
+// ErrCycle is returned when an indicator depends on itself.
+var ErrCycle = errors.New("indicator depends on itself")
+
+/*
+Pipeline computes the indicators of a candle stream in dependency order, every indicator once per candle and
+tick. The dependencies of the added indicators are added with them, and an indicator added several times, e.g.
+by several strategies sharing it, is only computed once. It is safe for concurrent use.
+*/
+type Pipeline struct {
+	mutex   *sync.Mutex
+	added   map[Indicator]bool
+	order   []Indicator // topological order, the dependencies first
+	candles map[Indicator]int
+	shared  map[string]Indicator
+}
+
+// NewPipeline is the Pipeline constructor.
+func NewPipeline() *Pipeline {
+	return &Pipeline{
+		mutex:   &sync.Mutex{},
+		added:   make(map[Indicator]bool),
+		candles: make(map[Indicator]int),
+		shared:  make(map[string]Indicator),
+	}
+}
+
+// Add adds the indicators and their dependencies, the ones already added are ignored.
+func (p *Pipeline) Add(indicators ...Indicator) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.add(indicators)
+}
+
+func (p *Pipeline) add(indicators []Indicator) error {
+
+	order := p.order
+	added := make(map[Indicator]bool, len(p.added))
+	for ind := range p.added {
+		added[ind] = true
+	}
+
+	visiting := make(map[Indicator]bool)
+
+	var visit func(ind Indicator) error
+	visit = func(ind Indicator) error {
+
+		if added[ind] {
+			return nil
+		}
+
+		if visiting[ind] {
+			return ErrCycle
+		}
+
+		visiting[ind] = true
+
+		if dependent, isDependent := ind.(Dependent); isDependent {
+			for _, dependency := range dependent.Dependencies() {
+				if err := visit(dependency); err != nil {
+					return err
+				}
+			}
+		}
+
+		added[ind] = true
+		order = append(order, ind)
+
+		return nil
+	}
+
+	for _, ind := range indicators {
+		if err := visit(ind); err != nil {
+			return err
+		}
+	}
+
+	p.added = added
+	p.order = order
+
+	return nil
+}
+
+// Shared returns the indicator added with the key, or adds the one created when there is none, so strategies
+// needing the same indicator, e.g. "ema-20", share an instance.
+func (p *Pipeline) Shared(key string, create func() Indicator) (Indicator, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if ind, exist := p.shared[key]; exist {
+		return ind, nil
+	}
+
+	ind := create()
+	if err := p.add([]Indicator{ind}); err != nil {
+		return nil, err
+	}
+
+	p.shared[key] = ind
+
+	return ind, nil
+}
+
+// OnCandle computes the indicators with the candle.
+func (p *Pipeline) OnCandle(candle *gotrader.Candle) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, ind := range p.order {
+		ind.OnCandle(candle)
+		p.candles[ind]++
+	}
+}
+
+// OnTick computes the indicators with the tick.
+func (p *Pipeline) OnTick(tick *gotrader.Tick) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for _, ind := range p.order {
+		ind.OnTick(tick)
+	}
+}
+
+// Ready returns true once the indicator has been computed on its WarmupPeriod candles by the pipeline.
+func (p *Pipeline) Ready(ind Indicator) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.added[ind] && p.candles[ind] >= ind.WarmupPeriod()
+}
+
+// Len returns the number of indicators computed, the dependencies included.
+func (p *Pipeline) Len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.order)
+}
@@ -0,0 +1,87 @@
+package indicator
+
+import (
+	"testing"
+
+	"github.com/luismcruz/gotrader"
+)
+
+type lastSpread struct {
+	spread float64
+}
+
+func (s *lastSpread) OnCandle(candle *gotrader.Candle) {}
+func (s *lastSpread) OnTick(tick *gotrader.Tick)       { s.spread = tick.Ask - tick.Bid }
+func (s *lastSpread) Value() float64                   { return s.spread }
+func (s *lastSpread) WarmupPeriod() int                { return 0 }
+
+type cyclic struct {
+	*SMA
+	dependency Indicator
+}
+
+func (c *cyclic) Dependencies() []Indicator {
+	return []Indicator{c.dependency}
+}
+
+func TestPipeline(t *testing.T) {
+
+	t.Run("Dependencies are computed first and shared indicators once", func(t *testing.T) {
+
+		sma := NewSMA(2)
+		smoothed := NewSMA(2)
+		derived := Of(smoothed, sma)
+
+		pipeline := NewPipeline()
+		pipeline.Add(derived)
+		pipeline.Add(sma, derived) // e.g. by another strategy
+
+		for _, c := range []float64{1, 3, 5, 7} {
+			pipeline.OnCandle(&gotrader.Candle{Close: c})
+		}
+
+		if pipeline.Len() != 2 || sma.Value() != 6 || derived.Value() != 5 {
+			t.Errorf("unexpected values %v %v with %d indicators", sma.Value(), derived.Value(), pipeline.Len())
+		}
+	})
+
+	t.Run("Custom indicators are shared and warmed up by the pipeline", func(t *testing.T) {
+
+		pipeline := NewPipeline()
+		created := 0
+		create := func() Indicator {
+			created++
+			return Of(NewSMA(2), &lastSpread{})
+		}
+
+		first, _ := pipeline.Shared("spread", create)
+		second, _ := pipeline.Shared("spread", create)
+
+		for _, spread := range []float64{1, 3, 5} {
+			pipeline.OnTick(&gotrader.Tick{Bid: 1, Ask: 1 + spread})
+			if pipeline.Ready(first) {
+				t.Errorf("expected the indicator warming up before its candles")
+			}
+		}
+
+		pipeline.OnCandle(&gotrader.Candle{})
+		pipeline.OnTick(&gotrader.Tick{Bid: 1, Ask: 4})
+		pipeline.OnCandle(&gotrader.Candle{})
+
+		if created != 1 || first != second || !pipeline.Ready(second) || second.Value() != 4 {
+			t.Errorf("unexpected shared indicator %v created %d times", second.Value(), created)
+		}
+	})
+
+	t.Run("Cycles are rejected", func(t *testing.T) {
+
+		a := &cyclic{SMA: NewSMA(2)}
+		b := &cyclic{SMA: NewSMA(2), dependency: a}
+		a.dependency = b
+
+		pipeline := NewPipeline()
+		if err := pipeline.Add(b); err != ErrCycle || pipeline.Len() != 0 {
+			t.Errorf("expected a cycle error, got %v", err)
+		}
+	})
+}
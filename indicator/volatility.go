@@ -0,0 +1,263 @@
+package indicator
+
+import (
+	"math"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// ATR is the average true range of the candles, with the Wilder smoothing. The true range is the range of the
+// candle extended to the previous close, the first one is its high - low.
+type ATR struct {
+	*Series
+	noTicks
+	period int
+	count  int
+	last   float64
+	atr    float64
+}
+
+// NewATR is the ATR constructor.
+func NewATR(period int) *ATR {
+	return &ATR{Series: newSeries(), period: period}
+}
+
+// WarmupPeriod implements Indicator, the period.
+func (a *ATR) WarmupPeriod() int {
+	return a.period
+}
+
+// OnCandle implements Indicator.
+func (a *ATR) OnCandle(candle *gotrader.Candle) {
+	a.Update(candle.High, candle.Low, candle.Close)
+}
+
+// Update adds the high, low and close of a candle to the average.
+func (a *ATR) Update(high, low, closePrice float64) {
+
+	trueRange := high - low
+	if a.count > 0 {
+		trueRange = math.Max(high, a.last) - math.Min(low, a.last)
+	}
+	a.last = closePrice
+
+	if a.count < a.period {
+		a.count++
+		a.atr += (trueRange - a.atr) / float64(a.count)
+
+		if a.count == a.period {
+			a.push(a.atr)
+		}
+
+		return
+	}
+
+	a.atr += (trueRange - a.atr) / float64(a.period)
+	a.push(a.atr)
+}
+
+// deviation keeps the mean and the standard deviation of a sliding window, updated in constant time. The values
+// are summed shifted by the first one, so the sums of squares don't cancel out on prices far from zero.
+type deviation struct {
+	window *window
+	period int
+	count  int
+	shift  float64
+	sum    float64
+	sumSq  float64
+}
+
+func newDeviation(period int) *deviation {
+	period = validPeriod(period)
+	return &deviation{window: newWindow(period), period: period}
+}
+
+// add adds a value and returns true once the window is full.
+func (d *deviation) add(value float64) bool {
+
+	if d.count == 0 {
+		d.shift = value
+	}
+
+	value -= d.shift
+	dropped := d.window.add(value)
+	d.sum += value - dropped
+	d.sumSq += value*value - dropped*dropped
+
+	if d.count < d.period {
+		d.count++
+	}
+
+	return d.count == d.period
+}
+
+func (d *deviation) mean() float64 {
+	return d.shift + d.sum/float64(d.period)
+}
+
+// stdDev returns the standard deviation of the window, of the sample when sample is true.
+func (d *deviation) stdDev(sample bool) float64 {
+
+	n := float64(d.period)
+	shifted := d.sum / n
+	variance := d.sumSq/n - shifted*shifted
+
+	if sample {
+		if d.period < 2 {
+			return 0
+		}
+		variance *= n / (n - 1)
+	}
+
+	return math.Sqrt(math.Max(variance, 0)) // the sums may round the variance under zero
+}
+
+// BollingerBands are the SMA of the close prices, its Value, with bands at a number of standard deviations.
+type BollingerBands struct {
+	*Series
+	noTicks
+	deviation  *deviation
+	deviations float64
+	upper      *Series
+	lower      *Series
+}
+
+// NewBollingerBands is the BollingerBands constructor, usually with a period of 20 and 2 deviations.
+func NewBollingerBands(period int, deviations float64) *BollingerBands {
+	return &BollingerBands{
+		Series:     newSeries(),
+		deviation:  newDeviation(period),
+		deviations: deviations,
+		upper:      newSeries(),
+		lower:      newSeries(),
+	}
+}
+
+// WarmupPeriod implements Indicator, the period.
+func (b *BollingerBands) WarmupPeriod() int {
+	return b.deviation.period
+}
+
+// OnCandle implements Indicator.
+func (b *BollingerBands) OnCandle(candle *gotrader.Candle) {
+	b.Update(candle.Close)
+}
+
+// Update adds a value to the bands.
+func (b *BollingerBands) Update(value float64) {
+
+	if !b.deviation.add(value) {
+		return
+	}
+
+	mean := b.deviation.mean()
+	width := b.deviations * b.deviation.stdDev(false)
+
+	b.push(mean)
+	b.upper.push(mean + width)
+	b.lower.push(mean - width)
+}
+
+// Upper returns the upper band.
+func (b *BollingerBands) Upper() *Series {
+	return b.upper
+}
+
+// Lower returns the lower band.
+func (b *BollingerBands) Lower() *Series {
+	return b.lower
+}
+
+// PercentB returns the position of the value between the current bands, 0 on the lower and 1 on the upper.
+func (b *BollingerBands) PercentB(value float64) float64 {
+
+	if b.upper.Value() == b.lower.Value() {
+		return 0.5
+	}
+
+	return (value - b.lower.Value()) / (b.upper.Value() - b.lower.Value())
+}
+
+// year is the duration used to annualize the volatilities, markets like forex trade every day.
+const year = 365 * 24 * time.Hour
+
+/*
+Volatility is the realized volatility of an instrument, the sample standard deviation of the log returns of the
+close prices over a period. Its Value is per candle, Annualized scales it by the number of candles in a year:
+
+	volatility := indicator.NewVolatility(20)
+	ctx.Attach("EUR_USD", time.Hour, volatility)
+	...
+	stop := price * volatility.Value() * 2 // a stop distance of two hourly deviations
+*/
+type Volatility struct {
+	*Series
+	noTicks
+	deviation *deviation
+	timeframe time.Duration
+	last      float64
+}
+
+// NewVolatility is the Volatility constructor, it is ready after period + 1 values.
+func NewVolatility(period int) *Volatility {
+	return &Volatility{Series: newSeries(), deviation: newDeviation(period)}
+}
+
+// WarmupPeriod implements Indicator, period + 1.
+func (v *Volatility) WarmupPeriod() int {
+	return v.deviation.period + 1
+}
+
+// OnCandle implements Indicator.
+func (v *Volatility) OnCandle(candle *gotrader.Candle) {
+	v.timeframe = candle.Timeframe
+	v.Update(candle.Close)
+}
+
+// Update adds a price to the volatility, prices must be positive.
+func (v *Volatility) Update(price float64) {
+
+	last := v.last
+	v.last = price
+
+	if last <= 0 || price <= 0 {
+		return
+	}
+
+	if v.deviation.add(math.Log(price / last)) {
+		v.push(v.deviation.stdDev(true))
+	}
+}
+
+// Annualized returns the current volatility scaled to a year, with the timeframe of the candles. It is 0 when
+// the indicator was only updated with Update.
+func (v *Volatility) Annualized() float64 {
+	return AnnualizedVolatility(v.Value(), v.timeframe)
+}
+
+// AnnualizedVolatility scales a volatility measured over a timeframe to a year, 0 without a timeframe.
+func AnnualizedVolatility(volatility float64, timeframe time.Duration) float64 {
+
+	if timeframe <= 0 {
+		return 0
+	}
+
+	return volatility * math.Sqrt(float64(year)/float64(timeframe))
+}
+
+// RealizedVolatility returns the realized volatility of the close prices of the candles, the sample standard
+// deviation of their log returns, 0 with less than three candles.
+func RealizedVolatility(candles []*gotrader.Candle) float64 {
+
+	if len(candles) < 3 {
+		return 0
+	}
+
+	d := newDeviation(len(candles) - 1)
+	for i := 1; i < len(candles); i++ {
+		d.add(math.Log(candles[i].Close / candles[i-1].Close))
+	}
+
+	return d.stdDev(true)
+}
@@ -0,0 +1,60 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+func TestVolatility(t *testing.T) {
+
+	t.Run("ATR extends the range to the previous close", func(t *testing.T) {
+
+		atr := NewATR(2)
+		atr.Update(11, 9, 10)  // 2
+		atr.Update(14, 12, 13) // 4, from the close of 10
+		atr.Update(13, 12, 12) // 1
+
+		if atr.At(1) != 3 || atr.Value() != 2 {
+			t.Errorf("unexpected ATR %v %v", atr.At(1), atr.Value())
+		}
+	})
+
+	t.Run("Bollinger bands are at the deviations of the mean", func(t *testing.T) {
+
+		bands := NewBollingerBands(4, 2)
+		for _, v := range []float64{1.1002, 1.1004, 1.1004, 1.1006} {
+			bands.Update(v)
+		}
+
+		deviation := math.Sqrt(0.00000002)
+		if math.Abs(bands.Value()-1.1004) > 1e-12 || math.Abs(bands.Upper().Value()-1.1004-2*deviation) > 1e-12 ||
+			math.Abs(bands.PercentB(bands.Lower().Value())) > 1e-9 {
+			t.Errorf("unexpected bands %v %v %v", bands.Lower().Value(), bands.Value(), bands.Upper().Value())
+		}
+	})
+
+	t.Run("Volatility is annualized with the timeframe of the candles", func(t *testing.T) {
+
+		volatility := NewVolatility(2)
+		candles := make([]*gotrader.Candle, 0)
+		for _, c := range []float64{100, 101, 100, 102} {
+			candle := &gotrader.Candle{Timeframe: 24 * time.Hour, Close: c}
+			candles = append(candles, candle)
+			volatility.OnCandle(candle)
+		}
+
+		r1, r2 := math.Log(100.0/101), math.Log(102.0/100)
+		expected := math.Abs(r1-r2) / math.Sqrt(2)
+
+		if math.Abs(volatility.Value()-expected) > 1e-12 || math.Abs(volatility.Annualized()-expected*math.Sqrt(365)) > 1e-12 {
+			t.Errorf("unexpected volatility %v %v", volatility.Value(), volatility.Annualized())
+		}
+
+		if RealizedVolatility(candles[1:]) != volatility.Value() {
+			t.Errorf("expected the realized volatility of the candles %v", RealizedVolatility(candles[1:]))
+		}
+	})
+}
@@ -1,10 +1,11 @@
 package gotrader
 
 import (
-	"math"
+	"context"
+	"fmt"
+	"sync"
 	"time"
 
-	"github.com/cornelk/hashmap"
 	"go.uber.org/atomic"
 )
 
@@ -18,27 +19,86 @@ const (
 )
 
 type Instrument struct {
+	lock                      sync.RWMutex // held by the writes of the trades and metrics, and by Snapshot
 	name                      string
 	baseCurrency              string
 	quoteCurrency             string
 	longPosition              *Position
 	shortPosition             *Position
 	tradesNumber              *atomic.Int32
-	trades                    *hashmap.HashMap
+	trades                    *syncMap[string, *Trade]
 	tradesTimeOrder           *sortedTrades
-	unrealizedNetProfit       float64
-	unrealizedEffectiveProfit float64
-	marginUsed                float64
+	unrealizedNetProfit       Decimal
+	unrealizedEffectiveProfit Decimal
+	marginUsed                Decimal
 	leverage                  *atomic.Float64
-	chargedFees               float64
+	minUnits                  *atomic.Int32 // of the opens, see SpecUpdate
+	unitSize                  float64       // see Quantity
+	stopDistance              float64       // pips reported by the broker, see MinStopDistance
+	synthetic                 bool          // derived from its legs, see Synthetic
+	status                    *atomic.Int32 // InstrumentStatus
+	limit                     *atomic.Int32 // LimitState
+	limitUntil                *atomic.Int64 // unix nanoseconds, zero without timer
+	chargedFees               Decimal
+	spreadCost                *atomicDecimal // of the opens and closes, see SpreadCost
+	realizedProfit            *atomicDecimal // of the ledger transactions, see RealizedProfit
 	ask                       *atomic.Float64
 	bid                       *atomic.Float64
 	pipLocation               int
 	ccyConversion             *instrumentConversion
 	hedgeType                 Hedge
+	marginMultiplier          float64 // of the margin windows
+	lastUpdate                time.Time
+	stale                     bool
+	epoch                     *atomic.Uint64 // bumped on every change of the price, conversion rates or trades
+	calculated                uint64         // epoch of the last recalculation
+	shard                     int
+	calculatedPrices          instrumentPrices // prices of the last calculation of the metrics
+	version                   *atomic.Uint64   // bumped on every calculation of the metrics
+	aggregatedVersion         uint64           // version of the metrics aggregated in the account totals
+	aggregated                instrumentTotals // metrics aggregated in the account totals
+	stats                     *pipelineStats
+	view                      atomic.Value // *InstrumentView of the last version viewed, see View
+	listeners                 atomic.Value // []*priceListener copied on write, see ObservePrices
+	listenersMutex            sync.Mutex
 	logger                    Logger
 }
 
+// instrumentTotals are the metrics of an instrument summed in the account totals.
+type instrumentTotals struct {
+	unrealizedNetProfit       Decimal
+	unrealizedEffectiveProfit Decimal
+	chargedFees               Decimal
+	marginUsed                Decimal
+}
+
+func (t instrumentTotals) add(o instrumentTotals) instrumentTotals {
+	return instrumentTotals{
+		unrealizedNetProfit:       t.unrealizedNetProfit.Add(o.unrealizedNetProfit),
+		unrealizedEffectiveProfit: t.unrealizedEffectiveProfit.Add(o.unrealizedEffectiveProfit),
+		chargedFees:               t.chargedFees.Add(o.chargedFees),
+		marginUsed:                t.marginUsed.Add(o.marginUsed),
+	}
+}
+
+func (t instrumentTotals) sub(o instrumentTotals) instrumentTotals {
+	return t.add(instrumentTotals{
+		unrealizedNetProfit:       o.unrealizedNetProfit.Neg(),
+		unrealizedEffectiveProfit: o.unrealizedEffectiveProfit.Neg(),
+		chargedFees:               o.chargedFees.Neg(),
+		marginUsed:                o.marginUsed.Neg(),
+	})
+}
+
+// instrumentPrices are the prices and conversion rates the metrics of an instrument were calculated with.
+type instrumentPrices struct {
+	time                time.Time
+	bid                 float64
+	ask                 float64
+	baseConversionRate  float64
+	quoteConversionRate float64
+}
+
 /**************************
 *
 *	Internal Methods
@@ -54,19 +114,62 @@ func newInstrument(
 	logger Logger,
 ) *Instrument {
 
-	return &Instrument{
-		name:            name,
-		baseCurrency:    baseCurrency,
-		quoteCurrency:   quoteCurrency,
-		leverage:        atomic.NewFloat64(leverage),
-		pipLocation:     pipLocation,
-		longPosition:    newPosition(Long),
-		shortPosition:   newPosition(Short),
-		tradesNumber:    atomic.NewInt32(0),
-		trades:          &hashmap.HashMap{},
-		tradesTimeOrder: newSortedTrades(),
-		ask:             atomic.NewFloat64(0.0),
-		bid:             atomic.NewFloat64(0.0),
+	inst := &Instrument{}
+	inst.init(name, baseCurrency, quoteCurrency, leverage, pipLocation, logger)
+
+	return inst
+}
+
+// init initializes an instrument in place, its positions hold its lock.
+func (i *Instrument) init(
+	name string,
+	baseCurrency string,
+	quoteCurrency string,
+	leverage float64,
+	pipLocation int,
+	logger Logger,
+) {
+
+	if logger == nil {
+		logger = DefaultLogger()
+	}
+
+	i.name = name
+	i.baseCurrency = baseCurrency
+	i.quoteCurrency = quoteCurrency
+	i.leverage = atomic.NewFloat64(leverage)
+	i.minUnits = atomic.NewInt32(0)
+	i.unitSize = 1
+	i.status = atomic.NewInt32(int32(InstrumentTradeable))
+	i.limit = atomic.NewInt32(int32(NoLimit))
+	i.limitUntil = atomic.NewInt64(0)
+	i.spreadCost = newAtomicDecimal(0)
+	i.realizedProfit = newAtomicDecimal(0)
+	i.pipLocation = pipLocation
+	i.tradesNumber = atomic.NewInt32(0)
+	i.trades = newSyncMap[string, *Trade]()
+	i.tradesTimeOrder = newSortedTrades()
+	i.ask = atomic.NewFloat64(0.0)
+	i.bid = atomic.NewFloat64(0.0)
+	i.epoch = atomic.NewUint64(1)
+	i.version = atomic.NewUint64(0)
+	i.logger = logger
+	i.marginMultiplier = 1
+	i.longPosition = newPosition(Long, &i.lock)
+	i.shortPosition = newPosition(Short, &i.lock)
+}
+
+// acquire locks the instrument for writing, counting the contentions when stats are collected.
+func (i *Instrument) acquire() {
+
+	if i.stats == nil {
+		i.lock.Lock()
+		return
+	}
+
+	if !i.lock.TryLock() {
+		i.stats.lockContended()
+		i.lock.Lock()
 	}
 }
 
@@ -78,11 +181,15 @@ func (i *Instrument) openTrade(
 	openPrice float64,
 ) *Trade {
 
+	i.acquire()
+	defer i.lock.Unlock()
+
 	i.tradesNumber.Inc()
 
 	trade := newTrade(i, id, side, units, openTime, openPrice)
 	i.trades.Set(id, trade)
-	i.tradesTimeOrder.Append(id)
+	i.tradesTimeOrder.Append(id, openTime)
+	i.touch()
 
 	if side == Short {
 		trade.currentPrice = i.ask
@@ -92,23 +199,27 @@ func (i *Instrument) openTrade(
 		i.longPosition.openTrade(trade)
 	}
 
+	i.unrealized() // the metrics stay consistent with the trades
+	i.margin()
+
 	return trade
 }
 
-func (i *Instrument) closeTrade(id string) {
+func (i *Instrument) closeTrade(id string) error {
 
-	i.tradesNumber.Dec()
+	i.acquire()
+	defer i.lock.Unlock()
 
-	i.tradesTimeOrder.Delete(id)
-	tr, exist := i.trades.GetStringKey(id)
+	trade, exist := i.trades.Get(id)
 	if !exist {
-		i.logger.Warn(i.name + ": trying to close unexisting trade")
-		return
+		return fmt.Errorf("%s trade %s: %w", i.name, id, ErrTradeNotFound)
 	}
 
-	i.trades.Del(id)
+	i.tradesNumber.Dec()
+	i.tradesTimeOrder.Delete(id)
 
-	trade := tr.(*Trade)
+	i.trades.Del(id)
+	i.touch()
 
 	if trade.side == Long {
 		i.longPosition.closeTrade(trade)
@@ -116,41 +227,124 @@ func (i *Instrument) closeTrade(id string) {
 		i.shortPosition.closeTrade(trade)
 	}
 
+	i.unrealized()
+	i.margin()
+
+	return nil
 }
 
 func (i *Instrument) calculateUnrealized() {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	i.unrealized()
+}
+
+func (i *Instrument) calculateMarginUsed() {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	i.margin()
+}
+
+// unrealized calculates the unrealized profit, with the lock held.
+func (i *Instrument) unrealized() {
+
+	i.calculatedPrices.time = i.lastUpdate
+	i.calculatedPrices.bid = i.bid.Load()
+	i.calculatedPrices.ask = i.ask.Load()
+	if i.ccyConversion != nil {
+		i.calculatedPrices.baseConversionRate = i.ccyConversion.BaseConversionRate.Load()
+		i.calculatedPrices.quoteConversionRate = i.ccyConversion.QuoteConversionRate.Load()
+	}
 
 	i.shortPosition.calculateUnrealized()
 	i.longPosition.calculateUnrealized()
 
-	i.unrealizedNetProfit = i.longPosition.unrealizedNetProfit + i.shortPosition.unrealizedNetProfit
-	i.unrealizedEffectiveProfit = i.longPosition.unrealizedEffectiveProfit + i.shortPosition.unrealizedEffectiveProfit
-	i.chargedFees = i.longPosition.chargedFees + i.shortPosition.chargedFees
+	i.unrealizedNetProfit = i.longPosition.unrealizedNetProfit.Add(i.shortPosition.unrealizedNetProfit)
+	i.unrealizedEffectiveProfit = i.longPosition.unrealizedEffectiveProfit.Add(i.shortPosition.unrealizedEffectiveProfit)
+	i.chargedFees = i.longPosition.chargedFees.Add(i.shortPosition.chargedFees)
+	i.version.Inc()
 
 }
 
-func (i *Instrument) calculateMarginUsed() {
+// margin calculates the margin used, with the lock held.
+func (i *Instrument) margin() {
 
 	i.shortPosition.calculateMarginUsed()
 	i.longPosition.calculateMarginUsed()
 
-	switch i.hedgeType {
+	i.marginUsed = hedgedMargin(i.hedgeType, i.shortPosition.marginUsed, i.longPosition.marginUsed,
+		i.marginMultiplier)
+
+	i.version.Inc()
+}
+
+// hedgedMargin combines the margins of the sides by the hedge type, raised by the margin windows multiplier.
+func hedgedMargin(hedge Hedge, short, long Decimal, multiplier float64) Decimal {
+
+	var margin Decimal
+
+	switch hedge {
 	case NoHedge:
-		i.marginUsed = i.shortPosition.marginUsed + i.longPosition.marginUsed
+		margin = short.Add(long)
 	case FullHedge:
-		i.marginUsed = math.Abs(i.shortPosition.marginUsed - i.longPosition.marginUsed)
+		margin = short.Sub(long).Abs()
 	case HalfHedge:
-		if i.shortPosition.marginUsed > i.longPosition.marginUsed {
-			i.marginUsed = i.shortPosition.marginUsed
+		if short > long {
+			margin = short
 		} else {
-			i.marginUsed = i.longPosition.marginUsed
+			margin = long
 		}
 	}
+
+	if multiplier != 1 {
+		margin = margin.MulFloat(multiplier)
+	}
+
+	return margin
+}
+
+// totals returns the metrics summed in the account totals, with the lock held.
+func (i *Instrument) totals() instrumentTotals {
+	return instrumentTotals{
+		unrealizedNetProfit:       i.unrealizedNetProfit,
+		unrealizedEffectiveProfit: i.unrealizedEffectiveProfit,
+		chargedFees:               i.chargedFees,
+		marginUsed:                i.marginUsed,
+	}
 }
 
 func (i *Instrument) updatePrice(tick *Tick) {
+	i.acquire()
 	i.ask.Store(tick.Ask)
 	i.bid.Store(tick.Bid)
+	i.lastUpdate = tick.Time
+	i.stale = false
+	i.touch()
+	i.lock.Unlock()
+
+	i.notifyPrice(tick)
+}
+
+// touch marks the instrument to be recalculated.
+func (i *Instrument) touch() {
+	i.epoch.Inc()
+}
+
+func (i *Instrument) changed() bool {
+	return i.epoch.Load() != i.calculated
+}
+
+// recalculate calculates the unrealized profit and the margin, the changes made meanwhile keep it changed.
+func (i *Instrument) recalculate() {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	epoch := i.epoch.Load()
+	i.unrealized()
+	i.margin()
+	i.calculated = epoch
 }
 
 /**************************
@@ -183,78 +377,118 @@ func (i *Instrument) TradeByOrder(index int) *Trade {
 	return i.Trade(i.tradesTimeOrder.Get(index))
 }
 
+// TradesByAscendingOrder returns up to tradesNumber trades from the oldest, all of them when tradesNumber is -1.
 func (i *Instrument) TradesByAscendingOrder(tradesNumber int) <-chan *Trade {
+	return tradesChannel(lookupTrades(i.trades, i.tradesTimeOrder.Ascend(tradesNumber)))
+}
 
-	ch := make(chan *Trade)
-	go func() {
-		for id := range i.tradesTimeOrder.AscendIter(tradesNumber) {
-			tr, exist := i.trades.GetStringKey(id)
-			if exist {
-				ch <- tr.(*Trade)
-			}
-		}
-		close(ch)
-	}()
+// TradesByAscendingOrderContext is TradesByAscendingOrder streamed until the context is done.
+func (i *Instrument) TradesByAscendingOrderContext(ctx context.Context, tradesNumber int) <-chan *Trade {
+	return tradesChannelContext(ctx, lookupTrades(i.trades, i.tradesTimeOrder.Ascend(tradesNumber)))
+}
 
-	return ch
+// RangeTradesByAscendingOrder calls f on up to tradesNumber trades from the oldest until f returns false.
+func (i *Instrument) RangeTradesByAscendingOrder(tradesNumber int, f func(trade *Trade) bool) {
+	rangeTrades(lookupTrades(i.trades, i.tradesTimeOrder.Ascend(tradesNumber)), f)
 }
 
+// TradesByDescendingOrder returns up to tradesNumber trades from the newest, all of them when tradesNumber is -1.
 func (i *Instrument) TradesByDescendingOrder(tradesNumber int) <-chan *Trade {
-	ch := make(chan *Trade)
-	go func() {
-		for id := range i.tradesTimeOrder.DescendIter(tradesNumber) {
-			tr, exist := i.trades.GetStringKey(id)
-			if exist {
-				ch <- tr.(*Trade)
-			}
-		}
-		close(ch)
-	}()
+	return tradesChannel(lookupTrades(i.trades, i.tradesTimeOrder.Descend(tradesNumber)))
+}
 
-	return ch
+// TradesByDescendingOrderContext is TradesByDescendingOrder streamed until the context is done.
+func (i *Instrument) TradesByDescendingOrderContext(ctx context.Context, tradesNumber int) <-chan *Trade {
+	return tradesChannelContext(ctx, lookupTrades(i.trades, i.tradesTimeOrder.Descend(tradesNumber)))
 }
 
-func (i *Instrument) Trade(id string) *Trade {
+// RangeTradesByDescendingOrder calls f on up to tradesNumber trades from the newest until f returns false.
+func (i *Instrument) RangeTradesByDescendingOrder(tradesNumber int, f func(trade *Trade) bool) {
+	rangeTrades(lookupTrades(i.trades, i.tradesTimeOrder.Descend(tradesNumber)), f)
+}
 
-	trade, exist := i.trades.GetStringKey(id)
-	if exist {
-		return trade.(*Trade)
-	}
+// TradesOpenedBetween returns the open trades opened in [from, to), from the oldest.
+func (i *Instrument) TradesOpenedBetween(from, to time.Time) []*Trade {
+	return lookupTrades(i.trades, i.tradesTimeOrder.Between(from, to))
+}
 
-	return nil
+// TradesOpenedByInterval returns the number of open trades opened in each interval of [from, to), e.g. by hour
+// of a session.
+func (i *Instrument) TradesOpenedByInterval(from, to time.Time, interval time.Duration) []int {
+	return i.tradesTimeOrder.CountByInterval(from, to, interval)
 }
 
+func (i *Instrument) Trade(id string) *Trade {
+
+	trade, _ := i.trades.Get(id)
+	return trade
+}
+
+// Trades returns the open trades, in no particular order.
 func (i *Instrument) Trades() <-chan *Trade {
+	return tradesChannel(i.trades.Values())
+}
 
-	ch := make(chan *Trade)
-	go func() {
-		for kv := range i.trades.Iter() {
-			ch <- kv.Value.(*Trade)
-		}
-		close(ch)
-	}()
+// TradesContext is Trades streamed until the context is done.
+func (i *Instrument) TradesContext(ctx context.Context) <-chan *Trade {
+	return tradesChannelContext(ctx, i.trades.Values())
+}
+
+// RangeTrades calls f on the open trades, in no particular order, until f returns false.
+func (i *Instrument) RangeTrades(f func(trade *Trade) bool) {
+	rangeTrades(i.trades.Values(), f)
+}
+
+// VenueUnits returns the net units (short trades count as negative) held on each execution venue.
+func (i *Instrument) VenueUnits() map[string]int32 {
+
+	units := make(map[string]int32)
+
+	for _, trade := range i.trades.Values() {
+		units[trade.venue] += trade.units * int32(trade.sideSign)
+	}
 
-	return ch
+	return units
 }
 
 func (i *Instrument) TradesNumber() int32 {
 	return i.tradesNumber.Load()
 }
 
+// UnrealizedNetProfit returns the unrealized profit of the open trades in the home currency, their price difference
+// converted with the quote conversion rate of the instrument.
 func (i *Instrument) UnrealizedNetProfit() float64 {
-	return i.unrealizedNetProfit
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.unrealizedNetProfit.Float64()
 }
 
 func (i *Instrument) UnrealizedEffectiveProfit() float64 { // = UnrealizedNetProfit + ChargedFees
-	return i.unrealizedEffectiveProfit
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.unrealizedEffectiveProfit.Float64()
+}
+
+// RealizedProfit returns the profit realized by the trades of the instrument in the home currency, the sum of its
+// trade close, financing and dividend transactions in the ledger.
+func (i *Instrument) RealizedProfit() float64 {
+	return i.realizedProfit.Load().Float64()
 }
 
 func (i *Instrument) MarginUsed() float64 {
-	return i.marginUsed
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.marginUsed.Float64()
 }
 
 func (i *Instrument) ChargedFees() float64 {
-	return i.chargedFees
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.chargedFees.Float64()
 }
 
 func (i *Instrument) Ask() float64 {
@@ -273,6 +507,21 @@ func (i *Instrument) Leverage() float64 {
 	return i.leverage.Load()
 }
 
+// MinUnits returns the minimum units of the opens of the instrument, zero without minimum.
+func (i *Instrument) MinUnits() int32 {
+	return i.minUnits.Load()
+}
+
+// Halted returns true when the trading of the instrument is halted.
+func (i *Instrument) Halted() bool {
+	return i.Status() == InstrumentHalted
+}
+
 func (i *Instrument) PipLocation() int {
 	return i.pipLocation
 }
+
+// Synthetic returns true for the synthetic crosses derived from their legs, see Synthetic.
+func (i *Instrument) Synthetic() bool {
+	return i.synthetic
+}
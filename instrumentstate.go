@@ -0,0 +1,83 @@
+package gotrader
+
+import (
+	"time"
+)
+
+// PositionState is a consistent copy of the metrics of a position, see Instrument.Snapshot.
+type PositionState struct {
+	Side                      Side
+	TradesNumber              int32
+	Units                     int32
+	AveragePrice              float64
+	UnrealizedNetProfit       float64
+	UnrealizedEffectiveProfit float64
+	MarginUsed                float64
+	ChargedFees               float64
+}
+
+/*
+InstrumentState is a consistent copy of the metrics of an instrument, see Instrument.Snapshot.
+
+The prices and conversion rates are the ones the profits and margins were calculated with, Time is the time of
+the tick of those prices. The Instrument accessors read the latest values one by one, so a price update or a
+recalculation may happen between two calls and the values disagree.
+*/
+type InstrumentState struct {
+	Name                      string
+	Time                      time.Time
+	Bid                       float64
+	Ask                       float64
+	BaseConversionRate        float64
+	QuoteConversionRate       float64
+	Leverage                  float64
+	TradesNumber              int32
+	UnrealizedNetProfit       float64
+	UnrealizedEffectiveProfit float64
+	MarginUsed                float64
+	ChargedFees               float64
+	Long                      PositionState
+	Short                     PositionState
+}
+
+func newPositionState(p *Position) PositionState {
+	return PositionState{
+		Side:                      p.side,
+		TradesNumber:              p.tradesNumber.Load(),
+		Units:                     p.units.Load(),
+		AveragePrice:              p.averagePrice(),
+		UnrealizedNetProfit:       p.unrealizedNetProfit.Float64(),
+		UnrealizedEffectiveProfit: p.unrealizedEffectiveProfit.Float64(),
+		MarginUsed:                p.marginUsed.Float64(),
+		ChargedFees:               p.chargedFees.Float64(),
+	}
+}
+
+// Snapshot returns the state of the instrument, captured under its lock so it is not modified by a price
+// update, a trade or a recalculation while it is copied. It is safe to call from any goroutine.
+func (i *Instrument) Snapshot() InstrumentState {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.state()
+}
+
+// state returns the state of the instrument, the caller holds its lock.
+func (i *Instrument) state() InstrumentState {
+	return InstrumentState{
+		Name:                      i.name,
+		Time:                      i.calculatedPrices.time,
+		Bid:                       i.calculatedPrices.bid,
+		Ask:                       i.calculatedPrices.ask,
+		BaseConversionRate:        i.calculatedPrices.baseConversionRate,
+		QuoteConversionRate:       i.calculatedPrices.quoteConversionRate,
+		Leverage:                  i.leverage.Load(),
+		TradesNumber:              i.tradesNumber.Load(),
+		UnrealizedNetProfit:       i.unrealizedNetProfit.Float64(),
+		UnrealizedEffectiveProfit: i.unrealizedEffectiveProfit.Float64(),
+		MarginUsed:                i.marginUsed.Float64(),
+		ChargedFees:               i.chargedFees.Float64(),
+		Long:                      newPositionState(i.longPosition),
+		Short:                     newPositionState(i.shortPosition),
+	}
+}
@@ -0,0 +1,366 @@
+package gotrader
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+/*
+The core types are encoded as JSON objects with camelCase field names, the sides as LONG or SHORT:
+
+	Tick        instrument, bid, ask, bidSize, askSize, time
+	Trade       id, instrument, side, units, openTime, openPrice, currentPrice, leverage, unrealizedNetProfit,
+	            unrealizedEffectiveProfit, marginUsed, chargedFees, fees, stopLoss, guaranteedStop, takeProfit,
+	            expiry, venue, tag
+	Position    side, tradesNumber, units, averagePrice, unrealizedNetProfit, unrealizedEffectiveProfit,
+	            marginUsed, chargedFees
+	Instrument  name, baseCurrency, quoteCurrency, pipLocation, leverage, time, bid, ask, baseConversionRate,
+	            quoteConversionRate, tradesNumber, unrealizedNetProfit, unrealizedEffectiveProfit, marginUsed,
+	            chargedFees, long, short, trades
+
+The profits, margins and fees are the derived values when encoded. A decoded trade or position is a detached
+copy of the encoded values, while a decoded instrument reopens its trades at its prices and conversion rates,
+recalculating them. Decoded values are not updated by a session.
+*/
+
+type tickJSON struct {
+	Instrument string    `json:"instrument"`
+	Bid        float64   `json:"bid"`
+	Ask        float64   `json:"ask"`
+	BidSize    float64   `json:"bidSize,omitempty"`
+	AskSize    float64   `json:"askSize,omitempty"`
+	Last       float64   `json:"last,omitempty"`
+	LastSize   float64   `json:"lastSize,omitempty"`
+	Time       time.Time `json:"time"`
+	Sequence   uint64    `json:"sequence,omitempty"`
+	BidSource  string    `json:"bidSource,omitempty"`
+	AskSource  string    `json:"askSource,omitempty"`
+}
+
+type tradeJSON struct {
+	ID                        string     `json:"id"`
+	Instrument                string     `json:"instrument"`
+	Side                      string     `json:"side"`
+	Units                     int32      `json:"units"`
+	OpenTime                  time.Time  `json:"openTime"`
+	OpenPrice                 float64    `json:"openPrice"`
+	CurrentPrice              float64    `json:"currentPrice"`
+	Leverage                  float64    `json:"leverage"`
+	UnrealizedNetProfit       float64    `json:"unrealizedNetProfit"`
+	UnrealizedEffectiveProfit float64    `json:"unrealizedEffectiveProfit"`
+	MarginUsed                float64    `json:"marginUsed"`
+	ChargedFees               float64    `json:"chargedFees"`
+	Fees                      Fees       `json:"fees"`
+	StopLoss                  float64    `json:"stopLoss,omitempty"`
+	GuaranteedStop            bool       `json:"guaranteedStop,omitempty"`
+	TakeProfit                float64    `json:"takeProfit,omitempty"`
+	Expiry                    *time.Time `json:"expiry,omitempty"` // nil without maximum lifetime
+	Venue                     string     `json:"venue,omitempty"`
+	Tag                       string     `json:"tag,omitempty"`
+	OpenSpread                float64    `json:"openSpread,omitempty"`
+	SpreadCost                float64    `json:"spreadCost,omitempty"`
+}
+
+type positionJSON struct {
+	Side                      string  `json:"side"`
+	TradesNumber              int32   `json:"tradesNumber"`
+	Units                     int32   `json:"units"`
+	AveragePrice              float64 `json:"averagePrice"`
+	UnrealizedNetProfit       float64 `json:"unrealizedNetProfit"`
+	UnrealizedEffectiveProfit float64 `json:"unrealizedEffectiveProfit"`
+	MarginUsed                float64 `json:"marginUsed"`
+	ChargedFees               float64 `json:"chargedFees"`
+}
+
+type instrumentJSON struct {
+	Name                      string        `json:"name"`
+	BaseCurrency              string        `json:"baseCurrency"`
+	QuoteCurrency             string        `json:"quoteCurrency"`
+	PipLocation               int           `json:"pipLocation"`
+	Leverage                  float64       `json:"leverage"`
+	Time                      time.Time     `json:"time"`
+	Bid                       float64       `json:"bid"`
+	Ask                       float64       `json:"ask"`
+	BaseConversionRate        float64       `json:"baseConversionRate"`
+	QuoteConversionRate       float64       `json:"quoteConversionRate"`
+	TradesNumber              int32         `json:"tradesNumber"`
+	UnrealizedNetProfit       float64       `json:"unrealizedNetProfit"`
+	UnrealizedEffectiveProfit float64       `json:"unrealizedEffectiveProfit"`
+	MarginUsed                float64       `json:"marginUsed"`
+	ChargedFees               float64       `json:"chargedFees"`
+	Long                      *positionJSON `json:"long"`
+	Short                     *positionJSON `json:"short"`
+	Trades                    []*tradeJSON  `json:"trades"` // by open time order
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t *Tick) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&tickJSON{
+		Instrument: t.Instrument,
+		Bid:        t.Bid,
+		Ask:        t.Ask,
+		BidSize:    t.BidSize,
+		AskSize:    t.AskSize,
+		Last:       t.Last,
+		LastSize:   t.LastSize,
+		Time:       t.Time,
+		Sequence:   t.Sequence,
+		BidSource:  t.BidSource,
+		AskSource:  t.AskSource,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Tick) UnmarshalJSON(data []byte) error {
+
+	var v tickJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	t.Instrument, t.Bid, t.Ask, t.BidSize, t.AskSize, t.Time = v.Instrument, v.Bid, v.Ask, v.BidSize, v.AskSize, v.Time
+	t.Last, t.LastSize, t.Sequence, t.BidSource, t.AskSource = v.Last, v.LastSize, v.Sequence, v.BidSource, v.AskSource
+
+	return nil
+}
+
+func newTradeJSON(t *Trade) *tradeJSON {
+	return &tradeJSON{
+		ID:                        t.id,
+		Instrument:                t.instrumentName,
+		Side:                      t.side.String(),
+		Units:                     t.units,
+		OpenTime:                  t.openTime,
+		OpenPrice:                 t.openPrice,
+		CurrentPrice:              t.currentPrice.Load(),
+		Leverage:                  t.leverage.Load(),
+		UnrealizedNetProfit:       t.unrealizedNetProfit.Float64(),
+		UnrealizedEffectiveProfit: t.unrealizedEffectiveProfit.Float64(),
+		MarginUsed:                t.marginUsed.Float64(),
+		ChargedFees:               t.chargedFees.Load().Float64(),
+		Fees:                      t.fees.breakdown(),
+		StopLoss:                  t.stopLoss,
+		GuaranteedStop:            t.guaranteedStop,
+		TakeProfit:                t.takeProfit,
+		Expiry:                    expiryJSON(t.expiry),
+		Venue:                     t.venue,
+		Tag:                       t.tag,
+		OpenSpread:                t.openSpread,
+		SpreadCost:                t.spreadCost.Float64(),
+	}
+}
+
+// expiryJSON returns the encoded expiry of a trade, nil for the zero time so it is omitted.
+func expiryJSON(expiry time.Time) *time.Time {
+
+	if expiry.IsZero() {
+		return nil
+	}
+
+	return &expiry
+}
+
+// expiryTime returns the expiry of a decoded trade, the zero time when it was omitted.
+func expiryTime(expiry *time.Time) time.Time {
+
+	if expiry == nil {
+		return time.Time{}
+	}
+
+	return *expiry
+}
+
+// MarshalJSON implements json.Marshaler, the derived values are read under the instrument lock.
+func (t *Trade) MarshalJSON() ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return json.Marshal(newTradeJSON(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the trade is a detached copy of the encoded values. The trades
+// book-kept by an instrument return ErrReadOnly.
+func (t *Trade) UnmarshalJSON(data []byte) error {
+
+	if t.lock != nil && !t.detached {
+		return fmt.Errorf("trade %s: %w", t.id, ErrReadOnly)
+	}
+
+	var v tradeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	side, err := ParseSide(v.Side)
+	if err != nil {
+		return err
+	}
+
+	*t = Trade{
+		lock:                      &sync.RWMutex{},
+		id:                        v.ID,
+		instrumentName:            v.Instrument,
+		side:                      side,
+		units:                     v.Units,
+		openTime:                  v.OpenTime,
+		openPrice:                 v.OpenPrice,
+		currentPrice:              atomic.NewFloat64(v.CurrentPrice),
+		leverage:                  atomic.NewFloat64(v.Leverage),
+		sideSign:                  sideSign(side),
+		unrealizedNetProfit:       NewDecimal(v.UnrealizedNetProfit),
+		unrealizedEffectiveProfit: NewDecimal(v.UnrealizedEffectiveProfit),
+		marginUsed:                NewDecimal(v.MarginUsed),
+		chargedFees:               newAtomicDecimal(0),
+		fees:                      newFeeItems(),
+		stopLoss:                  v.StopLoss,
+		guaranteedStop:            v.GuaranteedStop,
+		takeProfit:                v.TakeProfit,
+		expiry:                    expiryTime(v.Expiry),
+		venue:                     v.Venue,
+		tag:                       v.Tag,
+		openSpread:                v.OpenSpread,
+		spreadCost:                NewDecimal(v.SpreadCost),
+		detached:                  true,
+	}
+
+	t.restoreFees(v.ChargedFees, v.Fees)
+
+	return nil
+}
+
+func newPositionJSON(s PositionState) *positionJSON {
+	return &positionJSON{
+		Side:                      s.Side.String(),
+		TradesNumber:              s.TradesNumber,
+		Units:                     s.Units,
+		AveragePrice:              s.AveragePrice,
+		UnrealizedNetProfit:       s.UnrealizedNetProfit,
+		UnrealizedEffectiveProfit: s.UnrealizedEffectiveProfit,
+		MarginUsed:                s.MarginUsed,
+		ChargedFees:               s.ChargedFees,
+	}
+}
+
+// MarshalJSON implements json.Marshaler, the position is read under the instrument lock.
+func (p *Position) MarshalJSON() ([]byte, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return json.Marshal(newPositionJSON(newPositionState(p)))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the position is a detached copy of the encoded values, without
+// its trades. The positions of an instrument return ErrReadOnly.
+func (p *Position) UnmarshalJSON(data []byte) error {
+
+	if p.lock != nil && !p.detached {
+		return fmt.Errorf("%s position: %w", p.side, ErrReadOnly)
+	}
+
+	var v positionJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	side, err := ParseSide(v.Side)
+	if err != nil {
+		return err
+	}
+
+	*p = *newPosition(side, &sync.RWMutex{})
+	p.tradesNumber.Store(v.TradesNumber)
+	p.units.Store(v.Units)
+	p.notional = NewDecimal(v.AveragePrice).MulInt(int64(v.Units))
+	p.unrealizedNetProfit = NewDecimal(v.UnrealizedNetProfit)
+	p.unrealizedEffectiveProfit = NewDecimal(v.UnrealizedEffectiveProfit)
+	p.marginUsed = NewDecimal(v.MarginUsed)
+	p.chargedFees = NewDecimal(v.ChargedFees)
+	p.detached = true
+
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, the instrument and its trades are read under its lock.
+func (i *Instrument) MarshalJSON() ([]byte, error) {
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	s := i.state()
+	trades := lookupTrades(i.trades, i.tradesTimeOrder.Ascend(-1))
+	list := make([]*tradeJSON, 0, len(trades))
+	for _, t := range trades {
+		list = append(list, newTradeJSON(t))
+	}
+
+	return json.Marshal(&instrumentJSON{
+		Name:                      s.Name,
+		BaseCurrency:              i.baseCurrency,
+		QuoteCurrency:             i.quoteCurrency,
+		PipLocation:               i.pipLocation,
+		Leverage:                  s.Leverage,
+		Time:                      s.Time,
+		Bid:                       s.Bid,
+		Ask:                       s.Ask,
+		BaseConversionRate:        s.BaseConversionRate,
+		QuoteConversionRate:       s.QuoteConversionRate,
+		TradesNumber:              s.TradesNumber,
+		UnrealizedNetProfit:       s.UnrealizedNetProfit,
+		UnrealizedEffectiveProfit: s.UnrealizedEffectiveProfit,
+		MarginUsed:                s.MarginUsed,
+		ChargedFees:               s.ChargedFees,
+		Long:                      newPositionJSON(s.Long),
+		Short:                     newPositionJSON(s.Short),
+		Trades:                    list,
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the trades are reopened at the encoded prices and conversion rates
+// and the derived values recalculated.
+func (i *Instrument) UnmarshalJSON(data []byte) error {
+
+	var v instrumentJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	i.init(v.Name, v.BaseCurrency, v.QuoteCurrency, v.Leverage, v.PipLocation, nil)
+	i.bid.Store(v.Bid)
+	i.ask.Store(v.Ask)
+	i.lastUpdate = v.Time
+
+	conversion := newInstrumentConversion(v.Name, v.BaseCurrency, v.QuoteCurrency)
+	conversion.Bid = i.bid
+	conversion.Ask = i.ask
+	conversion.BaseConversionRate.Store(v.BaseConversionRate)
+	conversion.QuoteConversionRate.Store(v.QuoteConversionRate)
+	i.ccyConversion = conversion
+
+	for _, t := range v.Trades {
+
+		side, err := ParseSide(t.Side)
+		if err != nil {
+			return err
+		}
+
+		trade := i.openTrade(t.ID, side, t.OpenTime, t.Units, t.OpenPrice)
+		trade.restoreFees(t.ChargedFees, t.Fees)
+		trade.stopLoss = t.StopLoss
+		trade.guaranteedStop = t.GuaranteedStop
+		trade.takeProfit = t.TakeProfit
+		trade.expiry = expiryTime(t.Expiry)
+		trade.venue = t.Venue
+		trade.tag = t.Tag
+		trade.openSpread = t.OpenSpread
+		trade.spreadCost = NewDecimal(t.SpreadCost)
+	}
+
+	i.calculateUnrealized()
+	i.calculateMarginUsed()
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package gotrader
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyStage identifies the stage of the tick pipeline a latency is measured to.
+type LatencyStage int
+
+const (
+	TickToDecision   LatencyStage = iota // from the tick arrival until the strategy OnTick returns
+	TickToSubmission                     // from the tick arrival until an order requested on it is sent to the broker
+)
+
+func (s LatencyStage) String() string {
+	switch s {
+	case TickToDecision:
+		return "TICK_TO_DECISION"
+	case TickToSubmission:
+		return "TICK_TO_SUBMISSION"
+	}
+
+	return "UNKNOWN"
+}
+
+/*
+LatencyObserver receives the latencies of the tick pipeline, the instrument is the one of the tick. Observers are
+called from the engine goroutines, so they must be fast and safe for concurrent use.
+
+The live engine measures from the moment the client delivers the tick, so the time waiting in the ticks buffer is
+included. The backtest engine measures from the moment the tick is dequeued, since the feed runs ahead of it.
+Orders count for TickToSubmission when they are requested while the strategy handles a tick.
+*/
+type LatencyObserver interface {
+	ObserveLatency(stage LatencyStage, instrument string, latency time.Duration)
+}
+
+// latencyHooks feeds the observers of a session, it is nil (and measures nothing) without observers.
+type latencyHooks struct {
+	observers []LatencyObserver
+	tick      atomic.Pointer[Tick] // the tick being handled by the strategy
+}
+
+func newLatencyHooks(observers []LatencyObserver) *latencyHooks {
+
+	if len(observers) == 0 {
+		return nil
+	}
+
+	return &latencyHooks{observers: observers}
+}
+
+func (h *latencyHooks) observe(stage LatencyStage, tick *Tick) {
+
+	latency := time.Since(tick.arrival)
+
+	for _, o := range h.observers {
+		o.ObserveLatency(stage, tick.Instrument, latency)
+	}
+}
+
+// arrived stamps the arrival time of the tick.
+func (h *latencyHooks) arrived(tick *Tick) {
+	if h != nil {
+		tick.arrival = time.Now()
+	}
+}
+
+// deciding marks the tick as the one being handled by the strategy.
+func (h *latencyHooks) deciding(tick *Tick) {
+	if h != nil {
+		h.tick.Store(tick)
+	}
+}
+
+// decided observes the decision latency of the tick handled by the strategy.
+func (h *latencyHooks) decided(tick *Tick) {
+
+	if h == nil {
+		return
+	}
+
+	h.tick.Store(nil)
+	h.observe(TickToDecision, tick)
+}
+
+// decision returns the tick being handled by the strategy, nil outside its OnTick.
+func (h *latencyHooks) decision() *Tick {
+
+	if h == nil {
+		return nil
+	}
+
+	return h.tick.Load()
+}
+
+// submitted observes the submission latency of an order requested on the tick, if any.
+func (h *latencyHooks) submitted(tick *Tick) {
+	if h != nil && tick != nil {
+		h.observe(TickToSubmission, tick)
+	}
+}
+
+// LatencySnapshot is the state of a latency histogram. Counts has a count per bucket, each counting the
+// latencies up to its bound and above the previous one, and a last count for the latencies above every bound.
+type LatencySnapshot struct {
+	Buckets []time.Duration
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+}
+
+// Mean returns the mean latency, 0 without observations.
+func (s LatencySnapshot) Mean() time.Duration {
+
+	if s.Count == 0 {
+		return 0
+	}
+
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile returns the bound of the bucket of the q quantile (0 to 1), so it is an upper bound of the quantile.
+// It returns the last bound when the quantile is above every bound, 0 without observations.
+func (s LatencySnapshot) Quantile(q float64) time.Duration {
+
+	if s.Count == 0 || len(s.Buckets) == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(s.Count))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var cumulative uint64
+	for i, bound := range s.Buckets {
+		cumulative += s.Counts[i]
+		if cumulative >= rank {
+			return bound
+		}
+	}
+
+	return s.Buckets[len(s.Buckets)-1]
+}
+
+/*
+LatencyHistogram is a LatencyObserver keeping a histogram of the latencies of every stage, for the users not
+exporting them to a metrics system:
+
+	histogram := gotrader.NewLatencyHistogram()
+	session.ObserveLatency(histogram)
+	...
+	fmt.Println(histogram.Snapshot(gotrader.TickToDecision).Quantile(0.99))
+*/
+type LatencyHistogram struct {
+	mutex   *sync.Mutex
+	buckets []time.Duration
+	stages  map[LatencyStage]*LatencySnapshot
+}
+
+// NewLatencyHistogram is the LatencyHistogram constructor, the bucket bounds must be increasing and default
+// to 10µs doubling up to ~330ms.
+func NewLatencyHistogram(buckets ...time.Duration) *LatencyHistogram {
+
+	if len(buckets) == 0 {
+		for bound := 10 * time.Microsecond; len(buckets) < 16; bound *= 2 {
+			buckets = append(buckets, bound)
+		}
+	}
+
+	return &LatencyHistogram{
+		mutex:   &sync.Mutex{},
+		buckets: buckets,
+		stages:  make(map[LatencyStage]*LatencySnapshot),
+	}
+}
+
+// ObserveLatency implements LatencyObserver.
+func (h *LatencyHistogram) ObserveLatency(stage LatencyStage, _ string, latency time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	s, exist := h.stages[stage]
+	if !exist {
+		s = &LatencySnapshot{Buckets: h.buckets, Counts: make([]uint64, len(h.buckets)+1)}
+		h.stages[stage] = s
+	}
+
+	i := 0
+	for i < len(h.buckets) && latency > h.buckets[i] {
+		i++
+	}
+
+	s.Counts[i]++
+	s.Count++
+	s.Sum += latency
+}
+
+// Snapshot returns the histogram of the stage.
+func (h *LatencyHistogram) Snapshot(stage LatencyStage) LatencySnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	s, exist := h.stages[stage]
+	if !exist {
+		return LatencySnapshot{Buckets: h.buckets, Counts: make([]uint64, len(h.buckets)+1)}
+	}
+
+	snapshot := *s
+	snapshot.Counts = append([]uint64(nil), s.Counts...)
+
+	return snapshot
+}
@@ -0,0 +1,190 @@
+package gotrader
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// TransactionType identifies the kind of entry recorded in the ledger.
+type TransactionType int
+
+const (
+	// TradeCloseTransaction records the realized profit of a closed trade
+	TradeCloseTransaction TransactionType = iota
+
+	// FinancingTransaction records a swap/rollover charge
+	FinancingTransaction
+
+	// FundsTransferTransaction records a deposit or a withdrawal
+	FundsTransferTransaction
+
+	// BalanceAdjustmentTransaction records a correction of the balance by the broker, e.g. the negative balance
+	// written back to zero by the negative balance protection
+	BalanceAdjustmentTransaction
+
+	// DividendTransaction records a dividend adjustment of an open trade, credited to the longs and debited to
+	// the shorts
+	DividendTransaction
+
+	// ManagementFeeTransaction records the accrual of the management fee of a managed account, see FeePolicy
+	ManagementFeeTransaction
+
+	// PerformanceFeeTransaction records the accrual of the performance fee of a managed account above its
+	// high-water mark, see FeePolicy
+	PerformanceFeeTransaction
+)
+
+func (t TransactionType) String() string {
+
+	names := [...]string{"TRADE_CLOSE", "FINANCING", "FUNDS_TRANSFER", "BALANCE_ADJUSTMENT", "DIVIDEND",
+		"MANAGEMENT_FEE", "PERFORMANCE_FEE"}
+
+	return names[t]
+}
+
+// ParseTransactionType returns the transaction type of its name, e.g. TRADE_CLOSE.
+func ParseTransactionType(name string) (TransactionType, error) {
+
+	for t := TradeCloseTransaction; t <= PerformanceFeeTransaction; t++ {
+		if t.String() == name {
+			return t, nil
+		}
+	}
+
+	return 0, errors.New("invalid transaction type " + name)
+}
+
+// External returns true for the transactions moving the balance without trading, the funds transfers and the
+// balance adjustments, so they change the base of the returns instead of the profit.
+func (t TransactionType) External() bool {
+	return t == FundsTransferTransaction || t == BalanceAdjustmentTransaction
+}
+
+// Transaction is a realized entry of the account ledger.
+// Amount is the value credited to the balance (negative values are debits) in home currency.
+type Transaction struct {
+	Type       TransactionType
+	TradeID    string
+	Instrument string
+	Side       Side
+	Units      int32
+	OpenPrice  float64
+	ClosePrice float64
+	OpenTime   time.Time
+	Amount     float64
+	Fees       float64
+	SpreadCost float64 // of the trade closes, the half spreads of the open and the close
+	Balance    float64
+	Time       time.Time
+	Tag        string
+	Reason     CloseReason // of the trade closes
+}
+
+// Ledger keeps the time ordered history of every realized transaction of the account.
+type Ledger struct {
+	sync.RWMutex
+	openingBalance float64
+	transactions   []*Transaction
+	events         *EventBus
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newLedger() *Ledger {
+	return &Ledger{
+		transactions: make([]*Transaction, 0, 64),
+	}
+}
+
+func (l *Ledger) setOpeningBalance(balance float64) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.openingBalance = balance
+}
+
+func (l *Ledger) record(transaction *Transaction) {
+	l.Lock()
+	l.transactions = append(l.transactions, transaction)
+	l.Unlock()
+
+	l.events.publish(TransactionRecorded{Time: transaction.Time, Transaction: transaction})
+}
+
+// since returns the transactions recorded after the first n, sharing the backing array of the ledger.
+func (l *Ledger) since(n int) []*Transaction {
+	l.RLock()
+	defer l.RUnlock()
+
+	return l.transactions[n:len(l.transactions):len(l.transactions)]
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// OpeningBalance returns the balance of the account when the session started.
+func (l *Ledger) OpeningBalance() float64 {
+	l.RLock()
+	defer l.RUnlock()
+
+	return l.openingBalance
+}
+
+// Transactions returns a copy of all the recorded transactions by time order.
+func (l *Ledger) Transactions() []*Transaction {
+	l.RLock()
+	defer l.RUnlock()
+
+	transactions := make([]*Transaction, len(l.transactions))
+	copy(transactions, l.transactions)
+
+	return transactions
+}
+
+// ClosedTrades returns the trade close transactions by time order.
+func (l *Ledger) ClosedTrades() []*Transaction {
+	l.RLock()
+	defer l.RUnlock()
+
+	trades := make([]*Transaction, 0, len(l.transactions))
+
+	for _, t := range l.transactions {
+		if t.Type == TradeCloseTransaction {
+			trades = append(trades, t)
+		}
+	}
+
+	return trades
+}
+
+// RealizedProfit returns the sum of all the trading and financing transactions.
+func (l *Ledger) RealizedProfit() float64 {
+	l.RLock()
+	defer l.RUnlock()
+
+	var profit Decimal
+
+	for _, t := range l.transactions {
+		if !t.Type.External() {
+			profit = profit.Add(NewDecimal(t.Amount))
+		}
+	}
+
+	return profit.Float64()
+}
+
+// Len returns the number of recorded transactions.
+func (l *Ledger) Len() int {
+	l.RLock()
+	defer l.RUnlock()
+
+	return len(l.transactions)
+}
@@ -0,0 +1,129 @@
+package gotrader
+
+import (
+	"fmt"
+	"time"
+)
+
+// LimitState is the circuit breaker state of an instrument, e.g. the limit-up/limit-down bands of an exchange.
+type LimitState int
+
+const (
+	// NoLimit trades in both directions
+	NoLimit LimitState = iota
+
+	// LimitUp blocks the buys, the price is at the upper band
+	LimitUp
+
+	// LimitDown blocks the sells, the price is at the lower band
+	LimitDown
+)
+
+func (s LimitState) String() string {
+
+	names := [...]string{"NO_LIMIT", "LIMIT_UP", "LIMIT_DOWN"}
+
+	return names[s]
+}
+
+// blocks returns whether the state blocks the opens of the side.
+func (s LimitState) blocks(side Side) bool {
+	return s == LimitUp && side == Long || s == LimitDown && side == Short
+}
+
+// LimitError is the error of an open blocked by the limit state of its instrument, it matches ErrLimitState with
+// errors.Is. Until is the time the state clears, zero when it is cleared by the broker.
+type LimitError struct {
+	Instrument string
+	State      LimitState
+	Side       Side
+	Until      time.Time
+}
+
+func (e *LimitError) Error() string {
+
+	if e.Until.IsZero() {
+		return fmt.Sprintf("%s: %s blocks the %s opens", e.Instrument, e.State, e.Side)
+	}
+
+	return fmt.Sprintf("%s: %s blocks the %s opens until %s", e.Instrument, e.State, e.Side,
+		e.Until.Format(time.RFC3339))
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitState
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// setLimit enters the limit state until t, the zero time waits for the broker to clear it.
+func (i *Instrument) setLimit(state LimitState, until time.Time) {
+
+	i.limitUntil.Store(0)
+	if state != NoLimit && !until.IsZero() {
+		i.limitUntil.Store(until.UnixNano())
+	}
+
+	i.limit.Store(int32(state))
+}
+
+// limitExpiry returns the update clearing the limit state of the instrument expired at t, nil when there is none.
+func (i *Instrument) limitExpiry(t time.Time) *SpecUpdate {
+
+	until := i.LimitUntil()
+	if i.Limit() == NoLimit || until.IsZero() || t.Before(until) {
+		return nil
+	}
+
+	return &SpecUpdate{Instrument: i.name, Time: t, LimitCleared: true}
+}
+
+// limitExpiries returns the updates clearing the limit states of the instruments expired at t.
+func (a *Account) limitExpiries(t time.Time) []*SpecUpdate {
+
+	var updates []*SpecUpdate
+
+	for _, inst := range a.list() {
+		if update := inst.limitExpiry(t); update != nil {
+			updates = append(updates, update)
+		}
+	}
+
+	return updates
+}
+
+// checkLimit returns the error of an open of the side of an instrument in a limit state blocking it.
+func checkLimit(inst *Instrument, side Side) error {
+
+	if state := inst.Limit(); state.blocks(side) {
+		return &LimitError{Instrument: inst.name, State: state, Side: side, Until: inst.LimitUntil()}
+	}
+
+	return nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Limit returns the circuit breaker state of the instrument.
+func (i *Instrument) Limit() LimitState {
+	return LimitState(i.limit.Load())
+}
+
+// LimitUntil returns the time the limit state of the instrument clears, zero without limit state or when it is
+// cleared by the broker.
+func (i *Instrument) LimitUntil() time.Time {
+
+	if nanos := i.limitUntil.Load(); nanos != 0 {
+		return time.Unix(0, nanos)
+	}
+
+	return time.Time{}
+}
@@ -1,9 +1,19 @@
 package gotrader
 
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
 // Logger is an interface of a logger behaviour used by gotrader,
 // with it the user will be able to configure logging level and message format
 // at free will. The idea is to have a generic interface already implemented by a
 // by some logging packages, without importing them directly.
+//
+// slog loggers are adapted with NewSlogLogger, logrus and zap loggers with the logging/logruslog and
+// logging/zaplog packages, so the library doesn't depend on any of them.
 type Logger interface {
 	Fatal(args ...interface{})
 	Fatalf(format string, args ...interface{})
@@ -16,3 +26,70 @@ type Logger interface {
 	Debug(args ...interface{})
 	Debugf(format string, args ...interface{})
 }
+
+// DefaultLogger returns the logger used when none is given: the slog default logger.
+func DefaultLogger() Logger {
+	return NewSlogLogger(slog.Default())
+}
+
+// NewSlogLogger adapts a slog logger to the Logger interface, Fatal logs at error level and exits.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return &slogLogger{logger: logger}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l *slogLogger) log(level slog.Level, msg string) {
+	l.logger.Log(context.Background(), level, msg)
+}
+
+func (l *slogLogger) Fatal(args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+func (l *slogLogger) Fatalf(format string, args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func (l *slogLogger) Error(args ...interface{}) { l.log(slog.LevelError, fmt.Sprint(args...)) }
+func (l *slogLogger) Warn(args ...interface{})  { l.log(slog.LevelWarn, fmt.Sprint(args...)) }
+func (l *slogLogger) Info(args ...interface{})  { l.log(slog.LevelInfo, fmt.Sprint(args...)) }
+func (l *slogLogger) Debug(args ...interface{}) { l.log(slog.LevelDebug, fmt.Sprint(args...)) }
+
+func (l *slogLogger) Errorf(format string, args ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) Warnf(format string, args ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) Infof(format string, args ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+func (l *slogLogger) Debugf(format string, args ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprintf(format, args...))
+}
+
+// NopLogger returns a Logger discarding everything, Fatal still exits.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Fatal(args ...interface{})                 { os.Exit(1) }
+func (nopLogger) Fatalf(format string, args ...interface{}) { os.Exit(1) }
+func (nopLogger) Error(args ...interface{})                 {}
+func (nopLogger) Errorf(format string, args ...interface{}) {}
+func (nopLogger) Warn(args ...interface{})                  {}
+func (nopLogger) Warnf(format string, args ...interface{})  {}
+func (nopLogger) Info(args ...interface{})                  {}
+func (nopLogger) Infof(format string, args ...interface{})  {}
+func (nopLogger) Debug(args ...interface{})                 {}
+func (nopLogger) Debugf(format string, args ...interface{}) {}
@@ -0,0 +1,12 @@
+// Package logruslog adapts logrus loggers to the gotrader Logger interface.
+package logruslog
+
+import (
+	"github.com/luismcruz/gotrader"
+	"github.com/sirupsen/logrus"
+)
+
+// New returns the logrus logger, or entry with fields, as a gotrader Logger.
+func New(logger logrus.FieldLogger) gotrader.Logger {
+	return logger
+}
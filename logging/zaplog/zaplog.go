@@ -0,0 +1,12 @@
+// Package zaplog adapts zap loggers to the gotrader Logger interface.
+package zaplog
+
+import (
+	"github.com/luismcruz/gotrader"
+	"go.uber.org/zap"
+)
+
+// New returns the sugared zap logger as a gotrader Logger.
+func New(logger *zap.Logger) gotrader.Logger {
+	return logger.Sugar()
+}
@@ -0,0 +1,237 @@
+package gotrader
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+FeePolicy defines the fees of a managed account, accrued at the opens of Schedule: the management fee is the annual
+ManagementRate of the equity pro rata of the days since the previous accrual, and the performance fee the
+PerformanceRate of the equity above the high-water mark, which is then raised to the equity net of the fees.
+
+The high-water mark starts at the equity of the session start, the open trades valued at the first prices, and
+follows the deposits and the withdrawals, so they are not charged as performance. Every accrual is recorded in the
+ledger as a ManagementFeeTransaction or a PerformanceFeeTransaction debiting the balance, the equity of the account
+is reported net of the fees.
+*/
+type FeePolicy struct {
+	ManagementRate  float64         // annual, e.g. 0.02 for 2%
+	PerformanceRate float64         // of the gains above the high-water mark, e.g. 0.2 for 20%
+	Schedule        SessionCalendar // the opens are the accruals, every day at the rollover when nil
+	DayCount        float64         // days of the year of the management fee, 365 when zero
+}
+
+// ManagedFees is the functional option to accrue the management and performance fees of the account, see FeePolicy.
+func ManagedFees(policy FeePolicy) Option {
+	return func(p *sessionParameters) {
+		p.managedFees = &policy
+	}
+}
+
+// FeeAccruals are the fees accrued by the FeePolicy of the session, see Account.FeeAccruals.
+type FeeAccruals struct {
+	HighWaterMark   float64
+	ManagementFees  float64 // accrued since the start of the session
+	PerformanceFees float64 // accrued since the start of the session
+	GrossEquity     float64 // the equity before the fees
+	LastAccrual     time.Time
+	NextAccrual     time.Time
+}
+
+// feeAccruer accrues the fees of a FeePolicy as the session time moves, it is nil without policy.
+type feeAccruer struct {
+	mutex           *sync.Mutex
+	policy          FeePolicy
+	highWaterMark   Decimal
+	managementFees  Decimal
+	performanceFees Decimal
+	last            time.Time
+	next            time.Time
+	recorded        int // transactions of the ledger scanned for the flows moving the high-water mark
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newFeeAccruer(policy *FeePolicy) *feeAccruer {
+
+	if policy == nil {
+		return nil
+	}
+
+	a := &feeAccruer{mutex: &sync.Mutex{}, policy: *policy}
+
+	if a.policy.Schedule == nil {
+		a.policy.Schedule = defaultRollover()
+	}
+
+	if a.policy.DayCount == 0 {
+		a.policy.DayCount = 365
+	}
+
+	return a
+}
+
+// accrue accrues the fees of every accrual since the last call on the equity of the account, the first call only
+// sets the high-water mark and schedules the next accrual. It runs on the ticks of the ready engine, after the
+// recalculation of the account, so the mark values the open trades at the prices of the session start.
+func (f *feeAccruer) accrue(account *Account, t time.Time, logger Logger) {
+
+	if f == nil {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	account.lock.RLock()
+	equity := account.balance.Load().Add(account.unrealizedNetProfit)
+	account.lock.RUnlock()
+
+	if f.next.IsZero() {
+		f.highWaterMark = equity
+		f.last, f.next = t, f.policy.Schedule.NextOpen(t)
+		f.recorded = account.ledger.Len()
+		return
+	}
+
+	if f.next.After(t) {
+		return
+	}
+
+	defer account.aggregate() // the equity net of the fees charged
+
+	flows := account.ledger.since(f.recorded)
+	f.recorded += len(flows)
+
+	for _, transaction := range flows {
+		if transaction.Type.External() {
+			f.highWaterMark = f.highWaterMark.Add(NewDecimal(transaction.Amount))
+		}
+	}
+
+	for !f.next.IsZero() && !f.next.After(t) {
+
+		accrual := f.next
+		f.next = f.policy.Schedule.NextOpen(accrual)
+
+		days := accrual.Sub(f.last).Hours() / 24
+		f.last = accrual
+
+		management := equity.MulFloat(f.policy.ManagementRate * days / f.policy.DayCount)
+		if management.Sign() > 0 {
+			equity = equity.Sub(management)
+			f.managementFees = f.managementFees.Add(management)
+		}
+
+		performance := equity.Sub(f.highWaterMark).MulFloat(f.policy.PerformanceRate)
+		if performance.Sign() > 0 {
+			equity = equity.Sub(performance)
+			f.performanceFees = f.performanceFees.Add(performance)
+		}
+
+		if equity.Sub(f.highWaterMark).Sign() > 0 {
+			f.highWaterMark = equity
+		}
+
+		// charged after the high-water mark is raised, so the entries log the state of the accrual
+		if management.Sign() > 0 {
+			f.charge(account, ManagementFeeTransaction, management, accrual, logger)
+		}
+
+		if performance.Sign() > 0 {
+			f.charge(account, PerformanceFeeTransaction, performance, accrual, logger)
+		}
+	}
+}
+
+// charge records a fee debited from the balance, logged with the state of the accruals so they are restored with
+// the account, with the mutex held.
+func (f *feeAccruer) charge(account *Account, kind TransactionType, fee Decimal, t time.Time, logger Logger) {
+
+	transaction := &Transaction{
+		Type:   kind,
+		Amount: fee.Neg().Float64(),
+		Time:   t,
+	}
+
+	account.wal.write(&WALEntry{Operation: WALFee, Time: t, Transaction: transaction, Accruals: f.state()}, logger)
+
+	account.writes.begin()
+	transaction.Balance = account.balance.Add(fee.Neg()).Float64()
+	account.record(transaction)
+	account.writes.end()
+}
+
+// state returns the state of the accruals, with the mutex held.
+func (f *feeAccruer) state() *FeeSnapshot {
+	return &FeeSnapshot{
+		HighWaterMark:   f.highWaterMark.Float64(),
+		ManagementFees:  f.managementFees.Float64(),
+		PerformanceFees: f.performanceFees.Float64(),
+		LastAccrual:     f.last,
+		NextAccrual:     f.next,
+		Recorded:        f.recorded,
+	}
+}
+
+// snapshot returns the state of the accruals of a Snapshot, nil without policy.
+func (f *feeAccruer) snapshot() *FeeSnapshot {
+
+	if f == nil {
+		return nil
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.state()
+}
+
+// restore sets the state of the accruals of a snapshot or of a WALFee entry, so the accruals of a restored
+// session continue from the persisted high-water mark instead of the equity at its start.
+func (f *feeAccruer) restore(s *FeeSnapshot) {
+
+	if f == nil || s == nil {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.highWaterMark = NewDecimal(s.HighWaterMark)
+	f.managementFees = NewDecimal(s.ManagementFees)
+	f.performanceFees = NewDecimal(s.PerformanceFees)
+	f.last, f.next = s.LastAccrual, s.NextAccrual
+	f.recorded = s.Recorded
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// FeeAccruals returns the fees accrued by the ManagedFees policy of the session, zero without policy.
+func (a *Account) FeeAccruals() FeeAccruals {
+
+	if a.fees == nil {
+		return FeeAccruals{}
+	}
+
+	a.fees.mutex.Lock()
+	defer a.fees.mutex.Unlock()
+
+	return FeeAccruals{
+		HighWaterMark:   a.fees.highWaterMark.Float64(),
+		ManagementFees:  a.fees.managementFees.Float64(),
+		PerformanceFees: a.fees.performanceFees.Float64(),
+		GrossEquity:     a.Equity() + a.fees.managementFees.Add(a.fees.performanceFees).Float64(),
+		LastAccrual:     a.fees.last,
+		NextAccrual:     a.fees.next,
+	}
+}
@@ -0,0 +1,198 @@
+package manager
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Sizing returns the units of a follower trade copying a master trade, zero to skip it.
+type Sizing interface {
+	Units(master, follower *gotrader.Account, units int32) int32
+}
+
+// FixedUnits is a Sizing copying every master trade with the same units.
+type FixedUnits int32
+
+// Units implements Sizing.
+func (s FixedUnits) Units(master, follower *gotrader.Account, units int32) int32 {
+	return int32(s)
+}
+
+// Proportional is a Sizing scaling the units of the master trades by the ratio of the follower and master
+// equities, times its value, e.g. 1 to copy them with the same leverage.
+type Proportional float64
+
+// Units implements Sizing.
+func (s Proportional) Units(master, follower *gotrader.Account, units int32) int32 {
+
+	equity := master.Equity()
+	if equity <= 0 {
+		return 0
+	}
+
+	return int32(float64(s) * float64(units) * follower.Equity() / equity)
+}
+
+const copyTag = "copy:"
+
+/*
+copier mirrors the trades of a master account to a follower one. The follower orders are tagged with the master
+trade they copy, so their fills are matched to it: a copy rejected by the follower is forgotten, a copy filled
+in several trades is closed with all of them, and the fills arriving after the master trade was closed are
+closed when they arrive.
+*/
+type copier struct {
+	master   *account
+	follower *account
+	sizing   Sizing
+	prefix   string
+	mutex    *sync.Mutex
+	copies   map[string]*copied // by master trade ID
+}
+
+// copied is the copy of a master trade by a follower.
+type copied struct {
+	instrument string
+	units      int32 // expected, the filled ones after a rejection
+	filled     int32
+	trades     []string
+	closed     bool
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newCopier(master, follower *account, sizing Sizing) *copier {
+	return &copier{
+		master:   master,
+		follower: follower,
+		sizing:   sizing,
+		prefix:   copyTag + master.name + ":",
+		mutex:    &sync.Mutex{},
+		copies:   make(map[string]*copied),
+	}
+}
+
+// onMasterFill copies the opens and the closes of the master trades.
+func (c *copier) onMasterFill(fill *gotrader.OrderFill) {
+
+	if fill.Error != "" || !c.follower.started.Load() {
+		return
+	}
+
+	if fill.TradeClose {
+		c.close(fill.TradeID)
+		return
+	}
+
+	c.open(fill)
+}
+
+func (c *copier) open(fill *gotrader.OrderFill) {
+
+	units := c.sizing.Units(c.master.session.Account(), c.follower.session.Account(), fill.Units)
+	if units <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	c.copies[fill.TradeID] = &copied{instrument: fill.Instrument.Name, units: units}
+	c.mutex.Unlock()
+
+	_, err := c.follower.guarded.SubmitOrder(&gotrader.Order{
+		Type:       gotrader.MarketOrder,
+		Instrument: fill.Instrument.Name,
+		Side:       fill.Side,
+		Units:      units,
+		Tag:        c.prefix + fill.TradeID,
+	})
+
+	if err != nil {
+		c.mutex.Lock()
+		delete(c.copies, fill.TradeID)
+		c.mutex.Unlock()
+
+		c.follower.logger.Warnf("account %s: copy of %s trade %s rejected: %v", c.follower.name, c.master.name, fill.TradeID, err)
+	}
+}
+
+func (c *copier) close(masterID string) {
+
+	c.mutex.Lock()
+
+	cp, exist := c.copies[masterID]
+	if !exist {
+		c.mutex.Unlock()
+		return
+	}
+
+	cp.closed = true
+	trades := cp.trades
+	cp.trades = nil
+
+	if cp.filled >= cp.units {
+		delete(c.copies, masterID)
+	}
+
+	c.mutex.Unlock()
+
+	for _, id := range trades {
+		c.closeCopy(cp.instrument, id)
+	}
+}
+
+func (c *copier) closeCopy(instrument, id string) {
+	if err := c.follower.engine.CloseTrade(instrument, id); err != nil && !errors.Is(err, gotrader.ErrTradeNotFound) {
+		c.follower.logger.Errorf("account %s: closing the copy %s: %v", c.follower.name, id, err)
+	}
+}
+
+// onFollowerFill matches the fills of the copies to their master trades.
+func (c *copier) onFollowerFill(fill *gotrader.OrderFill) {
+
+	if fill.TradeClose || !strings.HasPrefix(fill.Tag, c.prefix) {
+		return
+	}
+
+	masterID := strings.TrimPrefix(fill.Tag, c.prefix)
+
+	c.mutex.Lock()
+
+	cp, exist := c.copies[masterID]
+	if !exist {
+		c.mutex.Unlock()
+		return
+	}
+
+	if fill.Error != "" { // no more fills, the copy is kept with the filled units
+		cp.units = cp.filled
+		if cp.filled == 0 || cp.closed {
+			delete(c.copies, masterID)
+		}
+		c.mutex.Unlock()
+
+		c.follower.logger.Warnf("account %s: copy of %s trade %s rejected: %s", c.follower.name, c.master.name, masterID, fill.Error)
+		return
+	}
+
+	cp.filled += fill.Units
+	late := cp.closed
+
+	if !late {
+		cp.trades = append(cp.trades, fill.TradeID)
+	} else if cp.filled >= cp.units {
+		delete(c.copies, masterID)
+	}
+
+	c.mutex.Unlock()
+
+	if late {
+		c.closeCopy(fill.Instrument.Name, fill.TradeID)
+	}
+}
@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+/*
+Feed shares the price subscriptions of a market data client between the accounts of a manager, so an instrument
+traded by several accounts is subscribed once, with the account of the feed, and its ticks are copied to every
+session subscribing it. The orders and the notifications of an account are still sent to its own client.
+*/
+type Feed struct {
+	client     gotrader.BrokerClient
+	accountID  string
+	mutex      *sync.RWMutex
+	handlers   map[string][]gotrader.TickHandler // by instrument
+	subscribed map[string]bool
+}
+
+// NewFeed is the Feed constructor, the prices of the client are subscribed with the account ID.
+func NewFeed(client gotrader.BrokerClient, accountID string) *Feed {
+	return &Feed{
+		client:     client,
+		accountID:  accountID,
+		mutex:      &sync.RWMutex{},
+		handlers:   make(map[string][]gotrader.TickHandler),
+		subscribed: make(map[string]bool),
+	}
+}
+
+// Client returns the client of an account subscribing its prices from the feed, a gotrader.Broker when the
+// client is one. The other optional interfaces of the client, e.g. gotrader.Reconnector, are not forwarded.
+func (f *Feed) Client(client gotrader.BrokerClient) gotrader.BrokerClient {
+
+	if broker, isBroker := client.(gotrader.Broker); isBroker {
+		return &feedBroker{Broker: broker, feed: f}
+	}
+
+	return &feedClient{BrokerClient: client, feed: f}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// subscribe adds the handler of a session to the instruments, subscribing the instruments not yet subscribed.
+func (f *Feed) subscribe(instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+
+	f.mutex.Lock()
+
+	missing := make([]gotrader.InstrumentDetails, 0)
+	for _, inst := range instruments {
+
+		f.handlers[inst.Name] = append(f.handlers[inst.Name], callback)
+
+		if !f.subscribed[inst.Name] {
+			f.subscribed[inst.Name] = true
+			missing = append(missing, inst)
+		}
+	}
+
+	f.mutex.Unlock()
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// the mutex is released, the client may stream the first ticks before returning
+	if err := f.client.SubscribePrices(f.accountID, missing, f.dispatch); err != nil {
+
+		f.mutex.Lock()
+		for _, inst := range missing {
+			delete(f.subscribed, inst.Name)
+		}
+		f.mutex.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// dispatch copies a tick to the sessions subscribing its instrument, every session owns its copy.
+func (f *Feed) dispatch(tick *gotrader.Tick) {
+
+	if tick == nil {
+		return
+	}
+
+	f.mutex.RLock()
+	handlers := f.handlers[tick.Instrument]
+	f.mutex.RUnlock()
+
+	for _, handler := range handlers {
+
+		t := gotrader.AcquireTick()
+		t.Instrument = tick.Instrument
+		t.Bid, t.Ask = tick.Bid, tick.Ask
+		t.BidSize, t.AskSize = tick.BidSize, tick.AskSize
+		t.Time = tick.Time
+		t.Sequence = tick.Sequence
+
+		handler(t)
+	}
+}
+
+type feedClient struct {
+	gotrader.BrokerClient
+	feed *Feed
+}
+
+// SubscribePrices implements gotrader.BrokerClient.
+func (c *feedClient) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+	return c.feed.subscribe(instruments, callback)
+}
+
+type feedBroker struct {
+	gotrader.Broker
+	feed *Feed
+}
+
+// SubscribePrices implements gotrader.BrokerClient.
+func (b *feedBroker) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails, callback gotrader.TickHandler) error {
+	return b.feed.subscribe(instruments, callback)
+}
@@ -0,0 +1,471 @@
+/*
+Package manager runs many independent trading sessions in one process, e.g. the accounts of several clients,
+each with its own instruments, broker client and risk limits. The accounts can share the price subscriptions of
+a market data client with a Feed, mirror the trades of a master account with Copy, and their state is
+aggregated by Summary. A pooled account is split between its investors by a Pool.
+*/
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/report"
+	"go.uber.org/atomic"
+)
+
+// ErrHalted is returned to the strategy of an account halted by its risk limits when it opens a trade.
+var ErrHalted = errors.New("account halted by its risk limits")
+
+// Option represents an account registration functional option
+type Option func(a *account)
+
+// Limits is the functional option to define the risk limits of an account.
+func Limits(limits RiskLimits) Option {
+	return func(a *account) {
+		a.limits = limits
+	}
+}
+
+/*
+RiskLimits are the per account limits checked on every tick, zero values are not checked. When a limit is
+breached the open trades of the account are closed and its strategy can no longer open trades, the session keeps
+running until the manager stops.
+*/
+type RiskLimits struct {
+	MaxDrawdown float64 // maximum drawdown of the equity from its peak, as a fraction
+	MinEquity   float64 // in the home currency of the account
+}
+
+// Manager runs the sessions of several accounts.
+type Manager struct {
+	mutex    *sync.RWMutex
+	logger   gotrader.Logger
+	accounts map[string]*account
+	order    []string
+	running  bool
+}
+
+// account is a session registered in the manager, its strategy guarded by the risk limits.
+type account struct {
+	name     string
+	session  *gotrader.TradingSession
+	strategy gotrader.Strategy
+	limits   RiskLimits
+	logger   gotrader.Logger
+	engine   gotrader.Engine
+	guarded  gotrader.Engine
+	copiers  []*copier // of the account trades, as master or follower
+	peak     float64
+	started  *atomic.Bool
+	halted   *atomic.Bool
+	stop     sync.Once
+	err      error
+}
+
+// New is the Manager constructor, a nil logger defaults to gotrader.DefaultLogger.
+func New(logger gotrader.Logger) *Manager {
+
+	if logger == nil {
+		logger = gotrader.DefaultLogger()
+	}
+
+	return &Manager{
+		mutex:    &sync.RWMutex{},
+		logger:   logger,
+		accounts: make(map[string]*account),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (a *account) Initialize() {
+	a.strategy.Initialize()
+	a.started.Store(true)
+}
+
+func (a *account) SetEngine(engine gotrader.Engine) {
+	a.engine = engine
+	a.guarded = &accountEngine{Engine: engine, account: a}
+	a.strategy.SetEngine(a.guarded)
+}
+
+func (a *account) OnOrderFill(orderFill *gotrader.OrderFill) {
+
+	a.strategy.OnOrderFill(orderFill)
+
+	for _, c := range a.copiers {
+		if c.master == a {
+			c.onMasterFill(orderFill)
+		} else {
+			c.onFollowerFill(orderFill)
+		}
+	}
+}
+
+func (a *account) OnOrderRejected(fill *gotrader.OrderFill, reason gotrader.RejectReason) {
+	if handler, ok := a.strategy.(gotrader.RejectionHandler); ok {
+		handler.OnOrderRejected(fill, reason)
+	}
+}
+
+func (a *account) OnTick(tick *gotrader.Tick) {
+	a.strategy.OnTick(tick)
+	a.check()
+}
+
+func (a *account) OnStop() {
+	a.strategy.OnStop()
+}
+
+// check halts the account when its equity breaches the risk limits.
+func (a *account) check() {
+
+	if a.halted.Load() {
+		return
+	}
+
+	equity := a.engine.Account().Equity()
+	if equity > a.peak {
+		a.peak = equity
+	}
+
+	var breach string
+
+	if a.limits.MinEquity != 0 && equity < a.limits.MinEquity {
+		breach = fmt.Sprintf("equity %.2f below %.2f", equity, a.limits.MinEquity)
+	}
+
+	if a.limits.MaxDrawdown != 0 && a.peak > 0 && (a.peak-equity)/a.peak > a.limits.MaxDrawdown {
+		breach = fmt.Sprintf("drawdown %.2f%% above %.2f%%", 100*(a.peak-equity)/a.peak, 100*a.limits.MaxDrawdown)
+	}
+
+	if breach != "" {
+		a.halt(breach)
+	}
+}
+
+// halt closes the open trades of the account and rejects the next opens of its strategy.
+func (a *account) halt(reason string) {
+
+	a.halted.Store(true)
+	a.logger.Warnf("account %s halted: %s", a.name, reason)
+
+	for name, inst := range a.engine.Account().Instruments() {
+
+		ids := make([]string, 0, inst.TradesNumber())
+		inst.RangeTradesByAscendingOrder(-1, func(trade *gotrader.Trade) bool { // oldest first, for the FIFO rules
+			ids = append(ids, trade.ID())
+			return true
+		})
+
+		for _, id := range ids {
+			if err := a.engine.CloseTrade(name, id); err != nil {
+				a.logger.Errorf("account %s: closing trade %s: %v", a.name, id, err)
+			}
+		}
+	}
+}
+
+/*
+accountEngine is the engine given to the strategy of an account, it rejects the opens of a halted account.
+*/
+type accountEngine struct {
+	gotrader.Engine
+	account *account
+}
+
+func (e *accountEngine) Buy(instrument string, units int32) error {
+
+	if e.account.halted.Load() {
+		return fmt.Errorf("%s: %w", e.account.name, ErrHalted)
+	}
+
+	return e.Engine.Buy(instrument, units)
+}
+
+func (e *accountEngine) Sell(instrument string, units int32) error {
+
+	if e.account.halted.Load() {
+		return fmt.Errorf("%s: %w", e.account.name, ErrHalted)
+	}
+
+	return e.Engine.Sell(instrument, units)
+}
+
+func (e *accountEngine) SubmitOrder(order *gotrader.Order) (string, error) {
+
+	if e.account.halted.Load() {
+		return "", fmt.Errorf("%s: %w", e.account.name, ErrHalted)
+	}
+
+	return e.Engine.SubmitOrder(order)
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Add registers the session of an account with the strategy it trades, the session client and engine type
+// must be set. The accounts are added before Run.
+func (m *Manager) Add(name string, session *gotrader.TradingSession, strategy gotrader.Strategy, opts ...Option) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.running {
+		return errors.New("manager is running")
+	}
+
+	if _, exist := m.accounts[name]; exist {
+		return errors.New("account " + name + " already registered")
+	}
+
+	if session.Engine() == nil {
+		return errors.New("account " + name + ": engine type is not defined")
+	}
+
+	a := &account{
+		name:     name,
+		session:  session,
+		strategy: strategy,
+		logger:   m.logger,
+		started:  atomic.NewBool(false),
+		halted:   atomic.NewBool(false),
+	}
+
+	for _, o := range opts {
+		o(a)
+	}
+
+	session.SetStrategy(a)
+	m.accounts[name] = a
+	m.order = append(m.order, name)
+
+	return nil
+}
+
+/*
+Copy mirrors the opens and the closes of the trades of the master account to the follower one, sized by the
+sizing, before Run. The copies are market orders tagged with the master trade, the client of the follower must
+be a gotrader.Broker to keep their tags, and are closed with their master trade. The follower opens are subject
+to its risk limits.
+*/
+func (m *Manager) Copy(master, follower string, sizing Sizing) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.running {
+		return errors.New("manager is running")
+	}
+
+	from, exist := m.accounts[master]
+	if !exist {
+		return errors.New("unknown account " + master)
+	}
+
+	to, exist := m.accounts[follower]
+	if !exist {
+		return errors.New("unknown account " + follower)
+	}
+
+	if from == to {
+		return errors.New("account " + master + " can not copy itself")
+	}
+
+	c := newCopier(from, to, sizing)
+	from.copiers = append(from.copiers, c)
+	to.copiers = append(to.copiers, c)
+
+	return nil
+}
+
+// Run starts the sessions of the accounts, each in its own goroutine, and waits for them to stop, returning
+// their errors.
+func (m *Manager) Run() error {
+
+	m.mutex.Lock()
+	if m.running {
+		m.mutex.Unlock()
+		return errors.New("manager is running")
+	}
+	m.running = true
+	accounts := make([]*account, 0, len(m.order))
+	for _, name := range m.order {
+		accounts = append(accounts, m.accounts[name])
+	}
+	m.mutex.Unlock()
+
+	wg := &sync.WaitGroup{}
+
+	for _, a := range accounts {
+
+		wg.Add(1)
+
+		go func(a *account) {
+			defer wg.Done()
+
+			if err := a.session.Start(); err != nil {
+				a.err = fmt.Errorf("account %s: %w", a.name, err)
+				m.logger.Error(a.err)
+			}
+		}(a)
+	}
+
+	wg.Wait()
+
+	m.mutex.Lock()
+	m.running = false
+	m.mutex.Unlock()
+
+	errs := make([]error, 0)
+	for _, a := range accounts {
+		if a.err != nil {
+			errs = append(errs, a.err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stop gracefully stops the sessions of the accounts that started.
+func (m *Manager) Stop() {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, a := range m.accounts {
+		if a.started.Load() {
+			a.stop.Do(a.session.Engine().StopSession)
+		}
+	}
+}
+
+// Names returns the names of the accounts in registration order.
+func (m *Manager) Names() []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]string(nil), m.order...)
+}
+
+// Account returns the account of a session, nil until the session has started.
+func (m *Manager) Account(name string) *gotrader.Account {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	a, exist := m.accounts[name]
+	if !exist || !a.started.Load() {
+		return nil
+	}
+
+	return a.session.Account()
+}
+
+// Halted returns whether an account has been halted by its risk limits.
+func (m *Manager) Halted(name string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	a, exist := m.accounts[name]
+
+	return exist && a.halted.Load()
+}
+
+// AccountSummary is the state of an account, in its home currency.
+type AccountSummary struct {
+	Name           string
+	ID             string
+	HomeCurrency   string
+	Balance        float64
+	Equity         float64
+	MarginUsed     float64
+	RealizedProfit float64
+	OpenTrades     int
+	Halted         bool
+}
+
+// Summary is the state of the accounts of a manager and their totals by home currency.
+type Summary struct {
+	Accounts []AccountSummary
+	Totals   []AccountSummary // by home currency, without name, ID and halt
+}
+
+// Summary returns the state of the started accounts.
+func (m *Manager) Summary() Summary {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	summary := Summary{}
+	totals := make(map[string]*AccountSummary)
+
+	for _, name := range m.order {
+
+		a := m.accounts[name]
+		if !a.started.Load() {
+			continue
+		}
+
+		acc := a.session.Account()
+
+		s := AccountSummary{
+			Name:           name,
+			ID:             acc.ID(),
+			HomeCurrency:   acc.HomeCurrency(),
+			Balance:        acc.Balance(),
+			Equity:         acc.Equity(),
+			MarginUsed:     acc.MarginUsed(),
+			RealizedProfit: acc.Ledger().RealizedProfit(),
+			Halted:         a.halted.Load(),
+		}
+
+		for _, inst := range acc.Instruments() {
+			s.OpenTrades += int(inst.TradesNumber())
+		}
+
+		summary.Accounts = append(summary.Accounts, s)
+
+		total, exist := totals[s.HomeCurrency]
+		if !exist {
+			total = &AccountSummary{HomeCurrency: s.HomeCurrency}
+			totals[s.HomeCurrency] = total
+		}
+
+		total.Balance += s.Balance
+		total.Equity += s.Equity
+		total.MarginUsed += s.MarginUsed
+		total.RealizedProfit += s.RealizedProfit
+		total.OpenTrades += s.OpenTrades
+	}
+
+	for _, total := range totals {
+		summary.Totals = append(summary.Totals, *total)
+	}
+
+	sort.Slice(summary.Totals, func(i, j int) bool { return summary.Totals[i].HomeCurrency < summary.Totals[j].HomeCurrency })
+
+	return summary
+}
+
+// Reports returns the performance reports of the started accounts, by name.
+func (m *Manager) Reports() map[string]*report.Report {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	reports := make(map[string]*report.Report, len(m.accounts))
+
+	for name, a := range m.accounts {
+		if a.started.Load() {
+			reports[name] = report.New(a.session.Account())
+		}
+	}
+
+	return reports
+}
@@ -0,0 +1,383 @@
+package manager
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/gotradertest"
+	"go.uber.org/atomic"
+)
+
+type counter struct {
+	engine gotrader.Engine
+	buy    bool // on every tick
+	ticks  *atomic.Int32
+	err    *atomic.Error
+}
+
+func (c *counter) Initialize()                               {}
+func (c *counter) SetEngine(engine gotrader.Engine)          { c.engine = engine }
+func (c *counter) OnOrderFill(orderFill *gotrader.OrderFill) {}
+func (c *counter) OnStop()                                   {}
+
+func (c *counter) OnTick(tick *gotrader.Tick) {
+	c.ticks.Inc()
+	if c.buy {
+		c.err.Store(c.engine.Buy(tick.Instrument, 1))
+	}
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(gotradertest.Timeout)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManager(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30}}
+
+	prices := gotradertest.NewBroker(instruments)
+	feed := NewFeed(prices, "prices")
+
+	brokers := map[string]*gotradertest.Broker{
+		"a": gotradertest.NewBroker(instruments, gotradertest.Currency("EUR"), gotradertest.Leverage(30)),
+		"b": gotradertest.NewBroker(instruments, gotradertest.Currency("EUR"), gotradertest.Leverage(30)),
+	}
+
+	for _, broker := range brokers {
+		broker.Quote("EUR_USD", 1.0, 1.0) // the fills are priced by the account brokers
+	}
+
+	brokers["a"].OpenTrade(gotrader.TradeDetails{
+		ID:         "1",
+		Instrument: instruments[0],
+		Side:       gotrader.Long,
+		Units:      10000,
+		OpenPrice:  1.1,
+	})
+
+	m := New(nil)
+	strategies := make(map[string]*counter)
+
+	for _, name := range []string{"a", "b"} {
+
+		session := gotrader.NewTradingSession(gotrader.AccountID(name), gotrader.Instruments([]string{"EUR_USD"})).
+			SetClient(feed.Client(brokers[name])).Live()
+
+		strategies[name] = &counter{buy: true, ticks: atomic.NewInt32(0), err: atomic.NewError(nil)}
+
+		if err := m.Add(name, session, strategies[name], Limits(RiskLimits{MaxDrawdown: 0.005})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.Add("a", gotrader.NewTradingSession().Live(), &counter{}); err == nil {
+		t.Error("expected an error registering an account twice")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run() }()
+
+	waitFor(t, "the sessions to start", func() bool { return m.Account("a") != nil && m.Account("b") != nil })
+
+	t.Run("prices are shared", func(t *testing.T) {
+
+		waitFor(t, "the ticks", func() bool {
+			prices.Tick("EUR_USD", 1.1, 1.1)
+			return strategies["a"].ticks.Load() > 0 && strategies["b"].ticks.Load() > 0
+		})
+
+		if s := prices.Subscribed(); len(s) != 1 || s[0] != "EUR_USD" {
+			t.Errorf("expected a single subscription of the feed, got %v", s)
+		}
+
+		if len(brokers["a"].Subscribed()) != 0 || len(brokers["b"].Subscribed()) != 0 {
+			t.Error("expected the account clients not to subscribe prices")
+		}
+	})
+
+	t.Run("accounts breaching their limits are halted", func(t *testing.T) {
+
+		waitFor(t, "the halt", func() bool {
+			prices.Tick("EUR_USD", 1.0, 1.0)
+			return m.Halted("a")
+		})
+
+		closed := false
+		for _, r := range brokers["a"].Requests() {
+			closed = closed || (r.Type == gotradertest.CloseTradeRequest && r.TradeID == "1")
+		}
+
+		if !closed {
+			t.Error("expected the open trades to be closed")
+		}
+
+		ticks := strategies["a"].ticks.Load()
+		waitFor(t, "a tick", func() bool {
+			prices.Tick("EUR_USD", 1.0, 1.0)
+			return strategies["a"].ticks.Load() > ticks
+		})
+
+		if err := strategies["a"].err.Load(); !errors.Is(err, ErrHalted) {
+			t.Errorf("expected the opens to be rejected, got %v", err)
+		}
+
+		if m.Halted("b") {
+			t.Error("expected the other account to keep trading")
+		}
+	})
+
+	t.Run("accounts are summarized", func(t *testing.T) {
+
+		summary := m.Summary()
+
+		if len(summary.Accounts) != 2 || summary.Accounts[0].Name != "a" || !summary.Accounts[0].Halted {
+			t.Errorf("unexpected accounts %+v", summary.Accounts)
+		}
+
+		if len(summary.Totals) != 1 || summary.Totals[0].HomeCurrency != "EUR" ||
+			summary.Totals[0].Balance != summary.Accounts[0].Balance+summary.Accounts[1].Balance {
+			t.Errorf("unexpected totals %+v", summary.Totals)
+		}
+
+		if len(m.Reports()) != 2 {
+			t.Error("expected a report by account")
+		}
+	})
+
+	m.Stop()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(gotradertest.Timeout):
+		t.Fatal("the sessions did not stop")
+	}
+}
+
+func TestCopier(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30}}
+
+	prices := gotradertest.NewBroker(instruments)
+	feed := NewFeed(prices, "prices")
+
+	m := New(nil)
+	brokers := make(map[string]*gotradertest.Broker)
+	strategies := make(map[string]*counter)
+
+	for _, name := range []string{"master", "proportional", "fixed"} {
+
+		brokers[name] = gotradertest.NewBroker(instruments, gotradertest.Currency("EUR"), gotradertest.Leverage(30))
+		brokers[name].Quote("EUR_USD", 1.1, 1.1)
+
+		session := gotrader.NewTradingSession(gotrader.AccountID(name), gotrader.Instruments([]string{"EUR_USD"})).
+			SetClient(feed.Client(brokers[name])).Live()
+
+		strategies[name] = &counter{ticks: atomic.NewInt32(0), err: atomic.NewError(nil)}
+
+		if err := m.Add(name, session, strategies[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.Copy("master", "proportional", Proportional(0.5)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Copy("master", "fixed", FixedUnits(5)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Copy("master", "unknown", FixedUnits(5)); err == nil {
+		t.Error("expected an error copying to an unknown account")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- m.Run() }()
+
+	waitFor(t, "the sessions to start", func() bool {
+		for _, name := range m.Names() {
+			if m.Account(name) == nil {
+				return false
+			}
+		}
+		return true
+	})
+
+	waitFor(t, "the ticks", func() bool {
+		prices.Tick("EUR_USD", 1.1, 1.1)
+		for _, s := range strategies {
+			if s.ticks.Load() == 0 {
+				return false
+			}
+		}
+		return true
+	})
+
+	trades := func(name string) int32 {
+		return m.Account(name).Instrument("EUR_USD").TradesNumber()
+	}
+
+	master := strategies["master"].engine
+
+	t.Run("opens are copied with their sizing", func(t *testing.T) {
+
+		if err := master.Buy("EUR_USD", 100); err != nil {
+			t.Fatal(err)
+		}
+
+		waitFor(t, "the copies", func() bool { return trades("proportional") == 1 && trades("fixed") == 1 })
+
+		for name, units := range map[string]int32{"proportional": 50, "fixed": 5} {
+
+			requests := brokers[name].Requests()
+			r := requests[len(requests)-1]
+
+			if r.Type != gotradertest.SubmitOrderRequest || r.Units != units || !strings.HasPrefix(r.Order.Tag, "copy:master:") {
+				t.Errorf("unexpected %s copy %+v", name, r)
+			}
+		}
+	})
+
+	t.Run("rejected and late copies do not desync", func(t *testing.T) {
+
+		brokers["proportional"].Reject("INSUFFICIENT_MARGIN")
+		brokers["fixed"].Program(gotradertest.Response{Latency: 100 * time.Millisecond})
+
+		if err := master.Sell("EUR_USD", 10); err != nil {
+			t.Fatal(err)
+		}
+
+		waitFor(t, "the master trades", func() bool { return trades("master") == 2 })
+		waitFor(t, "the copy requests", func() bool { return len(brokers["fixed"].Requests()) == 2 })
+
+		trade := m.Account("master").Instrument("EUR_USD").ShortPosition().TradeByOrder(0)
+		if err := master.CloseTrade("EUR_USD", trade.ID()); err != nil {
+			t.Fatal(err)
+		}
+
+		waitFor(t, "the late copy to be closed", func() bool {
+			requests := brokers["fixed"].Requests()
+			return len(requests) == 3 && requests[2].Type == gotradertest.CloseTradeRequest && trades("fixed") == 1
+		})
+
+		if trades("proportional") != 1 || trades("fixed") != 1 {
+			t.Errorf("expected the first copies to be kept, got %d and %d", trades("proportional"), trades("fixed"))
+		}
+	})
+
+	t.Run("closes are copied", func(t *testing.T) {
+
+		trade := m.Account("master").Instrument("EUR_USD").LongPosition().TradeByOrder(0)
+		if err := master.CloseTrade("EUR_USD", trade.ID()); err != nil {
+			t.Fatal(err)
+		}
+
+		waitFor(t, "the copies to be closed", func() bool { return trades("proportional") == 0 && trades("fixed") == 0 })
+
+		for _, c := range m.accounts["master"].copiers {
+			c.mutex.Lock()
+			if len(c.copies) != 0 {
+				t.Errorf("expected no copies, got %d", len(c.copies))
+			}
+			c.mutex.Unlock()
+		}
+	})
+
+	m.Stop()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(gotradertest.Timeout):
+		t.Fatal("the sessions did not stop")
+	}
+}
+
+func TestPool(t *testing.T) {
+
+	t.Run("profits are split by equity share without residuals", func(t *testing.T) {
+
+		pool := NewPool(EquityShare, 2)
+
+		for _, name := range []string{"a", "b", "c"} {
+			if err := pool.Deposit(name, 100); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		pool.Allocate(&gotrader.Transaction{Type: gotrader.TradeCloseTransaction, Amount: 1, Fees: -0.1})
+		pool.Allocate(&gotrader.Transaction{Type: gotrader.FundsTransferTransaction, Amount: 1000})
+
+		investors := pool.Investors(-0.02)
+
+		profit, fees, unrealized := 0.0, 0.0, 0.0
+		for _, inv := range investors {
+			profit += inv.RealizedProfit
+			fees += inv.Fees
+			unrealized += inv.UnrealizedProfit
+		}
+
+		if math.Abs(profit-1) > 1e-9 || math.Abs(fees+0.1) > 1e-9 || math.Abs(unrealized+0.02) > 1e-9 {
+			t.Errorf("expected the parts to sum to the pooled amounts, got %v, %v and %v", profit, fees, unrealized)
+		}
+
+		if investors[0].RealizedProfit != 0.34 || investors[1].RealizedProfit != 0.33 || investors[0].Balance != 100.34 {
+			t.Errorf("unexpected allocation %+v", investors)
+		}
+
+		if split := pool.Split(10); split["a"] != 4 || split["b"] != 3 || split["c"] != 3 {
+			t.Errorf("unexpected units %v", split)
+		}
+
+		if err := pool.Withdraw("b", 100.33); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := pool.Withdraw("c", 1000); err == nil {
+			t.Error("expected an error withdrawing more than the balance")
+		}
+
+		pool.Allocate(&gotrader.Transaction{Type: gotrader.FinancingTransaction, Amount: -2})
+
+		if investors := pool.Investors(0); investors[1].Balance != 0 || investors[0].Balance != 99.34 {
+			t.Errorf("expected the financing to be allocated to the remaining investors, got %+v", investors)
+		}
+	})
+
+	t.Run("profits are split by lot multiplier", func(t *testing.T) {
+
+		pool := NewPool(LotMultiplier, 2)
+
+		_ = pool.Deposit("a", 1000)
+		_ = pool.Deposit("b", 100)
+
+		if err := pool.SetMultiplier("b", 2); err != nil {
+			t.Fatal(err)
+		}
+
+		pool.Allocate(&gotrader.Transaction{Type: gotrader.TradeCloseTransaction, Amount: -3})
+
+		if investors := pool.Investors(0); investors[0].RealizedProfit != -1 || investors[1].RealizedProfit != -2 {
+			t.Errorf("unexpected allocation %+v", investors)
+		}
+	})
+}
@@ -0,0 +1,334 @@
+package manager
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Allocation is the way a Pool splits the positions and the profits of its account between its investors.
+type Allocation int
+
+const (
+	EquityShare   Allocation = iota // PAMM, by the balance of the investors
+	LotMultiplier                   // LAMM, by the multipliers of the investors
+)
+
+/*
+Pool splits a pooled account (PAMM or LAMM) between its investors: their deposits and withdrawals are recorded
+with Deposit and Withdraw, and the profits, financing and fees of the ledger of the account are allocated with
+Update by the share of each investor when they are realized. The amounts are allocated in the minor units of
+the home currency, the residual units of the rounding going to the largest remainders, so the investor parts
+always sum to the pooled amounts. It is safe for concurrent use.
+*/
+type Pool struct {
+	mutex       *sync.RWMutex
+	allocation  Allocation
+	scale       float64 // minor units by currency unit
+	investors   map[string]*investor
+	order       []string
+	processed   int // ledger transactions allocated
+	unallocated int64
+}
+
+type investor struct {
+	deposited  int64
+	withdrawn  int64
+	balance    int64
+	profit     int64
+	fees       int64
+	multiplier float64
+}
+
+// Investor is the allocated state of an investor of a Pool, in the home currency of its account.
+type Investor struct {
+	Name             string
+	Share            float64
+	Multiplier       float64 // LAMM
+	Deposited        float64
+	Withdrawn        float64
+	Balance          float64
+	RealizedProfit   float64 // including the fees
+	Fees             float64
+	UnrealizedProfit float64
+	Equity           float64
+}
+
+// NewPool is the Pool constructor, the precision is the number of decimals of the home currency, e.g. 2.
+func NewPool(allocation Allocation, precision int) *Pool {
+	return &Pool{
+		mutex:      &sync.RWMutex{},
+		allocation: allocation,
+		scale:      math.Pow10(precision),
+		investors:  make(map[string]*investor),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (p *Pool) units(amount float64) int64 {
+	return int64(math.Round(amount * p.scale))
+}
+
+func (p *Pool) amount(units int64) float64 {
+	return float64(units) / p.scale
+}
+
+func (p *Pool) investor(name string) *investor {
+
+	inv, exist := p.investors[name]
+	if !exist {
+		inv = &investor{multiplier: 1}
+		p.investors[name] = inv
+		p.order = append(p.order, name)
+	}
+
+	return inv
+}
+
+// weights returns the weights of the investors, by investor order, and their sum.
+func (p *Pool) weights() ([]float64, float64) {
+
+	weights := make([]float64, len(p.order))
+	total := 0.0
+
+	for i, name := range p.order {
+
+		inv := p.investors[name]
+		if inv.balance <= 0 {
+			continue
+		}
+
+		weights[i] = float64(inv.balance)
+		if p.allocation == LotMultiplier {
+			weights[i] = inv.multiplier
+		}
+
+		total += weights[i]
+	}
+
+	return weights, total
+}
+
+/*
+split divides an amount by the weights with the largest remainder method: every part is rounded down and the
+residual units go to the largest remainders, by investor order on ties, so the parts sum to the amount.
+*/
+func split(amount int64, weights []float64, total float64) []int64 {
+
+	parts := make([]int64, len(weights))
+	if amount == 0 || total <= 0 {
+		return parts
+	}
+
+	sign := int64(1)
+	if amount < 0 {
+		sign, amount = -1, -amount
+	}
+
+	remainders := make([]float64, len(weights))
+	residual := amount
+
+	for i, w := range weights {
+		exact := float64(amount) * w / total
+		parts[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(parts[i])
+		residual -= parts[i]
+	}
+
+	indexes := make([]int, len(weights))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool { return remainders[indexes[i]] > remainders[indexes[j]] })
+
+	for i := 0; residual > 0 && i < len(indexes); i++ {
+		if weights[indexes[i]] > 0 {
+			parts[indexes[i]]++
+			residual--
+		}
+	}
+
+	for i := range parts {
+		parts[i] *= sign
+	}
+
+	return parts
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Deposit adds the deposit of an investor, registering the new investors.
+func (p *Pool) Deposit(name string, amount float64) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if amount <= 0 {
+		return errors.New("deposit must be positive")
+	}
+
+	inv := p.investor(name)
+	inv.deposited += p.units(amount)
+	inv.balance += p.units(amount)
+
+	return nil
+}
+
+// Withdraw records the withdrawal of an investor, at most its balance.
+func (p *Pool) Withdraw(name string, amount float64) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	inv, exist := p.investors[name]
+	if !exist {
+		return errors.New("unknown investor " + name)
+	}
+
+	units := p.units(amount)
+	if units <= 0 || units > inv.balance {
+		return errors.New("invalid withdrawal of investor " + name)
+	}
+
+	inv.withdrawn += units
+	inv.balance -= units
+
+	return nil
+}
+
+// SetMultiplier sets the multiplier of an investor of a LotMultiplier pool, 1 by default.
+func (p *Pool) SetMultiplier(name string, multiplier float64) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	inv, exist := p.investors[name]
+	if !exist {
+		return errors.New("unknown investor " + name)
+	}
+
+	if multiplier < 0 {
+		return errors.New("multiplier must not be negative")
+	}
+
+	inv.multiplier = multiplier
+
+	return nil
+}
+
+// Allocate splits a realized transaction of the account between the investors, the external transactions, e.g.
+// the funds transfers, are not allocated since the investor deposits and withdrawals are recorded by the pool.
+func (p *Pool) Allocate(transaction *gotrader.Transaction) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.allocate(transaction)
+}
+
+// Update allocates the transactions recorded in the ledger since the last update.
+func (p *Pool) Update(ledger *gotrader.Ledger) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	transactions := ledger.Transactions()
+
+	for _, t := range transactions[min(p.processed, len(transactions)):] {
+		p.allocate(t)
+	}
+
+	p.processed = len(transactions)
+}
+
+func (p *Pool) allocate(transaction *gotrader.Transaction) {
+
+	if transaction.Type.External() {
+		return
+	}
+
+	weights, total := p.weights()
+	if total <= 0 {
+		p.unallocated += p.units(transaction.Amount)
+		return
+	}
+
+	profits := split(p.units(transaction.Amount), weights, total)
+	fees := split(p.units(transaction.Fees), weights, total)
+
+	for i, name := range p.order {
+		inv := p.investors[name]
+		inv.balance += profits[i]
+		inv.profit += profits[i]
+		inv.fees += fees[i]
+	}
+}
+
+// Split divides the units of a position of the account between the investors, by their shares.
+func (p *Pool) Split(units int32) map[string]int32 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	weights, total := p.weights()
+	parts := split(int64(units), weights, total)
+
+	allocated := make(map[string]int32, len(p.order))
+	for i, name := range p.order {
+		allocated[name] = int32(parts[i])
+	}
+
+	return allocated
+}
+
+// Unallocated returns the amount of the transactions allocated without investors.
+func (p *Pool) Unallocated() float64 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	return p.amount(p.unallocated)
+}
+
+// Investors returns the investors by registration order, with their share of the unrealized profit of the
+// account.
+func (p *Pool) Investors(unrealizedProfit float64) []Investor {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	weights, total := p.weights()
+	unrealized := split(p.units(unrealizedProfit), weights, total)
+
+	investors := make([]Investor, 0, len(p.order))
+
+	for i, name := range p.order {
+
+		inv := p.investors[name]
+
+		share := 0.0
+		if total > 0 {
+			share = weights[i] / total
+		}
+
+		investors = append(investors, Investor{
+			Name:             name,
+			Share:            share,
+			Multiplier:       inv.multiplier,
+			Deposited:        p.amount(inv.deposited),
+			Withdrawn:        p.amount(inv.withdrawn),
+			Balance:          p.amount(inv.balance),
+			RealizedProfit:   p.amount(inv.profit),
+			Fees:             p.amount(inv.fees),
+			UnrealizedProfit: p.amount(unrealized[i]),
+			Equity:           p.amount(inv.balance + unrealized[i]),
+		})
+	}
+
+	return investors
+}
@@ -0,0 +1,209 @@
+package gotrader
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+MarginWindow raises the margin used of the instruments by its Multiplier while its Schedule is in session, e.g.
+2x margin over the weekends with a WeeklySession, or around a scheduled news release with a Period. The margin
+reverts automatically when the session closes. Overlapping windows apply the highest multiplier.
+
+The raised margin counts for the margin free, the margin calls and the margin checks of the backtest orders,
+the margin of the single trades is not multiplied.
+*/
+type MarginWindow struct {
+	Schedule    SessionCalendar
+	Multiplier  float64
+	Instruments []string // every instrument when empty
+}
+
+// MarginWindows is the functional option to define the margin multiplier windows of the session.
+func MarginWindows(windows ...MarginWindow) Option {
+	return func(p *sessionParameters) {
+		p.marginWindows = append(p.marginWindows, windows...)
+	}
+}
+
+// MarginPreview is the margin impact of a hypothetical order, see Account.PreviewMargin.
+type MarginPreview struct {
+	Instrument     string
+	Side           Side
+	Units          int32
+	MarginRequired float64 // of the order alone, at the margin multiplier of the instrument
+	MarginImpact   float64 // on the margin used of the account, negative when the order offsets a hedged side
+	MarginUsed     float64 // of the account with the order
+	MarginFree     float64 // of the account with the order
+	MarginLevel    float64 // equity / margin used with the order, zero without margin used
+	Sufficient     bool    // whether the free margin covers the impact
+}
+
+// marginWindow is the state of a MarginWindow, recalculated at its next session open or close.
+type marginWindow struct {
+	MarginWindow
+	instruments map[string]bool
+	active      bool
+	until       time.Time
+}
+
+// marginSchedule applies the margin windows of a session, it is nil without windows.
+type marginSchedule struct {
+	windows []*marginWindow
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newMarginSchedule(windows []MarginWindow) *marginSchedule {
+
+	if len(windows) == 0 {
+		return nil
+	}
+
+	s := &marginSchedule{}
+
+	for _, w := range windows {
+
+		window := &marginWindow{MarginWindow: w, instruments: make(map[string]bool)}
+		for _, name := range w.Instruments {
+			window.instruments[name] = true
+		}
+
+		s.windows = append(s.windows, window)
+	}
+
+	return s
+}
+
+// advance updates the state of the window at t, returning true when it changed.
+func (w *marginWindow) advance(t time.Time) bool {
+
+	if !w.until.IsZero() && t.Before(w.until) {
+		return false
+	}
+
+	active := marketOpen(w.Schedule, t)
+
+	w.until = w.Schedule.NextOpen(t)
+	if active {
+		w.until = w.Schedule.NextClose(t)
+	}
+
+	if w.until.IsZero() { // the window never changes again
+		w.until = time.Unix(1<<62, 0)
+	}
+
+	changed := active != w.active
+	w.active = active
+
+	return changed
+}
+
+// update sets the margin multipliers of the instruments at t, when a window opened or closed.
+func (s *marginSchedule) update(account *Account, t time.Time) {
+
+	if s == nil {
+		return
+	}
+
+	changed := false
+	for _, w := range s.windows {
+		if w.advance(t) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	for name, inst := range account.instruments {
+
+		multiplier := 1.0
+
+		for _, w := range s.windows {
+			if w.active && (len(w.instruments) == 0 || w.instruments[name]) && w.Multiplier > multiplier {
+				multiplier = w.Multiplier
+			}
+		}
+
+		inst.setMarginMultiplier(multiplier)
+	}
+}
+
+// setMarginMultiplier changes the margin multiplier and marks the instrument to be recalculated.
+func (i *Instrument) setMarginMultiplier(multiplier float64) {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	if i.marginMultiplier != multiplier {
+		i.marginMultiplier = multiplier
+		i.touch()
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// MarginMultiplier returns the multiplier of the margin used of the instrument, one outside the margin windows.
+func (i *Instrument) MarginMultiplier() float64 {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return i.marginMultiplier
+}
+
+/*
+PreviewMargin returns the margin impact of an order of the units of the instrument, without placing it: the margin
+of the order side with the order is combined with the other side by the hedge type of the instrument and raised
+by the margin multiplier of the windows in session. The margins are the ones of the last calculation, at the
+current conversion rate and leverage of the instrument.
+*/
+func (a *Account) PreviewMargin(instrument string, side Side, units int32) (MarginPreview, error) {
+
+	preview := MarginPreview{Instrument: instrument, Side: side, Units: units}
+
+	inst := a.Instrument(instrument)
+	if inst == nil {
+		return preview, fmt.Errorf("%s: %w", instrument, ErrInstrumentNotTraded)
+	}
+
+	if units <= 0 {
+		return preview, fmt.Errorf("%s: %w", instrument, ErrInvalidQuantity)
+	}
+
+	inst.lock.RLock()
+	required := DecimalFromInt(int64(units)).MulFloat(inst.ccyConversion.BaseConversionRate.Load() / inst.leverage.Load())
+	short, long := inst.shortPosition.marginUsed, inst.longPosition.marginUsed
+	if side == Short {
+		short = short.Add(required)
+	} else {
+		long = long.Add(required)
+	}
+	impact := hedgedMargin(inst.hedgeType, short, long, inst.marginMultiplier).Sub(inst.marginUsed)
+	required = required.MulFloat(inst.marginMultiplier)
+	inst.lock.RUnlock()
+
+	a.lock.RLock()
+	equity, marginUsed, marginFree := a.equity, a.marginUsed.Add(impact), a.marginFree
+	a.lock.RUnlock()
+
+	preview.MarginRequired = required.Float64()
+	preview.MarginImpact = impact.Float64()
+	preview.MarginUsed = marginUsed.Float64()
+	preview.MarginFree = equity.Sub(marginUsed).Float64()
+	preview.Sufficient = impact.Float64() < marginFree.Float64()
+
+	if marginUsed.Sign() > 0 {
+		preview.MarginLevel = equity.Float64() / marginUsed.Float64()
+	}
+
+	return preview, nil
+}
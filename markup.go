@@ -0,0 +1,73 @@
+package gotrader
+
+import "math"
+
+/*
+PriceMarkup widens the quotes of an instrument, lowering the bid and raising the ask by a number of pips and a
+fraction of the price, e.g. to model the pricing of an introducing broker or to build an internal price engine.
+Negative values mark the quotes down, narrowing the spread, which never gets crossed: the bid and the ask meet
+at the mid price.
+
+The markup is applied to the ticks of the traded instruments before they update the account and reach the
+strategy, so the unrealized profits and the backtest fills use the marked up prices. Live fills are priced by
+the broker, the paper client marks up its fills and the ticks it streams with its Markup option instead. The
+markup paid by the backtest opens is itemized in the Fees of their trades.
+*/
+type PriceMarkup struct {
+	Pips    float64 // per side, in pips of the instrument
+	Percent float64 // per side, as a fraction of the price, e.g. 0.0005 for 5bps
+}
+
+// MarkupModel returns the markup of an instrument, false without (see Markups).
+type MarkupModel interface {
+	Markup(instrument string) (PriceMarkup, bool)
+}
+
+// Apply marks up the quotes of the tick, the pip location is the one of its instrument.
+func (m PriceMarkup) Apply(tick *Tick, pipLocation int) {
+
+	offset := m.Pips * math.Pow10(pipLocation)
+	mid := (tick.Bid + tick.Ask) / 2
+
+	tick.Bid = tick.Bid*(1-m.Percent) - offset
+	tick.Ask = tick.Ask*(1+m.Percent) + offset
+
+	if tick.Bid > tick.Ask {
+		tick.Bid, tick.Ask = mid, mid
+	}
+}
+
+// cost returns the markup paid per unit by a fill at a marked up price, negative.
+func (m PriceMarkup) cost(side Side, price float64, pipLocation int) float64 {
+
+	offset := m.Pips * math.Pow10(pipLocation)
+
+	if side == Long {
+		return (price-offset)/(1+m.Percent) - price
+	}
+
+	return price - (price+offset)/(1-m.Percent)
+}
+
+// markupOf returns the markup of the instrument, nil without.
+func (p *sessionParameters) markupOf(inst *Instrument) *PriceMarkup {
+
+	if p.markups != nil {
+		if markup, exist := p.markups.Markup(inst.name); exist {
+			return &markup
+		}
+	}
+
+	if markup, exist := p.instrumentMarkups[inst.name]; exist {
+		return &markup
+	}
+
+	return p.markup
+}
+
+// markUp applies the markup of the instrument to its tick.
+func (p *sessionParameters) markUp(inst *Instrument, tick *Tick) {
+	if markup := p.markupOf(inst); markup != nil {
+		markup.Apply(tick, inst.pipLocation)
+	}
+}
@@ -0,0 +1,391 @@
+/*
+Package metrics exposes the state of a trading session as Prometheus metrics. The account gauges are read
+when the metrics are scraped, the counters are updated from the session event bus, the tick pipeline latencies
+are observed from the engine, and the tick rate and order latency are measured by wrapping the strategy:
+
+	collector := metrics.NewCollector(session)
+	session.SetStrategy(collector.Wrap(strategy))
+	http.Handle("/metrics", collector.Handler())
+*/
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gotrader"
+
+var (
+	equityDesc       = prometheus.NewDesc(namespace+"_equity", "Account equity in home currency.", nil, nil)
+	balanceDesc      = prometheus.NewDesc(namespace+"_balance", "Account balance in home currency.", nil, nil)
+	marginUsedDesc   = prometheus.NewDesc(namespace+"_margin_used", "Margin used in home currency.", nil, nil)
+	marginFreeDesc   = prometheus.NewDesc(namespace+"_margin_free", "Free margin in home currency.", nil, nil)
+	marginLevelDesc  = prometheus.NewDesc(namespace+"_margin_level", "Equity over margin used, 0 without open trades.", nil, nil)
+	openTradesDesc   = prometheus.NewDesc(namespace+"_open_trades", "Open trades.", []string{"instrument"}, nil)
+	unitsDesc        = prometheus.NewDesc(namespace+"_position_units", "Position units.", []string{"instrument", "side"}, nil)
+	unrealizedDesc   = prometheus.NewDesc(namespace+"_unrealized_pnl", "Unrealized net profit in home currency.", []string{"instrument"}, nil)
+	instMarginDesc   = prometheus.NewDesc(namespace+"_instrument_margin_used", "Margin used in home currency.", []string{"instrument"}, nil)
+	droppedEventDesc = prometheus.NewDesc(namespace+"_metrics_dropped_events", "Events dropped by the metrics subscription.", nil, nil)
+)
+
+// Option represents a Collector functional option
+type Option func(c *Collector)
+
+// LatencyBuckets is the functional option to define the order latency histogram buckets in seconds,
+// defaults to 1ms to ~16s.
+func LatencyBuckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.buckets = buckets
+	}
+}
+
+// TickLatencyBuckets is the functional option to define the tick pipeline latency histogram buckets in seconds,
+// defaults to 10µs to ~330ms.
+func TickLatencyBuckets(buckets []float64) Option {
+	return func(c *Collector) {
+		c.tickBuckets = buckets
+	}
+}
+
+/*
+Collector is a prometheus.Collector of a trading session. Register it on a registry, or serve it with Handler.
+*/
+type Collector struct {
+	session      *gotrader.TradingSession
+	subscription *gotrader.Subscription
+	buckets      []float64
+	tickBuckets  []float64
+
+	ticks        *prometheus.CounterVec
+	tradesOpened *prometheus.CounterVec
+	tradesClosed *prometheus.CounterVec
+	orders       *prometheus.CounterVec
+	orderErrors  *prometheus.CounterVec
+	marginCalls  prometheus.Counter
+	staleprices  *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	tickLatency  *prometheus.HistogramVec
+
+	mutex   *sync.Mutex
+	pending map[string][]time.Time // request times by instrument and side, or by trade for closes
+}
+
+// NewCollector is the Collector constructor, it subscribes to the session events and observes its latencies,
+// so it must be called before the session starts.
+func NewCollector(session *gotrader.TradingSession, opts ...Option) *Collector {
+
+	c := &Collector{
+		session:     session,
+		buckets:     prometheus.ExponentialBuckets(0.001, 2, 15),
+		tickBuckets: prometheus.ExponentialBuckets(0.00001, 2, 16),
+		mutex:       &sync.Mutex{},
+		pending:     make(map[string][]time.Time),
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	c.ticks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "ticks_total", Help: "Ticks received by the strategy.",
+	}, []string{"instrument"})
+
+	c.tradesOpened = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "trades_opened_total", Help: "Trades opened.",
+	}, []string{"instrument"})
+
+	c.tradesClosed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "trades_closed_total", Help: "Trades closed.",
+	}, []string{"instrument"})
+
+	c.orders = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "orders_submitted_total", Help: "Orders submitted.",
+	}, []string{"instrument", "type"})
+
+	c.orderErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "order_errors_total", Help: "Order fills with an error.",
+	}, []string{"instrument", "error"})
+
+	c.marginCalls = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace, Name: "margin_calls_total", Help: "Margin calls.",
+	})
+
+	c.staleprices = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Name: "stale_prices_total", Help: "Instruments reported with stale prices.",
+	}, []string{"instrument"})
+
+	c.latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "order_latency_seconds",
+		Help:      "Time from the strategy order request to its fill.",
+		Buckets:   c.buckets,
+	}, []string{"operation"})
+
+	c.tickLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "tick_latency_seconds",
+		Help:      "Time from the tick arrival to the strategy decision, or to the submission of the orders requested on it.",
+		Buckets:   c.tickBuckets,
+	}, []string{"stage", "instrument"})
+
+	session.ObserveLatency(c)
+
+	c.subscription = session.Events().Subscribe(c.onEvent, 1000,
+		gotrader.TradeOpenedEvent,
+		gotrader.TradeClosedEvent,
+		gotrader.OrderFilledEvent,
+		gotrader.OrderSubmittedEvent,
+		gotrader.MarginCallEvent,
+		gotrader.PriceStaleEvent,
+	)
+
+	return c
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (c *Collector) onEvent(event gotrader.Event) {
+
+	switch e := event.(type) {
+	case gotrader.TradeOpened:
+		c.tradesOpened.WithLabelValues(e.Trade.InstrumentName()).Inc()
+	case gotrader.TradeClosed:
+		c.tradesClosed.WithLabelValues(e.Fill.Instrument.Name).Inc()
+	case gotrader.OrderFilled:
+		if e.Fill.Error != "" {
+			c.orderErrors.WithLabelValues(e.Fill.Instrument.Name, e.Fill.Error).Inc()
+		}
+	case gotrader.OrderSubmitted:
+		c.orders.WithLabelValues(e.Order.Instrument, e.Order.Type.String()).Inc()
+	case gotrader.MarginCall:
+		c.marginCalls.Inc()
+	case gotrader.PriceStale:
+		c.staleprices.WithLabelValues(e.Instrument).Inc()
+	}
+}
+
+func (c *Collector) submitted(key string) {
+	c.mutex.Lock()
+	c.pending[key] = append(c.pending[key], time.Now())
+	c.mutex.Unlock()
+}
+
+// withdrawn forgets the newest request with the key, it was rejected before reaching the broker.
+func (c *Collector) withdrawn(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	times := c.pending[key]
+	if len(times) <= 1 {
+		delete(c.pending, key)
+	} else {
+		c.pending[key] = times[:len(times)-1]
+	}
+}
+
+// filled observes the latency of the oldest request with the key, if any.
+func (c *Collector) filled(key, operation string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	times := c.pending[key]
+	if len(times) == 0 {
+		return false
+	}
+
+	c.latency.WithLabelValues(operation).Observe(time.Since(times[0]).Seconds())
+
+	if len(times) == 1 {
+		delete(c.pending, key)
+	} else {
+		c.pending[key] = times[1:]
+	}
+
+	return true
+}
+
+func (c *Collector) onFill(fill *gotrader.OrderFill) {
+
+	if fill.TradeClose || (fill.TradeID != "" && fill.Error != "" && fill.OrderID == "") {
+		if c.filled("close:"+fill.TradeID, "close") {
+			return
+		}
+	}
+
+	c.filled("market:"+fill.Instrument.Name+":"+fill.Side.String(), "market")
+}
+
+func (c *Collector) metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.ticks, c.tradesOpened, c.tradesClosed, c.orders, c.orderErrors, c.marginCalls, c.staleprices, c.latency,
+		c.tickLatency,
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+
+	for _, d := range []*prometheus.Desc{
+		equityDesc, balanceDesc, marginUsedDesc, marginFreeDesc, marginLevelDesc, openTradesDesc, unitsDesc,
+		unrealizedDesc, instMarginDesc, droppedEventDesc,
+	} {
+		ch <- d
+	}
+
+	for _, m := range c.metrics() {
+		m.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector, the account gauges are only collected once the session started.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+
+	for _, m := range c.metrics() {
+		m.Collect(ch)
+	}
+
+	ch <- prometheus.MustNewConstMetric(droppedEventDesc, prometheus.GaugeValue, float64(c.subscription.Dropped()))
+
+	account := c.session.Account()
+	if account == nil {
+		return
+	}
+
+	marginLevel := 0.0
+	if account.MarginUsed() > 0 {
+		marginLevel = account.Equity() / account.MarginUsed()
+	}
+
+	ch <- prometheus.MustNewConstMetric(equityDesc, prometheus.GaugeValue, account.Equity())
+	ch <- prometheus.MustNewConstMetric(balanceDesc, prometheus.GaugeValue, account.Balance())
+	ch <- prometheus.MustNewConstMetric(marginUsedDesc, prometheus.GaugeValue, account.MarginUsed())
+	ch <- prometheus.MustNewConstMetric(marginFreeDesc, prometheus.GaugeValue, account.MarginFree())
+	ch <- prometheus.MustNewConstMetric(marginLevelDesc, prometheus.GaugeValue, marginLevel)
+
+	for name, inst := range account.Instruments() {
+		ch <- prometheus.MustNewConstMetric(openTradesDesc, prometheus.GaugeValue, float64(inst.TradesNumber()), name)
+		ch <- prometheus.MustNewConstMetric(unrealizedDesc, prometheus.GaugeValue, inst.UnrealizedNetProfit(), name)
+		ch <- prometheus.MustNewConstMetric(instMarginDesc, prometheus.GaugeValue, inst.MarginUsed(), name)
+		ch <- prometheus.MustNewConstMetric(unitsDesc, prometheus.GaugeValue, float64(inst.LongPosition().Units()), name, "LONG")
+		ch <- prometheus.MustNewConstMetric(unitsDesc, prometheus.GaugeValue, float64(inst.ShortPosition().Units()), name, "SHORT")
+	}
+}
+
+// ObserveLatency implements gotrader.LatencyObserver.
+func (c *Collector) ObserveLatency(stage gotrader.LatencyStage, instrument string, latency time.Duration) {
+	c.tickLatency.WithLabelValues(strings.ToLower(stage.String()), instrument).Observe(latency.Seconds())
+}
+
+// Handler returns an http.Handler serving the session metrics, with the Go runtime and process metrics.
+func (c *Collector) Handler() http.Handler {
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c, prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Close stops the events subscription.
+func (c *Collector) Close() {
+	c.subscription.Unsubscribe()
+}
+
+// Wrap returns the strategy measuring its tick rate and the latency of its orders.
+func (c *Collector) Wrap(strategy gotrader.Strategy) gotrader.Strategy {
+	return &strategyWrapper{Strategy: strategy, collector: c}
+}
+
+type strategyWrapper struct {
+	gotrader.Strategy
+	collector *Collector
+}
+
+func (s *strategyWrapper) SetEngine(engine gotrader.Engine) {
+	s.Strategy.SetEngine(&engineWrapper{Engine: engine, collector: s.collector})
+}
+
+func (s *strategyWrapper) OnTick(tick *gotrader.Tick) {
+	s.collector.ticks.WithLabelValues(tick.Instrument).Inc()
+	s.Strategy.OnTick(tick)
+}
+
+func (s *strategyWrapper) OnOrderFill(fill *gotrader.OrderFill) {
+	s.collector.onFill(fill)
+	s.Strategy.OnOrderFill(fill)
+}
+
+func (s *strategyWrapper) OnOrderRejected(fill *gotrader.OrderFill, reason gotrader.RejectReason) {
+	if handler, ok := s.Strategy.(gotrader.RejectionHandler); ok {
+		handler.OnOrderRejected(fill, reason)
+	}
+}
+
+type engineWrapper struct {
+	gotrader.Engine
+	collector *Collector
+}
+
+// request tracks the latency of the request until its fill, unless it fails before reaching the broker.
+func (e *engineWrapper) request(key string, submit func() error) error {
+
+	e.collector.submitted(key)
+
+	err := submit()
+	if err != nil {
+		e.collector.withdrawn(key)
+	}
+
+	return err
+}
+
+func (e *engineWrapper) Buy(instrument string, units int32) error {
+	return e.request("market:"+instrument+":"+gotrader.Long.String(), func() error {
+		return e.Engine.Buy(instrument, units)
+	})
+}
+
+func (e *engineWrapper) Sell(instrument string, units int32) error {
+	return e.request("market:"+instrument+":"+gotrader.Short.String(), func() error {
+		return e.Engine.Sell(instrument, units)
+	})
+}
+
+func (e *engineWrapper) CloseTrade(instrument, id string) error {
+	return e.request("close:"+id, func() error {
+		return e.Engine.CloseTrade(instrument, id)
+	})
+}
+
+func (e *engineWrapper) SubmitOrder(order *gotrader.Order) (string, error) {
+
+	if order.Type != gotrader.MarketOrder { // pending orders latency would include the time waiting for the price
+		return e.Engine.SubmitOrder(order)
+	}
+
+	var id string
+
+	err := e.request("market:"+order.Instrument+":"+order.Side.String(), func() error {
+		var err error
+		id, err = e.Engine.SubmitOrder(order)
+		return err
+	})
+
+	return id, err
+}
@@ -0,0 +1,445 @@
+package ml
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/indicator"
+	"github.com/luismcruz/gotrader/signals"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The models are encoded as the onnx.proto messages, as exported by the Python converters.
+
+func message(fields ...func(b []byte) []byte) []byte {
+
+	var b []byte
+	for _, field := range fields {
+		b = field(b)
+	}
+
+	return b
+}
+
+func bytesField(num protowire.Number, value []byte) func(b []byte) []byte {
+	return func(b []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, value)
+	}
+}
+
+func stringField(num protowire.Number, value string) func(b []byte) []byte {
+	return bytesField(num, []byte(value))
+}
+
+func varintField(num protowire.Number, value int64) func(b []byte) []byte {
+	return func(b []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(value))
+	}
+}
+
+func floatField(num protowire.Number, value float32) func(b []byte) []byte {
+	return func(b []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, math.Float32bits(value))
+	}
+}
+
+// floatTensor encodes the values as float32 raw data.
+func floatTensor(name string, dims []int64, values ...float32) []byte {
+
+	fields := []func(b []byte) []byte{stringField(8, name), varintField(2, 1)}
+	for _, d := range dims {
+		fields = append(fields, varintField(1, d))
+	}
+
+	raw := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		raw = binary.LittleEndian.AppendUint32(raw, math.Float32bits(v))
+	}
+
+	return message(append(fields, bytesField(9, raw))...)
+}
+
+// intTensor encodes the values as packed int64 data.
+func intTensor(name string, values ...int64) []byte {
+
+	var packed []byte
+	for _, v := range values {
+		packed = protowire.AppendVarint(packed, uint64(v))
+	}
+
+	return message(stringField(8, name), varintField(2, 7), varintField(1, int64(len(values))), bytesField(7, packed))
+}
+
+func nodeProto(op string, inputs, outputs []string, attributes ...[]byte) []byte {
+
+	var fields []func(b []byte) []byte
+	for _, in := range inputs {
+		fields = append(fields, stringField(1, in))
+	}
+	for _, out := range outputs {
+		fields = append(fields, stringField(2, out))
+	}
+	fields = append(fields, stringField(4, op))
+	for _, a := range attributes {
+		fields = append(fields, bytesField(5, a))
+	}
+
+	return message(fields...)
+}
+
+// valueInfo encodes a float tensor value, the dimensions of -1 named as the batch.
+func valueInfoProto(name string, dims ...int64) []byte {
+
+	var shape []func(b []byte) []byte
+	for _, d := range dims {
+		if d < 0 {
+			shape = append(shape, bytesField(1, message(stringField(2, "batch"))))
+		} else {
+			shape = append(shape, bytesField(1, message(varintField(1, d))))
+		}
+	}
+
+	tensorType := message(varintField(1, 1), bytesField(2, message(shape...)))
+
+	return message(stringField(1, name), bytesField(2, message(bytesField(1, tensorType))))
+}
+
+func modelProto(opset int64, nodes, initializers, inputs, outputs [][]byte) []byte {
+
+	var g []func(b []byte) []byte
+	for _, n := range nodes {
+		g = append(g, bytesField(1, n))
+	}
+	for _, t := range initializers {
+		g = append(g, bytesField(5, t))
+	}
+	for _, in := range inputs {
+		g = append(g, bytesField(11, in))
+	}
+	for _, out := range outputs {
+		g = append(g, bytesField(12, out))
+	}
+
+	return message(varintField(1, 8), bytesField(7, message(g...)),
+		bytesField(8, message(stringField(1, ""), varintField(2, opset))))
+}
+
+// classifier is a network of 2 features and 3 classes, with a hidden layer of 3 units.
+func classifier(t *testing.T) *Model {
+
+	model, err := ParseONNX(modelProto(13,
+		[][]byte{
+			nodeProto("Gemm", []string{"x", "w1", "b1"}, []string{"h"},
+				message(stringField(1, "transB"), varintField(3, 1))),
+			nodeProto("Relu", []string{"h"}, []string{"a"}),
+			nodeProto("Gemm", []string{"a", "w2", "b2"}, []string{"z"},
+				message(stringField(1, "alpha"), floatField(2, 2))),
+			nodeProto("Softmax", []string{"z"}, []string{"p"}),
+		},
+		[][]byte{
+			floatTensor("w1", []int64{3, 2}, 1, -1, -1, 1, 1, 1),
+			floatTensor("b1", []int64{3}, 0, 0, -1),
+			floatTensor("w2", []int64{3, 3}, 1, 0, 0, 0, 1, 0, 0, 0, 1),
+			floatTensor("b2", []int64{3}, 0, 0, 0.5),
+		},
+		[][]byte{valueInfoProto("x", -1, 2)},
+		[][]byte{valueInfoProto("p", -1, 3)},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return model
+}
+
+func softmaxOf(z ...float64) []float64 {
+
+	sum := 0.0
+	p := make([]float64, len(z))
+	for i, v := range z {
+		p[i] = math.Exp(v)
+		sum += p[i]
+	}
+	for i := range p {
+		p[i] /= sum
+	}
+
+	return p
+}
+
+func near(a, b []float64) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-6 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestModel(t *testing.T) {
+
+	t.Run("classifier", func(t *testing.T) {
+
+		model := classifier(t)
+
+		if in, out := model.Inputs(), model.Outputs(); len(in) != 1 || in[0] != "x" || len(out) != 1 || out[0] != "p" {
+			t.Fatalf("expected the input x and the output p, got %v and %v", in, out)
+		}
+
+		// hidden: relu(x0-x1, x1-x0, x0+x1-1), logits: 2*hidden + (0, 0, 0.5)
+		p, err := model.Predict([]float64{2, 0.5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := softmaxOf(3, 0, 3.5); !near(p, expected) {
+			t.Errorf("expected %v, got %v", expected, p)
+		}
+
+		outputs, err := model.Run(map[string]*Tensor{"x": NewTensor([]int{2, 2}, []float64{2, 0.5, 0, 0})})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := append(softmaxOf(3, 0, 3.5), softmaxOf(0, 0, 0.5)...); !near(outputs["p"].Data, expected) {
+			t.Errorf("expected the batch %v, got %v", expected, outputs["p"].Data)
+		}
+
+		if _, err := model.Predict([]float64{1, 2, 3}); err == nil {
+			t.Error("expected 3 features to be rejected")
+		}
+	})
+
+	t.Run("regression", func(t *testing.T) {
+
+		// y = tanh(x·w + c), reshaped to a vector
+		model, err := ParseONNX(modelProto(11,
+			[][]byte{
+				nodeProto("MatMul", []string{"x", "w"}, []string{"m"}),
+				nodeProto("Constant", nil, []string{"c"}, message(stringField(1, "value_float"), floatField(2, 0.25))),
+				nodeProto("Add", []string{"m", "c"}, []string{"s"}),
+				nodeProto("Tanh", []string{"s"}, []string{"t"}),
+				nodeProto("Reshape", []string{"t", "shape"}, []string{"y"}),
+			},
+			[][]byte{floatTensor("w", []int64{2, 1}, 0.5, -1), intTensor("shape", -1)},
+			[][]byte{valueInfoProto("x", 1, 2)},
+			[][]byte{valueInfoProto("y", 1)},
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		y, err := model.Predict([]float64{1, 0.5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := []float64{math.Tanh(0.25)}; !near(y, expected) {
+			t.Errorf("expected %v, got %v", expected, y)
+		}
+	})
+
+	t.Run("unsupported operators", func(t *testing.T) {
+
+		_, err := ParseONNX(modelProto(13,
+			[][]byte{nodeProto("LSTM", []string{"x"}, []string{"y"})}, nil,
+			[][]byte{valueInfoProto("x", 1, 2)},
+			[][]byte{valueInfoProto("y", 1, 2)},
+		))
+		if err == nil || !strings.Contains(err.Error(), "LSTM") {
+			t.Errorf("expected the LSTM to be rejected, got %v", err)
+		}
+
+		if _, err := ParseONNX([]byte("not a model")); err == nil {
+			t.Error("expected an invalid model to be rejected")
+		}
+	})
+}
+
+func TestOperators(t *testing.T) {
+
+	run := func(op string, opset int64, inputs []*Tensor, attributes ...*attribute) *Tensor {
+
+		n := &node{opType: op, attributes: make(map[string]*attribute)}
+		for _, a := range attributes {
+			n.attributes[a.name] = a
+		}
+
+		outputs, err := ops[op](n, inputs, opset)
+		if err != nil {
+			t.Fatalf("%s: %v", op, err)
+		}
+
+		return outputs[0]
+	}
+
+	tests := []struct {
+		name     string
+		out      *Tensor
+		expected *Tensor
+	}{
+		{
+			"broadcast",
+			run("Sub", 13, []*Tensor{NewTensor([]int{2, 3}, []float64{1, 2, 3, 4, 5, 6}),
+				NewTensor([]int{2, 1}, []float64{1, 4})}),
+			NewTensor([]int{2, 3}, []float64{0, 1, 2, 0, 1, 2}),
+		},
+		{
+			"transpose",
+			run("Transpose", 13, []*Tensor{NewTensor([]int{2, 3}, []float64{1, 2, 3, 4, 5, 6})}),
+			NewTensor([]int{3, 2}, []float64{1, 4, 2, 5, 3, 6}),
+		},
+		{
+			"concat",
+			run("Concat", 13, []*Tensor{NewTensor([]int{1, 2}, []float64{1, 2}), NewTensor([]int{1, 1}, []float64{3})},
+				&attribute{name: "axis", i: -1}),
+			NewTensor([]int{1, 3}, []float64{1, 2, 3}),
+		},
+		{
+			"flattened softmax",
+			run("Softmax", 11, []*Tensor{NewTensor([]int{1, 2, 1}, []float64{0, 0})}),
+			NewTensor([]int{1, 2, 1}, []float64{0.5, 0.5}),
+		},
+		{
+			"argmax",
+			run("ArgMax", 13, []*Tensor{NewTensor([]int{2, 3}, []float64{1, 3, 2, 6, 5, 4})},
+				&attribute{name: "axis", i: 1}, &attribute{name: "keepdims", i: 0}),
+			NewTensor([]int{2}, []float64{1, 0}),
+		},
+		{
+			"clip",
+			run("Clip", 6, []*Tensor{NewTensor([]int{3}, []float64{-2, 0.5, 2})},
+				&attribute{name: "min", f: -1}, &attribute{name: "max", f: 1}),
+			NewTensor([]int{3}, []float64{-1, 0.5, 1}),
+		},
+		{
+			"unsqueeze",
+			run("Unsqueeze", 13, []*Tensor{NewTensor([]int{3}, []float64{1, 2, 3}), NewTensor([]int{1}, []float64{0})}),
+			NewTensor([]int{1, 3}, []float64{1, 2, 3}),
+		},
+		{
+			"squeeze",
+			run("Squeeze", 11, []*Tensor{NewTensor([]int{1, 3, 1}, []float64{1, 2, 3})}),
+			NewTensor([]int{3}, []float64{1, 2, 3}),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !near(test.out.Data, test.expected.Data) || len(test.out.Shape) != len(test.expected.Shape) {
+				t.Fatalf("expected %v, got %v", test.expected, test.out)
+			}
+			for i := range test.out.Shape {
+				if test.out.Shape[i] != test.expected.Shape[i] {
+					t.Fatalf("expected the shape %v, got %v", test.expected.Shape, test.out.Shape)
+				}
+			}
+		})
+	}
+}
+
+// closing is the feature of the close prices.
+type closing struct{ last float64 }
+
+func (c *closing) OnCandle(candle *gotrader.Candle) { c.last = candle.Close }
+func (c *closing) OnTick(tick *gotrader.Tick)       {}
+func (c *closing) Value() float64                   { return c.last }
+func (c *closing) WarmupPeriod() int                { return 0 }
+
+func TestPredictor(t *testing.T) {
+
+	ch := make(chan signals.Signal, 10)
+	sma := indicator.NewSMA(2)
+
+	predictor, err := NewPredictor(classifier(t), PredictorConfig{
+		Source:    "mlp",
+		Features:  []indicator.Indicator{&closing{}, sma},
+		Decision:  Classes(signals.Long, signals.Short, signals.Flat),
+		Publisher: signals.ChannelPublisher(ch),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline := indicator.NewPipeline()
+	if err := pipeline.Add(predictor); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	candle := func(i int, close float64) *gotrader.Candle {
+		return &gotrader.Candle{Instrument: "EUR_USD", Timeframe: time.Hour, Time: start.Add(time.Duration(i) * time.Hour),
+			Close: close}
+	}
+
+	pipeline.OnCandle(candle(0, 1))
+	if len(ch) != 0 {
+		t.Fatal("expected no signal before the features are warmed up")
+	}
+
+	// features (3, 2): logits (2, 0, 8.5), the flat class
+	pipeline.OnCandle(candle(1, 3))
+	// features (0, 1.5): logits (0, 3, 1.5), short
+	pipeline.OnCandle(candle(2, 0))
+
+	if len(ch) != 2 {
+		t.Fatalf("expected 2 signals, got %d", len(ch))
+	}
+
+	if s := <-ch; s.Direction != signals.Flat || s.Strength != 0 {
+		t.Errorf("expected a flat signal, got %+v", s)
+	}
+
+	s := <-ch
+	if expected := softmaxOf(0, 3, 1.5)[1]; s.Direction != signals.Short || math.Abs(s.Strength-expected) > 1e-6 {
+		t.Errorf("expected a short signal of strength %f, got %+v", expected, s)
+	}
+	if s.Source != "mlp" || s.Instrument != "EUR_USD" || !s.Time.Equal(start.Add(3*time.Hour)) ||
+		!s.Expiry.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("expected the signal of the candle close, got %+v", s)
+	}
+
+	if last, exist := predictor.Last(); !exist || last != s {
+		t.Errorf("expected the last signal %+v, got %+v", s, last)
+	}
+	if predictor.Err() != nil {
+		t.Error(predictor.Err())
+	}
+
+	if _, err := NewPredictor(classifier(t), PredictorConfig{Features: []indicator.Indicator{sma},
+		Publisher: signals.ChannelPublisher(ch)}, nil); err == nil {
+		t.Error("expected a model of 2 features to reject 1")
+	}
+}
+
+func TestDecisions(t *testing.T) {
+
+	tests := []struct {
+		decision  Decision
+		outputs   []float64
+		direction signals.Direction
+		strength  float64
+	}{
+		{Sign(0.1), []float64{0.4}, signals.Long, 0.4},
+		{Sign(0.1), []float64{-2}, signals.Short, 1},
+		{Sign(0.1), []float64{0.05}, signals.Flat, 0},
+		{Classes(signals.Short, signals.Long), []float64{0.3, 0.7}, signals.Long, 0.7},
+		{Classes(signals.Short, signals.Long), []float64{1}, signals.Flat, 0},
+	}
+
+	for _, test := range tests {
+		if direction, strength := test.decision(test.outputs); direction != test.direction || strength != test.strength {
+			t.Errorf("%v: expected %s %f, got %s %f", test.outputs, test.direction, test.strength, direction, strength)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+/*
+Package ml runs machine learning models, trained in Python and exported to ONNX, on the features computed by the
+indicators, so they drive the execution of the strategies. The models are interpreted in pure Go: the operators
+of the feed-forward networks and the linear models are supported, see Operators, on float tensors.
+
+A Predictor is attached to the candles of an instrument like an indicator, after its features, and publishes the
+signal decided from the model outputs on every candle:
+
+	model, err := ml.LoadONNX("model.onnx")
+	...
+	predictor, err := ml.NewPredictor(model, ml.PredictorConfig{
+		Source:    "mlp",
+		Features:  []indicator.Indicator{indicator.NewRSI(14), indicator.NewATR(14)},
+		Decision:  ml.Sign(0.1),
+		Publisher: signals.ChannelPublisher(ch),
+	}, nil)
+	...
+	ctx.Attach("EUR_USD", time.Hour, predictor)
+*/
+package ml
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Model is an ONNX model loaded, safe for concurrent use.
+type Model struct {
+	graph *graph
+	opset int64
+}
+
+// LoadONNX loads the ONNX model of the file.
+func LoadONNX(path string) (*Model, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := ParseONNX(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return model, nil
+}
+
+// ParseONNX parses a serialized ONNX model, an error is returned if it has operators not supported.
+func ParseONNX(data []byte) (*Model, error) {
+
+	g, opset, err := decodeModel(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model: %w", err)
+	}
+
+	if names := unsupported(g.nodes); names != "" {
+		return nil, fmt.Errorf("operators not supported: %s", names)
+	}
+
+	if opset == 0 {
+		opset = 1
+	}
+
+	// the inputs of the graph with an initializer are the default values of optional inputs
+	inputs := g.inputs[:0]
+	for _, in := range g.inputs {
+		if _, exist := g.initializers[in.name]; !exist {
+			inputs = append(inputs, in)
+		}
+	}
+	g.inputs = inputs
+
+	if len(g.inputs) == 0 || len(g.outputs) == 0 {
+		return nil, errors.New("model without inputs or outputs")
+	}
+
+	return &Model{graph: g, opset: opset}, nil
+}
+
+// Inputs returns the names of the model inputs.
+func (m *Model) Inputs() []string {
+	return names(m.graph.inputs)
+}
+
+// Outputs returns the names of the model outputs.
+func (m *Model) Outputs() []string {
+	return names(m.graph.outputs)
+}
+
+func names(infos []valueInfo) []string {
+
+	list := make([]string, len(infos))
+	for i, info := range infos {
+		list[i] = info.name
+	}
+
+	return list
+}
+
+// Run computes the outputs of the model, by name, with its inputs.
+func (m *Model) Run(inputs map[string]*Tensor) (map[string]*Tensor, error) {
+
+	values := make(map[string]*Tensor, len(m.graph.initializers)+len(inputs)+len(m.graph.nodes))
+	for name, t := range m.graph.initializers {
+		values[name] = t
+	}
+
+	for _, in := range m.graph.inputs {
+
+		t, exist := inputs[in.name]
+		if !exist || t == nil {
+			return nil, fmt.Errorf("input %s missing", in.name)
+		}
+
+		if t.size() != len(t.Data) {
+			return nil, fmt.Errorf("input %s: shape %v of %d values", in.name, t.Shape, len(t.Data))
+		}
+
+		values[in.name] = t
+	}
+
+	for _, n := range m.graph.nodes {
+
+		inputs := make([]*Tensor, len(n.inputs))
+		for i, name := range n.inputs {
+			if name == "" {
+				continue // optional input omitted
+			}
+			t, exist := values[name]
+			if !exist {
+				return nil, fmt.Errorf("%s: value %s not computed", n.opType, name)
+			}
+			inputs[i] = t
+		}
+
+		outputs, err := ops[n.opType](n, inputs, m.opset)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", n.opType, err)
+		}
+
+		for i, name := range n.outputs {
+			if i < len(outputs) && name != "" {
+				values[name] = outputs[i]
+			}
+		}
+	}
+
+	outputs := make(map[string]*Tensor, len(m.graph.outputs))
+	for _, out := range m.graph.outputs {
+
+		t, exist := values[out.name]
+		if !exist {
+			return nil, fmt.Errorf("output %s not computed", out.name)
+		}
+
+		outputs[out.name] = t
+	}
+
+	return outputs, nil
+}
+
+// Predict runs a model of a single input on a sample of features, shaped as the input, e.g. [1, n] for a
+// batch of one, and returns the values of the first output.
+func (m *Model) Predict(features []float64) ([]float64, error) {
+
+	if len(m.graph.inputs) != 1 {
+		return nil, fmt.Errorf("model of %d inputs", len(m.graph.inputs))
+	}
+
+	in := m.graph.inputs[0]
+
+	shape := make([]int, len(in.dims))
+	for i := range shape {
+		shape[i] = 1
+	}
+	if len(shape) == 0 {
+		shape = []int{1}
+	}
+	shape[len(shape)-1] = len(features)
+
+	if len(in.dims) > 0 {
+		if last := in.dims[len(in.dims)-1]; last > 0 && last != int64(len(features)) {
+			return nil, fmt.Errorf("%d features for the input %s of %d", len(features), in.name, last)
+		}
+	}
+
+	outputs, err := m.Run(map[string]*Tensor{in.name: {Shape: shape, Data: features}})
+	if err != nil {
+		return nil, err
+	}
+
+	return outputs[m.graph.outputs[0].name].Data, nil
+}
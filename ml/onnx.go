@@ -0,0 +1,412 @@
+package ml
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of onnx.proto decoded, with the field numbers of their fields read, the others are skipped.
+
+// ONNX tensor data types.
+const (
+	onnxFloat  = 1
+	onnxUint8  = 2
+	onnxInt8   = 3
+	onnxInt32  = 6
+	onnxInt64  = 7
+	onnxBool   = 9
+	onnxDouble = 11
+)
+
+type attribute struct {
+	name   string
+	f      float64
+	i      int64
+	t      *Tensor
+	floats []float64
+	ints   []int64
+}
+
+type node struct {
+	inputs     []string
+	outputs    []string
+	opType     string
+	domain     string
+	attributes map[string]*attribute
+}
+
+type valueInfo struct {
+	name string
+	dims []int64 // -1 for the symbolic dimensions, e.g. the batch size
+}
+
+type graph struct {
+	nodes        []*node
+	initializers map[string]*Tensor
+	inputs       []valueInfo
+	outputs      []valueInfo
+}
+
+// fields calls fn with each field of a message until fn fails.
+func fields(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error) error {
+
+	for len(b) > 0 {
+
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var value []byte
+		var v uint64
+
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, value, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// varints appends the repeated varint field, packed or not.
+func varints(values []int64, typ protowire.Type, value []byte, v uint64) ([]int64, error) {
+
+	if typ == protowire.VarintType {
+		return append(values, int64(v)), nil
+	}
+
+	for len(value) > 0 {
+		v, n := protowire.ConsumeVarint(value)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		values, value = append(values, int64(v)), value[n:]
+	}
+
+	return values, nil
+}
+
+// floats appends the repeated float (fixed32) or double (fixed64) field, packed or not.
+func floats(values []float64, typ protowire.Type, value []byte, v uint64, double bool) []float64 {
+
+	switch typ {
+	case protowire.Fixed32Type:
+		return append(values, float64(math.Float32frombits(uint32(v))))
+	case protowire.Fixed64Type:
+		return append(values, math.Float64frombits(v))
+	}
+
+	size := 4
+	if double {
+		size = 8
+	}
+
+	for ; len(value) >= size; value = value[size:] {
+		if double {
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(value)))
+		} else {
+			values = append(values, float64(math.Float32frombits(binary.LittleEndian.Uint32(value))))
+		}
+	}
+
+	return values
+}
+
+// rawValues decodes the little endian raw data of a tensor.
+func rawValues(dataType int64, raw []byte) ([]float64, error) {
+
+	size := map[int64]int{onnxFloat: 4, onnxDouble: 8, onnxInt32: 4, onnxInt64: 8, onnxUint8: 1, onnxInt8: 1,
+		onnxBool: 1}[dataType]
+	if size == 0 {
+		return nil, fmt.Errorf("unsupported tensor data type %d", dataType)
+	}
+
+	values := make([]float64, 0, len(raw)/size)
+
+	for ; len(raw) >= size; raw = raw[size:] {
+		switch dataType {
+		case onnxFloat:
+			values = append(values, float64(math.Float32frombits(binary.LittleEndian.Uint32(raw))))
+		case onnxDouble:
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(raw)))
+		case onnxInt32:
+			values = append(values, float64(int32(binary.LittleEndian.Uint32(raw))))
+		case onnxInt64:
+			values = append(values, float64(int64(binary.LittleEndian.Uint64(raw))))
+		case onnxInt8:
+			values = append(values, float64(int8(raw[0])))
+		default:
+			values = append(values, float64(raw[0]))
+		}
+	}
+
+	return values, nil
+}
+
+func decodeTensor(b []byte) (string, *Tensor, error) {
+
+	var name string
+	var dims, ints []int64
+	var values []float64
+	var dataType int64
+	var raw []byte
+	var err error
+
+	err = fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch num {
+		case 1:
+			dims, err = varints(dims, typ, value, v)
+		case 2:
+			dataType = int64(v)
+		case 4:
+			values = floats(values, typ, value, v, false)
+		case 5, 7:
+			ints, err = varints(ints, typ, value, v)
+		case 8:
+			name = string(value)
+		case 9:
+			raw = value
+		case 10:
+			values = floats(values, typ, value, v, true)
+		case 14:
+			if v != 0 {
+				return errors.New("tensors with external data are not supported")
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if raw != nil {
+		if values, err = rawValues(dataType, raw); err != nil {
+			return "", nil, fmt.Errorf("tensor %s: %w", name, err)
+		}
+	}
+
+	for _, i := range ints {
+		if dataType == onnxInt32 || dataType == onnxBool || dataType == onnxUint8 || dataType == onnxInt8 {
+			i = int64(int32(i))
+		}
+		values = append(values, float64(i))
+	}
+
+	t := &Tensor{Shape: make([]int, len(dims)), Data: values}
+	for i, d := range dims {
+		t.Shape[i] = int(d)
+	}
+
+	if t.size() != len(t.Data) {
+		return "", nil, fmt.Errorf("tensor %s: %d values for the shape %v", name, len(t.Data), t.Shape)
+	}
+
+	return name, t, nil
+}
+
+func decodeAttribute(b []byte) (*attribute, error) {
+
+	a := &attribute{}
+	var err error
+
+	err = fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch num {
+		case 1:
+			a.name = string(value)
+		case 2:
+			a.f = float64(math.Float32frombits(uint32(v)))
+		case 3:
+			a.i = int64(v)
+		case 5:
+			_, a.t, err = decodeTensor(value)
+		case 7:
+			a.floats = floats(a.floats, typ, value, v, false)
+		case 8:
+			a.ints, err = varints(a.ints, typ, value, v)
+		}
+		return err
+	})
+
+	return a, err
+}
+
+func decodeNode(b []byte) (*node, error) {
+
+	n := &node{attributes: make(map[string]*attribute)}
+
+	err := fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch num {
+		case 1:
+			n.inputs = append(n.inputs, string(value))
+		case 2:
+			n.outputs = append(n.outputs, string(value))
+		case 4:
+			n.opType = string(value)
+		case 7:
+			n.domain = string(value)
+		case 5:
+			a, err := decodeAttribute(value)
+			if err != nil {
+				return err
+			}
+			n.attributes[a.name] = a
+		}
+		return nil
+	})
+
+	return n, err
+}
+
+// decodeValueInfo decodes the name and the tensor shape of a value info.
+func decodeValueInfo(b []byte) (valueInfo, error) {
+
+	info := valueInfo{}
+
+	dimension := func(b []byte) error {
+		size := int64(-1)
+		err := fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+			if num == 1 {
+				size = int64(v)
+			}
+			return nil
+		})
+		info.dims = append(info.dims, size)
+		return err
+	}
+
+	shape := func(b []byte) error {
+		return fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+			if num == 1 {
+				return dimension(value)
+			}
+			return nil
+		})
+	}
+
+	tensorType := func(b []byte) error {
+		return fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+			if num == 2 {
+				return shape(value)
+			}
+			return nil
+		})
+	}
+
+	err := fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch num {
+		case 1:
+			info.name = string(value)
+		case 2:
+			return fields(value, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+				if num == 1 {
+					return tensorType(value)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+
+	return info, err
+}
+
+func decodeGraph(b []byte) (*graph, error) {
+
+	g := &graph{initializers: make(map[string]*Tensor)}
+
+	err := fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch num {
+		case 1:
+			n, err := decodeNode(value)
+			if err != nil {
+				return err
+			}
+			g.nodes = append(g.nodes, n)
+		case 5:
+			name, t, err := decodeTensor(value)
+			if err != nil {
+				return err
+			}
+			g.initializers[name] = t
+		case 11, 12:
+			info, err := decodeValueInfo(value)
+			if err != nil {
+				return err
+			}
+			if num == 11 {
+				g.inputs = append(g.inputs, info)
+			} else {
+				g.outputs = append(g.outputs, info)
+			}
+		}
+		return nil
+	})
+
+	return g, err
+}
+
+// decodeModel decodes the graph of a model and the version of its default operator set.
+func decodeModel(b []byte) (*graph, int64, error) {
+
+	var g *graph
+	var opset int64
+
+	err := fields(b, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch num {
+		case 7:
+			var err error
+			g, err = decodeGraph(value)
+			return err
+		case 8:
+			domain, version := "", int64(0)
+			err := fields(value, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+				switch num {
+				case 1:
+					domain = string(value)
+				case 2:
+					version = int64(v)
+				}
+				return nil
+			})
+			if domain == "" || domain == "ai.onnx" {
+				opset = version
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if g == nil {
+		return nil, 0, errors.New("model without graph")
+	}
+
+	return g, opset, nil
+}
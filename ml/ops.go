@@ -0,0 +1,725 @@
+package ml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Tensor is a dense tensor of a model, its values in row-major order. The values of every data type are
+// float64, the indices and the shapes included.
+type Tensor struct {
+	Shape []int
+	Data  []float64
+}
+
+// NewTensor returns the tensor of the values with the shape, nil if the shape does not match the values.
+func NewTensor(shape []int, data []float64) *Tensor {
+
+	t := &Tensor{Shape: shape, Data: data}
+	if t.size() != len(data) {
+		return nil
+	}
+
+	return t
+}
+
+func (t *Tensor) size() int {
+	return product(t.Shape)
+}
+
+func product(dims []int) int {
+
+	n := 1
+	for _, d := range dims {
+		n *= d
+	}
+
+	return n
+}
+
+// axis returns the axis in [0, rank) of a negative or positive one.
+func axis(a int64, rank int) (int, error) {
+
+	if a < 0 {
+		a += int64(rank)
+	}
+
+	if a < 0 || a >= int64(rank) && !(rank == 0 && a == 0) {
+		return 0, fmt.Errorf("axis %d out of the rank %d", a, rank)
+	}
+
+	return int(a), nil
+}
+
+// op computes the outputs of a node from its inputs, nil for the optional inputs missing.
+type op func(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error)
+
+var ops = map[string]op{
+	"Abs":       unary(math.Abs),
+	"Add":       elementwise(func(a, b float64) float64 { return a + b }),
+	"ArgMax":    argMax,
+	"Cast":      identity,
+	"Clip":      clip,
+	"Concat":    concat,
+	"Constant":  constant,
+	"Div":       elementwise(func(a, b float64) float64 { return a / b }),
+	"Dropout":   identity,
+	"Exp":       unary(math.Exp),
+	"Flatten":   flatten,
+	"Gemm":      gemm,
+	"Identity":  identity,
+	"LeakyRelu": leakyRelu,
+	"Log":       unary(math.Log),
+	"MatMul":    matMul,
+	"Mul":       elementwise(func(a, b float64) float64 { return a * b }),
+	"Neg":       unary(func(x float64) float64 { return -x }),
+	"Pow":       elementwise(math.Pow),
+	"Relu":      unary(func(x float64) float64 { return math.Max(x, 0) }),
+	"Reshape":   reshape,
+	"Sigmoid":   unary(func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }),
+	"Softmax":   softmax,
+	"Sqrt":      unary(math.Sqrt),
+	"Squeeze":   squeeze,
+	"Sub":       elementwise(func(a, b float64) float64 { return a - b }),
+	"Tanh":      unary(math.Tanh),
+	"Transpose": transpose,
+	"Unsqueeze": unsqueeze,
+}
+
+// Operators returns the names of the ONNX operators supported, of the default domain.
+func Operators() []string {
+
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (n *node) int(name string, def int64) int64 {
+	if a, exist := n.attributes[name]; exist {
+		return a.i
+	}
+	return def
+}
+
+func (n *node) float(name string, def float64) float64 {
+	if a, exist := n.attributes[name]; exist {
+		return a.f
+	}
+	return def
+}
+
+// ints returns the integers of the attribute, or else of the input at index, nil if there are none.
+func (n *node) ints(name string, inputs []*Tensor, index int) []int64 {
+
+	if a, exist := n.attributes[name]; exist {
+		return a.ints
+	}
+
+	if index < len(inputs) && inputs[index] != nil {
+		values := make([]int64, len(inputs[index].Data))
+		for i, v := range inputs[index].Data {
+			values[i] = int64(v)
+		}
+		return values
+	}
+
+	return nil
+}
+
+func required(inputs []*Tensor, n int) error {
+
+	if len(inputs) < n {
+		return fmt.Errorf("%d inputs, %d required", len(inputs), n)
+	}
+
+	for _, t := range inputs[:n] {
+		if t == nil {
+			return errors.New("required input missing")
+		}
+	}
+
+	return nil
+}
+
+func identity(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 1); err != nil {
+		return nil, err
+	}
+
+	return []*Tensor{inputs[0]}, nil
+}
+
+func unary(fn func(x float64) float64) op {
+	return func(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+		if err := required(inputs, 1); err != nil {
+			return nil, err
+		}
+
+		out := &Tensor{Shape: inputs[0].Shape, Data: make([]float64, len(inputs[0].Data))}
+		for i, x := range inputs[0].Data {
+			out.Data[i] = fn(x)
+		}
+
+		return []*Tensor{out}, nil
+	}
+}
+
+func leakyRelu(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	alpha := n.float("alpha", 0.01)
+
+	return unary(func(x float64) float64 {
+		if x < 0 {
+			return alpha * x
+		}
+		return x
+	})(n, inputs, opset)
+}
+
+// broadcast applies fn to the elements of a and b with the multidirectional (numpy) broadcasting.
+func broadcast(a, b *Tensor, fn func(x, y float64) float64) (*Tensor, error) {
+
+	rank := max(len(a.Shape), len(b.Shape))
+	shape := make([]int, rank)
+	aStrides, bStrides := make([]int, rank), make([]int, rank)
+
+	aStride, bStride := 1, 1
+
+	for i := rank - 1; i >= 0; i-- {
+
+		ad, bd := 1, 1
+		if j := i - (rank - len(a.Shape)); j >= 0 {
+			ad = a.Shape[j]
+		}
+		if j := i - (rank - len(b.Shape)); j >= 0 {
+			bd = b.Shape[j]
+		}
+
+		switch {
+		case ad == bd, bd == 1:
+			shape[i] = ad
+		case ad == 1:
+			shape[i] = bd
+		default:
+			return nil, fmt.Errorf("shapes %v and %v can't be broadcast", a.Shape, b.Shape)
+		}
+
+		if ad != 1 {
+			aStrides[i] = aStride
+		}
+		if bd != 1 {
+			bStrides[i] = bStride
+		}
+
+		aStride, bStride = aStride*ad, bStride*bd
+	}
+
+	out := &Tensor{Shape: shape, Data: make([]float64, product(shape))}
+	index := make([]int, rank)
+
+	for k := range out.Data {
+
+		ai, bi := 0, 0
+		for i := range index {
+			ai, bi = ai+index[i]*aStrides[i], bi+index[i]*bStrides[i]
+		}
+
+		out.Data[k] = fn(a.Data[ai], b.Data[bi])
+
+		for i := rank - 1; i >= 0; i-- {
+			if index[i]++; index[i] < shape[i] {
+				break
+			}
+			index[i] = 0
+		}
+	}
+
+	return out, nil
+}
+
+func elementwise(fn func(x, y float64) float64) op {
+	return func(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+		if err := required(inputs, 2); err != nil {
+			return nil, err
+		}
+
+		out, err := broadcast(inputs[0], inputs[1], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*Tensor{out}, nil
+	}
+}
+
+// matrix returns the rows and the columns of a 2D tensor, transposed or not, and its element at row i column j.
+func matrix(t *Tensor, transposed bool) (int, int, func(i, j int) float64, error) {
+
+	if len(t.Shape) != 2 {
+		return 0, 0, nil, fmt.Errorf("matrix of shape %v", t.Shape)
+	}
+
+	rows, cols := t.Shape[0], t.Shape[1]
+
+	if transposed {
+		return cols, rows, func(i, j int) float64 { return t.Data[j*cols+i] }, nil
+	}
+
+	return rows, cols, func(i, j int) float64 { return t.Data[i*cols+j] }, nil
+}
+
+func gemm(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 2); err != nil {
+		return nil, err
+	}
+
+	m, k, a, err := matrix(inputs[0], n.int("transA", 0) != 0)
+	if err != nil {
+		return nil, err
+	}
+
+	kb, cols, b, err := matrix(inputs[1], n.int("transB", 0) != 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if k != kb {
+		return nil, fmt.Errorf("gemm of shapes %v and %v", inputs[0].Shape, inputs[1].Shape)
+	}
+
+	alpha, beta := n.float("alpha", 1), n.float("beta", 1)
+	out := &Tensor{Shape: []int{m, cols}, Data: make([]float64, m*cols)}
+
+	for i := 0; i < m; i++ {
+		for j := 0; j < cols; j++ {
+			sum := 0.0
+			for l := 0; l < k; l++ {
+				sum += a(i, l) * b(l, j)
+			}
+			out.Data[i*cols+j] = alpha * sum
+		}
+	}
+
+	if len(inputs) > 2 && inputs[2] != nil {
+		if out, err = broadcast(out, inputs[2], func(x, c float64) float64 { return x + beta*c }); err != nil {
+			return nil, err
+		}
+	}
+
+	return []*Tensor{out}, nil
+}
+
+func matMul(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 2); err != nil {
+		return nil, err
+	}
+
+	a, b := *inputs[0], *inputs[1]
+	vectorA, vectorB := len(a.Shape) == 1, len(b.Shape) == 1
+
+	if vectorA {
+		a.Shape = []int{1, a.Shape[0]}
+	}
+	if vectorB {
+		b.Shape = []int{b.Shape[0], 1}
+	}
+
+	outs, err := gemm(&node{attributes: map[string]*attribute{}}, []*Tensor{&a, &b}, opset)
+	if err != nil {
+		return nil, err
+	}
+
+	out := outs[0]
+	switch {
+	case vectorA && vectorB:
+		out.Shape = []int{}
+	case vectorA:
+		out.Shape = out.Shape[1:]
+	case vectorB:
+		out.Shape = out.Shape[:1]
+	}
+
+	return []*Tensor{out}, nil
+}
+
+func softmax(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 1); err != nil {
+		return nil, err
+	}
+
+	in := inputs[0]
+	def := int64(-1)
+	if opset < 13 {
+		def = 1
+	}
+
+	ax, err := axis(n.int("axis", def), len(in.Shape))
+	if err != nil {
+		return nil, err
+	}
+
+	// the versions before 13 compute the softmax of the input coerced to 2D at the axis
+	outer, length, inner := product(in.Shape[:ax]), in.Shape[ax], product(in.Shape[ax+1:])
+	if opset < 13 {
+		length, inner = product(in.Shape[ax:]), 1
+	}
+
+	out := &Tensor{Shape: in.Shape, Data: make([]float64, len(in.Data))}
+
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+
+			at := func(k int) int { return (o*length+k)*inner + i }
+
+			maximum := math.Inf(-1)
+			for k := 0; k < length; k++ {
+				maximum = math.Max(maximum, in.Data[at(k)])
+			}
+
+			sum := 0.0
+			for k := 0; k < length; k++ {
+				out.Data[at(k)] = math.Exp(in.Data[at(k)] - maximum)
+				sum += out.Data[at(k)]
+			}
+
+			for k := 0; k < length; k++ {
+				out.Data[at(k)] /= sum
+			}
+		}
+	}
+
+	return []*Tensor{out}, nil
+}
+
+func flatten(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 1); err != nil {
+		return nil, err
+	}
+
+	in := inputs[0]
+
+	ax := n.int("axis", 1)
+	if ax < 0 {
+		ax += int64(len(in.Shape))
+	}
+	if ax < 0 || ax > int64(len(in.Shape)) {
+		return nil, fmt.Errorf("flatten axis %d out of the rank %d", ax, len(in.Shape))
+	}
+
+	shape := []int{product(in.Shape[:ax]), product(in.Shape[ax:])}
+
+	return []*Tensor{{Shape: shape, Data: in.Data}}, nil
+}
+
+func reshape(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 2); err != nil {
+		return nil, err
+	}
+
+	in := inputs[0]
+	shape := make([]int, len(inputs[1].Data))
+	inferred := -1
+
+	for i, v := range inputs[1].Data {
+		switch {
+		case v == 0 && i < len(in.Shape):
+			shape[i] = in.Shape[i]
+		case v == -1 && inferred < 0:
+			inferred = i
+		case v < 0:
+			return nil, fmt.Errorf("reshape to %v", inputs[1].Data)
+		default:
+			shape[i] = int(v)
+		}
+	}
+
+	if inferred >= 0 {
+		shape[inferred] = 1
+		if known := product(shape); known > 0 {
+			shape[inferred] = len(in.Data) / known
+		}
+	}
+
+	if product(shape) != len(in.Data) {
+		return nil, fmt.Errorf("reshape of %v to %v", in.Shape, shape)
+	}
+
+	return []*Tensor{{Shape: shape, Data: in.Data}}, nil
+}
+
+func concat(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 1); err != nil {
+		return nil, err
+	}
+
+	first := inputs[0]
+
+	ax, err := axis(n.int("axis", 0), len(first.Shape))
+	if err != nil {
+		return nil, err
+	}
+
+	shape := append([]int(nil), first.Shape...)
+	shape[ax] = 0
+
+	for _, in := range inputs {
+
+		if in == nil || len(in.Shape) != len(shape) {
+			return nil, errors.New("concat of tensors of different ranks")
+		}
+
+		for i := range shape {
+			if i != ax && in.Shape[i] != first.Shape[i] {
+				return nil, fmt.Errorf("concat of shapes %v and %v on axis %d", first.Shape, in.Shape, ax)
+			}
+		}
+
+		shape[ax] += in.Shape[ax]
+	}
+
+	outer, inner := product(shape[:ax]), product(shape[ax+1:])
+	out := &Tensor{Shape: shape, Data: make([]float64, 0, product(shape))}
+
+	for o := 0; o < outer; o++ {
+		for _, in := range inputs {
+			chunk := in.Shape[ax] * inner
+			out.Data = append(out.Data, in.Data[o*chunk:(o+1)*chunk]...)
+		}
+	}
+
+	return []*Tensor{out}, nil
+}
+
+func clip(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	low, high := math.Inf(-1), math.Inf(1)
+
+	if opset < 11 {
+		low, high = n.float("min", low), n.float("max", high)
+	} else {
+		if len(inputs) > 1 && inputs[1] != nil && len(inputs[1].Data) == 1 {
+			low = inputs[1].Data[0]
+		}
+		if len(inputs) > 2 && inputs[2] != nil && len(inputs[2].Data) == 1 {
+			high = inputs[2].Data[0]
+		}
+	}
+
+	return unary(func(x float64) float64 { return math.Min(math.Max(x, low), high) })(n, inputs, opset)
+}
+
+func constant(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	for name, a := range n.attributes {
+		switch name {
+		case "value":
+			if a.t != nil {
+				return []*Tensor{a.t}, nil
+			}
+		case "value_float":
+			return []*Tensor{{Shape: []int{}, Data: []float64{a.f}}}, nil
+		case "value_int":
+			return []*Tensor{{Shape: []int{}, Data: []float64{float64(a.i)}}}, nil
+		case "value_floats":
+			return []*Tensor{{Shape: []int{len(a.floats)}, Data: a.floats}}, nil
+		case "value_ints":
+			data := make([]float64, len(a.ints))
+			for i, v := range a.ints {
+				data[i] = float64(v)
+			}
+			return []*Tensor{{Shape: []int{len(data)}, Data: data}}, nil
+		}
+	}
+
+	return nil, errors.New("constant without a supported value")
+}
+
+func transpose(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 1); err != nil {
+		return nil, err
+	}
+
+	in := inputs[0]
+	rank := len(in.Shape)
+
+	perm := n.ints("perm", nil, 0)
+	if perm == nil {
+		for i := rank - 1; i >= 0; i-- {
+			perm = append(perm, int64(i))
+		}
+	}
+
+	if len(perm) != rank {
+		return nil, fmt.Errorf("transpose of rank %d with the permutation %v", rank, perm)
+	}
+
+	strides := make([]int, rank)
+	for i, stride := rank-1, 1; i >= 0; i-- {
+		strides[i], stride = stride, stride*in.Shape[i]
+	}
+
+	shape, outStrides := make([]int, rank), make([]int, rank)
+	for i, p := range perm {
+		if p < 0 || int(p) >= rank {
+			return nil, fmt.Errorf("transpose permutation %v", perm)
+		}
+		shape[i], outStrides[i] = in.Shape[p], strides[p]
+	}
+
+	out := &Tensor{Shape: shape, Data: make([]float64, len(in.Data))}
+	index := make([]int, rank)
+
+	for k := range out.Data {
+
+		from := 0
+		for i := range index {
+			from += index[i] * outStrides[i]
+		}
+		out.Data[k] = in.Data[from]
+
+		for i := rank - 1; i >= 0; i-- {
+			if index[i]++; index[i] < shape[i] {
+				break
+			}
+			index[i] = 0
+		}
+	}
+
+	return []*Tensor{out}, nil
+}
+
+func squeeze(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 1); err != nil {
+		return nil, err
+	}
+
+	in := inputs[0]
+	removed := make(map[int]bool)
+
+	for _, a := range n.ints("axes", inputs, 1) {
+		ax, err := axis(a, len(in.Shape))
+		if err != nil {
+			return nil, err
+		}
+		removed[ax] = true
+	}
+
+	shape := make([]int, 0, len(in.Shape))
+	for i, d := range in.Shape {
+		if len(removed) == 0 && d == 1 || removed[i] {
+			continue
+		}
+		shape = append(shape, d)
+	}
+
+	return []*Tensor{{Shape: shape, Data: in.Data}}, nil
+}
+
+func unsqueeze(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 1); err != nil {
+		return nil, err
+	}
+
+	in := inputs[0]
+	axes := n.ints("axes", inputs, 1)
+	rank := len(in.Shape) + len(axes)
+	inserted := make(map[int]bool)
+
+	for _, a := range axes {
+		ax, err := axis(a, rank)
+		if err != nil {
+			return nil, err
+		}
+		inserted[ax] = true
+	}
+
+	shape := make([]int, 0, rank)
+	for i, j := 0, 0; i < rank; i++ {
+		if inserted[i] {
+			shape = append(shape, 1)
+		} else if j < len(in.Shape) {
+			shape, j = append(shape, in.Shape[j]), j+1
+		}
+	}
+
+	return []*Tensor{{Shape: shape, Data: in.Data}}, nil
+}
+
+func argMax(n *node, inputs []*Tensor, opset int64) ([]*Tensor, error) {
+
+	if err := required(inputs, 1); err != nil {
+		return nil, err
+	}
+
+	in := inputs[0]
+
+	ax, err := axis(n.int("axis", 0), len(in.Shape))
+	if err != nil {
+		return nil, err
+	}
+
+	last := n.int("select_last_index", 0) != 0
+	outer, length, inner := product(in.Shape[:ax]), in.Shape[ax], product(in.Shape[ax+1:])
+
+	shape := append([]int(nil), in.Shape...)
+	if n.int("keepdims", 1) != 0 {
+		shape[ax] = 1
+	} else {
+		shape = append(shape[:ax], shape[ax+1:]...)
+	}
+
+	out := &Tensor{Shape: shape, Data: make([]float64, 0, outer*inner)}
+
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			best := 0
+			for k := 1; k < length; k++ {
+				v, b := in.Data[(o*length+k)*inner+i], in.Data[(o*length+best)*inner+i]
+				if v > b || last && v == b {
+					best = k
+				}
+			}
+			out.Data = append(out.Data, float64(best))
+		}
+	}
+
+	return []*Tensor{out}, nil
+}
+
+// unsupported returns the operators of the nodes not supported, by name order.
+func unsupported(nodes []*node) string {
+
+	names := make(map[string]bool)
+	for _, n := range nodes {
+		if _, exist := ops[n.opType]; !exist || n.domain != "" && n.domain != "ai.onnx" {
+			names[strings.TrimPrefix(n.domain+"."+n.opType, ".")] = true
+		}
+	}
+
+	list := make([]string, 0, len(names))
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+
+	return strings.Join(list, ", ")
+}
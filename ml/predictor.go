@@ -0,0 +1,244 @@
+package ml
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/indicator"
+	"github.com/luismcruz/gotrader/signals"
+)
+
+// Decision returns the direction and the strength, from 0 to 1, of the signal of the model outputs.
+type Decision func(outputs []float64) (signals.Direction, float64)
+
+// Sign decides on the single output of a regression, e.g. the expected return: long above the threshold, short
+// below its opposite and flat otherwise, with the absolute output as the strength, capped at 1.
+func Sign(threshold float64) Decision {
+	return func(outputs []float64) (signals.Direction, float64) {
+
+		if len(outputs) == 0 {
+			return signals.Flat, 0
+		}
+
+		y := outputs[0]
+		strength := math.Min(math.Abs(y), 1)
+
+		switch {
+		case y > threshold:
+			return signals.Long, strength
+		case y < -threshold:
+			return signals.Short, strength
+		default:
+			return signals.Flat, 0
+		}
+	}
+}
+
+// Classes decides on the probabilities of the classes of a classifier, e.g. the softmax output: the direction of
+// the most probable class, with its probability as the strength. The directions are given in the class order.
+func Classes(directions ...signals.Direction) Decision {
+	return func(outputs []float64) (signals.Direction, float64) {
+
+		if len(outputs) != len(directions) {
+			return signals.Flat, 0
+		}
+
+		best := 0
+		for i, p := range outputs {
+			if p > outputs[best] {
+				best = i
+			}
+		}
+
+		if directions[best] == signals.Flat {
+			return signals.Flat, 0
+		}
+
+		return directions[best], math.Min(math.Max(outputs[best], 0), 1)
+	}
+}
+
+/*
+PredictorConfig sets the features of a Predictor, the values of its indicators in order, and the signals published.
+The signals expire after Expiry, the candle timeframe when zero, so a consumer goes flat if the predictions stop.
+*/
+type PredictorConfig struct {
+	Source    string
+	Features  []indicator.Indicator
+	Decision  Decision // Sign(0) when nil
+	Expiry    time.Duration
+	Publisher signals.Publisher
+}
+
+/*
+Predictor runs a model on the features of each candle of an instrument timeframe and publishes the signal decided.
+It is an indicator, attached after its features so the pipeline computes them first, with the first model output as
+its value. The model is run once the features are warmed up, the signals timed at the close of the candles.
+*/
+type Predictor struct {
+	model   *Model
+	config  PredictorConfig
+	logger  gotrader.Logger
+	mutex   *sync.Mutex
+	candles int
+	outputs []float64
+	last    *signals.Signal
+	err     error
+}
+
+// NewPredictor is the Predictor constructor, a nil logger defaults to gotrader.DefaultLogger. An error is returned
+// if the model does not take a single input of the features.
+func NewPredictor(model *Model, config PredictorConfig, logger gotrader.Logger) (*Predictor, error) {
+
+	if model == nil || config.Publisher == nil {
+		return nil, errors.New("predictor without model or publisher")
+	}
+
+	if len(config.Features) == 0 {
+		return nil, errors.New("predictor without features")
+	}
+
+	inputs := model.graph.inputs
+	if len(inputs) != 1 {
+		return nil, fmt.Errorf("model of %d inputs", len(inputs))
+	}
+
+	if dims := inputs[0].dims; len(dims) > 0 && dims[len(dims)-1] > 0 && dims[len(dims)-1] != int64(len(config.Features)) {
+		return nil, fmt.Errorf("%d features for the input %s of %d", len(config.Features), inputs[0].name,
+			dims[len(dims)-1])
+	}
+
+	if config.Decision == nil {
+		config.Decision = Sign(0)
+	}
+
+	if logger == nil {
+		logger = gotrader.DefaultLogger()
+	}
+
+	return &Predictor{
+		model:  model,
+		config: config,
+		logger: logger,
+		mutex:  &sync.Mutex{},
+	}, nil
+}
+
+// OnCandle implements indicator.Indicator, running the model on the features of the candle.
+func (p *Predictor) OnCandle(candle *gotrader.Candle) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.candles++
+
+	if p.candles < p.WarmupPeriod() {
+		return
+	}
+
+	features := make([]float64, len(p.config.Features))
+	for i, feature := range p.config.Features {
+		features[i] = feature.Value()
+	}
+
+	outputs, err := p.model.Predict(features)
+	if err != nil {
+		p.fail(fmt.Errorf("%s %s: %w", candle.Instrument, candle.Time, err))
+		return
+	}
+
+	p.outputs = outputs
+
+	direction, strength := p.config.Decision(outputs)
+
+	expiry := p.config.Expiry
+	if expiry == 0 {
+		expiry = candle.Timeframe
+	}
+
+	closed := candle.Time.Add(candle.Timeframe)
+
+	signal := signals.Signal{
+		Source:     p.config.Source,
+		Instrument: candle.Instrument,
+		Direction:  direction,
+		Strength:   strength,
+		Time:       closed,
+		Expiry:     closed.Add(expiry),
+	}
+
+	if err := p.config.Publisher.Publish(signal); err != nil {
+		p.fail(fmt.Errorf("%s %s: %w", candle.Instrument, candle.Time, err))
+		return
+	}
+
+	p.last = &signal
+	p.err = nil
+}
+
+func (p *Predictor) fail(err error) {
+	p.err = err
+	p.logger.Error(err)
+}
+
+// OnTick implements indicator.Indicator, the model is only run on the candles.
+func (p *Predictor) OnTick(tick *gotrader.Tick) {}
+
+// Value implements indicator.Indicator, the first output of the last run of the model.
+func (p *Predictor) Value() float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.outputs) == 0 {
+		return 0
+	}
+
+	return p.outputs[0]
+}
+
+// WarmupPeriod implements indicator.Indicator, the candles warming up every feature.
+func (p *Predictor) WarmupPeriod() int {
+
+	period := 0
+	for _, feature := range p.config.Features {
+		period = max(period, feature.WarmupPeriod())
+	}
+
+	return period
+}
+
+// Dependencies implements indicator.Dependent.
+func (p *Predictor) Dependencies() []indicator.Indicator {
+	return p.config.Features
+}
+
+// Outputs returns the outputs of the last run of the model.
+func (p *Predictor) Outputs() []float64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]float64(nil), p.outputs...)
+}
+
+// Last returns the last signal published, false if there is none.
+func (p *Predictor) Last() (signals.Signal, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.last == nil {
+		return signals.Signal{}, false
+	}
+
+	return *p.last, true
+}
+
+// Err returns the error of the last candle, of the model or the publisher, nil if the signal was published.
+func (p *Predictor) Err() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.err
+}
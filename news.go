@@ -0,0 +1,135 @@
+package gotrader
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Impact is the expected market impact of an EconomicEvent.
+type Impact int
+
+const (
+	LowImpact Impact = iota
+	MediumImpact
+	HighImpact
+)
+
+func (i Impact) String() string {
+
+	names := [...]string{"LOW", "MEDIUM", "HIGH"}
+
+	if i < LowImpact || i > HighImpact {
+		return "UNKNOWN"
+	}
+
+	return names[i]
+}
+
+// EconomicEvent is a scheduled release of an economic calendar, e.g. the US non-farm payrolls.
+type EconomicEvent struct {
+	Name     string
+	Currency string // affected currency, e.g. USD
+	Impact   Impact
+	Time     time.Time
+}
+
+// EconomicCalendar returns the economic events after from and not after to.
+type EconomicCalendar interface {
+	Events(from, to time.Time) []EconomicEvent
+}
+
+// EconomicEvents is an EconomicCalendar of a fixed list of events, sorted by time.
+type EconomicEvents []EconomicEvent
+
+// Events implements EconomicCalendar.
+func (e EconomicEvents) Events(from, to time.Time) []EconomicEvent {
+
+	first := sort.Search(len(e), func(i int) bool { return e[i].Time.After(from) })
+	last := sort.Search(len(e), func(i int) bool { return e[i].Time.After(to) })
+
+	if first >= last {
+		return nil
+	}
+
+	return e[first:last]
+}
+
+/*
+NewsPolicy pauses the opens of the instruments of an affected currency, base or quote, from Before to After an
+event of the calendar with at least the Impact: the market orders and the pending orders are rejected with
+ErrTradingPaused and the backtests reject the pending orders triggered during the pause. The trades can still
+be closed.
+
+The SpreadFactor widens the spreads of the backtest ticks around their mid price during the pauses, e.g. 3 to
+triple them, the spreads are unchanged when it is zero or one.
+*/
+type NewsPolicy struct {
+	Calendar     EconomicCalendar
+	Impact       Impact // minimum impact of the events pausing the trading
+	Before       time.Duration
+	After        time.Duration
+	SpreadFactor float64
+}
+
+// PauseAroundNews is the functional option to pause the opens around the economic events of a NewsPolicy.
+func PauseAroundNews(policy NewsPolicy) Option {
+	return func(p *sessionParameters) {
+		p.news = &policy
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// event returns the first event pausing the trading of the currencies at t.
+func (p *NewsPolicy) event(base, quote string, t time.Time) (EconomicEvent, bool) {
+
+	if p == nil || p.Calendar == nil {
+		return EconomicEvent{}, false
+	}
+
+	for _, event := range p.Calendar.Events(t.Add(-p.After), t.Add(p.Before)) {
+		if event.Impact >= p.Impact && (event.Currency == base || event.Currency == quote) {
+			return event, true
+		}
+	}
+
+	return EconomicEvent{}, false
+}
+
+// check returns ErrTradingPaused when the opens of a traded instrument are paused at t.
+func (p *NewsPolicy) check(account *Account, instrument string, t time.Time) error {
+
+	inst, exist := account.instruments[instrument]
+	if !exist {
+		return nil
+	}
+
+	if event, paused := p.event(inst.baseCurrency, inst.quoteCurrency, t); paused {
+		return fmt.Errorf("%s: %w by %s %s at %s", instrument, ErrTradingPaused, event.Currency, event.Name,
+			event.Time.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// widen multiplies the spread of a tick of the instrument by the spread factor during the pauses.
+func (p *NewsPolicy) widen(inst *Instrument, tick *Tick) {
+
+	if p == nil || p.SpreadFactor == 0 || p.SpreadFactor == 1 {
+		return
+	}
+
+	if _, paused := p.event(inst.baseCurrency, inst.quoteCurrency, tick.Time); !paused {
+		return
+	}
+
+	mid := (tick.Bid + tick.Ask) / 2
+	half := (tick.Ask - tick.Bid) / 2 * p.SpreadFactor
+
+	tick.Bid, tick.Ask = mid-half, mid+half
+}
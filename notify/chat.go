@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+func checkResponse(resp *http.Response, err error) error {
+
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("chat service responded " + resp.Status)
+	}
+
+	return nil
+}
+
+// Slack sends messages to a Slack incoming webhook.
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlack is the Slack constructor, a nil client defaults to one with a 10 seconds timeout.
+func NewSlack(webhookURL string, client *http.Client) *Slack {
+
+	if client == nil {
+		client = defaultClient
+	}
+
+	return &Slack{url: webhookURL, client: client}
+}
+
+// Send implements Sender.
+func (s *Slack) Send(text string) error {
+
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	return checkResponse(s.client.Post(s.url, "application/json", bytes.NewReader(body)))
+}
+
+// TelegramAPI is the base URL of the Telegram bot API.
+var TelegramAPI = "https://api.telegram.org"
+
+// Telegram sends messages to a Telegram chat through a bot.
+type Telegram struct {
+	token  string
+	chatID string
+	client *http.Client
+}
+
+// NewTelegram is the Telegram constructor, a nil client defaults to one with a 10 seconds timeout.
+func NewTelegram(botToken, chatID string, client *http.Client) *Telegram {
+
+	if client == nil {
+		client = defaultClient
+	}
+
+	return &Telegram{token: botToken, chatID: chatID, client: client}
+}
+
+// Send implements Sender.
+func (t *Telegram) Send(text string) error {
+
+	form := url.Values{}
+	form.Set("chat_id", t.chatID)
+	form.Set("text", text)
+
+	return checkResponse(t.client.PostForm(TelegramAPI+"/bot"+t.token+"/sendMessage", form))
+}
@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/tools"
+)
+
+// Sender delivers a text message to a chat service.
+type Sender interface {
+	Send(text string) error
+}
+
+// DefaultTemplates are the text/template message templates of each event type, executed with the event Payload.
+var DefaultTemplates = map[gotrader.EventType]string{
+	gotrader.TradeOpenedEvent:  `Opened {{.Data.Side}} {{.Data.Units}} {{.Data.Instrument}} @ {{.Data.OpenPrice}} (trade {{.Data.ID}})`,
+	gotrader.TradeClosedEvent:  `Closed trade {{.Data.TradeID}} {{.Data.Side}} {{.Data.Units}} {{.Data.Instrument}} @ {{.Data.Price}}, profit {{printf "%.2f" .Data.Profit}}`,
+	gotrader.OrderFilledEvent:  `{{if .Data.Error}}Order on {{.Data.Instrument}} failed: {{.Data.Error}}{{else}}Filled {{.Data.Side}} {{.Data.Units}} {{.Data.Instrument}} @ {{.Data.Price}}{{end}}`,
+	gotrader.MarginCallEvent:   `MARGIN CALL: equity {{printf "%.2f" .Data.Equity}}, margin used {{printf "%.2f" .Data.MarginUsed}}, level {{printf "%.0f" (percent .Data.MarginLevel)}}%`,
+	gotrader.PriceStaleEvent:   `No prices for {{.Data.Instrument}} since {{.Data.LastUpdate.Format "2006-01-02 15:04:05 MST"}}`,
+	gotrader.SessionCloseEvent: `Trading session closed at {{.Time.Format "2006-01-02 15:04:05 MST"}}`,
+}
+
+var functions = template.FuncMap{
+	"percent": func(v float64) float64 { return v * 100 },
+}
+
+// NotifierOption represents a Notifier functional option
+type NotifierOption func(n *Notifier)
+
+// Template is the functional option to replace the message template of an event type.
+func Template(event gotrader.EventType, text string) NotifierOption {
+	return func(n *Notifier) {
+		n.texts[event] = text
+	}
+}
+
+// Types is the functional option to choose the notified event types, defaults to margin calls, stale prices,
+// closed trades and the session close.
+func Types(types ...gotrader.EventType) NotifierOption {
+	return func(n *Notifier) {
+		n.types = types
+	}
+}
+
+// Limit is the functional option to define the messages rate limit, defaults to 1 per second with bursts of 5,
+// 2 of them reserved to the urgent messages (margin calls and stale prices).
+func Limit(budget tools.Budget) NotifierOption {
+	return func(n *Notifier) {
+		n.limiter = tools.NewRateLimiter(budget)
+	}
+}
+
+// NotifierLogger is the functional option to define the notifier logger.
+func NotifierLogger(logger gotrader.Logger) NotifierOption {
+	return func(n *Notifier) {
+		n.logger = logger
+	}
+}
+
+/*
+Notifier sends human readable messages of the event bus events to a chat service (see NewSlack and
+NewTelegram). Messages are rate limited, and urgent ones (margin calls and stale prices) are sent before
+the queued ones and can use reserved tokens, so they are not delayed by a burst of trade notifications.
+*/
+type Notifier struct {
+	sender    Sender
+	texts     map[gotrader.EventType]string
+	templates map[gotrader.EventType]*template.Template
+	types     []gotrader.EventType
+	limiter   *tools.RateLimiter
+	logger    gotrader.Logger
+}
+
+// NewNotifier is the Notifier constructor, it fails if a template can't be parsed.
+func NewNotifier(sender Sender, opts ...NotifierOption) (*Notifier, error) {
+
+	n := &Notifier{
+		sender:    sender,
+		texts:     make(map[gotrader.EventType]string),
+		templates: make(map[gotrader.EventType]*template.Template),
+		types: []gotrader.EventType{
+			gotrader.MarginCallEvent,
+			gotrader.PriceStaleEvent,
+			gotrader.TradeClosedEvent,
+			gotrader.SessionCloseEvent,
+		},
+		limiter: tools.NewRateLimiter(tools.Budget{Rate: 1, Burst: 5, Reserve: 2}),
+	}
+
+	for t, text := range DefaultTemplates {
+		n.texts[t] = text
+	}
+
+	for _, o := range opts {
+		o(n)
+	}
+
+	if n.logger == nil {
+		n.logger = gotrader.DefaultLogger()
+	}
+
+	for t, text := range n.texts {
+		tmpl, err := template.New(t.String()).Funcs(functions).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		n.templates[t] = tmpl
+	}
+
+	return n, nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Message returns the message of an event.
+func (n *Notifier) Message(event gotrader.Event) (string, error) {
+
+	tmpl, exist := n.templates[event.Type()]
+	if !exist {
+		return event.Type().String(), nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, NewPayload(event)); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// Notify sends the message of an event without blocking, waiting for the rate limit on its own goroutine.
+func (n *Notifier) Notify(event gotrader.Event) {
+
+	text, err := n.Message(event)
+	if err != nil {
+		n.logger.Error(err)
+		return
+	}
+
+	priority := tools.Normal
+	if event.Type() == gotrader.MarginCallEvent || event.Type() == gotrader.PriceStaleEvent {
+		priority = tools.RiskReducing
+	}
+
+	go func() {
+
+		n.limiter.Wait("messages", priority)
+
+		if err := n.sender.Send(text); err != nil {
+			n.logger.Errorf("notification %q failed: %v", text, err)
+		}
+	}()
+}
+
+// Attach subscribes the notifier to the event types of the event bus, unsubscribe to stop it.
+func (n *Notifier) Attach(bus *gotrader.EventBus) *gotrader.Subscription {
+	return bus.Subscribe(n.Notify, 0, n.types...)
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+type recorder chan string
+
+func (r recorder) Send(text string) error {
+	r <- text
+	return nil
+}
+
+func TestNotifier(t *testing.T) {
+
+	t.Run("default templates", func(t *testing.T) {
+
+		n, err := NewNotifier(make(recorder))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		events := map[gotrader.Event]string{
+			gotrader.MarginCall{Equity: 50, MarginUsed: 100, MarginLevel: 0.5}: "MARGIN CALL: equity 50.00, margin used 100.00, level 50%",
+			gotrader.TradeClosed{Fill: &gotrader.OrderFill{
+				TradeClose: true,
+				TradeID:    "7",
+				Side:       gotrader.Long,
+				Units:      1000,
+				Instrument: gotrader.InstrumentDetails{Name: "EUR_USD"},
+				Price:      1.1,
+				Profit:     -12.345,
+			}}: "Closed trade 7 LONG 1000 EUR_USD @ 1.1, profit -12.35",
+		}
+
+		for event, expected := range events {
+			if msg, err := n.Message(event); err != nil || msg != expected {
+				t.Errorf("expected %q, got %q (%v)", expected, msg, err)
+			}
+		}
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+
+		r := make(recorder, 1)
+
+		n, err := NewNotifier(r, Types(gotrader.SessionCloseEvent), Template(gotrader.SessionCloseEvent, "bye"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n.Notify(gotrader.SessionClose{Time: time.Now()})
+
+		select {
+		case msg := <-r:
+			if msg != "bye" {
+				t.Errorf("expected the custom template, got %q", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message was not sent")
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package notify
+
+import (
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Payload is the JSON representation of an event sent to the external systems.
+type Payload struct {
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// TradePayload is the Payload data of the TRADE_OPENED and TRADE_UPDATED events.
+type TradePayload struct {
+	ID         string    `json:"id"`
+	Instrument string    `json:"instrument"`
+	Side       string    `json:"side"`
+	Units      int32     `json:"units"`
+	OpenPrice  float64   `json:"openPrice"`
+	OpenTime   time.Time `json:"openTime"`
+	StopLoss   float64   `json:"stopLoss,omitempty"`
+	TakeProfit float64   `json:"takeProfit,omitempty"`
+	Tag        string    `json:"tag,omitempty"`
+}
+
+// FillPayload is the Payload data of the TRADE_CLOSED and ORDER_FILLED events.
+type FillPayload struct {
+	Error       string    `json:"error,omitempty"`
+	TradeClose  bool      `json:"tradeClose"`
+	OrderID     string    `json:"orderId,omitempty"`
+	TradeID     string    `json:"tradeId,omitempty"`
+	Instrument  string    `json:"instrument"`
+	Side        string    `json:"side"`
+	Price       float64   `json:"price"`
+	Units       int32     `json:"units"`
+	Profit      float64   `json:"profit"`
+	ChargedFees float64   `json:"chargedFees"`
+	Time        time.Time `json:"time"`
+	Venue       string    `json:"venue,omitempty"`
+	Tag         string    `json:"tag,omitempty"`
+}
+
+// OrderPayload is the Payload data of the ORDER_SUBMITTED events.
+type OrderPayload struct {
+	ID          string    `json:"id,omitempty"`
+	Type        string    `json:"type"`
+	Instrument  string    `json:"instrument"`
+	Side        string    `json:"side"`
+	Units       int32     `json:"units"`
+	Price       float64   `json:"price,omitempty"`
+	StopLoss    float64   `json:"stopLoss,omitempty"`
+	TakeProfit  float64   `json:"takeProfit,omitempty"`
+	TimeInForce string    `json:"timeInForce"`
+	Expiry      time.Time `json:"expiry,omitempty"`
+	Tag         string    `json:"tag,omitempty"`
+}
+
+// TransactionPayload is the Payload data of the TRANSACTION_RECORDED events.
+type TransactionPayload struct {
+	Type       string    `json:"type"`
+	TradeID    string    `json:"tradeId,omitempty"`
+	Instrument string    `json:"instrument,omitempty"`
+	Side       string    `json:"side,omitempty"`
+	Units      int32     `json:"units,omitempty"`
+	OpenPrice  float64   `json:"openPrice,omitempty"`
+	ClosePrice float64   `json:"closePrice,omitempty"`
+	Amount     float64   `json:"amount"`
+	Fees       float64   `json:"fees,omitempty"`
+	Balance    float64   `json:"balance"`
+	Time       time.Time `json:"time"`
+	Tag        string    `json:"tag,omitempty"`
+}
+
+// NewPayload converts an event to its Payload.
+func NewPayload(event gotrader.Event) *Payload {
+
+	p := &Payload{Type: event.Type().String()}
+
+	switch e := event.(type) {
+	case gotrader.TradeOpened:
+		p.Time = e.Time
+		p.Data = newTradePayload(e.Trade)
+	case gotrader.TradeUpdated:
+		p.Time = e.Time
+		p.Data = newTradePayload(e.Trade)
+	case gotrader.TradeClosed:
+		p.Time = e.Time
+		p.Data = newFillPayload(e.Fill)
+	case gotrader.OrderFilled:
+		p.Time = e.Time
+		p.Data = newFillPayload(e.Fill)
+	case gotrader.MarginCall:
+		p.Time = e.Time
+		p.Data = e
+	case gotrader.PriceStale:
+		p.Time = e.Time
+		p.Data = e
+	case gotrader.SessionClose:
+		p.Time = e.Time
+	case gotrader.OrderSubmitted:
+		p.Time = e.Time
+		p.Data = newOrderPayload(e.Order)
+	case gotrader.OrderExpired:
+		p.Time = e.Time
+		p.Data = newOrderPayload(e.Order)
+	case gotrader.TransactionRecorded:
+		p.Time = e.Time
+		p.Data = newTransactionPayload(e.Transaction)
+	}
+
+	return p
+}
+
+func newOrderPayload(order *gotrader.Order) *OrderPayload {
+	return &OrderPayload{
+		ID:          order.ID,
+		Type:        order.Type.String(),
+		Instrument:  order.Instrument,
+		Side:        order.Side.String(),
+		Units:       order.Units,
+		Price:       order.Price,
+		StopLoss:    order.StopLoss,
+		TakeProfit:  order.TakeProfit,
+		TimeInForce: order.TimeInForce.String(),
+		Expiry:      order.Expiry,
+		Tag:         order.Tag,
+	}
+}
+
+func newTradePayload(trade *gotrader.Trade) *TradePayload {
+	return &TradePayload{
+		ID:         trade.ID(),
+		Instrument: trade.InstrumentName(),
+		Side:       trade.Side().String(),
+		Units:      trade.Units(),
+		OpenPrice:  trade.OpenPrice(),
+		OpenTime:   trade.OpenTime(),
+		StopLoss:   trade.StopLoss(),
+		TakeProfit: trade.TakeProfit(),
+		Tag:        trade.Tag(),
+	}
+}
+
+func newFillPayload(fill *gotrader.OrderFill) *FillPayload {
+	return &FillPayload{
+		Error:       fill.Error,
+		TradeClose:  fill.TradeClose,
+		OrderID:     fill.OrderID,
+		TradeID:     fill.TradeID,
+		Instrument:  fill.Instrument.Name,
+		Side:        fill.Side.String(),
+		Price:       fill.Price,
+		Units:       fill.Units,
+		Profit:      fill.Profit,
+		ChargedFees: fill.ChargedFees,
+		Time:        fill.Time,
+		Venue:       fill.Venue,
+		Tag:         fill.Tag,
+	}
+}
+
+func newTransactionPayload(t *gotrader.Transaction) *TransactionPayload {
+
+	p := &TransactionPayload{
+		Type:       t.Type.String(),
+		TradeID:    t.TradeID,
+		Instrument: t.Instrument,
+		Units:      t.Units,
+		OpenPrice:  t.OpenPrice,
+		ClosePrice: t.ClosePrice,
+		Amount:     t.Amount,
+		Fees:       t.Fees,
+		Balance:    t.Balance,
+		Time:       t.Time,
+		Tag:        t.Tag,
+	}
+
+	if !t.Type.External() {
+		p.Side = t.Side.String()
+	}
+
+	return p
+}
@@ -0,0 +1,328 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/tools"
+	"go.uber.org/atomic"
+)
+
+// SignatureHeader is the header with the hex HMAC-SHA256 of the request body, when the webhook has a secret.
+const SignatureHeader = "X-Gotrader-Signature"
+
+// Webhook is an URL receiving the events of the given types, every event if no type is given.
+type Webhook struct {
+	URL    string
+	Secret string // signs the payloads when not empty
+	Types  []gotrader.EventType
+}
+
+func (w *Webhook) wants(t gotrader.EventType) bool {
+
+	if len(w.Types) == 0 {
+		return true
+	}
+
+	for _, wt := range w.Types {
+		if wt == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DeliveryStatus represents the state of a webhook delivery.
+type DeliveryStatus int
+
+const (
+	DeliveryPending DeliveryStatus = iota
+	DeliveryDelivered
+	DeliveryFailed
+)
+
+func (s DeliveryStatus) String() string {
+
+	names := [...]string{"PENDING", "DELIVERED", "FAILED"}
+
+	return names[s]
+}
+
+// Delivery is the status of a payload sent to a webhook.
+type Delivery struct {
+	ID         int64
+	URL        string
+	Event      gotrader.EventType
+	Status     DeliveryStatus
+	Attempts   int
+	StatusCode int    // of the last attempt
+	Error      string // of the last attempt
+	Time       time.Time
+}
+
+// DeliveryHandler represents the delivery status handler function type
+type DeliveryHandler func(delivery Delivery)
+
+// WebhookOption represents a WebhookDispatcher functional option
+type WebhookOption func(d *WebhookDispatcher)
+
+// HTTPClient is the functional option to define the http client used to post the payloads.
+func HTTPClient(client *http.Client) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.client = client
+	}
+}
+
+// Retry is the functional option to define the delays between delivery attempts and their number,
+// defaults to 5 attempts from 1 second to 1 minute.
+func Retry(backoff *tools.Backoff) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.backoff = backoff
+	}
+}
+
+// History is the functional option to define how many deliveries are kept, defaults to 1000.
+func History(size int) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.history = size
+	}
+}
+
+// OnDelivery is the functional option to receive the delivery status changes.
+func OnDelivery(handler DeliveryHandler) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.handler = handler
+	}
+}
+
+// WebhookLogger is the functional option to define the dispatcher logger.
+func WebhookLogger(logger gotrader.Logger) WebhookOption {
+	return func(d *WebhookDispatcher) {
+		d.logger = logger
+	}
+}
+
+/*
+WebhookDispatcher POSTs the JSON Payload of the event bus events to the configured webhooks. Failed deliveries
+(network errors, 429 and 5xx responses) are retried with an exponential backoff, and the status of the
+latest deliveries is kept to be inspected.
+*/
+type WebhookDispatcher struct {
+	mutex      *sync.RWMutex
+	webhooks   []Webhook
+	client     *http.Client
+	backoff    *tools.Backoff
+	history    int
+	deliveries []*Delivery
+	sequence   *atomic.Int64
+	handler    DeliveryHandler
+	logger     gotrader.Logger
+}
+
+// NewWebhookDispatcher is the WebhookDispatcher constructor.
+func NewWebhookDispatcher(webhooks []Webhook, opts ...WebhookOption) *WebhookDispatcher {
+
+	d := &WebhookDispatcher{
+		mutex:    &sync.RWMutex{},
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		backoff:  tools.NewBackoff(time.Second, time.Minute, 5),
+		history:  1000,
+		sequence: atomic.NewInt64(0),
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	if d.logger == nil {
+		d.logger = gotrader.DefaultLogger()
+	}
+
+	return d
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (d *WebhookDispatcher) record(delivery *Delivery) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.deliveries = append(d.deliveries, delivery)
+
+	if len(d.deliveries) > d.history {
+		d.deliveries = d.deliveries[len(d.deliveries)-d.history:]
+	}
+}
+
+func (d *WebhookDispatcher) update(delivery *Delivery, fn func(delivery *Delivery)) {
+
+	d.mutex.Lock()
+	fn(delivery)
+	status := *delivery
+	d.mutex.Unlock()
+
+	if d.handler != nil {
+		d.handler(status)
+	}
+}
+
+func (d *WebhookDispatcher) post(webhook Webhook, body []byte) (int, error) {
+
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if webhook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New("webhook responded " + resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) deliver(webhook Webhook, event gotrader.EventType, body []byte) {
+
+	delivery := &Delivery{
+		ID:    d.sequence.Inc(),
+		URL:   webhook.URL,
+		Event: event,
+		Time:  time.Now(),
+	}
+	d.record(delivery)
+
+	for attempt := 0; ; attempt++ {
+
+		code, err := d.post(webhook, body)
+
+		retry := err != nil && (code == 0 || code == http.StatusTooManyRequests || code >= 500)
+		exhausted := d.backoff.Attempts != 0 && attempt+1 >= d.backoff.Attempts
+
+		d.update(delivery, func(delivery *Delivery) {
+			delivery.Attempts = attempt + 1
+			delivery.StatusCode = code
+			delivery.Time = time.Now()
+			delivery.Error = ""
+
+			switch {
+			case err == nil:
+				delivery.Status = DeliveryDelivered
+			case !retry || exhausted:
+				delivery.Status = DeliveryFailed
+				delivery.Error = err.Error()
+			default:
+				delivery.Error = err.Error()
+			}
+		})
+
+		if err == nil {
+			return
+		}
+
+		if !retry || exhausted {
+			d.logger.Errorf("webhook %s delivery %d failed: %v", webhook.URL, delivery.ID, err)
+			return
+		}
+
+		time.Sleep(d.backoff.Delay(attempt))
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Sign returns the hex HMAC-SHA256 of the body, as sent in the SignatureHeader.
+func Sign(secret string, body []byte) string {
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch sends the event to the webhooks that want it, each delivery runs on its own goroutine.
+func (d *WebhookDispatcher) Dispatch(event gotrader.Event) {
+
+	body, err := json.Marshal(NewPayload(event))
+	if err != nil {
+		d.logger.Error(err)
+		return
+	}
+
+	for _, webhook := range d.webhooks {
+		if webhook.wants(event.Type()) {
+			go d.deliver(webhook, event.Type(), body)
+		}
+	}
+}
+
+// Attach subscribes the dispatcher to the event bus, unsubscribe to stop it.
+func (d *WebhookDispatcher) Attach(bus *gotrader.EventBus) *gotrader.Subscription {
+
+	types := make([]gotrader.EventType, 0)
+
+	for _, webhook := range d.webhooks {
+		if len(webhook.Types) == 0 { // at least one webhook wants every event
+			types = nil
+			break
+		}
+		types = append(types, webhook.Types...)
+	}
+
+	return bus.Subscribe(d.Dispatch, 0, types...)
+}
+
+// Deliveries returns the status of the latest deliveries, oldest first.
+func (d *WebhookDispatcher) Deliveries() []Delivery {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	deliveries := make([]Delivery, len(d.deliveries))
+	for i, delivery := range d.deliveries {
+		deliveries[i] = *delivery
+	}
+
+	return deliveries
+}
+
+// Delivery returns the status of a delivery by its ID, false if it is no longer kept.
+func (d *WebhookDispatcher) Delivery(id int64) (Delivery, bool) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	for _, delivery := range d.deliveries {
+		if delivery.ID == id {
+			return *delivery, true
+		}
+	}
+
+	return Delivery{}, false
+}
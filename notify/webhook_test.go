@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/tools"
+	"go.uber.org/atomic"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+
+	t.Run("signed delivery after retries", func(t *testing.T) {
+
+		calls := atomic.NewInt32(0)
+		bodies := make(chan *Payload, 1)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			if calls.Inc() < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Header.Get(SignatureHeader) != Sign("secret", body) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			p := &Payload{}
+			json.Unmarshal(body, p)
+			bodies <- p
+		}))
+		defer server.Close()
+
+		d := NewWebhookDispatcher(
+			[]Webhook{{URL: server.URL, Secret: "secret", Types: []gotrader.EventType{gotrader.MarginCallEvent}}},
+			Retry(tools.NewBackoff(time.Millisecond, time.Millisecond, 5)),
+		)
+
+		d.Dispatch(gotrader.SessionClose{Time: time.Now()}) // not wanted
+		d.Dispatch(gotrader.MarginCall{Time: time.Now(), Equity: 50, MarginUsed: 100, MarginLevel: 0.5})
+
+		select {
+		case p := <-bodies:
+			if p.Type != "MARGIN_CALL" {
+				t.Errorf("expected MARGIN_CALL payload, got %s", p.Type)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("webhook was not delivered")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		deliveries := d.Deliveries()
+		if len(deliveries) != 1 {
+			t.Fatalf("expected 1 delivery, got %d", len(deliveries))
+		}
+
+		if deliveries[0].Status != DeliveryDelivered || deliveries[0].Attempts != 3 {
+			t.Errorf("expected delivered on the 3rd attempt, got %s after %d", deliveries[0].Status, deliveries[0].Attempts)
+		}
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+
+		calls := atomic.NewInt32(0)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Inc()
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		done := make(chan Delivery, 1)
+
+		d := NewWebhookDispatcher(
+			[]Webhook{{URL: server.URL}},
+			Retry(tools.NewBackoff(time.Millisecond, time.Millisecond, 5)),
+			OnDelivery(func(delivery Delivery) {
+				if delivery.Status != DeliveryPending {
+					done <- delivery
+				}
+			}),
+		)
+
+		d.Dispatch(gotrader.SessionClose{Time: time.Now()})
+
+		select {
+		case delivery := <-done:
+			if delivery.Status != DeliveryFailed || delivery.StatusCode != http.StatusBadRequest || calls.Load() != 1 {
+				t.Errorf("expected a single failed attempt, got %s with %d after %d calls", delivery.Status, delivery.StatusCode, calls.Load())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("delivery did not finish")
+		}
+	})
+}
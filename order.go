@@ -0,0 +1,222 @@
+package gotrader
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OrderType represents the execution type of an order.
+type OrderType int
+
+const (
+	// MarketOrder is filled immediately at the current price
+	MarketOrder OrderType = iota
+
+	// LimitOrder is filled when the price reaches a level equal or better than the order price
+	LimitOrder
+
+	// StopOrder is filled when the price crosses the order price
+	StopOrder
+)
+
+func (o OrderType) String() string {
+
+	names := [...]string{"MARKET", "LIMIT", "STOP"}
+
+	return names[o]
+}
+
+// TimeInForce defines how long an order stays pending.
+type TimeInForce int
+
+const (
+	// GoodTillCancelled orders stay pending until filled or cancelled
+	GoodTillCancelled TimeInForce = iota
+
+	// GoodTillDate orders are cancelled at the expiry time
+	GoodTillDate
+
+	// FillOrKill orders are cancelled if they can't be filled immediately
+	FillOrKill
+
+	// ImmediateOrCancel orders are cancelled if they can't be filled immediately, partial fills allowed
+	ImmediateOrCancel
+)
+
+func (t TimeInForce) String() string {
+
+	names := [...]string{"GTC", "GTD", "FOK", "IOC"}
+
+	return names[t]
+}
+
+// Order represents an order request. Price is only used by pending orders, StopLoss and
+// TakeProfit are optional levels attached to the trade once the order is filled (zero means not set), making the
+// order a bracket: they must be on their side of the entry price and cancel each other (see Bracketed).
+// A GuaranteedStop stop loss is filled exactly at its level regardless of the gaps, for a premium charged to
+// the trade when it is attached (see GuaranteedStopPremium).
+// MaxLifetime is the optional maximum holding time of the trade, closed with the Expired reason once it elapses.
+// Tag is an optional label carried to the fills and trades of the order, e.g. the name of the strategy.
+// ClientID is the idempotency key of the submission, generated by SubmitOrder when empty and carried to the
+// fills: submitting an order again with the same ClientID, e.g. after a timeout, does not open it twice.
+type Order struct {
+	ID             string
+	ClientID       string
+	Type           OrderType
+	Instrument     string
+	Side           Side
+	Units          int32
+	Price          float64
+	StopLoss       float64
+	GuaranteedStop bool
+	TakeProfit     float64
+	MaxLifetime    time.Duration
+	TimeInForce    TimeInForce
+	Expiry         time.Time
+	CreateTime     time.Time
+	Tag            string
+	queue          *queuePosition // of the pending limit orders of the backtests, see QueueFills
+}
+
+// expiry returns the time the trade opened by the order at t expires, zero without maximum lifetime.
+func (o *Order) expiry(t time.Time) time.Time {
+
+	if o.MaxLifetime <= 0 {
+		return time.Time{}
+	}
+
+	return t.Add(o.MaxLifetime)
+}
+
+// expired returns true when the order is good till date and its expiry is before t.
+func (o *Order) expired(t time.Time) bool {
+	return o.TimeInForce == GoodTillDate && !o.Expiry.IsZero() && o.Expiry.Before(t)
+}
+
+// Triggered returns true if a pending order should be filled with the current prices.
+func (o *Order) Triggered(bid, ask float64) bool {
+
+	switch o.Type {
+	case LimitOrder:
+		if o.Side == Long {
+			return ask <= o.Price
+		}
+		return bid >= o.Price
+	case StopOrder:
+		if o.Side == Long {
+			return ask >= o.Price
+		}
+		return bid <= o.Price
+	}
+
+	return true
+}
+
+// orderBook keeps the pending orders of a simulated account.
+type orderBook struct {
+	sync.RWMutex
+	orders map[string]*Order
+}
+
+func newOrderBook() *orderBook {
+	return &orderBook{
+		orders: make(map[string]*Order),
+	}
+}
+
+func (b *orderBook) add(order *Order) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.orders[order.ID] = order
+}
+
+func (b *orderBook) get(id string) (*Order, bool) {
+	b.RLock()
+	defer b.RUnlock()
+
+	order, exist := b.orders[id]
+
+	return order, exist
+}
+
+func (b *orderBook) remove(id string) (*Order, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	order, exist := b.orders[id]
+	delete(b.orders, id)
+
+	return order, exist
+}
+
+// expired removes and returns the good till date orders whose expiry is before t.
+func (b *orderBook) expired(t time.Time) []*Order {
+	b.Lock()
+	defer b.Unlock()
+
+	expired := make([]*Order, 0)
+
+	for id, order := range b.orders {
+		if order.expired(t) {
+			expired = append(expired, order)
+			delete(b.orders, id)
+		}
+	}
+
+	return expired
+}
+
+// triggered removes and returns the orders of the instrument that are filled, the trigger being called with every
+// order of the instrument.
+func (b *orderBook) triggered(instrument string, trigger func(order *Order) bool) []*Order {
+	b.Lock()
+	defer b.Unlock()
+
+	triggered := make([]*Order, 0)
+
+	for id, order := range b.orders {
+		if order.Instrument == instrument && trigger(order) {
+			triggered = append(triggered, order)
+			delete(b.orders, id)
+		}
+	}
+
+	return triggered
+}
+
+// pending returns copies of the orders of the instrument, of every instrument when empty, by creation order.
+func (b *orderBook) pending(instrument string) []*Order {
+	b.RLock()
+	defer b.RUnlock()
+
+	orders := make([]*Order, 0, len(b.orders))
+	for _, order := range b.orders {
+		if instrument == "" || order.Instrument == instrument {
+			copied := *order
+			orders = append(orders, &copied)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreateTime.Equal(orders[j].CreateTime) {
+			return orders[i].CreateTime.Before(orders[j].CreateTime)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+
+	return orders
+}
+
+func (b *orderBook) list() []*Order {
+	b.RLock()
+	defer b.RUnlock()
+
+	orders := make([]*Order, 0, len(b.orders))
+	for _, order := range b.orders {
+		orders = append(orders, order)
+	}
+
+	return orders
+}
@@ -0,0 +1,484 @@
+/*
+Package parity checks that a strategy takes the same decisions live and in a backtest: a Harness runs the strategy in
+shadow mode against a live client, filling its orders locally with the paper client while recording the ticks of
+the feed, then runs a new instance of the strategy as a backtest of the recorded ticks and diffs the decisions of
+both runs. A decision taken only in the backtest usually reveals a lookahead bias, one taken only live or at
+another price a mismatch between the environments, e.g. dropped ticks or a dependency on the wall clock.
+
+	h := &parity.Harness{
+		Options:  []gotrader.Option{gotrader.Instruments([]string{"EUR_USD"}), gotrader.InitialBalance(10000)},
+		Strategy: func() gotrader.Strategy { return &strategy{} },
+		Paper:    []paper.Option{paper.Balance(10000)},
+	}
+
+	shadow, err := h.Shadow(client)
+	...
+	time.Sleep(time.Hour)
+	live, err := shadow.Stop()
+	...
+	backtest, err := h.Replay(live)
+	...
+	for _, d := range h.Compare(live, backtest).Differences {
+		log.Println(d)
+	}
+*/
+package parity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/clients/paper"
+)
+
+// Action is the kind of a decision of the strategy.
+type Action int
+
+const (
+	// OpenAction is a market order, Buy or Sell
+	OpenAction Action = iota
+
+	// CloseAction is a trade close
+	CloseAction
+
+	// SubmitAction is an order submission, one per order of a basket
+	SubmitAction
+
+	// ModifyAction is a modification of a submitted order
+	ModifyAction
+
+	// CancelAction is a cancellation of a submitted order
+	CancelAction
+)
+
+func (a Action) String() string {
+
+	names := [...]string{"OPEN", "CLOSE", "SUBMIT", "MODIFY", "CANCEL"}
+
+	return names[a]
+}
+
+/*
+Decision is an order request of the strategy to its engine. The trade and order IDs differ between the runs, so the
+closes carry the side and units of the trade, and the modifications and cancellations the ones of the order.
+*/
+type Decision struct {
+	Time       time.Time // of the tick handled by the strategy, or of its last one outside OnTick
+	Action     Action
+	Type       gotrader.OrderType // of the submitted, modified and cancelled orders
+	Instrument string
+	Side       gotrader.Side
+	Units      int32
+	Price      float64 // of the submitted and modified orders
+	Error      string  // returned by the engine, e.g. ErrMarketClosed
+}
+
+func (d Decision) String() string {
+
+	s := fmt.Sprintf("%s %s %s %s %d", d.Time.Format(time.RFC3339Nano), d.Action, d.Instrument, d.Side, d.Units)
+
+	if d.Action >= SubmitAction {
+		s += fmt.Sprintf(" %s", d.Type)
+		if d.Price != 0 {
+			s += fmt.Sprintf(" @ %v", d.Price)
+		}
+	}
+
+	if d.Error != "" {
+		s += ": " + d.Error
+	}
+
+	return s
+}
+
+// same returns whether the decisions are the same request, regardless of the time and the price.
+func (d Decision) same(other Decision) bool {
+	return d.Action == other.Action && d.Type == other.Type && d.Instrument == other.Instrument &&
+		d.Side == other.Side && d.Units == other.Units && d.Error == other.Error
+}
+
+// Run is the record of a run of the strategy.
+type Run struct {
+	Instruments []gotrader.InstrumentDetails
+	Ticks       []gotrader.Tick // of the live feed, as received before the engine
+	Handled     int             // ticks delivered to the strategy
+	Decisions   []Decision
+	Account     *gotrader.Account
+}
+
+/*
+Harness runs the live and backtest sessions of a strategy. Both sessions are created with Options, so the initial
+balance, home currency and leverage of the backtest should match the Paper options of the shadow session.
+*/
+type Harness struct {
+	Options        []gotrader.Option
+	Strategy       func() gotrader.Strategy // a new instance for every run
+	Paper          []paper.Option
+	Window         time.Duration // accepted between the times of the same decision, exact when zero
+	PriceTolerance float64       // accepted between the prices of the same decision
+}
+
+// Difference is a decision taken only in one run, or taken in both at different prices.
+type Difference struct {
+	Live     *Decision // nil when only taken in the backtest
+	Backtest *Decision // nil when only taken live
+}
+
+func (d Difference) String() string {
+
+	switch {
+	case d.Live == nil:
+		return "only in the backtest: " + d.Backtest.String()
+	case d.Backtest == nil:
+		return "only live: " + d.Live.String()
+	default:
+		return fmt.Sprintf("at %v in the backtest: %s", d.Backtest.Price, d.Live)
+	}
+}
+
+// time returns the time of the difference, the live one when taken in both runs.
+func (d Difference) time() time.Time {
+
+	if d.Live != nil {
+		return d.Live.Time
+	}
+
+	return d.Backtest.Time
+}
+
+// Report is the comparison of the decisions of a live and a backtest run.
+type Report struct {
+	LiveTicks     int // handled by the strategy
+	BacktestTicks int
+	Matched       int
+	Differences   []Difference // by time
+}
+
+// Parity returns whether both runs took the same decisions and handled the same ticks.
+func (r *Report) Parity() bool {
+	return len(r.Differences) == 0 && r.LiveTicks == r.BacktestTicks
+}
+
+// Shadow is a live session of the strategy in shadow mode, see Harness.Shadow.
+type Shadow struct {
+	session  *gotrader.TradingSession
+	recorder *recorder
+	tape     *tape
+	done     chan error
+}
+
+// recorder wraps the strategy of a run, recording its decisions.
+type recorder struct {
+	gotrader.Strategy
+	mutex     *sync.Mutex
+	last      time.Time
+	handled   int
+	decisions []Decision
+	orders    map[string]Decision // submitted by ID
+	started   chan struct{}
+}
+
+// engine records the decisions of the strategy before passing them to the engine of the session.
+type engine struct {
+	gotrader.Engine
+	recorder *recorder
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newRecorder(strategy gotrader.Strategy) *recorder {
+	return &recorder{
+		Strategy: strategy,
+		mutex:    &sync.Mutex{},
+		orders:   make(map[string]Decision),
+		started:  make(chan struct{}),
+	}
+}
+
+func (r *recorder) record(d Decision, err error) error {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	d.Time = r.last
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	r.decisions = append(r.decisions, d)
+
+	return err
+}
+
+func (r *recorder) submitted(id string, d Decision) {
+	r.mutex.Lock()
+	r.orders[id] = d
+	r.mutex.Unlock()
+}
+
+func (r *recorder) order(id string) Decision {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.orders[id]
+}
+
+func (r *recorder) SetEngine(e gotrader.Engine) {
+	r.Strategy.SetEngine(&engine{Engine: e, recorder: r})
+}
+
+func (r *recorder) Initialize() {
+	r.Strategy.Initialize()
+	close(r.started)
+}
+
+func (r *recorder) OnTick(tick *gotrader.Tick) {
+
+	r.mutex.Lock()
+	r.last = tick.Time
+	r.handled++
+	r.mutex.Unlock()
+
+	r.Strategy.OnTick(tick)
+}
+
+func (r *recorder) OnOrderRejected(fill *gotrader.OrderFill, reason gotrader.RejectReason) {
+	if handler, ok := r.Strategy.(gotrader.RejectionHandler); ok {
+		handler.OnOrderRejected(fill, reason)
+	}
+}
+
+// run returns the record of the run.
+func (r *recorder) run(account *gotrader.Account) *Run {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return &Run{
+		Handled:   r.handled,
+		Decisions: append([]Decision(nil), r.decisions...),
+		Account:   account,
+	}
+}
+
+func (e *engine) Buy(instrument string, units int32) error {
+	d := Decision{Action: OpenAction, Instrument: instrument, Side: gotrader.Long, Units: units}
+	return e.recorder.record(d, e.Engine.Buy(instrument, units))
+}
+
+func (e *engine) Sell(instrument string, units int32) error {
+	d := Decision{Action: OpenAction, Instrument: instrument, Side: gotrader.Short, Units: units}
+	return e.recorder.record(d, e.Engine.Sell(instrument, units))
+}
+
+func (e *engine) CloseTrade(instrument string, id string) error {
+
+	d := Decision{Action: CloseAction, Instrument: instrument}
+
+	if inst := e.Account().Instrument(instrument); inst != nil {
+		if trade := inst.Trade(id); trade != nil {
+			d.Side, d.Units = trade.Side(), trade.Units()
+		}
+	}
+
+	return e.recorder.record(d, e.Engine.CloseTrade(instrument, id))
+}
+
+func (e *engine) SubmitOrder(order *gotrader.Order) (string, error) {
+
+	d := Decision{Action: SubmitAction, Type: order.Type, Instrument: order.Instrument, Side: order.Side,
+		Units: order.Units, Price: order.Price}
+
+	id, err := e.Engine.SubmitOrder(order)
+	if err == nil {
+		e.recorder.submitted(id, d)
+	}
+
+	return id, e.recorder.record(d, err)
+}
+
+func (e *engine) SubmitBasket(orders []*gotrader.Order) ([]string, error) {
+
+	ids, err := e.Engine.SubmitBasket(orders)
+
+	for i, order := range orders {
+
+		d := Decision{Action: SubmitAction, Type: order.Type, Instrument: order.Instrument, Side: order.Side,
+			Units: order.Units, Price: order.Price}
+
+		if i < len(ids) {
+			e.recorder.submitted(ids[i], d)
+		}
+
+		e.recorder.record(d, err)
+	}
+
+	return ids, err
+}
+
+func (e *engine) ModifyOrder(id string, order *gotrader.Order) error {
+
+	d := Decision{Action: ModifyAction, Type: order.Type, Instrument: order.Instrument, Side: order.Side,
+		Units: order.Units, Price: order.Price}
+
+	err := e.Engine.ModifyOrder(id, order)
+	if err == nil {
+		e.recorder.submitted(id, d)
+	}
+
+	return e.recorder.record(d, err)
+}
+
+func (e *engine) CancelOrder(id string) error {
+
+	d := e.recorder.order(id)
+	d.Action, d.Price = CancelAction, 0
+
+	return e.recorder.record(d, e.Engine.CancelOrder(id))
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+/*
+Shadow starts a live session of a new instance of the strategy in shadow mode: the prices and the instruments are
+taken from the client, while the orders are filled locally by a paper client with the Paper options, never reaching
+the broker. It returns once the strategy is initialized, the session records the ticks of the client and the
+decisions of the strategy until it is stopped.
+*/
+func (h *Harness) Shadow(client gotrader.BrokerClient) (*Shadow, error) {
+
+	if h.Strategy == nil || client == nil {
+		return nil, errors.New("parity harness requires a strategy factory and a client")
+	}
+
+	s := &Shadow{
+		recorder: newRecorder(h.Strategy()),
+		tape:     newTape(client),
+		done:     make(chan error, 1),
+	}
+
+	s.session = gotrader.NewTradingSession(h.Options...).SetClient(paper.NewPaperClient(s.tape, h.Paper...)).
+		SetStrategy(s.recorder).Live()
+
+	go func() {
+		s.done <- s.session.Start()
+	}()
+
+	select {
+	case <-s.recorder.started:
+		return s, nil
+	case err := <-s.done:
+		if err == nil {
+			err = errors.New("session stopped before the strategy was initialized")
+		}
+		return nil, err
+	}
+}
+
+// Run returns the record of the session so far.
+func (s *Shadow) Run() *Run {
+
+	run := s.recorder.run(s.session.Account())
+	run.Instruments, run.Ticks = s.tape.record()
+
+	return run
+}
+
+// Stop stops the session and returns its record.
+func (s *Shadow) Stop() (*Run, error) {
+
+	s.session.Engine().StopSession()
+
+	if err := <-s.done; err != nil {
+		return nil, err
+	}
+
+	return s.Run(), nil
+}
+
+// Replay runs a new instance of the strategy as a backtest of the ticks of the run, returning its record.
+func (h *Harness) Replay(run *Run) (*Run, error) {
+
+	if h.Strategy == nil || run == nil {
+		return nil, errors.New("parity harness requires a strategy factory and a run")
+	}
+
+	recorder := newRecorder(h.Strategy())
+
+	session := gotrader.NewTradingSession(h.Options...)
+	session.SetStrategy(recorder).SetClient(newReplayClient(run.Instruments, run.Ticks)).Backtest()
+
+	if err := session.Start(); err != nil {
+		return nil, err
+	}
+
+	replay := recorder.run(session.Account())
+	replay.Instruments, replay.Ticks = run.Instruments, run.Ticks
+
+	return replay, nil
+}
+
+// Compare diffs the decisions of a live and a backtest run, matching each live decision with the first same one of
+// the backtest within the Window.
+func (h *Harness) Compare(live, backtest *Run) *Report {
+
+	r := &Report{LiveTicks: live.Handled, BacktestTicks: backtest.Handled}
+	matched := make([]bool, len(backtest.Decisions))
+
+	for i := range live.Decisions {
+
+		l := &live.Decisions[i]
+		found := -1
+
+		for j := range backtest.Decisions {
+
+			b := &backtest.Decisions[j]
+			if matched[j] || !l.same(*b) {
+				continue
+			}
+
+			if gap := l.Time.Sub(b.Time); gap <= h.Window && gap >= -h.Window {
+				found = j
+				break
+			}
+		}
+
+		if found < 0 {
+			r.Differences = append(r.Differences, Difference{Live: l})
+			continue
+		}
+
+		matched[found] = true
+		b := &backtest.Decisions[found]
+
+		if math.Abs(l.Price-b.Price) > h.PriceTolerance {
+			r.Differences = append(r.Differences, Difference{Live: l, Backtest: b})
+		} else {
+			r.Matched++
+		}
+	}
+
+	for j := range backtest.Decisions {
+		if !matched[j] {
+			r.Differences = append(r.Differences, Difference{Backtest: &backtest.Decisions[j]})
+		}
+	}
+
+	sort.SliceStable(r.Differences, func(i, j int) bool {
+		return r.Differences[i].time().Before(r.Differences[j].time())
+	})
+
+	return r
+}
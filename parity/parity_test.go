@@ -0,0 +1,171 @@
+package parity
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/clients/paper"
+	"github.com/luismcruz/gotrader/gotradertest"
+)
+
+// breakout buys 1000 units when the ask crosses the level and closes its trades when the bid falls below it, once
+// its previous request is filled.
+type breakout struct {
+	engine  gotrader.Engine
+	level   float64
+	mutex   sync.Mutex
+	pending bool
+}
+
+func (s *breakout) Initialize()                      {}
+func (s *breakout) SetEngine(engine gotrader.Engine) { s.engine = engine }
+func (s *breakout) OnStop()                          {}
+
+func (s *breakout) OnOrderFill(fill *gotrader.OrderFill) {
+	s.mutex.Lock()
+	s.pending = false
+	s.mutex.Unlock()
+}
+
+func (s *breakout) OnTick(tick *gotrader.Tick) {
+
+	if !s.settled() {
+		return
+	}
+
+	inst := s.engine.Account().Instrument(tick.Instrument)
+
+	if tick.Ask > s.level && inst.TradesNumber() == 0 {
+		s.request(func() error { return s.engine.Buy(tick.Instrument, 1000) })
+	}
+
+	if tick.Bid < s.level {
+		for trade := range inst.Trades() {
+			s.request(func() error { return s.engine.CloseTrade(tick.Instrument, trade.ID()) })
+		}
+	}
+}
+
+// request sends a request pending until its fill, which the backtests notify before the request returns.
+func (s *breakout) request(send func() error) {
+
+	s.mutex.Lock()
+	s.pending = true
+	s.mutex.Unlock()
+
+	if err := send(); err != nil {
+		s.mutex.Lock()
+		s.pending = false
+		s.mutex.Unlock()
+	}
+}
+
+func (s *breakout) settled() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return !s.pending
+}
+
+// shadow runs the strategies of the harness live against a mock broker streaming the quotes, the first one
+// readying the session, and as a backtest. The quotes are streamed once the previous one is handled and settled.
+func shadow(t *testing.T, h *Harness, quotes [][2]float64) (*Run, *Run) {
+
+	t.Helper()
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	clock := gotrader.NewSimulatedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	broker := gotradertest.NewBroker(instruments, gotradertest.WithClock(clock))
+
+	var strategy *breakout
+	factory := h.Strategy
+	h.Strategy = func() gotrader.Strategy {
+		strategy = factory().(*breakout)
+		return strategy
+	}
+
+	s, err := h.Shadow(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, q := range quotes {
+
+		clock.Advance(time.Second)
+		broker.Tick("EUR_USD", q[0], q[1])
+
+		deadline := time.Now().Add(gotradertest.Timeout)
+		for s.Run().Handled < i || !strategy.settled() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for the quote %d", i)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	live, err := s.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backtest, err := h.Replay(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return live, backtest
+}
+
+func TestHarness_Compare(t *testing.T) {
+
+	quotes := [][2]float64{{1.0990, 1.0992}, {1.0990, 1.0992}, {1.1010, 1.1012}, {1.1020, 1.1022}, {1.0980, 1.0982}}
+
+	t.Run("same decisions", func(t *testing.T) {
+
+		h := &Harness{
+			Options: []gotrader.Option{gotrader.Instruments([]string{"EUR_USD"}), gotrader.InitialBalance(10000),
+				gotrader.HomeCurrency("EUR"), gotrader.Leverage(30)},
+			Strategy: func() gotrader.Strategy { return &breakout{level: 1.1} },
+			Paper:    []paper.Option{paper.Balance(10000), paper.Currency("EUR"), paper.Leverage(30)},
+		}
+
+		live, backtest := shadow(t, h, quotes)
+
+		if len(live.Ticks) != len(quotes) || len(live.Decisions) != 2 {
+			t.Fatalf("expected the ticks and an open and a close recorded, got %d ticks and %v", len(live.Ticks),
+				live.Decisions)
+		}
+
+		if r := h.Compare(live, backtest); !r.Parity() || r.Matched != 2 {
+			t.Fatalf("expected the same decisions, got %+v", r)
+		}
+	})
+
+	t.Run("different decisions", func(t *testing.T) {
+
+		levels := []float64{1.1, 1.1015} // the backtest one was fitted to the recorded prices
+		h := &Harness{
+			Options: []gotrader.Option{gotrader.Instruments([]string{"EUR_USD"}), gotrader.InitialBalance(10000),
+				gotrader.HomeCurrency("EUR"), gotrader.Leverage(30)},
+			Strategy: func() gotrader.Strategy {
+				s := &breakout{level: levels[0]}
+				levels = levels[1:]
+				return s
+			},
+			Paper: []paper.Option{paper.Balance(10000), paper.Currency("EUR"), paper.Leverage(30)},
+		}
+
+		live, backtest := shadow(t, h, quotes)
+		r := h.Compare(live, backtest)
+
+		if r.Parity() || r.Matched != 1 || len(r.Differences) != 2 || r.Differences[0].Backtest != nil ||
+			r.Differences[1].Live != nil || r.Differences[1].Backtest.Action != OpenAction {
+			t.Fatalf("expected the opens at different ticks, got %+v", r)
+		}
+	})
+}
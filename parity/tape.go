@@ -0,0 +1,117 @@
+package parity
+
+import (
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// tape wraps the live client of a shadow session, recording its instruments and the ticks of its feed.
+type tape struct {
+	gotrader.BrokerClient
+	mutex       *sync.Mutex
+	instruments []gotrader.InstrumentDetails
+	ticks       []gotrader.Tick
+}
+
+// replayClient is the backtest client streaming the ticks of a tape.
+type replayClient struct {
+	gotrader.BrokerClient
+	instruments []gotrader.InstrumentDetails
+	ticks       []gotrader.Tick
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newTape(client gotrader.BrokerClient) *tape {
+	return &tape{BrokerClient: client, mutex: &sync.Mutex{}}
+}
+
+func (t *tape) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+
+	instruments, err := t.BrokerClient.GetAvailableInstruments(accountID)
+	if err == nil {
+		t.mutex.Lock()
+		t.instruments = append([]gotrader.InstrumentDetails(nil), instruments...)
+		t.mutex.Unlock()
+	}
+
+	return instruments, err
+}
+
+func (t *tape) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails,
+	callback gotrader.TickHandler) error {
+
+	return t.BrokerClient.SubscribePrices(accountID, instruments, func(tick *gotrader.Tick) {
+
+		if tick != nil {
+			t.mutex.Lock()
+			t.ticks = append(t.ticks, gotrader.Tick{
+				Instrument: tick.Instrument,
+				Bid:        tick.Bid,
+				Ask:        tick.Ask,
+				BidSize:    tick.BidSize,
+				AskSize:    tick.AskSize,
+				Last:       tick.Last,
+				LastSize:   tick.LastSize,
+				Time:       tick.Time,
+				Sequence:   tick.Sequence,
+			})
+			t.mutex.Unlock()
+		}
+
+		callback(tick)
+	})
+}
+
+// record returns the instruments and the ticks recorded so far.
+func (t *tape) record() ([]gotrader.InstrumentDetails, []gotrader.Tick) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.instruments, append([]gotrader.Tick(nil), t.ticks...)
+}
+
+func newReplayClient(instruments []gotrader.InstrumentDetails, ticks []gotrader.Tick) gotrader.BrokerClient {
+	return &replayClient{instruments: instruments, ticks: ticks}
+}
+
+func (c *replayClient) GetAvailableInstruments(accountID string) ([]gotrader.InstrumentDetails, error) {
+	return c.instruments, nil
+}
+
+func (c *replayClient) SubscribePrices(accountID string, instruments []gotrader.InstrumentDetails,
+	callback gotrader.TickHandler) error {
+
+	subscribed := make(map[string]bool, len(instruments))
+	for _, inst := range instruments {
+		subscribed[inst.Name] = true
+	}
+
+	go func() {
+
+		for i := range c.ticks {
+
+			if !subscribed[c.ticks[i].Instrument] {
+				continue
+			}
+
+			tick := gotrader.AcquireTick()
+			tick.Instrument, tick.Bid, tick.Ask = c.ticks[i].Instrument, c.ticks[i].Bid, c.ticks[i].Ask
+			tick.BidSize, tick.AskSize = c.ticks[i].BidSize, c.ticks[i].AskSize
+			tick.Last, tick.LastSize = c.ticks[i].Last, c.ticks[i].LastSize
+			tick.Time, tick.Sequence = c.ticks[i].Time, c.ticks[i].Sequence
+
+			callback(tick)
+		}
+
+		callback(nil)
+
+	}()
+
+	return nil
+}
@@ -1,7 +1,11 @@
 package gotrader
 
 import (
-	"github.com/cornelk/hashmap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
 	"go.uber.org/atomic"
 )
 
@@ -23,19 +27,35 @@ func (s Side) String() string {
 	return names[s]
 }
 
+// ParseSide returns the side of its name, LONG or SHORT.
+func ParseSide(name string) (Side, error) {
+
+	switch name {
+	case "LONG":
+		return Long, nil
+	case "SHORT":
+		return Short, nil
+	}
+
+	return Long, errors.New("invalid side " + name)
+}
+
 // Position represents the total exposure in a single side of an instrument.
 // Is the aggregation of all the trades of that side.
 type Position struct {
+	lock                      *sync.RWMutex // of the instrument
 	side                      Side
-	trades                    *hashmap.HashMap
+	trades                    *syncMap[string, *Trade]
+	tradeList                 atomic.Value // []*Trade copied on write, iterated without allocations on ticks
 	tradesTimeOrder           *sortedTrades
 	tradesNumber              *atomic.Int32
 	units                     *atomic.Int32
-	unrealizedNetProfit       float64
-	unrealizedEffectiveProfit float64
-	marginUsed                float64
-	chargedFees               float64
-	averagePrice              float64
+	unrealizedNetProfit       Decimal
+	unrealizedEffectiveProfit Decimal
+	marginUsed                Decimal
+	chargedFees               Decimal
+	notional                  Decimal // sum of the open price times the units of the trades
+	detached                  bool    // decoded by UnmarshalJSON, not book-kept by an instrument
 }
 
 /**************************
@@ -44,10 +64,11 @@ type Position struct {
 *
 ***************************/
 
-func newPosition(side Side) *Position {
+func newPosition(side Side, lock *sync.RWMutex) *Position {
 	return &Position{
+		lock:            lock,
 		side:            side,
-		trades:          &hashmap.HashMap{},
+		trades:          newSyncMap[string, *Trade](),
 		tradesTimeOrder: newSortedTrades(),
 		tradesNumber:    atomic.NewInt32(0),
 		units:           atomic.NewInt32(0),
@@ -56,73 +77,102 @@ func newPosition(side Side) *Position {
 
 func (p *Position) openTrade(trade *Trade) {
 
-	p.tradesTimeOrder.Append(trade.id)
+	p.tradesTimeOrder.Append(trade.id, trade.openTime)
 	p.trades.Set(trade.id, trade)
+	p.addToList(trade)
 	p.tradesNumber.Inc()
-	p.averagePrice = (p.averagePrice*float64(p.units.Load()) + trade.openPrice*float64(trade.units)) /
-		float64(p.units.Load()+trade.units)
+	p.notional = p.notional.Add(NewDecimal(trade.openPrice).MulInt(int64(trade.units)))
 	p.units.Add(trade.units)
 	trade.calculateMarginUsed()
-	p.marginUsed += trade.marginUsed
+	p.marginUsed = p.marginUsed.Add(trade.marginUsed)
 
 }
 
 func (p *Position) closeTrade(trade *Trade) {
 	p.tradesTimeOrder.Delete(trade.id)
 	p.trades.Del(trade.id)
+	p.removeFromList(trade)
 	p.tradesNumber.Dec()
-	p.averagePrice = (p.averagePrice*float64(p.units.Load()) - trade.openPrice*float64(trade.units)) /
-		float64(p.units.Load()-trade.units)
+	p.notional = p.notional.Sub(NewDecimal(trade.openPrice).MulInt(int64(trade.units)))
 	p.units.Sub(trade.units)
 	trade.calculateMarginUsed()
-	p.marginUsed -= trade.marginUsed
+	p.marginUsed = p.marginUsed.Sub(trade.marginUsed)
 }
 
-func (p *Position) calculateUnrealized() {
+// list returns the trades of the position, the slice must not be modified.
+func (p *Position) list() []*Trade {
+	trades, _ := p.tradeList.Load().([]*Trade)
+	return trades
+}
+
+func (p *Position) addToList(trade *Trade) {
 
-	unrealizedNet := 0.0
-	unrealizedEffective := 0.0
-	averagePrice := 0.0 // recalculate to prevent possible cumulative errors
-	totalUnits := 0.0
-	chargedFees := 0.0
+	trades := p.list()
+	list := make([]*Trade, len(trades), len(trades)+1)
+	copy(list, trades)
 
-	for kv := range p.trades.Iter() {
+	p.tradeList.Store(append(list, trade))
+}
 
-		trade := kv.Value.(*Trade)
+func (p *Position) removeFromList(trade *Trade) {
 
-		trade.calculateUnrealized()
+	trades := p.list()
+	list := make([]*Trade, 0, len(trades))
+
+	for _, t := range trades {
+		if t != trade {
+			list = append(list, t)
+		}
+	}
 
-		unrealizedNet += trade.unrealizedNetProfit
-		unrealizedEffective += trade.unrealizedEffectiveProfit
-		chargedFees += trade.chargedFees.Load()
+	p.tradeList.Store(list)
+}
+
+func (p *Position) calculateUnrealized() {
+
+	var unrealizedNet, unrealizedEffective, chargedFees, notional Decimal
+
+	for _, trade := range p.list() {
+
+		trade.calculateUnrealized()
 
-		averagePrice = (averagePrice*totalUnits + trade.openPrice*float64(trade.units)) / (totalUnits + float64(trade.units))
-		totalUnits += float64(trade.units)
+		unrealizedNet = unrealizedNet.Add(trade.unrealizedNetProfit)
+		unrealizedEffective = unrealizedEffective.Add(trade.unrealizedEffectiveProfit)
+		chargedFees = chargedFees.Add(trade.chargedFees.Load())
+		notional = notional.Add(NewDecimal(trade.openPrice).MulInt(int64(trade.units)))
 	}
 
 	p.unrealizedNetProfit = unrealizedNet
 	p.unrealizedEffectiveProfit = unrealizedEffective
-	p.averagePrice = averagePrice
+	p.notional = notional
 	p.chargedFees = chargedFees
 
 }
 
 func (p *Position) calculateMarginUsed() {
 
-	marginUsed := 0.0
+	var marginUsed Decimal
 
-	for kv := range p.trades.Iter() {
-
-		trade := kv.Value.(*Trade)
+	for _, trade := range p.list() {
 
 		trade.calculateMarginUsed()
-		marginUsed += trade.marginUsed
+		marginUsed = marginUsed.Add(trade.marginUsed)
 
 	}
 
 	p.marginUsed = marginUsed
 }
 
+func (p *Position) averagePrice() float64 {
+
+	units := p.units.Load()
+	if units == 0 {
+		return 0
+	}
+
+	return p.notional.Div(DecimalFromInt(int64(units))).Float64()
+}
+
 /**************************
 *
 *	Accessible Methods
@@ -137,59 +187,66 @@ func (p *Position) TradeByOrder(index int) *Trade {
 	return p.Trade(p.tradesTimeOrder.Get(index))
 }
 
+// TradesByAscendingOrder returns up to tradesNumber trades from the oldest, all of them when tradesNumber is -1.
 func (p *Position) TradesByAscendingOrder(tradesNumber int) <-chan *Trade {
+	return tradesChannel(lookupTrades(p.trades, p.tradesTimeOrder.Ascend(tradesNumber)))
+}
 
-	ch := make(chan *Trade)
-	go func() {
-		for id := range p.tradesTimeOrder.AscendIter(tradesNumber) {
-			tr, exist := p.trades.GetStringKey(id)
-			if exist {
-				ch <- tr.(*Trade)
-			}
-		}
-		close(ch)
-	}()
+// TradesByAscendingOrderContext is TradesByAscendingOrder streamed until the context is done.
+func (p *Position) TradesByAscendingOrderContext(ctx context.Context, tradesNumber int) <-chan *Trade {
+	return tradesChannelContext(ctx, lookupTrades(p.trades, p.tradesTimeOrder.Ascend(tradesNumber)))
+}
 
-	return ch
+// RangeTradesByAscendingOrder calls f on up to tradesNumber trades from the oldest until f returns false.
+func (p *Position) RangeTradesByAscendingOrder(tradesNumber int, f func(trade *Trade) bool) {
+	rangeTrades(lookupTrades(p.trades, p.tradesTimeOrder.Ascend(tradesNumber)), f)
 }
 
+// TradesByDescendingOrder returns up to tradesNumber trades from the newest, all of them when tradesNumber is -1.
 func (p *Position) TradesByDescendingOrder(tradesNumber int) <-chan *Trade {
-	ch := make(chan *Trade)
-	go func() {
-		for id := range p.tradesTimeOrder.DescendIter(tradesNumber) {
-			tr, exist := p.trades.GetStringKey(id)
-			if exist {
-				ch <- tr.(*Trade)
-			}
-		}
-		close(ch)
-	}()
+	return tradesChannel(lookupTrades(p.trades, p.tradesTimeOrder.Descend(tradesNumber)))
+}
 
-	return ch
+// TradesByDescendingOrderContext is TradesByDescendingOrder streamed until the context is done.
+func (p *Position) TradesByDescendingOrderContext(ctx context.Context, tradesNumber int) <-chan *Trade {
+	return tradesChannelContext(ctx, lookupTrades(p.trades, p.tradesTimeOrder.Descend(tradesNumber)))
 }
 
-func (p *Position) Trade(id string) *Trade {
+// RangeTradesByDescendingOrder calls f on up to tradesNumber trades from the newest until f returns false.
+func (p *Position) RangeTradesByDescendingOrder(tradesNumber int, f func(trade *Trade) bool) {
+	rangeTrades(lookupTrades(p.trades, p.tradesTimeOrder.Descend(tradesNumber)), f)
+}
+
+// TradesOpenedBetween returns the open trades opened in [from, to), from the oldest.
+func (p *Position) TradesOpenedBetween(from, to time.Time) []*Trade {
+	return lookupTrades(p.trades, p.tradesTimeOrder.Between(from, to))
+}
 
-	trade, exist := p.trades.GetStringKey(id)
+// TradesOpenedByInterval returns the number of open trades opened in each interval of [from, to), e.g. by hour
+// of a session.
+func (p *Position) TradesOpenedByInterval(from, to time.Time, interval time.Duration) []int {
+	return p.tradesTimeOrder.CountByInterval(from, to, interval)
+}
 
-	if exist {
-		return trade.(*Trade)
-	}
+func (p *Position) Trade(id string) *Trade {
 
-	return nil
+	trade, _ := p.trades.Get(id)
+	return trade
 }
 
+// Trades returns the open trades, in no particular order.
 func (p *Position) Trades() <-chan *Trade {
+	return tradesChannel(p.trades.Values())
+}
 
-	ch := make(chan *Trade)
-	go func() {
-		for kv := range p.trades.Iter() {
-			ch <- kv.Value.(*Trade)
-		}
-		close(ch)
-	}()
+// TradesContext is Trades streamed until the context is done.
+func (p *Position) TradesContext(ctx context.Context) <-chan *Trade {
+	return tradesChannelContext(ctx, p.trades.Values())
+}
 
-	return ch
+// RangeTrades calls f on the open trades, in no particular order, until f returns false.
+func (p *Position) RangeTrades(f func(trade *Trade) bool) {
+	rangeTrades(p.trades.Values(), f)
 }
 
 func (p *Position) TradesNumber() int32 {
@@ -201,21 +258,59 @@ func (p *Position) Units() int32 {
 }
 
 func (p *Position) UnrealizedNetProfit() float64 {
-	return p.unrealizedNetProfit
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.unrealizedNetProfit.Float64()
 }
 
 func (p *Position) UnrealizedEffectiveProfit() float64 {
-	return p.unrealizedEffectiveProfit
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.unrealizedEffectiveProfit.Float64()
 }
 
 func (p *Position) MarginUsed() float64 {
-	return p.marginUsed
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.marginUsed.Float64()
 }
 
 func (p *Position) ChargedFees() float64 {
-	return p.chargedFees
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.chargedFees.Float64()
 }
 
 func (p *Position) AveragePrice() float64 {
-	return p.averagePrice
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.averagePrice()
+}
+
+// State returns a copy of the metrics of the position, captured under the instrument lock.
+func (p *Position) State() PositionState {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return newPositionState(p)
+}
+
+// TradeStates returns copies of the trades of the position by open time order, captured under the instrument
+// lock with the metrics of the same calculation.
+func (p *Position) TradeStates() []TradeState {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	trades := lookupTrades(p.trades, p.tradesTimeOrder.Ascend(-1))
+	states := make([]TradeState, len(trades))
+	for n, trade := range trades {
+		states[n] = trade.state(trade.currentPrice.Load())
+	}
+
+	return states
 }
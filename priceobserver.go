@@ -0,0 +1,179 @@
+package gotrader
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PriceUpdate is a price of an instrument delivered to a PriceObserver.
+type PriceUpdate struct {
+	Instrument string
+	Time       time.Time // of the tick
+	Bid        float64
+	Ask        float64
+	Ticks      int // conflated into the update since the previous one, itself included
+}
+
+// PriceObserver receives the price updates of an instrument, see Instrument.ObservePrices.
+type PriceObserver func(update PriceUpdate)
+
+// ObserverOption represents a PriceObserver functional option.
+type ObserverOption func(l *priceListener)
+
+// Throttle delivers at most one update every interval of wall clock time, the latest price of the interval.
+func Throttle(interval time.Duration) ObserverOption {
+	return func(l *priceListener) {
+		l.interval = interval
+	}
+}
+
+// MinMove delivers only the prices whose mid moved by at least pips from the mid of the previous update.
+func MinMove(pips float64) ObserverOption {
+	return func(l *priceListener) {
+		l.pips = pips
+	}
+}
+
+// PriceSubscription is a registration of a PriceObserver.
+type PriceSubscription struct {
+	instrument *Instrument
+	listener   *priceListener
+	once       sync.Once
+}
+
+// priceListener conflates the prices of a PriceObserver to the latest: the engine stores it and signals the
+// goroutine of the listener, which delivers it and waits for the throttle interval before the next one.
+type priceListener struct {
+	observer  PriceObserver
+	interval  time.Duration
+	pips      float64
+	pip       float64 // of the instrument, in price units
+	last      float64 // mid of the last price passed on, set by the engine goroutine only
+	latest    atomic.Pointer[PriceUpdate]
+	conflated atomic.Int64
+	signal    chan struct{}
+	done      chan struct{}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// offer passes the price of the tick on to the listener, unless it moved less than the minimum move.
+func (l *priceListener) offer(tick *Tick) {
+
+	mid := (tick.Bid + tick.Ask) / 2
+	if l.pips > 0 && l.last != 0 && math.Abs(mid-l.last)/l.pip < l.pips-1e-9 { // tolerates the rounding of the prices
+		return
+	}
+	l.last = mid
+
+	l.conflated.Add(1)
+	l.latest.Store(&PriceUpdate{Instrument: tick.Instrument, Time: tick.Time, Bid: tick.Bid, Ask: tick.Ask})
+
+	select {
+	case l.signal <- struct{}{}:
+	default: // already signaled, the update is conflated
+	}
+}
+
+func (l *priceListener) run() {
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-l.signal:
+		}
+
+		update := l.latest.Swap(nil)
+		if update == nil {
+			continue
+		}
+
+		update.Ticks = int(l.conflated.Swap(0))
+		l.observer(*update)
+
+		if l.interval <= 0 {
+			continue
+		}
+
+		timer := time.NewTimer(l.interval)
+		select {
+		case <-l.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// notifyPrice offers the price of the tick to the observers of the instrument.
+func (i *Instrument) notifyPrice(tick *Tick) {
+
+	listeners, _ := i.listeners.Load().([]*priceListener)
+	for _, l := range listeners {
+		l.offer(tick)
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+/*
+ObservePrices delivers the prices of the instrument to the observer, on a goroutine of the subscription so a slow
+consumer like a user interface never blocks the ticks. The prices are conflated to the latest: without options
+the observer receives every price it keeps up with, Throttle limits the rate and MinMove drops the small moves.
+The updates are delivered until the subscription is stopped.
+*/
+func (i *Instrument) ObservePrices(observer PriceObserver, opts ...ObserverOption) *PriceSubscription {
+
+	l := &priceListener{
+		observer: observer,
+		signal:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.pip = math.Pow10(i.pipLocation)
+
+	go l.run()
+
+	i.listenersMutex.Lock()
+	defer i.listenersMutex.Unlock()
+
+	listeners, _ := i.listeners.Load().([]*priceListener)
+	i.listeners.Store(append(listeners[:len(listeners):len(listeners)], l))
+
+	return &PriceSubscription{instrument: i, listener: l}
+}
+
+// Stop stops the deliveries of the subscription, an update being delivered completes.
+func (s *PriceSubscription) Stop() {
+
+	s.once.Do(func() {
+
+		s.instrument.listenersMutex.Lock()
+		defer s.instrument.listenersMutex.Unlock()
+
+		listeners, _ := s.instrument.listeners.Load().([]*priceListener)
+		kept := make([]*priceListener, 0, len(listeners))
+		for _, l := range listeners {
+			if l != s.listener {
+				kept = append(kept, l)
+			}
+		}
+
+		s.instrument.listeners.Store(kept)
+		close(s.listener.done)
+	})
+}
@@ -0,0 +1,80 @@
+package gotrader
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quantity is an amount of the base of an instrument, e.g. 150000 EUR of EUR_USD, 0.25 BTC of BTC_USDT or 1.5
+// shares, converted to the units of the orders by the unit size of the instrument (see InstrumentDetails).
+type Quantity float64
+
+// The lot sizes of the FX instruments.
+const (
+	StandardLot Quantity = 100000
+	MiniLot     Quantity = 10000
+	MicroLot    Quantity = 1000
+)
+
+// quantityTolerance is the relative error of a quantity still matching a multiple of the unit size.
+const quantityTolerance = 1e-9
+
+// Lots returns the quantity of n lots of the size, e.g. Lots(1.5, StandardLot) for 150000 units of an FX pair.
+func Lots(n float64, size Quantity) Quantity {
+	return Quantity(n) * size
+}
+
+// Lots returns the quantity in lots of the size.
+func (q Quantity) Lots(size Quantity) float64 {
+	return float64(q / size)
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// UnitSize returns the quantity of a unit of the instrument, the minimum step of its orders.
+func (i *Instrument) UnitSize() float64 {
+	return i.unitSize
+}
+
+// Quantity returns the quantity of the units of the instrument.
+func (i *Instrument) Quantity(units int32) Quantity {
+	return Quantity(float64(units) * i.unitSize)
+}
+
+// RoundQuantity returns the quantity rounded down to a multiple of the unit size of the instrument.
+func (i *Instrument) RoundQuantity(q Quantity) Quantity {
+
+	units := math.Floor(float64(q)/i.unitSize + quantityTolerance)
+
+	return Quantity(units * i.unitSize)
+}
+
+/*
+Units returns the units of the orders of a quantity of the instrument. The quantity must be positive, a multiple of
+the unit size, see RoundQuantity, and within the int32 units; it is rejected with ErrInvalidQuantity otherwise, and
+with ErrBelowMinUnits below the minimum units of the instrument.
+*/
+func (i *Instrument) Units(q Quantity) (int32, error) {
+
+	units := float64(q) / i.unitSize
+	rounded := math.Round(units)
+
+	switch {
+	case !(q > 0) || math.IsInf(units, 0):
+		return 0, fmt.Errorf("%s: %w, %v", i.name, ErrInvalidQuantity, float64(q))
+	case math.Abs(units-rounded) > quantityTolerance*math.Max(rounded, 1):
+		return 0, fmt.Errorf("%s: %w, %v is not a multiple of %v", i.name, ErrInvalidQuantity, float64(q), i.unitSize)
+	case rounded > math.MaxInt32:
+		return 0, fmt.Errorf("%s: %w, %v exceeds the units", i.name, ErrInvalidQuantity, float64(q))
+	}
+
+	if minimum := i.minUnits.Load(); int32(rounded) < minimum {
+		return 0, fmt.Errorf("%s: %w, %d units of %d", i.name, ErrBelowMinUnits, int32(rounded), minimum)
+	}
+
+	return int32(rounded), nil
+}
@@ -0,0 +1,97 @@
+package gotrader
+
+import (
+	"math"
+)
+
+/*
+QueueFills is the functional option of the backtests to fill the pending limit orders from an estimate of their
+position in the queue of their price level, instead of as soon as the level is touched, on the feeds reporting the
+quoted sizes and the trades of the ticks (Tick.Last and Tick.LastSize), e.g. the L2 and trades CSV files.
+
+An order joins the queue of its level behind the size quoted there, once its level is the best one of its side, or
+ahead of everyone when the best price is worse than its level. The trades at the level consume the queue ahead of
+the order first, and the decreases of the quoted size that are not traded are cancellations, taken from the queue
+ahead in proportion to its share of the level. The order is filled at its price, for all its units, once the size
+traded at the level after the queue ahead reaches its units or when a trade goes through its price. It is still
+filled when the opposite side of the market crosses its price, as without the option, and its queue position is
+lost when its price is modified or its units increased.
+*/
+func QueueFills() Option {
+	return func(p *sessionParameters) {
+		p.queueFills = true
+	}
+}
+
+// queuePosition is the estimated position of a pending limit order in the queue of its level.
+type queuePosition struct {
+	ahead  float64 // size queued before the order
+	traded float64 // size traded at the level after the queue ahead, filling the order
+	size   float64 // size quoted at the level on the previous tick, zero when it was not the best one
+}
+
+// samePrice returns true if the prices are equal, to the float rounding of the feeds and the order prices.
+func samePrice(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// advanceQueue updates the queue position of a pending limit order with a tick, returning true once the order
+// is filled at its level.
+func (o *Order) advanceQueue(tick *Tick) bool {
+
+	level, size := tick.Bid, tick.BidSize
+	through := tick.LastSize > 0 && tick.Last < o.Price
+	best := level < o.Price // the order is the best bid
+
+	if o.Side == Short {
+		level, size = tick.Ask, tick.AskSize
+		through = tick.LastSize > 0 && tick.Last > o.Price
+		best = level > o.Price
+	}
+
+	if through {
+		return true
+	}
+
+	at := samePrice(level, o.Price)
+
+	q := o.queue
+	if q == nil {
+
+		if !at && !best {
+			return false // the order is deeper than the best level, its queue is not quoted yet
+		}
+
+		o.queue = &queuePosition{}
+		if at {
+			o.queue.ahead, o.queue.size = size, size
+		}
+
+		return false
+	}
+
+	traded := 0.0
+	if tick.LastSize > 0 && samePrice(tick.Last, o.Price) {
+		traded = tick.LastSize
+	}
+
+	consumed := math.Min(traded, q.ahead)
+	q.traded += traded - consumed
+	q.ahead -= consumed
+
+	switch {
+	case at:
+		if q.size > 0 {
+			cancelled := math.Max(q.size-size-traded, 0)
+			q.ahead -= cancelled * q.ahead / q.size
+		}
+		q.ahead = math.Max(math.Min(q.ahead, size), 0) // the queue ahead is quoted at the level
+		q.size = size
+	case best:
+		q.ahead, q.size = 0, 0
+	default:
+		q.size = 0 // the level is behind the best one, its queue is not quoted
+	}
+
+	return q.traded >= float64(o.Units)
+}
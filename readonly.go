@@ -0,0 +1,86 @@
+package gotrader
+
+import (
+	"time"
+)
+
+/*
+TradeReader is the read only interface of a Trade. The trades and positions returned by the instruments are the
+ones the engine book-keeps, so the strategy code handed a TradeReader or a PositionReader, or the copies of
+State and TradeStates, can't change them.
+*/
+type TradeReader interface {
+	ID() string
+	InstrumentName() string
+	Side() Side
+	Units() int32
+	OpenTime() time.Time
+	OpenPrice() float64
+	CurrentPrice() float64
+	UnrealizedNetProfit() float64
+	UnrealizedEffectiveProfit() float64
+	MarginUsed() float64
+	ChargedFees() float64
+	Fees() Fees
+	StopLoss() float64
+	GuaranteedStop() bool
+	TakeProfit() float64
+	Expiry() time.Time
+	Tag() string
+	GapFill() bool
+	Venue() string
+	OpenSpread() float64
+	SpreadCost() float64
+	State() TradeState
+}
+
+// PositionReader is the read only interface of a Position, its trades are read as TradeReader or TradeState.
+type PositionReader interface {
+	Side() Side
+	TradesNumber() int32
+	Units() int32
+	AveragePrice() float64
+	UnrealizedNetProfit() float64
+	UnrealizedEffectiveProfit() float64
+	MarginUsed() float64
+	ChargedFees() float64
+	State() PositionState
+	TradeStates() []TradeState
+	RangeTradeReaders(f func(trade TradeReader) bool)
+}
+
+var (
+	_ TradeReader    = (*Trade)(nil)
+	_ PositionReader = (*Position)(nil)
+)
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// RangeTradeReaders calls f on the trades of the position by open time order, until f returns false.
+func (p *Position) RangeTradeReaders(f func(trade TradeReader) bool) {
+	p.RangeTradesByAscendingOrder(-1, func(trade *Trade) bool { return f(trade) })
+}
+
+// LongPositionReader returns the long position of the instrument as a PositionReader.
+func (i *Instrument) LongPositionReader() PositionReader {
+	return i.longPosition
+}
+
+// ShortPositionReader returns the short position of the instrument as a PositionReader.
+func (i *Instrument) ShortPositionReader() PositionReader {
+	return i.shortPosition
+}
+
+// TradeReader returns the open trade as a TradeReader, nil when it is not open.
+func (i *Instrument) TradeReader(id string) TradeReader {
+
+	if trade, exist := i.trades.Get(id); exist {
+		return trade
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package rebalance
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// DefaultHedgeTag is the tag of the hedging trades of a CurrencyHedge without tag.
+const DefaultHedgeTag = "currency-hedge"
+
+/*
+HedgeConfig sets the band of a CurrencyHedge. The hedge of a currency is adjusted when its residual exposure, once
+hedged, exceeds Tolerance of its gross exposure; the adjustments of fewer units than MinUnits are left. The hedging
+trades carry Tag, DefaultHedgeTag when empty.
+*/
+type HedgeConfig struct {
+	Tolerance float64
+	MinUnits  int32
+	Tag       string
+}
+
+// Exposure is the exposure of the account to a currency, in units of the currency: the gross exposure of the open
+// trades, the hedges excluded, the exposure of the hedging trades and the residual of both, and its value in the
+// home currency.
+type Exposure struct {
+	Currency   string
+	Instrument string // hedging the currency, empty when the account has no pair of it and the home currency
+	Gross      float64
+	Hedged     float64
+	Residual   float64
+	Value      float64
+}
+
+/*
+CurrencyHedge is an overlay hedging the exposure of the open trades to the currencies other than the home one, e.g.
+the EUR of a long position in EUR_GBP for a USD account. The exposure of a trade is its units in the base currency
+and their value at the mid price in the quote currency. Every currency is hedged with its pair with the home
+currency, EUR_USD or USD_EUR, that must be an instrument of the session; the hedging trades are tagged and kept out
+of the exposure. OnTick and OnOrderFill must be called with every tick and fill of the strategy.
+*/
+type CurrencyHedge struct {
+	mutex    *sync.Mutex
+	engine   gotrader.Engine
+	config   HedgeConfig
+	targets  *Targets
+	reported map[string]error // of the last adjustment by instrument
+}
+
+// NewCurrencyHedge is the CurrencyHedge constructor.
+func NewCurrencyHedge(engine gotrader.Engine, config HedgeConfig) *CurrencyHedge {
+
+	if config.Tag == "" {
+		config.Tag = DefaultHedgeTag
+	}
+
+	return &CurrencyHedge{
+		mutex:    &sync.Mutex{},
+		engine:   engine,
+		config:   config,
+		targets:  newTargets(engine, config.MinUnits, config.Tag),
+		reported: make(map[string]error),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// hedging returns the instrument hedging the currency, and whether the currency is its base.
+func hedging(account *gotrader.Account, currency string) (*gotrader.Instrument, bool) {
+
+	home := account.HomeCurrency()
+
+	for _, inst := range account.Instruments() {
+		switch {
+		case inst.BaseCurrency() == currency && inst.QuoteCurrency() == home:
+			return inst, true
+		case inst.BaseCurrency() == home && inst.QuoteCurrency() == currency:
+			return inst, false
+		}
+	}
+
+	return nil, false
+}
+
+// exposures returns the exposures of the account by currency, on a single view of the account.
+func (h *CurrencyHedge) exposures() (map[string]*Exposure, error) {
+
+	account := h.engine.Account()
+	view := account.View()
+
+	tagged := func(trade gotrader.TradeState) bool { return trade.Tag == h.config.Tag }
+	gross := view.CurrencyExposures(func(trade gotrader.TradeState) bool { return !tagged(trade) })
+	hedged := view.CurrencyExposures(tagged)
+
+	exposures := make(map[string]*Exposure)
+	rates := make(map[string]float64)
+
+	exposure := func(e gotrader.CurrencyExposure) *Exposure {
+
+		rates[e.Currency] = e.Rate
+
+		x, exist := exposures[e.Currency]
+		if !exist {
+			x = &Exposure{Currency: e.Currency}
+			exposures[e.Currency] = x
+		}
+
+		return x
+	}
+
+	for _, e := range gross.Currencies {
+		if e.Currency != account.HomeCurrency() {
+			exposure(e).Gross = e.Net
+		}
+	}
+
+	for _, e := range hedged.Currencies {
+		if e.Currency != account.HomeCurrency() {
+			exposure(e).Hedged = e.Net
+		}
+	}
+
+	var errs []error
+
+	for currency, e := range exposures {
+
+		e.Residual = e.Gross + e.Hedged
+
+		if rates[currency] == 0 {
+			errs = append(errs, fmt.Errorf("no conversion of %s to %s", currency, account.HomeCurrency()))
+			continue
+		}
+
+		e.Value = e.Residual * rates[currency]
+
+		if inst, _ := hedging(account, currency); inst != nil {
+			e.Instrument = inst.Name()
+		}
+	}
+
+	return exposures, errors.Join(errs...)
+}
+
+// hedge sets the hedge target of the currencies whose residual exposure left the band.
+func (h *CurrencyHedge) hedge() error {
+
+	exposures, err := h.exposures()
+	account := h.engine.Account()
+
+	for currency, e := range exposures {
+
+		if math.Abs(e.Residual) <= h.config.Tolerance*math.Abs(e.Gross) {
+			continue
+		}
+
+		inst, base := hedging(account, currency)
+		if inst == nil || mid(inst) == 0 {
+			err = errors.Join(err, errors.New("no instrument hedging "+currency))
+			continue
+		}
+
+		// the hedge in base units offsets the gross exposure, a long position in HOME_CCY is short CCY at its price
+		units := -e.Gross
+		if !base {
+			units = e.Gross / mid(inst)
+		}
+
+		h.targets.Set(inst.Name(), int32(math.Round(units)))
+	}
+
+	return err
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Exposures returns the exposures of the account to the currencies other than the home one, by currency order.
+func (h *CurrencyHedge) Exposures() ([]Exposure, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	exposures, err := h.exposures()
+
+	list := make([]Exposure, 0, len(exposures))
+	for _, e := range exposures {
+		list = append(list, *e)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Currency < list[j].Currency })
+
+	return list, err
+}
+
+// Rebalance adjusts the hedges out of the band and reconciles the hedging trades with them.
+func (h *CurrencyHedge) Rebalance() error {
+
+	h.mutex.Lock()
+	err := h.hedge()
+	h.mutex.Unlock()
+
+	return errors.Join(err, h.targets.Reconcile())
+}
+
+// OnTick adjusts the hedges out of the band and reconciles the hedging trades of the instrument of the tick, the
+// errors are reported by Err.
+func (h *CurrencyHedge) OnTick(tick *gotrader.Tick) {
+
+	h.mutex.Lock()
+	err := h.hedge()
+	h.mutex.Unlock()
+
+	h.targets.OnTick(tick)
+
+	h.mutex.Lock()
+	h.reported[tick.Instrument] = errors.Join(err, h.targets.Err(tick.Instrument))
+	h.mutex.Unlock()
+}
+
+// OnOrderFill removes the hedging orders and closes filled from the ones in flight.
+func (h *CurrencyHedge) OnOrderFill(fill *gotrader.OrderFill) {
+	h.targets.OnOrderFill(fill)
+}
+
+// Err returns the error of the last adjustment of the hedges on the ticks of the instrument.
+func (h *CurrencyHedge) Err(instrument string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.reported[instrument]
+}
@@ -0,0 +1,327 @@
+/*
+Package rebalance keeps the net positions of an account at targets: the units declared by a strategy with Targets,
+weights of its equity with a Rebalancer, or the hedges of its currency exposure with a CurrencyHedge. A Rebalancer computes the Plan to reach the weights, the trades to close
+and the market orders to submit per instrument, and executes it on demand with Rebalance or on a schedule from the
+ticks of the strategy:
+
+	rebalancer := rebalance.New(engine, rebalance.Weights{"EUR_USD": 0.5, "USD_JPY": -0.25}, rebalance.Config{
+		MinUnits:  1000,
+		Threshold: 0.05,
+		Interval:  24 * time.Hour,
+	})
+	...
+	func (s *strategy) OnTick(tick *gotrader.Tick) { s.rebalancer.OnTick(tick) }
+*/
+package rebalance
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Weights are the target net positions by instrument as shares of the equity, positive long and negative short,
+// valued at the mid prices in the home currency. The instruments of the account without weight are not rebalanced,
+// a zero weight closes the position.
+type Weights map[string]float64
+
+/*
+Config sets the rebalancing constraints. The adjustments of fewer units than MinUnits are skipped, and a plan is
+only executed when its turnover, the notional traded as a share of the equity, reaches Threshold. Interval is the
+period of the rebalances started by OnTick, zero disables them.
+*/
+type Config struct {
+	MinUnits  int32
+	Threshold float64
+	Interval  time.Duration
+}
+
+// Adjustment is the change of the net position of an instrument, in units: the trades closed, oldest first, and
+// the market order of the units left, nil without.
+type Adjustment struct {
+	Instrument string
+	Weight     float64
+	Current    int32 // negative for the shorts
+	Target     int32
+	Closes     []string
+	Order      *gotrader.Order
+}
+
+// Plan is the rebalance of the account at a time, Skipped when its turnover is below the threshold.
+type Plan struct {
+	Time        time.Time
+	Equity      float64
+	Turnover    float64
+	Adjustments []Adjustment
+	Skipped     bool
+}
+
+// Rebalancer rebalances the account of an engine to the target weights.
+type Rebalancer struct {
+	mutex   *sync.Mutex
+	engine  gotrader.Engine
+	weights Weights
+	config  Config
+	last    time.Time // of the last rebalance
+}
+
+// New is the Rebalancer constructor.
+func New(engine gotrader.Engine, weights Weights, config Config) *Rebalancer {
+	return &Rebalancer{
+		mutex:   &sync.Mutex{},
+		engine:  engine,
+		weights: weights,
+		config:  config,
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func mid(inst *gotrader.Instrument) float64 {
+	return (inst.Bid() + inst.Ask()) / 2
+}
+
+// rate returns the value of a unit of the currency in the home currency, at the mid prices of the instruments of
+// the account.
+func rate(account *gotrader.Account, currency string) (float64, error) {
+
+	home := account.HomeCurrency()
+
+	if currency == home {
+		return 1, nil
+	}
+
+	for _, conversion := range account.Instruments() {
+
+		price := mid(conversion)
+		if price == 0 {
+			continue
+		}
+
+		switch {
+		case conversion.BaseCurrency() == currency && conversion.QuoteCurrency() == home:
+			return price, nil
+		case conversion.BaseCurrency() == home && conversion.QuoteCurrency() == currency:
+			return 1 / price, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no conversion of %s to %s", currency, home)
+}
+
+// unitValue returns the value of a unit of the instrument in the home currency, at the mid prices.
+func unitValue(account *gotrader.Account, inst *gotrader.Instrument) (float64, error) {
+
+	if inst.BaseCurrency() == account.HomeCurrency() {
+		return 1, nil
+	}
+
+	r, err := rate(account, inst.QuoteCurrency())
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", inst.Name(), err)
+	}
+
+	return mid(inst) * r, nil
+}
+
+// netUnits returns the net position of the instrument, negative for the shorts, of the trades with the tag or of
+// every trade without tag.
+func netUnits(inst *gotrader.Instrument, tag string) int32 {
+
+	if tag == "" {
+		return inst.LongPosition().Units() - inst.ShortPosition().Units()
+	}
+
+	units := int32(0)
+
+	inst.RangeTrades(func(trade *gotrader.Trade) bool {
+		if trade.Tag() == tag && trade.Side() == gotrader.Long {
+			units += trade.Units()
+		} else if trade.Tag() == tag {
+			units -= trade.Units()
+		}
+		return true
+	})
+
+	return units
+}
+
+// adjust returns the adjustment of the instrument from the current net position to the target units. The trades on
+// the side reduced are closed, oldest first, while they fit in the reduction; only the ones with the tag are when it
+// is set, and the order carries it.
+func adjust(inst *gotrader.Instrument, current, target int32, tag string) Adjustment {
+
+	a := Adjustment{
+		Instrument: inst.Name(),
+		Current:    current,
+		Target:     target,
+	}
+
+	delta := target - a.Current
+
+	reduced := inst.LongPosition()
+	if delta > 0 {
+		reduced = inst.ShortPosition()
+	}
+
+	left := delta
+	if left < 0 {
+		left = -left
+	}
+
+	reduced.RangeTradesByAscendingOrder(int(reduced.TradesNumber()), func(trade *gotrader.Trade) bool {
+		if (tag == "" || trade.Tag() == tag) && trade.Units() <= left {
+			a.Closes = append(a.Closes, trade.ID())
+			left -= trade.Units()
+		}
+		return left > 0
+	})
+
+	if left > 0 {
+		a.Order = &gotrader.Order{Type: gotrader.MarketOrder, Instrument: inst.Name(), Side: gotrader.Long, Units: left,
+			Tag: tag}
+		if delta < 0 {
+			a.Order.Side = gotrader.Short
+		}
+	}
+
+	return a
+}
+
+func (r *Rebalancer) plan() (Plan, error) {
+
+	account := r.engine.Account()
+
+	p := Plan{Time: account.Time(), Equity: account.Equity()}
+	if p.Equity <= 0 {
+		return p, errors.New("account without equity")
+	}
+
+	names := make([]string, 0, len(r.weights))
+	for name := range r.weights {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	traded := 0.0
+
+	for _, name := range names {
+
+		inst := account.Instrument(name)
+		if inst == nil {
+			return p, fmt.Errorf("%s: %w", name, gotrader.ErrInstrumentNotTraded)
+		}
+
+		value, err := unitValue(account, inst)
+		if err != nil {
+			return p, err
+		}
+
+		if value <= 0 {
+			return p, fmt.Errorf("%s: no price", name)
+		}
+
+		target := int32(math.Round(r.weights[name] * p.Equity / value))
+		a := adjust(inst, netUnits(inst, ""), target, "")
+		a.Weight = r.weights[name]
+
+		if delta := target - a.Current; delta == 0 || (delta > -r.config.MinUnits && delta < r.config.MinUnits) {
+			continue
+		}
+
+		traded += math.Abs(float64(a.Target-a.Current)) * value
+		p.Adjustments = append(p.Adjustments, a)
+	}
+
+	p.Turnover = traded / p.Equity
+	p.Skipped = len(p.Adjustments) == 0 || p.Turnover < r.config.Threshold
+
+	return p, nil
+}
+
+func (r *Rebalancer) execute(p Plan) error {
+
+	var errs []error
+
+	for _, a := range p.Adjustments {
+
+		for _, id := range a.Closes {
+			if err := r.engine.CloseTrade(a.Instrument, id); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if a.Order != nil {
+			if _, err := r.engine.SubmitOrder(a.Order); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// SetWeights replaces the target weights, applied by the next rebalance.
+func (r *Rebalancer) SetWeights(weights Weights) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.weights = weights
+}
+
+// Plan returns the rebalance of the account at the current prices, without executing it.
+func (r *Rebalancer) Plan() (Plan, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.plan()
+}
+
+// Rebalance executes the rebalance of the account unless skipped, returning its plan and the errors of its
+// closes and orders.
+func (r *Rebalancer) Rebalance() (Plan, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	p, err := r.plan()
+	if err != nil {
+		return p, err
+	}
+
+	r.last = p.Time
+
+	if p.Skipped {
+		return p, nil
+	}
+
+	return p, r.execute(p)
+}
+
+// OnTick rebalances the account once the interval elapsed since the last rebalance, the first one on the first
+// tick. The errors are only reported by Rebalance.
+func (r *Rebalancer) OnTick(tick *gotrader.Tick) {
+
+	r.mutex.Lock()
+	due := r.config.Interval > 0 && (r.last.IsZero() || !tick.Time.Before(r.last.Add(r.config.Interval)))
+	r.mutex.Unlock()
+
+	if due {
+		r.Rebalance()
+	}
+}
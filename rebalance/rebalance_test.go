@@ -0,0 +1,167 @@
+package rebalance
+
+import (
+	"testing"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/gotradertest"
+)
+
+type holder struct{ engine gotrader.Engine }
+
+func (s *holder) Initialize()                          {}
+func (s *holder) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *holder) OnTick(tick *gotrader.Tick)           {}
+func (s *holder) OnOrderFill(fill *gotrader.OrderFill) {}
+func (s *holder) OnStop()                              {}
+
+func TestRebalancer(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "USD_JPY", BaseCurrency: "USD", QuoteCurrency: "JPY", Leverage: 30, PipLocation: -2},
+	}
+
+	broker := gotradertest.NewBroker(instruments, gotradertest.Balance(10000), gotradertest.Leverage(30))
+	broker.Quote("EUR_USD", 1.2499, 1.2501)
+	broker.Quote("USD_JPY", 149.99, 150.01)
+
+	s := &holder{}
+	h := gotradertest.New(t, s, broker, gotrader.Instruments([]string{"EUR_USD", "USD_JPY"}))
+
+	r := New(s.engine, Weights{"EUR_USD": 0.5, "USD_JPY": -0.3}, Config{MinUnits: 100, Threshold: 0.1})
+
+	p, err := r.Rebalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.Settle()
+	h.AssertUnits("EUR_USD", gotrader.Long, 4000)
+	h.AssertUnits("USD_JPY", gotrader.Short, 3000)
+
+	if p.Skipped || len(p.Adjustments) != 2 {
+		t.Fatalf("expected two adjustments, got %+v", p)
+	}
+
+	r.SetWeights(Weights{"EUR_USD": 0.51, "USD_JPY": -0.3})
+
+	if p, err := r.Rebalance(); err != nil || !p.Skipped {
+		t.Fatalf("expected the rebalance below the turnover threshold to be skipped, got %+v, %v", p, err)
+	}
+
+	r.SetWeights(Weights{"EUR_USD": -0.25, "USD_JPY": 0})
+
+	if _, err := r.Rebalance(); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Settle()
+	h.AssertUnits("EUR_USD", gotrader.Long, 0)
+	h.AssertUnits("EUR_USD", gotrader.Short, 2000)
+	h.AssertOpenTrades("USD_JPY", 0)
+}
+
+// declarer holds the positions set by the test, reconciled on its ticks.
+type declarer struct {
+	holder
+	targets *Targets
+}
+
+func (s *declarer) SetEngine(engine gotrader.Engine) {
+	s.engine, s.targets = engine, NewTargets(engine, 1)
+}
+func (s *declarer) OnTick(tick *gotrader.Tick)           { s.targets.OnTick(tick) }
+func (s *declarer) OnOrderFill(fill *gotrader.OrderFill) { s.targets.OnOrderFill(fill) }
+
+func TestTargets(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := gotradertest.NewBroker(instruments, gotradertest.Balance(10000), gotradertest.Leverage(30))
+	broker.Quote("EUR_USD", 1.0999, 1.1001)
+
+	s := &declarer{}
+	h := gotradertest.New(t, s, broker, gotrader.Instruments([]string{"EUR_USD"}))
+
+	s.targets.Set("EUR_USD", 3000)
+
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Settle()
+	h.AssertUnits("EUR_USD", gotrader.Long, 3000)
+	h.AssertOpenTrades("EUR_USD", 1)
+
+	s.targets.Set("EUR_USD", 5000)
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Settle()
+
+	s.targets.Set("EUR_USD", -1000)
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Settle()
+	h.AssertUnits("EUR_USD", gotrader.Long, 0)
+	h.AssertUnits("EUR_USD", gotrader.Short, 1000)
+
+	if diff, err := s.targets.Diff(); err != nil || len(diff) != 0 {
+		t.Errorf("expected the position reconciled, got %+v, %v", diff, err)
+	}
+}
+
+// overlay hedges the currency exposure of the trades opened by the test.
+type overlay struct {
+	holder
+	hedge *CurrencyHedge
+}
+
+func (s *overlay) SetEngine(engine gotrader.Engine) {
+	s.engine, s.hedge = engine, NewCurrencyHedge(engine, HedgeConfig{Tolerance: 0.1, MinUnits: 100})
+}
+func (s *overlay) OnTick(tick *gotrader.Tick)           { s.hedge.OnTick(tick) }
+func (s *overlay) OnOrderFill(fill *gotrader.OrderFill) { s.hedge.OnOrderFill(fill) }
+
+func TestCurrencyHedge(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_GBP", BaseCurrency: "EUR", QuoteCurrency: "GBP", Leverage: 30, PipLocation: -4},
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+		{Name: "GBP_USD", BaseCurrency: "GBP", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := gotradertest.NewBroker(instruments, gotradertest.Balance(100000), gotradertest.Leverage(30))
+	broker.Quote("EUR_GBP", 0.8499, 0.8501)
+	broker.Quote("EUR_USD", 1.0999, 1.1001)
+	broker.Quote("GBP_USD", 1.2999, 1.3001)
+
+	s := &overlay{}
+	h := gotradertest.New(t, s, broker, gotrader.Instruments([]string{"EUR_GBP", "EUR_USD", "GBP_USD"}))
+
+	if err := s.engine.Buy("EUR_GBP", 10000); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Settle()
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Tick("GBP_USD", 1.2999, 1.3001)
+	h.Settle()
+
+	h.AssertUnits("EUR_USD", gotrader.Short, 10000)
+	h.AssertUnits("GBP_USD", gotrader.Long, 8500)
+
+	exposures, err := s.hedge.Exposures()
+	if err != nil || len(exposures) != 2 {
+		t.Fatalf("expected the EUR and GBP exposures, got %+v, %v", exposures, err)
+	}
+
+	for _, e := range exposures {
+		if e.Residual > 1 || e.Residual < -1 {
+			t.Errorf("expected the %s exposure hedged, got %+v", e.Currency, e)
+		}
+	}
+
+	h.Tick("EUR_GBP", 0.8599, 0.8601) // the GBP exposure moves within the band
+	h.Tick("GBP_USD", 1.2999, 1.3001)
+	h.Settle()
+	h.AssertUnits("GBP_USD", gotrader.Long, 8500)
+}
@@ -0,0 +1,272 @@
+package rebalance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+/*
+Targets reconciles the net positions of an account with the positions a strategy declares, in units: the strategy
+sets the position it wants per instrument with Set, and Targets diffs it against the open trades, closing the trades
+and submitting the market orders that make up the difference. An instrument with orders or closes in flight is only
+reconciled again once they are filled, so the ticks received in between do not send them twice; OnTick and
+OnOrderFill must be called with every tick and fill of the strategy:
+
+	func (s *strategy) OnTick(tick *gotrader.Tick) {
+		if s.signal(tick) {
+			s.targets.Set(tick.Instrument, 10000)
+		}
+		s.targets.OnTick(tick)
+	}
+	func (s *strategy) OnOrderFill(fill *gotrader.OrderFill) { s.targets.OnOrderFill(fill) }
+*/
+type Targets struct {
+	mutex    *sync.Mutex
+	engine   gotrader.Engine
+	minUnits int32
+	tag      string // of the trades reconciled, every trade without
+	counter  int
+	targets  map[string]int32
+	orders   map[string]*gotrader.Order // in flight, by client ID
+	closing  map[string]string          // instruments of the trades closing, by trade ID
+	reported map[string]error           // of the last reconciliation by instrument
+}
+
+// NewTargets is the Targets constructor, the differences of fewer units than minUnits are left.
+func NewTargets(engine gotrader.Engine, minUnits int32) *Targets {
+	return newTargets(engine, minUnits, "")
+}
+
+// newTargets returns Targets reconciling the trades with the tag, e.g. the hedges of an overlay, the others are
+// left as is.
+func newTargets(engine gotrader.Engine, minUnits int32, tag string) *Targets {
+	return &Targets{
+		mutex:    &sync.Mutex{},
+		engine:   engine,
+		minUnits: minUnits,
+		tag:      tag,
+		targets:  make(map[string]int32),
+		orders:   make(map[string]*gotrader.Order),
+		closing:  make(map[string]string),
+		reported: make(map[string]error),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// inFlight returns whether the instrument has orders or closes in flight.
+func (t *Targets) inFlight(instrument string) bool {
+
+	for _, order := range t.orders {
+		if order.Instrument == instrument {
+			return true
+		}
+	}
+
+	for _, closing := range t.closing {
+		if closing == instrument {
+			return true
+		}
+	}
+
+	return false
+}
+
+// diff returns the adjustment of the instrument to its target, false when it has none, is within the minimum units
+// of it or has orders or closes in flight.
+func (t *Targets) diff(instrument string) (Adjustment, bool, error) {
+
+	target, exist := t.targets[instrument]
+	if !exist || t.inFlight(instrument) {
+		return Adjustment{}, false, nil
+	}
+
+	inst := t.engine.Account().Instrument(instrument)
+	if inst == nil {
+		return Adjustment{}, false, fmt.Errorf("%s: %w", instrument, gotrader.ErrInstrumentNotTraded)
+	}
+
+	current := netUnits(inst, t.tag)
+	if delta := target - current; delta == 0 || (delta > -t.minUnits && delta < t.minUnits) {
+		return Adjustment{}, false, nil
+	}
+
+	return adjust(inst, current, target, t.tag), true, nil
+}
+
+// reconcile sends the adjustment of the instrument, registered as in flight. The engine is called without holding
+// the lock, since the backtests fill the orders synchronously.
+func (t *Targets) reconcile(instrument string) error {
+
+	t.mutex.Lock()
+
+	a, due, err := t.diff(instrument)
+	if err != nil || !due {
+		t.mutex.Unlock()
+		return err
+	}
+
+	for _, id := range a.Closes {
+		t.closing[id] = instrument
+	}
+
+	if a.Order != nil {
+		t.counter++
+		a.Order.ClientID = "target-" + strconv.Itoa(t.counter)
+		t.orders[a.Order.ClientID] = a.Order
+	}
+
+	t.mutex.Unlock()
+
+	var errs []error
+	failed := make([]string, 0)
+
+	for _, id := range a.Closes {
+		if err := t.engine.CloseTrade(instrument, id); err != nil {
+			failed = append(failed, id)
+			errs = append(errs, err)
+		}
+	}
+
+	if a.Order != nil {
+		if _, err := t.engine.SubmitOrder(a.Order); err != nil {
+			failed = append(failed, a.Order.ClientID)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(failed) > 0 {
+		t.mutex.Lock()
+		for _, id := range failed {
+			delete(t.closing, id)
+			delete(t.orders, id)
+		}
+		t.mutex.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Set declares the target net position of the instrument, negative for the shorts, reconciled on its next tick.
+func (t *Targets) Set(instrument string, units int32) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.targets[instrument] = units
+}
+
+// Clear stops reconciling the instrument, its position is left as is.
+func (t *Targets) Clear(instrument string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.targets, instrument)
+}
+
+// Target returns the target net position of the instrument, false without target.
+func (t *Targets) Target(instrument string) (int32, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	units, exist := t.targets[instrument]
+
+	return units, exist
+}
+
+// Diff returns the adjustments that Reconcile would send, by instrument name order.
+func (t *Targets) Diff() ([]Adjustment, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	names := make([]string, 0, len(t.targets))
+	for name := range t.targets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	adjustments := make([]Adjustment, 0)
+
+	for _, name := range names {
+
+		a, due, err := t.diff(name)
+		if err != nil {
+			return nil, err
+		}
+
+		if due {
+			adjustments = append(adjustments, a)
+		}
+	}
+
+	return adjustments, nil
+}
+
+// Reconcile sends the adjustments of every instrument with target.
+func (t *Targets) Reconcile() error {
+
+	t.mutex.Lock()
+	names := make([]string, 0, len(t.targets))
+	for name := range t.targets {
+		names = append(names, name)
+	}
+	t.mutex.Unlock()
+
+	sort.Strings(names)
+
+	var errs []error
+
+	for _, name := range names {
+		if err := t.reconcile(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// OnTick reconciles the instrument of the tick, the errors are kept until the next tick, see Err.
+func (t *Targets) OnTick(tick *gotrader.Tick) {
+
+	err := t.reconcile(tick.Instrument)
+
+	t.mutex.Lock()
+	t.reported[tick.Instrument] = err
+	t.mutex.Unlock()
+}
+
+// OnOrderFill removes the orders and closes filled, or rejected, from the ones in flight.
+func (t *Targets) OnOrderFill(fill *gotrader.OrderFill) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if fill.TradeClose {
+		delete(t.closing, fill.TradeID)
+		return
+	}
+
+	delete(t.orders, fill.ClientOrderID)
+}
+
+// Err returns the error of the last reconciliation of the instrument on its ticks.
+func (t *Targets) Err(instrument string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.reported[instrument]
+}
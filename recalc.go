@@ -0,0 +1,125 @@
+package gotrader
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+/*
+recalculator recalculates the unrealized profit and the margin of the instruments changed since their last
+calculation. Every instrument has an epoch, bumped when its price, its conversion rates or its trades change,
+so a tick only recalculates its instrument and the instruments converted with it, instead of every instrument.
+
+The instruments are sharded across worker goroutines, each instrument always on the same shard, so a tick moving
+the conversion rates of hundreds of instruments is recalculated in parallel. The prices are still stored
+lock-free by the tick and read atomically by the workers.
+*/
+type recalculator struct {
+	shards []chan *recalculation
+	jobs   []*recalculation // reused by every run, runs are not concurrent
+	done   *sync.WaitGroup
+	wg     *sync.WaitGroup
+}
+
+type recalculation struct {
+	instruments []*Instrument
+	done        *sync.WaitGroup
+}
+
+// newRecalculator starts the workers of the instruments, shards defaults to GOMAXPROCS when it is not positive.
+// It is nil, and the instruments are recalculated by the caller, with a single shard.
+func newRecalculator(instruments map[string]*Instrument, shards int) *recalculator {
+
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	if shards > len(instruments) {
+		shards = len(instruments)
+	}
+
+	if shards <= 1 {
+		return nil
+	}
+
+	names := make([]string, 0, len(instruments))
+	for name := range instruments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		instruments[name].shard = i % shards
+	}
+
+	r := &recalculator{
+		shards: make([]chan *recalculation, shards),
+		jobs:   make([]*recalculation, shards),
+		done:   &sync.WaitGroup{},
+		wg:     &sync.WaitGroup{},
+	}
+
+	for i := range r.shards {
+		r.shards[i] = make(chan *recalculation, 1)
+		r.jobs[i] = &recalculation{done: r.done}
+		r.wg.Add(1)
+		go r.work(r.shards[i])
+	}
+
+	return r
+}
+
+func (r *recalculator) work(jobs chan *recalculation) {
+	defer r.wg.Done()
+
+	for job := range jobs {
+		for _, inst := range job.instruments {
+			inst.recalculate()
+		}
+		job.done.Done()
+	}
+}
+
+// run recalculates the instruments and waits for them, a single instrument is recalculated by the caller.
+func (r *recalculator) run(instruments []*Instrument) {
+
+	if r == nil || len(instruments) <= 1 {
+		for _, inst := range instruments {
+			inst.recalculate()
+		}
+		return
+	}
+
+	for _, inst := range instruments {
+		job := r.jobs[inst.shard]
+		job.instruments = append(job.instruments, inst)
+	}
+
+	for i, job := range r.jobs {
+		if len(job.instruments) > 0 {
+			r.done.Add(1)
+			r.shards[i] <- job
+		}
+	}
+
+	r.done.Wait()
+
+	for _, job := range r.jobs {
+		job.instruments = job.instruments[:0]
+	}
+}
+
+// stop stops the workers.
+func (r *recalculator) stop() {
+
+	if r == nil {
+		return
+	}
+
+	for _, jobs := range r.shards {
+		close(jobs)
+	}
+
+	r.wg.Wait()
+}
@@ -0,0 +1,148 @@
+package gotrader
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// benchmarkAccount returns an account trading instruments quoted in EUR with trades on both sides, converted to
+// the USD home currency with EUR_USD.
+func benchmarkAccount(instruments, trades, shards int) (*Account, *currencyConversionEngine) {
+
+	account := newAccount("benchmark")
+	account.homeCurrency = "USD"
+	account.balance.Store(DecimalFromInt(100000))
+
+	available := map[string]InstrumentDetails{
+		"EUR_USD": {Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30},
+	}
+	conversions := make(map[string]*instrumentConversion)
+
+	for i := 0; i < instruments; i++ {
+		name := fmt.Sprintf("C%03d_EUR", i)
+		available[name] = InstrumentDetails{Name: name, BaseCurrency: name[:4], QuoteCurrency: "EUR", Leverage: 30}
+		account.instruments[name] = newInstrument(name, name[:4], "EUR", 30, -4, NopLogger())
+		conversions[name] = newInstrumentConversion(name, name[:4], "EUR")
+	}
+
+	ce := newCurrencyConversionEngine(conversions, available, "USD", NopLogger())
+	ce.start()
+	ce.setPricePointers(account.instruments)
+	ce.conversionInstruments["EUR_USD"].Bid.Store(1.1)
+	ce.conversionInstruments["EUR_USD"].Ask.Store(1.1002)
+	ce.updateRate("EUR_USD")
+
+	now := time.Now()
+
+	for name, inst := range account.instruments {
+		inst.updatePrice(&Tick{Instrument: name, Bid: 1.2, Ask: 1.2002, Time: now})
+		ce.updateRate(name)
+		for t := 0; t < trades; t++ {
+			side := Long
+			if t%2 == 1 {
+				side = Short
+			}
+			inst.openTrade(name+strconv.Itoa(t), side, now, 1000, 1.2)
+		}
+	}
+
+	account.recalculator = newRecalculator(account.instruments, shards)
+	account.recalculate()
+
+	return account, ce
+}
+
+func BenchmarkTick(b *testing.B) {
+
+	for _, size := range []struct{ instruments, trades int }{{10, 10}, {300, 10}} {
+
+		b.Run(fmt.Sprintf("%dx%d", size.instruments, size.trades), func(b *testing.B) {
+
+			account, ce := benchmarkAccount(size.instruments, size.trades, 0)
+			defer account.recalculator.stop()
+
+			names := make([]string, 0, len(account.instruments))
+			for name := range account.instruments {
+				names = append(names, name)
+			}
+
+			now := time.Now()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				name := names[n%len(names)]
+				tick := &Tick{Instrument: name, Bid: 1.2 + float64(n%10)*0.0001, Ask: 1.2002, Time: now}
+				account.instruments[name].updatePrice(tick)
+				ce.updateRate(name)
+				account.recalculate()
+			}
+		})
+	}
+}
+
+func BenchmarkConversionTick(b *testing.B) {
+
+	for _, shards := range []int{1, 4} {
+
+		b.Run(fmt.Sprintf("300x10/shards=%d", shards), func(b *testing.B) {
+
+			account, ce := benchmarkAccount(300, 10, shards)
+			defer account.recalculator.stop()
+
+			conversion := ce.conversionInstruments["EUR_USD"]
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				conversion.Bid.Store(1.1 + float64(n%10)*0.0001)
+				ce.updateRate("EUR_USD")
+				account.recalculate()
+			}
+		})
+	}
+}
+
+// BenchmarkTickDelivery sends the ticks to a consumer goroutine as the clients do to the engines, with allocated
+// and pooled ticks.
+func BenchmarkTickDelivery(b *testing.B) {
+
+	for _, pooled := range []bool{false, true} {
+
+		b.Run(fmt.Sprintf("pooled=%t", pooled), func(b *testing.B) {
+
+			ticks := make(chan *Tick, 300)
+			done := make(chan struct{})
+
+			go func() {
+				for tick := range ticks {
+					releaseTick(tick)
+				}
+				close(done)
+			}()
+
+			now := time.Now()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				var tick *Tick
+				if pooled {
+					tick = AcquireTick()
+				} else {
+					tick = &Tick{}
+				}
+				tick.Instrument, tick.Bid, tick.Ask, tick.Time = "EUR_USD", 1.1, 1.1002, now
+				ticks <- tick
+			}
+
+			close(ticks)
+			<-done
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package gotrader
+
+import (
+	"time"
+)
+
+// DiscrepancyType identifies a difference between the local state and the broker state.
+type DiscrepancyType int
+
+const (
+	// MissingLocalTrade is a trade open on the broker that was not tracked locally, it is added to the account
+	MissingLocalTrade DiscrepancyType = iota
+
+	// MissingBrokerTrade is a local trade that is no longer open on the broker, it is removed from the account
+	MissingBrokerTrade
+
+	// UnitsMismatch is a trade whose units differ between the local state and the broker
+	UnitsMismatch
+
+	// MissingLocalOrder is a pending order on the broker that was not tracked locally
+	MissingLocalOrder
+
+	// MissingBrokerOrder is a local pending order that is no longer pending on the broker
+	MissingBrokerOrder
+
+	// BalanceMismatch is a difference between the local and the broker balance, the broker balance is adopted
+	BalanceMismatch
+)
+
+func (d DiscrepancyType) String() string {
+
+	names := [...]string{
+		"MISSING_LOCAL_TRADE",
+		"MISSING_BROKER_TRADE",
+		"UNITS_MISMATCH",
+		"MISSING_LOCAL_ORDER",
+		"MISSING_BROKER_ORDER",
+		"BALANCE_MISMATCH",
+	}
+
+	return names[d]
+}
+
+// Discrepancy is emitted by the reconciliation pass that runs after a broker reconnection.
+// Local and Broker hold the compared values (units or balance) when applicable.
+type Discrepancy struct {
+	Type       DiscrepancyType
+	Instrument string
+	TradeID    string
+	OrderID    string
+	Local      float64
+	Broker     float64
+	Time       time.Time
+}
+
+// DiscrepancyHandler is the callback of the reconciliation discrepancies.
+type DiscrepancyHandler func(discrepancy *Discrepancy)
+
+// reconcile diffs the broker open trades and pending orders against the local state, the broker is
+// considered the source of truth and the local state is updated accordingly.
+func (e *liveEngine) reconcile(t time.Time) {
+
+	emit := func(d *Discrepancy) {
+		d.Time = t
+		e.logger.Warnf("reconciliation: %s %s %s%s", d.Type, d.Instrument, d.TradeID, d.OrderID)
+		if e.parameters.discrepancyHandler != nil {
+			e.parameters.discrepancyHandler(d)
+		}
+	}
+
+	var status AccountStatus
+	err := e.retry("account status", AuditRecord{}, true, func() (err error) {
+		status, err = e.client.GetAccountStatus(e.account.id)
+		return err
+	})
+	if err != nil {
+		e.logger.Warn(err)
+		return
+	}
+
+	if local := e.account.balance.Load(); local != NewDecimal(status.Balance) {
+		e.account.balance.Store(NewDecimal(status.Balance))
+		emit(&Discrepancy{Type: BalanceMismatch, Local: local.Float64(), Broker: status.Balance})
+	}
+
+	var trades []TradeDetails
+	err = e.retry("open trades", AuditRecord{}, true, func() (err error) {
+		trades, err = e.client.GetOpenTrades(e.account.id)
+		return err
+	})
+	if err != nil {
+		e.logger.Warn(err)
+		return
+	}
+
+	brokerTrades := make(map[string]TradeDetails, len(trades))
+	for _, tr := range trades {
+		brokerTrades[tr.ID] = tr
+	}
+
+	for name, inst := range e.account.instruments {
+		for trade := range inst.Trades() {
+
+			brokerTrade, exist := brokerTrades[trade.id]
+
+			if !exist {
+				e.account.wal.write(&WALEntry{Operation: WALCloseTrade, Time: e.clock.Now(), Instrument: name, TradeID: trade.id}, e.logger)
+				inst.closeTrade(trade.id)
+				emit(&Discrepancy{Type: MissingBrokerTrade, Instrument: name, TradeID: trade.id, Local: float64(trade.units)})
+				continue
+			}
+
+			if brokerTrade.Units != trade.units {
+				emit(&Discrepancy{
+					Type:       UnitsMismatch,
+					Instrument: name,
+					TradeID:    trade.id,
+					Local:      float64(trade.units),
+					Broker:     float64(brokerTrade.Units),
+				})
+			}
+
+			delete(brokerTrades, trade.id)
+		}
+	}
+
+	for _, tr := range brokerTrades {
+
+		inst, exist := e.account.instruments[tr.Instrument.Name]
+		if !exist {
+			continue
+		}
+
+		e.account.wal.write(&WALEntry{
+			Operation:  WALOpenTrade,
+			Time:       tr.OpenTime,
+			Instrument: tr.Instrument.Name,
+			TradeID:    tr.ID,
+			Side:       tr.Side,
+			Units:      tr.Units,
+			Price:      tr.OpenPrice,
+			Fees:       tr.ChargedFees,
+			Venue:      tr.Venue,
+			Tag:        tr.Tag,
+		}, e.logger)
+
+		trade := inst.openTrade(tr.ID, tr.Side, tr.OpenTime, tr.Units, tr.OpenPrice)
+		trade.charge(UnitemizedFee, NewDecimal(tr.ChargedFees))
+		trade.venue = tr.Venue
+		trade.tag = tr.Tag
+
+		emit(&Discrepancy{Type: MissingLocalTrade, Instrument: tr.Instrument.Name, TradeID: tr.ID, Broker: float64(tr.Units)})
+	}
+
+	broker, isBroker := e.client.(Broker)
+	if !isBroker {
+		return
+	}
+
+	orders, err := broker.GetPendingOrders(e.account.id)
+	if err != nil {
+		e.logger.Warn(err)
+		return
+	}
+
+	brokerOrders := make(map[string]*Order, len(orders))
+	for _, o := range orders {
+		brokerOrders[o.ID] = o
+	}
+
+	for _, o := range e.pendingOrders.list() {
+		if _, exist := brokerOrders[o.ID]; !exist {
+			e.pendingOrders.remove(o.ID)
+			emit(&Discrepancy{Type: MissingBrokerOrder, Instrument: o.Instrument, OrderID: o.ID})
+		}
+	}
+
+	for id, o := range brokerOrders {
+		if _, exist := e.pendingOrders.get(id); !exist {
+			e.pendingOrders.add(o)
+			emit(&Discrepancy{Type: MissingLocalOrder, Instrument: o.Instrument, OrderID: id})
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package gotrader
+
+import (
+	"strings"
+	"time"
+)
+
+// RejectReason is the standardized reason of a rejected order or trade close, classified from the reasons of the
+// brokers, see ParseRejectReason.
+type RejectReason int
+
+const (
+	// RejectUnknown is a reason not classified
+	RejectUnknown RejectReason = iota
+
+	// RejectInsufficientMargin is a lack of margin or balance
+	RejectInsufficientMargin
+
+	// RejectMarketClosed is an order outside the trading hours
+	RejectMarketClosed
+
+	// RejectPriceChanged is a price moved beyond the bounds of the order, e.g. a requote
+	RejectPriceChanged
+
+	// RejectSizeLimit is a size below the minimum or above the maximum of the instrument
+	RejectSizeLimit
+
+	// RejectOrderExpired is a pending order past its expiry
+	RejectOrderExpired
+
+	// RejectInstrumentHalted is an instrument not trading, halted, close only or expired
+	RejectInstrumentHalted
+
+	// RejectLimitState is a side blocked by the limit-up/limit-down state of the instrument
+	RejectLimitState
+
+	// RejectTradingPaused is an order in a trading pause, e.g. around the news
+	RejectTradingPaused
+
+	// RejectComplianceViolation is an order breaking a compliance rule
+	RejectComplianceViolation
+)
+
+func (r RejectReason) String() string {
+
+	names := [...]string{"UNKNOWN", "INSUFFICIENT_MARGIN", "MARKET_CLOSED", "PRICE_CHANGED", "SIZE_LIMIT",
+		"ORDER_EXPIRED", "INSTRUMENT_HALTED", "LIMIT_STATE", "TRADING_PAUSED", "COMPLIANCE_VIOLATION"}
+
+	return names[r]
+}
+
+// rejectPatterns classify the reasons of the brokers by the first pattern they contain, normalized in upper case
+// with underscores, the specific patterns come first.
+var rejectPatterns = []struct {
+	pattern string
+	reason  RejectReason
+}{
+	{"INSTRUMENT_EXPIRED", RejectInstrumentHalted},
+	{"EXPIRED", RejectOrderExpired},
+	{"MARGIN", RejectInsufficientMargin},
+	{"INSUFFICIENT_BALANCE", RejectInsufficientMargin},
+	{"INSUFFICIENT_FUNDS", RejectInsufficientMargin},
+	{"BUYING_POWER", RejectInsufficientMargin},
+	{"LIMIT_STATE", RejectLimitState},
+	{"LIMIT_UP", RejectLimitState},
+	{"LIMIT_DOWN", RejectLimitState},
+	{"MARKET_CLOSED", RejectMarketClosed},
+	{"MARKET_IS_CLOSED", RejectMarketClosed},
+	{"TRADING_HOURS", RejectMarketClosed},
+	{"HALTED", RejectInstrumentHalted},
+	{"CLOSE_ONLY", RejectInstrumentHalted},
+	{"NOT_TRADEABLE", RejectInstrumentHalted},
+	{"PAUSED", RejectTradingPaused},
+	{"COMPLIANCE", RejectComplianceViolation},
+	{"PRICE_BOUND", RejectPriceChanged},
+	{"BOUNDS_VIOLATION", RejectPriceChanged},
+	{"PRICE_CHANGED", RejectPriceChanged},
+	{"REQUOTE", RejectPriceChanged},
+	{"OFF_QUOTES", RejectPriceChanged},
+	{"PERCENT_PRICE", RejectPriceChanged},
+	{"UNITS", RejectSizeLimit},
+	{"LOT_SIZE", RejectSizeLimit},
+	{"MIN_NOTIONAL", RejectSizeLimit},
+	{"QUANTITY", RejectSizeLimit},
+	{"SIZE", RejectSizeLimit},
+}
+
+// ParseRejectReason classifies the reason of a rejection given by a broker, e.g. the OANDA INSUFFICIENT_MARGIN,
+// the Binance LOT_SIZE filter failure or the rejections of the engines, RejectUnknown when it is not recognized.
+func ParseRejectReason(reason string) RejectReason {
+
+	normalized := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.':
+			return '_'
+		}
+		return r
+	}, strings.ToUpper(reason))
+
+	for _, p := range rejectPatterns {
+		if strings.Contains(normalized, p.pattern) {
+			return p.reason
+		}
+	}
+
+	return RejectUnknown
+}
+
+// OrderRejected is published when an order or a trade close is rejected, after its failed OrderFilled.
+type OrderRejected struct {
+	Time   time.Time
+	Fill   *OrderFill
+	Reason RejectReason
+}
+
+// RejectionHandler is implemented by the strategies handling the rejections, OnOrderRejected is called after the
+// OnOrderFill of the failed fill.
+type RejectionHandler interface {
+	OnOrderRejected(fill *OrderFill, reason RejectReason)
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// classifyRejection sets the reason of a failed fill from its error, unless the client classified it.
+func classifyRejection(fill *OrderFill) {
+	if fill.Error != "" && fill.Rejection == RejectUnknown {
+		fill.Rejection = ParseRejectReason(fill.Error)
+	}
+}
+
+// notifyRejection calls the OnOrderRejected of the strategy for a failed fill.
+func notifyRejection(strategy Strategy, fill *OrderFill) {
+
+	if fill.Error == "" {
+		return
+	}
+
+	if handler, ok := strategy.(RejectionHandler); ok {
+		handler.OnOrderRejected(fill, fill.Rejection)
+	}
+}
@@ -0,0 +1,311 @@
+package report
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Column is a column of the exported history, Numeric columns are written as numbers to xlsx.
+type Column struct {
+	Header  string
+	Numeric bool
+	Value   func(t *gotrader.Transaction, format func(time.Time) string) string
+}
+
+func textColumn(header string, value func(t *gotrader.Transaction) string) Column {
+	return Column{
+		Header: header,
+		Value:  func(t *gotrader.Transaction, _ func(time.Time) string) string { return value(t) },
+	}
+}
+
+func numberColumn(header string, value func(t *gotrader.Transaction) float64) Column {
+	return Column{
+		Header:  header,
+		Numeric: true,
+		Value: func(t *gotrader.Transaction, _ func(time.Time) string) string {
+			return strconv.FormatFloat(value(t), 'f', -1, 64)
+		},
+	}
+}
+
+func timeColumn(header string, value func(t *gotrader.Transaction) time.Time) Column {
+	return Column{
+		Header: header,
+		Value: func(t *gotrader.Transaction, format func(time.Time) string) string {
+			if value(t).IsZero() {
+				return ""
+			}
+			return format(value(t))
+		},
+	}
+}
+
+var (
+	colTime       = timeColumn("Time", func(t *gotrader.Transaction) time.Time { return t.Time })
+	colType       = textColumn("Type", func(t *gotrader.Transaction) string { return t.Type.String() })
+	colTradeID    = textColumn("Trade ID", func(t *gotrader.Transaction) string { return t.TradeID })
+	colInstrument = textColumn("Instrument", func(t *gotrader.Transaction) string { return t.Instrument })
+	colSide       = textColumn("Side", func(t *gotrader.Transaction) string {
+		if t.Type.External() {
+			return ""
+		}
+		return t.Side.String()
+	})
+	colUnits      = numberColumn("Units", func(t *gotrader.Transaction) float64 { return float64(t.Units) })
+	colOpenTime   = timeColumn("Open Time", func(t *gotrader.Transaction) time.Time { return t.OpenTime })
+	colOpenPrice  = numberColumn("Open Price", func(t *gotrader.Transaction) float64 { return t.OpenPrice })
+	colCloseTime  = timeColumn("Close Time", func(t *gotrader.Transaction) time.Time { return t.Time })
+	colClosePrice = numberColumn("Close Price", func(t *gotrader.Transaction) float64 { return t.ClosePrice })
+	colProfit     = numberColumn("Profit", func(t *gotrader.Transaction) float64 { return t.Amount })
+	colAmount     = numberColumn("Amount", func(t *gotrader.Transaction) float64 { return t.Amount })
+	colFees       = numberColumn("Fees", func(t *gotrader.Transaction) float64 { return t.Fees })
+	colBalance    = numberColumn("Balance", func(t *gotrader.Transaction) float64 { return t.Balance })
+	colTag        = textColumn("Tag", func(t *gotrader.Transaction) string { return t.Tag })
+)
+
+// TradeColumns are the default columns of the closed trades export.
+var TradeColumns = []Column{
+	colTradeID, colInstrument, colSide, colUnits, colOpenTime, colOpenPrice, colCloseTime, colClosePrice,
+	colProfit, colFees, colBalance, colTag,
+}
+
+// FundingColumns are the default columns of the financing and funds transfer export.
+var FundingColumns = []Column{
+	colTime, colType, colTradeID, colInstrument, colSide, colUnits, colAmount, colBalance,
+}
+
+// SelectColumns returns the columns with the given headers, in the headers order.
+func SelectColumns(columns []Column, headers ...string) []Column {
+
+	selected := make([]Column, 0, len(headers))
+
+	for _, header := range headers {
+		for _, c := range columns {
+			if c.Header == header {
+				selected = append(selected, c)
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// ExportOption represents an Exporter functional option
+type ExportOption func(e *Exporter)
+
+// Columns is the functional option to define the exported columns, defaults to TradeColumns.
+func Columns(columns ...Column) ExportOption {
+	return func(e *Exporter) {
+		e.columns = columns
+	}
+}
+
+// Location is the functional option to define the timezone of the exported times, defaults to UTC.
+func Location(location *time.Location) ExportOption {
+	return func(e *Exporter) {
+		e.location = location
+	}
+}
+
+// TimeFormat is the functional option to define the layout of the exported times, defaults to
+// "2006-01-02 15:04:05".
+func TimeFormat(layout string) ExportOption {
+	return func(e *Exporter) {
+		e.layout = layout
+	}
+}
+
+// Types is the functional option to define the exported transaction types, defaults to the trade closes.
+func Types(types ...gotrader.TransactionType) ExportOption {
+	return func(e *Exporter) {
+		e.types = types
+	}
+}
+
+// Exporter writes the ledger history as CSV or xlsx, for accounting and tax reporting.
+type Exporter struct {
+	columns  []Column
+	location *time.Location
+	layout   string
+	types    []gotrader.TransactionType
+}
+
+// NewExporter is the Exporter constructor, use Columns(FundingColumns...) with the financing and funds
+// transfer types to export the funding history.
+func NewExporter(opts ...ExportOption) *Exporter {
+
+	e := &Exporter{
+		columns:  TradeColumns,
+		location: time.UTC,
+		layout:   "2006-01-02 15:04:05",
+		types:    []gotrader.TransactionType{gotrader.TradeCloseTransaction},
+	}
+
+	for _, o := range opts {
+		o(e)
+	}
+
+	return e
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (e *Exporter) format(t time.Time) string {
+	return t.In(e.location).Format(e.layout)
+}
+
+func (e *Exporter) exported(t *gotrader.Transaction) bool {
+
+	for _, tt := range e.types {
+		if tt == t.Type {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rows returns the headers and the values of the exported transactions.
+func (e *Exporter) rows(transactions []*gotrader.Transaction) [][]string {
+
+	headers := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		headers[i] = c.Header
+	}
+
+	rows := [][]string{headers}
+
+	for _, t := range transactions {
+
+		if !e.exported(t) {
+			continue
+		}
+
+		row := make([]string, len(e.columns))
+		for i, c := range e.columns {
+			row[i] = c.Value(t, e.format)
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func (e *Exporter) writeSheet(w io.Writer, rows [][]string) error {
+
+	io.WriteString(w, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	for r, row := range rows {
+
+		io.WriteString(w, `<row r="`+strconv.Itoa(r+1)+`">`)
+
+		for c, value := range row {
+
+			ref := cellReference(c, r)
+
+			if r > 0 && e.columns[c].Numeric && value != "" {
+				io.WriteString(w, `<c r="`+ref+`"><v>`+value+`</v></c>`)
+				continue
+			}
+
+			io.WriteString(w, `<c r="`+ref+`" t="inlineStr"><is><t>`)
+			if err := xml.EscapeText(w, []byte(value)); err != nil {
+				return err
+			}
+			io.WriteString(w, `</t></is></c>`)
+		}
+
+		io.WriteString(w, `</row>`)
+	}
+
+	_, err := io.WriteString(w, `</sheetData></worksheet>`)
+
+	return err
+}
+
+// cellReference returns the A1 reference of a zero based cell.
+func cellReference(column, row int) string {
+
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+
+	return name + strconv.Itoa(row+1)
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// WriteCSV writes the transactions as CSV, with a header row.
+func (e *Exporter) WriteCSV(w io.Writer, transactions []*gotrader.Transaction) error {
+
+	writer := csv.NewWriter(w)
+
+	if err := writer.WriteAll(e.rows(transactions)); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+// WriteXLSX writes the transactions as an Excel workbook with a single sheet, with a header row.
+func (e *Exporter) WriteXLSX(w io.Writer, transactions []*gotrader.Transaction) error {
+
+	archive := zip.NewWriter(w)
+
+	for _, part := range xlsxParts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+
+	if err := e.writeSheet(sheet, e.rows(transactions)); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="History" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
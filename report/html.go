@@ -0,0 +1,127 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+const (
+	chartWidth  = 800.0
+	chartHeight = 200.0
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent": func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"money":   func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"price":   func(v float64) string { return fmt.Sprintf("%.5f", v) },
+	"date":    func(t Trade) string { return t.CloseTime.Format("2006-01-02 15:04:05") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gotrader report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th { background: #eee; }
+svg { border: 1px solid #ccc; margin-bottom: 2em; }
+</style>
+</head>
+<body>
+<h1>Report ({{.Report.HomeCurrency}})</h1>
+<table>
+<tr><th>Initial Balance</th><th>Final Balance</th><th>Net Profit</th><th>Return</th><th>Fees</th><th>Trades</th><th>Win Rate</th><th>Max Drawdown</th><th>Sharpe</th></tr>
+<tr>
+<td>{{money .Report.Summary.InitialBalance}}</td>
+<td>{{money .Report.Summary.FinalBalance}}</td>
+<td>{{money .Report.Summary.NetProfit}}</td>
+<td>{{percent .Report.Summary.Return}}</td>
+<td>{{money .Report.Summary.Fees}}</td>
+<td>{{.Report.Summary.Trades}}</td>
+<td>{{percent .Report.Summary.WinRate}}</td>
+<td>{{percent .Report.Summary.MaxDrawdown}}</td>
+<td>{{printf "%.2f" .Report.Summary.Sharpe}}</td>
+</tr>
+</table>
+<p>Fingerprint {{.Report.Fingerprint}}{{if .Report.Seed}}, seed {{.Report.Seed}}{{end}}</p>
+<h2>Equity</h2>
+<svg width="{{.Width}}" height="{{.Height}}"><polyline fill="none" stroke="steelblue" points="{{.Equity}}"/></svg>
+<h2>Drawdown</h2>
+<svg width="{{.Width}}" height="{{.Height}}"><polyline fill="none" stroke="firebrick" points="{{.Drawdown}}"/></svg>
+<h2>Monthly Returns</h2>
+<table>
+<tr><th>Month</th><th>Profit</th><th>Return</th></tr>
+{{range .Report.MonthlyReturns}}<tr><td>{{.Year}}-{{printf "%02d" .Month}}</td><td>{{money .Profit}}</td><td>{{percent .Return}}</td></tr>
+{{end}}</table>
+<h2>Instruments</h2>
+<table>
+<tr><th>Instrument</th><th>Trades</th><th>Wins</th><th>Losses</th><th>Units</th><th>Profit</th><th>Fees</th></tr>
+{{range .Report.Instruments}}<tr><td>{{.Instrument}}</td><td>{{.Trades}}</td><td>{{.Wins}}</td><td>{{.Losses}}</td><td>{{.Units}}</td><td>{{money .Profit}}</td><td>{{money .Fees}}</td></tr>
+{{end}}</table>
+<h2>Trades</h2>
+<table>
+<tr><th>ID</th><th>Instrument</th><th>Side</th><th>Units</th><th>Open Price</th><th>Close Price</th><th>Closed</th><th>Profit</th><th>Fees</th></tr>
+{{range .Report.Trades}}<tr><td>{{.ID}}</td><td>{{.Instrument}}</td><td>{{.Side}}</td><td>{{.Units}}</td><td>{{price .OpenPrice}}</td><td>{{price .ClosePrice}}</td><td>{{date .}}</td><td>{{money .Profit}}</td><td>{{money .Fees}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a self contained HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+
+	return htmlTemplate.Execute(w, struct {
+		Report   *Report
+		Width    float64
+		Height   float64
+		Equity   string
+		Drawdown string
+	}{
+		Report:   r,
+		Width:    chartWidth,
+		Height:   chartHeight,
+		Equity:   polyline(r.EquityCurve),
+		Drawdown: polyline(r.DrawdownCurve),
+	})
+}
+
+// polyline scales a series into the svg coordinates space.
+func polyline(points []Point) string {
+
+	if len(points) == 0 {
+		return ""
+	}
+
+	minValue, maxValue := points[0].Value, points[0].Value
+	for _, p := range points {
+		if p.Value < minValue {
+			minValue = p.Value
+		}
+		if p.Value > maxValue {
+			maxValue = p.Value
+		}
+	}
+
+	valueRange := maxValue - minValue
+	if valueRange == 0 {
+		valueRange = 1
+	}
+
+	step := chartWidth
+	if len(points) > 1 {
+		step = chartWidth / float64(len(points)-1)
+	}
+
+	var sb strings.Builder
+
+	for i, p := range points {
+		x := step * float64(i)
+		y := chartHeight - chartHeight*(p.Value-minValue)/valueRange
+		fmt.Fprintf(&sb, "%.1f,%.1f ", x, y)
+	}
+
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,319 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Point is a sample of a time series.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// Trade is a closed trade entry of the report.
+type Trade struct {
+	ID         string        `json:"id"`
+	Instrument string        `json:"instrument"`
+	Side       string        `json:"side"`
+	Units      int32         `json:"units"`
+	OpenTime   time.Time     `json:"openTime"`
+	CloseTime  time.Time     `json:"closeTime"`
+	OpenPrice  float64       `json:"openPrice"`
+	ClosePrice float64       `json:"closePrice"`
+	Profit     float64       `json:"profit"`
+	Fees       float64       `json:"fees"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// MonthlyReturn is the realized return of a calendar month.
+type MonthlyReturn struct {
+	Year   int     `json:"year"`
+	Month  int     `json:"month"`
+	Profit float64 `json:"profit"`
+	Return float64 `json:"return"`
+}
+
+// InstrumentBreakdown aggregates the realized results of a single instrument.
+type InstrumentBreakdown struct {
+	Instrument string  `json:"instrument"`
+	Trades     int     `json:"trades"`
+	Wins       int     `json:"wins"`
+	Losses     int     `json:"losses"`
+	Profit     float64 `json:"profit"`
+	Fees       float64 `json:"fees"`
+	Units      int64   `json:"units"`
+}
+
+// Summary holds the headline figures of the report.
+type Summary struct {
+	InitialBalance float64 `json:"initialBalance"`
+	FinalBalance   float64 `json:"finalBalance"`
+	NetProfit      float64 `json:"netProfit"`
+	Return         float64 `json:"return"`
+	Fees           float64 `json:"fees"`
+	Trades         int     `json:"trades"`
+	WinRate        float64 `json:"winRate"`
+	MaxDrawdown    float64 `json:"maxDrawdown"`
+	Sharpe         float64 `json:"sharpe"`
+}
+
+// Report is the structured result of a trading session, built from the realized P&L ledger.
+type Report struct {
+	HomeCurrency   string                `json:"homeCurrency"`
+	Summary        Summary               `json:"summary"`
+	EquityCurve    []Point               `json:"equityCurve"`
+	DrawdownCurve  []Point               `json:"drawdownCurve"`
+	Trades         []Trade               `json:"trades"`
+	MonthlyReturns []MonthlyReturn       `json:"monthlyReturns"`
+	Instruments    []InstrumentBreakdown `json:"instruments"`
+	Seed           int64                 `json:"seed,omitempty"` // seed of the backtest run, see backtest.Config
+	Fingerprint    string                `json:"fingerprint"`    // hash of the ledger, equal for bit-identical runs
+}
+
+// New builds the report of an account from its ledger.
+func New(account *gotrader.Account) *Report {
+	return FromLedger(account.Ledger(), account.HomeCurrency())
+}
+
+// FromLedger builds a report from a ledger.
+func FromLedger(ledger *gotrader.Ledger, homeCurrency string) *Report {
+	return FromTransactions(ledger.Transactions(), ledger.OpeningBalance(), homeCurrency)
+}
+
+// FromTransactions builds a report from a time ordered list of transactions.
+func FromTransactions(transactions []*gotrader.Transaction, initialBalance float64, homeCurrency string) *Report {
+
+	r := &Report{
+		HomeCurrency:   homeCurrency,
+		EquityCurve:    make([]Point, 0, len(transactions)+1),
+		DrawdownCurve:  make([]Point, 0, len(transactions)+1),
+		Trades:         make([]Trade, 0, len(transactions)),
+		MonthlyReturns: make([]MonthlyReturn, 0),
+		Instruments:    make([]InstrumentBreakdown, 0),
+	}
+
+	r.Summary.InitialBalance = initialBalance
+	r.Summary.FinalBalance = initialBalance
+
+	instruments := make(map[string]*InstrumentBreakdown)
+	highWaterMark := initialBalance
+	wins := 0
+
+	if len(transactions) > 0 {
+		r.EquityCurve = append(r.EquityCurve, Point{Time: startTime(transactions), Value: initialBalance})
+		r.DrawdownCurve = append(r.DrawdownCurve, Point{Time: startTime(transactions), Value: 0})
+	}
+
+	for _, t := range transactions {
+
+		r.EquityCurve = append(r.EquityCurve, Point{Time: t.Time, Value: t.Balance})
+
+		highWaterMark = math.Max(highWaterMark, t.Balance)
+		drawdown := 0.0
+		if highWaterMark > 0 {
+			drawdown = (highWaterMark - t.Balance) / highWaterMark
+		}
+		r.DrawdownCurve = append(r.DrawdownCurve, Point{Time: t.Time, Value: drawdown})
+		r.Summary.MaxDrawdown = math.Max(r.Summary.MaxDrawdown, drawdown)
+		r.Summary.FinalBalance = t.Balance
+
+		if t.Type.External() {
+			continue
+		}
+
+		r.Summary.NetProfit += t.Amount
+
+		if t.Type != gotrader.TradeCloseTransaction {
+			r.Summary.Fees += t.Amount
+			continue
+		}
+
+		r.Summary.Fees += t.Fees
+		r.Summary.Trades++
+		if t.Amount > 0 {
+			wins++
+		}
+
+		r.Trades = append(r.Trades, Trade{
+			ID:         t.TradeID,
+			Instrument: t.Instrument,
+			Side:       t.Side.String(),
+			Units:      t.Units,
+			OpenTime:   t.OpenTime,
+			CloseTime:  t.Time,
+			OpenPrice:  t.OpenPrice,
+			ClosePrice: t.ClosePrice,
+			Profit:     t.Amount,
+			Fees:       t.Fees,
+			Duration:   t.Time.Sub(t.OpenTime),
+		})
+
+		inst, exist := instruments[t.Instrument]
+		if !exist {
+			inst = &InstrumentBreakdown{Instrument: t.Instrument}
+			instruments[t.Instrument] = inst
+		}
+
+		inst.Trades++
+		inst.Profit += t.Amount
+		inst.Fees += t.Fees
+		inst.Units += int64(t.Units)
+		if t.Amount > 0 {
+			inst.Wins++
+		} else {
+			inst.Losses++
+		}
+	}
+
+	if initialBalance != 0 {
+		r.Summary.Return = r.Summary.NetProfit / initialBalance
+	}
+
+	if r.Summary.Trades > 0 {
+		r.Summary.WinRate = float64(wins) / float64(r.Summary.Trades)
+	}
+
+	r.MonthlyReturns = monthlyReturns(transactions, initialBalance)
+	r.Summary.Sharpe = sharpe(r.MonthlyReturns)
+
+	for _, inst := range instruments {
+		r.Instruments = append(r.Instruments, *inst)
+	}
+
+	sort.Slice(r.Instruments, func(i, j int) bool { return r.Instruments[i].Instrument < r.Instruments[j].Instrument })
+
+	r.Fingerprint = fingerprint(transactions, initialBalance)
+
+	return r
+}
+
+// fingerprint hashes the exact values of the transactions, so runs differing in a single bit of a price or
+// amount have different fingerprints.
+func fingerprint(transactions []*gotrader.Transaction, initialBalance float64) string {
+
+	h := sha256.New()
+	buf := make([]byte, 8)
+
+	write := func(v uint64) {
+		binary.BigEndian.PutUint64(buf, v)
+		h.Write(buf)
+	}
+
+	writeString := func(s string) {
+		write(uint64(len(s)))
+		h.Write([]byte(s))
+	}
+
+	write(math.Float64bits(initialBalance))
+
+	for _, t := range transactions {
+		write(uint64(t.Type))
+		writeString(t.TradeID)
+		writeString(t.Instrument)
+		write(uint64(t.Side))
+		write(uint64(t.Units))
+		write(math.Float64bits(t.OpenPrice))
+		write(math.Float64bits(t.ClosePrice))
+		write(uint64(t.OpenTime.UnixNano()))
+		write(math.Float64bits(t.Amount))
+		write(math.Float64bits(t.Fees))
+		write(math.Float64bits(t.Balance))
+		write(uint64(t.Time.UnixNano()))
+		writeString(t.Tag)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+func startTime(transactions []*gotrader.Transaction) time.Time {
+
+	first := transactions[0]
+
+	if first.Type == gotrader.TradeCloseTransaction && !first.OpenTime.IsZero() {
+		return first.OpenTime
+	}
+
+	return first.Time
+}
+
+func monthlyReturns(transactions []*gotrader.Transaction, initialBalance float64) []MonthlyReturn {
+
+	returns := make([]MonthlyReturn, 0)
+	startBalance := initialBalance
+
+	for _, t := range transactions {
+
+		year, month, _ := t.Time.Date()
+
+		if len(returns) == 0 || returns[len(returns)-1].Year != year || returns[len(returns)-1].Month != int(month) {
+
+			if len(returns) > 0 {
+				last := &returns[len(returns)-1]
+				if startBalance != 0 {
+					last.Return = last.Profit / startBalance
+				}
+				startBalance += last.Profit
+			}
+
+			returns = append(returns, MonthlyReturn{Year: year, Month: int(month)})
+		}
+
+		if t.Type.External() { // transfers and adjustments move the base, not the return
+			startBalance += t.Amount
+			continue
+		}
+
+		returns[len(returns)-1].Profit += t.Amount
+	}
+
+	if len(returns) > 0 && startBalance != 0 {
+		last := &returns[len(returns)-1]
+		last.Return = last.Profit / startBalance
+	}
+
+	return returns
+}
+
+// sharpe returns the annualized sharpe ratio of the monthly returns, assuming a zero risk free rate.
+func sharpe(returns []MonthlyReturn) float64 {
+
+	if len(returns) < 2 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r.Return
+	}
+	mean /= float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r.Return - mean) * (r.Return - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+
+	if std == 0 {
+		return 0
+	}
+
+	return mean / std * math.Sqrt(12)
+}
@@ -0,0 +1,139 @@
+package gotrader
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+/*
+RetryPolicy retries the requests of the live engine to the broker failing with a transient error: the order
+submissions, modifications, cancellations and trade closes, and the queries of the account state. A request is
+attempted up to Attempts times, waiting Base doubling up to Max between the attempts, and every retry is recorded in
+the audit trail as an AuditRetried record.
+
+Retryable classifies the errors retried, TransientError when nil. The timeouts are only retried for the queries by
+default, an order request timing out may still fill; a Retryable retrying them must be used with client order IDs,
+see Order.ClientID, or it may send an order twice.
+
+The modifications and cancellations of the pending orders are retried out of the strategy goroutine, so it doesn't
+wait the delays: when their first attempt fails with a retryable error they return nil, and they are applied once
+the broker confirms them. The pending orders keep their state until then, and after the last attempt failed.
+*/
+type RetryPolicy struct {
+	Attempts  int           // of a request, the first one included, 1 when zero
+	Base      time.Duration // delay before the first retry
+	Max       time.Duration // upper bound of a delay, unbounded when zero
+	Retryable func(err error) bool
+}
+
+// Retry is the functional option to define the retry policy of the requests of the live engine to the broker, they
+// are not retried by default.
+func Retry(policy RetryPolicy) Option {
+	return func(p *sessionParameters) {
+		p.retry = policy
+	}
+}
+
+// TransientError returns true for the errors of the requests that did not reach the broker: the ones wrapping
+// ErrTransient and the network errors other than the timeouts, e.g. a refused connection.
+func TransientError(err error) bool {
+
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && !netErr.Timeout()
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// delay returns the delay before the retry of the failed attempt, starting at one.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+
+	delay := time.Duration(float64(p.Base) * math.Pow(2, float64(attempt-1)))
+
+	if p.Max > 0 && (delay > p.Max || delay < 0) { // also guards against overflow
+		return p.Max
+	}
+
+	return delay
+}
+
+// retryable returns whether the error of a request is retried, query for the requests without side effects.
+func (p RetryPolicy) retryable(err error, query bool) bool {
+
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+
+	return TransientError(err) || query && isTimeout(err)
+}
+
+// retry runs a request to the broker with the retry policy, recording the retries in the audit trail with the
+// details of the record, query for the requests without side effects. It returns the error of the last attempt.
+func (e *liveEngine) retry(request string, record AuditRecord, query bool, fn func() error) error {
+
+	policy := e.parameters.retry
+
+	for attempt := 1; ; attempt++ {
+
+		err := fn()
+		if err == nil || attempt >= policy.Attempts || !policy.retryable(err, query) {
+			return err
+		}
+
+		record.Time = e.clock.Now()
+		record.Event = AuditRetried
+		record.Attempt = attempt
+		record.Reason = fmt.Sprintf("%s: %v", request, err)
+		e.parameters.audit.record(record)
+
+		e.logger.Warnf("%s attempt %d failed, retrying: %v", request, attempt, err)
+
+		time.Sleep(policy.delay(attempt))
+	}
+}
+
+// retryAsync runs a request to the broker once and, when it fails with a retryable error, retries it in the
+// background with the retry policy. done runs when the request succeeds, the failure of the last retry is logged.
+func (e *liveEngine) retryAsync(request string, record AuditRecord, done func(), fn func() error) error {
+
+	err := fn()
+	if err == nil {
+		done()
+		return nil
+	}
+
+	if e.parameters.retry.Attempts <= 1 || !e.parameters.retry.retryable(err, false) {
+		return err
+	}
+
+	go func(failed error) {
+
+		err := e.retry(request, record, false, func() error {
+			if attempt := failed; attempt != nil { // the synchronous attempt
+				failed = nil
+				return attempt
+			}
+			return fn()
+		})
+
+		if err != nil {
+			e.logger.Warnf("%s failed: %v", request, err)
+			return
+		}
+
+		done()
+	}(err)
+
+	return nil
+}
@@ -0,0 +1,447 @@
+package gotrader
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+const venueSeparator = ":"
+
+// Venue is an execution venue of a Router.
+type Venue struct {
+	Name      string
+	AccountID string
+	Client    BrokerClient
+}
+
+type router struct {
+	venues      []Venue
+	mutex       *sync.RWMutex
+	instruments map[string][]int   // instrument -> indexes of the venues that trade it
+	quotes      map[string][]*Tick // instrument -> last tick of each venue
+	details     map[string]InstrumentDetails
+}
+
+// NewRouter is the smart order router constructor. The router is a Broker that aggregates several venues:
+// prices are consolidated into the best bid/ask, orders are sent to the venue quoting the best price with
+// enough size, and trade/order IDs are prefixed with the venue name so they can be routed back.
+func NewRouter(venues ...Venue) Broker {
+	return &router{
+		venues:      venues,
+		mutex:       &sync.RWMutex{},
+		instruments: make(map[string][]int),
+		quotes:      make(map[string][]*Tick),
+		details:     make(map[string]InstrumentDetails),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (r *router) venueID(venue int, id string) string {
+	return r.venues[venue].Name + venueSeparator + id
+}
+
+// split returns the venue index and the venue local ID of a routed ID.
+func (r *router) split(id string) (int, string, error) {
+
+	parts := strings.SplitN(id, venueSeparator, 2)
+
+	if len(parts) == 2 {
+		for i, v := range r.venues {
+			if v.Name == parts[0] {
+				return i, parts[1], nil
+			}
+		}
+	}
+
+	return 0, "", errors.New("unknown venue for " + id)
+}
+
+// route returns the venue with the best price for the side, preferring the ones with enough quoted size.
+func (r *router) route(instrument string, side Side, units int32) (int, error) {
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	best, bestSized := -1, -1
+
+	for _, i := range r.instruments[instrument] {
+
+		q := r.quotes[instrument][i]
+		if q == nil {
+			continue
+		}
+
+		price, size := q.Ask, q.AskSize
+		if side == Short {
+			price, size = q.Bid, q.BidSize
+		}
+
+		better := func(current int) bool {
+			if current < 0 {
+				return true
+			}
+			c := r.quotes[instrument][current]
+			if side == Short {
+				return price > c.Bid
+			}
+			return price < c.Ask
+		}
+
+		if better(best) {
+			best = i
+		}
+
+		if (size == 0 || size >= float64(units)) && better(bestSized) {
+			bestSized = i
+		}
+	}
+
+	if bestSized >= 0 {
+		return bestSized, nil
+	}
+
+	if best >= 0 {
+		return best, nil
+	}
+
+	return 0, errors.New("no venue is quoting " + instrument)
+}
+
+// consolidated returns the best bid and ask across venues, must be called with the read lock held.
+func (r *router) consolidated(instrument string) *Tick {
+
+	var tick *Tick
+
+	for _, q := range r.quotes[instrument] {
+
+		if q == nil {
+			continue
+		}
+
+		if tick == nil {
+			t := *q
+			tick = &t
+			continue
+		}
+
+		if q.Bid > tick.Bid {
+			tick.Bid, tick.BidSize = q.Bid, q.BidSize
+		}
+
+		if q.Ask < tick.Ask {
+			tick.Ask, tick.AskSize = q.Ask, q.AskSize
+		}
+
+		if q.Time.After(tick.Time) {
+			tick.Time = q.Time
+		}
+	}
+
+	return tick
+}
+
+func (r *router) broker(venue int) (Broker, error) {
+
+	broker, isBroker := r.venues[venue].Client.(Broker)
+	if !isBroker {
+		return nil, errors.New("venue " + r.venues[venue].Name + " does not support pending orders")
+	}
+
+	return broker, nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// GetAccountStatus sums the balances of all venues, which must share the same home currency.
+func (r *router) GetAccountStatus(accountID string) (AccountStatus, error) {
+
+	status := AccountStatus{}
+
+	for i, v := range r.venues {
+
+		s, err := v.Client.GetAccountStatus(v.AccountID)
+		if err != nil {
+			return status, err
+		}
+
+		if i == 0 {
+			status.Currency = s.Currency
+			status.Hedge = s.Hedge
+			status.Leverage = s.Leverage
+		} else if s.Currency != status.Currency {
+			return status, errors.New("venue " + v.Name + " has a different home currency")
+		}
+
+		status.Equity += s.Equity
+		status.Balance += s.Balance
+		status.UnrealizedGrossProfit += s.UnrealizedGrossProfit
+		status.MarginUsed += s.MarginUsed
+		status.MarginFree += s.MarginFree
+	}
+
+	return status, nil
+}
+
+func (r *router) GetAvailableInstruments(accountID string) ([]InstrumentDetails, error) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	instruments := make([]InstrumentDetails, 0)
+
+	for i, v := range r.venues {
+
+		available, err := v.Client.GetAvailableInstruments(v.AccountID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, inst := range available {
+
+			if _, exist := r.details[inst.Name]; !exist {
+				r.details[inst.Name] = inst
+				r.quotes[inst.Name] = make([]*Tick, len(r.venues))
+				instruments = append(instruments, inst)
+			}
+
+			r.instruments[inst.Name] = append(r.instruments[inst.Name], i)
+		}
+	}
+
+	return instruments, nil
+}
+
+func (r *router) OpenMarketOrder(accountID, instrument string, units int32, side string) error {
+
+	s := Long
+	if side == Short.String() {
+		s = Short
+	}
+
+	venue, err := r.route(instrument, s, units)
+	if err != nil {
+		return err
+	}
+
+	return r.venues[venue].Client.OpenMarketOrder(r.venues[venue].AccountID, instrument, units, side)
+}
+
+func (r *router) CloseTrade(accountID, id string) error {
+
+	venue, tradeID, err := r.split(id)
+	if err != nil {
+		return err
+	}
+
+	return r.venues[venue].Client.CloseTrade(r.venues[venue].AccountID, tradeID)
+}
+
+func (r *router) GetOpenTrades(accountID string) ([]TradeDetails, error) {
+
+	trades := make([]TradeDetails, 0)
+
+	for i, v := range r.venues {
+
+		venueTrades, err := v.Client.GetOpenTrades(v.AccountID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range venueTrades {
+			t.ID = r.venueID(i, t.ID)
+			t.Venue = v.Name
+			trades = append(trades, t)
+		}
+	}
+
+	return trades, nil
+}
+
+func (r *router) SubmitOrder(accountID string, order *Order) (string, error) {
+
+	venue, err := r.route(order.Instrument, order.Side, order.Units)
+	if err != nil {
+		return "", err
+	}
+
+	broker, err := r.broker(venue)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := broker.SubmitOrder(r.venues[venue].AccountID, order)
+	if err != nil {
+		return "", err
+	}
+
+	return r.venueID(venue, id), nil
+}
+
+func (r *router) ModifyOrder(accountID, orderID string, order *Order) error {
+
+	venue, id, err := r.split(orderID)
+	if err != nil {
+		return err
+	}
+
+	broker, err := r.broker(venue)
+	if err != nil {
+		return err
+	}
+
+	return broker.ModifyOrder(r.venues[venue].AccountID, id, order)
+}
+
+func (r *router) CancelOrder(accountID, orderID string) error {
+
+	venue, id, err := r.split(orderID)
+	if err != nil {
+		return err
+	}
+
+	broker, err := r.broker(venue)
+	if err != nil {
+		return err
+	}
+
+	return broker.CancelOrder(r.venues[venue].AccountID, id)
+}
+
+func (r *router) GetPendingOrders(accountID string) ([]*Order, error) {
+
+	orders := make([]*Order, 0)
+
+	for i, v := range r.venues {
+
+		broker, isBroker := v.Client.(Broker)
+		if !isBroker {
+			continue
+		}
+
+		venueOrders, err := broker.GetPendingOrders(v.AccountID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range venueOrders {
+			o.ID = r.venueID(i, o.ID)
+			orders = append(orders, o)
+		}
+	}
+
+	return orders, nil
+}
+
+// SubscribePrices subscribes every venue and streams the consolidated best bid/ask.
+func (r *router) SubscribePrices(accountID string, instruments []InstrumentDetails, callback TickHandler) error {
+
+	for i, v := range r.venues {
+
+		venueInstruments := make([]InstrumentDetails, 0, len(instruments))
+
+		r.mutex.RLock()
+		for _, inst := range instruments {
+			for _, venue := range r.instruments[inst.Name] {
+				if venue == i {
+					venueInstruments = append(venueInstruments, inst)
+				}
+			}
+		}
+		r.mutex.RUnlock()
+
+		if len(venueInstruments) == 0 {
+			continue
+		}
+
+		venue := i
+		err := v.Client.SubscribePrices(v.AccountID, venueInstruments, func(tick *Tick) {
+
+			if tick == nil {
+				return
+			}
+
+			r.mutex.Lock()
+			r.quotes[tick.Instrument][venue] = tick
+			consolidated := r.consolidated(tick.Instrument)
+			r.mutex.Unlock()
+
+			callback(consolidated)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *router) SubscribeOrderFillNotifications(accountID string, orderFillCallback OrderFillHandler) error {
+
+	for i, v := range r.venues {
+
+		venue := i
+		err := v.Client.SubscribeOrderFillNotifications(v.AccountID, func(fill *OrderFill) {
+
+			if fill.TradeID != "" {
+				fill.TradeID = r.venueID(venue, fill.TradeID)
+			}
+
+			if fill.OrderID != "" {
+				fill.OrderID = r.venueID(venue, fill.OrderID)
+			}
+
+			fill.Venue = r.venues[venue].Name
+			orderFillCallback(fill)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *router) SubscribeSwapChargeNotifications(accountID string, swapChargeCallback SwapChargeHandler) error {
+
+	for i, v := range r.venues {
+
+		venue := i
+		err := v.Client.SubscribeSwapChargeNotifications(v.AccountID, func(charges *SwapCharge) {
+
+			for _, c := range charges.Charges {
+				c.ID = r.venueID(venue, c.ID)
+			}
+
+			swapChargeCallback(charges)
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *router) SubscribeFundsTransferNotifications(accountID string, fundsTransferCallback FundsTransferHandler) error {
+
+	for _, v := range r.venues {
+		if err := v.Client.SubscribeFundsTransferNotifications(v.AccountID, fundsTransferCallback); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
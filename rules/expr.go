@@ -0,0 +1,716 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/indicator"
+)
+
+/**************************
+*
+*	Lexer
+*
+***************************/
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	number float64
+	column int
+}
+
+var operators = []string{"<=", ">=", "==", "!=", "&&", "||", "<", ">", "+", "-", "*", "/", "(", ")", "[", "]", ",",
+	"!"}
+
+func tokenize(source string) ([]token, error) {
+
+	var tokens []token
+
+	for i := 0; i < len(source); {
+
+		c := rune(source[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(source) && (unicode.IsDigit(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			value, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("column %d: invalid number %s", start+1, source[start:i])
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], number: value, column: start + 1})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(source) && (unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i])) ||
+				source[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.ToLower(source[start:i]), column: start + 1})
+
+		default:
+			matched := ""
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					matched = op
+					break
+				}
+			}
+			if matched == "" {
+				return nil, fmt.Errorf("column %d: unexpected %q", i+1, c)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: matched, column: i + 1})
+			i += len(matched)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, column: len(source) + 1}), nil
+}
+
+/**************************
+*
+*	Compiler
+*
+***************************/
+
+// state is the state of an instrument the expressions are evaluated on.
+type state struct {
+	instrument string
+	candles    *candles
+	series     []history // by indicator output, see compiler.outputs
+	account    func(name string) float64
+	missing    bool // set when a value is not computed yet, e.g. before the warm up of an indicator
+}
+
+// candles is the history of the candles of an instrument, from the newest.
+type candles struct {
+	values []*gotrader.Candle
+	next   int
+	len    int
+}
+
+func newCandles(size int) *candles {
+	return &candles{values: make([]*gotrader.Candle, size)}
+}
+
+func (c *candles) push(candle *gotrader.Candle) {
+
+	c.values[c.next] = candle
+	c.next = (c.next + 1) % len(c.values)
+
+	if c.len < len(c.values) {
+		c.len++
+	}
+}
+
+func (c *candles) at(n int) *gotrader.Candle {
+
+	if n < 0 || n >= c.len {
+		return nil
+	}
+
+	return c.values[(c.next-1-n+len(c.values))%len(c.values)]
+}
+
+// The values are evaluated on the candle offset candles ago, the account values are the current ones.
+type (
+	number    func(s *state, offset int) float64
+	condition func(s *state, offset int) bool
+)
+
+// value is a compiled expression, a number or a condition.
+type value struct {
+	number    number
+	condition condition
+	constant  bool // number literal
+	literal   float64
+}
+
+func (v value) isNumber() bool {
+	return v.number != nil
+}
+
+// output is a series of an indicator, e.g. the signal line of a MACD.
+type output struct {
+	key    string // indicator key, shared by the strategies of the runner
+	create func() indicator.Indicator
+	series func(ind indicator.Indicator) (history, bool)
+}
+
+type compiler struct {
+	tokens  []token
+	pos     int
+	outputs []output
+}
+
+func (c *compiler) peek() token {
+	return c.tokens[c.pos]
+}
+
+func (c *compiler) next() token {
+
+	t := c.tokens[c.pos]
+	if t.kind != tokenEnd {
+		c.pos++
+	}
+
+	return t
+}
+
+func (c *compiler) accept(texts ...string) (token, bool) {
+
+	t := c.peek()
+	if t.kind == tokenOperator || t.kind == tokenIdent {
+		for _, text := range texts {
+			if t.text == text {
+				return c.next(), true
+			}
+		}
+	}
+
+	return t, false
+}
+
+func (c *compiler) expect(text string) error {
+
+	if t, ok := c.accept(text); !ok {
+		return c.errorf(t, "expected %s", text)
+	}
+
+	return nil
+}
+
+func (c *compiler) errorf(t token, format string, args ...interface{}) error {
+
+	found := t.text
+	if t.kind == tokenEnd {
+		found = "end"
+	}
+
+	return fmt.Errorf("column %d: %s, found %s", t.column, fmt.Sprintf(format, args...), found)
+}
+
+// compile compiles a condition.
+func (c *compiler) compile(source string) (condition, error) {
+
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	c.tokens, c.pos = tokens, 0
+
+	v, err := c.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := c.peek(); t.kind != tokenEnd {
+		return nil, c.errorf(t, "expected an operator")
+	}
+
+	if v.isNumber() {
+		return nil, errors.New("not a condition")
+	}
+
+	return v.condition, nil
+}
+
+func (c *compiler) conditions(op token, left, right value) (condition, condition, error) {
+
+	if left.isNumber() || right.isNumber() {
+		return nil, nil, fmt.Errorf("column %d: %s of a number", op.column, op.text)
+	}
+
+	return left.condition, right.condition, nil
+}
+
+func (c *compiler) numbers(op token, left, right value) (number, number, error) {
+
+	if !left.isNumber() || !right.isNumber() {
+		return nil, nil, fmt.Errorf("column %d: %s of a condition", op.column, op.text)
+	}
+
+	return left.number, right.number, nil
+}
+
+func (c *compiler) or() (value, error) {
+
+	left, err := c.and()
+	if err != nil {
+		return value{}, err
+	}
+
+	for {
+		op, ok := c.accept("or", "||")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := c.and()
+		if err != nil {
+			return value{}, err
+		}
+
+		a, b, err := c.conditions(op, left, right)
+		if err != nil {
+			return value{}, err
+		}
+
+		left = value{condition: func(s *state, o int) bool { return a(s, o) || b(s, o) }}
+	}
+}
+
+func (c *compiler) and() (value, error) {
+
+	left, err := c.not()
+	if err != nil {
+		return value{}, err
+	}
+
+	for {
+		op, ok := c.accept("and", "&&")
+		if !ok {
+			return left, nil
+		}
+
+		right, err := c.not()
+		if err != nil {
+			return value{}, err
+		}
+
+		a, b, err := c.conditions(op, left, right)
+		if err != nil {
+			return value{}, err
+		}
+
+		left = value{condition: func(s *state, o int) bool { return a(s, o) && b(s, o) }}
+	}
+}
+
+func (c *compiler) not() (value, error) {
+
+	op, ok := c.accept("not", "!")
+	if !ok {
+		return c.comparison()
+	}
+
+	v, err := c.not()
+	if err != nil {
+		return value{}, err
+	}
+
+	if v.isNumber() {
+		return value{}, fmt.Errorf("column %d: %s of a number", op.column, op.text)
+	}
+
+	return value{condition: func(s *state, o int) bool { return !v.condition(s, o) }}, nil
+}
+
+var comparisons = map[string]func(a, b float64) bool{
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"==": func(a, b float64) bool { return a == b },
+	"!=": func(a, b float64) bool { return a != b },
+}
+
+func (c *compiler) comparison() (value, error) {
+
+	left, err := c.sum()
+	if err != nil {
+		return value{}, err
+	}
+
+	op, ok := c.accept("<", "<=", ">", ">=", "==", "!=")
+	if !ok {
+		return left, nil
+	}
+
+	right, err := c.sum()
+	if err != nil {
+		return value{}, err
+	}
+
+	a, b, err := c.numbers(op, left, right)
+	if err != nil {
+		return value{}, err
+	}
+
+	compare := comparisons[op.text]
+
+	return value{condition: func(s *state, o int) bool { return compare(a(s, o), b(s, o)) }}, nil
+}
+
+var arithmetic = map[string]func(a, b float64) float64{
+	"+": func(a, b float64) float64 { return a + b },
+	"-": func(a, b float64) float64 { return a - b },
+	"*": func(a, b float64) float64 { return a * b },
+	"/": func(a, b float64) float64 { return a / b },
+}
+
+func (c *compiler) sum() (value, error) {
+	return c.binary(c.product, "+", "-")
+}
+
+func (c *compiler) product() (value, error) {
+	return c.binary(c.unary, "*", "/")
+}
+
+// binary compiles the arithmetic operations of the operators, left associative.
+func (c *compiler) binary(operand func() (value, error), ops ...string) (value, error) {
+
+	left, err := operand()
+	if err != nil {
+		return value{}, err
+	}
+
+	for {
+		op, ok := c.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+
+		right, err := operand()
+		if err != nil {
+			return value{}, err
+		}
+
+		a, b, err := c.numbers(op, left, right)
+		if err != nil {
+			return value{}, err
+		}
+
+		fn := arithmetic[op.text]
+		left = value{number: func(s *state, o int) float64 { return fn(a(s, o), b(s, o)) }}
+	}
+}
+
+func (c *compiler) unary() (value, error) {
+
+	op, ok := c.accept("-")
+	if !ok {
+		return c.postfix()
+	}
+
+	v, err := c.unary()
+	if err != nil {
+		return value{}, err
+	}
+
+	if !v.isNumber() {
+		return value{}, fmt.Errorf("column %d: - of a condition", op.column)
+	}
+
+	if v.constant {
+		return constant(-v.literal), nil
+	}
+
+	return value{number: func(s *state, o int) float64 { return -v.number(s, o) }}, nil
+}
+
+// postfix compiles the values of the previous candles, x[n] being the value of x n candles ago.
+func (c *compiler) postfix() (value, error) {
+
+	v, err := c.primary()
+	if err != nil {
+		return value{}, err
+	}
+
+	for {
+		if _, ok := c.accept("["); !ok {
+			return v, nil
+		}
+
+		t := c.next()
+		if t.kind != tokenNumber || t.number != math.Trunc(t.number) {
+			return value{}, c.errorf(t, "expected a number of candles")
+		}
+
+		if err := c.expect("]"); err != nil {
+			return value{}, err
+		}
+
+		n := int(t.number)
+
+		if v.isNumber() {
+			inner := v.number
+			v = value{number: func(s *state, o int) float64 { return inner(s, o+n) }}
+		} else {
+			inner := v.condition
+			v = value{condition: func(s *state, o int) bool { return inner(s, o+n) }}
+		}
+	}
+}
+
+func constant(x float64) value {
+	return value{number: func(s *state, o int) float64 { return x }, constant: true, literal: x}
+}
+
+func (c *compiler) primary() (value, error) {
+
+	t := c.next()
+
+	switch t.kind {
+	case tokenNumber:
+		return constant(t.number), nil
+
+	case tokenIdent:
+		if t.text == "true" || t.text == "false" {
+			b := t.text == "true"
+			return value{condition: func(s *state, o int) bool { return b }}, nil
+		}
+
+		if _, ok := c.accept("("); !ok {
+			return c.variable(t)
+		}
+
+		var args []value
+		if _, ok := c.accept(")"); !ok {
+			for {
+				arg, err := c.or()
+				if err != nil {
+					return value{}, err
+				}
+				args = append(args, arg)
+
+				if _, ok := c.accept(","); ok {
+					continue
+				}
+				if err := c.expect(")"); err != nil {
+					return value{}, err
+				}
+				break
+			}
+		}
+
+		return c.call(t, args)
+
+	case tokenOperator:
+		if t.text == "(" {
+			v, err := c.or()
+			if err != nil {
+				return value{}, err
+			}
+			return v, c.expect(")")
+		}
+	}
+
+	return value{}, c.errorf(t, "expected a value")
+}
+
+// prices are the values of the candles.
+var prices = map[string]func(candle *gotrader.Candle) float64{
+	"open":  func(candle *gotrader.Candle) float64 { return candle.Open },
+	"high":  func(candle *gotrader.Candle) float64 { return candle.High },
+	"low":   func(candle *gotrader.Candle) float64 { return candle.Low },
+	"close": func(candle *gotrader.Candle) float64 { return candle.Close },
+}
+
+// accountValues are the values of the strategy account, see Strategy.
+var accountValues = map[string]bool{
+	"position": true, "trades": true, "entry": true, "profit": true, "equity": true, "balance": true, "drawdown": true,
+}
+
+func (c *compiler) variable(t token) (value, error) {
+
+	if price, exist := prices[t.text]; exist {
+		return value{number: func(s *state, o int) float64 {
+			candle := s.candles.at(o)
+			if candle == nil {
+				s.missing = true
+				return 0
+			}
+			return price(candle)
+		}}, nil
+	}
+
+	if accountValues[t.text] {
+		name := t.text
+		return value{number: func(s *state, o int) float64 { return s.account(name) }}, nil
+	}
+
+	return value{}, fmt.Errorf("column %d: unknown value %s", t.column, t.text)
+}
+
+// history is the series of the values of an indicator, from the newest, see indicator.Series.
+type history interface {
+	At(n int) float64
+	Len() int
+}
+
+// function is an indicator function: its number of arguments, the constructor of the indicator of the arguments
+// and its output.
+type function struct {
+	args   int
+	create func(args []float64) indicator.Indicator
+	series func(ind indicator.Indicator) (history, bool)
+}
+
+// mainSeries returns the series of the values of an indicator.
+func mainSeries(ind indicator.Indicator) (history, bool) {
+	h, ok := ind.(history)
+	return h, ok
+}
+
+func period(create func(period int) indicator.Indicator) function {
+	return function{1, func(args []float64) indicator.Indicator { return create(int(args[0])) }, mainSeries}
+}
+
+func macd(args []float64) indicator.Indicator {
+	return indicator.NewMACD(int(args[0]), int(args[1]), int(args[2]))
+}
+
+func bands(args []float64) indicator.Indicator {
+	return indicator.NewBollingerBands(int(args[0]), args[1])
+}
+
+func stochastic(args []float64) indicator.Indicator {
+	return indicator.NewStochastic(int(args[0]), int(args[1]), int(args[2]))
+}
+
+var functions = map[string]function{
+	"sma":        period(func(n int) indicator.Indicator { return indicator.NewSMA(n) }),
+	"ema":        period(func(n int) indicator.Indicator { return indicator.NewEMA(n) }),
+	"wma":        period(func(n int) indicator.Indicator { return indicator.NewWMA(n) }),
+	"dema":       period(func(n int) indicator.Indicator { return indicator.NewDEMA(n) }),
+	"rsi":        period(func(n int) indicator.Indicator { return indicator.NewRSI(n) }),
+	"atr":        period(func(n int) indicator.Indicator { return indicator.NewATR(n) }),
+	"volatility": period(func(n int) indicator.Indicator { return indicator.NewVolatility(n) }),
+	"macd":       {3, macd, mainSeries},
+	"macd_signal": {3, macd, func(ind indicator.Indicator) (history, bool) {
+		m, ok := ind.(*indicator.MACD)
+		if !ok {
+			return nil, false
+		}
+		return m.Signal(), true
+	}},
+	"macd_hist": {3, macd, func(ind indicator.Indicator) (history, bool) {
+		m, ok := ind.(*indicator.MACD)
+		if !ok {
+			return nil, false
+		}
+		return m.Histogram(), true
+	}},
+	"bb_middle": {2, bands, mainSeries},
+	"bb_upper": {2, bands, func(ind indicator.Indicator) (history, bool) {
+		b, ok := ind.(*indicator.BollingerBands)
+		if !ok {
+			return nil, false
+		}
+		return b.Upper(), true
+	}},
+	"bb_lower": {2, bands, func(ind indicator.Indicator) (history, bool) {
+		b, ok := ind.(*indicator.BollingerBands)
+		if !ok {
+			return nil, false
+		}
+		return b.Lower(), true
+	}},
+	"stoch": {3, stochastic, mainSeries},
+	"stoch_d": {3, stochastic, func(ind indicator.Indicator) (history, bool) {
+		st, ok := ind.(*indicator.Stochastic)
+		if !ok {
+			return nil, false
+		}
+		return st.D(), true
+	}},
+}
+
+func (c *compiler) call(t token, args []value) (value, error) {
+
+	switch t.text {
+	case "crossover", "crossunder":
+		if len(args) != 2 || !args[0].isNumber() || !args[1].isNumber() {
+			return value{}, fmt.Errorf("column %d: %s takes 2 numbers", t.column, t.text)
+		}
+
+		a, b := args[0].number, args[1].number
+		if t.text == "crossunder" {
+			a, b = b, a
+		}
+
+		// a crosses over b when it is above and was not on the previous candle
+		return value{condition: func(s *state, o int) bool {
+			return a(s, o) > b(s, o) && a(s, o+1) <= b(s, o+1)
+		}}, nil
+
+	case "abs":
+		if len(args) != 1 || !args[0].isNumber() {
+			return value{}, fmt.Errorf("column %d: abs takes a number", t.column)
+		}
+
+		x := args[0].number
+		return value{number: func(s *state, o int) float64 { return math.Abs(x(s, o)) }}, nil
+
+	case "min", "max":
+		if len(args) != 2 || !args[0].isNumber() || !args[1].isNumber() {
+			return value{}, fmt.Errorf("column %d: %s takes 2 numbers", t.column, t.text)
+		}
+
+		fn := math.Min
+		if t.text == "max" {
+			fn = math.Max
+		}
+
+		a, b := args[0].number, args[1].number
+		return value{number: func(s *state, o int) float64 { return fn(a(s, o), b(s, o)) }}, nil
+	}
+
+	f, exist := functions[t.text]
+	if !exist {
+		return value{}, fmt.Errorf("column %d: unknown function %s", t.column, t.text)
+	}
+
+	if len(args) != f.args {
+		return value{}, fmt.Errorf("column %d: %s takes %d arguments", t.column, t.text, f.args)
+	}
+
+	literals := make([]float64, len(args))
+	key := []string{strings.SplitN(t.text, "_", 2)[0]}
+
+	for i, arg := range args {
+		if !arg.constant || arg.literal <= 0 {
+			return value{}, fmt.Errorf("column %d: the arguments of %s are positive numbers", t.column, t.text)
+		}
+		literals[i] = arg.literal
+		key = append(key, strconv.FormatFloat(arg.literal, 'f', -1, 64))
+	}
+
+	index := len(c.outputs)
+	c.outputs = append(c.outputs, output{
+		key:    strings.Join(key, "-"),
+		create: func() indicator.Indicator { return f.create(literals) },
+		series: f.series,
+	})
+
+	return value{number: func(s *state, o int) float64 {
+		series := s.series[index]
+		if o >= series.Len() {
+			s.missing = true
+			return 0
+		}
+		return series.At(o)
+	}}, nil
+}
@@ -0,0 +1,152 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/gotradertest"
+	"github.com/luismcruz/gotrader/runner"
+)
+
+func TestCompile(t *testing.T) {
+
+	t.Run("errors", func(t *testing.T) {
+
+		tests := []struct {
+			source string
+			err    string
+		}{
+			{"close + 1", "not a condition"},
+			{"close > 1 and 2", "column 11: and of a number"},
+			{"rsi(14) > ", "column 11: expected a value, found end"},
+			{"sma(x) > 1", "column 5: unknown value x"},
+			{"sma(close) > 1", "arguments of sma are positive numbers"},
+			{"foo(1) > 1", "unknown function foo"},
+			{"close[1.5] > 1", "expected a number of candles"},
+			{"(close > 1", "column 11: expected ), found end"},
+			{"close > 1 $", "column 11: unexpected '$'"},
+		}
+
+		for _, test := range tests {
+			if _, err := (&compiler{}).compile(test.source); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: expected the error %q, got %v", test.source, test.err, err)
+			}
+		}
+
+		if _, err := Compile(Config{Timeframe: time.Minute, Units: 1000, ExitLong: "close > 1"}); err == nil {
+			t.Error("expected the rules without entry to be rejected")
+		}
+	})
+
+	t.Run("evaluation", func(t *testing.T) {
+
+		c := &compiler{}
+
+		crossover, err := c.compile("crossover(close, sma(2)) and not (close[1] >= 3 || -position > 0)")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		arithmetic, err := c.compile("max(abs(close - high), 1) * 2 + 1 == 3 && sma(2)[1] < sma(2)")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sma := c.outputs[0].create()
+		series, _ := c.outputs[0].series(sma)
+		st := &state{
+			candles: newCandles(10),
+			series:  make([]history, len(c.outputs)),
+			account: func(name string) float64 { return 0 },
+		}
+		for i := range st.series {
+			st.series[i] = series // the sma-2 of the conditions
+		}
+
+		candle := func(close float64) {
+			k := &gotrader.Candle{Close: close, High: close}
+			sma.OnCandle(k)
+			st.candles.push(k)
+		}
+
+		expected := []bool{false, false, false, true, false}
+		for i, close := range []float64{2, 2, 1, 3, 4} {
+			candle(close)
+			if met := st.met(crossover); met != expected[i] {
+				t.Errorf("candle %d: expected the crossover %t, got %t", i, expected[i], met)
+			}
+		}
+
+		if !st.met(arithmetic) {
+			t.Error("expected the arithmetic condition to be met")
+		}
+
+		if c.outputs[0].key != "sma-2" {
+			t.Errorf("expected the key sma-2, got %s", c.outputs[0].key)
+		}
+	})
+}
+
+func TestStrategy(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := gotradertest.NewBroker(instruments, gotradertest.Balance(10000), gotradertest.Currency("EUR"),
+		gotradertest.Leverage(30))
+	broker.Quote("EUR_USD", 1.0999, 1.1001)
+
+	strategy, err := Compile(Config{
+		Timeframe:  time.Minute,
+		Units:      1000,
+		EntryLong:  "close > sma(3) and close > close[1]",
+		ExitLong:   "close < sma(3)",
+		EntryShort: "close < entry", // no trades, entry is 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := runner.New(nil)
+	if err := r.Add("rules", strategy, runner.Instruments("EUR_USD"), runner.Candles(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	h := gotradertest.New(t, r, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.HomeCurrency("EUR"))
+
+	// a candle of the mid price per minute, closed by the tick of the next one
+	candle := func(mid float64) {
+		h.Advance(time.Minute)
+		h.Tick("EUR_USD", mid-0.0001, mid+0.0001)
+		h.Settle()
+	}
+
+	for _, mid := range []float64{1.10, 1.10, 1.10, 1.10} {
+		candle(mid)
+	}
+	h.AssertOpenTrades("EUR_USD", 0)
+
+	candle(1.12)
+	candle(1.12) // closes the candle of 1.12
+	h.AssertUnits("EUR_USD", gotrader.Long, 1000)
+
+	candle(1.08)
+	candle(1.08)
+	h.AssertUnits("EUR_USD", gotrader.Long, 0)
+	h.AssertOpenTrades("EUR_USD", 0)
+
+	if strategy.Err() != nil {
+		t.Error(strategy.Err())
+	}
+
+	unregistered, _ := Compile(Config{Timeframe: time.Hour, Units: 1000, EntryLong: "close > 0"})
+	if err := r.Add("unregistered", unregistered, runner.Instruments("EUR_USD")); err != nil {
+		t.Fatal(err)
+	}
+	if unregistered.Err() == nil {
+		t.Error("expected the strategy without the candles of its timeframe to fail")
+	}
+}
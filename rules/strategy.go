@@ -0,0 +1,323 @@
+/*
+Package rules configures simple trading systems instead of coding them: the entry and exit conditions of a
+Strategy are expressions of the indicators, the prices and the strategy account, compiled when it is created:
+
+	strategy, err := rules.Compile(rules.Config{
+		Timeframe: time.Hour,
+		Units:     10000,
+		EntryLong: "crossover(ema(10), ema(30)) and rsi(14) < 70",
+		ExitLong:  "crossunder(ema(10), ema(30)) or close < entry - 2 * atr(14)",
+	})
+	...
+	r.Add("trend", strategy, runner.Instruments("EUR_USD"), runner.Candles(time.Hour))
+
+The expressions are made of:
+
+	numbers          1.5, -2
+	prices           open, high, low, close of the candle
+	indicators       sma(n), ema(n), wma(n), dema(n), rsi(n), atr(n), volatility(n),
+	                 macd(fast, slow, signal), macd_signal(...), macd_hist(...),
+	                 bb_upper(n, deviations), bb_middle(...), bb_lower(...), stoch(n, smoothing, d), stoch_d(...)
+	account          position (net units), trades (open trades), entry (average open price), profit (unrealized),
+	                 of the strategy trades of the instrument, and equity, balance, drawdown of the strategy
+	previous values  x[n], the value of x n candles ago, e.g. close[1] or rsi(14)[2]
+	functions        crossover(a, b), crossunder(a, b), abs(x), min(a, b), max(a, b)
+	operators        + - * / < <= > >= == != and (&&) or (||) not (!) and parentheses
+
+The account values are the current ones, whatever the candle. The indicators are attached to the candles of the
+timeframe of each instrument, shared with the other strategies of the runner by key, e.g. "ema-10". A condition
+referencing a value not computed yet, before the warm up of an indicator, is false.
+*/
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/indicator"
+	"github.com/luismcruz/gotrader/runner"
+)
+
+// Config is the configuration of a rules Strategy, the empty conditions are never met.
+type Config struct {
+	Timeframe  time.Duration `yaml:"timeframe"` // of the candles the conditions are evaluated on
+	Units      int32         `yaml:"units"`     // of the entries
+	EntryLong  string        `yaml:"entryLong"`
+	ExitLong   string        `yaml:"exitLong"`
+	EntryShort string        `yaml:"entryShort"`
+	ExitShort  string        `yaml:"exitShort"`
+}
+
+/*
+Strategy is a runner strategy trading the conditions of its Config on the close of the candles of its timeframe,
+which must be registered with runner.Candles. It opens a position of Units when flat and the entry condition of a
+side is met, not the one of the other side, and closes the position when the exit condition of its side is met.
+No conditions are evaluated while its orders of the instrument are pending, and an exit and an entry are never
+taken on the same candle.
+*/
+type Strategy struct {
+	runner.BaseStrategy
+	config     Config
+	entryLong  condition
+	exitLong   condition
+	entryShort condition
+	exitShort  condition
+	outputs    []output
+	mutex      *sync.Mutex
+	ctx        *runner.Context
+	states     map[string]*state
+	pending    map[string]bool
+	err        error
+}
+
+// Compile compiles the conditions of the configuration into a Strategy.
+func Compile(config Config) (*Strategy, error) {
+
+	if config.Timeframe <= 0 || config.Units <= 0 {
+		return nil, errors.New("rules without timeframe or units")
+	}
+
+	if config.EntryLong == "" && config.EntryShort == "" {
+		return nil, errors.New("rules without entry")
+	}
+
+	s := &Strategy{
+		config:  config,
+		mutex:   &sync.Mutex{},
+		states:  make(map[string]*state),
+		pending: make(map[string]bool),
+	}
+
+	c := &compiler{}
+
+	for _, rule := range []struct {
+		name   string
+		source string
+		cond   *condition
+	}{
+		{"entryLong", config.EntryLong, &s.entryLong},
+		{"exitLong", config.ExitLong, &s.exitLong},
+		{"entryShort", config.EntryShort, &s.entryShort},
+		{"exitShort", config.ExitShort, &s.exitShort},
+	} {
+		if rule.source == "" {
+			continue
+		}
+
+		cond, err := c.compile(rule.source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rule.name, err)
+		}
+
+		*rule.cond = cond
+	}
+
+	s.outputs = c.outputs
+
+	return s, nil
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (s *Strategy) fail(err error) {
+	s.err = err
+	s.ctx.Logger.Error(err)
+}
+
+// account returns the value of the strategy account on the instrument.
+func (s *Strategy) account(instrument, name string) float64 {
+
+	sub := s.ctx.SubAccount
+
+	switch name {
+	case "position":
+		return float64(sub.NetUnits(instrument))
+	case "equity":
+		return sub.Equity()
+	case "balance":
+		return sub.Balance()
+	case "drawdown":
+		return sub.Drawdown()
+	}
+
+	var trades, units, cost, profit float64
+
+	for _, t := range sub.OpenTrades() {
+		if t.InstrumentName() == instrument {
+			trades++
+			units += float64(t.Units())
+			cost += float64(t.Units()) * t.OpenPrice()
+			profit += t.UnrealizedNetProfit()
+		}
+	}
+
+	switch name {
+	case "trades":
+		return trades
+	case "profit":
+		return profit
+	default: // entry
+		if units == 0 {
+			return 0
+		}
+		return cost / units
+	}
+}
+
+// met evaluates a condition on the last candle, false if it references values not computed yet.
+func (st *state) met(cond condition) bool {
+
+	if cond == nil {
+		return false
+	}
+
+	st.missing = false
+	met := cond(st, 0)
+
+	return met && !st.missing
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// OnStart implements runner.Strategy, attaching the indicators of the conditions.
+func (s *Strategy) OnStart(ctx *runner.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.ctx = ctx
+
+	registered := false
+	for _, tf := range ctx.Timeframes {
+		registered = registered || tf == s.config.Timeframe
+	}
+
+	if !registered {
+		s.fail(fmt.Errorf("strategy %s: the candles of %s are not registered", ctx.Name, s.config.Timeframe))
+		return
+	}
+
+	for _, instrument := range ctx.Instruments {
+
+		instrument := instrument
+		st := &state{
+			instrument: instrument,
+			candles:    newCandles(max(indicator.HistorySize, 2)),
+			series:     make([]history, len(s.outputs)),
+			account:    func(name string) float64 { return s.account(instrument, name) },
+		}
+
+		for i, out := range s.outputs {
+
+			ind, err := ctx.SharedIndicator(instrument, s.config.Timeframe, out.key, out.create)
+			if err != nil {
+				s.fail(fmt.Errorf("strategy %s: %s: %w", ctx.Name, out.key, err))
+				return
+			}
+
+			series, ok := out.series(ind)
+			if !ok {
+				s.fail(fmt.Errorf("strategy %s: indicator %s shared with another type", ctx.Name, out.key))
+				return
+			}
+
+			st.series[i] = series
+		}
+
+		s.states[instrument] = st
+	}
+}
+
+// OnCandle implements runner.Strategy, evaluating the conditions on the candles of the timeframe.
+func (s *Strategy) OnCandle(candle *gotrader.Candle) {
+
+	s.mutex.Lock()
+
+	st, exist := s.states[candle.Instrument]
+	if s.err != nil || !exist || candle.Timeframe != s.config.Timeframe {
+		s.mutex.Unlock()
+		return
+	}
+
+	st.candles.push(candle)
+
+	if s.pending[candle.Instrument] {
+		s.mutex.Unlock()
+		return
+	}
+
+	net := s.ctx.SubAccount.NetUnits(candle.Instrument)
+
+	var order func() error
+	instrument := candle.Instrument
+
+	switch {
+	case net > 0 && st.met(s.exitLong), net < 0 && st.met(s.exitShort):
+		order = func() error {
+			for _, t := range s.ctx.SubAccount.OpenTrades() {
+				if t.InstrumentName() == instrument {
+					if err := s.ctx.Engine.CloseTrade(instrument, t.ID()); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+
+	case net == 0:
+		long, short := st.met(s.entryLong), st.met(s.entryShort)
+		if long && !short {
+			order = func() error { return s.ctx.Engine.Buy(instrument, s.config.Units) }
+		} else if short && !long {
+			order = func() error { return s.ctx.Engine.Sell(instrument, s.config.Units) }
+		}
+	}
+
+	if order == nil {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.pending[instrument] = true
+	s.mutex.Unlock()
+
+	// the backtests fill the orders synchronously, the lock is not held
+	if err := order(); err != nil {
+		s.ctx.Logger.Warnf("strategy %s: %s: %v", s.ctx.Name, instrument, err)
+
+		s.mutex.Lock()
+		s.pending[instrument] = false
+		s.mutex.Unlock()
+	}
+}
+
+// OnOrderFilled implements runner.Strategy.
+func (s *Strategy) OnOrderFilled(orderFill *gotrader.OrderFill) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.pending[orderFill.Instrument.Name] = false
+}
+
+// OnTradeClosed implements runner.Strategy.
+func (s *Strategy) OnTradeClosed(orderFill *gotrader.OrderFill) {
+	s.OnOrderFilled(orderFill)
+}
+
+// Err returns the error that stopped the strategy when it started, e.g. the candles of its timeframe not registered.
+func (s *Strategy) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.err
+}
@@ -0,0 +1,235 @@
+package runner
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Allocate is the functional option to define the equity allocated to the strategy sub-account,
+// zero means the strategy is only bounded by the real account margin.
+func Allocate(equity float64) Option {
+	return func(s *slot) {
+		s.sub.allocated = equity
+		s.sub.peak = equity
+	}
+}
+
+// Limits is the functional option to define the risk limits of the strategy.
+func Limits(limits RiskLimits) Option {
+	return func(s *slot) {
+		s.limits = limits
+	}
+}
+
+// RiskLimits are the per strategy limits checked before a trade is opened, zero values are not checked.
+type RiskLimits struct {
+	MaxUnits      int32   // maximum absolute net units per instrument
+	MaxOpenTrades int     // maximum number of open trades
+	MaxDrawdown   float64 // maximum drawdown of the sub-account equity from its peak, as a fraction
+}
+
+/*
+SubAccount is the virtual account of a strategy. It tracks the trades opened by the strategy and its
+realized profit, while the trades themselves net into the real account positions.
+*/
+type SubAccount struct {
+	mutex     *sync.RWMutex
+	name      string
+	allocated float64
+	realized  float64
+	peak      float64
+	trades    map[string]*gotrader.Trade
+}
+
+func newSubAccount(name string) *SubAccount {
+	return &SubAccount{
+		mutex:  &sync.RWMutex{},
+		name:   name,
+		trades: make(map[string]*gotrader.Trade),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (a *SubAccount) openTrade(trade *gotrader.Trade) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.trades[trade.ID()] = trade
+}
+
+func (a *SubAccount) closeTrade(id string, profit float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.trades, id)
+	a.realized += profit
+}
+
+func (a *SubAccount) owns(id string) bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	_, exist := a.trades[id]
+
+	return exist
+}
+
+// mark updates the equity peak used by the drawdown.
+func (a *SubAccount) mark() {
+
+	equity := a.Equity()
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if equity > a.peak {
+		a.peak = equity
+	}
+}
+
+// check returns an error if opening the trade breaks the risk limits.
+func (a *SubAccount) check(limits RiskLimits, instrument string, side gotrader.Side, units int32) error {
+
+	if limits.MaxOpenTrades > 0 && len(a.OpenTrades()) >= limits.MaxOpenTrades {
+		return errors.New("STRATEGY_MAX_OPEN_TRADES")
+	}
+
+	if limits.MaxUnits > 0 {
+		net := a.NetUnits(instrument)
+		if side == gotrader.Short {
+			net -= units
+		} else {
+			net += units
+		}
+
+		if net > limits.MaxUnits || net < -limits.MaxUnits {
+			return errors.New("STRATEGY_MAX_UNITS")
+		}
+	}
+
+	if limits.MaxDrawdown > 0 && a.Drawdown() >= limits.MaxDrawdown {
+		return errors.New("STRATEGY_MAX_DRAWDOWN")
+	}
+
+	if a.allocated > 0 && a.Equity() <= a.MarginUsed() {
+		return errors.New("STRATEGY_NOT_ENOUGH_EQUITY")
+	}
+
+	return nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Name returns the strategy name.
+func (a *SubAccount) Name() string {
+	return a.name
+}
+
+// Allocated returns the allocated equity.
+func (a *SubAccount) Allocated() float64 {
+	return a.allocated
+}
+
+// RealizedProfit returns the profit of the closed trades of the strategy.
+func (a *SubAccount) RealizedProfit() float64 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.realized
+}
+
+// UnrealizedProfit returns the unrealized effective profit of the open trades of the strategy.
+func (a *SubAccount) UnrealizedProfit() float64 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	profit := 0.0
+	for _, t := range a.trades {
+		profit += t.UnrealizedEffectiveProfit()
+	}
+
+	return profit
+}
+
+// Balance returns the allocated equity plus the realized profit.
+func (a *SubAccount) Balance() float64 {
+	return a.allocated + a.RealizedProfit()
+}
+
+// Equity returns the balance plus the unrealized profit.
+func (a *SubAccount) Equity() float64 {
+	return a.Balance() + a.UnrealizedProfit()
+}
+
+// MarginUsed returns the margin used by the open trades of the strategy.
+func (a *SubAccount) MarginUsed() float64 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	margin := 0.0
+	for _, t := range a.trades {
+		margin += t.MarginUsed()
+	}
+
+	return margin
+}
+
+// Drawdown returns the current drawdown from the equity peak, as a fraction.
+func (a *SubAccount) Drawdown() float64 {
+
+	equity := a.Equity()
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.peak <= 0 || equity >= a.peak {
+		return 0
+	}
+
+	return (a.peak - equity) / a.peak
+}
+
+// NetUnits returns the net units (short trades count as negative) of the strategy on the instrument.
+func (a *SubAccount) NetUnits(instrument string) int32 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var net int32
+	for _, t := range a.trades {
+		if t.InstrumentName() != instrument {
+			continue
+		}
+
+		if t.Side() == gotrader.Short {
+			net -= t.Units()
+		} else {
+			net += t.Units()
+		}
+	}
+
+	return net
+}
+
+// OpenTrades returns the open trades of the strategy.
+func (a *SubAccount) OpenTrades() []*gotrader.Trade {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	trades := make([]*gotrader.Trade, 0, len(a.trades))
+	for _, t := range a.trades {
+		trades = append(trades, t)
+	}
+
+	return trades
+}
@@ -0,0 +1,272 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// request is a market order sent by a strategy, kept until its fill is attributed.
+type request struct {
+	strategy   string
+	instrument string
+	side       gotrader.Side
+	units      int32
+}
+
+// attribution maps fills to the strategies. Orders are tagged with the strategy name, and for clients that
+// do not carry tags the fill is matched against the oldest request with the same instrument, side and units.
+type attribution struct {
+	mutex    *sync.Mutex
+	requests []request
+	trades   map[string]string // trade ID -> strategy
+	orders   map[string]string // pending order ID -> strategy
+}
+
+func newAttribution() *attribution {
+	return &attribution{
+		mutex:  &sync.Mutex{},
+		trades: make(map[string]string),
+		orders: make(map[string]string),
+	}
+}
+
+func (a *attribution) request(r request) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.requests = append(a.requests, r)
+}
+
+// match removes and returns the strategy of the oldest request matching the fill, restricted to the
+// tagged strategy when the fill has a tag.
+func (a *attribution) match(fill *gotrader.OrderFill) string {
+
+	for i, r := range a.requests {
+
+		if fill.Tag != "" && r.strategy != fill.Tag {
+			continue
+		}
+
+		if r.instrument == fill.Instrument.Name && r.side == fill.Side && (fill.Units == 0 || r.units == fill.Units) {
+			a.requests = append(a.requests[:i], a.requests[i+1:]...)
+			return r.strategy
+		}
+	}
+
+	return fill.Tag
+}
+
+// owner returns the strategy of a fill, empty when it can't be attributed.
+func (a *attribution) owner(fill *gotrader.OrderFill) string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if fill.TradeClose {
+
+		owner := a.trades[fill.TradeID]
+		if fill.Error == "" {
+			delete(a.trades, fill.TradeID)
+		}
+
+		return owner
+	}
+
+	if owner, exist := a.orders[fill.OrderID]; exist {
+		delete(a.orders, fill.OrderID)
+		if fill.Error == "" {
+			a.trades[fill.TradeID] = owner
+		}
+		return owner
+	}
+
+	owner := a.match(fill)
+
+	if owner != "" && fill.Error == "" {
+		a.trades[fill.TradeID] = owner
+	}
+
+	return owner
+}
+
+/*
+strategyEngine is the engine given to each strategy, it tags the orders with the strategy name, checks the
+strategy risk limits before opening trades, and only allows closing the trades owned by the strategy.
+Changing the hedge type is left to the session owner.
+*/
+type strategyEngine struct {
+	gotrader.Engine
+	runner *Runner
+	name   string
+	slot   *slot
+}
+
+func (e *strategyEngine) open(instrument string, units int32, side gotrader.Side) error {
+
+	_, err := e.SubmitOrder(&gotrader.Order{
+		Type:       gotrader.MarketOrder,
+		Instrument: instrument,
+		Side:       side,
+		Units:      units,
+	})
+
+	return err
+}
+
+func (e *strategyEngine) Buy(instrument string, units int32) error {
+	return e.open(instrument, units, gotrader.Long)
+}
+
+func (e *strategyEngine) Sell(instrument string, units int32) error {
+	return e.open(instrument, units, gotrader.Short)
+}
+
+func (e *strategyEngine) CloseTrade(instrument string, id string) error {
+
+	if !e.slot.sub.owns(id) {
+		return fmt.Errorf("trade %s is not owned by %s: %w", id, e.name, gotrader.ErrTradeNotFound)
+	}
+
+	return e.Engine.CloseTrade(instrument, id)
+}
+
+func (e *strategyEngine) SubmitOrder(order *gotrader.Order) (string, error) {
+
+	if err := e.slot.sub.check(e.slot.limits, order.Instrument, order.Side, order.Units); err != nil {
+		return "", err
+	}
+
+	tagged := *order
+	tagged.Tag = e.name
+
+	if tagged.Type == gotrader.MarketOrder { // the fill may arrive before SubmitOrder returns
+		e.runner.attribution.request(request{
+			strategy:   e.name,
+			instrument: tagged.Instrument,
+			side:       tagged.Side,
+			units:      tagged.Units,
+		})
+	}
+
+	id, err := e.Engine.SubmitOrder(&tagged)
+
+	a := e.runner.attribution
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if err != nil {
+		if tagged.Type == gotrader.MarketOrder {
+			a.match(&gotrader.OrderFill{
+				Instrument: gotrader.InstrumentDetails{Name: tagged.Instrument},
+				Side:       tagged.Side,
+				Units:      tagged.Units,
+				Tag:        e.name,
+			})
+		}
+		return "", err
+	}
+
+	if tagged.Type != gotrader.MarketOrder {
+		a.orders[id] = e.name
+	}
+
+	return id, nil
+}
+
+// SubmitBasket checks the limits of the strategy for every order of the basket before submitting it.
+func (e *strategyEngine) SubmitBasket(orders []*gotrader.Order) ([]string, error) {
+
+	basket := make([]*gotrader.Order, len(orders))
+
+	for i, order := range orders {
+
+		if err := e.slot.sub.check(e.slot.limits, order.Instrument, order.Side, order.Units); err != nil {
+			return nil, fmt.Errorf("basket order %d: %w", i, err)
+		}
+
+		tagged := *order
+		tagged.Tag = e.name
+		basket[i] = &tagged
+	}
+
+	for _, order := range basket {
+		e.runner.attribution.request(request{
+			strategy:   e.name,
+			instrument: order.Instrument,
+			side:       order.Side,
+			units:      order.Units,
+		})
+	}
+
+	ids, err := e.Engine.SubmitBasket(basket)
+	if err != nil {
+		a := e.runner.attribution
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+
+		for _, order := range basket { // the requests of the orders not filled
+			a.match(&gotrader.OrderFill{
+				Instrument: gotrader.InstrumentDetails{Name: order.Instrument},
+				Side:       order.Side,
+				Units:      order.Units,
+				Tag:        e.name,
+			})
+		}
+	}
+
+	return ids, err
+}
+
+func (e *strategyEngine) ModifyOrder(id string, order *gotrader.Order) error {
+
+	if !e.ownsOrder(id) {
+		return errors.New("order " + id + " is not owned by " + e.name)
+	}
+
+	tagged := *order
+	tagged.Tag = e.name
+
+	return e.Engine.ModifyOrder(id, &tagged)
+}
+
+func (e *strategyEngine) CancelOrder(id string) error {
+
+	if !e.ownsOrder(id) {
+		return errors.New("order " + id + " is not owned by " + e.name)
+	}
+
+	return e.Engine.CancelOrder(id)
+}
+
+// PendingOrders returns the pending orders owned by the strategy.
+func (e *strategyEngine) PendingOrders(instrument string) []*gotrader.Order {
+
+	owned := make([]*gotrader.Order, 0)
+	for _, order := range e.Engine.PendingOrders(instrument) {
+		if e.ownsOrder(order.ID) {
+			owned = append(owned, order)
+		}
+	}
+
+	return owned
+}
+
+// SetHedge is not allowed, netting the positions would close the trades of the other strategies.
+func (e *strategyEngine) SetHedge(instrument string, hedge gotrader.Hedge) error {
+	return errors.New("the hedge type can't be changed by " + e.name + ", the instruments are shared")
+}
+
+// SetStatus is not allowed, the instruments are shared by the strategies.
+func (e *strategyEngine) SetStatus(instrument string, status gotrader.InstrumentStatus) error {
+	return errors.New("the instrument status can't be changed by " + e.name + ", the instruments are shared")
+}
+
+func (e *strategyEngine) ownsOrder(id string) bool {
+	a := e.runner.attribution
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.orders[id] == e.name
+}
@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"plugin"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// PluginStrategySymbol is the constructor a strategy plugin must export: func NewStrategy() runner.Strategy
+	PluginStrategySymbol = "NewStrategy"
+
+	// PluginOptionsSymbol is the optional registration options a plugin may export: func Options() []runner.Option
+	PluginOptionsSymbol = "Options"
+)
+
+// LoadPlugin opens a strategy compiled with -buildmode=plugin, returning the strategy and its options.
+// The plugin must be built with the same gotrader version as the host.
+func LoadPlugin(path string) (Strategy, []Option, error) {
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	symbol, err := p.Lookup(PluginStrategySymbol)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	constructor, ok := symbol.(func() Strategy)
+	if !ok {
+		return nil, nil, errors.New(path + ": " + PluginStrategySymbol + " must be a func() runner.Strategy")
+	}
+
+	var opts []Option
+
+	if symbol, err := p.Lookup(PluginOptionsSymbol); err == nil {
+		options, ok := symbol.(func() []Option)
+		if !ok {
+			return nil, nil, errors.New(path + ": " + PluginOptionsSymbol + " must be a func() []runner.Option")
+		}
+		opts = options()
+	}
+
+	return constructor(), opts, nil
+}
+
+// AddPlugin loads a strategy plugin and registers it, the given options are applied after the plugin ones.
+func (r *Runner) AddPlugin(name, path string, opts ...Option) error {
+
+	strategy, pluginOpts, err := LoadPlugin(path)
+	if err != nil {
+		return err
+	}
+
+	return r.Add(name, strategy, append(pluginOpts, opts...)...)
+}
+
+/*
+PluginWatcher keeps the runner strategies in sync with the plugins of a directory, so a long running session
+can add and remove strategies without restarting. Each *.so file is registered under its base name, and
+removing the file stops the strategy. Go plugins can't be unloaded, so a changed plugin must be deployed
+under a new file name to be reloaded.
+*/
+type PluginWatcher struct {
+	runner   *Runner
+	dir      string
+	interval time.Duration
+	opts     []Option
+	loaded   map[string]bool
+	failed   map[string]bool
+	mutex    *sync.Mutex
+	done     chan struct{}
+}
+
+// NewPluginWatcher is the PluginWatcher constructor, opts are applied to every loaded plugin.
+// The interval defaults to 10 seconds.
+func NewPluginWatcher(runner *Runner, dir string, interval time.Duration, opts ...Option) *PluginWatcher {
+
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	return &PluginWatcher{
+		runner:   runner,
+		dir:      dir,
+		interval: interval,
+		opts:     opts,
+		loaded:   make(map[string]bool),
+		failed:   make(map[string]bool),
+		mutex:    &sync.Mutex{},
+		done:     make(chan struct{}),
+	}
+}
+
+// Sync loads the new plugins of the directory and removes the strategies whose plugin was deleted.
+func (w *PluginWatcher) Sync() error {
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	files, err := filepath.Glob(filepath.Join(w.dir, "*.so"))
+	if err != nil {
+		return err
+	}
+
+	present := make(map[string]bool, len(files))
+
+	for _, file := range files {
+
+		name := strings.TrimSuffix(filepath.Base(file), ".so")
+		present[name] = true
+
+		if w.loaded[name] || w.failed[name] {
+			continue
+		}
+
+		if err := w.runner.AddPlugin(name, file, w.opts...); err != nil {
+			w.failed[name] = true // retried only if the file is removed and deployed again
+			w.runner.logger.Errorf("plugin %s: %v", file, err)
+			continue
+		}
+
+		w.loaded[name] = true
+		w.runner.logger.Infof("plugin strategy %s loaded", name)
+	}
+
+	for name := range w.loaded {
+		if !present[name] {
+			delete(w.loaded, name)
+			if err := w.runner.Remove(name); err == nil {
+				w.runner.logger.Infof("plugin strategy %s removed", name)
+			}
+		}
+	}
+
+	for name := range w.failed {
+		if !present[name] {
+			delete(w.failed, name)
+		}
+	}
+
+	return nil
+}
+
+// Start syncs the directory periodically until Stop is called.
+func (w *PluginWatcher) Start() error {
+
+	if _, err := os.Stat(w.dir); err != nil {
+		return err
+	}
+
+	if err := w.Sync(); err != nil {
+		return err
+	}
+
+	go func() {
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+				if err := w.Sync(); err != nil {
+					w.runner.logger.Error(err)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops the periodic sync, the loaded strategies keep running.
+func (w *PluginWatcher) Stop() {
+	close(w.done)
+}
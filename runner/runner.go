@@ -0,0 +1,477 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/indicator"
+)
+
+// Option represents a strategy registration functional option
+type Option func(s *slot)
+
+// Instruments is the functional option to define the instruments delivered to the strategy.
+func Instruments(instruments ...string) Option {
+	return func(s *slot) {
+		s.ctx.Instruments = append(s.ctx.Instruments, instruments...)
+	}
+}
+
+// Candles is the functional option to build candles of the given timeframes for the strategy instruments.
+func Candles(timeframes ...time.Duration) Option {
+	return func(s *slot) {
+		s.ctx.Timeframes = append(s.ctx.Timeframes, timeframes...)
+	}
+}
+
+type slot struct {
+	strategy    Strategy
+	ctx         *Context
+	sub         *SubAccount
+	limits      RiskLimits
+	instruments map[string]bool
+	candles     map[string][]*gotrader.CandleBuilder
+	started     bool
+	err         error
+}
+
+// indicators are the indicators of the candles of an instrument timeframe, shared by the strategies.
+type indicators struct {
+	candles  *gotrader.CandleBuilder
+	pipeline *indicator.Pipeline
+}
+
+func (s *slot) wants(instrument string) bool {
+	return s.instruments[instrument]
+}
+
+/*
+Runner hosts several strategies on one trading session. It implements gotrader.Strategy, so it is set as
+the session strategy, and dispatches the engine callbacks to the strategies registered for each instrument.
+Each strategy trades through its own sub-account, fills of its orders are only delivered to it, and a
+strategy that panics is stopped and its error is kept, without affecting the others.
+*/
+type Runner struct {
+	mutex       *sync.RWMutex
+	engine      gotrader.Engine
+	logger      gotrader.Logger
+	strategies  map[string]*slot
+	order       []string
+	attribution *attribution
+	scheduler   *scheduler
+	indicators  map[string][]*indicators
+	history     []*gotrader.Candle // warm up candles, until the strategies are started
+	running     bool
+}
+
+// New is the Runner constructor, a nil logger defaults to gotrader.DefaultLogger.
+func New(logger gotrader.Logger) *Runner {
+
+	if logger == nil {
+		logger = gotrader.DefaultLogger()
+	}
+
+	return &Runner{
+		mutex:       &sync.RWMutex{},
+		logger:      logger,
+		strategies:  make(map[string]*slot),
+		attribution: newAttribution(),
+		scheduler:   newScheduler(),
+		indicators:  make(map[string][]*indicators),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// call runs a strategy callback, recovering from panics. Returns false if the strategy has failed.
+func (r *Runner) call(name string, s *slot, callback func()) (ok bool) {
+
+	if s.err != nil {
+		return false
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.err = fmt.Errorf("strategy %s panicked: %v", name, rec)
+			r.logger.Errorf("%v\n%s", s.err, debug.Stack())
+			ok = false
+		}
+	}()
+
+	callback()
+
+	return true
+}
+
+func (r *Runner) start(name string, s *slot) {
+
+	s.ctx.Engine = &strategyEngine{Engine: r.engine, runner: r, name: name, slot: s}
+	s.started = r.call(name, s, func() { s.strategy.OnStart(s.ctx) })
+}
+
+func (r *Runner) stop(name string, s *slot) {
+
+	if s.started {
+		r.call(name, s, s.strategy.OnStop)
+		s.started = false
+	}
+}
+
+// each calls fn for every started strategy registered for the instrument, in registration order.
+// Strategies are called without holding the lock, since orders may be filled synchronously (backtests).
+func (r *Runner) each(instrument string, fn func(name string, s *slot)) {
+
+	names, slots := r.snapshot()
+
+	for i, s := range slots {
+		if s.started && s.wants(instrument) {
+			fn(names[i], s)
+		}
+	}
+}
+
+// advance moves the scheduler clock and runs the due jobs of the started strategies.
+func (r *Runner) advance(now time.Time) {
+
+	jobs, times := r.scheduler.advance(now)
+
+	for i, job := range jobs {
+
+		r.mutex.RLock()
+		s, exist := r.strategies[job.strategy]
+		r.mutex.RUnlock()
+
+		if exist && s.started {
+			t := times[i]
+			r.call(job.strategy, s, func() { job.callback(t) })
+		}
+	}
+}
+
+// pipeline returns the indicators pipeline of the instrument timeframe, created if it doesn't exist.
+func (r *Runner) pipeline(instrument string, timeframe time.Duration) *indicator.Pipeline {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, i := range r.indicators[instrument] {
+		if i.candles.Timeframe() == timeframe {
+			return i.pipeline
+		}
+	}
+
+	i := &indicators{candles: gotrader.NewCandleBuilder(instrument, timeframe), pipeline: indicator.NewPipeline()}
+	r.indicators[instrument] = append(r.indicators[instrument], i)
+
+	return i.pipeline
+}
+
+// computeIndicators computes the indicators with the tick and the candles it closes.
+func (r *Runner) computeIndicators(tick *gotrader.Tick) {
+
+	r.mutex.RLock()
+	streams := r.indicators[tick.Instrument]
+	r.mutex.RUnlock()
+
+	for _, i := range streams {
+		if candle := i.candles.Update(tick); candle != nil {
+			i.pipeline.OnCandle(candle)
+		}
+		i.pipeline.OnTick(tick)
+	}
+}
+
+// warmUp computes the indicators attached to the candles timeframes with the historical candles.
+func (r *Runner) warmUp(candles []*gotrader.Candle) {
+
+	for _, candle := range candles {
+
+		r.mutex.RLock()
+		streams := r.indicators[candle.Instrument]
+		r.mutex.RUnlock()
+
+		for _, i := range streams {
+			if i.candles.Timeframe() == candle.Timeframe {
+				i.pipeline.OnCandle(candle)
+			}
+		}
+	}
+}
+
+func (r *Runner) snapshot() ([]string, []*slot) {
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	slots := make([]*slot, len(r.order))
+	for i, name := range r.order {
+		slots[i] = r.strategies[name]
+	}
+
+	return append([]string(nil), r.order...), slots
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Add registers a strategy under a unique name, it is started right away if the runner is already running.
+func (r *Runner) Add(name string, strategy Strategy, opts ...Option) error {
+
+	s := &slot{
+		strategy:    strategy,
+		ctx:         &Context{Name: name, Logger: r.logger, runner: r},
+		sub:         newSubAccount(name),
+		instruments: make(map[string]bool),
+		candles:     make(map[string][]*gotrader.CandleBuilder),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if len(s.ctx.Instruments) == 0 {
+		return errors.New("strategy " + name + " has no instruments")
+	}
+
+	s.ctx.SubAccount = s.sub
+
+	for _, inst := range s.ctx.Instruments {
+		s.instruments[inst] = true
+		for _, tf := range s.ctx.Timeframes {
+			s.candles[inst] = append(s.candles[inst], gotrader.NewCandleBuilder(inst, tf))
+		}
+	}
+
+	r.mutex.Lock()
+
+	if _, exist := r.strategies[name]; exist {
+		r.mutex.Unlock()
+		return errors.New("strategy " + name + " already exists")
+	}
+
+	r.strategies[name] = s
+	r.order = append(r.order, name)
+	running := r.running
+
+	r.mutex.Unlock()
+
+	if running {
+		r.start(name, s)
+	}
+
+	return nil
+}
+
+// Remove stops and unregisters a strategy, its open trades are kept.
+func (r *Runner) Remove(name string) error {
+
+	r.mutex.Lock()
+
+	s, exist := r.strategies[name]
+	if !exist {
+		r.mutex.Unlock()
+		return errors.New("strategy " + name + " does not exist")
+	}
+
+	delete(r.strategies, name)
+
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+
+	r.mutex.Unlock()
+
+	r.scheduler.removeStrategy(name)
+	r.stop(name, s)
+
+	return nil
+}
+
+// Strategies returns the names of the registered strategies.
+func (r *Runner) Strategies() []string {
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return append([]string(nil), r.order...)
+}
+
+// Err returns the panic error of a failed strategy, nil if it is healthy.
+func (r *Runner) Err(name string) error {
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if s, exist := r.strategies[name]; exist {
+		return s.err
+	}
+
+	return nil
+}
+
+// WallClock advances the scheduler with the wall clock at the given interval, so scheduled callbacks run
+// on live sessions even when no ticks are received. It must not be used on backtests, that follow the
+// simulated time of the ticks. The returned function stops the clock.
+func (r *Runner) WallClock(interval time.Duration) (stop func()) {
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				r.advance(now)
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
+
+// WarmUp computes the indicators with historical candles, e.g. backfilled with store.Backfill, sorted by time and
+// older than the first tick of the session. The candles given before the session starts are computed once the
+// strategies have attached their indicators on start, the ones given later by the indicators already attached.
+func (r *Runner) WarmUp(candles []*gotrader.Candle) {
+
+	r.mutex.Lock()
+
+	if !r.running {
+		r.history = append(r.history, candles...)
+		r.mutex.Unlock()
+		return
+	}
+
+	r.mutex.Unlock()
+
+	r.warmUp(candles)
+}
+
+// Initialize implements gotrader.Strategy, starting the registered strategies.
+func (r *Runner) Initialize() {
+
+	r.mutex.Lock()
+	r.running = true
+	history := r.history
+	r.history = nil
+	r.mutex.Unlock()
+
+	names, slots := r.snapshot()
+
+	for i, s := range slots {
+		r.start(names[i], s)
+	}
+
+	r.warmUp(history)
+}
+
+// SetEngine implements gotrader.Strategy.
+func (r *Runner) SetEngine(engine gotrader.Engine) {
+	r.engine = engine
+}
+
+// OnTick implements gotrader.Strategy, candles are delivered before the tick that closes them, after computing
+// the indicators attached to them.
+func (r *Runner) OnTick(tick *gotrader.Tick) {
+
+	if tick == nil {
+		return
+	}
+
+	r.advance(tick.Time)
+	r.computeIndicators(tick)
+
+	r.each(tick.Instrument, func(name string, s *slot) {
+
+		s.sub.mark()
+
+		for _, builder := range s.candles[tick.Instrument] {
+			if candle := builder.Update(tick); candle != nil {
+				if !r.call(name, s, func() { s.strategy.OnCandle(candle) }) {
+					return
+				}
+			}
+		}
+
+		r.call(name, s, func() { s.strategy.OnTick(tick) })
+	})
+}
+
+// OnOrderFill implements gotrader.Strategy. Fills are delivered to the strategy that sent the order,
+// the ones that can't be attributed (e.g. trades opened outside the runner) go to every strategy of the instrument.
+func (r *Runner) OnOrderFill(orderFill *gotrader.OrderFill) {
+
+	owner := r.attribution.owner(orderFill)
+
+	deliver := func(name string, s *slot) {
+
+		if orderFill.TradeClose && orderFill.Error == "" {
+			if s.sub.owns(orderFill.TradeID) {
+				s.sub.closeTrade(orderFill.TradeID, orderFill.Profit+orderFill.ChargedFees)
+			}
+			r.call(name, s, func() { s.strategy.OnTradeClosed(orderFill) })
+			return
+		}
+
+		if !orderFill.TradeClose && orderFill.Error == "" && name == owner && r.engine != nil {
+			if inst := r.engine.Account().Instrument(orderFill.Instrument.Name); inst != nil {
+				if trade := inst.Trade(orderFill.TradeID); trade != nil {
+					s.sub.openTrade(trade)
+				}
+			}
+		}
+
+		r.call(name, s, func() { s.strategy.OnOrderFilled(orderFill) })
+	}
+
+	r.mutex.RLock()
+	s, exist := r.strategies[owner]
+	r.mutex.RUnlock()
+
+	if exist {
+		if s.started {
+			deliver(owner, s)
+		}
+		return
+	}
+
+	r.each(orderFill.Instrument.Name, deliver)
+}
+
+// OnStop implements gotrader.Strategy, stopping every strategy.
+func (r *Runner) OnStop() {
+
+	r.mutex.Lock()
+	running := r.running
+	r.running = false
+	r.mutex.Unlock()
+
+	if !running {
+		return
+	}
+
+	names, slots := r.snapshot()
+
+	for i, s := range slots {
+		r.stop(names[i], s)
+	}
+}
@@ -0,0 +1,149 @@
+package runner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Schedule returns the trigger times of a scheduled callback.
+type Schedule interface {
+	Next(t time.Time) time.Time // first trigger time strictly after t
+}
+
+// ScheduleFunc adapts a function to the Schedule interface.
+type ScheduleFunc func(t time.Time) time.Time
+
+// Next implements Schedule.
+func (f ScheduleFunc) Next(t time.Time) time.Time {
+	return f(t)
+}
+
+// Every triggers at multiples of the interval since the zero time, e.g. Every(time.Hour) triggers every
+// hour on the hour.
+func Every(interval time.Duration) Schedule {
+	return ScheduleFunc(func(t time.Time) time.Time {
+		return t.Truncate(interval).Add(interval)
+	})
+}
+
+// Daily triggers every day at the given time of the day in the location.
+func Daily(hour, minute int, loc *time.Location) Schedule {
+	return ScheduleFunc(func(t time.Time) time.Time {
+
+		local := t.In(loc)
+		next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+
+		if !next.After(t) {
+			next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+		}
+
+		return next
+	})
+}
+
+// BeforeClose triggers the offset before every session close of the calendar.
+func BeforeClose(calendar gotrader.SessionCalendar, offset time.Duration) Schedule {
+	return ScheduleFunc(func(t time.Time) time.Time {
+		return calendar.NextClose(t.Add(offset)).Add(-offset)
+	})
+}
+
+// AfterOpen triggers the offset after every session open of the calendar.
+func AfterOpen(calendar gotrader.SessionCalendar, offset time.Duration) Schedule {
+	return ScheduleFunc(func(t time.Time) time.Time {
+		return calendar.NextOpen(t.Add(-offset)).Add(offset)
+	})
+}
+
+// Job is a callback scheduled by a strategy.
+type Job struct {
+	strategy string
+	schedule Schedule
+	callback func(t time.Time)
+	next     time.Time
+}
+
+/*
+scheduler triggers the jobs as time advances. In backtests time is advanced by the ticks, so schedules follow
+the simulated clock, while live sessions can also advance it with the wall clock (see Runner.WallClock).
+Missed triggers are collapsed into a single call.
+*/
+type scheduler struct {
+	mutex *sync.Mutex
+	jobs  []*Job
+	now   time.Time
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{mutex: &sync.Mutex{}}
+}
+
+func (s *scheduler) add(job *Job) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.now.IsZero() {
+		job.next = job.schedule.Next(s.now)
+	}
+
+	s.jobs = append(s.jobs, job)
+}
+
+func (s *scheduler) remove(job *Job) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, j := range s.jobs {
+		if j == job {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *scheduler) removeStrategy(strategy string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobs := s.jobs[:0]
+	for _, j := range s.jobs {
+		if j.strategy != strategy {
+			jobs = append(jobs, j)
+		}
+	}
+
+	s.jobs = jobs
+}
+
+// advance moves the clock forward and returns the due jobs with their trigger times.
+func (s *scheduler) advance(now time.Time) ([]*Job, []time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !now.After(s.now) {
+		return nil, nil
+	}
+
+	s.now = now
+
+	due := make([]*Job, 0)
+	times := make([]time.Time, 0)
+
+	for _, j := range s.jobs {
+
+		if j.next.IsZero() { // first time seen, schedule from now
+			j.next = j.schedule.Next(now)
+			continue
+		}
+
+		if !j.next.After(now) {
+			due = append(due, j)
+			times = append(times, j.next)
+			j.next = j.schedule.Next(now)
+		}
+	}
+
+	return due, times
+}
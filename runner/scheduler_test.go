@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+func TestScheduler(t *testing.T) {
+
+	start := time.Date(2020, 1, 6, 9, 0, 0, 0, time.UTC) // monday
+
+	t.Run("Every hour on the hour", func(t *testing.T) {
+
+		s := newScheduler()
+		s.add(&Job{schedule: Every(time.Hour)})
+
+		s.advance(start.Add(10 * time.Minute))
+
+		if jobs, _ := s.advance(start.Add(50 * time.Minute)); len(jobs) != 0 {
+			t.Errorf("job triggered before the hour")
+		}
+
+		jobs, times := s.advance(start.Add(3 * time.Hour)) // missed triggers are collapsed
+		if len(jobs) != 1 || !times[0].Equal(start.Add(time.Hour)) {
+			t.Errorf("expected a single trigger at 10:00, got %v", times)
+		}
+	})
+
+	t.Run("Before session close", func(t *testing.T) {
+
+		session := gotrader.DailySession{
+			Open:     9*time.Hour + 30*time.Minute,
+			Close:    16 * time.Hour,
+			Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		}
+
+		schedule := BeforeClose(session, 5*time.Minute)
+
+		if next := schedule.Next(start); !next.Equal(time.Date(2020, 1, 6, 15, 55, 0, 0, time.UTC)) {
+			t.Errorf("unexpected trigger %v", next)
+		}
+
+		friday := time.Date(2020, 1, 10, 15, 55, 0, 0, time.UTC)
+		if next := schedule.Next(friday); !next.Equal(time.Date(2020, 1, 13, 15, 55, 0, 0, time.UTC)) {
+			t.Errorf("expected monday trigger after friday close, got %v", next)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/indicator"
+)
+
+// Strategy is the interface of the strategies hosted by a Runner. Callbacks are only delivered for the
+// instruments the strategy was registered with.
+type Strategy interface {
+	OnStart(ctx *Context)
+	OnTick(tick *gotrader.Tick)
+	OnCandle(candle *gotrader.Candle)
+	OnOrderFilled(orderFill *gotrader.OrderFill) // also receives the order errors
+	OnTradeClosed(orderFill *gotrader.OrderFill)
+	OnStop()
+}
+
+// BaseStrategy implements every Strategy callback as a no-op, it can be embedded so a strategy only
+// implements the callbacks it needs.
+type BaseStrategy struct{}
+
+// OnStart implements Strategy.
+func (BaseStrategy) OnStart(ctx *Context) {}
+
+// OnTick implements Strategy.
+func (BaseStrategy) OnTick(tick *gotrader.Tick) {}
+
+// OnCandle implements Strategy.
+func (BaseStrategy) OnCandle(candle *gotrader.Candle) {}
+
+// OnOrderFilled implements Strategy.
+func (BaseStrategy) OnOrderFilled(orderFill *gotrader.OrderFill) {}
+
+// OnTradeClosed implements Strategy.
+func (BaseStrategy) OnTradeClosed(orderFill *gotrader.OrderFill) {}
+
+// OnStop implements Strategy.
+func (BaseStrategy) OnStop() {}
+
+// Context is given to a strategy when it starts, with the engine to trade and the registration details.
+type Context struct {
+	Name        string
+	Instruments []string
+	Timeframes  []time.Duration
+	Engine      gotrader.Engine // tags the orders and checks the strategy risk limits
+	SubAccount  *SubAccount
+	Logger      gotrader.Logger
+	runner      *Runner
+}
+
+// Schedule registers a callback triggered by the schedule, with the trigger time. The callback runs on
+// the session clock: tick time in backtests, so timing logic is testable, and also wall clock on live
+// sessions when the runner WallClock is started.
+func (c *Context) Schedule(schedule Schedule, callback func(t time.Time)) *Job {
+
+	job := &Job{strategy: c.Name, schedule: schedule, callback: callback}
+	c.runner.scheduler.add(job)
+
+	return job
+}
+
+// Unschedule removes a scheduled callback.
+func (c *Context) Unschedule(job *Job) {
+	c.runner.scheduler.remove(job)
+}
+
+// Attach computes the indicators, and their dependencies, on the candles of the instrument timeframe before
+// they are delivered to the strategies. The indicators are shared by the strategies of the runner, so an
+// indicator attached by several strategies is computed once per candle.
+func (c *Context) Attach(instrument string, timeframe time.Duration, indicators ...indicator.Indicator) error {
+	return c.runner.pipeline(instrument, timeframe).Add(indicators...)
+}
+
+// SharedIndicator returns the indicator of the instrument timeframe attached with the key, attaching the one
+// created when there is none, so the strategies needing the same indicator compute it once:
+//
+//	ema, err := ctx.SharedIndicator("EUR_USD", time.Minute, "ema-20", func() indicator.Indicator {
+//		return indicator.NewEMA(20)
+//	})
+func (c *Context) SharedIndicator(instrument string, timeframe time.Duration, key string,
+	create func() indicator.Indicator) (indicator.Indicator, error) {
+	return c.runner.pipeline(instrument, timeframe).Shared(key, create)
+}
+
+// Account returns the trading account.
+func (c *Context) Account() *gotrader.Account {
+	return c.Engine.Account()
+}
+
+// Instrument returns an account instrument.
+func (c *Context) Instrument(name string) *gotrader.Instrument {
+	return c.Engine.Account().Instrument(name)
+}
@@ -2,8 +2,9 @@ package gotrader
 
 import (
 	"errors"
+	"time"
 
-	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Option represents trading session functional option
@@ -84,6 +85,163 @@ func SetLogger(logger Logger) Option {
 	}
 }
 
+// OnDiscrepancy is the functional option to receive the discrepancies found when the live engine
+// resynchronizes with the broker after a reconnection.
+func OnDiscrepancy(handler DiscrepancyHandler) Option {
+	return func(p *sessionParameters) {
+		p.discrepancyHandler = handler
+	}
+}
+
+// MarginCallLevel is the functional option to define the margin level (equity / margin used) under which
+// a MarginCall event is published, defaults to 1.
+func MarginCallLevel(level float64) Option {
+	return func(p *sessionParameters) {
+		p.marginCallLevel = level
+	}
+}
+
+// StaleAfter is the functional option to publish a PriceStale event when an instrument has not been updated
+// for the given duration, staleness is not checked by default.
+func StaleAfter(d time.Duration) Option {
+	return func(p *sessionParameters) {
+		p.staleAfter = d
+	}
+}
+
+// Restore is the functional option to resume from a snapshot of the account. Live sessions keep the open
+// trades and balance of the broker, restoring the ledger and the trades book-keeping (stop loss, take profit,
+// tags), while backtests restore the balance and reopen the snapshot trades.
+func Restore(snapshot *Snapshot) Option {
+	return func(p *sessionParameters) {
+		p.snapshot = snapshot
+	}
+}
+
+// WriteAheadLog is the functional option to log the account mutations before they are applied. On start,
+// the entries logged after the Restore snapshot (every entry without one) are replayed, as the snapshot is.
+func WriteAheadLog(wal *WAL) Option {
+	return func(p *sessionParameters) {
+		p.wal = wal
+	}
+}
+
+// Audit is the functional option to record the state transitions of the orders and trades of the session in an
+// audit log.
+func Audit(log *AuditLog) Option {
+	return func(p *sessionParameters) {
+		p.audit = log
+		p.events.audit = log
+	}
+}
+
+// Tracer is the functional option to define the OpenTelemetry tracer of the live engine orders, defaults to
+// the TracerName tracer of the global provider. Backtests are not traced.
+func Tracer(tracer trace.Tracer) Option {
+	return func(p *sessionParameters) {
+		p.tracer = tracer
+	}
+}
+
+// RecalculationShards is the functional option to define the number of goroutines recalculating the instruments
+// on every tick, defaults to GOMAXPROCS. With 1 the instruments are recalculated by the engine goroutine.
+func RecalculationShards(shards int) Option {
+	return func(p *sessionParameters) {
+		p.recalculationShards = shards
+	}
+}
+
+// MarketHours is the functional option to reject the orders and the trade closes submitted while the calendar
+// is closed with ErrMarketClosed, the market is always open by default.
+func MarketHours(calendar SessionCalendar) Option {
+	return func(p *sessionParameters) {
+		p.marketHours = calendar
+	}
+}
+
+// InstrumentMarketHours is the functional option to define the calendar of an instrument, used instead of the
+// MarketHours calendar for its orders and trade closes, e.g. for the instruments of different venues.
+func InstrumentMarketHours(instrument string, calendar SessionCalendar) Option {
+	return func(p *sessionParameters) {
+		if p.instrumentHours == nil {
+			p.instrumentHours = make(map[string]SessionCalendar)
+		}
+		p.instrumentHours[instrument] = calendar
+	}
+}
+
+// InstrumentHedge is the functional option to define the hedge type of an instrument instead of the account
+// one (the broker one on live sessions, HedgeType on backtests), e.g. for brokers hedging FX but netting
+// indices. Live sessions fail to start when the broker client is a HedgeValidator not supporting it.
+func InstrumentHedge(instrument string, hedge Hedge) Option {
+	return func(p *sessionParameters) {
+		if p.instrumentHedges == nil {
+			p.instrumentHedges = make(map[string]Hedge)
+		}
+		p.instrumentHedges[instrument] = hedge
+	}
+}
+
+// Markup is the functional option to mark up the quotes of every traded instrument, see PriceMarkup.
+func Markup(markup PriceMarkup) Option {
+	return func(p *sessionParameters) {
+		p.markup = &markup
+	}
+}
+
+// Markups is the functional option to mark up the quotes with a model queried on every tick, e.g. a fee schedule
+// updated at runtime, used instead of the Markup and InstrumentMarkup ones for the instruments it marks up.
+func Markups(model MarkupModel) Option {
+	return func(p *sessionParameters) {
+		p.markups = model
+	}
+}
+
+// InstrumentMarkup is the functional option to define the markup of an instrument, used instead of the Markup one.
+func InstrumentMarkup(instrument string, markup PriceMarkup) Option {
+	return func(p *sessionParameters) {
+		if p.instrumentMarkups == nil {
+			p.instrumentMarkups = make(map[string]PriceMarkup)
+		}
+		p.instrumentMarkups[instrument] = markup
+	}
+}
+
+// NegativeBalanceProtection is the functional option to write the balance back to zero in the backtest engine
+// when the closes of the trades, e.g. stops gapping through their levels, take it negative, as brokers protecting
+// retail accounts do. The adjustment is recorded in the ledger as a BalanceAdjustmentTransaction.
+func NegativeBalanceProtection() Option {
+	return func(p *sessionParameters) {
+		p.negativeBalanceProtection = true
+	}
+}
+
+// SetClock is the functional option to define the clock of the session, e.g. a SimulatedClock to control the
+// time of a live session in tests. It defaults to the WallClock on live sessions, backtests set their clock to
+// the time of every tick, so only a SimulatedClock is used by them.
+func SetClock(clock Clock) Option {
+	return func(p *sessionParameters) {
+		p.clock = clock
+	}
+}
+
+// CollectStats is the functional option to collect the internal statistics of the tick pipeline, returned by
+// TradingSession.Stats. They are not collected by default, keeping the tick path free of their overhead.
+func CollectStats() Option {
+	return func(p *sessionParameters) {
+		p.stats = &pipelineStats{}
+	}
+}
+
+// TrackEquity is the functional option to sample the account equity on the ticks into its EquityCurve, with a
+// point per resolution interval (every tick when it is not positive).
+func TrackEquity(resolution time.Duration) Option {
+	return func(p *sessionParameters) {
+		p.trackEquity = true
+		p.equityResolution = resolution
+	}
+}
+
 type testParameters struct {
 	initialBalance float64
 	homeCurrency   string
@@ -92,10 +250,79 @@ type testParameters struct {
 }
 
 type sessionParameters struct {
-	instruments    []string
-	account        string
-	testParameters *testParameters
-	logger         Logger
+	instruments               []string
+	account                   string
+	testParameters            *testParameters
+	logger                    Logger
+	discrepancyHandler        DiscrepancyHandler
+	marginCallLevel           float64
+	staleAfter                time.Duration
+	tickOrder                 *tickOrder
+	feedLatency               *feedLatency
+	gaps                      *gapDetector
+	queueFills                bool
+	events                    *EventBus
+	snapshot                  *Snapshot
+	wal                       *WAL
+	audit                     *AuditLog
+	retry                     RetryPolicy
+	health                    *HealthPolicy
+	sweep                     *SweepPolicy
+	feeds                     *feedRoutes
+	consolidation             *consolidation
+	synthetics                *synthetics
+	compliance                *ComplianceRules
+	tracer                    trace.Tracer
+	latency                   []LatencyObserver
+	recalculationShards       int
+	marketHours               SessionCalendar
+	instrumentHours           map[string]SessionCalendar
+	flatten                   *FlattenPolicy
+	instrumentFlatten         map[string]FlattenPolicy
+	news                      *NewsPolicy
+	spread                    SpreadModel
+	instrumentSpreads         map[string]SpreadModel
+	instrumentHedges          map[string]Hedge
+	markup                    *PriceMarkup
+	guaranteedStopPremium     CommissionModel
+	negativeBalanceProtection bool
+	marginWindows             []MarginWindow
+	dividends                 DividendCalendar
+	managedFees               *FeePolicy
+	financing                 FinancingModel
+	rollover                  SessionCalendar
+	instrumentMarkups         map[string]PriceMarkup
+	markups                   MarkupModel
+	specPolicy                SpecPolicy
+	stopDistance              *float64 // pips, replacing the broker ones
+	instrumentStopDistances   map[string]float64
+	clock                     Clock
+	sharedClock               *SharedClock
+	stats                     *pipelineStats
+	trackEquity               bool
+	equityResolution          time.Duration
+	dayOffset                 time.Duration
+	dayLocation               *time.Location
+}
+
+// equityCurve returns a new equity curve of the session, nil when the equity is not tracked.
+func (p *sessionParameters) equityCurve() *EquityCurve {
+
+	if !p.trackEquity {
+		return nil
+	}
+
+	return newEquityCurve(p.equityResolution)
+}
+
+// calendar returns the market hours of an instrument, nil when the market is always open.
+func (p *sessionParameters) calendar(instrument string) SessionCalendar {
+
+	if calendar, exist := p.instrumentHours[instrument]; exist {
+		return calendar
+	}
+
+	return p.marketHours
 }
 
 // TradingSession represents the entrypoint struct of the gotrader package, representing a trading session.
@@ -110,7 +337,10 @@ type TradingSession struct {
 // NewTradingSession is the TradingSession constructor.
 func NewTradingSession(opts ...Option) *TradingSession {
 
-	params := &sessionParameters{}
+	params := &sessionParameters{
+		marginCallLevel: 1,
+		events:          newEventBus(),
+	}
 
 	for _, o := range opts {
 		o(params)
@@ -139,7 +369,10 @@ func (s *TradingSession) SetClient(client BrokerClient) *TradingSession {
 func (s *TradingSession) Live() *TradingSession {
 
 	if s.parameters.logger == nil {
-		s.parameters.logger = logrus.New()
+		s.parameters.logger = DefaultLogger()
+	}
+	if s.parameters.clock == nil {
+		s.parameters.clock = WallClock()
 	}
 	s.engine = newLiveEngine(s.parameters.logger)
 
@@ -150,7 +383,10 @@ func (s *TradingSession) Live() *TradingSession {
 func (s *TradingSession) Backtest() *TradingSession {
 
 	if s.parameters.logger == nil {
-		s.parameters.logger = logrus.New()
+		s.parameters.logger = DefaultLogger()
+	}
+	if _, simulated := s.parameters.clock.(*SimulatedClock); !simulated {
+		s.parameters.clock = NewSimulatedClock(time.Time{})
 	}
 	s.engine = newBtEngine(s.parameters.logger)
 	s.engineType = 1
@@ -158,6 +394,64 @@ func (s *TradingSession) Backtest() *TradingSession {
 	return s
 }
 
+// Account returns the account of the session, available after the engine has started.
+// On a backtest session it can be used to inspect the final state once Start returns.
+func (s *TradingSession) Account() *Account {
+
+	if s.engine == nil {
+		return nil
+	}
+
+	return s.engine.Account()
+}
+
+// Engine returns the engine of the session, its account is available after the engine has started.
+// It can be used to drive the session from outside the strategy, e.g. by an API server.
+func (s *TradingSession) Engine() Engine {
+	return s.engine
+}
+
+// Clock returns the clock of the session, available once the session is defined as live or backtest.
+func (s *TradingSession) Clock() Clock {
+	return s.parameters.clock
+}
+
+// ObserveLatency adds observers of the tick pipeline latencies, it must be called before the session starts.
+func (s *TradingSession) ObserveLatency(observers ...LatencyObserver) *TradingSession {
+	s.parameters.latency = append(s.parameters.latency, observers...)
+
+	return s
+}
+
+// Events returns the event bus of the session, subscriptions can be made before the session starts.
+func (s *TradingSession) Events() *EventBus {
+	return s.parameters.events
+}
+
+// Stats returns the internal statistics of the tick pipeline, zero without the CollectStats option. It is safe
+// to call from any goroutine while the session runs.
+func (s *TradingSession) Stats() Stats {
+	return s.parameters.stats.snapshot()
+}
+
+// FeedLatency returns the delay statistics of the ticks by instrument, empty without the MeasureFeedLatency
+// option or on backtests. It is safe to call from any goroutine while the session runs.
+func (s *TradingSession) FeedLatency() map[string]FeedLatency {
+	return s.parameters.feedLatency.snapshot()
+}
+
+// ActiveFeeds returns the active price source of the instruments with a failover, empty without the Failover
+// option or on backtests. It is safe to call from any goroutine while the session runs.
+func (s *TradingSession) ActiveFeeds() map[string]FeedSource {
+	return s.parameters.feeds.snapshot()
+}
+
+// ConsolidatedQuotes returns the best bid and ask of the instruments across the feeds, empty without the Consolidate
+// option or on backtests. It is safe to call from any goroutine while the session runs.
+func (s *TradingSession) ConsolidatedQuotes() map[string]ConsolidatedQuote {
+	return s.parameters.consolidation.snapshot()
+}
+
 // Start trading session.
 func (s *TradingSession) Start() error {
 
@@ -0,0 +1,130 @@
+package gotrader
+
+import (
+	"sync"
+	"time"
+)
+
+// SyncClock is the functional option to synchronize a backtest on a SharedClock with the other backtests run in
+// parallel, ignored by the live sessions.
+func SyncClock(clock *SharedClock) Option {
+	return func(p *sessionParameters) {
+		p.sharedClock = clock
+	}
+}
+
+/*
+SharedClock is the virtual clock of backtests run in parallel, e.g. the shards of an instrument universe split
+between sessions: a session waits before a tick more than a step ahead of the slowest session, so the times of the
+sessions are never further apart than the step, and what they share (an event bus, a risk monitor, the progress of the
+run) sees a consistent time. A session joins the clock when its ticks start and leaves it when they end, so the
+sessions can be started by a pool of workers smaller than their number, a late one holds the others until it
+catches up.
+*/
+type SharedClock struct {
+	step    time.Duration
+	mutex   sync.Mutex
+	changed *sync.Cond
+	times   map[*clockMember]time.Time // of the tick of each session, zero before the first one
+}
+
+// clockMember is a session synchronized on a SharedClock.
+type clockMember struct {
+	clock *SharedClock
+}
+
+// NewSharedClock is the SharedClock constructor, the sessions run in lockstep with a zero step.
+func NewSharedClock(step time.Duration) *SharedClock {
+
+	c := &SharedClock{step: step, times: make(map[*clockMember]time.Time)}
+	c.changed = sync.NewCond(&c.mutex)
+
+	return c
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// join adds a session to the clock, nil without clock.
+func (c *SharedClock) join() *clockMember {
+
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	member := &clockMember{clock: c}
+	c.times[member] = time.Time{}
+
+	return member
+}
+
+// slowest returns the time of the slowest session, with the lock held.
+func (c *SharedClock) slowest() time.Time {
+
+	var slowest time.Time
+	first := true
+
+	for _, t := range c.times {
+		if first || t.Before(slowest) {
+			slowest, first = t, false
+		}
+	}
+
+	return slowest
+}
+
+// wait moves the session to the time of its next tick, once it is at most a step ahead of the slowest session.
+func (m *clockMember) wait(t time.Time) {
+
+	if m == nil {
+		return
+	}
+
+	c := m.clock
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.times[m] = t
+	c.changed.Broadcast()
+
+	for t.Sub(c.slowest()) > c.step {
+		c.changed.Wait()
+	}
+}
+
+// leave removes the session from the clock, the others don't wait for it anymore.
+func (m *clockMember) leave() {
+
+	if m == nil {
+		return
+	}
+
+	c := m.clock
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.times, m)
+	c.changed.Broadcast()
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Now returns the time of the slowest session, zero without sessions or before their first tick.
+func (c *SharedClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.slowest()
+}
@@ -0,0 +1,211 @@
+package signals
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/notify"
+	"github.com/luismcruz/gotrader/rebalance"
+)
+
+// maxBody is the size limit of the signals posted to a Consumer.
+const maxBody = 1 << 20
+
+/*
+ConsumerConfig sets the signals a Consumer trades: the ones of the instruments with a maximum position, from the
+sources accepted. The differences to the positions of fewer units than MinUnits are left, and the signed requests
+of an HTTP publisher are verified when the Secret is set.
+*/
+type ConsumerConfig struct {
+	MaxUnits map[string]int32 // net units of a signal of strength 1, by instrument
+	MinUnits int32
+	Sources  []string // every source when empty
+	Secret   string
+}
+
+/*
+Consumer is a strategy trading the signals it imports, set as the session strategy: the net position of each
+instrument is kept at the one called for by its last signal, on the ticks of the instrument. An expired signal
+calls for a flat position. The signals are received with Receive, from a channel with Consume, a reader with
+Import or HTTP requests, the Consumer being their http.Handler.
+*/
+type Consumer struct {
+	config  ConsumerConfig
+	engine  gotrader.Engine
+	targets *rebalance.Targets
+	mutex   *sync.Mutex
+	signals map[string]Signal
+	logger  gotrader.Logger
+}
+
+// NewConsumer is the Consumer constructor, a nil logger defaults to gotrader.DefaultLogger.
+func NewConsumer(config ConsumerConfig, logger gotrader.Logger) *Consumer {
+
+	if logger == nil {
+		logger = gotrader.DefaultLogger()
+	}
+
+	return &Consumer{
+		config:  config,
+		mutex:   &sync.Mutex{},
+		signals: make(map[string]Signal),
+		logger:  logger,
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (c *Consumer) accepts(source string) bool {
+
+	if len(c.config.Sources) == 0 {
+		return true
+	}
+
+	for _, s := range c.config.Sources {
+		if s == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Receive imports a signal, replacing the one of its instrument unless it is older. The signals of the sources
+// not accepted or of the instruments without maximum position return an error.
+func (c *Consumer) Receive(s Signal) error {
+
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	if !c.accepts(s.Source) {
+		return errors.New("signal source " + s.Source + " is not accepted")
+	}
+
+	if _, exist := c.config.MaxUnits[s.Instrument]; !exist {
+		return errors.New("signal instrument " + s.Instrument + " is not traded")
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if last, exist := c.signals[s.Instrument]; exist && s.Time.Before(last.Time) {
+		return nil
+	}
+
+	c.signals[s.Instrument] = s
+
+	return nil
+}
+
+// Signal returns the last signal of the instrument, false without signal.
+func (c *Consumer) Signal(instrument string) (Signal, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	s, exist := c.signals[instrument]
+	return s, exist
+}
+
+// Consume receives the signals of the channel until it is closed, the signals rejected are logged.
+func (c *Consumer) Consume(signals <-chan Signal) {
+	for s := range signals {
+		if err := c.Receive(s); err != nil {
+			c.logger.Warn(err)
+		}
+	}
+}
+
+// Import receives the signals of r, see Read, until the first invalid or rejected one.
+func (c *Consumer) Import(r io.Reader) error {
+	return Read(r, c.Receive)
+}
+
+// ServeHTTP implements http.Handler, receiving the signals posted, one or several JSON objects.
+func (c *Consumer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "signals must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if c.config.Secret != "" {
+		signature := []byte(r.Header.Get(notify.SignatureHeader))
+		if !hmac.Equal(signature, []byte(notify.Sign(c.config.Secret, body))) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if err := c.Import(bytes.NewReader(body)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Err returns the error of the last position adjustment of the instrument.
+func (c *Consumer) Err(instrument string) error {
+
+	if c.targets == nil {
+		return nil
+	}
+
+	return c.targets.Err(instrument)
+}
+
+// Initialize implements gotrader.Strategy.
+func (c *Consumer) Initialize() {}
+
+// SetEngine implements gotrader.Strategy.
+func (c *Consumer) SetEngine(engine gotrader.Engine) {
+	c.engine = engine
+	c.targets = rebalance.NewTargets(engine, c.config.MinUnits)
+}
+
+// OnTick implements gotrader.Strategy, adjusting the position of the instrument to its signal.
+func (c *Consumer) OnTick(tick *gotrader.Tick) {
+
+	if s, exist := c.Signal(tick.Instrument); exist {
+
+		units := int32(0)
+		if !s.Expired(tick.Time) {
+			units = s.Units(c.config.MaxUnits[tick.Instrument])
+		}
+
+		c.targets.Set(tick.Instrument, units)
+	}
+
+	c.targets.OnTick(tick)
+}
+
+// OnOrderFill implements gotrader.Strategy.
+func (c *Consumer) OnOrderFill(fill *gotrader.OrderFill) {
+	c.targets.OnOrderFill(fill)
+}
+
+// OnStop implements gotrader.Strategy.
+func (c *Consumer) OnStop() {}
@@ -0,0 +1,153 @@
+package signals
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/luismcruz/gotrader/notify"
+)
+
+// ErrFull is returned by a channel publisher when its channel is full.
+var ErrFull = errors.New("signals channel is full")
+
+// Publisher exports the signals of a strategy.
+type Publisher interface {
+	Publish(s Signal) error
+}
+
+// Publishers publishes the signals to several publishers, the errors of each one are joined.
+type Publishers []Publisher
+
+// Publish implements Publisher.
+func (p Publishers) Publish(s Signal) error {
+
+	errs := make([]error, 0)
+	for _, publisher := range p {
+		if err := publisher.Publish(s); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// FilePublisher appends the signals to a file, one JSON object per line.
+type FilePublisher struct {
+	mutex *sync.Mutex
+	file  *os.File
+}
+
+// NewFilePublisher opens the file at path to append the signals, creating it if needed.
+func NewFilePublisher(path string) (*FilePublisher, error) {
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FilePublisher{mutex: &sync.Mutex{}, file: file}, nil
+}
+
+// Publish implements Publisher.
+func (p *FilePublisher) Publish(s Signal) error {
+
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	_, err = p.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (p *FilePublisher) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.file.Close()
+}
+
+// HTTPPublisher posts the signals to an URL, e.g. a Consumer, signed with notify.Sign in the notify.SignatureHeader
+// when it has a secret.
+type HTTPPublisher struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewHTTPPublisher is the HTTPPublisher constructor, a nil client defaults to http.DefaultClient.
+func NewHTTPPublisher(url, secret string, client *http.Client) *HTTPPublisher {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &HTTPPublisher{url: url, secret: secret, client: client}
+}
+
+// Publish implements Publisher, the signal is posted synchronously.
+func (p *HTTPPublisher) Publish(s Signal) error {
+
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if p.secret != "" {
+		req.Header.Set(notify.SignatureHeader, notify.Sign(p.secret, body))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("signal endpoint responded " + resp.Status)
+	}
+
+	return nil
+}
+
+// ChannelPublisher sends the signals to a channel, e.g. the one of a Consumer on the same process, without
+// blocking the strategy: the signals are dropped with ErrFull when the channel is full.
+type ChannelPublisher chan<- Signal
+
+// Publish implements Publisher.
+func (p ChannelPublisher) Publish(s Signal) error {
+
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	select {
+	case p <- s:
+		return nil
+	default:
+		return ErrFull
+	}
+}
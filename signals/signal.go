@@ -0,0 +1,139 @@
+/*
+Package signals decouples the generation of trading signals from their execution: a strategy exports its signals
+with a Publisher, to a file, an HTTP endpoint or a channel, and a Consumer strategy, possibly on another process
+or account, trades the ones it imports. Signals are JSON objects, one per line in the files:
+
+	{"source": "trend", "instrument": "EUR_USD", "direction": "LONG", "strength": 0.5,
+	 "time": "2024-01-02T10:00:00Z", "expiry": "2024-01-02T14:00:00Z"}
+*/
+package signals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Direction is the position a signal calls for.
+type Direction int
+
+const (
+	Flat Direction = iota
+	Long
+	Short
+)
+
+var directionNames = [...]string{"FLAT", "LONG", "SHORT"}
+
+func (d Direction) String() string {
+
+	if d < Flat || d > Short {
+		return "UNKNOWN"
+	}
+
+	return directionNames[d]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Direction) MarshalText() ([]byte, error) {
+
+	if d < Flat || d > Short {
+		return nil, fmt.Errorf("unknown direction %d", int(d))
+	}
+
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, the names are case insensitive.
+func (d *Direction) UnmarshalText(text []byte) error {
+
+	for i, name := range directionNames {
+		if strings.EqualFold(name, string(text)) {
+			*d = Direction(i)
+			return nil
+		}
+	}
+
+	return errors.New("unknown direction " + string(text))
+}
+
+// Signal is a call for a position on an instrument, its strength the share of the maximum position of the
+// consumers, from 0 to 1.
+type Signal struct {
+	Source     string    `json:"source,omitempty"` // strategy or system emitting it
+	Instrument string    `json:"instrument"`
+	Direction  Direction `json:"direction"`
+	Strength   float64   `json:"strength"`
+	Time       time.Time `json:"time"`
+	Expiry     time.Time `json:"expiry,omitempty"` // never expires when zero
+}
+
+// Validate returns an error if the signal has no instrument or time, or a strength out of [0, 1].
+func (s Signal) Validate() error {
+
+	if s.Instrument == "" {
+		return errors.New("signal without instrument")
+	}
+
+	if s.Time.IsZero() {
+		return errors.New("signal of " + s.Instrument + " without time")
+	}
+
+	if s.Strength < 0 || s.Strength > 1 {
+		return fmt.Errorf("signal of %s with strength %g out of [0, 1]", s.Instrument, s.Strength)
+	}
+
+	if !s.Expiry.IsZero() && !s.Expiry.After(s.Time) {
+		return errors.New("signal of " + s.Instrument + " expiring before its time")
+	}
+
+	return nil
+}
+
+// Expired returns whether the signal has expired at t.
+func (s Signal) Expired(t time.Time) bool {
+	return !s.Expiry.IsZero() && !t.Before(s.Expiry)
+}
+
+// Units returns the net units the signal calls for given the maximum position, negative for the shorts.
+func (s Signal) Units(maxUnits int32) int32 {
+
+	units := int32(s.Strength * float64(maxUnits))
+
+	switch s.Direction {
+	case Long:
+		return units
+	case Short:
+		return -units
+	}
+
+	return 0
+}
+
+// Read decodes the signals of r, JSON objects one after the other, e.g. one per line, calling handler on each of
+// them until the end of r or the first invalid one.
+func Read(r io.Reader, handler func(s Signal) error) error {
+
+	decoder := json.NewDecoder(r)
+
+	for {
+
+		s := Signal{}
+		if err := decoder.Decode(&s); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := s.Validate(); err != nil {
+			return err
+		}
+
+		if err := handler(s); err != nil {
+			return err
+		}
+	}
+}
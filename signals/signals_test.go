@@ -0,0 +1,131 @@
+package signals
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/gotradertest"
+)
+
+func TestPublishers(t *testing.T) {
+
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	signal := Signal{Source: "trend", Instrument: "EUR_USD", Direction: Short, Strength: 0.5, Time: now,
+		Expiry: now.Add(time.Hour)}
+
+	t.Run("files round trip", func(t *testing.T) {
+
+		path := filepath.Join(t.TempDir(), "signals.jsonl")
+
+		p, err := NewFilePublisher(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := p.Publish(signal); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Publish(Signal{Instrument: "EUR_USD", Strength: 2, Time: now}); err == nil {
+			t.Error("expected the invalid signal to be rejected")
+		}
+		p.Close()
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		signals := make([]Signal, 0)
+		if err := Read(f, func(s Signal) error { signals = append(signals, s); return nil }); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(signals) != 1 || signals[0] != signal {
+			t.Errorf("expected %+v, got %+v", signal, signals)
+		}
+	})
+
+	t.Run("signed requests are verified", func(t *testing.T) {
+
+		c := NewConsumer(ConsumerConfig{MaxUnits: map[string]int32{"EUR_USD": 1000}, Secret: "secret"}, nil)
+		server := httptest.NewServer(c)
+		defer server.Close()
+
+		if err := NewHTTPPublisher(server.URL, "other", nil).Publish(signal); err == nil {
+			t.Error("expected the signal signed with another secret to be rejected")
+		}
+
+		if err := NewHTTPPublisher(server.URL, "secret", nil).Publish(signal); err != nil {
+			t.Fatal(err)
+		}
+
+		if s, exist := c.Signal("EUR_USD"); !exist || s != signal {
+			t.Errorf("expected the signal received, got %+v", s)
+		}
+	})
+
+	t.Run("full channels drop the signals", func(t *testing.T) {
+
+		ch := make(chan Signal, 1)
+		p := Publishers{ChannelPublisher(ch)}
+
+		if err := p.Publish(signal); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := p.Publish(signal); !errors.Is(err, ErrFull) {
+			t.Errorf("expected ErrFull, got %v", err)
+		}
+	})
+}
+
+func TestConsumer(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := gotradertest.NewBroker(instruments, gotradertest.Balance(10000), gotradertest.Currency("EUR"),
+		gotradertest.Leverage(30))
+	broker.Quote("EUR_USD", 1.0999, 1.1001)
+
+	c := NewConsumer(ConsumerConfig{MaxUnits: map[string]int32{"EUR_USD": 2000}, Sources: []string{"trend"}}, nil)
+	h := gotradertest.New(t, c, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.HomeCurrency("EUR"))
+
+	start := h.Account().Time()
+
+	if err := c.Receive(Signal{Source: "other", Instrument: "EUR_USD", Direction: Long, Strength: 1,
+		Time: start}); err == nil {
+		t.Error("expected the signal of a source not accepted to be rejected")
+	}
+
+	if err := c.Receive(Signal{Source: "trend", Instrument: "EUR_USD", Direction: Long, Strength: 0.5,
+		Time: start, Expiry: start.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Settle()
+	h.AssertUnits("EUR_USD", gotrader.Long, 1000)
+
+	c.Receive(Signal{Source: "trend", Instrument: "EUR_USD", Direction: Short, Strength: 0.25,
+		Time: start.Add(time.Second), Expiry: start.Add(time.Minute)})
+	c.Receive(Signal{Source: "trend", Instrument: "EUR_USD", Direction: Long, Strength: 1, Time: start})
+
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Settle()
+	h.AssertUnits("EUR_USD", gotrader.Long, 0)
+	h.AssertUnits("EUR_USD", gotrader.Short, 500)
+
+	h.Advance(time.Minute)
+	h.Tick("EUR_USD", 1.0999, 1.1001)
+	h.Settle()
+	h.AssertUnits("EUR_USD", gotrader.Short, 0)
+	h.AssertOpenTrades("EUR_USD", 0)
+}
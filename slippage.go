@@ -0,0 +1,239 @@
+package gotrader
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FillSlippage is the slippage of a live fill, its price against the price requested: the ask or the bid at the
+// request of the market orders and closes, the price of the pending orders. Slippage is signed, positive when the
+// fill is worse than requested, in price units and in Pips.
+type FillSlippage struct {
+	Time       time.Time
+	Instrument string
+	Venue      string // empty for the fills of a client without venues
+	OrderID    string
+	TradeID    string
+	Side       Side // of the execution, the opposite of the trade side for the closes
+	Close      bool
+	Requested  float64
+	Filled     float64
+	Slippage   float64
+	Pips       float64
+}
+
+// SlippageDistribution is the distribution of the slippages of a set of fills, in pips.
+type SlippageDistribution struct {
+	Count  int
+	Mean   float64
+	StdDev float64
+	Min    float64
+	Max    float64
+	pips   []float64 // sorted
+}
+
+// Quantile returns the q quantile (0 to 1) of the slippages, by nearest rank, 0 without fills.
+func (d SlippageDistribution) Quantile(q float64) float64 {
+
+	if len(d.pips) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(q*float64(len(d.pips)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+
+	return d.pips[rank]
+}
+
+/*
+Slippages records the slippage of the live fills of an account, to compare the execution quality of the instruments
+and of the venues of a Router. The requested prices are matched to the fills as the order spans, see orderTracer: by
+trade ID for the closes, by order ID for the pending orders and in request order by instrument and side for the
+market orders. The fills without request, e.g. the exits triggered by the broker, are not recorded.
+*/
+type Slippages struct {
+	mutex     *sync.Mutex
+	requested map[string][]float64 // by order key
+	fills     []FillSlippage
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newSlippages() *Slippages {
+	return &Slippages{
+		mutex:     &sync.Mutex{},
+		requested: make(map[string][]float64),
+	}
+}
+
+// opposite returns the side of the executions closing the trades of the side.
+func opposite(side Side) Side {
+	if side == Long {
+		return Short
+	}
+
+	return Long
+}
+
+// requestedPrice returns the price of a market execution of the side, the ask for the buys and the bid for the sells.
+func requestedPrice(inst *Instrument, side Side) float64 {
+	if side == Long {
+		return inst.Ask()
+	}
+
+	return inst.Bid()
+}
+
+// request records the price requested by the order of the key.
+func (s *Slippages) request(key string, price float64) {
+
+	if price == 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requested[key] = append(s.requested[key], price)
+}
+
+// drop forgets the first price requested with the key, e.g. the order was refused or cancelled.
+func (s *Slippages) drop(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.take(key)
+}
+
+// take returns the first price requested with the key, 0 when there is none, with the lock held.
+func (s *Slippages) take(key string) float64 {
+
+	prices := s.requested[key]
+	if len(prices) == 0 {
+		return 0
+	}
+
+	if len(prices) == 1 {
+		delete(s.requested, key)
+	} else {
+		s.requested[key] = prices[1:]
+	}
+
+	return prices[0]
+}
+
+// fill records the slippage of a fill against its requested price, the failed fills only consume their request.
+func (s *Slippages) fill(fill *OrderFill, pipLocation int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var requested float64
+
+	if fill.TradeClose || (fill.TradeID != "" && fill.OrderID == "") {
+		requested = s.take(closeKey(fill.TradeID))
+	}
+	if requested == 0 && fill.OrderID != "" {
+		requested = s.take(orderKey(fill.OrderID))
+	}
+	if requested == 0 {
+		requested = s.take(marketKey(fill.Instrument.Name, fill.Side))
+	}
+
+	if requested == 0 || fill.Error != "" {
+		return
+	}
+
+	side := fill.Side
+	if fill.TradeClose {
+		side = opposite(side)
+	}
+
+	slippage := (fill.Price - requested) * sideSign(side)
+
+	s.fills = append(s.fills, FillSlippage{
+		Time:       fill.Time,
+		Instrument: fill.Instrument.Name,
+		Venue:      fill.Venue,
+		OrderID:    fill.OrderID,
+		TradeID:    fill.TradeID,
+		Side:       side,
+		Close:      fill.TradeClose,
+		Requested:  requested,
+		Filled:     fill.Price,
+		Slippage:   slippage,
+		Pips:       slippage / math.Pow10(pipLocation),
+	})
+}
+
+// distributions returns the distributions of the slippages grouped by key.
+func (s *Slippages) distributions(key func(f *FillSlippage) string) map[string]SlippageDistribution {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pips := make(map[string][]float64)
+	for i := range s.fills {
+		k := key(&s.fills[i])
+		pips[k] = append(pips[k], s.fills[i].Pips)
+	}
+
+	distributions := make(map[string]SlippageDistribution, len(pips))
+	for k, p := range pips {
+		distributions[k] = newSlippageDistribution(p)
+	}
+
+	return distributions
+}
+
+func newSlippageDistribution(pips []float64) SlippageDistribution {
+
+	sort.Float64s(pips)
+
+	d := SlippageDistribution{Count: len(pips), Min: pips[0], Max: pips[len(pips)-1], pips: pips}
+
+	for _, p := range pips {
+		d.Mean += p
+	}
+	d.Mean /= float64(len(pips))
+
+	for _, p := range pips {
+		d.StdDev += (p - d.Mean) * (p - d.Mean)
+	}
+	d.StdDev = math.Sqrt(d.StdDev / float64(len(pips)))
+
+	return d
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Fills returns the slippages of the fills by fill order.
+func (s *Slippages) Fills() []FillSlippage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fills := make([]FillSlippage, len(s.fills))
+	copy(fills, s.fills)
+
+	return fills
+}
+
+// ByInstrument returns the distributions of the slippages by instrument.
+func (s *Slippages) ByInstrument() map[string]SlippageDistribution {
+	return s.distributions(func(f *FillSlippage) string { return f.Instrument })
+}
+
+// ByVenue returns the distributions of the slippages by venue.
+func (s *Slippages) ByVenue() map[string]SlippageDistribution {
+	return s.distributions(func(f *FillSlippage) string { return f.Venue })
+}
@@ -0,0 +1,306 @@
+package gotrader
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot layout written by this package.
+const SnapshotVersion = 1
+
+// Snapshot is the serializable state of an account: balance, instruments with their prices and conversion
+// rates, open trades (positions are rebuilt from them) and the ledger. It can be encoded with encoding/json
+// (see WriteSnapshot) or encoding/gob.
+type Snapshot struct {
+	Version        int
+	Time           time.Time
+	AccountID      string
+	HomeCurrency   string
+	Balance        float64
+	Leverage       float64
+	OpeningBalance float64
+	Instruments    []*InstrumentSnapshot
+	Transactions   []*Transaction
+	WALSequence    uint64       `json:",omitempty"` // of the last write-ahead log entry in the snapshot
+	Accruals       *FeeSnapshot `json:",omitempty"` // of the ManagedFees policy of the session
+}
+
+// InstrumentSnapshot is the state of an instrument in a Snapshot.
+type InstrumentSnapshot struct {
+	Name                string
+	BaseCurrency        string
+	QuoteCurrency       string
+	Leverage            float64
+	PipLocation         int
+	Hedge               Hedge
+	Bid                 float64
+	Ask                 float64
+	BaseConversionRate  float64
+	QuoteConversionRate float64
+	LastUpdate          time.Time
+	Trades              []*TradeSnapshot // by open time order
+}
+
+// FeeSnapshot is the state of the fee accruals of a managed account in a Snapshot and in the WALFee entries.
+type FeeSnapshot struct {
+	HighWaterMark   float64
+	ManagementFees  float64
+	PerformanceFees float64
+	LastAccrual     time.Time
+	NextAccrual     time.Time
+	Recorded        int // transactions of the ledger scanned for the flows moving the high-water mark
+}
+
+// TradeSnapshot is the state of an open trade in a Snapshot.
+type TradeSnapshot struct {
+	ID          string
+	Side        Side
+	Units       int32
+	OpenPrice   float64
+	OpenTime    time.Time
+	ChargedFees float64
+	Fees        Fees
+	StopLoss    float64
+	Guaranteed  bool
+	TakeProfit  float64
+	Expiry      time.Time
+	Venue       string
+	Tag         string
+	OpenSpread  float64
+	SpreadCost  float64 // of the open
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newTradeSnapshot(t *Trade) *TradeSnapshot {
+	return &TradeSnapshot{
+		ID:          t.id,
+		Side:        t.side,
+		Units:       t.units,
+		OpenPrice:   t.openPrice,
+		OpenTime:    t.openTime,
+		ChargedFees: t.chargedFees.Load().Float64(),
+		Fees:        t.fees.breakdown(),
+		StopLoss:    t.stopLoss,
+		Guaranteed:  t.guaranteedStop,
+		TakeProfit:  t.takeProfit,
+		Expiry:      t.expiry,
+		Venue:       t.venue,
+		Tag:         t.tag,
+		OpenSpread:  t.openSpread,
+		SpreadCost:  t.spreadCost.Float64(),
+	}
+}
+
+// restore copies the trade book-keeping that brokers don't keep to a hydrated trade.
+func (s *TradeSnapshot) restore(t *Trade) {
+	t.stopLoss = s.StopLoss
+	t.guaranteedStop = s.Guaranteed
+	t.takeProfit = s.TakeProfit
+	t.expiry = s.Expiry
+	t.openSpread = s.OpenSpread
+	t.spreadCost = NewDecimal(s.SpreadCost)
+
+	if t.tag == "" {
+		t.tag = s.Tag
+	}
+
+	if t.venue == "" {
+		t.venue = s.Venue
+	}
+}
+
+// restoreTrades opens the snapshot trades on the account instruments, or only restores the book-keeping of the
+// trades already open when hydrated is true (live sessions get the open trades from the broker).
+func (a *Account) restoreTrades(snapshot *Snapshot, hydrated bool) {
+
+	for _, is := range snapshot.Instruments {
+
+		inst, exist := a.instruments[is.Name]
+		if !exist {
+			continue
+		}
+
+		for _, ts := range is.Trades {
+
+			if hydrated {
+				if trade := inst.Trade(ts.ID); trade != nil {
+					ts.restore(trade)
+				}
+				continue
+			}
+
+			trade := inst.openTrade(ts.ID, ts.Side, ts.OpenTime, ts.Units, ts.OpenPrice)
+			trade.restoreFees(ts.ChargedFees, ts.Fees)
+			ts.restore(trade)
+		}
+	}
+}
+
+// restoreLedger replaces the ledger with the snapshot transactions, after the trades are restored.
+func (a *Account) restoreLedger(snapshot *Snapshot) {
+
+	a.ledger.Lock()
+	defer a.ledger.Unlock()
+
+	a.ledger.openingBalance = snapshot.OpeningBalance
+	a.ledger.transactions = append(make([]*Transaction, 0, len(snapshot.Transactions)), snapshot.Transactions...)
+
+	for _, t := range snapshot.Transactions {
+		a.realize(t)
+	}
+
+	a.restoreSpreadCost()
+}
+
+// maxTradeID returns the highest numeric trade ID of the snapshot, used by the backtest engine counters.
+func (s *Snapshot) maxTradeID() int32 {
+
+	var max int32
+
+	for _, is := range s.Instruments {
+		for _, ts := range is.Trades {
+			if id, err := strconv.ParseInt(ts.ID, 10, 32); err == nil && int32(id) > max {
+				max = int32(id)
+			}
+		}
+	}
+
+	for _, t := range s.Transactions {
+		if id, err := strconv.ParseInt(t.TradeID, 10, 32); err == nil && int32(id) > max {
+			max = int32(id)
+		}
+	}
+
+	return max
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Snapshot returns the current state of the account. It should be taken from the strategy callbacks, so the
+// state is not modified while it is copied.
+func (a *Account) Snapshot() *Snapshot {
+
+	s := &Snapshot{
+		Version:        SnapshotVersion,
+		Time:           a.time,
+		AccountID:      a.id,
+		HomeCurrency:   a.homeCurrency,
+		Balance:        a.balance.Load().Float64(),
+		Leverage:       a.leverage,
+		OpeningBalance: a.ledger.OpeningBalance(),
+		Instruments:    make([]*InstrumentSnapshot, 0, len(a.instruments)),
+		Transactions:   a.ledger.Transactions(),
+		WALSequence:    a.wal.Sequence(),
+		Accruals:       a.fees.snapshot(),
+	}
+
+	for _, inst := range a.instruments {
+
+		is := &InstrumentSnapshot{
+			Name:          inst.name,
+			BaseCurrency:  inst.baseCurrency,
+			QuoteCurrency: inst.quoteCurrency,
+			Leverage:      inst.leverage.Load(),
+			PipLocation:   inst.pipLocation,
+			Hedge:         inst.hedgeType,
+			Bid:           inst.bid.Load(),
+			Ask:           inst.ask.Load(),
+			LastUpdate:    inst.lastUpdate,
+			Trades:        make([]*TradeSnapshot, 0, inst.TradesNumber()),
+		}
+
+		if inst.ccyConversion != nil {
+			is.BaseConversionRate = inst.ccyConversion.BaseConversionRate.Load()
+			is.QuoteConversionRate = inst.ccyConversion.QuoteConversionRate.Load()
+		}
+
+		for trade := range inst.Trades() {
+			is.Trades = append(is.Trades, newTradeSnapshot(trade))
+		}
+
+		s.Instruments = append(s.Instruments, is)
+	}
+
+	sort.Slice(s.Instruments, func(i, j int) bool {
+		return s.Instruments[i].Name < s.Instruments[j].Name
+	})
+
+	return s
+}
+
+// WriteSnapshot encodes a snapshot as JSON.
+func WriteSnapshot(w io.Writer, snapshot *Snapshot) error {
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(snapshot)
+}
+
+// ReadSnapshot decodes a JSON snapshot.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+
+	snapshot := &Snapshot{}
+
+	if err := json.NewDecoder(r).Decode(snapshot); err != nil {
+		return nil, err
+	}
+
+	if snapshot.Version != SnapshotVersion {
+		return nil, errors.New("unsupported snapshot version " + strconv.Itoa(snapshot.Version))
+	}
+
+	return snapshot, nil
+}
+
+/*
+RestoreAccount rebuilds an account from a snapshot outside of a session, e.g. to inspect or report on a saved
+state. Trades are linked to the atomic prices and leverage of their instruments and to the conversion rates
+of the snapshot, so profits and margin are calculated as when the snapshot was taken.
+*/
+func RestoreAccount(snapshot *Snapshot, logger Logger) *Account {
+
+	a := newAccount(snapshot.AccountID)
+	a.time = snapshot.Time
+	a.homeCurrency = snapshot.HomeCurrency
+	a.leverage = snapshot.Leverage
+	a.balance.Store(NewDecimal(snapshot.Balance))
+
+	for _, is := range snapshot.Instruments {
+
+		inst := newInstrument(is.Name, is.BaseCurrency, is.QuoteCurrency, is.Leverage, is.PipLocation, logger)
+		inst.hedgeType = is.Hedge
+		inst.bid.Store(is.Bid)
+		inst.ask.Store(is.Ask)
+		inst.lastUpdate = is.LastUpdate
+
+		conversion := newInstrumentConversion(is.Name, is.BaseCurrency, is.QuoteCurrency)
+		conversion.Bid = inst.bid
+		conversion.Ask = inst.ask
+		conversion.BaseConversionRate.Store(is.BaseConversionRate)
+		conversion.QuoteConversionRate.Store(is.QuoteConversionRate)
+		inst.ccyConversion = conversion
+
+		a.instruments[is.Name] = inst
+	}
+
+	a.restoreTrades(snapshot, false)
+	a.restoreLedger(snapshot)
+
+	a.calculate()
+
+	return a
+}
@@ -0,0 +1,231 @@
+package gotrader
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// SpecUpdate is a change of the specification of a traded instrument pushed by the broker, e.g. a leverage cut
+// ahead of an election or a halt of the trading. The zero fields are left unchanged.
+type SpecUpdate struct {
+	Instrument   string
+	Time         time.Time
+	Leverage     float64          // capped by the account leverage
+	MinUnits     int32            // of the opens
+	Halted       bool             // halts the trading, see InstrumentHalted
+	Resumed      bool             // resumes it, see InstrumentTradeable
+	Status       InstrumentStatus // sets a status other than tradeable
+	Limit        LimitState       // enters a limit state blocking the opens of one side
+	LimitUntil   time.Time        // clearing the limit state, it is kept until LimitCleared when zero
+	LimitCleared bool             // clears it
+}
+
+type SpecUpdateHandler func(update *SpecUpdate)
+
+/*
+SpecNotifier is implemented by clients with a feed of the changes of the instrument specifications. The sessions
+update the instruments between the ticks, the backtests on the first tick at or after the time of the update, and
+publish SpecChanged. The orders are checked against the InstrumentStatus, the opens below the minimum units are
+rejected with ErrBelowMinUnits and the ones of the side blocked by the limit state with a *LimitError, the closes
+are still allowed; the open trades are kept or adjusted per the SpecPolicy of the session. A limit state
+with LimitUntil is cleared by the first tick at or after it, publishing SpecChanged as well.
+*/
+type SpecNotifier interface {
+	SubscribeSpecUpdates(accountID string, callback SpecUpdateHandler) error
+}
+
+// SpecPolicy is the treatment of the open trades of an instrument whose specification changed.
+type SpecPolicy int
+
+const (
+	// Grandfathered trades keep the leverage they were opened with, and stay open below the new minimum units
+	Grandfathered SpecPolicy = iota
+
+	// ForceAdjusted trades are margined at the new leverage, and closed below the new minimum units with the
+	// SpecAdjusted reason
+	ForceAdjusted
+)
+
+func (p SpecPolicy) String() string {
+
+	names := [...]string{"GRANDFATHERED", "FORCE_ADJUSTED"}
+
+	return names[p]
+}
+
+// SpecChanges is the functional option to define the treatment of the open trades by the changes of the
+// instrument specifications pushed by the broker (see SpecNotifier), Grandfathered by default.
+func SpecChanges(policy SpecPolicy) Option {
+	return func(p *sessionParameters) {
+		p.specPolicy = policy
+	}
+}
+
+/*
+SpecChanged is published when the specification of an instrument is updated, with the instrument leverage before
+and after the update and its margin used. Adjusted are the trades margined at the new leverage and Closed the ones
+below the new minimum units, whose closes are sent after the event, their fills are notified as usual.
+*/
+type SpecChanged struct {
+	Time         time.Time
+	Update       SpecUpdate
+	Policy       SpecPolicy
+	From         float64
+	To           float64
+	MinUnits     int32
+	Halted       bool
+	Status       InstrumentStatus
+	Limit        LimitState
+	LimitUntil   time.Time
+	Adjusted     []string
+	Closed       []string
+	MarginBefore float64
+	MarginAfter  float64
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (u *SpecUpdate) validate() error {
+
+	if u.Leverage < 0 || math.IsNaN(u.Leverage) || math.IsInf(u.Leverage, 0) {
+		return fmt.Errorf("%s: invalid leverage %v", u.Instrument, u.Leverage)
+	}
+
+	if u.MinUnits < 0 {
+		return fmt.Errorf("%s: invalid minimum units %d", u.Instrument, u.MinUnits)
+	}
+
+	if u.Halted && u.Resumed || u.Status != InstrumentTradeable && (u.Halted || u.Resumed) {
+		return fmt.Errorf("%s: conflicting status", u.Instrument)
+	}
+
+	if u.Limit < NoLimit || u.Limit > LimitDown || u.Limit != NoLimit && u.LimitCleared {
+		return fmt.Errorf("%s: invalid limit state %d", u.Instrument, u.Limit)
+	}
+
+	return nil
+}
+
+// setLeverage changes the leverage of the instrument, the trades keep their leverage unless adjusted, returning
+// the trades adjusted.
+func (i *Instrument) setLeverage(leverage float64, adjust bool) []string {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	adjusted := make([]string, 0)
+
+	for _, position := range []*Position{i.longPosition, i.shortPosition} {
+		for _, trade := range position.list() {
+
+			switch {
+			case adjust && trade.leverage.Load() != leverage:
+				trade.leverage = i.leverage
+				adjusted = append(adjusted, trade.id)
+			case !adjust && trade.leverage == i.leverage:
+				trade.leverage = atomic.NewFloat64(i.leverage.Load())
+			}
+		}
+	}
+
+	i.leverage.Store(leverage)
+	i.touch()
+	i.margin()
+
+	return adjusted
+}
+
+/*
+applySpecUpdate updates an instrument to the specification pushed by the broker and returns the event to
+publish. It must be called by the goroutine processing the ticks, the trades to close are the ones of the event.
+*/
+func applySpecUpdate(account *Account, policy SpecPolicy, update *SpecUpdate) (SpecChanged, error) {
+
+	event := SpecChanged{Time: update.Time, Update: *update, Policy: policy}
+
+	if err := update.validate(); err != nil {
+		return event, err
+	}
+
+	inst, exist := account.instruments[update.Instrument]
+	if !exist {
+		return event, fmt.Errorf("%s: %w", update.Instrument, ErrInstrumentNotTraded)
+	}
+
+	status := inst.Status()
+	switch {
+	case update.Halted:
+		status = InstrumentHalted
+	case update.Resumed:
+		status = InstrumentTradeable
+	case update.Status != InstrumentTradeable:
+		status = update.Status
+	}
+
+	if err := setStatus(account, inst, status, update.Time, false); err != nil {
+		return event, err
+	}
+
+	event.From, event.To = inst.Leverage(), inst.Leverage()
+	event.MarginBefore = inst.MarginUsed()
+
+	if update.Leverage != 0 {
+
+		event.To = update.Leverage
+		if account.leverage > 0 {
+			event.To = math.Min(event.To, account.leverage)
+		}
+
+		event.Adjusted = inst.setLeverage(event.To, policy == ForceAdjusted)
+	}
+
+	if update.MinUnits != 0 {
+		inst.minUnits.Store(update.MinUnits)
+	}
+
+	if update.Limit != NoLimit || update.LimitCleared {
+		inst.setLimit(update.Limit, update.LimitUntil)
+	}
+
+	event.MinUnits, event.Halted, event.Status = inst.MinUnits(), inst.Halted(), inst.Status()
+	event.Limit, event.LimitUntil = inst.Limit(), inst.LimitUntil()
+	event.MarginAfter = inst.MarginUsed()
+
+	if policy == ForceAdjusted && update.MinUnits != 0 {
+		for _, position := range []*Position{inst.longPosition, inst.shortPosition} {
+			for _, trade := range position.list() {
+				if trade.units < update.MinUnits {
+					event.Closed = append(event.Closed, trade.id)
+				}
+			}
+		}
+	}
+
+	return event, nil
+}
+
+// checkSpec returns the error of an open of the units of a traded instrument, not allowed by its status, below its
+// minimum units or blocked by its limit state.
+func checkSpec(account *Account, instrument string, side Side, units int32) error {
+
+	inst, exist := account.instruments[instrument]
+	if !exist {
+		return nil
+	}
+
+	if err := checkOpenStatus(inst); err != nil {
+		return err
+	}
+
+	if minimum := inst.minUnits.Load(); units < minimum {
+		return fmt.Errorf("%s: %w, %d units of %d", instrument, ErrBelowMinUnits, units, minimum)
+	}
+
+	return checkLimit(inst, side)
+}
@@ -0,0 +1,188 @@
+package gotrader
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// SpreadModel returns the spread, as a price difference, of a backtest tick of an instrument from its mid price.
+type SpreadModel interface {
+	Spread(instrument string, mid float64, t time.Time) float64
+}
+
+// Spread is the functional option to replace the spreads of the backtest ticks by the ones of a model, around
+// their mid price, before the markup is applied. The client spreads are kept by default.
+func Spread(model SpreadModel) Option {
+	return func(p *sessionParameters) {
+		p.spread = model
+	}
+}
+
+// InstrumentSpread is the functional option to define the spread model of an instrument, used instead of the
+// Spread one.
+func InstrumentSpread(instrument string, model SpreadModel) Option {
+	return func(p *sessionParameters) {
+		if p.instrumentSpreads == nil {
+			p.instrumentSpreads = make(map[string]SpreadModel)
+		}
+		p.instrumentSpreads[instrument] = model
+	}
+}
+
+/*
+DynamicSpread is a SpreadModel widening a Base spread with the recent realized volatility of the instrument and
+scaling it by the time of day, since the constant spreads overstate the strategies trading often:
+
+	spread = (Base + Volatility * σ * mid) * Hourly[hour]
+
+where σ is the exponentially weighted standard deviation of the mid price returns of the last Window ticks.
+It keeps the volatility of every instrument and is safe for concurrent use, a model must not be shared between
+sessions.
+*/
+type DynamicSpread struct {
+	Base       float64
+	Volatility float64     // spread per unit of realized volatility of the price
+	Window     int         // ticks of the realized volatility, 100 when zero
+	Hourly     [24]float64 // multiplier by hour of day, 1 when zero
+	Location   *time.Location
+
+	lock   sync.Mutex
+	states map[string]*volatility
+}
+
+// volatility is the exponentially weighted variance of the returns of an instrument.
+type volatility struct {
+	mid      float64
+	variance float64
+}
+
+// Spread implements SpreadModel.
+func (s *DynamicSpread) Spread(instrument string, mid float64, t time.Time) float64 {
+
+	spread := s.Base + s.Volatility*s.realized(instrument, mid)*mid
+
+	location := s.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	if multiplier := s.Hourly[t.In(location).Hour()]; multiplier != 0 {
+		spread *= multiplier
+	}
+
+	return math.Max(0, spread)
+}
+
+// realized updates and returns the realized volatility of an instrument with its mid price.
+func (s *DynamicSpread) realized(instrument string, mid float64) float64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.states == nil {
+		s.states = make(map[string]*volatility)
+	}
+
+	state, exist := s.states[instrument]
+	if !exist || state.mid <= 0 {
+		s.states[instrument] = &volatility{mid: mid}
+		return 0
+	}
+
+	window := s.Window
+	if window <= 0 {
+		window = 100
+	}
+
+	alpha := 2 / float64(window+1)
+	r := mid/state.mid - 1
+
+	state.variance = (1-alpha)*state.variance + alpha*r*r
+	state.mid = mid
+
+	return math.Sqrt(state.variance)
+}
+
+/*
+SpreadProfile is a SpreadModel of the typical spreads of an instrument by time of day, e.g. measured on the quotes
+of its broker, reconstructing plausible bid and ask prices for the backtests of candles, which only have the mid
+prices (see the csvdata package):
+
+	gotrader.InstrumentSpread("EUR_USD", &gotrader.SpreadProfile{
+		Default: 0.00012,
+		Periods: []gotrader.SpreadPeriod{
+			{From: 21 * time.Hour, To: 23 * time.Hour, Spread: 0.0005}, // rollover
+			{From: 7 * time.Hour, To: 16 * time.Hour, Spread: 0.00008},
+		},
+	})
+
+The spread of a tick is the one of the first period including its time of day, in Location (UTC when nil), and
+the Default one outside the periods.
+*/
+type SpreadProfile struct {
+	Default  float64
+	Periods  []SpreadPeriod
+	Location *time.Location
+}
+
+// SpreadPeriod is the spread of a SpreadProfile in [From, To), offsets from midnight, wrapping around midnight
+// when To is before From.
+type SpreadPeriod struct {
+	From   time.Duration
+	To     time.Duration
+	Spread float64
+}
+
+// includes returns true if the offset from midnight is in the period.
+func (p SpreadPeriod) includes(offset time.Duration) bool {
+
+	if p.To < p.From {
+		return offset >= p.From || offset < p.To
+	}
+
+	return offset >= p.From && offset < p.To
+}
+
+// Spread implements SpreadModel.
+func (p *SpreadProfile) Spread(instrument string, mid float64, t time.Time) float64 {
+
+	location := p.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	local := t.In(location)
+	offset := local.Sub(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location))
+
+	for _, period := range p.Periods {
+		if period.includes(offset) {
+			return period.Spread
+		}
+	}
+
+	return p.Default
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// respread replaces the spread of a tick of the instrument by the one of its spread model.
+func (p *sessionParameters) respread(inst *Instrument, tick *Tick) {
+
+	model, exist := p.instrumentSpreads[inst.name]
+	if !exist {
+		model = p.spread
+	}
+
+	if model == nil {
+		return
+	}
+
+	mid := (tick.Bid + tick.Ask) / 2
+	half := model.Spread(inst.name, mid, tick.Time) / 2
+
+	tick.Bid, tick.Ask = mid-half, mid+half
+}
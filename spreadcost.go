@@ -0,0 +1,100 @@
+package gotrader
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// halfSpreadCost returns the current spread of the instrument, and the cost of half of it on the units of a trade
+// in the home currency, negative like the fees: the fills at the ask or the bid are half the spread away from the mid.
+func (i *Instrument) halfSpreadCost(t *Trade) (float64, Decimal) {
+
+	ask, bid := i.ask.Load(), i.bid.Load()
+	if ask == 0 || bid == 0 {
+		return 0, 0
+	}
+
+	spread := NewDecimal(ask).Sub(NewDecimal(bid))
+	cost := spread.MulInt(int64(t.units)).MulFloat(t.ccyConversion.QuoteConversionRate.Load() / 2).Neg()
+
+	return spread.Float64(), cost
+}
+
+// paySpread records the half spread paid at the open of a trade.
+func (i *Instrument) paySpread(t *Trade) {
+
+	spread, cost := i.halfSpreadCost(t)
+
+	i.acquire()
+	t.openSpread = spread
+	t.spreadCost = cost
+	i.lock.Unlock()
+
+	i.spreadCost.Add(cost)
+}
+
+// closeSpread records the half spread paid at the close of a trade, and returns the spread cost of the trade.
+func (i *Instrument) closeSpread(t *Trade) Decimal {
+
+	_, cost := i.halfSpreadCost(t)
+	i.spreadCost.Add(cost)
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	return t.spreadCost.Add(cost)
+}
+
+// restoreSpreadCost rebuilds the spread cost of the instruments of a restored account from the open halves of the
+// open trades and the spread costs of the closes in the ledger, with the ledger locked.
+func (a *Account) restoreSpreadCost() {
+
+	costs := make(map[string]Decimal, len(a.instruments))
+
+	for name, inst := range a.instruments {
+		inst.RangeTrades(func(trade *Trade) bool {
+			costs[name] = costs[name].Add(NewDecimal(trade.SpreadCost()))
+			return true
+		})
+	}
+
+	for _, t := range a.ledger.transactions {
+		if t.Type == TradeCloseTransaction {
+			costs[t.Instrument] = costs[t.Instrument].Add(NewDecimal(t.SpreadCost))
+		}
+	}
+
+	for name, inst := range a.instruments {
+		inst.spreadCost.Store(costs[name])
+	}
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// SpreadCost returns the cost of the spreads paid by the opens and closes of the trades of the instrument, in the
+// home currency and negative like the fees. The spreads are not charged, they are in the open and close prices.
+func (i *Instrument) SpreadCost() float64 {
+	return i.spreadCost.Load().Float64()
+}
+
+// OpenSpread returns the spread of the instrument at the open of the trade, in price units.
+func (t *Trade) OpenSpread() float64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.openSpread
+}
+
+// SpreadCost returns the cost of the half spread paid at the open of the trade, in the home currency and negative
+// like the fees. The close pays the other half, see Transaction.SpreadCost.
+func (t *Trade) SpreadCost() float64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.spreadCost.Float64()
+}
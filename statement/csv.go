@@ -0,0 +1,164 @@
+package statement
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+/*
+ReadCSV imports a statement from a CSV file with a header row, its columns matched by name regardless of case
+and order: Time (RFC 3339), Type, Exec ID, Order ID, Trade ID, Instrument, Side, Units, Price, Fees, Amount and
+Balance; only Time and Type are required.
+
+The rows of type OPEN and CLOSE are executions, the rows of a transaction type (TRADE_CLOSE, FINANCING,
+FUNDS_TRANSFER, BALANCE_ADJUSTMENT, DIVIDEND, MANAGEMENT_FEE or PERFORMANCE_FEE) are transactions, and the rows of
+type BALANCE only report the balance. The closing balance is the Balance of the last row reporting one.
+*/
+func ReadCSV(r io.Reader) (*Statement, error) {
+
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"time", "type"} {
+		if _, exist := columns[required]; !exist {
+			return nil, errors.New("statement without " + required + " column")
+		}
+	}
+
+	s := &Statement{}
+
+	for line := 2; ; line++ {
+
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.addRow(columns, row); err != nil {
+			return nil, fmt.Errorf("statement line %d: %w", line, err)
+		}
+	}
+
+	return s, nil
+}
+
+// addRow adds a CSV row to the statement, widening its period.
+func (s *Statement) addRow(columns map[string]int, row []string) error {
+
+	value := func(name string) string {
+		if i, exist := columns[name]; exist && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	number := func(name string) (float64, error) {
+		if v := value(name); v != "" {
+			return strconv.ParseFloat(v, 64)
+		}
+		return 0, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value("time"))
+	if err != nil {
+		return err
+	}
+
+	units, err := number("units")
+	if err != nil {
+		return err
+	}
+
+	price, err := number("price")
+	if err != nil {
+		return err
+	}
+
+	fees, err := number("fees")
+	if err != nil {
+		return err
+	}
+
+	amount, err := number("amount")
+	if err != nil {
+		return err
+	}
+
+	side := gotrader.Long
+	if v := strings.ToUpper(value("side")); v != "" {
+		if side, err = gotrader.ParseSide(v); err != nil {
+			return err
+		}
+	}
+
+	if v := value("balance"); v != "" {
+		if s.ClosingBalance, err = strconv.ParseFloat(v, 64); err != nil {
+			return err
+		}
+		s.HasBalance = true
+	}
+
+	if s.From.IsZero() || t.Before(s.From) {
+		s.From = t
+	}
+
+	if t.After(s.To) {
+		s.To = t
+	}
+
+	switch kind := strings.ToUpper(value("type")); kind {
+	case "BALANCE":
+	case "OPEN", "CLOSE":
+		s.Executions = append(s.Executions, Execution{
+			ID:         value("exec id"),
+			OrderID:    value("order id"),
+			TradeID:    value("trade id"),
+			Time:       t,
+			Instrument: value("instrument"),
+			Side:       side,
+			Units:      int32(units),
+			Price:      price,
+			Fees:       fees,
+			Close:      kind == "CLOSE",
+		})
+	default:
+		transactionType, err := gotrader.ParseTransactionType(kind)
+		if err != nil {
+			return err
+		}
+
+		s.Transactions = append(s.Transactions, &gotrader.Transaction{
+			Type:       transactionType,
+			TradeID:    value("trade id"),
+			Instrument: value("instrument"),
+			Side:       side,
+			Units:      int32(units),
+			ClosePrice: price,
+			Amount:     amount,
+			Fees:       fees,
+			Time:       t,
+		})
+	}
+
+	return nil
+}
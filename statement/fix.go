@@ -0,0 +1,93 @@
+package statement
+
+import (
+	"io"
+	"math"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/clients/fix"
+)
+
+// FIX tags of the drop copy execution reports
+const (
+	tagCommission     = 12
+	tagExecID         = 17
+	tagLastPx         = 31
+	tagLastQty        = 32
+	tagOrderID        = 37
+	tagSide           = 54
+	tagSymbol         = 55
+	tagTransactTime   = 60
+	tagPositionEffect = 77
+	tagExecType       = 150
+	tagTradeID        = 1003
+)
+
+const transactTimeFmt = "20060102-15:04:05.000"
+
+/*
+ReadDropCopy imports the executions of a FIX drop copy, the trade execution reports (35=8 with 150=F) of the
+stream. The closes are the reports with a closing position effect (77=C), the trade IDs are the TradeID (1003)
+of the reports, or the execution ID of the opens without it, and the fees their Commission (12). The symbols are
+mapped to the instrument names by instrument, kept when it is nil. A drop copy reports no cash transactions nor
+balance.
+*/
+func ReadDropCopy(r io.Reader, instrument func(symbol string) string) (*Statement, error) {
+
+	messages, err := fix.ReadMessages(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Statement{}
+
+	for _, msg := range messages {
+
+		if execType, _ := msg.Get(tagExecType); msg.Type() != "8" || execType != "F" {
+			continue
+		}
+
+		e := Execution{
+			Side:  gotrader.Long,
+			Units: int32(math.Round(msg.GetFloat(tagLastQty))),
+			Price: msg.GetFloat(tagLastPx),
+			Fees:  msg.GetFloat(tagCommission),
+		}
+
+		e.ID, _ = msg.Get(tagExecID)
+		e.OrderID, _ = msg.Get(tagOrderID)
+		e.TradeID, _ = msg.Get(tagTradeID)
+		e.Instrument, _ = msg.Get(tagSymbol)
+
+		if instrument != nil {
+			e.Instrument = instrument(e.Instrument)
+		}
+
+		if side, _ := msg.Get(tagSide); side == "2" {
+			e.Side = gotrader.Short
+		}
+
+		if effect, _ := msg.Get(tagPositionEffect); effect == "C" {
+			e.Close = true
+		}
+
+		if v, exist := msg.Get(tagTransactTime); exist {
+			if e.Time, err = time.Parse(transactTimeFmt, v); err != nil {
+				return nil, err
+			}
+		}
+
+		if s.From.IsZero() || e.Time.Before(s.From) {
+			s.From = e.Time
+		}
+
+		if e.Time.After(s.To) {
+			s.To = e.Time
+		}
+
+		s.Executions = append(s.Executions, e)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,386 @@
+/*
+Package statement reconciles the end-of-day statements of a broker with the local ledger of an account. A
+Statement is imported from a CSV file with ReadCSV or from a FIX drop copy with ReadDropCopy, its symbols mapped
+to the instrument names with MapInstruments, and Reconcile reports its breaks: the fills missing on either side,
+the fees and amounts that differ and the closing balance that does not match the local one.
+*/
+package statement
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Execution is a fill of a statement, opening or closing a trade.
+type Execution struct {
+	ID         string
+	OrderID    string
+	TradeID    string // of the trade opened or closed, the execution ID for the opens without it
+	Time       time.Time
+	Instrument string
+	Side       gotrader.Side
+	Units      int32
+	Price      float64
+	Fees       float64
+	Close      bool
+}
+
+/*
+Statement is the activity of an account reported by the broker between From and To: its fills, its cash
+transactions (closes, financing, funds transfers...) and its closing balance when the statement reports one. The
+readers set the period from the first and last entries, it can be widened to the statement day.
+*/
+type Statement struct {
+	From           time.Time
+	To             time.Time
+	Executions     []Execution
+	Transactions   []*gotrader.Transaction
+	ClosingBalance float64
+	HasBalance     bool
+}
+
+// BreakType identifies a difference between a statement and the local ledger.
+type BreakType int
+
+const (
+	MissingLocalFill         BreakType = iota // a fill of the statement is not recorded locally
+	MissingBrokerFill                         // a local open or close is not in the statement
+	UnitsMismatch                             // the units of a fill differ
+	FeeMismatch                               // the fees of a trade or a transaction differ
+	MissingLocalTransaction                   // a transaction of the statement is not in the ledger
+	MissingBrokerTransaction                  // a ledger transaction is not in the statement
+	AmountMismatch                            // the amount of a transaction differs
+	BalanceBreak                              // the closing balance differs
+)
+
+func (b BreakType) String() string {
+
+	names := [...]string{
+		"MISSING_LOCAL_FILL",
+		"MISSING_BROKER_FILL",
+		"UNITS_MISMATCH",
+		"FEE_MISMATCH",
+		"MISSING_LOCAL_TRANSACTION",
+		"MISSING_BROKER_TRANSACTION",
+		"AMOUNT_MISMATCH",
+		"BALANCE_BREAK",
+	}
+
+	return names[b]
+}
+
+// Break is a difference found by Reconcile, Local and Broker hold the compared values (units, fees, amounts or
+// balances) when applicable.
+type Break struct {
+	Type        BreakType
+	Instrument  string
+	TradeID     string
+	Close       bool                     // of the fill breaks
+	Transaction gotrader.TransactionType // of the transaction breaks
+	Local       float64
+	Broker      float64
+}
+
+// Report is the result of the reconciliation of a statement.
+type Report struct {
+	From    time.Time
+	To      time.Time
+	Matched int // fills and transactions matched without differences
+	Breaks  []Break
+}
+
+// Clean returns whether the statement matches the ledger.
+func (r *Report) Clean() bool {
+	return len(r.Breaks) == 0
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// fill is the aggregate of the fills opening or closing a trade.
+type fill struct {
+	instrument string
+	units      int32
+	fees       float64
+	feesKnown  bool // the local fees of the fill are known, the broker ones are always
+}
+
+type fillKey struct {
+	tradeID string
+	close   bool
+}
+
+// transaction is the aggregate of the transactions of a type and trade.
+type transaction struct {
+	instrument string
+	amount     float64
+	fees       float64
+}
+
+type transactionKey struct {
+	kind    gotrader.TransactionType
+	tradeID string
+}
+
+func (s *Statement) contains(t time.Time) bool {
+	return (s.From.IsZero() || !t.Before(s.From)) && (s.To.IsZero() || !t.After(s.To))
+}
+
+// localFills returns the opens and closes of the account in the period of the statement. The fees of an open
+// are the fees charged to its trade when it was opened in the period, the closes carry the fees of the trade.
+func localFills(account *gotrader.Account, s *Statement, ledger []*gotrader.Transaction) map[fillKey]*fill {
+
+	fills := make(map[fillKey]*fill)
+
+	for _, t := range ledger {
+
+		if t.Type != gotrader.TradeCloseTransaction {
+			continue
+		}
+
+		if s.contains(t.OpenTime) {
+			fills[fillKey{tradeID: t.TradeID}] = &fill{instrument: t.Instrument, units: t.Units}
+		}
+
+		if s.contains(t.Time) {
+			f := fills[fillKey{tradeID: t.TradeID, close: true}]
+			if f == nil {
+				f = &fill{instrument: t.Instrument}
+				fills[fillKey{tradeID: t.TradeID, close: true}] = f
+			}
+			f.units += t.Units
+		}
+	}
+
+	for name, inst := range account.Instruments() {
+		inst.RangeTrades(func(trade *gotrader.Trade) bool {
+			if s.contains(trade.OpenTime()) {
+				fills[fillKey{tradeID: trade.ID()}] = &fill{
+					instrument: name,
+					units:      trade.Units(),
+					fees:       trade.ChargedFees(),
+					feesKnown:  true,
+				}
+			}
+			return true
+		})
+	}
+
+	return fills
+}
+
+func brokerFills(s *Statement) map[fillKey]*fill {
+
+	fills := make(map[fillKey]*fill)
+
+	for _, e := range s.Executions {
+
+		id := e.TradeID
+		if id == "" {
+			id = e.ID
+		}
+
+		key := fillKey{tradeID: id, close: e.Close}
+
+		f := fills[key]
+		if f == nil {
+			f = &fill{instrument: e.Instrument, feesKnown: true}
+			fills[key] = f
+		}
+
+		f.units += e.Units
+		f.fees += e.Fees
+	}
+
+	return fills
+}
+
+func aggregate(transactions []*gotrader.Transaction, s *Statement) map[transactionKey]*transaction {
+
+	aggregated := make(map[transactionKey]*transaction)
+
+	for _, t := range transactions {
+
+		if !s.contains(t.Time) {
+			continue
+		}
+
+		key := transactionKey{kind: t.Type, tradeID: t.TradeID}
+
+		a := aggregated[key]
+		if a == nil {
+			a = &transaction{instrument: t.Instrument}
+			aggregated[key] = a
+		}
+
+		a.amount += t.Amount
+		a.fees += t.Fees
+	}
+
+	return aggregated
+}
+
+// localBalance returns the balance of the ledger at the end of the period.
+func localBalance(account *gotrader.Account, s *Statement, ledger []*gotrader.Transaction) float64 {
+
+	if s.To.IsZero() {
+		return account.Balance()
+	}
+
+	balance := account.Ledger().OpeningBalance()
+
+	for _, t := range ledger {
+		if t.Time.After(s.To) {
+			break
+		}
+		balance = t.Balance
+	}
+
+	return balance
+}
+
+func differ(a, b, tolerance float64) bool {
+	return math.Abs(a-b) > tolerance
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// MapInstruments replaces the symbols of the executions and transactions with the instrument names returned by
+// instrument, e.g. the Resolver of a gotrader.SymbolMap for the statements of a venue.
+func (s *Statement) MapInstruments(instrument func(symbol string) string) {
+
+	for i := range s.Executions {
+		s.Executions[i].Instrument = instrument(s.Executions[i].Instrument)
+	}
+
+	for _, t := range s.Transactions {
+		if t.Instrument != "" {
+			t.Instrument = instrument(t.Instrument)
+		}
+	}
+}
+
+/*
+Reconcile diffs the statement against the ledger and the open trades of the account, the amounts differing by
+at most the tolerance match, e.g. 0.005 for the cents of the home currency.
+
+The fills are matched by trade: the local opens are the trades opened in the period, open or closed since, and
+the local closes the trade close transactions of the period; they are only diffed when the statement reports
+executions. The fees of the opens are compared for the trades still open, those of the closed trades are in the
+close transactions. The transactions are matched by type and trade, summed over the period.
+*/
+func Reconcile(account *gotrader.Account, s *Statement, tolerance float64) *Report {
+
+	report := &Report{From: s.From, To: s.To, Breaks: make([]Break, 0)}
+	ledger := account.Ledger().Transactions()
+
+	if len(s.Executions) > 0 {
+
+		local, broker := localFills(account, s, ledger), brokerFills(s)
+
+		for key, b := range broker {
+
+			l, exist := local[key]
+			if !exist {
+				report.Breaks = append(report.Breaks, Break{Type: MissingLocalFill, Instrument: b.instrument,
+					TradeID: key.tradeID, Close: key.close, Broker: float64(b.units)})
+				continue
+			}
+
+			matched := true
+
+			if l.units != b.units {
+				report.Breaks = append(report.Breaks, Break{Type: UnitsMismatch, Instrument: b.instrument,
+					TradeID: key.tradeID, Close: key.close, Local: float64(l.units), Broker: float64(b.units)})
+				matched = false
+			}
+
+			if l.feesKnown && differ(l.fees, b.fees, tolerance) {
+				report.Breaks = append(report.Breaks, Break{Type: FeeMismatch, Instrument: b.instrument,
+					TradeID: key.tradeID, Close: key.close, Local: l.fees, Broker: b.fees})
+				matched = false
+			}
+
+			if matched {
+				report.Matched++
+			}
+		}
+
+		for key, l := range local {
+			if _, exist := broker[key]; !exist {
+				report.Breaks = append(report.Breaks, Break{Type: MissingBrokerFill, Instrument: l.instrument,
+					TradeID: key.tradeID, Close: key.close, Local: float64(l.units)})
+			}
+		}
+	}
+
+	local, broker := aggregate(ledger, s), aggregate(s.Transactions, s)
+
+	for key, b := range broker {
+
+		l, exist := local[key]
+		if !exist {
+			report.Breaks = append(report.Breaks, Break{Type: MissingLocalTransaction, Instrument: b.instrument,
+				TradeID: key.tradeID, Transaction: key.kind, Broker: b.amount})
+			continue
+		}
+
+		matched := true
+
+		if differ(l.amount, b.amount, tolerance) {
+			report.Breaks = append(report.Breaks, Break{Type: AmountMismatch, Instrument: b.instrument,
+				TradeID: key.tradeID, Transaction: key.kind, Local: l.amount, Broker: b.amount})
+			matched = false
+		}
+
+		if differ(l.fees, b.fees, tolerance) {
+			report.Breaks = append(report.Breaks, Break{Type: FeeMismatch, Instrument: b.instrument,
+				TradeID: key.tradeID, Transaction: key.kind, Local: l.fees, Broker: b.fees})
+			matched = false
+		}
+
+		if matched {
+			report.Matched++
+		}
+	}
+
+	if len(s.Transactions) > 0 {
+		for key, l := range local {
+			if _, exist := broker[key]; !exist {
+				report.Breaks = append(report.Breaks, Break{Type: MissingBrokerTransaction, Instrument: l.instrument,
+					TradeID: key.tradeID, Transaction: key.kind, Local: l.amount})
+			}
+		}
+	}
+
+	if s.HasBalance {
+		if balance := localBalance(account, s, ledger); differ(balance, s.ClosingBalance, tolerance) {
+			report.Breaks = append(report.Breaks, Break{Type: BalanceBreak, Local: balance, Broker: s.ClosingBalance})
+		}
+	}
+
+	sort.SliceStable(report.Breaks, func(i, j int) bool {
+		a, b := report.Breaks[i], report.Breaks[j]
+		switch {
+		case a.Type != b.Type:
+			return a.Type < b.Type
+		case a.TradeID != b.TradeID:
+			return a.TradeID < b.TradeID
+		case a.Close != b.Close:
+			return b.Close
+		}
+		return a.Transaction < b.Transaction
+	})
+
+	return report
+}
@@ -0,0 +1,174 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/clients/btrand"
+	"github.com/luismcruz/gotrader/clients/fix"
+)
+
+// alternate opens a trade every few ticks and closes the oldest one once a few are open.
+type alternate struct {
+	engine gotrader.Engine
+	ticks  int
+}
+
+func (s *alternate) Initialize()                          {}
+func (s *alternate) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *alternate) OnOrderFill(fill *gotrader.OrderFill) {}
+func (s *alternate) OnStop()                              {}
+
+func (s *alternate) OnTick(tick *gotrader.Tick) {
+
+	s.ticks++
+	if s.ticks%50 != 0 {
+		return
+	}
+
+	inst := s.engine.Account().Instrument(tick.Instrument)
+
+	if inst.TradesNumber() >= 3 {
+		s.engine.CloseTrade(tick.Instrument, inst.TradeByOrder(0).ID())
+		return
+	}
+
+	s.engine.Buy(tick.Instrument, 1000)
+}
+
+// export writes the statement of the account as the broker would report it.
+func export(account *gotrader.Account) string {
+
+	var b strings.Builder
+
+	b.WriteString("Time,Type,Trade ID,Instrument,Side,Units,Price,Fees,Amount,Balance\n")
+
+	row := func(t time.Time, kind, id, instrument string, side gotrader.Side, units int32, price, fees, amount float64, balance string) {
+		fmt.Fprintf(&b, "%s,%s,%s,%s,%s,%d,%v,%v,%v,%s\n", t.Format(time.RFC3339Nano), kind, id, instrument, side, units,
+			price, fees, amount, balance)
+	}
+
+	for _, t := range account.Ledger().ClosedTrades() {
+		row(t.OpenTime, "OPEN", t.TradeID, t.Instrument, t.Side, t.Units, t.OpenPrice, 0, 0, "")
+		row(t.Time, "CLOSE", t.TradeID, t.Instrument, t.Side, t.Units, t.ClosePrice, 0, 0, "")
+		row(t.Time, "TRADE_CLOSE", t.TradeID, t.Instrument, t.Side, t.Units, t.ClosePrice, t.Fees, t.Amount, "")
+	}
+
+	for name, inst := range account.Instruments() {
+		inst.RangeTrades(func(t *gotrader.Trade) bool {
+			row(t.OpenTime(), "OPEN", t.ID(), name, t.Side(), t.Units(), t.OpenPrice(), t.ChargedFees(), 0, "")
+			return true
+		})
+	}
+
+	row(account.Time(), "BALANCE", "", "", gotrader.Long, 0, 0, 0, 0, fmt.Sprint(account.Balance()))
+
+	return b.String()
+}
+
+func TestReconcile(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4}}
+	from := time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	session := gotrader.NewTradingSession(
+		gotrader.Instruments([]string{"EUR_USD"}),
+		gotrader.InitialBalance(10000),
+		gotrader.HomeCurrency("EUR"),
+	).SetStrategy(&alternate{}).SetClient(btrand.NewBTRandClient(instruments, from, from.Add(6*time.Hour), btrand.Seed(1))).Backtest()
+
+	if err := session.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	account := session.Account()
+	statement := export(account)
+
+	read := func(csv string) *Statement {
+		s, err := ReadCSV(strings.NewReader(csv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	t.Run("clean", func(t *testing.T) {
+
+		report := Reconcile(account, read(statement), 0.005)
+
+		if !report.Clean() || report.Matched == 0 {
+			t.Fatalf("expected a clean report, got %d matched and breaks %+v", report.Matched, report.Breaks)
+		}
+	})
+
+	t.Run("breaks", func(t *testing.T) {
+
+		s := read(statement)
+
+		closed := account.Ledger().ClosedTrades()[0]
+
+		s.Executions = s.Executions[1:] // the open of the first closed trade
+		s.Executions = append(s.Executions, Execution{ID: "x", Instrument: "EUR_USD", Units: 1000, Time: s.To})
+		s.Transactions[0].Fees += 1
+		s.ClosingBalance += 10
+
+		report := Reconcile(account, s, 0.005)
+
+		expected := []Break{
+			{Type: MissingLocalFill, Instrument: "EUR_USD", TradeID: "x", Broker: 1000},
+			{Type: MissingBrokerFill, Instrument: "EUR_USD", TradeID: closed.TradeID, Local: float64(closed.Units)},
+			{Type: FeeMismatch, Instrument: "EUR_USD", TradeID: closed.TradeID, Transaction: gotrader.TradeCloseTransaction,
+				Local: closed.Fees, Broker: closed.Fees + 1},
+			{Type: BalanceBreak, Local: account.Balance(), Broker: account.Balance() + 10},
+		}
+
+		if len(report.Breaks) != len(expected) {
+			t.Fatalf("expected %d breaks, got %+v", len(expected), report.Breaks)
+		}
+
+		for i, b := range report.Breaks {
+			if b != expected[i] {
+				t.Errorf("break %d: expected %+v, got %+v", i, expected[i], b)
+			}
+		}
+	})
+
+	t.Run("drop copy", func(t *testing.T) {
+
+		var stream bytes.Buffer
+
+		for i, effect := range []string{"O", "C"} {
+			stream.Write(fix.NewMessage("8").
+				Set(150, "F").
+				Set(17, fmt.Sprint(i+1)).
+				Set(1003, "7").
+				Set(55, "EUR/USD").
+				Set(54, "2").
+				Set(32, "1000").
+				Set(31, "1.1").
+				Set(12, "0.5").
+				Set(77, effect).
+				Set(60, from.Add(time.Duration(i)*time.Hour).Format(transactTimeFmt)).
+				Bytes("FIX.4.4"))
+			stream.WriteString("\n")
+		}
+
+		s, err := ReadDropCopy(&stream, func(symbol string) string { return strings.Replace(symbol, "/", "_", 1) })
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(s.Executions) != 2 || !s.From.Equal(from) || !s.To.Equal(from.Add(time.Hour)) {
+			t.Fatalf("unexpected statement %+v", s)
+		}
+
+		e := s.Executions[1]
+		if !e.Close || e.TradeID != "7" || e.Instrument != "EUR_USD" || e.Side != gotrader.Short || e.Units != 1000 || e.Fees != 0.5 {
+			t.Errorf("unexpected execution %+v", e)
+		}
+	})
+}
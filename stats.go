@@ -0,0 +1,138 @@
+package gotrader
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+/*
+Stats are the internal statistics of the tick pipeline of a session, collected with the CollectStats option to
+diagnose performance issues without an external profiler.
+
+The counters accumulate since the session started, the queue depths are read when Stats is called. A lock
+contention is counted when the engine has to wait for the lock of an instrument, held by a Snapshot or an
+accessor from another goroutine.
+*/
+type Stats struct {
+	TicksProcessed       uint64
+	TicksDropped         uint64 // replaced by newer ticks because the live engine fell behind the feed
+	TicksDiscarded       uint64 // duplicated or out of order, with the DedupeTicks option
+	Recalculations       uint64
+	RecalculationTime    time.Duration // total time recalculating the instruments and the account
+	MaxRecalculationTime time.Duration
+	LockContentions      uint64
+	TickQueueDepth       int
+	TickQueueCapacity    int
+	OrderQueueDepth      int // order fills waiting for the live engine, 0 on backtests
+}
+
+// MeanRecalculationTime returns the mean time of a recalculation, 0 without recalculations.
+func (s Stats) MeanRecalculationTime() time.Duration {
+
+	if s.Recalculations == 0 {
+		return 0
+	}
+
+	return s.RecalculationTime / time.Duration(s.Recalculations)
+}
+
+// pipelineStats collects the Stats of a session, it is nil (and collects nothing) without the CollectStats option.
+type pipelineStats struct {
+	ticksProcessed       atomic.Uint64
+	ticksDropped         atomic.Uint64
+	ticksDiscarded       atomic.Uint64
+	recalculations       atomic.Uint64
+	recalculationTime    atomic.Int64
+	maxRecalculationTime atomic.Int64
+	lockContentions      atomic.Uint64
+	ticks                atomic.Pointer[chan *Tick]
+	orders               atomic.Pointer[chan *OrderFill]
+}
+
+// watch sets the queues of the engine, orders is nil on backtests.
+func (s *pipelineStats) watch(ticks chan *Tick, orders chan *OrderFill) {
+	if s != nil {
+		s.ticks.Store(&ticks)
+		s.orders.Store(&orders)
+	}
+}
+
+func (s *pipelineStats) tickProcessed() {
+	if s != nil {
+		s.ticksProcessed.Add(1)
+	}
+}
+
+func (s *pipelineStats) tickDropped() {
+	if s != nil {
+		s.ticksDropped.Add(1)
+	}
+}
+
+func (s *pipelineStats) tickDiscarded() {
+	if s != nil {
+		s.ticksDiscarded.Add(1)
+	}
+}
+
+// startRecalculation returns the start time of a recalculation, zero without stats.
+func (s *pipelineStats) startRecalculation() time.Time {
+
+	if s == nil {
+		return time.Time{}
+	}
+
+	return time.Now()
+}
+
+func (s *pipelineStats) recalculated(start time.Time) {
+
+	if s == nil {
+		return
+	}
+
+	elapsed := int64(time.Since(start))
+
+	s.recalculations.Add(1)
+	s.recalculationTime.Add(elapsed)
+
+	for max := s.maxRecalculationTime.Load(); elapsed > max; max = s.maxRecalculationTime.Load() {
+		if s.maxRecalculationTime.CompareAndSwap(max, elapsed) {
+			break
+		}
+	}
+}
+
+func (s *pipelineStats) lockContended() {
+	if s != nil {
+		s.lockContentions.Add(1)
+	}
+}
+
+func (s *pipelineStats) snapshot() Stats {
+
+	if s == nil {
+		return Stats{}
+	}
+
+	stats := Stats{
+		TicksProcessed:       s.ticksProcessed.Load(),
+		TicksDropped:         s.ticksDropped.Load(),
+		TicksDiscarded:       s.ticksDiscarded.Load(),
+		Recalculations:       s.recalculations.Load(),
+		RecalculationTime:    time.Duration(s.recalculationTime.Load()),
+		MaxRecalculationTime: time.Duration(s.maxRecalculationTime.Load()),
+		LockContentions:      s.lockContentions.Load(),
+	}
+
+	if ticks := s.ticks.Load(); ticks != nil {
+		stats.TickQueueDepth = len(*ticks)
+		stats.TickQueueCapacity = cap(*ticks)
+	}
+
+	if orders := s.orders.Load(); orders != nil {
+		stats.OrderQueueDepth = len(*orders)
+	}
+
+	return stats
+}
@@ -0,0 +1,123 @@
+package gotrader
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// InstrumentStatus is the trading status of an instrument, consulted by the engines before the opens and closes.
+type InstrumentStatus int
+
+const (
+	// InstrumentTradeable opens and closes
+	InstrumentTradeable InstrumentStatus = iota
+
+	// InstrumentHalted neither opens nor closes, the orders are rejected with ErrInstrumentHalted
+	InstrumentHalted
+
+	// InstrumentCloseOnly closes, the opens are rejected with ErrCloseOnly
+	InstrumentCloseOnly
+
+	// InstrumentExpired neither opens nor closes, e.g. a contract past its expiry, and is final: the orders are
+	// rejected with ErrInstrumentExpired
+	InstrumentExpired
+)
+
+func (s InstrumentStatus) String() string {
+
+	names := [...]string{"TRADEABLE", "HALTED", "CLOSE_ONLY", "EXPIRED"}
+
+	return names[s]
+}
+
+// StatusChanged is published when the status of an instrument changes, set by the broker through a SpecUpdate or
+// manually with Engine.SetStatus.
+type StatusChanged struct {
+	Time       time.Time
+	Instrument string
+	From       InstrumentStatus
+	To         InstrumentStatus
+	Manual     bool
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// setStatus changes the status of the instrument and publishes the transition, the expired instruments keep their
+// status.
+func setStatus(account *Account, inst *Instrument, status InstrumentStatus, t time.Time, manual bool) error {
+
+	if status < InstrumentTradeable || status > InstrumentExpired {
+		return fmt.Errorf("%s: invalid instrument status %d", inst.name, status)
+	}
+
+	from := inst.Status()
+	if from == status {
+		return nil
+	}
+
+	if from == InstrumentExpired {
+		return fmt.Errorf("%s: %w", inst.name, ErrInstrumentExpired)
+	}
+
+	inst.status.Store(int32(status))
+	account.events.publish(StatusChanged{Time: t, Instrument: inst.name, From: from, To: status, Manual: manual})
+
+	return nil
+}
+
+// checkOpenStatus returns the error of an open of the instrument in its status.
+func checkOpenStatus(inst *Instrument) error {
+
+	switch inst.Status() {
+	case InstrumentHalted:
+		return fmt.Errorf("%s: %w", inst.name, ErrInstrumentHalted)
+	case InstrumentCloseOnly:
+		return fmt.Errorf("%s: %w", inst.name, ErrCloseOnly)
+	case InstrumentExpired:
+		return fmt.Errorf("%s: %w", inst.name, ErrInstrumentExpired)
+	}
+
+	return nil
+}
+
+// checkCloseStatus returns the error of a close of a trade of the instrument in its status.
+func checkCloseStatus(inst *Instrument) error {
+
+	switch inst.Status() {
+	case InstrumentHalted:
+		return fmt.Errorf("%s: %w", inst.name, ErrInstrumentHalted)
+	case InstrumentExpired:
+		return fmt.Errorf("%s: %w", inst.name, ErrInstrumentExpired)
+	}
+
+	return nil
+}
+
+// statusRejection returns the reason of the rejection of a triggered order by the error of its open.
+func statusRejection(err error) string {
+
+	switch {
+	case errors.Is(err, ErrCloseOnly):
+		return "CLOSE_ONLY"
+	case errors.Is(err, ErrInstrumentExpired):
+		return "INSTRUMENT_EXPIRED"
+	}
+
+	return "INSTRUMENT_HALTED"
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Status returns the trading status of the instrument.
+func (i *Instrument) Status() InstrumentStatus {
+	return InstrumentStatus(i.status.Load())
+}
@@ -0,0 +1,239 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// BatchOption represents a Batch functional option
+type BatchOption func(b *Batch)
+
+// BatchSize is the functional option to define the number of records that triggers a flush, defaults to 100.
+func BatchSize(size int) BatchOption {
+	return func(b *Batch) {
+		b.size = size
+	}
+}
+
+// FlushInterval is the functional option to define the maximum time a record waits to be flushed,
+// defaults to 1 second.
+func FlushInterval(interval time.Duration) BatchOption {
+	return func(b *Batch) {
+		b.interval = interval
+	}
+}
+
+// BatchLogger is the functional option to define the logger of the background flush errors.
+func BatchLogger(logger gotrader.Logger) BatchOption {
+	return func(b *Batch) {
+		b.logger = logger
+	}
+}
+
+/*
+Batch is a Store buffering the writes of a SQL store and flushing them in a single database transaction, when
+the buffer is full or on every flush interval. Queries flush the buffer first, so they always see the writes.
+A write returns the error of the flush it triggers, the errors of the background flushes are logged.
+*/
+type Batch struct {
+	*SQL
+	mutex    *sync.Mutex
+	pending  []func(w *Writer) error
+	size     int
+	interval time.Duration
+	logger   gotrader.Logger
+	done     chan struct{}
+	wg       *sync.WaitGroup
+}
+
+// NewBatch is the Batch constructor, it starts the flush interval goroutine.
+func NewBatch(s *SQL, opts ...BatchOption) *Batch {
+
+	b := &Batch{
+		SQL:      s,
+		mutex:    &sync.Mutex{},
+		size:     100,
+		interval: time.Second,
+		done:     make(chan struct{}),
+		wg:       &sync.WaitGroup{},
+	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	if b.logger == nil {
+		b.logger = gotrader.DefaultLogger()
+	}
+
+	b.wg.Add(1)
+	go b.run()
+
+	return b
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (b *Batch) run() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.Flush(); err != nil {
+				b.logger.Errorf("store flush: %v", err)
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *Batch) add(write func(w *Writer) error) error {
+
+	b.mutex.Lock()
+	b.pending = append(b.pending, write)
+	full := len(b.pending) >= b.size
+	b.mutex.Unlock()
+
+	if full {
+		return b.Flush()
+	}
+
+	return nil
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Flush writes the buffered records. When the database transaction fails they are kept to be retried on the
+// next flush, but they are discarded when a record is rejected, so a bad record doesn't block the others forever.
+func (b *Batch) Flush() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	w, err := b.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, write := range b.pending {
+		if err := write(w); err != nil {
+			w.Rollback()
+			b.pending = b.pending[:0]
+			return err
+		}
+	}
+
+	if err := w.Commit(); err != nil {
+		return err
+	}
+
+	b.pending = b.pending[:0]
+
+	return nil
+}
+
+// Pending returns the number of buffered records.
+func (b *Batch) Pending() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.pending)
+}
+
+// OpenTrade implements Store.
+func (b *Batch) OpenTrade(trade *TradeRecord) error {
+	return b.add(func(w *Writer) error { return w.OpenTrade(trade) })
+}
+
+// CloseTrade implements Store.
+func (b *Batch) CloseTrade(trade *TradeRecord) error {
+	return b.add(func(w *Writer) error { return w.CloseTrade(trade) })
+}
+
+// SaveOrder implements Store.
+func (b *Batch) SaveOrder(order *gotrader.Order) error {
+	return b.add(func(w *Writer) error { return w.SaveOrder(order) })
+}
+
+// SaveFill implements Store.
+func (b *Batch) SaveFill(fill *gotrader.OrderFill) error {
+	return b.add(func(w *Writer) error { return w.SaveFill(fill) })
+}
+
+// SaveTransaction implements Store.
+func (b *Batch) SaveTransaction(transaction *gotrader.Transaction) error {
+	return b.add(func(w *Writer) error { return w.SaveTransaction(transaction) })
+}
+
+// Trades implements Store.
+func (b *Batch) Trades(q Query) ([]*TradeRecord, error) {
+
+	if err := b.Flush(); err != nil {
+		return nil, err
+	}
+
+	return b.SQL.Trades(q)
+}
+
+// Orders implements Store.
+func (b *Batch) Orders(q Query) ([]*gotrader.Order, error) {
+
+	if err := b.Flush(); err != nil {
+		return nil, err
+	}
+
+	return b.SQL.Orders(q)
+}
+
+// Fills implements Store.
+func (b *Batch) Fills(q Query) ([]*gotrader.OrderFill, error) {
+
+	if err := b.Flush(); err != nil {
+		return nil, err
+	}
+
+	return b.SQL.Fills(q)
+}
+
+// Transactions implements Store.
+func (b *Batch) Transactions(q Query) ([]*gotrader.Transaction, error) {
+
+	if err := b.Flush(); err != nil {
+		return nil, err
+	}
+
+	return b.SQL.Transactions(q)
+}
+
+// Close implements Store, it stops the flush goroutine, flushes the buffer and closes the database.
+func (b *Batch) Close() error {
+
+	close(b.done)
+	b.wg.Wait()
+
+	err := b.Flush()
+
+	if cerr := b.SQL.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// CandleStore is the local storage of the historical candles, filled by Backfill to warm up the indicators.
+type CandleStore interface {
+	SaveCandles(candles []*gotrader.Candle) error // replaces the candles already stored
+	Candles(instrument string, timeframe time.Duration, from, to time.Time) ([]*gotrader.Candle, error)
+	LastCandle(instrument string, timeframe time.Duration) (*gotrader.Candle, error) // nil when none is stored
+}
+
+// DefaultBackfillBatch is the number of candles requested at once by Backfill.
+const DefaultBackfillBatch = 500
+
+/*
+Backfill downloads the candles of the instrument timeframe from the provider into the store, from from until to,
+and returns the number of candles saved. It is incremental: the download resumes after the last candle stored, so
+it can be run at every startup to only fetch the candles missed since the previous one. The candles are requested
+in batches of the given number of candles (DefaultBackfillBatch when 0, it must not exceed the candles the broker
+returns by request), each saved as it is received, so an interrupted download keeps its progress.
+*/
+func Backfill(provider gotrader.CandleProvider, s CandleStore, instrument string, timeframe time.Duration,
+	from, to time.Time, batch int) (int, error) {
+
+	if batch <= 0 {
+		batch = DefaultBackfillBatch
+	}
+
+	last, err := s.LastCandle(instrument, timeframe)
+	if err != nil {
+		return 0, err
+	}
+
+	if last != nil && !last.Time.Before(from) {
+		from = last.Time.Add(timeframe)
+	}
+
+	from, to = from.Truncate(timeframe), to.Truncate(timeframe) // the candle of to is not complete
+	saved := 0
+
+	for from.Before(to) {
+
+		end := from.Add(time.Duration(batch) * timeframe)
+		if end.After(to) {
+			end = to
+		}
+
+		candles, err := provider.GetCandles(instrument, timeframe, from, end)
+		if err != nil {
+			return saved, err
+		}
+
+		if err := s.SaveCandles(candles); err != nil {
+			return saved, err
+		}
+
+		saved += len(candles)
+		from = end
+	}
+
+	return saved, nil
+}
+
+var candleColumns = []string{"instrument", "timeframe", "time", "open", "high", "low", "close", "ticks"}
+
+// SaveCandles implements CandleStore, in a single database transaction.
+func (s *SQL) SaveCandles(candles []*gotrader.Candle) error {
+
+	if len(candles) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO candles (" + strings.Join(candleColumns, ", ") + ") VALUES (" +
+		s.placeholders(1, len(candleColumns)) + ") ON CONFLICT (instrument, timeframe, time) DO UPDATE SET " +
+		"open = excluded.open, high = excluded.high, low = excluded.low, close = excluded.close, ticks = excluded.ticks"
+
+	for _, c := range candles {
+		_, err := tx.Exec(query, c.Instrument, int64(c.Timeframe), c.Time.UnixNano(), c.Open, c.High, c.Low, c.Close, c.Ticks)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Candles implements CandleStore, from is inclusive and to exclusive, zero values are not filtered.
+func (s *SQL) Candles(instrument string, timeframe time.Duration, from, to time.Time) ([]*gotrader.Candle, error) {
+
+	query := "SELECT " + strings.Join(candleColumns, ", ") + " FROM candles WHERE instrument = " +
+		s.dialect.Placeholder(1) + " AND timeframe = " + s.dialect.Placeholder(2)
+	args := []interface{}{instrument, int64(timeframe)}
+
+	if !from.IsZero() {
+		args = append(args, from.UnixNano())
+		query += " AND time >= " + s.dialect.Placeholder(len(args))
+	}
+
+	if !to.IsZero() {
+		args = append(args, to.UnixNano())
+		query += " AND time < " + s.dialect.Placeholder(len(args))
+	}
+
+	return s.queryCandles(query+" ORDER BY time", args...)
+}
+
+// LastCandle implements CandleStore.
+func (s *SQL) LastCandle(instrument string, timeframe time.Duration) (*gotrader.Candle, error) {
+
+	candles, err := s.queryCandles("SELECT "+strings.Join(candleColumns, ", ")+" FROM candles WHERE instrument = "+
+		s.dialect.Placeholder(1)+" AND timeframe = "+s.dialect.Placeholder(2)+" ORDER BY time DESC LIMIT 1",
+		instrument, int64(timeframe))
+	if err != nil || len(candles) == 0 {
+		return nil, err
+	}
+
+	return candles[0], nil
+}
+
+func (s *SQL) queryCandles(query string, args ...interface{}) ([]*gotrader.Candle, error) {
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candles := make([]*gotrader.Candle, 0)
+
+	for rows.Next() {
+
+		c := &gotrader.Candle{}
+		var timeframe, t int64
+
+		if err := rows.Scan(&c.Instrument, &timeframe, &t, &c.Open, &c.High, &c.Low, &c.Close, &c.Ticks); err != nil {
+			return nil, err
+		}
+
+		c.Timeframe = time.Duration(timeframe)
+		c.Time = time.Unix(0, t).UTC()
+
+		candles = append(candles, c)
+	}
+
+	return candles, rows.Err()
+}
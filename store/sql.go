@@ -0,0 +1,568 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// Dialect defines the differences between the SQL databases.
+type Dialect struct {
+	Name        string
+	Serial      string             // auto increment primary key column definition
+	Placeholder func(n int) string // n-th (from 1) query parameter
+}
+
+// migrations creates and updates the schema, applied in order and recorded in schema_migrations.
+// Times are stored as unix nanoseconds, zero for unset times, and %[1]s is the Serial column of the dialect.
+var migrations = []string{
+	`CREATE TABLE trades (
+		account     TEXT NOT NULL,
+		id          TEXT NOT NULL,
+		instrument  TEXT NOT NULL,
+		side        INTEGER NOT NULL,
+		units       INTEGER NOT NULL,
+		open_price  DOUBLE PRECISION NOT NULL,
+		open_time   BIGINT NOT NULL,
+		close_price DOUBLE PRECISION NOT NULL,
+		close_time  BIGINT NOT NULL,
+		profit      DOUBLE PRECISION NOT NULL,
+		fees        DOUBLE PRECISION NOT NULL,
+		closed      INTEGER NOT NULL,
+		venue       TEXT NOT NULL,
+		tag         TEXT NOT NULL,
+		PRIMARY KEY (account, id)
+	);
+	CREATE INDEX trades_time ON trades (account, open_time);
+
+	CREATE TABLE orders (
+		seq           %[1]s,
+		account       TEXT NOT NULL,
+		id            TEXT NOT NULL,
+		type          INTEGER NOT NULL,
+		instrument    TEXT NOT NULL,
+		side          INTEGER NOT NULL,
+		units         INTEGER NOT NULL,
+		price         DOUBLE PRECISION NOT NULL,
+		stop_loss     DOUBLE PRECISION NOT NULL,
+		take_profit   DOUBLE PRECISION NOT NULL,
+		time_in_force INTEGER NOT NULL,
+		expiry        BIGINT NOT NULL,
+		create_time   BIGINT NOT NULL,
+		tag           TEXT NOT NULL
+	);
+	CREATE INDEX orders_time ON orders (account, create_time);
+
+	CREATE TABLE fills (
+		seq          %[1]s,
+		account      TEXT NOT NULL,
+		error        TEXT NOT NULL,
+		trade_close  INTEGER NOT NULL,
+		order_id     TEXT NOT NULL,
+		trade_id     TEXT NOT NULL,
+		instrument   TEXT NOT NULL,
+		side         INTEGER NOT NULL,
+		price        DOUBLE PRECISION NOT NULL,
+		units        INTEGER NOT NULL,
+		profit       DOUBLE PRECISION NOT NULL,
+		charged_fees DOUBLE PRECISION NOT NULL,
+		time         BIGINT NOT NULL,
+		venue        TEXT NOT NULL,
+		tag          TEXT NOT NULL
+	);
+	CREATE INDEX fills_time ON fills (account, time);
+
+	CREATE TABLE transactions (
+		seq         %[1]s,
+		account     TEXT NOT NULL,
+		type        INTEGER NOT NULL,
+		trade_id    TEXT NOT NULL,
+		instrument  TEXT NOT NULL,
+		side        INTEGER NOT NULL,
+		units       INTEGER NOT NULL,
+		open_price  DOUBLE PRECISION NOT NULL,
+		close_price DOUBLE PRECISION NOT NULL,
+		open_time   BIGINT NOT NULL,
+		amount      DOUBLE PRECISION NOT NULL,
+		fees        DOUBLE PRECISION NOT NULL,
+		balance     DOUBLE PRECISION NOT NULL,
+		time        BIGINT NOT NULL,
+		tag         TEXT NOT NULL
+	);
+	CREATE INDEX transactions_time ON transactions (account, time);`,
+
+	`CREATE TABLE candles (
+		instrument TEXT NOT NULL,
+		timeframe  BIGINT NOT NULL,
+		time       BIGINT NOT NULL,
+		open       DOUBLE PRECISION NOT NULL,
+		high       DOUBLE PRECISION NOT NULL,
+		low        DOUBLE PRECISION NOT NULL,
+		close      DOUBLE PRECISION NOT NULL,
+		ticks      INTEGER NOT NULL,
+		PRIMARY KEY (instrument, timeframe, time)
+	);`,
+}
+
+/*
+SQL implements Store and CandleStore over database/sql. Records are scoped by account, so several accounts can
+share the same database, while the candles of the broker are shared by every account. The schema is migrated when
+the store is created.
+*/
+type SQL struct {
+	db      *sql.DB
+	dialect Dialect
+	account string
+}
+
+// NewSQL is the SQL constructor, it applies the pending migrations.
+func NewSQL(db *sql.DB, dialect Dialect, account string) (*SQL, error) {
+
+	s := &SQL{db: db, dialect: dialect, account: account}
+
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (s *SQL) migrate() error {
+
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+
+	var version int
+	if err := s.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+
+		for _, statement := range strings.Split(strings.ReplaceAll(migrations[i], "%[1]s", s.dialect.Serial), ";") {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+			if _, err := tx.Exec(statement); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %d: %v", i+1, err)
+			}
+		}
+
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (`+s.dialect.Placeholder(1)+`)`, i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// placeholders returns the n query parameters from the given position, comma separated.
+func (s *SQL) placeholders(from, n int) string {
+
+	params := make([]string, n)
+	for i := range params {
+		params[i] = s.dialect.Placeholder(from + i)
+	}
+
+	return strings.Join(params, ", ")
+}
+
+func (s *SQL) insert(execer execer, table string, columns []string, values ...interface{}) error {
+
+	query := "INSERT INTO " + table + " (account, " + strings.Join(columns, ", ") + ") VALUES (" +
+		s.placeholders(1, len(columns)+1) + ")"
+
+	_, err := execer.Exec(query, append([]interface{}{s.account}, values...)...)
+
+	return err
+}
+
+// where returns the filter of a query on the table time column, with the ordering and limit.
+func (s *SQL) where(q Query, timeColumn string, tag bool) (string, []interface{}) {
+
+	conditions := []string{"account = " + s.dialect.Placeholder(1)}
+	args := []interface{}{s.account}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, condition+" "+s.dialect.Placeholder(len(args)))
+	}
+
+	if q.Instrument != "" {
+		add("instrument =", q.Instrument)
+	}
+
+	if q.Tag != "" && tag {
+		add("tag =", q.Tag)
+	}
+
+	if !q.From.IsZero() {
+		add(timeColumn+" >=", q.From.UnixNano())
+	}
+
+	if !q.To.IsZero() {
+		add(timeColumn+" <", q.To.UnixNano())
+	}
+
+	clause := " WHERE " + strings.Join(conditions, " AND ") + " ORDER BY " + timeColumn
+
+	if q.Limit > 0 {
+		clause += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+
+	return clause, args
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func nanos(t time.Time) int64 {
+
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+func fromNanos(n int64) time.Time {
+
+	if n == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, n).UTC()
+}
+
+func boolean(b bool) int {
+
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+var tradeColumns = []string{
+	"id", "instrument", "side", "units", "open_price", "open_time", "close_price", "close_time",
+	"profit", "fees", "closed", "venue", "tag",
+}
+
+func tradeValues(t *TradeRecord) []interface{} {
+	return []interface{}{
+		t.ID, t.Instrument, int(t.Side), t.Units, t.OpenPrice, nanos(t.OpenTime), t.ClosePrice, nanos(t.CloseTime),
+		t.Profit, t.Fees, boolean(t.Closed), t.Venue, t.Tag,
+	}
+}
+
+var orderColumns = []string{
+	"id", "type", "instrument", "side", "units", "price", "stop_loss", "take_profit", "time_in_force",
+	"expiry", "create_time", "tag",
+}
+
+func orderValues(o *gotrader.Order) []interface{} {
+	return []interface{}{
+		o.ID, int(o.Type), o.Instrument, int(o.Side), o.Units, o.Price, o.StopLoss, o.TakeProfit, int(o.TimeInForce),
+		nanos(o.Expiry), nanos(o.CreateTime), o.Tag,
+	}
+}
+
+var fillColumns = []string{
+	"error", "trade_close", "order_id", "trade_id", "instrument", "side", "price", "units", "profit",
+	"charged_fees", "time", "venue", "tag",
+}
+
+func fillValues(f *gotrader.OrderFill) []interface{} {
+	return []interface{}{
+		f.Error, boolean(f.TradeClose), f.OrderID, f.TradeID, f.Instrument.Name, int(f.Side), f.Price, f.Units, f.Profit,
+		f.ChargedFees, nanos(f.Time), f.Venue, f.Tag,
+	}
+}
+
+var transactionColumns = []string{
+	"type", "trade_id", "instrument", "side", "units", "open_price", "close_price", "open_time", "amount",
+	"fees", "balance", "time", "tag",
+}
+
+func transactionValues(t *gotrader.Transaction) []interface{} {
+	return []interface{}{
+		int(t.Type), t.TradeID, t.Instrument, int(t.Side), t.Units, t.OpenPrice, t.ClosePrice, nanos(t.OpenTime), t.Amount,
+		t.Fees, t.Balance, nanos(t.Time), t.Tag,
+	}
+}
+
+func (s *SQL) openTrade(execer execer, t *TradeRecord) error {
+	return s.insert(execer, "trades", tradeColumns, tradeValues(t)...)
+}
+
+func (s *SQL) closeTrade(execer execer, t *TradeRecord) error {
+
+	query := "INSERT INTO trades (account, " + strings.Join(tradeColumns, ", ") + ") VALUES (" +
+		s.placeholders(1, len(tradeColumns)+1) + ") ON CONFLICT (account, id) DO UPDATE SET " +
+		"close_price = excluded.close_price, close_time = excluded.close_time, profit = excluded.profit, " +
+		"fees = excluded.fees, closed = excluded.closed"
+
+	_, err := execer.Exec(query, append([]interface{}{s.account}, tradeValues(t)...)...)
+
+	return err
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// DB returns the database of the store.
+func (s *SQL) DB() *sql.DB {
+	return s.db
+}
+
+// OpenTrade implements Store.
+func (s *SQL) OpenTrade(trade *TradeRecord) error {
+	return s.openTrade(s.db, trade)
+}
+
+// CloseTrade implements Store.
+func (s *SQL) CloseTrade(trade *TradeRecord) error {
+	return s.closeTrade(s.db, trade)
+}
+
+// SaveOrder implements Store.
+func (s *SQL) SaveOrder(order *gotrader.Order) error {
+	return s.insert(s.db, "orders", orderColumns, orderValues(order)...)
+}
+
+// SaveFill implements Store.
+func (s *SQL) SaveFill(fill *gotrader.OrderFill) error {
+	return s.insert(s.db, "fills", fillColumns, fillValues(fill)...)
+}
+
+// SaveTransaction implements Store.
+func (s *SQL) SaveTransaction(transaction *gotrader.Transaction) error {
+	return s.insert(s.db, "transactions", transactionColumns, transactionValues(transaction)...)
+}
+
+// Trades implements Store, filtered by open time.
+func (s *SQL) Trades(q Query) ([]*TradeRecord, error) {
+
+	where, args := s.where(q, "open_time", true)
+
+	rows, err := s.db.Query("SELECT "+strings.Join(tradeColumns, ", ")+" FROM trades"+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := make([]*TradeRecord, 0)
+
+	for rows.Next() {
+
+		t := &TradeRecord{}
+		var side, closed int
+		var openTime, closeTime int64
+
+		err := rows.Scan(&t.ID, &t.Instrument, &side, &t.Units, &t.OpenPrice, &openTime, &t.ClosePrice, &closeTime,
+			&t.Profit, &t.Fees, &closed, &t.Venue, &t.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Side = gotrader.Side(side)
+		t.Closed = closed == 1
+		t.OpenTime = fromNanos(openTime)
+		t.CloseTime = fromNanos(closeTime)
+
+		trades = append(trades, t)
+	}
+
+	return trades, rows.Err()
+}
+
+// Orders implements Store, filtered by creation time.
+func (s *SQL) Orders(q Query) ([]*gotrader.Order, error) {
+
+	where, args := s.where(q, "create_time", true)
+
+	rows, err := s.db.Query("SELECT "+strings.Join(orderColumns, ", ")+" FROM orders"+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]*gotrader.Order, 0)
+
+	for rows.Next() {
+
+		o := &gotrader.Order{}
+		var orderType, side, tif int
+		var expiry, createTime int64
+
+		err := rows.Scan(&o.ID, &orderType, &o.Instrument, &side, &o.Units, &o.Price, &o.StopLoss, &o.TakeProfit, &tif,
+			&expiry, &createTime, &o.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		o.Type = gotrader.OrderType(orderType)
+		o.Side = gotrader.Side(side)
+		o.TimeInForce = gotrader.TimeInForce(tif)
+		o.Expiry = fromNanos(expiry)
+		o.CreateTime = fromNanos(createTime)
+
+		orders = append(orders, o)
+	}
+
+	return orders, rows.Err()
+}
+
+// Fills implements Store, the fills instrument details only have the name.
+func (s *SQL) Fills(q Query) ([]*gotrader.OrderFill, error) {
+
+	where, args := s.where(q, "time", true)
+
+	rows, err := s.db.Query("SELECT "+strings.Join(fillColumns, ", ")+" FROM fills"+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fills := make([]*gotrader.OrderFill, 0)
+
+	for rows.Next() {
+
+		f := &gotrader.OrderFill{}
+		var tradeClose, side int
+		var t int64
+
+		err := rows.Scan(&f.Error, &tradeClose, &f.OrderID, &f.TradeID, &f.Instrument.Name, &side, &f.Price, &f.Units,
+			&f.Profit, &f.ChargedFees, &t, &f.Venue, &f.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		f.TradeClose = tradeClose == 1
+		f.Side = gotrader.Side(side)
+		f.Time = fromNanos(t)
+
+		fills = append(fills, f)
+	}
+
+	return fills, rows.Err()
+}
+
+// Transactions implements Store.
+func (s *SQL) Transactions(q Query) ([]*gotrader.Transaction, error) {
+
+	where, args := s.where(q, "time", true)
+
+	rows, err := s.db.Query("SELECT "+strings.Join(transactionColumns, ", ")+" FROM transactions"+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := make([]*gotrader.Transaction, 0)
+
+	for rows.Next() {
+
+		t := &gotrader.Transaction{}
+		var transactionType, side int
+		var openTime, tm int64
+
+		err := rows.Scan(&transactionType, &t.TradeID, &t.Instrument, &side, &t.Units, &t.OpenPrice, &t.ClosePrice,
+			&openTime, &t.Amount, &t.Fees, &t.Balance, &tm, &t.Tag)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Type = gotrader.TransactionType(transactionType)
+		t.Side = gotrader.Side(side)
+		t.OpenTime = fromNanos(openTime)
+		t.Time = fromNanos(tm)
+
+		transactions = append(transactions, t)
+	}
+
+	return transactions, rows.Err()
+}
+
+// Begin starts a Writer, so several records are written in a single database transaction.
+func (s *SQL) Begin() (*Writer, error) {
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{s: s, tx: tx}, nil
+}
+
+// Close implements Store, closing the database.
+func (s *SQL) Close() error {
+	return s.db.Close()
+}
+
+// Writer writes records in a database transaction, they are only persisted when it is committed.
+type Writer struct {
+	s  *SQL
+	tx *sql.Tx
+}
+
+// OpenTrade records an opened trade.
+func (w *Writer) OpenTrade(trade *TradeRecord) error {
+	return w.s.openTrade(w.tx, trade)
+}
+
+// CloseTrade records a closed trade.
+func (w *Writer) CloseTrade(trade *TradeRecord) error {
+	return w.s.closeTrade(w.tx, trade)
+}
+
+// SaveOrder records an order.
+func (w *Writer) SaveOrder(order *gotrader.Order) error {
+	return w.s.insert(w.tx, "orders", orderColumns, orderValues(order)...)
+}
+
+// SaveFill records a fill.
+func (w *Writer) SaveFill(fill *gotrader.OrderFill) error {
+	return w.s.insert(w.tx, "fills", fillColumns, fillValues(fill)...)
+}
+
+// SaveTransaction records a ledger transaction.
+func (w *Writer) SaveTransaction(transaction *gotrader.Transaction) error {
+	return w.s.insert(w.tx, "transactions", transactionColumns, transactionValues(transaction)...)
+}
+
+// Commit persists the records written.
+func (w *Writer) Commit() error {
+	return w.tx.Commit()
+}
+
+// Rollback discards the records written.
+func (w *Writer) Rollback() error {
+	return w.tx.Rollback()
+}
@@ -0,0 +1,39 @@
+/*
+Package sqlite is the embedded SQLite store, for deployments that want durability without an external database.
+*/
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/luismcruz/gotrader/store"
+
+	_ "github.com/mattn/go-sqlite3" // database/sql driver
+)
+
+// Dialect is the SQLite store dialect.
+var Dialect = store.Dialect{
+	Name:        "sqlite3",
+	Serial:      "INTEGER PRIMARY KEY AUTOINCREMENT",
+	Placeholder: func(n int) string { return "?" },
+}
+
+// New opens (or creates) the SQLite database at path, storing the records of the given account.
+// The database is written in WAL mode so the history can be queried while the session runs.
+func New(path string, account string) (*store.SQL, error) {
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL")
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1) // sqlite has a single writer
+
+	s, err := store.NewSQL(db, Dialect, account)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
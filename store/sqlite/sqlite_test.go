@@ -0,0 +1,158 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/store"
+)
+
+func TestStore(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "history.db")
+	open := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	s, err := New(path, "account")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("trades are opened and closed", func(t *testing.T) {
+
+		err := s.OpenTrade(&store.TradeRecord{ID: "1", Instrument: "EUR_USD", Side: gotrader.Long, Units: 100, OpenPrice: 1.1, OpenTime: open, Tag: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = s.CloseTrade(&store.TradeRecord{ID: "1", Instrument: "EUR_USD", Side: gotrader.Long, Units: 100, ClosePrice: 1.2, CloseTime: open.Add(time.Hour), Profit: 10, Closed: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		trades, err := s.Trades(store.Query{Instrument: "EUR_USD"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(trades) != 1 || !trades[0].Closed || trades[0].OpenPrice != 1.1 || trades[0].Profit != 10 || !trades[0].OpenTime.Equal(open) {
+			t.Errorf("unexpected trades %+v", trades)
+		}
+	})
+
+	t.Run("queries filter by time range", func(t *testing.T) {
+
+		for i := 0; i < 3; i++ {
+			err := s.SaveTransaction(&gotrader.Transaction{Type: gotrader.TradeCloseTransaction, Instrument: "EUR_USD", Amount: float64(i), Time: open.Add(time.Duration(i) * time.Hour)})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		transactions, err := s.Transactions(store.Query{From: open.Add(time.Hour), To: open.Add(2 * time.Hour)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(transactions) != 1 || transactions[0].Amount != 1 {
+			t.Errorf("unexpected transactions %+v", transactions)
+		}
+	})
+
+	t.Run("history survives reopening", func(t *testing.T) {
+
+		s.Close()
+
+		s, err = New(path, "account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		trades, err := s.Trades(store.Query{})
+		if err != nil || len(trades) != 1 {
+			t.Errorf("expected the trade to be persisted, got %v %v", trades, err)
+		}
+
+		other, _ := New(path, "other")
+		defer other.Close()
+
+		if trades, _ := other.Trades(store.Query{}); len(trades) != 0 {
+			t.Errorf("expected the accounts to be isolated, got %v", trades)
+		}
+	})
+
+	t.Run("batched writes are flushed", func(t *testing.T) {
+
+		db, err := New(filepath.Join(t.TempDir(), "batch.db"), "account")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b := store.NewBatch(db, store.BatchSize(3), store.FlushInterval(time.Hour))
+		defer b.Close()
+
+		b.SaveOrder(&gotrader.Order{Instrument: "EUR_USD", Units: 1, CreateTime: open})
+		b.SaveOrder(&gotrader.Order{Instrument: "EUR_USD", Units: 2, CreateTime: open})
+
+		if orders, _ := db.Orders(store.Query{}); len(orders) != 0 || b.Pending() != 2 {
+			t.Errorf("expected the orders to be buffered, got %d saved and %d pending", len(orders), b.Pending())
+		}
+
+		b.SaveOrder(&gotrader.Order{Instrument: "EUR_USD", Units: 3, CreateTime: open})
+
+		if orders, _ := db.Orders(store.Query{}); len(orders) != 3 || b.Pending() != 0 {
+			t.Errorf("expected the full buffer to be flushed, got %d saved and %d pending", len(orders), b.Pending())
+		}
+
+		b.SaveFill(&gotrader.OrderFill{Price: 1.1, Time: open})
+
+		if fills, _ := b.Fills(store.Query{}); len(fills) != 1 {
+			t.Errorf("expected the queries to flush the buffer, got %v", fills)
+		}
+	})
+	t.Run("candles are backfilled incrementally", func(t *testing.T) {
+
+		db, err := New(filepath.Join(t.TempDir(), "candles.db"), "account")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		provider := &candleProvider{}
+
+		n, err := store.Backfill(provider, db, "EUR_USD", time.Minute, open, open.Add(10*time.Minute+30*time.Second), 4)
+		if err != nil || n != 10 || provider.requests != 3 {
+			t.Fatalf("expected 10 candles in 3 requests, got %d in %d: %v", n, provider.requests, err)
+		}
+
+		n, err = store.Backfill(provider, db, "EUR_USD", time.Minute, open, open.Add(12*time.Minute), 4)
+		if err != nil || n != 2 {
+			t.Fatalf("expected the 2 missing candles, got %d: %v", n, err)
+		}
+
+		candles, err := db.Candles("EUR_USD", time.Minute, open.Add(10*time.Minute), time.Time{})
+		if err != nil || len(candles) != 2 || !candles[0].Time.Equal(open.Add(10*time.Minute)) || candles[1].Close != 11 {
+			t.Errorf("unexpected candles %+v: %v", candles, err)
+		}
+	})
+}
+
+// candleProvider returns a candle per minute, closing at its minute from 2024-01-02 10:00.
+type candleProvider struct {
+	requests int
+}
+
+func (p *candleProvider) GetCandles(instrument string, timeframe time.Duration, from, to time.Time) ([]*gotrader.Candle, error) {
+
+	p.requests++
+
+	candles := make([]*gotrader.Candle, 0)
+	for t := from; t.Before(to); t = t.Add(timeframe) {
+		minute := float64(t.Sub(time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)) / time.Minute)
+		candles = append(candles, &gotrader.Candle{Instrument: instrument, Timeframe: timeframe, Time: t, Close: minute})
+	}
+
+	return candles, nil
+}
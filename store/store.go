@@ -0,0 +1,112 @@
+/*
+Package store persists the trading history of an account: trades, orders, fills and ledger transactions.
+Record subscribes a Store to the session event bus so everything is saved as it happens, and the query
+methods return the history filtered by instrument, tag and time range. The SQL implementation is shared by
+the database backends in the subpackages.
+*/
+package store
+
+import (
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+// TradeRecord is the persisted history of a trade, the close fields are set once it is closed.
+type TradeRecord struct {
+	ID         string
+	Instrument string
+	Side       gotrader.Side
+	Units      int32
+	OpenPrice  float64
+	OpenTime   time.Time
+	ClosePrice float64
+	CloseTime  time.Time
+	Profit     float64
+	Fees       float64
+	Closed     bool
+	Venue      string
+	Tag        string
+}
+
+// Query filters the history, zero values are not filtered. Results are sorted by time, From is inclusive
+// and To exclusive.
+type Query struct {
+	Instrument string
+	Tag        string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// Store is the interface of the persistence backends.
+type Store interface {
+	OpenTrade(trade *TradeRecord) error
+	CloseTrade(trade *TradeRecord) error // inserts the trade if its opening was not recorded
+	SaveOrder(order *gotrader.Order) error
+	SaveFill(fill *gotrader.OrderFill) error
+	SaveTransaction(transaction *gotrader.Transaction) error
+	Trades(q Query) ([]*TradeRecord, error)
+	Orders(q Query) ([]*gotrader.Order, error)
+	Fills(q Query) ([]*gotrader.OrderFill, error)
+	Transactions(q Query) ([]*gotrader.Transaction, error)
+	Close() error
+}
+
+// Record saves the events of the bus on the store as they happen, unsubscribe to stop it.
+// Write errors are logged, a nil logger defaults to gotrader.DefaultLogger.
+func Record(bus *gotrader.EventBus, s Store, logger gotrader.Logger) *gotrader.Subscription {
+
+	if logger == nil {
+		logger = gotrader.DefaultLogger()
+	}
+
+	return bus.Subscribe(func(event gotrader.Event) {
+
+		var err error
+
+		switch e := event.(type) {
+		case gotrader.TradeOpened:
+			err = s.OpenTrade(&TradeRecord{
+				ID:         e.Trade.ID(),
+				Instrument: e.Trade.InstrumentName(),
+				Side:       e.Trade.Side(),
+				Units:      e.Trade.Units(),
+				OpenPrice:  e.Trade.OpenPrice(),
+				OpenTime:   e.Trade.OpenTime(),
+				Venue:      e.Trade.Venue(),
+				Tag:        e.Trade.Tag(),
+			})
+		case gotrader.TradeClosed:
+			err = s.CloseTrade(&TradeRecord{
+				ID:         e.Fill.TradeID,
+				Instrument: e.Fill.Instrument.Name,
+				Side:       e.Fill.Side,
+				Units:      e.Fill.Units,
+				ClosePrice: e.Fill.Price,
+				CloseTime:  e.Fill.Time,
+				Profit:     e.Fill.Profit,
+				Fees:       e.Fill.ChargedFees,
+				Closed:     true,
+				Venue:      e.Fill.Venue,
+				Tag:        e.Fill.Tag,
+			})
+		case gotrader.OrderFilled:
+			err = s.SaveFill(e.Fill)
+		case gotrader.OrderSubmitted:
+			err = s.SaveOrder(e.Order)
+		case gotrader.TransactionRecorded:
+			err = s.SaveTransaction(e.Transaction)
+		}
+
+		if err != nil {
+			logger.Errorf("store %s: %v", event.Type(), err)
+		}
+	}, 10000,
+		gotrader.TradeOpenedEvent,
+		gotrader.TradeClosedEvent,
+		gotrader.OrderFilledEvent,
+		gotrader.OrderSubmittedEvent,
+		gotrader.TransactionRecordedEvent,
+	)
+}
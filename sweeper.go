@@ -0,0 +1,263 @@
+package gotrader
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultSweepInterval is the interval of the sweeps of the pending orders without SweepPolicy.Interval.
+const defaultSweepInterval = time.Second
+
+/*
+SweepPolicy configures the sweeper of the pending orders of the live engine, see OrderSweeper. The sweeps run every
+Interval of the session Clock, which times the orders too: the good till date orders past their expiry are
+cancelled at the broker, and the orders not amended for KeepAlive are amended again with the same levels, for the
+brokers dropping the resting orders that are not re-confirmed. The clocks that are not a TimerClock are swept every
+Interval of wall clock time.
+*/
+type SweepPolicy struct {
+	Interval  time.Duration // of the sweeps, defaultSweepInterval when zero
+	KeepAlive time.Duration // half the OrderKeepAlive of a KeepAliveBroker when zero, never without one
+}
+
+// OrderSweeper is the functional option to sweep the pending orders of the live engine, which also sweeps them with
+// the default policy when the client is a KeepAliveBroker. The backtests expire the orders with every tick.
+func OrderSweeper(policy SweepPolicy) Option {
+	return func(p *sessionParameters) {
+		p.sweep = &policy
+	}
+}
+
+// KeepAliveBroker is implemented by the brokers cancelling the pending orders that are not amended for a while, e.g.
+// on their maintenance windows. OrderKeepAlive returns the longest time an order rests without an amendment.
+type KeepAliveBroker interface {
+	OrderKeepAlive() time.Duration
+}
+
+// OrderExpired is published when a pending order expires, after its expiry time or when the broker dropped it.
+type OrderExpired struct {
+	Time   time.Time
+	Order  *Order
+	Lapsed bool // dropped by the broker before its expiry, e.g. not kept alive
+}
+
+// sweep is the outcome of a sweep of the pending orders at the broker, applied by the engine loop.
+type sweep struct {
+	time    time.Time
+	pending []*Order // when the sweep started
+	kept    []*Order // amended again at the broker
+	expired []*Order // cancelled at their expiry, or no longer at the broker after their expiry
+	lapsed  []*Order // no longer at the broker before their expiry
+}
+
+// orderSweeper keeps the time the pending orders were last confirmed to the broker, it is nil without sweeps.
+type orderSweeper struct {
+	interval  time.Duration
+	keepAlive time.Duration
+	mutex     *sync.Mutex
+	confirmed map[string]time.Time // by order ID
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func newOrderSweeper(policy *SweepPolicy, client BrokerClient) *orderSweeper {
+
+	keeper, isKeeper := client.(KeepAliveBroker)
+
+	if policy == nil && !isKeeper {
+		return nil
+	}
+
+	s := &orderSweeper{
+		interval:  defaultSweepInterval,
+		mutex:     &sync.Mutex{},
+		confirmed: make(map[string]time.Time),
+	}
+
+	if policy != nil {
+		if policy.Interval > 0 {
+			s.interval = policy.Interval
+		}
+		s.keepAlive = policy.KeepAlive
+	}
+
+	if s.keepAlive <= 0 && isKeeper {
+		s.keepAlive = keeper.OrderKeepAlive() / 2
+	}
+
+	return s
+}
+
+// due returns true when the order must be confirmed again at now. The orders loaded from the broker at the start of
+// the session, without creation time, are confirmed from their first sweep.
+func (s *orderSweeper) due(order *Order, now time.Time) bool {
+
+	if s.keepAlive <= 0 {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	last, exist := s.confirmed[order.ID]
+	if !exist {
+		last = order.CreateTime
+		if last.IsZero() {
+			last = now
+		}
+		s.confirmed[order.ID] = last
+	}
+
+	return now.Sub(last) >= s.keepAlive
+}
+
+// amended records the confirmation of an order at t, by a keep-alive or by a modification of the strategy.
+func (s *orderSweeper) amended(id string, t time.Time) {
+
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.confirmed[id] = t
+}
+
+// prune forgets the orders no longer pending.
+func (s *orderSweeper) prune(pending []*Order) {
+
+	ids := make(map[string]bool, len(pending))
+	for _, order := range pending {
+		ids[order.ID] = true
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for id := range s.confirmed {
+		if !ids[id] {
+			delete(s.confirmed, id)
+		}
+	}
+}
+
+/*
+sweepOrders cancels the good till date orders past their expiry and keeps alive the orders due, at the broker. The
+orders whose request failed are looked up at the broker, the ones it no longer has expired or lapsed, e.g. dropped
+during a maintenance window, so they are removed instead of rotting in the pending orders. It blocks on the broker
+requests and their retries, so it runs out of the engine loop, which applies the sweep (see applySweep).
+*/
+func (e *liveEngine) sweepOrders(broker Broker) *sweep {
+
+	now := e.clock.Now()
+	s := &sweep{time: now, pending: e.pendingOrders.pending("")}
+	var failed []*Order
+
+	for _, order := range s.pending {
+
+		switch {
+		case order.expired(now):
+
+			err := e.retry("order expiry", AuditRecord{OrderID: order.ID}, false, func() error {
+				return broker.CancelOrder(e.account.id, order.ID)
+			})
+			if err != nil {
+				e.logger.Warnf("expiry of order %s failed: %v", order.ID, err)
+				failed = append(failed, order)
+				continue
+			}
+
+			s.expired = append(s.expired, order)
+
+		case e.sweeper.due(order, now):
+
+			confirmed := *order
+			err := e.retry("order keep-alive", AuditRecord{OrderID: order.ID}, false, func() error {
+				return broker.ModifyOrder(e.account.id, order.ID, &confirmed)
+			})
+			if err != nil {
+				e.logger.Warnf("keep-alive of order %s failed: %v", order.ID, err)
+				failed = append(failed, order)
+				continue
+			}
+
+			s.kept = append(s.kept, order)
+		}
+	}
+
+	if len(failed) == 0 {
+		return s
+	}
+
+	var orders []*Order
+	err := e.retry("pending orders", AuditRecord{}, true, func() (err error) {
+		orders, err = broker.GetPendingOrders(e.account.id)
+		return err
+	})
+	if err != nil {
+		e.logger.Warn(err)
+		return s
+	}
+
+	resting := make(map[string]bool, len(orders))
+	for _, order := range orders {
+		resting[order.ID] = true
+	}
+
+	for _, order := range failed {
+		switch {
+		case resting[order.ID]:
+		case order.expired(now):
+			s.expired = append(s.expired, order)
+		default:
+			s.lapsed = append(s.lapsed, order)
+		}
+	}
+
+	return s
+}
+
+// applySweep records the orders kept alive by a sweep and removes the ones expired.
+func (e *liveEngine) applySweep(s *sweep) {
+
+	for _, order := range s.kept {
+		e.sweeper.amended(order.ID, s.time)
+		e.parameters.audit.amended(s.time, order.ID, order)
+	}
+
+	for _, order := range s.expired {
+		e.expireOrder(order, s.time, false)
+	}
+
+	for _, order := range s.lapsed {
+		e.expireOrder(order, s.time, true)
+	}
+
+	e.sweeper.prune(s.pending)
+}
+
+// expireOrder removes an expired order from the pending orders and publishes its expiry, unless it was filled or
+// cancelled meanwhile.
+func (e *liveEngine) expireOrder(order *Order, now time.Time, lapsed bool) {
+
+	if _, exist := e.pendingOrders.remove(order.ID); !exist {
+		return
+	}
+
+	e.brackets.Del(order.ID)
+	e.tracing.end(orderKey(order.ID), nil)
+	e.account.slippages.drop(orderKey(order.ID))
+
+	reason := "ORDER_EXPIRED"
+	if lapsed {
+		reason = "ORDER_LAPSED"
+	}
+	e.parameters.audit.expired(now, order, reason)
+
+	e.account.events.publish(OrderExpired{Time: now, Order: order, Lapsed: lapsed})
+}
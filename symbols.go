@@ -0,0 +1,195 @@
+package gotrader
+
+import (
+	"strings"
+	"sync"
+)
+
+// SymbolFormat is the format of the symbols of a venue derived from the canonical instrument names, BASE_QUOTE
+// joined by Separator, e.g. "/" for EUR/USD or "" for EURUSD, and lower case when Lower.
+type SymbolFormat struct {
+	Separator string
+	Lower     bool
+}
+
+/*
+SymbolMap maps the symbols of the venues to the canonical instrument names, the BASE_QUOTE names of the sessions,
+e.g. EUR_USD for EURUSD, EUR/USD or the 6E future. The venues are named by the broker adapters, the broker types of
+the configuration, e.g. "fix" or "binance".
+
+A symbol is mapped by its alias for the venue, or for every venue with the empty venue, or else normalized: upper
+case, with its separators replaced by an underscore, and the compact symbols, without separator, matched with the
+instruments registered. The canonical names are mapped back by the aliases, or else in the SymbolFormat of the
+venue, unchanged without format. A nil SymbolMap keeps the symbols as they are. It is safe for concurrent use.
+
+	symbols := gotrader.NewSymbolMap()
+	symbols.Register("EUR_USD", "BTC_USDT")
+	symbols.SetFormat("fix", gotrader.SymbolFormat{Separator: "/"})
+	symbols.Alias("cme", "6E", "EUR_USD")
+
+	symbols.Canonical("fix", "EUR/USD")     // EUR_USD
+	symbols.Canonical("binance", "BTCUSDT") // BTC_USDT
+	symbols.Canonical("cme", "6E")          // EUR_USD
+	symbols.Symbol("fix", "EUR_USD")        // EUR/USD
+*/
+type SymbolMap struct {
+	mutex    *sync.RWMutex
+	formats  map[string]SymbolFormat      // by venue
+	aliases  map[string]map[string]string // canonical names by venue and symbol
+	symbols  map[string]map[string]string // symbols by venue and canonical name
+	compacts map[string]string            // canonical names by compact symbol, e.g. EURUSD
+}
+
+// NewSymbolMap is the SymbolMap constructor.
+func NewSymbolMap() *SymbolMap {
+	return &SymbolMap{
+		mutex:    &sync.RWMutex{},
+		formats:  make(map[string]SymbolFormat),
+		aliases:  make(map[string]map[string]string),
+		symbols:  make(map[string]map[string]string),
+		compacts: make(map[string]string),
+	}
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// normalize returns the symbol in upper case with its separators replaced by an underscore.
+func normalize(symbol string) string {
+
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '-', '.', ' ', ':':
+			return '_'
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(symbol)))
+}
+
+// compact returns the symbol without separators.
+func compact(symbol string) string {
+	return strings.ReplaceAll(normalize(symbol), "_", "")
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Register adds canonical instrument names, matched by the compact symbols of the venues.
+func (m *SymbolMap) Register(names ...string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, name := range names {
+		m.compacts[compact(name)] = name
+	}
+}
+
+// SetFormat sets the format of the symbols of the venue, of every venue without format when empty.
+func (m *SymbolMap) SetFormat(venue string, format SymbolFormat) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.formats[venue] = format
+}
+
+// Alias maps a symbol of the venue, every venue when empty, to a canonical instrument name and back.
+func (m *SymbolMap) Alias(venue, symbol, canonical string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.aliases[venue] == nil {
+		m.aliases[venue] = make(map[string]string)
+		m.symbols[venue] = make(map[string]string)
+	}
+
+	m.aliases[venue][symbol] = canonical
+	m.symbols[venue][canonical] = symbol
+}
+
+// Lookup returns the canonical name of an alias of the venue.
+func (m *SymbolMap) Lookup(venue, symbol string) (string, bool) {
+
+	if m == nil {
+		return "", false
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if canonical, exist := m.aliases[venue][symbol]; exist {
+		return canonical, true
+	}
+
+	canonical, exist := m.aliases[""][symbol]
+
+	return canonical, exist
+}
+
+// Canonical returns the canonical instrument name of a symbol of the venue.
+func (m *SymbolMap) Canonical(venue, symbol string) string {
+
+	if m == nil {
+		return symbol
+	}
+
+	if canonical, exist := m.Lookup(venue, symbol); exist {
+		return canonical
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if canonical, exist := m.compacts[compact(symbol)]; exist {
+		return canonical
+	}
+
+	return normalize(symbol)
+}
+
+// Symbol returns the symbol of the venue of a canonical instrument name.
+func (m *SymbolMap) Symbol(venue, canonical string) string {
+
+	if m == nil {
+		return canonical
+	}
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if symbol, exist := m.symbols[venue][canonical]; exist {
+		return symbol
+	}
+
+	if symbol, exist := m.symbols[""][canonical]; exist {
+		return symbol
+	}
+
+	format, exist := m.formats[venue]
+	if !exist {
+		format, exist = m.formats[""]
+	}
+
+	if !exist {
+		return canonical
+	}
+
+	symbol := strings.ReplaceAll(canonical, "_", format.Separator)
+	if format.Lower {
+		symbol = strings.ToLower(symbol)
+	}
+
+	return symbol
+}
+
+// Resolver returns the mapping of the symbols of the venue to the canonical names, e.g. for the data loaders.
+func (m *SymbolMap) Resolver(venue string) func(symbol string) string {
+	return func(symbol string) string {
+		return m.Canonical(venue, symbol)
+	}
+}
@@ -0,0 +1,60 @@
+package gotrader
+
+import (
+	"sync"
+)
+
+// syncMap is a typed map safe for concurrent use, the trades are read by the strategy and the observers while
+// the engine opens and closes them.
+type syncMap[K comparable, V any] struct {
+	sync.RWMutex
+	items map[K]V
+}
+
+func newSyncMap[K comparable, V any]() *syncMap[K, V] {
+	return &syncMap[K, V]{
+		items: make(map[K]V),
+	}
+}
+
+func (m *syncMap[K, V]) Get(key K) (V, bool) {
+	m.RLock()
+	defer m.RUnlock()
+
+	value, exist := m.items[key]
+	return value, exist
+}
+
+func (m *syncMap[K, V]) Set(key K, value V) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.items[key] = value
+}
+
+func (m *syncMap[K, V]) Del(key K) {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.items, key)
+}
+
+func (m *syncMap[K, V]) Len() int {
+	m.RLock()
+	defer m.RUnlock()
+
+	return len(m.items)
+}
+
+// Values returns a copy of the values, in no particular order.
+func (m *syncMap[K, V]) Values() []V {
+	m.RLock()
+	defer m.RUnlock()
+
+	values := make([]V, 0, len(m.items))
+	for _, value := range m.items {
+		values = append(values, value)
+	}
+
+	return values
+}
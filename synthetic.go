@@ -0,0 +1,232 @@
+package gotrader
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+/*
+SyntheticCross defines an instrument derived from two instruments sharing a currency, its legs: the cross quotes the
+other currency of the First leg in the other currency of the Second one, e.g. EUR_GBP from EUR_USD and GBP_USD, or
+EUR_JPY from EUR_USD and USD_JPY. The legs are subscribed even when they are not traded.
+*/
+type SyntheticCross struct {
+	Name   string // the currencies of the cross joined by an underscore when empty
+	First  string
+	Second string
+}
+
+/*
+Synthetic is the functional option to add a synthetic cross to the instruments of the session: its bid and ask are
+updated with the ticks of either leg, crossing the bid with the bid and the ask with the ask of the legs, and the
+strategy receives its ticks as the ones of the traded instruments. The synthetic instruments value the signals and
+the alerts, but are not traded, their orders are rejected with ErrSyntheticInstrument, and they have no conversion
+rates to the home currency. The pip location of a cross is the one of its Second leg.
+*/
+func Synthetic(cross SyntheticCross) Option {
+	return func(p *sessionParameters) {
+		if p.synthetics == nil {
+			p.synthetics = &synthetics{mutex: &sync.Mutex{}, legs: make(map[string][]*synthetic)}
+		}
+		p.synthetics.crosses = append(p.synthetics.crosses, cross)
+	}
+}
+
+// syntheticLeg is a leg of a synthetic cross, inverted when it quotes the currencies of the cross the other way.
+type syntheticLeg struct {
+	name   string
+	invert bool
+	bid    float64
+	ask    float64
+}
+
+// rate returns the bid and the ask of the leg in the direction of the cross.
+func (l *syntheticLeg) rate() (float64, float64) {
+
+	if l.invert {
+		return 1 / l.ask, 1 / l.bid
+	}
+
+	return l.bid, l.ask
+}
+
+// synthetic is a synthetic cross with the last prices of its legs.
+type synthetic struct {
+	details InstrumentDetails
+	legs    [2]*syntheticLeg
+}
+
+// synthetics derives the synthetic crosses from their legs, it is nil without the Synthetic option. The clients may
+// deliver the ticks from several goroutines.
+type synthetics struct {
+	mutex   *sync.Mutex
+	crosses []SyntheticCross
+	legs    map[string][]*synthetic // by leg name
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// newSynthetic resolves a cross from the details of its legs.
+func newSynthetic(cross SyntheticCross, available map[string]InstrumentDetails) (*synthetic, error) {
+
+	first, exist := available[cross.First]
+	if !exist {
+		return nil, fmt.Errorf("synthetic %s: leg %s: %w", cross.Name, cross.First, ErrInstrumentNotTraded)
+	}
+
+	second, exist := available[cross.Second]
+	if !exist {
+		return nil, fmt.Errorf("synthetic %s: leg %s: %w", cross.Name, cross.Second, ErrInstrumentNotTraded)
+	}
+
+	var common string
+
+	for _, c := range []string{first.BaseCurrency, first.QuoteCurrency} {
+		if c == second.BaseCurrency || c == second.QuoteCurrency {
+			common = c
+		}
+	}
+
+	if common == "" || first.Name == second.Name {
+		return nil, fmt.Errorf("synthetic %s: the legs %s and %s do not share one currency", cross.Name,
+			first.Name, second.Name)
+	}
+
+	// the first leg is oriented as base/common and the second one as common/quote
+	s := &synthetic{legs: [2]*syntheticLeg{
+		{name: first.Name, invert: first.BaseCurrency == common},
+		{name: second.Name, invert: second.QuoteCurrency == common},
+	}}
+
+	s.details = InstrumentDetails{
+		Name:          cross.Name,
+		BaseCurrency:  first.BaseCurrency,
+		QuoteCurrency: second.QuoteCurrency,
+		Leverage:      min(first.Leverage, second.Leverage),
+		PipLocation:   second.PipLocation,
+	}
+
+	if s.legs[0].invert {
+		s.details.BaseCurrency = first.QuoteCurrency
+	}
+
+	if s.legs[1].invert {
+		s.details.QuoteCurrency = second.BaseCurrency
+	}
+
+	if s.details.Name == "" {
+		s.details.Name = s.details.BaseCurrency + "_" + s.details.QuoteCurrency
+	}
+
+	return s, nil
+}
+
+// add adds the synthetic crosses to the instruments of the account, after the conversion engine has set up the
+// traded ones, and returns the subscriptions with their legs.
+func (s *synthetics) add(account *Account, available map[string]InstrumentDetails, subscribed []InstrumentDetails,
+	logger Logger) ([]InstrumentDetails, error) {
+
+	if s == nil {
+		return subscribed, nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, cross := range s.crosses {
+
+		synth, err := newSynthetic(cross, available)
+		if err != nil {
+			return nil, err
+		}
+
+		d := synth.details
+		if _, exist := account.instruments[d.Name]; exist {
+			return nil, fmt.Errorf("synthetic %s: the instrument is already traded", d.Name)
+		}
+
+		inst := newInstrument(d.Name, d.BaseCurrency, d.QuoteCurrency, math.Min(d.Leverage, account.leverage),
+			d.PipLocation, logger)
+		inst.synthetic = true
+		inst.ccyConversion = newInstrumentConversion(d.Name, d.BaseCurrency, d.QuoteCurrency)
+		inst.ccyConversion.Bid, inst.ccyConversion.Ask = inst.bid, inst.ask
+		account.instruments[d.Name] = inst
+
+		for _, leg := range synth.legs {
+
+			s.legs[leg.name] = append(s.legs[leg.name], synth)
+
+			if !containsInstrument(subscribed, leg.name) {
+				subscribed = append(subscribed, available[leg.name])
+			}
+		}
+	}
+
+	return subscribed, nil
+}
+
+// leg returns whether the instrument is a leg of a synthetic cross.
+func (s *synthetics) leg(instrument string) bool {
+
+	if s == nil {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, exist := s.legs[instrument]
+
+	return exist
+}
+
+// update records the prices of a tick of a leg and returns the ticks of its crosses priced by both legs.
+func (s *synthetics) update(tick *Tick) []*Tick {
+
+	if s == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var ticks []*Tick
+
+	for _, synth := range s.legs[tick.Instrument] {
+
+		for _, leg := range synth.legs {
+			if leg.name == tick.Instrument {
+				leg.bid, leg.ask = tick.Bid, tick.Ask
+			}
+		}
+
+		if synth.legs[0].bid <= 0 || synth.legs[1].bid <= 0 {
+			continue
+		}
+
+		bid1, ask1 := synth.legs[0].rate()
+		bid2, ask2 := synth.legs[1].rate()
+
+		t := AcquireTick()
+		t.Instrument, t.Bid, t.Ask, t.Time = synth.details.Name, bid1*bid2, ask1*ask2, tick.Time
+		ticks = append(ticks, t)
+	}
+
+	return ticks
+}
+
+func containsInstrument(details []InstrumentDetails, name string) bool {
+
+	for _, d := range details {
+		if d.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package gotrader
+
+import "time"
+
+/*
+tickOrder drops the duplicated and out of order ticks of the feeds, enabled with the DedupeTicks option. The
+ticks carrying a sequence are ordered by it, a tick at or below the last sequence of its instrument is dropped;
+the ticks without one are ordered by their time, a tick older than the last one is dropped, as is a tick with the
+time and the prices of the last one. It is only used by the engine goroutine.
+*/
+type tickOrder struct {
+	last map[string]lastTick // by instrument
+}
+
+type lastTick struct {
+	sequence uint64
+	time     time.Time
+	bid      float64
+	ask      float64
+}
+
+// DedupeTicks is the functional option to drop the duplicated and out of order ticks, replayed or received
+// from several feeds, so the prices never step backward. The dropped ticks are counted by the Stats.
+func DedupeTicks() Option {
+	return func(p *sessionParameters) {
+		p.tickOrder = &tickOrder{last: make(map[string]lastTick)}
+	}
+}
+
+// accept returns whether the tick is applied, recording it as the last tick of its instrument. A nil order
+// accepts every tick.
+func (o *tickOrder) accept(tick *Tick) bool {
+
+	if o == nil {
+		return true
+	}
+
+	last, exist := o.last[tick.Instrument]
+
+	if exist {
+		switch {
+		case tick.Sequence != 0 && last.sequence != 0:
+			if tick.Sequence <= last.sequence {
+				return false
+			}
+		case tick.Time.Before(last.time):
+			return false
+		case tick.Time.Equal(last.time) && tick.Bid == last.bid && tick.Ask == last.ask:
+			return false
+		}
+	}
+
+	o.last[tick.Instrument] = lastTick{sequence: tick.Sequence, time: tick.Time, bid: tick.Bid, ask: tick.Ask}
+
+	return true
+}
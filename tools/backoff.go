@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"math"
+	"time"
+)
+
+// Backoff computes exponentially increasing delays between reconnection attempts.
+type Backoff struct {
+	Base     time.Duration // delay before the first retry
+	Max      time.Duration // upper bound of a single delay
+	Attempts int           // maximum number of retries, zero means unlimited
+}
+
+// NewBackoff is the Backoff constructor.
+func NewBackoff(base, max time.Duration, attempts int) *Backoff {
+	return &Backoff{Base: base, Max: max, Attempts: attempts}
+}
+
+// Delay returns the delay before the given retry attempt (starting at zero).
+func (b *Backoff) Delay(attempt int) time.Duration {
+
+	delay := time.Duration(float64(b.Base) * math.Pow(2, float64(attempt)))
+
+	if delay > b.Max || delay <= 0 { // also guards against overflow
+		return b.Max
+	}
+
+	return delay
+}
+
+// Retry calls fn until it succeeds or the attempts are exhausted, sleeping the backoff delay before each call.
+// The last error is returned when all attempts fail.
+func (b *Backoff) Retry(fn func() error) (err error) {
+
+	for i := 0; b.Attempts == 0 || i < b.Attempts; i++ {
+
+		time.Sleep(b.Delay(i))
+
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"sync"
+	"time"
+)
+
+// Priority defines the queueing order of the rate limited requests.
+type Priority int
+
+const (
+	// Normal priority, used by risk increasing requests like opening trades
+	Normal Priority = iota
+
+	// RiskReducing priority, used by closes and cancels, is served first and can use the reserved tokens
+	RiskReducing
+)
+
+// Budget is the token bucket configuration of an endpoint.
+type Budget struct {
+	Rate    float64 // tokens refilled per second
+	Burst   int     // bucket capacity
+	Reserve int     // tokens that only risk reducing requests can take
+}
+
+type bucket struct {
+	budget  Budget
+	tokens  float64
+	last    time.Time
+	queues  [2][]chan struct{} // indexed by priority
+	pending bool               // a dispatch is scheduled
+}
+
+/*
+RateLimiter is a token bucket rate limiter shared by the broker adapters. Each endpoint has its own budget,
+requests that exceed it are queued, and risk reducing requests are dispatched before the normal ones.
+*/
+type RateLimiter struct {
+	mutex   *sync.Mutex
+	budget  Budget
+	buckets map[string]*bucket
+}
+
+// NewRateLimiter is the RateLimiter constructor, the default budget is used by endpoints without their own.
+func NewRateLimiter(budget Budget) *RateLimiter {
+	return &RateLimiter{
+		mutex:   &sync.Mutex{},
+		budget:  budget,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// SetBudget defines the budget of an endpoint.
+func (l *RateLimiter) SetBudget(endpoint string, budget Budget) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.buckets[endpoint] = &bucket{budget: budget, tokens: float64(budget.Burst), last: time.Now()}
+}
+
+// Wait blocks until the request is allowed by the endpoint budget.
+func (l *RateLimiter) Wait(endpoint string, priority Priority) {
+
+	ready := make(chan struct{}, 1)
+
+	l.mutex.Lock()
+
+	b, exist := l.buckets[endpoint]
+	if !exist {
+		b = &bucket{budget: l.budget, tokens: float64(l.budget.Burst), last: time.Now()}
+		l.buckets[endpoint] = b
+	}
+
+	b.queues[priority] = append(b.queues[priority], ready)
+	l.dispatch(b)
+
+	l.mutex.Unlock()
+
+	<-ready
+}
+
+// dispatch refills the bucket and releases the queued requests it can afford, must be called with the mutex locked.
+func (l *RateLimiter) dispatch(b *bucket) {
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.budget.Rate
+	b.last = now
+
+	if b.tokens > float64(b.budget.Burst) {
+		b.tokens = float64(b.budget.Burst)
+	}
+
+	for b.tokens >= 1 && len(b.queues[RiskReducing]) > 0 {
+		b.queues[RiskReducing][0] <- struct{}{}
+		b.queues[RiskReducing] = b.queues[RiskReducing][1:]
+		b.tokens--
+	}
+
+	for b.tokens >= float64(b.budget.Reserve)+1 && len(b.queues[Normal]) > 0 {
+		b.queues[Normal][0] <- struct{}{}
+		b.queues[Normal] = b.queues[Normal][1:]
+		b.tokens--
+	}
+
+	if b.pending || len(b.queues[RiskReducing])+len(b.queues[Normal]) == 0 || b.budget.Rate <= 0 {
+		return
+	}
+
+	// wait for the tokens needed by the first queued request
+	needed := 1 - b.tokens
+	if len(b.queues[RiskReducing]) == 0 {
+		needed += float64(b.budget.Reserve)
+	}
+
+	b.pending = true
+	time.AfterFunc(time.Duration(needed/b.budget.Rate*float64(time.Second)), func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		b.pending = false
+		l.dispatch(b)
+	})
+}
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+
+	t.Run("Risk reducing requests are served first", func(t *testing.T) {
+
+		limiter := NewRateLimiter(Budget{Rate: 20, Burst: 1})
+		limiter.Wait("orders", Normal) // drains the bucket
+
+		order := make(chan Priority, 2)
+
+		go func() {
+			limiter.Wait("orders", Normal)
+			order <- Normal
+		}()
+
+		time.Sleep(10 * time.Millisecond) // the normal request is queued first
+
+		go func() {
+			limiter.Wait("orders", RiskReducing)
+			order <- RiskReducing
+		}()
+
+		if first := <-order; first != RiskReducing {
+			t.Errorf("expected the risk reducing request first")
+		}
+
+		<-order
+	})
+
+	t.Run("Reserved tokens are kept for risk reducing requests", func(t *testing.T) {
+
+		limiter := NewRateLimiter(Budget{Rate: 1, Burst: 2, Reserve: 1})
+		limiter.Wait("orders", Normal)
+
+		start := time.Now()
+		limiter.Wait("orders", RiskReducing)
+
+		if time.Since(start) > 100*time.Millisecond {
+			t.Errorf("risk reducing request should use the reserved token")
+		}
+	})
+}
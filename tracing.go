@@ -0,0 +1,197 @@
+package gotrader
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the OpenTelemetry tracer of the engines, when no tracer is given to the session.
+const TracerName = "github.com/luismcruz/gotrader"
+
+// ContextClient is implemented by clients accepting a context on their order requests, so the trace of the
+// order is propagated to the broker requests.
+type ContextClient interface {
+	OpenMarketOrderContext(ctx context.Context, accountID, instrument string, units int32, side string) error
+	CloseTradeContext(ctx context.Context, accountID, id string) error
+}
+
+/*
+orderTracer traces the order lifecycle: the "order" span covers the strategy request until its fill, with
+the "broker.submit" child span around the broker request (its acknowledgement) and the "order.fill" child span
+around the fill processing, itself with a "position.update" child span around the account update.
+
+Fills are matched to their order span by trade ID for closes, by order ID for pending orders and in request
+order by instrument and side for market orders, since brokers don't return an ID for them.
+*/
+type orderTracer struct {
+	tracer  trace.Tracer
+	mutex   *sync.Mutex
+	pending map[string][]trace.Span
+}
+
+func newOrderTracer(tracer trace.Tracer) *orderTracer {
+
+	if tracer == nil {
+		tracer = otel.Tracer(TracerName)
+	}
+
+	return &orderTracer{
+		tracer:  tracer,
+		mutex:   &sync.Mutex{},
+		pending: make(map[string][]trace.Span),
+	}
+}
+
+func marketKey(instrument string, side Side) string {
+	return "market:" + instrument + ":" + side.String()
+}
+
+func closeKey(tradeID string) string {
+	return "close:" + tradeID
+}
+
+func orderKey(orderID string) string {
+	return "order:" + orderID
+}
+
+func clientKey(clientID string) string {
+	return "client:" + clientID
+}
+
+// start starts an order span waiting for the fill of the key.
+func (t *orderTracer) start(key, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+
+	ctx, span := t.tracer.Start(context.Background(), "order",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(append(attributes, attribute.String("order.operation", operation))...),
+	)
+
+	t.mutex.Lock()
+	t.pending[key] = append(t.pending[key], span)
+	t.mutex.Unlock()
+
+	return ctx, span
+}
+
+// rekey moves the span to the key of its broker order ID, once the pending order is accepted.
+func (t *orderTracer) rekey(span trace.Span, from, to string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	spans := t.pending[from]
+	for i, s := range spans {
+		if s.SpanContext().Equal(span.SpanContext()) { // spans may not be comparable, the no-op ones share a context
+			t.pending[from] = append(spans[:i:i], spans[i+1:]...)
+			t.pending[to] = append(t.pending[to], span)
+			return
+		}
+	}
+}
+
+func (t *orderTracer) take(key string) trace.Span {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	spans := t.pending[key]
+	if len(spans) == 0 {
+		return nil
+	}
+
+	if len(spans) == 1 {
+		delete(t.pending, key)
+	} else {
+		t.pending[key] = spans[1:]
+	}
+
+	return spans[0]
+}
+
+// submit traces a broker request.
+func (t *orderTracer) submit(ctx context.Context, request func(ctx context.Context) error) error {
+
+	ctx, span := t.tracer.Start(ctx, "broker.submit", trace.WithSpanKind(trace.SpanKindClient))
+	err := request(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	return err
+}
+
+// end ends an order span without fill, e.g. it was refused by the broker or cancelled.
+func (t *orderTracer) end(key string, err error) {
+
+	span := t.take(key)
+	if span == nil {
+		return
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// fill starts the fill span of the order span of the fill, the returned function ends both.
+func (t *orderTracer) fill(fill *OrderFill) (context.Context, func()) {
+
+	var order trace.Span
+
+	if fill.TradeClose || (fill.TradeID != "" && fill.OrderID == "") {
+		order = t.take(closeKey(fill.TradeID))
+	}
+	if order == nil && fill.OrderID != "" {
+		order = t.take(orderKey(fill.OrderID))
+	}
+	if order == nil {
+		order = t.take(marketKey(fill.Instrument.Name, fill.Side))
+	}
+
+	ctx := context.Background()
+	if order != nil {
+		ctx = trace.ContextWithSpan(ctx, order)
+	}
+
+	ctx, span := t.tracer.Start(ctx, "order.fill", trace.WithAttributes(
+		attribute.String("order.instrument", fill.Instrument.Name),
+		attribute.String("order.side", fill.Side.String()),
+		attribute.Int("order.units", int(fill.Units)),
+		attribute.String("order.id", fill.OrderID),
+		attribute.String("trade.id", fill.TradeID),
+		attribute.Float64("fill.price", fill.Price),
+	))
+
+	return ctx, func() {
+		for _, s := range []trace.Span{span, order} {
+			if s == nil {
+				continue
+			}
+			if fill.Error != "" {
+				s.SetStatus(codes.Error, fill.Error)
+			}
+			s.End()
+		}
+	}
+}
+
+// update starts the position update span of a fill.
+func (t *orderTracer) update(ctx context.Context) trace.Span {
+	_, span := t.tracer.Start(ctx, "position.update")
+	return span
+}
+
+func orderAttributes(instrument string, side Side, units int32) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("order.instrument", instrument),
+		attribute.String("order.side", side.String()),
+		attribute.Int("order.units", int(units)),
+	}
+}
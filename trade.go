@@ -1,29 +1,75 @@
 package gotrader
 
 import (
+	"sync"
 	"time"
 
 	"go.uber.org/atomic"
 )
 
+// CloseReason is the reason a trade was closed.
+type CloseReason int
+
+const (
+	// CloseRequested is a close requested by the strategy, or by the broker for the live sessions
+	CloseRequested CloseReason = iota
+
+	// StopLossClose is a close by the stop loss of the trade
+	StopLossClose
+
+	// TakeProfitClose is a close by the take profit of the trade
+	TakeProfitClose
+
+	// Expired is a close at the end of the maximum lifetime of the trade (see Order.MaxLifetime)
+	Expired
+
+	// Flattened is a close before the session close of the instrument (see FlattenBeforeClose)
+	Flattened
+
+	// RolledBack is a close of a trade opened by a basket that failed (see Engine.SubmitBasket)
+	RolledBack
+
+	// SpecAdjusted is a close of a trade below the new minimum units of its instrument (see ForceAdjusted)
+	SpecAdjusted
+)
+
+func (r CloseReason) String() string {
+
+	names := [...]string{"CLOSE_REQUESTED", "STOP_LOSS", "TAKE_PROFIT", "EXPIRED", "FLATTENED", "ROLLED_BACK", "SPEC_ADJUSTED"}
+
+	return names[r]
+}
+
 // Trade represents a transaction in a broker (execution of an order).
 // Not all brokers have the possibility to operate over single trades, making impossible to use
 // this engine in the current state.
 type Trade struct {
+	lock                      *sync.RWMutex // of the instrument
 	id                        string
 	instrumentName            string
 	side                      Side
 	units                     int32
 	openTime                  time.Time
-	unrealizedNetProfit       float64
-	unrealizedEffectiveProfit float64
-	marginUsed                float64
+	unrealizedNetProfit       Decimal
+	unrealizedEffectiveProfit Decimal
+	marginUsed                Decimal
 	leverage                  *atomic.Float64
-	chargedFees               *atomic.Float64
+	chargedFees               *atomicDecimal
+	fees                      feeItems
 	openPrice                 float64
 	currentPrice              *atomic.Float64
 	sideSign                  float64
 	ccyConversion             *instrumentConversion
+	stopLoss                  float64
+	guaranteedStop            bool
+	takeProfit                float64
+	expiry                    time.Time // zero without maximum lifetime
+	venue                     string
+	tag                       string
+	gapFill                   bool
+	openSpread                float64 // in price units, see SpreadCost
+	spreadCost                Decimal // of the open
+	detached                  bool    // decoded by UnmarshalJSON, not book-kept by an instrument
 }
 
 /**************************
@@ -42,6 +88,7 @@ func newTrade(
 ) *Trade {
 
 	tr := &Trade{
+		lock:           &inst.lock,
 		id:             tradeID,
 		instrumentName: inst.name,
 		side:           tradeSide,
@@ -51,25 +98,73 @@ func newTrade(
 		sideSign:       sideSign(tradeSide),
 		ccyConversion:  inst.ccyConversion,
 		leverage:       inst.leverage,
-		chargedFees:    atomic.NewFloat64(0),
+		chargedFees:    newAtomicDecimal(0),
+		fees:           newFeeItems(),
 	}
 
 	return tr
 
 }
 
+// calculateUnrealized calculates the profit in decimal, the price difference times the units is exact and only
+// the conversion to the home currency is rounded.
 func (t *Trade) calculateUnrealized() {
-	t.unrealizedNetProfit = (t.currentPrice.Load() - t.openPrice) * t.sideSign * float64(t.units) * t.ccyConversion.QuoteConversionRate.Load()
-	t.unrealizedEffectiveProfit = t.unrealizedNetProfit + t.chargedFees.Load()
+	priceDifference := NewDecimal(t.currentPrice.Load()).Sub(NewDecimal(t.openPrice))
+	t.unrealizedNetProfit = priceDifference.MulInt(int64(t.units) * int64(t.sideSign)).
+		MulFloat(t.ccyConversion.QuoteConversionRate.Load())
+	t.unrealizedEffectiveProfit = t.unrealizedNetProfit.Add(t.chargedFees.Load())
 }
 
 func (t *Trade) calculateMarginUsed() {
-	t.marginUsed = float64(t.units) / t.leverage.Load() * t.ccyConversion.BaseConversionRate.Load()
+	t.marginUsed = DecimalFromInt(int64(t.units)).MulFloat(t.ccyConversion.BaseConversionRate.Load() / t.leverage.Load())
 }
 
 func (t *Trade) updateChargedFee(fee float64) {
-	t.chargedFees.Add(fee)
-	t.unrealizedEffectiveProfit += fee
+	t.chargedFees.Add(NewDecimal(fee))
+	t.unrealizedEffectiveProfit = t.unrealizedEffectiveProfit.Add(NewDecimal(fee))
+}
+
+func (t *Trade) stopLossHit() bool {
+
+	if t.stopLoss == 0 {
+		return false
+	}
+
+	if t.side == Long {
+		return t.currentPrice.Load() <= t.stopLoss
+	}
+
+	return t.currentPrice.Load() >= t.stopLoss
+}
+
+// profitAt returns the net and effective profits of the trade closed at a price, with the lock held.
+func (t *Trade) profitAt(price float64) (Decimal, Decimal) {
+	priceDifference := NewDecimal(price).Sub(NewDecimal(t.openPrice))
+	net := priceDifference.MulInt(int64(t.units) * int64(t.sideSign)).
+		MulFloat(t.ccyConversion.QuoteConversionRate.Load())
+
+	return net, net.Add(t.chargedFees.Load())
+}
+
+// expired returns true when the maximum lifetime of the trade elapsed at now.
+func (t *Trade) expired(now time.Time) bool {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return !t.expiry.IsZero() && !now.Before(t.expiry)
+}
+
+func (t *Trade) takeProfitHit() bool {
+
+	if t.takeProfit == 0 {
+		return false
+	}
+
+	if t.side == Long {
+		return t.currentPrice.Load() >= t.takeProfit
+	}
+
+	return t.currentPrice.Load() <= t.takeProfit
 }
 
 func sideSign(side Side) float64 {
@@ -93,6 +188,9 @@ func (t *Trade) ID() string {
 
 // InstrumentName return the instrument name.
 func (t *Trade) InstrumentName() string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
 	return t.instrumentName
 }
 
@@ -103,6 +201,9 @@ func (t *Trade) Side() Side {
 
 // Units returns the total units that this trade is exposed.
 func (t *Trade) Units() int32 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
 	return t.units
 }
 
@@ -113,26 +214,43 @@ func (t *Trade) OpenTime() time.Time {
 
 // UnrealizedNetProfit returns the unrealized profit.
 func (t *Trade) UnrealizedNetProfit() float64 {
-	return t.unrealizedNetProfit
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.unrealizedNetProfit.Float64()
 }
 
 // UnrealizedEffectiveProfit returns the unrealized profit plus the charged fees.
 func (t *Trade) UnrealizedEffectiveProfit() float64 {
-	return t.unrealizedEffectiveProfit
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.unrealizedEffectiveProfit.Float64()
 }
 
 // MarginUsed return the margin that the trade is using
 func (t *Trade) MarginUsed() float64 {
-	return t.marginUsed
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.marginUsed.Float64()
 }
 
 // ChargedFees returns the total charged fees, like rollovers.
 func (t *Trade) ChargedFees() float64 {
-	return t.chargedFees.Load()
+	return t.chargedFees.Load().Float64()
+}
+
+// Fees returns the breakdown of the costs of the trade, see Fees.
+func (t *Trade) Fees() Fees {
+	return t.fees.breakdown()
 }
 
 // OpenPrice returns the openning price of the trade.
 func (t *Trade) OpenPrice() float64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
 	return t.openPrice
 }
 
@@ -140,3 +258,49 @@ func (t *Trade) OpenPrice() float64 {
 func (t *Trade) CurrentPrice() float64 {
 	return t.currentPrice.Load()
 }
+
+// StopLoss returns the stop loss level attached to the trade, zero if not set.
+func (t *Trade) StopLoss() float64 {
+	return t.stopLoss
+}
+
+// GuaranteedStop returns true when the stop loss of the trade is guaranteed, filled exactly at its level.
+func (t *Trade) GuaranteedStop() bool {
+	return t.guaranteedStop
+}
+
+// TakeProfit returns the take profit level attached to the trade, zero if not set.
+func (t *Trade) TakeProfit() float64 {
+	return t.takeProfit
+}
+
+// Expiry returns the time the trade is closed at the end of its maximum lifetime, zero if not set.
+func (t *Trade) Expiry() time.Time {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.expiry
+}
+
+// Tag returns the tag of the order that opened the trade.
+func (t *Trade) Tag() string {
+	return t.tag
+}
+
+// GapFill returns whether the trade was opened on the tick of a price gap, see GapPolicy.
+func (t *Trade) GapFill() bool {
+	return t.gapFill
+}
+
+// Venue returns the execution venue of the trade, empty when the session has a single broker.
+func (t *Trade) Venue() string {
+	return t.venue
+}
+
+// State returns a copy of the trade, its metrics at the current price, captured under the instrument lock.
+func (t *Trade) State() TradeState {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.state(t.currentPrice.Load())
+}
@@ -0,0 +1,71 @@
+package gotrader
+
+import (
+	"context"
+)
+
+/*
+The trade iterators work on a snapshot of the trades taken when they are called, so the trades opened or closed
+while iterating, even by the iteration itself, don't affect it.
+
+The channels of Trades, TradesByAscendingOrder and TradesByDescendingOrder are buffered with the whole snapshot
+and already closed, a caller can stop consuming them at any time without leaking a goroutine. The Context
+variants stream the snapshot from a goroutine that exits when the context is done, and the Range methods call
+a function on every trade until it returns false.
+*/
+
+// lookupTrades returns the trades of the ids still open, in the order of the ids.
+func lookupTrades(trades *syncMap[string, *Trade], ids []string) []*Trade {
+
+	result := make([]*Trade, 0, len(ids))
+
+	for _, id := range ids {
+		if trade, exist := trades.Get(id); exist {
+			result = append(result, trade)
+		}
+	}
+
+	return result
+}
+
+// tradesChannel returns a closed channel buffered with the trades.
+func tradesChannel(trades []*Trade) <-chan *Trade {
+
+	ch := make(chan *Trade, len(trades))
+
+	for _, trade := range trades {
+		ch <- trade
+	}
+
+	close(ch)
+
+	return ch
+}
+
+// tradesChannelContext streams the trades until they are all consumed or the context is done.
+func tradesChannelContext(ctx context.Context, trades []*Trade) <-chan *Trade {
+
+	ch := make(chan *Trade)
+
+	go func() {
+		defer close(ch)
+
+		for _, trade := range trades {
+			select {
+			case ch <- trade:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+func rangeTrades(trades []*Trade, f func(trade *Trade) bool) {
+	for _, trade := range trades {
+		if !f(trade) {
+			return
+		}
+	}
+}
@@ -0,0 +1,257 @@
+/*
+Package tui is a terminal dashboard of a gotrader session, so operators can watch a headless process over SSH:
+the prices and spreads of the instruments, the open positions, the account equity and margin, and the recent
+events. The dashboard redraws the terminal with ANSI escape codes, without taking over its input.
+*/
+package tui
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/notify"
+)
+
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	timeFormat  = "2006-01-02 15:04:05 MST"
+)
+
+// Option represents a Dashboard functional option
+type Option func(d *Dashboard)
+
+// Interval is the functional option to define how often the dashboard is redrawn, defaults to 1 second.
+func Interval(interval time.Duration) Option {
+	return func(d *Dashboard) {
+		d.interval = interval
+	}
+}
+
+// RecentEvents is the functional option to define how many recent events are shown, defaults to 10.
+func RecentEvents(n int) Option {
+	return func(d *Dashboard) {
+		d.size = n
+	}
+}
+
+// SetLogger is the functional option to define the dashboard logger.
+func SetLogger(logger gotrader.Logger) Option {
+	return func(d *Dashboard) {
+		d.logger = logger
+	}
+}
+
+// event is a line of the recent events.
+type event struct {
+	time time.Time
+	text string
+}
+
+/*
+Dashboard draws the state of a session on a terminal at its interval. The events are described with the
+notify default templates, the ones without a template by their type.
+*/
+type Dashboard struct {
+	engine       gotrader.Engine
+	out          io.Writer
+	interval     time.Duration
+	size         int
+	mutex        *sync.Mutex
+	events       []event
+	messages     *notify.Notifier
+	subscription *gotrader.Subscription
+	done         chan struct{}
+	logger       gotrader.Logger
+}
+
+// NewDashboard is the Dashboard constructor, the engine is usually the TradingSession Engine and out the
+// terminal, e.g. os.Stdout. The dashboard is drawn after Start is called.
+func NewDashboard(engine gotrader.Engine, out io.Writer, opts ...Option) *Dashboard {
+
+	d := &Dashboard{
+		engine:   engine,
+		out:      out,
+		interval: time.Second,
+		size:     10,
+		mutex:    &sync.Mutex{},
+		done:     make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	if d.logger == nil {
+		d.logger = gotrader.DefaultLogger()
+	}
+
+	d.messages, _ = notify.NewNotifier(nil, notify.NotifierLogger(d.logger))
+
+	return d
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (d *Dashboard) onEvent(e gotrader.Event) {
+
+	text, err := d.messages.Message(e)
+	if err != nil {
+		text = e.Type().String()
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.events = append(d.events, event{time: d.engine.Account().Time(), text: text})
+	if len(d.events) > d.size {
+		d.events = d.events[len(d.events)-d.size:]
+	}
+}
+
+// subscribe subscribes to the account events once the session has started.
+func (d *Dashboard) subscribe() {
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.subscription == nil && d.engine.Account() != nil {
+		d.subscription = d.engine.Account().Events().Subscribe(d.onEvent, 100)
+	}
+}
+
+func (d *Dashboard) draw() {
+
+	d.subscribe()
+
+	frame := &strings.Builder{}
+	frame.WriteString(clearScreen)
+	d.Render(frame)
+
+	if _, err := io.WriteString(d.out, frame.String()); err != nil {
+		d.logger.Warn(err)
+	}
+}
+
+func pips(inst *gotrader.Instrument, value float64) float64 {
+	return value / math.Pow10(inst.PipLocation())
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Render writes a frame of the dashboard, without escape codes.
+func (d *Dashboard) Render(w io.Writer) {
+
+	account := d.engine.Account()
+	if account == nil {
+		fmt.Fprintln(w, "waiting for the session to start")
+		return
+	}
+
+	fmt.Fprintf(w, "Account %s (%s)  %s\n\n", account.ID(), account.HomeCurrency(),
+		account.Time().Format(timeFormat))
+
+	level := "-"
+	if account.MarginUsed() > 0 {
+		level = fmt.Sprintf("%.0f%%", account.Equity()/account.MarginUsed()*100)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintln(tw, "BALANCE\tEQUITY\tUNREALIZED\tMARGIN USED\tMARGIN FREE\tMARGIN LEVEL\t")
+	fmt.Fprintf(tw, "%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%s\t\n", account.Balance(), account.Equity(),
+		account.UnrealizedNetProfit(), account.MarginUsed(), account.MarginFree(), level)
+	tw.Flush()
+
+	names := make([]string, 0, len(account.Instruments()))
+	for name := range account.Instruments() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "INSTRUMENT\tBID\tASK\tSPREAD\tLONG\tSHORT\tAVG LONG\tAVG SHORT\tUNREALIZED\t")
+
+	for _, name := range names {
+
+		inst := account.Instrument(name)
+		long, short := inst.LongPosition(), inst.ShortPosition()
+
+		fmt.Fprintf(tw, "%s\t%g\t%g\t%.1f\t%d\t%d\t%g\t%g\t%.2f\t\n", name, inst.Bid(), inst.Ask(),
+			pips(inst, inst.Ask()-inst.Bid()), long.Units(), short.Units(), long.AveragePrice(), short.AveragePrice(),
+			inst.UnrealizedNetProfit())
+	}
+	tw.Flush()
+
+	if exposures := account.CurrencyExposures(); len(exposures.Currencies) > 0 {
+
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintf(tw, "CURRENCY\tLONG\tSHORT\tNET\tNOTIONAL %s\t\n", account.HomeCurrency())
+
+		for _, e := range exposures.Currencies {
+			fmt.Fprintf(tw, "%s\t%.0f\t%.0f\t%.0f\t%.2f\t\n", e.Currency, e.Long, e.Short, e.Net, e.Notional)
+		}
+		tw.Flush()
+	}
+
+	d.mutex.Lock()
+	events := append([]event(nil), d.events...)
+	d.mutex.Unlock()
+
+	fmt.Fprintln(w, "\nRECENT EVENTS")
+
+	for i := len(events) - 1; i >= 0; i-- {
+		fmt.Fprintf(w, "%s  %s\n", events[i].time.Format(timeFormat), events[i].text)
+	}
+}
+
+// Start draws the dashboard at its interval until Stop is called.
+func (d *Dashboard) Start() {
+
+	d.subscribe()
+
+	go func() {
+
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+
+		for {
+			d.draw()
+
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the dashboard.
+func (d *Dashboard) Stop() {
+
+	close(d.done)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.subscription != nil {
+		d.subscription.Unsubscribe()
+	}
+}
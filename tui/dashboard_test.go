@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+	"github.com/luismcruz/gotrader/gotradertest"
+)
+
+type holder struct{ engine gotrader.Engine }
+
+func (s *holder) Initialize()                          {}
+func (s *holder) SetEngine(engine gotrader.Engine)     { s.engine = engine }
+func (s *holder) OnTick(tick *gotrader.Tick)           {}
+func (s *holder) OnOrderFill(fill *gotrader.OrderFill) {}
+func (s *holder) OnStop()                              {}
+
+func TestDashboard_Render(t *testing.T) {
+
+	instruments := []gotrader.InstrumentDetails{
+		{Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4},
+	}
+
+	broker := gotradertest.NewBroker(instruments, gotradertest.Balance(10000), gotradertest.Currency("EUR"),
+		gotradertest.Leverage(30))
+	broker.Quote("EUR_USD", 1.0999, 1.1001)
+
+	s := &holder{}
+	h := gotradertest.New(t, s, broker, gotrader.Instruments([]string{"EUR_USD"}), gotrader.HomeCurrency("EUR"))
+
+	d := NewDashboard(s.engine, &strings.Builder{})
+	d.subscribe()
+	defer d.Stop()
+
+	s.engine.Buy("EUR_USD", 1000)
+	h.Settle()
+
+	frame := &strings.Builder{}
+	deadline := time.Now().Add(gotradertest.Timeout)
+
+	for !strings.Contains(frame.String(), "Opened LONG 1000 EUR_USD") {
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the open in the recent events, got\n%s", frame)
+		}
+
+		time.Sleep(time.Millisecond)
+		frame.Reset()
+		d.Render(frame)
+	}
+
+	row := strings.Fields(strings.Split(frame.String(), "\n")[6])
+	if len(row) != 9 || row[0] != "EUR_USD" || row[3] != "2.0" || row[4] != "1000" || row[5] != "0" {
+		t.Fatalf("expected the instrument row with its spread in pips and the long units, got %v in\n%s", row, frame)
+	}
+
+	if !strings.Contains(frame.String(), "CURRENCY") || !strings.Contains(frame.String(), "NOTIONAL EUR") {
+		t.Fatalf("expected the currency exposures, got\n%s", frame)
+	}
+}
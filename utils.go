@@ -1,13 +1,17 @@
 package gotrader
 
 import (
+	"sort"
 	"sync"
+	"time"
 )
 
+// sortedTrades keeps the trade ids by open time, the trades opened at the same time by arrival.
 type sortedTrades struct {
 	sync.RWMutex
 	tradesOrder map[string]int
 	orderTrades []string
+	openTimes   []time.Time // open time of orderTrades
 	count       int
 }
 
@@ -18,70 +22,116 @@ func newSortedTrades() *sortedTrades {
 	}
 }
 
-func (cs *sortedTrades) Append(tradeID string) {
+// Append adds a trade, at the end unless it was opened before the newest trade (e.g. recovered from the broker).
+func (cs *sortedTrades) Append(tradeID string, openTime time.Time) {
 	cs.Lock()
 	defer cs.Unlock()
 
-	cs.tradesOrder[tradeID] = cs.count
-	cs.orderTrades = append(cs.orderTrades, tradeID)
+	index := cs.count
+	if index > 0 && openTime.Before(cs.openTimes[index-1]) {
+		index = sort.Search(cs.count, func(i int) bool { return cs.openTimes[i].After(openTime) })
+		for k, v := range cs.tradesOrder {
+			if v >= index {
+				cs.tradesOrder[k]++
+			}
+		}
+	}
+
+	cs.orderTrades = append(cs.orderTrades, "")
+	copy(cs.orderTrades[index+1:], cs.orderTrades[index:])
+	cs.orderTrades[index] = tradeID
+
+	cs.openTimes = append(cs.openTimes, time.Time{})
+	copy(cs.openTimes[index+1:], cs.openTimes[index:])
+	cs.openTimes[index] = openTime
+
+	cs.tradesOrder[tradeID] = index
 	cs.count++
 }
 
-func (cs *sortedTrades) AscendIter(maxIterations int) <-chan string {
-	c := make(chan string)
+// search returns the index of the first trade opened at or after t.
+func (cs *sortedTrades) search(t time.Time) int {
+	return sort.Search(cs.count, func(i int) bool { return !cs.openTimes[i].Before(t) })
+}
 
-	go func() {
+// Between returns the ids of the trades opened in [from, to), from the oldest.
+func (cs *sortedTrades) Between(from, to time.Time) []string {
+	cs.RLock()
+	defer cs.RUnlock()
 
-		cs.RLock()
+	start, end := cs.search(from), cs.search(to)
+	if end <= start {
+		return nil
+	}
 
-		trades := make([]string, 0, len(cs.orderTrades))
+	trades := make([]string, end-start)
+	copy(trades, cs.orderTrades[start:end])
 
-		if maxIterations == -1 {
-			maxIterations = cs.count
-		}
+	return trades
+}
 
-		for i := 0; (i < len(cs.orderTrades)) && (i < maxIterations); i++ {
-			trades = append(trades, cs.orderTrades[i])
-		}
+// CountByInterval returns the number of trades opened in each interval of [from, to), the last interval is
+// shorter when the range is not a multiple of the interval.
+func (cs *sortedTrades) CountByInterval(from, to time.Time, interval time.Duration) []int {
+
+	if interval <= 0 || !from.Before(to) {
+		return nil
+	}
+
+	cs.RLock()
+	defer cs.RUnlock()
 
-		cs.RUnlock()
+	counts := make([]int, (to.Sub(from)+interval-1)/interval)
 
-		for _, t := range trades {
-			c <- t
+	previous := cs.search(from)
+	for i := range counts {
+		end := from.Add(time.Duration(i+1) * interval)
+		if end.After(to) {
+			end = to
 		}
 
-		close(c)
-	}()
+		next := cs.search(end)
+		counts[i] = next - previous
+		previous = next
+	}
 
-	return c
+	return counts
 }
 
-func (cs *sortedTrades) DescendIter(maxIterations int) <-chan string {
-	c := make(chan string)
+// Ascend returns up to maxIterations trade ids from the oldest, all of them when maxIterations is -1.
+func (cs *sortedTrades) Ascend(maxIterations int) []string {
+	cs.RLock()
+	defer cs.RUnlock()
 
-	go func() {
-		cs.RLock()
+	trades := make([]string, 0, len(cs.orderTrades))
 
-		trades := make([]string, 0, len(cs.orderTrades))
+	if maxIterations == -1 {
+		maxIterations = cs.count
+	}
 
-		if maxIterations == -1 {
-			maxIterations = cs.count
-		}
+	for i := 0; (i < len(cs.orderTrades)) && (i < maxIterations); i++ {
+		trades = append(trades, cs.orderTrades[i])
+	}
 
-		for i := len(cs.orderTrades) - 1; (i >= 0) && (i > -maxIterations+len(cs.orderTrades)-1); i-- {
-			trades = append(trades, cs.orderTrades[i])
-		}
+	return trades
+}
 
-		cs.RUnlock()
+// Descend returns up to maxIterations trade ids from the newest, all of them when maxIterations is -1.
+func (cs *sortedTrades) Descend(maxIterations int) []string {
+	cs.RLock()
+	defer cs.RUnlock()
 
-		for _, t := range trades {
-			c <- t
-		}
+	trades := make([]string, 0, len(cs.orderTrades))
+
+	if maxIterations == -1 {
+		maxIterations = cs.count
+	}
 
-		close(c)
-	}()
+	for i := len(cs.orderTrades) - 1; (i >= 0) && (i > -maxIterations+len(cs.orderTrades)-1); i-- {
+		trades = append(trades, cs.orderTrades[i])
+	}
 
-	return c
+	return trades
 }
 
 func (cs *sortedTrades) Get(index int) string {
@@ -103,7 +153,11 @@ func (cs *sortedTrades) Delete(item string) {
 	cs.Lock()
 	defer cs.Unlock()
 
-	itemIndex := cs.tradesOrder[item]
+	itemIndex, exist := cs.tradesOrder[item]
+	if !exist {
+		return
+	}
+
 	delete(cs.tradesOrder, item)
 	for k, v := range cs.tradesOrder {
 		if v > itemIndex {
@@ -111,6 +165,7 @@ func (cs *sortedTrades) Delete(item string) {
 		}
 	}
 	cs.orderTrades = cs.orderTrades[:itemIndex+copy(cs.orderTrades[itemIndex:], cs.orderTrades[itemIndex+1:])]
+	cs.openTimes = cs.openTimes[:itemIndex+copy(cs.openTimes[itemIndex:], cs.openTimes[itemIndex+1:])]
 	cs.count--
 }
 
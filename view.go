@@ -0,0 +1,270 @@
+package gotrader
+
+import (
+	"runtime"
+	"sort"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// viewAttempts is the number of times Account.View builds a view before returning one the writes overlapped.
+const viewAttempts = 64
+
+/*
+AccountView is a consistent, read only copy of an account: its totals, the state and the open trades of every
+instrument and the ledger, for the reporting goroutines that scan thousands of trades.
+
+The views of the instruments are versioned and shared: an instrument not recalculated since the previous view
+is not copied again, the view of the last calculation is reused. The transactions share the backing array of the
+ledger, which is only appended to, so they must not be modified.
+*/
+type AccountView struct {
+	Time                      time.Time
+	HomeCurrency              string
+	Balance                   float64
+	Equity                    float64
+	UnrealizedNetProfit       float64
+	UnrealizedEffectiveProfit float64
+	MarginUsed                float64
+	MarginFree                float64
+	ChargedFees               float64
+	Instruments               []*InstrumentView // by name order
+	Transactions              []*Transaction    // by time order
+}
+
+// InstrumentView is the state of an instrument with its open trades, as of the same calculation of the metrics.
+type InstrumentView struct {
+	InstrumentState
+	BaseCurrency  string
+	QuoteCurrency string
+	Version       uint64       // of the calculation, views of the same version are the same
+	Trades        []TradeState // by open time order
+}
+
+// TradeState is a copy of an open trade, see Trade.State. In an InstrumentView the metrics are at its prices.
+type TradeState struct {
+	ID                        string
+	Side                      Side
+	Units                     int32
+	OpenPrice                 float64
+	OpenTime                  time.Time
+	CurrentPrice              float64
+	UnrealizedNetProfit       float64
+	UnrealizedEffectiveProfit float64
+	MarginUsed                float64
+	ChargedFees               float64
+	StopLoss                  float64
+	Guaranteed                bool
+	TakeProfit                float64
+	Expiry                    time.Time
+	Venue                     string
+	Tag                       string
+}
+
+// viewGuard counts the writes that change the account in several steps, a trade close credits the balance,
+// records the transaction and closes the trade, so the views built while one is in progress are built again.
+// Several goroutines of the live engine write, hence the two counters instead of a sequence parity.
+type viewGuard struct {
+	started  atomic.Uint64
+	finished atomic.Uint64
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+func (g *viewGuard) begin() {
+	g.started.Inc()
+}
+
+func (g *viewGuard) end() {
+	g.finished.Inc()
+}
+
+// idle returns the writes started, and whether none is in progress.
+func (g *viewGuard) idle() (uint64, bool) {
+	started := g.started.Load()
+	return started, g.finished.Load() == started
+}
+
+// amend runs f with the lock held, for the changes of the trades book-keeping that the metrics don't depend on,
+// and drops the view of the instrument.
+func (i *Instrument) amend(f func()) {
+	i.acquire()
+	defer i.lock.Unlock()
+
+	f()
+	i.view.Store((*InstrumentView)(nil))
+}
+
+// buildView copies the state and the trades of the instrument under the read lock, unless the view of its
+// version is cached. A cached view is a copy, the one returned while a calculation is in progress is the one of
+// the state before it.
+func (i *Instrument) buildView() *InstrumentView {
+
+	if cached, _ := i.view.Load().(*InstrumentView); cached != nil && cached.Version == i.version.Load() {
+		return cached
+	}
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+
+	view := &InstrumentView{
+		InstrumentState: i.state(),
+		BaseCurrency:    i.baseCurrency,
+		QuoteCurrency:   i.quoteCurrency,
+		Version:         i.version.Load(),
+	}
+
+	trades := lookupTrades(i.trades, i.tradesTimeOrder.Ascend(-1))
+	view.Trades = make([]TradeState, len(trades))
+
+	for n, trade := range trades {
+
+		price := i.calculatedPrices.bid
+		if trade.side == Short {
+			price = i.calculatedPrices.ask
+		}
+
+		view.Trades[n] = trade.state(price)
+	}
+
+	i.view.Store(view)
+
+	return view
+}
+
+// state returns a copy of the trade with its current price, the caller holds the instrument lock.
+func (t *Trade) state(price float64) TradeState {
+	return TradeState{
+		ID:                        t.id,
+		Side:                      t.side,
+		Units:                     t.units,
+		OpenPrice:                 t.openPrice,
+		OpenTime:                  t.openTime,
+		CurrentPrice:              price,
+		UnrealizedNetProfit:       t.unrealizedNetProfit.Float64(),
+		UnrealizedEffectiveProfit: t.unrealizedEffectiveProfit.Float64(),
+		MarginUsed:                t.marginUsed.Float64(),
+		ChargedFees:               t.chargedFees.Load().Float64(),
+		StopLoss:                  t.stopLoss,
+		Guaranteed:                t.guaranteedStop,
+		TakeProfit:                t.takeProfit,
+		Expiry:                    t.expiry,
+		Venue:                     t.venue,
+		Tag:                       t.tag,
+	}
+}
+
+// buildView copies the account once, the writes may overlap it.
+func (a *Account) buildView() *AccountView {
+
+	a.ledger.RLock()
+	transactions := a.ledger.transactions[:len(a.ledger.transactions):len(a.ledger.transactions)]
+	a.ledger.RUnlock()
+
+	view := &AccountView{
+		Time:         a.Time(),
+		HomeCurrency: a.homeCurrency,
+		Balance:      a.balance.Load().Float64(),
+		Instruments:  make([]*InstrumentView, 0, len(a.instruments)),
+		Transactions: transactions,
+	}
+
+	var unrealizedNetProfit, unrealizedEffectiveProfit, marginUsed, chargedFees Decimal
+
+	for _, instrument := range a.instruments {
+
+		iv := instrument.buildView()
+		view.Instruments = append(view.Instruments, iv)
+
+		unrealizedNetProfit = unrealizedNetProfit.Add(NewDecimal(iv.UnrealizedNetProfit))
+		unrealizedEffectiveProfit = unrealizedEffectiveProfit.Add(NewDecimal(iv.UnrealizedEffectiveProfit))
+		marginUsed = marginUsed.Add(NewDecimal(iv.MarginUsed))
+		chargedFees = chargedFees.Add(NewDecimal(iv.ChargedFees))
+	}
+
+	sort.Slice(view.Instruments, func(i, j int) bool { return view.Instruments[i].Name < view.Instruments[j].Name })
+
+	equity := unrealizedNetProfit.Add(NewDecimal(view.Balance))
+
+	view.Equity = equity.Float64()
+	view.UnrealizedNetProfit = unrealizedNetProfit.Float64()
+	view.UnrealizedEffectiveProfit = unrealizedEffectiveProfit.Float64()
+	view.MarginUsed = marginUsed.Float64()
+	view.MarginFree = equity.Sub(marginUsed).Float64()
+	view.ChargedFees = chargedFees.Float64()
+
+	return view
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+/*
+View returns a consistent view of the account, safe to call from any goroutine while the session runs.
+
+It doesn't stop the tick path: the instruments are copied one at a time under their read locks, which a price
+update only waits for while the trades of one instrument are copied, and the instruments not recalculated since
+the last view aren't copied at all. The view is built again when a trade open or close, or a balance transaction,
+happened meanwhile; after a few attempts on an account that never stops writing, the last one is returned.
+*/
+func (a *Account) View() *AccountView {
+
+	var view *AccountView
+
+	for attempt := 0; attempt < viewAttempts; attempt++ {
+
+		started, idle := a.writes.idle()
+		if !idle {
+			runtime.Gosched()
+			continue
+		}
+
+		view = a.buildView()
+
+		if a.writes.started.Load() == started {
+			return view
+		}
+	}
+
+	if view == nil {
+		view = a.buildView()
+	}
+
+	return view
+}
+
+// View returns the state of the instrument with its open trades, see Account.View.
+func (i *Instrument) View() *InstrumentView {
+	return i.buildView()
+}
+
+// Instrument returns the view of an instrument, nil when the account doesn't have it.
+func (v *AccountView) Instrument(name string) *InstrumentView {
+
+	for _, iv := range v.Instruments {
+		if iv.Name == name {
+			return iv
+		}
+	}
+
+	return nil
+}
+
+// TradesNumber returns the number of open trades in the view.
+func (v *AccountView) TradesNumber() int {
+
+	n := 0
+	for _, iv := range v.Instruments {
+		n += len(iv.Trades)
+	}
+
+	return n
+}
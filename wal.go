@@ -0,0 +1,520 @@
+package gotrader
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// WALOperation identifies the account mutation of a WALEntry.
+type WALOperation int
+
+const (
+	WALOpenTrade        WALOperation = iota // a trade is opened
+	WALCloseTrade                           // a trade is closed, with its transaction unless it was closed by a resync
+	WALFinancing                            // a trade is charged, with its transaction
+	WALFunds                                // funds are transferred, with its transaction
+	WALAdjustment                           // the balance is adjusted, with its transaction
+	WALAdjustTrade                          // the units and prices of a trade are adjusted by a split
+	WALRenameInstrument                     // an instrument is renamed by a ticker change, from Renamed
+	WALDividend                             // a trade is adjusted by a dividend, with its transaction
+	WALFee                                  // a fee of the managed account is accrued, with its transaction
+)
+
+func (o WALOperation) String() string {
+	switch o {
+	case WALOpenTrade:
+		return "OPEN_TRADE"
+	case WALCloseTrade:
+		return "CLOSE_TRADE"
+	case WALFinancing:
+		return "FINANCING"
+	case WALFunds:
+		return "FUNDS"
+	case WALAdjustment:
+		return "ADJUSTMENT"
+	case WALAdjustTrade:
+		return "ADJUST_TRADE"
+	case WALRenameInstrument:
+		return "RENAME_INSTRUMENT"
+	case WALDividend:
+		return "DIVIDEND"
+	case WALFee:
+		return "FEE"
+	}
+
+	return "UNKNOWN"
+}
+
+// WALEntry is an account mutation appended to the write-ahead log before it is applied. The Balance of the
+// transactions is not logged, it is recalculated when the entries are replayed.
+type WALEntry struct {
+	Sequence    uint64
+	Operation   WALOperation
+	Time        time.Time
+	Instrument  string       `json:",omitempty"`
+	TradeID     string       `json:",omitempty"`
+	Side        Side         `json:",omitempty"`
+	Units       int32        `json:",omitempty"`
+	Price       float64      `json:",omitempty"`
+	Fees        float64      `json:",omitempty"`
+	StopLoss    float64      `json:",omitempty"`
+	Guaranteed  bool         `json:",omitempty"`
+	TakeProfit  float64      `json:",omitempty"`
+	Expiry      time.Time    `json:",omitempty"`
+	Venue       string       `json:",omitempty"`
+	Tag         string       `json:",omitempty"`
+	Renamed     string       `json:",omitempty"`
+	Transaction *Transaction `json:",omitempty"`
+	Accruals    *FeeSnapshot `json:",omitempty"` // of a WALFee entry, after its fee
+}
+
+// WALOption represents a WAL functional option
+type WALOption func(w *WAL)
+
+// NoSync is the functional option to not fsync the log on every append, trading durability for speed,
+// e.g. for backtests.
+func NoSync() WALOption {
+	return func(w *WAL) {
+		w.sync = false
+	}
+}
+
+/*
+WAL is the write-ahead log of the account mutations. Every trade open and close, financing charge and funds
+transfer is appended (and synced) before the in-memory state is changed, so after a crash the session
+restores its last snapshot and replays the entries logged after it to rebuild the exact state.
+
+Each entry is framed by its length and CRC32, a torn entry at the end of the log (a crash while writing it)
+is discarded when the log is opened.
+*/
+type WAL struct {
+	mutex    *sync.Mutex
+	file     *os.File
+	path     string
+	sequence uint64
+	sync     bool
+}
+
+// OpenWAL opens (or creates) the write-ahead log at path.
+func OpenWAL(path string, opts ...WALOption) (*WAL, error) {
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WAL{
+		mutex: &sync.Mutex{},
+		file:  file,
+		path:  path,
+		sync:  true,
+	}
+
+	for _, o := range opts {
+		o(w)
+	}
+
+	entries, size, err := readWAL(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if len(entries) > 0 {
+		w.sequence = entries[len(entries)-1].Sequence
+	}
+
+	if err := file.Truncate(size); err != nil { // drops the torn tail
+		file.Close()
+		return nil, err
+	}
+
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+/**************************
+*
+*	Internal Methods
+*
+***************************/
+
+// readFrames reads the framed bodies from the start of a log until its end or the first torn or corrupt frame,
+// returning the size of the valid frames.
+func readFrames(r io.ReadSeeker) ([][]byte, int64, error) {
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	reader := bufio.NewReader(r)
+	bodies := make([][]byte, 0)
+	header := make([]byte, 8)
+
+	var size int64
+
+	for {
+
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break
+		}
+
+		length := binary.BigEndian.Uint32(header[:4])
+		checksum := binary.BigEndian.Uint32(header[4:])
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			break
+		}
+
+		if crc32.ChecksumIEEE(body) != checksum {
+			break
+		}
+
+		bodies = append(bodies, body)
+		size += int64(len(header) + len(body))
+	}
+
+	return bodies, size, nil
+}
+
+// frame prefixes a body with its length and CRC32.
+func frame(body []byte) []byte {
+
+	frame := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(frame[:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(frame[4:], crc32.ChecksumIEEE(body))
+
+	return append(frame, body...)
+}
+
+// readWAL decodes the entries from the start of the log until its end or the first torn or corrupt entry,
+// returning the size of the valid entries.
+func readWAL(r io.ReadSeeker) ([]*WALEntry, int64, error) {
+
+	bodies, _, err := readFrames(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]*WALEntry, 0, len(bodies))
+
+	var size int64
+
+	for _, body := range bodies {
+
+		entry := &WALEntry{}
+		if err := json.Unmarshal(body, entry); err != nil {
+			break
+		}
+
+		entries = append(entries, entry)
+		size += int64(8 + len(body))
+	}
+
+	return entries, size, nil
+}
+
+func encodeWALEntry(entry *WALEntry) ([]byte, error) {
+
+	body, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return frame(body), nil
+}
+
+// append logs an entry, assigning its sequence. A nil WAL logs nothing.
+func (w *WAL) append(entry *WALEntry) error {
+
+	if w == nil {
+		return nil
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	entry.Sequence = w.sequence + 1
+
+	frame, err := encodeWALEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.file.Write(frame); err != nil {
+		return err
+	}
+
+	if w.sync {
+		if err := w.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	w.sequence = entry.Sequence
+
+	return nil
+}
+
+// replayWAL replays the entries of the log after the snapshot, if there is a log.
+func (a *Account) replayWAL(wal *WAL, snapshot *Snapshot, hydrated bool) error {
+
+	if wal == nil {
+		return nil
+	}
+
+	entries, err := wal.Entries()
+	if err != nil {
+		return err
+	}
+
+	var after uint64
+	if snapshot != nil {
+		after = snapshot.WALSequence
+	}
+
+	a.replay(entries, after, time.Time{}, hydrated)
+
+	return nil
+}
+
+// write appends an entry, logging the error: the mutation is applied anyway, since the broker already did it.
+func (w *WAL) write(entry *WALEntry, logger Logger) {
+	if err := w.append(entry); err != nil {
+		logger.Errorf("write-ahead log %s: %v", entry.Operation, err)
+	}
+}
+
+// replay applies the entries logged after the sequence to the account, up to until when it is not zero. When
+// hydrated is true the open trades come from the broker, so only the ledger and the book-keeping of the open
+// trades are replayed.
+func (a *Account) replay(entries []*WALEntry, after uint64, until time.Time, hydrated bool) {
+
+	a.ledger.Lock()
+	defer a.ledger.Unlock()
+
+	// the broker balance of hydrated accounts already has the amounts of the entries
+	balance := NewDecimal(a.ledger.openingBalance)
+	if n := len(a.ledger.transactions); n > 0 {
+		balance = NewDecimal(a.ledger.transactions[n-1].Balance)
+	}
+
+	record := func(entry *WALEntry) {
+		if entry.Transaction == nil {
+			return
+		}
+
+		transaction := *entry.Transaction
+		if hydrated {
+			balance = balance.Add(NewDecimal(transaction.Amount))
+			transaction.Balance = balance.Float64()
+		} else {
+			transaction.Balance = a.balance.Add(NewDecimal(transaction.Amount)).Float64()
+		}
+		a.ledger.transactions = append(a.ledger.transactions, &transaction)
+		a.realize(&transaction)
+	}
+
+	// the instruments renamed by ticker changes are traded with their last name
+	renames := make(map[string]string)
+	for _, entry := range entries {
+		if entry.Operation == WALRenameInstrument && (until.IsZero() || !entry.Time.After(until)) {
+			renames[entry.Renamed] = entry.Instrument
+		}
+	}
+
+	for _, entry := range entries {
+
+		if entry.Sequence <= after || !until.IsZero() && entry.Time.After(until) {
+			continue
+		}
+
+		name := entry.Instrument
+		for i := 0; i < len(renames); i++ {
+			renamed, exist := renames[name]
+			if !exist {
+				break
+			}
+			name = renamed
+		}
+
+		inst := a.instruments[name]
+		if inst == nil {
+			inst = a.instruments[entry.Instrument]
+		}
+
+		switch entry.Operation {
+		case WALOpenTrade:
+			if inst == nil {
+				continue
+			}
+
+			trade := inst.Trade(entry.TradeID)
+			if trade == nil {
+				if hydrated {
+					continue
+				}
+				trade = inst.openTrade(entry.TradeID, entry.Side, entry.Time, entry.Units, entry.Price)
+				trade.charge(UnitemizedFee, NewDecimal(entry.Fees))
+			}
+
+			trade.stopLoss = entry.StopLoss
+			trade.guaranteedStop = entry.Guaranteed
+			trade.takeProfit = entry.TakeProfit
+			trade.expiry = entry.Expiry
+			if trade.venue == "" {
+				trade.venue = entry.Venue
+			}
+			if trade.tag == "" {
+				trade.tag = entry.Tag
+			}
+		case WALCloseTrade:
+			if inst != nil && !hydrated {
+				inst.closeTrade(entry.TradeID)
+			}
+			record(entry)
+		case WALFinancing:
+			if inst != nil && !hydrated {
+				if trade := inst.Trade(entry.TradeID); trade != nil {
+					trade.charge(FinancingFee, NewDecimal(entry.Fees))
+				}
+			}
+			record(entry)
+		case WALAdjustTrade:
+			if inst != nil && !hydrated {
+				inst.adjustTrade(entry.TradeID, entry.Units, entry.Price, entry.StopLoss, entry.TakeProfit)
+			}
+		case WALFee:
+			a.fees.restore(entry.Accruals)
+			record(entry)
+		case WALFunds, WALAdjustment, WALDividend:
+			record(entry)
+		}
+	}
+
+	a.restoreSpreadCost()
+}
+
+func (w *WAL) entries() ([]*WALEntry, error) {
+
+	file, err := os.Open(w.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, _, err := readWAL(file)
+
+	return entries, err
+}
+
+/**************************
+*
+*	Accessible Methods
+*
+***************************/
+
+// Sequence returns the sequence of the last entry logged, 0 for a nil WAL.
+func (w *WAL) Sequence() uint64 {
+
+	if w == nil {
+		return 0
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.sequence
+}
+
+// Entries returns the entries of the log.
+func (w *WAL) Entries() ([]*WALEntry, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.entries()
+}
+
+/*
+Compact rewrites the log without the entries already in the snapshot, it should be called after the snapshot
+has been saved. The log is replaced atomically, so a crash while compacting keeps the previous log.
+*/
+func (w *WAL) Compact(snapshot *Snapshot) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	entries, err := w.entries()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.OpenFile(w.path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+
+		if entry.Sequence <= snapshot.WALSequence {
+			continue
+		}
+
+		frame, err := encodeWALEntry(entry)
+		if err == nil {
+			_, err = tmp.Write(frame)
+		}
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := os.Rename(w.path+".tmp", w.path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	w.file.Close()
+	w.file = tmp
+
+	_, err = w.file.Seek(0, io.SeekEnd)
+
+	return err
+}
+
+// Close closes the log file.
+func (w *WAL) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.file.Close()
+}
+
+// ReplayWAL applies the entries logged after the snapshot (every entry for a nil snapshot) to an account rebuilt
+// with RestoreAccount, so it has the state of the last entry.
+func ReplayWAL(account *Account, snapshot *Snapshot, wal *WAL) error {
+
+	if err := account.replayWAL(wal, snapshot, false); err != nil {
+		return err
+	}
+
+	account.calculate()
+
+	return nil
+}
@@ -0,0 +1,214 @@
+package gotrader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWAL(t *testing.T) {
+
+	now := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	base := &Snapshot{
+		Version:        SnapshotVersion,
+		AccountID:      "account",
+		HomeCurrency:   "USD",
+		Balance:        1000,
+		Leverage:       30,
+		OpeningBalance: 1000,
+		Instruments: []*InstrumentSnapshot{{
+			Name: "EUR_USD", BaseCurrency: "EUR", QuoteCurrency: "USD", Leverage: 30, PipLocation: -4,
+			Bid: 1.1, Ask: 1.1002, BaseConversionRate: 1.1, QuoteConversionRate: 1,
+		}},
+	}
+
+	entries := func() []*WALEntry {
+		return []*WALEntry{
+			{Operation: WALOpenTrade, Time: now, Instrument: "EUR_USD", TradeID: "1", Side: Long, Units: 1000,
+				Price: 1.1002, Fees: -0.5, StopLoss: 1.09, Tag: "a"},
+			{Operation: WALOpenTrade, Time: now.Add(time.Minute), Instrument: "EUR_USD", TradeID: "2", Side: Short,
+				Units: 500, Price: 1.1},
+			{Operation: WALFinancing, Time: now.Add(time.Hour), Instrument: "EUR_USD", TradeID: "1", Fees: -0.2,
+				Transaction: &Transaction{Type: FinancingTransaction, TradeID: "1", Instrument: "EUR_USD", Amount: -0.2,
+					Time: now.Add(time.Hour)}},
+			{Operation: WALFunds, Time: now.Add(2 * time.Hour),
+				Transaction: &Transaction{Type: FundsTransferTransaction, Amount: 500, Time: now.Add(2 * time.Hour)}},
+			{Operation: WALCloseTrade, Time: now.Add(3 * time.Hour), Instrument: "EUR_USD", TradeID: "2",
+				Transaction: &Transaction{Type: TradeCloseTransaction, TradeID: "2", Instrument: "EUR_USD", Side: Short,
+					Units: 500, OpenPrice: 1.1, ClosePrice: 1.097, Amount: 1.5, Time: now.Add(3 * time.Hour)}},
+			{Operation: WALFee, Time: now.Add(4 * time.Hour),
+				Transaction: &Transaction{Type: ManagementFeeTransaction, Amount: -0.1, Time: now.Add(4 * time.Hour)}},
+		}
+	}
+
+	// logged writes the entries to a new log, then tears a last entry as a crash while writing it would.
+	logged := func(t *testing.T) string {
+
+		t.Helper()
+
+		path := filepath.Join(t.TempDir(), "account.wal")
+
+		wal, err := OpenWAL(path, NoSync())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, entry := range entries() {
+			if err := wal.append(entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		torn, _ := encodeWALEntry(&WALEntry{Sequence: 7, Operation: WALFunds, Time: now.Add(5 * time.Hour),
+			Transaction: &Transaction{Type: FundsTransferTransaction, Amount: -200, Time: now.Add(5 * time.Hour)}})
+
+		if _, err := wal.file.Write(torn[:len(torn)-7]); err != nil {
+			t.Fatal(err)
+		}
+
+		wal.Close()
+
+		return path
+	}
+
+	open := func(t *testing.T, path string) *WAL {
+		t.Helper()
+		wal, err := OpenWAL(path, NoSync())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { wal.Close() })
+		return wal
+	}
+
+	// state returns the snapshot of an account with its trades by ID, without the log sequence.
+	state := func(a *Account) *Snapshot {
+		s := a.Snapshot()
+		s.WALSequence = 0
+		for _, is := range s.Instruments {
+			sort.Slice(is.Trades, func(i, j int) bool { return is.Trades[i].ID < is.Trades[j].ID })
+		}
+		return s
+	}
+
+	t.Run("torn entries are discarded", func(t *testing.T) {
+
+		path := logged(t)
+		wal := open(t, path)
+
+		replayed, err := wal.Entries()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(replayed) != 6 || wal.Sequence() != 6 {
+			t.Fatalf("expected the 6 complete entries, got %d and the sequence %d", len(replayed), wal.Sequence())
+		}
+
+		if err := wal.append(&WALEntry{Operation: WALAdjustment, Time: now.Add(6 * time.Hour),
+			Transaction: &Transaction{Type: BalanceAdjustmentTransaction, Amount: 1}}); err != nil {
+			t.Fatal(err)
+		}
+
+		wal.Close()
+
+		if replayed, _ = open(t, path).Entries(); len(replayed) != 7 || replayed[6].Sequence != 7 ||
+			replayed[6].Operation != WALAdjustment {
+			t.Errorf("expected the entry appended after the torn one, got %d entries", len(replayed))
+		}
+	})
+
+	t.Run("corrupt entries end the log", func(t *testing.T) {
+
+		path := logged(t)
+
+		data, _ := os.ReadFile(path)
+		second := bytes.Index(data[8:], []byte(`{"Sequence":2`)) + 8
+		data[second+20] ^= 0xff // its checksum fails
+
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if replayed, _ := open(t, path).Entries(); len(replayed) != 1 || replayed[0].TradeID != "1" {
+			t.Errorf("expected the entry before the corrupt one, got %d entries", len(replayed))
+		}
+	})
+
+	t.Run("the log is replayed after a crash", func(t *testing.T) {
+
+		account := RestoreAccount(base, NopLogger())
+		if err := ReplayWAL(account, nil, open(t, logged(t))); err != nil {
+			t.Fatal(err)
+		}
+
+		if account.Balance() != 1501.2 || account.Instrument("EUR_USD").TradesNumber() != 1 {
+			t.Errorf("expected the balance and the trade of the entries, got %f and %d trades",
+				account.Balance(), account.Instrument("EUR_USD").TradesNumber())
+		}
+
+		trade := account.Instrument("EUR_USD").Trade("1")
+		if trade == nil || trade.ChargedFees() != -0.7 || trade.StopLoss() != 1.09 || trade.Tag() != "a" {
+			t.Errorf("expected the trade with its fees and exits, got %+v", trade)
+		}
+
+		if transactions := account.Ledger().Transactions(); len(transactions) != 4 ||
+			transactions[3].Balance != 1501.2 {
+			t.Errorf("expected the transactions with their balances, got %v", transactions)
+		}
+	})
+
+	t.Run("a snapshot and the log give the state of the log", func(t *testing.T) {
+
+		wal := open(t, logged(t))
+
+		replayed := RestoreAccount(base, NopLogger())
+		if err := ReplayWAL(replayed, nil, wal); err != nil {
+			t.Fatal(err)
+		}
+
+		all, err := wal.Entries()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, sequence := range []uint64{0, 1, 3, 5, 6} {
+
+			before := RestoreAccount(base, NopLogger()) // the state of the entries up to the snapshot
+			before.replay(all[:sequence], 0, time.Time{}, false)
+			before.calculate()
+
+			snapshot := before.Snapshot()
+			snapshot.WALSequence = sequence
+
+			var buffer bytes.Buffer
+			if err := WriteSnapshot(&buffer, snapshot); err != nil {
+				t.Fatal(err)
+			}
+
+			persisted, err := ReadSnapshot(&buffer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			restored := RestoreAccount(persisted, NopLogger())
+			if err := ReplayWAL(restored, persisted, wal); err != nil {
+				t.Fatal(err)
+			}
+
+			if expected, got := state(replayed), state(restored); !reflect.DeepEqual(expected, got) {
+				t.Errorf("snapshot at %d: expected %+v, got %+v", sequence, expected, got)
+			}
+
+			if restored.Equity() != replayed.Equity() || restored.MarginUsed() != replayed.MarginUsed() {
+				t.Errorf("snapshot at %d: expected the equity %f and margin %f, got %f and %f", sequence,
+					replayed.Equity(), replayed.MarginUsed(), restored.Equity(), restored.MarginUsed())
+			}
+		}
+	})
+}
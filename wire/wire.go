@@ -0,0 +1,234 @@
+/*
+Package wire is a compact binary encoding of ticks, for the low-latency distribution of a price feed between
+gotrader processes, e.g. one process connected to the broker feeding several strategy processes.
+
+Each tick is a fixed-width record of TickSize bytes, big-endian:
+
+	instrument id  uint16
+	bid            float64 (IEEE 754 bits)
+	ask            float64 (IEEE 754 bits)
+	time           int64   (unix nanoseconds, 0 for the zero time)
+
+The instruments are sent by id, so both ends must share the same Symbols table. The ticks are written in
+batches, each framed by a BatchHeaderSize header with the number of ticks of the batch, so a batch is read
+with two reads and decoded without allocations. The sizes of the ticks are not encoded.
+*/
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+const (
+	// TickSize is the size of an encoded tick in bytes.
+	TickSize = 26
+
+	// BatchHeaderSize is the size of the header of a batch in bytes.
+	BatchHeaderSize = 2
+
+	// MaxBatchSize is the maximum number of ticks of a batch.
+	MaxBatchSize = math.MaxUint16
+)
+
+// ErrBatchSize is returned when encoding more than MaxBatchSize ticks in a batch.
+var ErrBatchSize = errors.New("batch larger than " + strconv.Itoa(MaxBatchSize) + " ticks")
+
+// Symbols maps the instruments to their ids on the wire, the id of an instrument is its index.
+type Symbols struct {
+	names []string
+	ids   map[string]uint16
+}
+
+// NewSymbols is the Symbols constructor, the instruments get their ids by the given order.
+func NewSymbols(instruments ...string) (*Symbols, error) {
+
+	if len(instruments) > math.MaxUint16+1 {
+		return nil, errors.New("too many instruments")
+	}
+
+	s := &Symbols{
+		names: make([]string, 0, len(instruments)),
+		ids:   make(map[string]uint16, len(instruments)),
+	}
+
+	for _, name := range instruments {
+
+		if _, exist := s.ids[name]; exist {
+			return nil, errors.New("duplicated instrument " + name)
+		}
+
+		s.ids[name] = uint16(len(s.names))
+		s.names = append(s.names, name)
+	}
+
+	return s, nil
+}
+
+// ID returns the id of an instrument, false if the instrument is unknown.
+func (s *Symbols) ID(instrument string) (uint16, bool) {
+	id, exist := s.ids[instrument]
+	return id, exist
+}
+
+// Name returns the instrument of an id, false if the id is unknown.
+func (s *Symbols) Name(id uint16) (string, bool) {
+
+	if int(id) >= len(s.names) {
+		return "", false
+	}
+
+	return s.names[id], true
+}
+
+// Instruments returns the instruments by id order.
+func (s *Symbols) Instruments() []string {
+	return append([]string(nil), s.names...)
+}
+
+/**************************
+*
+*	Records
+*
+***************************/
+
+// PutTick encodes a tick in the first TickSize bytes of b, with the instrument id.
+func PutTick(b []byte, id uint16, tick *gotrader.Tick) {
+
+	var nanos int64
+	if !tick.Time.IsZero() {
+		nanos = tick.Time.UnixNano()
+	}
+
+	_ = b[TickSize-1]
+	binary.BigEndian.PutUint16(b[0:], id)
+	binary.BigEndian.PutUint64(b[2:], math.Float64bits(tick.Bid))
+	binary.BigEndian.PutUint64(b[10:], math.Float64bits(tick.Ask))
+	binary.BigEndian.PutUint64(b[18:], uint64(nanos))
+}
+
+// ReadTick decodes a tick from the first TickSize bytes of b, returning its instrument id. The instrument name
+// of the tick is not set, see Symbols.Name.
+func ReadTick(b []byte, tick *gotrader.Tick) uint16 {
+
+	_ = b[TickSize-1]
+	id := binary.BigEndian.Uint16(b[0:])
+	tick.Bid = math.Float64frombits(binary.BigEndian.Uint64(b[2:]))
+	tick.Ask = math.Float64frombits(binary.BigEndian.Uint64(b[10:]))
+	tick.Time = time.Time{}
+
+	if nanos := int64(binary.BigEndian.Uint64(b[18:])); nanos != 0 {
+		tick.Time = time.Unix(0, nanos).UTC()
+	}
+
+	return id
+}
+
+/**************************
+*
+*	Batches
+*
+***************************/
+
+// Encoder writes batches of ticks.
+type Encoder struct {
+	w       io.Writer
+	symbols *Symbols
+	buffer  []byte
+}
+
+// NewEncoder is the Encoder constructor.
+func NewEncoder(w io.Writer, symbols *Symbols) *Encoder {
+	return &Encoder{w: w, symbols: symbols}
+}
+
+// Encode writes the ticks as a batch with a single write, failing without writing anything when an instrument
+// is unknown.
+func (e *Encoder) Encode(ticks ...*gotrader.Tick) error {
+
+	if len(ticks) > MaxBatchSize {
+		return ErrBatchSize
+	}
+
+	size := BatchHeaderSize + len(ticks)*TickSize
+	if cap(e.buffer) < size {
+		e.buffer = make([]byte, size)
+	}
+
+	b := e.buffer[:size]
+	binary.BigEndian.PutUint16(b, uint16(len(ticks)))
+
+	for i, tick := range ticks {
+
+		id, exist := e.symbols.ID(tick.Instrument)
+		if !exist {
+			return errors.New("unknown instrument " + tick.Instrument)
+		}
+
+		PutTick(b[BatchHeaderSize+i*TickSize:], id, tick)
+	}
+
+	_, err := e.w.Write(b)
+
+	return err
+}
+
+// Decoder reads batches of ticks.
+type Decoder struct {
+	r       io.Reader
+	symbols *Symbols
+	header  [BatchHeaderSize]byte
+	buffer  []byte
+}
+
+// NewDecoder is the Decoder constructor, r should be buffered when it is not a stream of whole batches.
+func NewDecoder(r io.Reader, symbols *Symbols) *Decoder {
+	return &Decoder{r: r, symbols: symbols}
+}
+
+// Decode reads the next batch, appending its ticks to dst[:0] so the ticks of a batch can reuse the slice of
+// the previous one. It returns io.EOF at the end of the stream, io.ErrUnexpectedEOF for a truncated batch.
+func (d *Decoder) Decode(dst []gotrader.Tick) ([]gotrader.Tick, error) {
+
+	dst = dst[:0]
+
+	if _, err := io.ReadFull(d.r, d.header[:]); err != nil {
+		return dst, err
+	}
+
+	size := int(binary.BigEndian.Uint16(d.header[:])) * TickSize
+	if cap(d.buffer) < size {
+		d.buffer = make([]byte, size)
+	}
+
+	b := d.buffer[:size]
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return dst, err
+	}
+
+	for offset := 0; offset < size; offset += TickSize {
+
+		var tick gotrader.Tick
+
+		id := ReadTick(b[offset:], &tick)
+
+		name, exist := d.symbols.Name(id)
+		if !exist {
+			return dst, errors.New("unknown instrument id " + strconv.Itoa(int(id)))
+		}
+
+		tick.Instrument = name
+		dst = append(dst, tick)
+	}
+
+	return dst, nil
+}
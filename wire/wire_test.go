@@ -0,0 +1,82 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/luismcruz/gotrader"
+)
+
+func TestWire(t *testing.T) {
+
+	symbols, err := NewSymbols("EUR_USD", "GBP_USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 1, 2, 10, 0, 0, 123, time.UTC)
+	buffer := &bytes.Buffer{}
+	encoder := NewEncoder(buffer, symbols)
+
+	t.Run("batches round trip", func(t *testing.T) {
+
+		err := encoder.Encode(
+			&gotrader.Tick{Instrument: "EUR_USD", Bid: 1.1, Ask: 1.1002, Time: now},
+			&gotrader.Tick{Instrument: "GBP_USD", Bid: 1.27, Ask: 1.2703},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := encoder.Encode(&gotrader.Tick{Instrument: "GBP_USD", Bid: 1.28, Ask: 1.2803, Time: now}); err != nil {
+			t.Fatal(err)
+		}
+
+		if buffer.Len() != 2*BatchHeaderSize+3*TickSize {
+			t.Errorf("expected fixed-width records, got %d bytes", buffer.Len())
+		}
+
+		decoder := NewDecoder(buffer, symbols)
+
+		ticks, err := decoder.Decode(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(ticks) != 2 || ticks[0].Instrument != "EUR_USD" || ticks[0].Ask != 1.1002 || !ticks[0].Time.Equal(now) ||
+			ticks[1].Instrument != "GBP_USD" || !ticks[1].Time.IsZero() {
+			t.Errorf("unexpected first batch %+v", ticks)
+		}
+
+		ticks, err = decoder.Decode(ticks)
+		if err != nil || len(ticks) != 1 || ticks[0].Bid != 1.28 {
+			t.Errorf("unexpected second batch %+v %v", ticks, err)
+		}
+
+		if _, err := decoder.Decode(ticks); err != io.EOF {
+			t.Errorf("expected the end of the stream, got %v", err)
+		}
+	})
+
+	t.Run("unknown instruments are rejected", func(t *testing.T) {
+
+		buffer.Reset()
+
+		if err := encoder.Encode(&gotrader.Tick{Instrument: "USD_JPY"}); err == nil || buffer.Len() != 0 {
+			t.Errorf("expected an error without writes, got %v and %d bytes", err, buffer.Len())
+		}
+	})
+
+	t.Run("truncated batches are detected", func(t *testing.T) {
+
+		buffer.Reset()
+		encoder.Encode(&gotrader.Tick{Instrument: "EUR_USD", Time: now})
+		buffer.Truncate(buffer.Len() - 1)
+
+		if _, err := NewDecoder(buffer, symbols).Decode(nil); err != io.ErrUnexpectedEOF {
+			t.Errorf("expected an unexpected EOF, got %v", err)
+		}
+	})
+}